@@ -0,0 +1,99 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"context"
+)
+
+// ChangeOperation is the kind of write a ChangeEvent is about.
+type ChangeOperation string
+
+// Operations of change events.
+const (
+	ChangeInsert ChangeOperation = "insert"
+	ChangeUpdate ChangeOperation = "update"
+	ChangeUpsert ChangeOperation = "upsert"
+	ChangeDelete ChangeOperation = "delete"
+)
+
+// ChangeEvent describes a successful write on a collection.
+type ChangeEvent struct {
+	// Collection is the name of the collection that was written.
+	Collection string
+
+	// Operation is the kind of write.
+	Operation ChangeOperation
+
+	// Key is the primary key of the inserted item, or the one the updated or
+	// deleted result set was found by, like with Collection.Find(id), if it's
+	// known.
+	Key interface{}
+
+	// Conditions are the conditions of the result set that was updated or
+	// deleted, they are empty when every item was affected.
+	Conditions []interface{}
+
+	// Before holds the deleted items when they were read back, like with
+	// Result.DeleteReturning, or the updated items as they were before the
+	// update if the listener is a BeforeChangeListener that wants them.
+	Before interface{}
+
+	// After is the item that was inserted or the values the result set was
+	// updated with.
+	After interface{}
+
+	// Session is the session (or transaction) the write was made with.
+	Session Database
+
+	// Context is the context the write was made within.
+	Context context.Context
+}
+
+// ChangeListener is notified of the writes made through the collections and
+// result sets of SQL sessions, see Settings.SetChangeListener. Writes made
+// within a transaction are notified before it's committed, listeners that
+// must only see committed changes can defer their work with the AfterCommit
+// method of the transaction.
+type ChangeListener interface {
+	OnChange(*ChangeEvent)
+}
+
+// BeforeChangeListener is a ChangeListener that is given the items of the
+// result sets that are updated, as they were before the update, in
+// ChangeEvent.Before. They're read with an extra query right before the
+// update.
+type BeforeChangeListener interface {
+	ChangeListener
+
+	// WantsBefore reports whether the items of the given collection must be
+	// read before they're updated.
+	WantsBefore(collection string) bool
+}
+
+// ChangeListenerFunc is a function that implements ChangeListener.
+type ChangeListenerFunc func(*ChangeEvent)
+
+// OnChange calls fn.
+func (fn ChangeListenerFunc) OnChange(ev *ChangeEvent) {
+	fn(ev)
+}
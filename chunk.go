@@ -0,0 +1,109 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"reflect"
+)
+
+// DefaultChunkSize is the number of values of each chunk of InChunks when no
+// size is given, it's well within the limit of parameters of a statement of
+// every supported database, like the 2100 of MSSQL.
+const DefaultChunkSize = 1000
+
+// ChunkedComparison is an IN comparison whose values are compared in chunks,
+// see InChunks.
+type ChunkedComparison interface {
+	Comparison
+
+	// Chunks returns the values of the comparison split into chunks, there's
+	// always at least one.
+	Chunks() [][]interface{}
+}
+
+type chunkedComparison struct {
+	dbComparisonOperator
+	size int
+}
+
+// Chunks splits the values into chunks of the size of the comparison.
+func (c *chunkedComparison) Chunks() [][]interface{} {
+	values := c.v.([]interface{})
+	if len(values) == 0 {
+		return [][]interface{}{values}
+	}
+
+	chunks := make([][]interface{}, 0, (len(values)+c.size-1)/c.size)
+	for len(values) > c.size {
+		chunks = append(chunks, values[:c.size])
+		values = values[c.size:]
+	}
+	return append(chunks, values)
+}
+
+// InChunks is like In, but a result set with it is fetched with one query
+// for each chunk of the given size the values are split into, which avoids
+// exceeding the limit of parameters of a statement with huge lists, like the
+// 65535 of PostgreSQL or the 2100 of MSSQL. The results of the queries are
+// merged, so they're only ordered within each chunk. Repeated values are
+// compared once. A size of zero or less means DefaultChunkSize.
+//
+//	// Runs one query for every 500 ids.
+//	res := col.Find(db.Cond{"id": db.InChunks(ids, 500)})
+//
+// Only the first InChunks of the db.Cond values given to Find, Where or And
+// is split, any other, like the ones within Or, is compared as a single IN.
+// Results can only be read with All, One, Exists and Count, the methods that
+// can't merge the results of many queries, like Next or Paginate, fail with
+// ErrUnsupported when there's more than one chunk. Update and Delete run one
+// statement per chunk, they're only atomic within a transaction.
+func InChunks(v interface{}, size int) ChunkedComparison {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	return &chunkedComparison{
+		dbComparisonOperator: dbComparisonOperator{
+			t: ComparisonOperatorIn,
+			v: uniqueValues(toInterfaceArray(v)),
+		},
+		size: size,
+	}
+}
+
+// uniqueValues returns the given values without the repeated ones, in the
+// same order. Values of types that can't be compared are all kept.
+func uniqueValues(values []interface{}) []interface{} {
+	seen := make(map[interface{}]struct{}, len(values))
+	unique := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		if v == nil || reflect.TypeOf(v).Comparable() {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+		}
+		unique = append(unique, v)
+	}
+	return unique
+}
+
+var _ ChunkedComparison = &chunkedComparison{}
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInChunks(t *testing.T) {
+	c := InChunks([]int{1, 2, 3, 4, 5}, 2)
+	assert.Equal(t, ComparisonOperatorIn, c.Operator())
+	assert.Equal(t, []interface{}{1, 2, 3, 4, 5}, c.Value())
+	assert.Equal(t, [][]interface{}{{1, 2}, {3, 4}, {5}}, c.Chunks())
+
+	assert.Equal(t, [][]interface{}{{1, 2}}, InChunks([]int{1, 2}, 2).Chunks())
+
+	// An empty list is still compared once.
+	assert.Equal(t, 1, len(InChunks([]int{}, 2).Chunks()))
+
+	// Repeated values would be counted once per chunk.
+	assert.Equal(t, [][]interface{}{{1, 2}, {3}}, InChunks([]int{1, 2, 1, 3, 2}, 2).Chunks())
+	assert.Equal(t, [][]interface{}{{[]byte("a"), []byte("a")}}, InChunks([][]byte{[]byte("a"), []byte("a")}, 2).Chunks())
+
+	ids := make([]int64, DefaultChunkSize+1)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+	chunks := InChunks(ids, 0).Chunks()
+	if assert.Equal(t, 2, len(chunks)) {
+		assert.Equal(t, DefaultChunkSize, len(chunks[0]))
+		assert.Equal(t, 1, len(chunks[1]))
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// collection is the actual implementation of a collection.
+type collection struct {
+	sqladapter.BaseCollection // Leveraged by sqladapter
+
+	d    *database
+	name string
+}
+
+var (
+	_ = sqladapter.Collection(&collection{})
+	_ = sqladapter.BulkLoader(&collection{})
+	_ = db.Collection(&collection{})
+)
+
+// newCollection binds *collection with sqladapter.
+func newCollection(d *database, name string) *collection {
+	c := &collection{
+		name: name,
+		d:    d,
+	}
+	c.BaseCollection = sqladapter.NewBaseCollection(c)
+	return c
+}
+
+func (c *collection) Name() string {
+	return c.name
+}
+
+func (c *collection) Database() sqladapter.Database {
+	return c.d
+}
+
+// InsertContext inserts an item (map or struct) into the collection.
+// ClickHouse does not generate keys, the returned ID is built from the primary
+// key values of the item.
+func (c *collection) InsertContext(ctx context.Context, item interface{}) (interface{}, error) {
+	return sqladapter.InsertWithHooks(ctx, c.d, c.Name(), item, func() (interface{}, error) {
+		return c.insert(ctx, item)
+	})
+}
+
+func (c *collection) insert(ctx context.Context, item interface{}) (interface{}, error) {
+	columns, rows, err := c.BulkRows(ctx, []interface{}{item})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.insertRows(ctx, columns, rows); err != nil {
+		return nil, err
+	}
+
+	pKey := c.BaseCollection.PrimaryKeys()
+
+	keyMap := db.Cond{}
+	for i := range columns {
+		for j := range pKey {
+			if pKey[j] == columns[i] {
+				keyMap[pKey[j]] = rows[0][i]
+			}
+		}
+	}
+
+	if len(keyMap) == 0 {
+		return nil, nil
+	}
+
+	if len(pKey) == 1 {
+		return keyMap[pKey[0]], nil
+	}
+
+	return keyMap, nil
+}
+
+// BulkLoadContext sends all items to the server in a single batch, it must be
+// called within a transaction.
+func (c *collection) BulkLoadContext(ctx context.Context, items []interface{}) error {
+	columns, rows, err := c.BulkRows(ctx, items)
+	if err != nil {
+		return err
+	}
+	return c.insertRows(ctx, columns, rows)
+}
+
+// insertRows sends rows to the server as a batch. The ClickHouse driver only
+// accepts INSERT statements that are prepared within a transaction, the batch
+// is sent when the transaction is committed, so a transaction is started if
+// the session is not already within one.
+func (c *collection) insertRows(ctx context.Context, columns []string, rows [][]interface{}) error {
+	if sqlTx, ok := c.d.Driver().(*sql.Tx); ok {
+		return c.execBatch(ctx, sqlTx, columns, rows)
+	}
+
+	return c.d.Tx(ctx, func(sess sqlbuilder.Tx) error {
+		return sess.Collection(c.Name()).(*collection).execBatch(ctx, sess.Driver().(*sql.Tx), columns, rows)
+	})
+}
+
+func (c *collection) execBatch(ctx context.Context, sqlTx *sql.Tx, columns []string, rows [][]interface{}) error {
+	query, err := insertQuery(c.Name(), columns)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := sqlTx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range rows {
+		if _, err := stmt.ExecContext(ctx, rows[i]...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertQuery returns an INSERT statement with placeholders for the given
+// columns, the driver prepares it once and sends all the rows together.
+func insertQuery(table string, columns []string) (string, error) {
+	fragments := make([]exql.Fragment, len(columns))
+	placeholders := make([]exql.Fragment, len(columns))
+	for i := range columns {
+		fragments[i] = exql.ColumnWithName(columns[i])
+		placeholders[i] = exql.RawValue("?")
+	}
+
+	stmt := exql.Statement{
+		Type:    exql.Insert,
+		Table:   exql.TableWithName(table),
+		Columns: exql.JoinColumns(fragments...),
+		Values:  exql.JoinValueGroups(exql.NewValueGroup(placeholders...)),
+	}
+
+	return stmt.Compile(template)
+}
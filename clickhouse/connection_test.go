@@ -0,0 +1,75 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package clickhouse
+
+import (
+	"testing"
+)
+
+func TestConnectionURL(t *testing.T) {
+	c := ConnectionURL{}
+
+	// Zero value equals to an empty string.
+	if c.String() != "" {
+		t.Fatal(`Expecting default connection string to be empty, got:`, c.String())
+	}
+
+	c.Database = "analytics"
+
+	if c.String() != "tcp://127.0.0.1:9000?database=analytics" {
+		t.Fatal(`Test failed, got:`, c.String())
+	}
+
+	c.Host = "clickhouse.example.com:9440"
+	c.User = "user"
+	c.Password = "pa$$"
+	c.Options = map[string]string{
+		"secure": "true",
+	}
+
+	if c.String() != "tcp://clickhouse.example.com:9440?database=analytics&password=pa%24%24&secure=true&username=user" {
+		t.Fatal(`Test failed, got:`, c.String())
+	}
+}
+
+func TestParseConnectionURL(t *testing.T) {
+	u, err := ParseURL("tcp://clickhouse.example.com:9000?username=user&password=pass&database=analytics&read_timeout=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Host != "clickhouse.example.com:9000" {
+		t.Fatal("Failed to parse host.")
+	}
+
+	if u.User != "user" || u.Password != "pass" {
+		t.Fatal("Failed to parse credentials.")
+	}
+
+	if u.Database != "analytics" {
+		t.Fatal("Failed to parse database.")
+	}
+
+	if u.Options["read_timeout"] != "10" || len(u.Options) != 1 {
+		t.Fatal("Failed to parse options.")
+	}
+}
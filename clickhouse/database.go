@@ -0,0 +1,353 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package clickhouse
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	clickhousedriver "github.com/ClickHouse/clickhouse-go"
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// database is the actual implementation of Database
+type database struct {
+	sqladapter.BaseDatabase
+
+	sqlbuilder.SQLBuilder
+
+	connURL db.ConnectionURL
+	mu      sync.Mutex
+}
+
+var (
+	_ = sqlbuilder.Database(&database{})
+	_ = sqladapter.Database(&database{})
+)
+
+// newDatabase creates a new *database session for internal use.
+func newDatabase(settings db.ConnectionURL) *database {
+	return &database{
+		connURL: settings,
+	}
+}
+
+// Adapter returns the name of the adapter.
+func (d *database) Adapter() string {
+	return Adapter
+}
+
+// ConnectionURL returns this database session's connection URL, if any.
+func (d *database) ConnectionURL() db.ConnectionURL {
+	return d.connURL
+}
+
+// Open attempts to open a connection with the database server.
+func (d *database) Open(connURL db.ConnectionURL) error {
+	if connURL == nil {
+		return db.ErrMissingConnURL
+	}
+	d.connURL = connURL
+	return d.open()
+}
+
+// NewTx begins a transaction block with the given context.
+func (d *database) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	nTx, err := d.NewDatabaseTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{DatabaseTx: nTx}, nil
+}
+
+// Collections returns a list of non-system tables from the database.
+func (d *database) Collections() (collections []string, err error) {
+	q := d.Select("name").
+		From("system.tables").
+		Where("database = currentDatabase() AND is_temporary = 0")
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	for iter.Next() {
+		var tableName string
+		if err := iter.Scan(&tableName); err != nil {
+			return nil, err
+		}
+		collections = append(collections, tableName)
+	}
+
+	return collections, nil
+}
+
+// open attempts to establish a connection with the ClickHouse server.
+func (d *database) open() error {
+	// Binding with sqladapter's logic.
+	d.BaseDatabase = sqladapter.NewBaseDatabase(d)
+
+	if err := sqladapter.SetPoolOptions(d.BaseDatabase, sqladapter.ConnectionOptions(d.connURL)); err != nil {
+		return err
+	}
+
+	if err := setupTLS(d.connURL); err != nil {
+		return err
+	}
+
+	// Binding with sqlbuilder.
+	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
+
+	connFn := func() error {
+		sess, err := d.BaseDatabase.OpenFailover(sqlDriver, sqladapter.HostDSNs(d.connURL), sqladapter.ConnectionOptions(d.connURL))
+		if err == nil {
+			sess.SetConnMaxLifetime(d.BaseDatabase.ConnMaxLifetime())
+			compat.SetConnMaxIdleTime(sess, d.BaseDatabase.ConnMaxIdleTime())
+			sess.SetMaxIdleConns(d.BaseDatabase.MaxIdleConns())
+			sess.SetMaxOpenConns(d.BaseDatabase.MaxOpenConns())
+			return d.BaseDatabase.BindSession(sess)
+		}
+		return err
+	}
+
+	if err := d.BaseDatabase.WaitForConnection(connFn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// connectionTLS returns the TLS configuration of the given connection URL.
+func connectionTLS(connURL db.ConnectionURL) *db.TLSConfig {
+	switch u := connURL.(type) {
+	case ConnectionURL:
+		return u.TLS
+	case *ConnectionURL:
+		if u != nil {
+			return u.TLS
+		}
+	}
+	return nil
+}
+
+// setupTLS registers the TLS configuration of the given connection URL with
+// the driver, under the name its DSN refers to.
+func setupTLS(connURL db.ConnectionURL) error {
+	cfg := connectionTLS(connURL)
+	if cfg == nil {
+		return nil
+	}
+	tlsConfig, err := cfg.Load()
+	if err != nil {
+		return err
+	}
+	return clickhousedriver.RegisterTLSConfig(sqladapter.TLSConfigKey(cfg), tlsConfig)
+}
+
+// Clone creates a copy of the database session on the given context.
+func (d *database) clone(ctx context.Context, checkConn bool) (*database, error) {
+	clone := newDatabase(d.connURL)
+
+	var err error
+	clone.BaseDatabase, err = d.NewClone(clone, checkConn)
+	if err != nil {
+		return nil, err
+	}
+
+	clone.SetContext(ctx)
+
+	clone.SQLBuilder = sqlbuilder.WithSession(clone.BaseDatabase, template)
+
+	return clone, nil
+}
+
+// CompileStatement allows sqladapter to compile the given statement into the
+// format ClickHouse expects.
+func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
+	compiled, err := stmt.Compile(template)
+	if err != nil {
+		panic(err.Error())
+	}
+	return sqlbuilder.Preprocess(compiled, args)
+}
+
+// Err allows sqladapter to translate specific ClickHouse string errors into
+// custom error values.
+func (d *database) Err(err error) error {
+	if err != nil {
+		// Code 202: TOO_MANY_SIMULTANEOUS_QUERIES.
+		if strings.Contains(err.Error(), `Too many simultaneous queries`) {
+			return db.ErrTooManyClients
+		}
+		// Code 469: VIOLATED_CONSTRAINT, ClickHouse only has CHECK constraints.
+		if s := err.Error(); strings.Contains(s, `Code: 469`) {
+			cErr := &db.ErrCheckViolation{Err: err}
+			if m := reViolatedConstraint.FindStringSubmatch(s); m != nil {
+				cErr.Constraint = m[1]
+			}
+			return cErr
+		}
+	}
+	return err
+}
+
+// reViolatedConstraint extracts the name of the constraint from messages like
+// "Constraint `positive_id` for table default.t is violated at row 1".
+var reViolatedConstraint = regexp.MustCompile("Constraint `([^`]+)`")
+
+// NewCollection creates a db.Collection by name.
+func (d *database) NewCollection(name string) db.Collection {
+	return newCollection(d, name)
+}
+
+// Tx creates a transaction block on the given context and passes it to the
+// function fn. ClickHouse uses transactions to send inserts in batches, they
+// are not atomic: if fn returns an error the batches that were not sent yet
+// are discarded but the other statements are not undone.
+func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) error {
+	return sqladapter.RunTx(d, ctx, fn)
+}
+
+// TxWithRetry is like Tx, ClickHouse does not report serialization failures
+// so fn is never retried.
+func (d *database) TxWithRetry(ctx context.Context, fn func(tx sqlbuilder.Tx) error, policy sqlbuilder.RetryPolicy) error {
+	return sqladapter.TxWithRetry(d, ctx, fn, policy)
+}
+
+// NewDatabaseTx begins a transaction block. Nested transactions are not
+// supported.
+func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
+	if d.BaseDatabase.Transaction() != nil {
+		return nil, db.ErrUnsupported
+	}
+
+	clone, err := d.clone(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	clone.mu.Lock()
+	defer clone.mu.Unlock()
+
+	connFn := func() error {
+		sqlTx, err := compat.BeginTx(clone.BaseDatabase.Session(), ctx, clone.TxOptions())
+		if err == nil {
+			return clone.BindTx(ctx, sqlTx)
+		}
+		return err
+	}
+
+	if err := clone.BaseDatabase.WaitForConnection(connFn); err != nil {
+		return nil, err
+	}
+
+	return sqladapter.NewDatabaseTx(clone), nil
+}
+
+// ParseURL parses the given DSN into a ConnectionURL, so that sqladapter can
+// replace its credentials.
+func (d *database) ParseURL(dsn string) (db.ConnectionURL, error) {
+	return ParseURL(dsn)
+}
+
+// LookupName looks for the name of the database and it's often used as a
+// test to determine if the connection settings are valid.
+func (d *database) LookupName() (string, error) {
+	q := d.Select(db.Raw("currentDatabase() AS name"))
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	if iter.Next() {
+		var name string
+		err := iter.Scan(&name)
+		return name, err
+	}
+
+	return "", iter.Err()
+}
+
+// TableExists returns an error if the given table name does not exist on the
+// database.
+func (d *database) TableExists(name string) error {
+	q := d.Select("name").
+		From("system.tables").
+		Where("database = currentDatabase() AND name = ?", name)
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	if iter.Next() {
+		var name string
+		if err := iter.Scan(&name); err != nil {
+			return err
+		}
+		return nil
+	}
+	return db.ErrCollectionDoesNotExist
+}
+
+// PrimaryKeys returns the names of the columns of the primary key of the
+// table. ClickHouse does not enforce the uniqueness of primary keys.
+func (d *database) PrimaryKeys(tableName string) ([]string, error) {
+	q := d.Select("name").
+		From("system.columns").
+		Where("database = currentDatabase() AND table = ? AND is_in_primary_key = 1", tableName).
+		OrderBy("position")
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	pk := []string{}
+
+	for iter.Next() {
+		var k string
+		if err := iter.Scan(&k); err != nil {
+			return nil, err
+		}
+		pk = append(pk, k)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+// WithContext creates a copy of the session on the given context.
+func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
+	newDB, _ := d.clone(ctx, false)
+	return newDB
+}
+
+// ReadOnly creates a copy of the session that can't change the database.
+func (d *database) ReadOnly() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UseReadOnly()
+	return newDB
+}
@@ -0,0 +1,84 @@
+package clickhouse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+func TestTemplateSelect(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		"SELECT * FROM `visits` WHERE (`user_id` = $1) LIMIT 10",
+		b.SelectFrom("visits").Where("user_id", 1).Limit(10).String(),
+	)
+
+	assert.Equal(
+		"SELECT * FROM `visits` FINAL WHERE (`user_id` = $1)",
+		b.SelectFrom("visits").Final().Where("user_id", 1).String(),
+	)
+
+	assert.Equal(
+		"SELECT * FROM `visits` ORDER BY `created_at` DESC LIMIT 3 BY `user_id` LIMIT 100",
+		b.SelectFrom("visits").OrderBy("-created_at").LimitBy(3, "user_id").Limit(100).String(),
+	)
+
+	assert.Equal(
+		"SELECT `domain`, count() AS `hits` FROM `visits` GROUP BY `domain` ORDER BY `hits` DESC LIMIT 1 BY `domain`, `hits`",
+		b.Select("domain", db.Raw("count() AS `hits`")).From("visits").GroupBy("domain").OrderBy("-hits").LimitBy(1, "domain", "hits").String(),
+	)
+
+	{
+		_, _, err := b.SelectFrom("visits").ForUpdate().Compile()
+		assert.Equal(db.ErrUnsupported, err)
+	}
+}
+
+func TestTemplateInsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		"INSERT INTO `visits` (`user_id`, `domain`) VALUES ($1, $2)",
+		b.InsertInto("visits").Columns("user_id", "domain").Values(1, "upper.io").String(),
+	)
+
+	query, err := insertQuery("visits", []string{"user_id", "domain"})
+	assert.NoError(err)
+	assert.Equal("INSERT INTO `visits` (`user_id`, `domain`) VALUES (?, ?)", strings.Join(strings.Fields(query), " "))
+}
+
+func TestTemplateUpdate(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		"ALTER TABLE `visits` UPDATE `domain` = $1 WHERE (`user_id` = $2)",
+		b.Update("visits").Set("domain", "upper.io").Where("user_id", 1).String(),
+	)
+
+	assert.Equal(
+		"ALTER TABLE `visits` UPDATE `domain` = $1 WHERE 1",
+		b.Update("visits").Set("domain", "upper.io").String(),
+	)
+}
+
+func TestTemplateDelete(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		"ALTER TABLE `visits` DELETE WHERE (`user_id` = $1)",
+		b.DeleteFrom("visits").Where("user_id", 1).String(),
+	)
+
+	assert.Equal(
+		"ALTER TABLE `visits` DELETE WHERE 1",
+		b.DeleteFrom("visits").String(),
+	)
+}
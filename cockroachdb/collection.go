@@ -0,0 +1,174 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package cockroachdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
+)
+
+// collection is the actual implementation of a collection.
+type collection struct {
+	sqladapter.BaseCollection // Leveraged by sqladapter
+
+	d    *database
+	name string
+}
+
+var (
+	_ = sqladapter.Collection(&collection{})
+	_ = sqladapter.BatchInserter(&collection{})
+	_ = db.Collection(&collection{})
+)
+
+// newCollection binds *collection with sqladapter.
+func newCollection(d *database, name string) *collection {
+	c := &collection{
+		name: name,
+		d:    d,
+	}
+	c.BaseCollection = sqladapter.NewBaseCollection(c)
+	return c
+}
+
+func (c *collection) Name() string {
+	return c.name
+}
+
+func (c *collection) Database() sqladapter.Database {
+	return c.d
+}
+
+// InsertContext inserts an item (map or struct) into the collection.
+func (c *collection) InsertContext(ctx context.Context, item interface{}) (interface{}, error) {
+	return sqladapter.InsertWithHooks(ctx, c.d, c.Name(), item, func() (interface{}, error) {
+		return c.insert(ctx, item)
+	})
+}
+
+func (c *collection) insert(ctx context.Context, item interface{}) (interface{}, error) {
+	var err error
+
+	pKey := c.BaseCollection.PrimaryKeys()
+
+	// The item is mapped here so that its key and its tenant can be given a
+	// value.
+	columnNames, columnValues, err := c.InsertValues(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	q := c.d.InsertInto(c.Name()).Columns(columnNames...).Values(columnValues...)
+
+	if len(pKey) == 0 || c.d.DryRun() {
+		// There is no primary key, or the statement won't run because the
+		// session is in dry-run mode and there's no key to return.
+		var res sql.Result
+
+		if res, err = q.ExecContext(ctx); err != nil {
+			return nil, err
+		}
+
+		// Attempt to use LastInsertId() (probably won't work, but the Exec()
+		// succeeded, so we can safely ignore the error from LastInsertId()).
+		lastID, _ := res.LastInsertId()
+
+		return lastID, nil
+	}
+
+	// Asking the database to return the primary key after insertion.
+	q = q.Returning(pKey...)
+
+	var keyMap db.Cond
+	if err = q.IteratorContext(ctx).One(&keyMap); err != nil {
+		return nil, err
+	}
+
+	// The IDSetter interface does not match, look for another interface match.
+	if len(keyMap) == 1 {
+		return sqladapter.ReturnedKey(keyMap[pKey[0]]), nil
+	}
+
+	// This was a compound key and no interface matched it, let's return a map.
+	return keyMap, nil
+}
+
+// InsertBatchContext inserts all items with a single statement and returns
+// their primary keys in the same order.
+func (c *collection) InsertBatchContext(ctx context.Context, items []interface{}) ([]interface{}, error) {
+	pKey := c.BaseCollection.PrimaryKeys()
+	if len(pKey) == 0 {
+		return nil, db.ErrUnsupported
+	}
+
+	for i := range items {
+		if hook, ok := items[i].(db.BeforeInsertHook); ok {
+			if err := hook.BeforeInsert(c.d); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	columns, rows, err := c.BulkRows(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	q := c.d.InsertInto(c.Name()).Columns(columns...)
+	for i := range rows {
+		q = q.Values(rows[i]...)
+	}
+
+	// Asking the database to return the primary keys after insertion.
+	q = q.Returning(pKey...)
+
+	var keyMaps []db.Cond
+	if err := q.IteratorContext(ctx).All(&keyMaps); err != nil {
+		return nil, err
+	}
+
+	if len(keyMaps) != len(items) {
+		return nil, fmt.Errorf("Expecting %d IDs but got %d", len(items), len(keyMaps))
+	}
+
+	ids := make([]interface{}, len(keyMaps))
+	for i := range keyMaps {
+		if len(keyMaps[i]) == 1 {
+			ids[i] = keyMaps[i][pKey[0]]
+			continue
+		}
+		ids[i] = keyMaps[i]
+	}
+
+	for i := range items {
+		if hook, ok := items[i].(db.AfterInsertHook); ok {
+			if err := hook.AfterInsert(c.d); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return ids, nil
+}
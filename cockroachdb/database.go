@@ -0,0 +1,454 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package cockroachdb
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
+	"upper.io/db.v3/postgresql"
+)
+
+// restartSavepoint is the savepoint CockroachDB uses to retry a transaction
+// without losing its priority, see
+// https://www.cockroachlabs.com/docs/stable/advanced-client-side-transaction-retries.html
+const restartSavepoint = `cockroach_restart`
+
+// database is the actual implementation of Database
+type database struct {
+	sqladapter.BaseDatabase
+
+	sqlbuilder.SQLBuilder
+
+	connURL db.ConnectionURL
+	mu      sync.Mutex
+}
+
+var (
+	_ = sqlbuilder.Database(&database{})
+	_ = sqladapter.Database(&database{})
+)
+
+// newDatabase creates a new *database session for internal use.
+func newDatabase(settings db.ConnectionURL) *database {
+	return &database{
+		connURL: settings,
+	}
+}
+
+// Adapter returns the name of the adapter.
+func (d *database) Adapter() string {
+	return Adapter
+}
+
+// ConnectionURL returns this database session's connection URL, if any.
+func (d *database) ConnectionURL() db.ConnectionURL {
+	return d.connURL
+}
+
+// Open attempts to open a connection with the database server.
+func (d *database) Open(connURL db.ConnectionURL) error {
+	if connURL == nil {
+		return db.ErrMissingConnURL
+	}
+	d.connURL = connURL
+	return d.open()
+}
+
+// NewTx begins a transaction block with the given context.
+func (d *database) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	nTx, err := d.NewDatabaseTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{DatabaseTx: nTx}, nil
+}
+
+// Collections returns a list of non-system tables from the database.
+func (d *database) Collections() (collections []string, err error) {
+	q := d.Select("table_name").
+		From("information_schema.tables").
+		Where("table_schema = ? AND table_type = ?", "public", "BASE TABLE")
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	for iter.Next() {
+		var tableName string
+		if err := iter.Scan(&tableName); err != nil {
+			return nil, err
+		}
+		collections = append(collections, tableName)
+	}
+
+	return collections, nil
+}
+
+// open attempts to establish a connection with the CockroachDB server.
+func (d *database) open() error {
+	// Binding with sqladapter's logic.
+	d.BaseDatabase = sqladapter.NewBaseDatabase(d)
+
+	if err := sqladapter.SetPoolOptions(d.BaseDatabase, sqladapter.ConnectionOptions(d.connURL)); err != nil {
+		return err
+	}
+
+	if err := setupTLS(d.connURL); err != nil {
+		return err
+	}
+
+	// Binding with sqlbuilder.
+	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
+
+	connFn := func() error {
+		sess, err := d.BaseDatabase.OpenFailover(sqlDriver, sqladapter.HostDSNs(d.connURL), sqladapter.ConnectionOptions(d.connURL))
+		if err == nil {
+			sess.SetConnMaxLifetime(d.BaseDatabase.ConnMaxLifetime())
+			compat.SetConnMaxIdleTime(sess, d.BaseDatabase.ConnMaxIdleTime())
+			sess.SetMaxIdleConns(d.BaseDatabase.MaxIdleConns())
+			sess.SetMaxOpenConns(d.BaseDatabase.MaxOpenConns())
+			return d.BaseDatabase.BindSession(sess)
+		}
+		return err
+	}
+
+	if err := d.BaseDatabase.WaitForConnection(connFn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// connectionTLS returns the TLS configuration of the given connection URL.
+func connectionTLS(connURL db.ConnectionURL) *db.TLSConfig {
+	switch u := connURL.(type) {
+	case postgresql.ConnectionURL:
+		return u.TLS
+	case *postgresql.ConnectionURL:
+		if u != nil {
+			return u.TLS
+		}
+	}
+	return nil
+}
+
+// setupTLS checks that the TLS configuration of the given connection URL can
+// be used, pq only takes file paths.
+func setupTLS(connURL db.ConnectionURL) error {
+	cfg := connectionTLS(connURL)
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Config != nil {
+		return db.ErrUnsupported
+	}
+	return cfg.Validate()
+}
+
+// Clone creates a copy of the database session on the given context.
+func (d *database) clone(ctx context.Context, checkConn bool) (*database, error) {
+	clone := newDatabase(d.connURL)
+
+	var err error
+	clone.BaseDatabase, err = d.NewClone(clone, checkConn)
+	if err != nil {
+		return nil, err
+	}
+
+	clone.SetContext(ctx)
+
+	clone.SQLBuilder = sqlbuilder.WithSession(clone.BaseDatabase, template)
+
+	return clone, nil
+}
+
+// ConvertValues uses the same conversions as the postgresql adapter, CockroachDB
+// understands PostgreSQL's arrays and JSONB values.
+func (d *database) ConvertValues(values []interface{}) []interface{} {
+	return postgresql.ConvertValues(values)
+}
+
+// OutParamsRow reads the OUT parameters of stored procedures from the row
+// CALL returns.
+func (d *database) OutParamsRow() bool {
+	return true
+}
+
+// TruncateStatements returns the given Truncate statement, CockroachDB can't
+// restart the sequences of the table.
+func (d *database) TruncateStatements(table string, stmt *exql.Statement) ([]*exql.Statement, error) {
+	if stmt.RestartIdentity {
+		return nil, db.ErrUnsupported
+	}
+	return []*exql.Statement{stmt}, nil
+}
+
+// NextSequenceValueQuery returns the statement that advances the given
+// sequence, it's used by NextSequenceValue.
+func (d *database) NextSequenceValueQuery(name string) string {
+	return "SELECT nextval('" + strings.Replace(name, "'", "''", -1) + "')"
+}
+
+// ServerUUIDs leaves fields tagged with "uuid" to the default value of their
+// columns, like gen_random_uuid().
+func (d *database) ServerUUIDs() bool {
+	return true
+}
+
+// CompileStatement compiles a *exql.Statement into arguments that sql/database
+// accepts.
+func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
+	compiled, err := stmt.Compile(template)
+	if err != nil {
+		panic(err.Error())
+	}
+	query, args := sqlbuilder.Preprocess(compiled, args)
+	return sqladapter.ReplaceWithDollarSign(query), args
+}
+
+// Err allows sqladapter to translate specific CockroachDB errors into custom
+// error values.
+func (d *database) Err(err error) error {
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "40001" {
+			// CockroachDB reports every retryable error with SQLSTATE 40001.
+			return db.ErrSerializationFailure
+		}
+		if cErr := postgresql.ConstraintError(err); cErr != nil {
+			return cErr
+		}
+		s := err.Error()
+		if strings.Contains(s, `too many clients`) || strings.Contains(s, `remaining connection slots are reserved`) || strings.Contains(s, `too many open`) {
+			return db.ErrTooManyClients
+		}
+		if strings.Contains(s, `restart transaction`) || strings.Contains(s, `TransactionRetryWithProtoRefreshError`) {
+			return db.ErrSerializationFailure
+		}
+	}
+	return err
+}
+
+// NewCollection creates a db.Collection by name.
+func (d *database) NewCollection(name string) db.Collection {
+	return newCollection(d, name)
+}
+
+// Tx creates a transaction block on the given context and passes it to the
+// function fn. If fn returns no error the transaction is commited, else the
+// transaction is rolled back. After being commited or rolled back the
+// transaction is closed automatically.
+func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) error {
+	return sqladapter.RunTx(d, ctx, fn)
+}
+
+// TxWithRetry is like Tx but it follows CockroachDB's client-side retry
+// protocol: fn runs after a "cockroach_restart" savepoint and, if the
+// transaction fails with a retryable error, the transaction is rolled back to
+// that savepoint and fn is run again within the same transaction, which keeps
+// its priority among conflicting transactions.
+func (d *database) TxWithRetry(ctx context.Context, fn func(tx sqlbuilder.Tx) error, policy sqlbuilder.RetryPolicy) error {
+	sess, err := d.NewTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	if _, err := sess.Exec(`SAVEPOINT ` + restartSavepoint); err != nil {
+		sess.Rollback()
+		return err
+	}
+
+	for retries := 0; ; retries++ {
+		err := fn(sess)
+		if err == nil {
+			if _, err = sess.Exec(`RELEASE SAVEPOINT ` + restartSavepoint); err == nil {
+				return sess.Commit()
+			}
+		}
+
+		if retries >= policy.MaxRetries || !sqladapter.IsSerializationFailure(d, err) {
+			sess.Rollback()
+			return err
+		}
+
+		if _, err := sess.Exec(`ROLLBACK TO SAVEPOINT ` + restartSavepoint); err != nil {
+			sess.Rollback()
+			return err
+		}
+
+		var done <-chan struct{}
+		if ctx != nil {
+			done = ctx.Done()
+		}
+
+		select {
+		case <-time.After(sqladapter.RetryBackoff(policy, retries)):
+		case <-done:
+			sess.Rollback()
+			return ctx.Err()
+		}
+	}
+}
+
+// NewDatabaseTx begins a transaction block. If the session is already within a
+// transaction, the new one is nested using a savepoint.
+func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
+	clone, err := d.clone(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	clone.mu.Lock()
+	defer clone.mu.Unlock()
+
+	if currTx := d.BaseDatabase.Transaction(); currTx != nil {
+		// Already within a transaction, nest the new one with a savepoint.
+		if err := clone.BindSavepoint(ctx, currTx); err != nil {
+			return nil, err
+		}
+		return sqladapter.NewDatabaseTx(clone), nil
+	}
+
+	connFn := func() error {
+		sqlTx, err := compat.BeginTx(clone.BaseDatabase.Session(), ctx, clone.TxOptions())
+		if err == nil {
+			return clone.BindTx(ctx, sqlTx)
+		}
+		return err
+	}
+
+	if err := clone.BaseDatabase.WaitForConnection(connFn); err != nil {
+		return nil, err
+	}
+
+	return sqladapter.NewDatabaseTx(clone), nil
+}
+
+// IsPrimary returns true, all the nodes of a CockroachDB cluster accept
+// writes.
+func (d *database) IsPrimary(ctx context.Context, sess *sql.DB) (bool, error) {
+	return true, nil
+}
+
+// ParseURL parses the given DSN into a ConnectionURL, so that sqladapter can
+// replace its credentials.
+func (d *database) ParseURL(dsn string) (db.ConnectionURL, error) {
+	return postgresql.ParseURL(dsn)
+}
+
+// LookupName looks for the name of the database and it's often used as a
+// test to determine if the connection settings are valid.
+func (d *database) LookupName() (string, error) {
+	q := d.Select(db.Raw("CURRENT_DATABASE() AS name"))
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	if iter.Next() {
+		var name string
+		err := iter.Scan(&name)
+		return name, err
+	}
+
+	return "", iter.Err()
+}
+
+// TableExists returns an error if the given table name does not exist on the
+// database.
+func (d *database) TableExists(name string) error {
+	q := d.Select("table_name").
+		From("information_schema.tables").
+		Where("table_catalog = ? AND table_name = ?", d.BaseDatabase.Name(), name)
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	if iter.Next() {
+		var name string
+		if err := iter.Scan(&name); err != nil {
+			return err
+		}
+		return nil
+	}
+	return db.ErrCollectionDoesNotExist
+}
+
+// PrimaryKeys returns the names of all the primary keys on the table. The
+// hidden rowid column CockroachDB adds to tables without a primary key is not
+// reported.
+func (d *database) PrimaryKeys(tableName string) ([]string, error) {
+	q := d.Select("kcu.column_name AS pkey").
+		From("information_schema.table_constraints AS tc").
+		Join("information_schema.key_column_usage AS kcu").
+		Using("table_schema", "table_name", "constraint_name").
+		Join("information_schema.columns AS c").
+		Using("table_schema", "table_name", "column_name").
+		Where(`
+			tc.constraint_type = 'PRIMARY KEY'
+			AND tc.table_schema = ?
+			AND tc.table_name = ?
+			AND c.is_hidden = 'NO'
+		`, "public", tableName).
+		OrderBy("kcu.ordinal_position")
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	pk := []string{}
+
+	for iter.Next() {
+		var k string
+		if err := iter.Scan(&k); err != nil {
+			return nil, err
+		}
+		pk = append(pk, k)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+// WithContext creates a copy of the session on the given context.
+func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
+	newDB, _ := d.clone(ctx, false)
+	return newDB
+}
+
+// ReadOnly creates a copy of the session that can't change the database.
+func (d *database) ReadOnly() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UseReadOnly()
+	return newDB
+}
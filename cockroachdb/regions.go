@@ -0,0 +1,95 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package cockroachdb
+
+import (
+	"strings"
+
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// Locality defines how the rows of a table are placed on a multi-region
+// database, see
+// https://www.cockroachlabs.com/docs/stable/multiregion-overview.html
+type Locality string
+
+// Localities that do not depend on a region.
+const (
+	// Global tables are optimized for low-latency reads from every region.
+	Global = Locality(`GLOBAL`)
+	// RegionalByRow tables place each row in the region that is stored on its
+	// hidden crdb_region column.
+	RegionalByRow = Locality(`REGIONAL BY ROW`)
+)
+
+// RegionalByTable returns a Locality that places the whole table in the given
+// region, or in the primary region of the database if region is empty.
+func RegionalByTable(region string) Locality {
+	if region == "" {
+		return Locality(`REGIONAL BY TABLE IN PRIMARY REGION`)
+	}
+	return Locality(`REGIONAL BY TABLE IN ` + quoteIdentifier(region))
+}
+
+// SetPrimaryRegion sets the primary region of the current database, which
+// turns it into a multi-region database.
+func SetPrimaryRegion(sess sqlbuilder.SQLBuilder, region string) error {
+	return alterDatabase(sess, `SET PRIMARY REGION `+quoteIdentifier(region))
+}
+
+// AddRegion adds a region to the current multi-region database.
+func AddRegion(sess sqlbuilder.SQLBuilder, region string) error {
+	return alterDatabase(sess, `ADD REGION `+quoteIdentifier(region))
+}
+
+// DropRegion removes a region from the current multi-region database.
+func DropRegion(sess sqlbuilder.SQLBuilder, region string) error {
+	return alterDatabase(sess, `DROP REGION `+quoteIdentifier(region))
+}
+
+// SetLocality changes the locality of the given table.
+func SetLocality(sess sqlbuilder.SQLBuilder, table string, locality Locality) error {
+	_, err := sess.Exec(`ALTER TABLE ` + quoteIdentifier(table) + ` SET LOCALITY ` + string(locality))
+	return err
+}
+
+func alterDatabase(sess sqlbuilder.SQLBuilder, action string) error {
+	var name string
+	row, err := sess.QueryRow(`SELECT CURRENT_DATABASE()`)
+	if err != nil {
+		return err
+	}
+	if err := row.Scan(&name); err != nil {
+		return err
+	}
+	_, err = sess.Exec(`ALTER DATABASE ` + quoteIdentifier(name) + ` ` + action)
+	return err
+}
+
+// quoteIdentifier quotes each part of a possibly qualified name.
+func quoteIdentifier(s string) string {
+	chunks := strings.Split(s, ".")
+	for i := range chunks {
+		chunks[i] = `"` + strings.Replace(chunks[i], `"`, `""`, -1) + `"`
+	}
+	return strings.Join(chunks, ".")
+}
@@ -0,0 +1,342 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package cockroachdb
+
+import (
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/cache"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+const (
+	adapterColumnSeparator     = `.`
+	adapterIdentifierSeparator = `, `
+	adapterIdentifierQuote     = `"{{.Value}}"`
+	adapterValueSeparator      = `, `
+	adapterValueQuote          = `'{{.}}'`
+	adapterAndKeyword          = `AND`
+	adapterOrKeyword           = `OR`
+	adapterDescKeyword         = `DESC`
+	adapterAscKeyword          = `ASC`
+	adapterAssignmentOperator  = `=`
+	adapterClauseGroup         = `({{.}})`
+	adapterClauseOperator      = ` {{.}} `
+	adapterColumnValue         = `{{.Column}} {{.Operator}} {{.Value}}`
+	adapterTableAliasLayout    = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
+	adapterColumnAliasLayout   = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
+	adapterSortByColumnLayout  = `{{.Column}} {{.Order}}`
+	adapterWindowLayout        = `{{.Function}} OVER ({{.Definition}})`
+	adapterJSONPathLayout      = `{{.Column}}{{range .Segments}}{{if .Last}}->>{{else}}->{{end}}{{if .Index}}{{.Key}}{{else}}'{{.Key}}'{{end}}{{end}}`
+	adapterFullTextLayout      = `to_tsvector({{.Column}}) @@ plainto_tsquery(?)`
+	adapterFullTextRankLayout  = `ts_rank(to_tsvector({{.Column}}), plainto_tsquery(?)) DESC`
+	adapterDistinctOnLayout    = `DISTINCT ON ({{.Columns}})`
+	adapterLockLayout          = `{{if .Update}}FOR UPDATE{{else}}FOR SHARE{{end}}{{if .SkipLocked}} SKIP LOCKED{{end}}{{if .NoWait}} NOWAIT{{end}}`
+	adapterAsOfLayout          = `AS OF SYSTEM TIME {{.}}`
+	adapterUpdateFromLayout    = `{{if .Tables}}FROM {{.Tables}}{{end}} {{.Joins}}`
+	adapterDeleteUsingLayout   = `{{if .Tables}}USING {{.Tables}}{{end}} {{.Joins}}`
+
+	adapterOrderByLayout = `
+    {{if .SortColumns}}
+      ORDER BY {{.SortColumns}}
+    {{end}}
+  `
+
+	adapterWhereLayout = `
+    {{if .Conds}}
+      WHERE {{.Conds}}
+    {{end}}
+  `
+
+	adapterUsingLayout = `
+    {{if .Columns}}
+      USING ({{.Columns}})
+    {{end}}
+  `
+
+	adapterJoinLayout = `
+    {{if .Table}}
+      {{ if .On }}
+        {{.Type}} JOIN {{.Table}}
+        {{.On}}
+      {{ else if .Using }}
+        {{.Type}} JOIN {{.Table}}
+        {{.Using}}
+      {{ else if .Type | eq "CROSS" }}
+        {{.Type}} JOIN {{.Table}}
+      {{else}}
+        NATURAL {{.Type}} JOIN {{.Table}}
+      {{end}}
+    {{end}}
+  `
+
+	adapterOnLayout = `
+    {{if .Conds}}
+      ON {{.Conds}}
+    {{end}}
+  `
+
+	adapterWithLayout = `
+    WITH {{if .Recursive}}RECURSIVE {{end}}{{.CTEs}}
+  `
+
+	adapterCompoundLayout = `
+    {{range .}}{{if .Operator}} {{.Operator}} {{end}}({{.Query}}){{end}}
+  `
+
+	adapterSelectLayout = `
+    {{if .With}}
+      {{.With}}
+    {{end}}
+
+    {{if .Compound}}
+      {{.Compound}}
+    {{else}}
+      SELECT
+        {{if .DistinctOn}}
+          {{.DistinctOn}}
+        {{else if .Distinct}}
+          DISTINCT
+        {{end}}
+
+        {{if .Columns}}
+          {{.Columns}}
+        {{else}}
+          *
+        {{end}}
+
+        {{if .Table}}
+          FROM {{.Table}}
+        {{end}}
+
+        {{.Joins}}
+
+        {{if .AsOf}}
+          {{.AsOf}}
+        {{end}}
+
+        {{.Where}}
+
+        {{.GroupBy}}
+    {{end}}
+
+      {{.OrderBy}}
+
+      {{if .Limit}}
+        LIMIT {{.Limit}}
+      {{end}}
+
+      {{if .Offset}}
+        OFFSET {{.Offset}}
+      {{end}}
+
+      {{if .Lock}}
+        {{.Lock}}
+      {{end}}
+  `
+	adapterDeleteLayout = `
+    DELETE
+      FROM {{.Table}}
+      {{.From}}
+      {{.Where}}
+    {{if .Returning}}
+      RETURNING {{.Returning}}
+    {{end}}
+  `
+	adapterUpdateLayout = `
+    UPDATE
+      {{.Table}}
+    SET {{.ColumnValues}}
+      {{.From}}
+      {{ .Where }}
+    {{if .Returning}}
+      RETURNING {{.Returning}}
+    {{end}}
+  `
+
+	adapterSelectCountLayout = `
+    SELECT
+      COUNT(1) AS _t
+    FROM {{.Table}}
+      {{.Where}}
+  `
+
+	adapterInsertLayout = `
+    INSERT INTO {{.Table}}
+      {{if .Columns }}({{.Columns}}){{end}}
+    {{if .Query}}
+      {{.Query}}
+    {{else}}
+      VALUES
+      {{if .Values}}
+        {{.Values}}
+      {{else}}
+        (default)
+      {{end}}
+    {{end}}
+    {{if .OnConflict}}
+      {{.OnConflict}}
+    {{end}}
+    {{if .Returning}}
+      RETURNING {{.Returning}}
+    {{end}}
+  `
+
+	adapterOnConflictLayout = `
+    ON CONFLICT
+      {{if .Target}}({{range $i, $c := .Target}}{{if $i}}, {{end}}{{$c}}{{end}}){{end}}
+    {{if .Update}}
+      DO UPDATE SET {{.Update}}
+    {{else}}
+      DO NOTHING
+    {{end}}
+  `
+
+	adapterExcludedColumn = `EXCLUDED.{{.}}`
+
+	adapterTruncateLayout = `
+    TRUNCATE TABLE {{.Table}}
+    {{if .Cascade}}
+      CASCADE
+    {{end}}
+  `
+
+	adapterDropDatabaseLayout = `
+    DROP DATABASE {{.Database}}
+  `
+
+	adapterDropTableLayout = `
+    DROP TABLE {{.Table}}
+  `
+
+	adapterCreateTableLayout = `
+    CREATE TABLE {{.Table}} ({{.Definitions}})
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table}} {{.Definitions}}
+  `
+
+	adapterCreateIndexLayout = `
+    CREATE {{if .Unique}}UNIQUE {{end}}INDEX {{.Index}} ON {{.Table}} ({{.Columns}})
+  `
+
+	adapterCallLayout = `
+    CALL {{.Table}}({{.Columns}})
+  `
+
+	adapterCallOutLayout = `?`
+
+	adapterAddColumnLayout = `ADD COLUMN {{.}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.}}`
+
+	adapterColumnDefinitionLayout = `
+    {{.Name}}
+    {{if .AutoIncrement}}{{if eq .Type "BIGINT"}}BIGSERIAL{{else}}SERIAL{{end}}{{else}}{{.Type}}{{end}}
+    {{if .PrimaryKey}}PRIMARY KEY{{end}}
+    {{if .NotNull}}NOT NULL{{end}}
+    {{if .Unique}}UNIQUE{{end}}
+    {{if .Default}}DEFAULT {{.Default}}{{end}}
+    {{if .References}}REFERENCES {{.References}}{{end}}
+  `
+
+	adapterGroupByLayout = `
+    {{if .GroupColumns}}
+      GROUP BY {{.GroupColumns}}
+    {{end}}
+  `
+)
+
+var adapterColumnTypes = map[exql.ColumnType]string{
+	exql.TypeBoolean:    `BOOLEAN`,
+	exql.TypeInteger:    `INTEGER`,
+	exql.TypeBigInteger: `BIGINT`,
+	exql.TypeFloat:      `DOUBLE PRECISION`,
+	exql.TypeDecimal:    `NUMERIC({{.Precision}}, {{.Scale}})`,
+	exql.TypeString:     `VARCHAR({{.Size}})`,
+	exql.TypeText:       `TEXT`,
+	exql.TypeTimestamp:  `TIMESTAMP`,
+	exql.TypeDate:       `DATE`,
+	exql.TypeBinary:     `BYTEA`,
+	exql.TypeJSON:       `JSONB`,
+}
+
+var template = &exql.Template{
+	ColumnSeparator:        adapterColumnSeparator,
+	IdentifierSeparator:    adapterIdentifierSeparator,
+	IdentifierQuote:        adapterIdentifierQuote,
+	ValueSeparator:         adapterValueSeparator,
+	ValueQuote:             adapterValueQuote,
+	AndKeyword:             adapterAndKeyword,
+	OrKeyword:              adapterOrKeyword,
+	DescKeyword:            adapterDescKeyword,
+	AscKeyword:             adapterAscKeyword,
+	AssignmentOperator:     adapterAssignmentOperator,
+	ClauseGroup:            adapterClauseGroup,
+	ClauseOperator:         adapterClauseOperator,
+	ColumnValue:            adapterColumnValue,
+	TableAliasLayout:       adapterTableAliasLayout,
+	ColumnAliasLayout:      adapterColumnAliasLayout,
+	SortByColumnLayout:     adapterSortByColumnLayout,
+	WhereLayout:            adapterWhereLayout,
+	WindowLayout:           adapterWindowLayout,
+	CompoundLayout:         adapterCompoundLayout,
+	WithLayout:             adapterWithLayout,
+	JoinLayout:             adapterJoinLayout,
+	JSONPathLayout:         adapterJSONPathLayout,
+	FullTextLayout:         adapterFullTextLayout,
+	FullTextRankLayout:     adapterFullTextRankLayout,
+	DistinctOnLayout:       adapterDistinctOnLayout,
+	LockLayout:             adapterLockLayout,
+	AsOfLayout:             adapterAsOfLayout,
+	OnLayout:               adapterOnLayout,
+	UsingLayout:            adapterUsingLayout,
+	OrderByLayout:          adapterOrderByLayout,
+	InsertLayout:           adapterInsertLayout,
+	OnConflictLayout:       adapterOnConflictLayout,
+	ConflictTargetNeeded:   true,
+	ExcludedColumn:         adapterExcludedColumn,
+	SelectLayout:           adapterSelectLayout,
+	UpdateLayout:           adapterUpdateLayout,
+	UpdateFromLayout:       adapterUpdateFromLayout,
+	DeleteUsingLayout:      adapterDeleteUsingLayout,
+	DeleteLayout:           adapterDeleteLayout,
+	TruncateLayout:         adapterTruncateLayout,
+	DropDatabaseLayout:     adapterDropDatabaseLayout,
+	DropTableLayout:        adapterDropTableLayout,
+	CreateTableLayout:      adapterCreateTableLayout,
+	AlterTableLayout:       adapterAlterTableLayout,
+	CreateIndexLayout:      adapterCreateIndexLayout,
+	CallLayout:             adapterCallLayout,
+	CallOutLayout:          adapterCallOutLayout,
+	AddColumnLayout:        adapterAddColumnLayout,
+	DropColumnLayout:       adapterDropColumnLayout,
+	ColumnDefinitionLayout: adapterColumnDefinitionLayout,
+	ColumnTypes:            adapterColumnTypes,
+	CountLayout:            adapterSelectCountLayout,
+	GroupByLayout:          adapterGroupByLayout,
+	Cache:                  cache.NewCache(),
+	ComparisonOperator: map[db.ComparisonOperator]string{
+		db.ComparisonOperatorRegExp:    "~",
+		db.ComparisonOperatorNotRegExp: "!~",
+	},
+}
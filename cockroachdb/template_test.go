@@ -0,0 +1,35 @@
+package cockroachdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+func TestTemplateSelect(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`SELECT * FROM "artist" WHERE ("id" = $1)`,
+		b.SelectFrom("artist").Where("id", 1).String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "artist" AS OF SYSTEM TIME '-10s' WHERE ("id" = $1)`,
+		b.SelectFrom("artist").AsOf(-10*time.Second).Where("id", 1).String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "artist" AS OF SYSTEM TIME '2018-06-01 12:30:00' ORDER BY "name" ASC`,
+		b.SelectFrom("artist").AsOf(time.Date(2018, 6, 1, 12, 30, 0, 0, time.UTC)).OrderBy("name").String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "artist" AS OF SYSTEM TIME follower_read_timestamp()`,
+		b.SelectFrom("artist").AsOf(db.Raw("follower_read_timestamp()")).String(),
+	)
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package cockroachdb
+
+import (
+	"context"
+
+	"upper.io/db.v3/internal/sqladapter"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+type tx struct {
+	sqladapter.DatabaseTx
+}
+
+var (
+	_ = sqlbuilder.Tx(&tx{})
+)
+
+func (t *tx) WithContext(ctx context.Context) sqlbuilder.Tx {
+	var newTx tx
+	newTx = *t
+	newTx.DatabaseTx.SetContext(ctx)
+	return &newTx
+}
+
+// NewTx begins a nested transaction, which is delimited by a savepoint within
+// the current one.
+func (t *tx) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	if ctx == nil {
+		ctx = t.Context()
+	}
+	nTx, err := t.DatabaseTx.NewDatabaseTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{DatabaseTx: nTx}, nil
+}
+
+// Tx creates a nested transaction block on the given context and passes it to
+// the function fn. If fn returns no error the savepoint is released, else the
+// transaction is rolled back to it.
+func (t *tx) Tx(ctx context.Context, fn func(sess sqlbuilder.Tx) error) error {
+	return sqladapter.RunTx(t, ctx, fn)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+package db
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Codec encodes the values of a Go type into values SQL drivers accept, and
+// decodes the values of columns into them. Codecs let types that don't
+// implement driver.Valuer and sql.Scanner, like the ones of third party
+// packages, be used as arguments and struct fields across adapters.
+type Codec struct {
+	// Encode returns the value that is bound in place of value, values are
+	// bound as they are if it's nil.
+	Encode func(value interface{}) (interface{}, error)
+
+	// Decode sets dst, a pointer to a value of the type, given the value of
+	// a column as the driver returns it, src is nil for NULL. Byte slices are
+	// owned by the driver and must be copied to be kept. Values are scanned
+	// as usual if it's nil.
+	Decode func(src interface{}, dst interface{}) error
+}
+
+// Codecs is a registry of codecs by Go type, see Settings.SetCodecs. It's
+// safe for concurrent use.
+type Codecs struct {
+	mu     sync.RWMutex
+	codecs map[reflect.Type]*Codec
+}
+
+// NewCodecs returns an empty registry of codecs.
+func NewCodecs() *Codecs {
+	return &Codecs{codecs: map[reflect.Type]*Codec{}}
+}
+
+// Register sets the codec of the type of the given value:
+//
+//	codecs.Register(decimal.Decimal{}, db.Codec{
+//		Encode: func(v interface{}) (interface{}, error) {
+//			return v.(decimal.Decimal).String(), nil
+//		},
+//		Decode: func(src interface{}, dst interface{}) error {
+//			...
+//		},
+//	})
+//
+// The codec is used for values of that exact type only, pointers to it are
+// dereferenced, nil ones are NULL.
+func (c *Codecs) Register(value interface{}, codec Codec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.codecs[reflect.TypeOf(value)] = &codec
+}
+
+// Lookup returns the codec of type t, nil if there's none.
+func (c *Codecs) Lookup(t reflect.Type) *Codec {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.codecs[t]
+}
@@ -21,6 +21,27 @@
 
 package db
 
+import (
+	"context"
+)
+
+// TruncateOption changes how Collection.Truncate empties a collection.
+type TruncateOption uint8
+
+// Options of Collection.Truncate.
+const (
+	// TruncateCascade also empties the tables that have foreign keys to the
+	// collection. Databases that can't truncate tables referenced by foreign
+	// keys, like MySQL or MSSQL, delete the rows of the collection instead,
+	// which empties the referencing tables only if their foreign keys are ON
+	// DELETE CASCADE.
+	TruncateCascade TruncateOption = 1 << iota
+
+	// TruncateRestartIdentity resets the sequences of the auto-increment
+	// columns of the collection.
+	TruncateRestartIdentity
+)
+
 // Collection is an interface that defines methods useful for handling tables.
 type Collection interface {
 	// Insert inserts a new item into the collection, it accepts one argument
@@ -31,6 +52,10 @@ type Collection interface {
 	// element.
 	Insert(interface{}) (interface{}, error)
 
+	// InsertContext is like Insert() but the query runs within the given
+	// context.
+	InsertContext(context.Context, interface{}) (interface{}, error)
+
 	// InsertReturning is like Insert() but it updates the passed pointer to map
 	// or struct with the newly inserted element (and with automatic fields, like
 	// IDs, timestamps, etc). This is all done atomically within a transaction.
@@ -38,6 +63,33 @@ type Collection interface {
 	// db.ErrUnsupported.
 	InsertReturning(interface{}) error
 
+	// InsertReturningContext is like InsertReturning() but the queries run
+	// within the given context.
+	InsertReturningContext(context.Context, interface{}) error
+
+	// InsertReturningAll is like InsertReturning() but it takes a slice of
+	// pointers to maps or structs, inserts all of them and updates each one
+	// with the newly inserted element. This is all done atomically within a
+	// transaction, using a single statement on databases that support
+	// RETURNING. If the database does not support transactions this method
+	// returns db.ErrUnsupported.
+	InsertReturningAll(interface{}) error
+
+	// InsertReturningAllContext is like InsertReturningAll() but the queries
+	// run within the given context.
+	InsertReturningAllContext(context.Context, interface{}) error
+
+	// CopyFrom takes a slice of maps or structs and inserts all of them into
+	// the collection as fast as the database allows, using a bulk-load
+	// protocol (like PostgreSQL's COPY) when the adapter supports one and
+	// multi-row inserts otherwise. CopyFrom does not return IDs and does not
+	// call insert hooks.
+	CopyFrom(rows interface{}) error
+
+	// CopyFromContext is like CopyFrom() but the queries run within the given
+	// context.
+	CopyFromContext(ctx context.Context, rows interface{}) error
+
 	// UpdateReturning takes a pointer to map or struct and tries to update the
 	// given item on the collection based on the item's primary keys. Once the
 	// element is updated, UpdateReturning will query the element that was just
@@ -45,15 +97,48 @@ type Collection interface {
 	// db.ErrUnsupported
 	UpdateReturning(interface{}) error
 
+	// UpdateReturningContext is like UpdateReturning() but the queries run
+	// within the given context.
+	UpdateReturningContext(context.Context, interface{}) error
+
+	// Upsert takes a map or struct and inserts it into the collection, if an
+	// element with the same primary key values already exists it gets updated
+	// with the given values instead. If the database does not support upserts
+	// this method returns db.ErrUnsupported.
+	Upsert(interface{}) error
+
+	// UpsertContext is like Upsert() but the query runs within the given
+	// context.
+	UpsertContext(context.Context, interface{}) error
+
 	// Exists returns true if the collection exists, false otherwise.
 	Exists() bool
 
 	// Find defines a new result set with elements from the collection.
 	Find(...interface{}) Result
 
+	// FindByIDs defines a new result set with the elements of the collection
+	// that have any of the given primary key values, ids is a slice. The set
+	// is fetched with one query for each chunk of chunkSize ids, or of
+	// DefaultChunkSize if it's zero, see InChunks:
+	//
+	//   err = col.FindByIDs(ids, 500).All(&items)
+	FindByIDs(ids interface{}, chunkSize int) Result
+
 	// Truncate removes all elements on the collection and resets the
-	// collection's IDs.
-	Truncate() error
+	// collection's IDs. Tables that are referenced by foreign keys can be
+	// truncated along with the tables that reference them using the
+	// TruncateCascade option:
+	//
+	//   err = col.Truncate(db.TruncateCascade, db.TruncateRestartIdentity)
+	//
+	// Options that the database can't honour make Truncate fail with
+	// ErrUnsupported.
+	Truncate(...TruncateOption) error
+
+	// TruncateContext is like Truncate() but the query runs within the given
+	// context.
+	TruncateContext(context.Context, ...TruncateOption) error
 
 	// Name returns the name of the collection.
 	Name() string
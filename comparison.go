@@ -306,7 +306,16 @@ func NotRegExp(v string) Comparison {
 	}
 }
 
-// Op represents a custom comparison operator against the reference.
+// Op represents a custom comparison operator against the reference. If the
+// operator contains ":column" it's used as a template where ":column" is
+// replaced by the reference, such a template may have several "?"
+// placeholders, in which case v must be an []interface{} with a value for
+// each one of them.
+//
+// Example:
+//
+//	// "location" <-> $1 < $2
+//	db.Cond{"location": db.Op(":column <-> ? < ?", []interface{}{point, 100})}
 func Op(customOperator string, v interface{}) Comparison {
 	return &dbComparisonOperator{
 		op: customOperator,
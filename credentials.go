@@ -0,0 +1,77 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// Credentials authenticate new connections to the database server.
+type Credentials struct {
+	// User replaces the user of the connection URL, unless it's empty.
+	User string
+
+	// Password replaces the password of the connection URL.
+	Password string
+
+	// Token is sent instead of Password when it's not empty, like the
+	// authentication tokens of AWS RDS IAM.
+	Token string
+
+	// TTL is for how long the credentials may be used to open new
+	// connections. Zero means the provider is asked again for each new
+	// connection.
+	TTL time.Duration
+}
+
+// Secret returns Token, or Password if there's no token.
+func (c Credentials) Secret() string {
+	if c.Token != "" {
+		return c.Token
+	}
+	return c.Password
+}
+
+// CredentialsProvider gives the credentials of new connections, so they can
+// be rotated without opening a new session. It's set with
+// Settings.SetCredentialsProvider, set it on DefaultSettings to authenticate
+// the first connection of new sessions too:
+//
+//	db.DefaultSettings.SetCredentialsProvider(db.CredentialsProviderFunc(func(ctx context.Context) (db.Credentials, error) {
+//		token, err := rdsutils.BuildAuthToken(endpoint, region, user, creds)
+//		return db.Credentials{User: user, Token: token, TTL: 10 * time.Minute}, err
+//	}))
+//
+// Connections that are already open are not affected, they keep the
+// credentials they were opened with.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsProviderFunc is a function that satisfies CredentialsProvider.
+type CredentialsProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials calls fn.
+func (fn CredentialsProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return fn(ctx)
+}
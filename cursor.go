@@ -0,0 +1,84 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// Cursor is an opaque representation of the value of a cursor column, it's
+// safe to be handed to clients, for instance as a query string parameter, and
+// can be given back to NextPage() or PrevPage().
+type Cursor string
+
+type cursorT struct {
+	Value interface{} `json:"v"`
+	Time  bool        `json:"t,omitempty"`
+}
+
+// EncodeCursor encodes the given value into a Cursor. Only values that can be
+// represented in JSON, and time.Time, are supported.
+func EncodeCursor(value interface{}) (Cursor, error) {
+	c := cursorT{Value: value}
+	if t, ok := value.(time.Time); ok {
+		c.Value, c.Time = t.Format(time.RFC3339Nano), true
+	}
+	buf, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return Cursor(base64.RawURLEncoding.EncodeToString(buf)), nil
+}
+
+// Value decodes the cursor and returns the value it was created from. Integers
+// are returned as int64, other numbers as float64.
+func (c Cursor) Value() (interface{}, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(string(c))
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+
+	var v cursorT
+	if err := dec.Decode(&v); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	switch value := v.Value.(type) {
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return n, nil
+		}
+		return value.Float64()
+	case string:
+		if v.Time {
+			return time.Parse(time.RFC3339Nano, value)
+		}
+	}
+
+	return v.Value, nil
+}
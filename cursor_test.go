@@ -0,0 +1,39 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	now := time.Date(2018, 3, 12, 10, 4, 5, 123, time.UTC)
+
+	values := []interface{}{int64(42), 4.5, "Flea", true, now}
+
+	for i := range values {
+		c, err := EncodeCursor(values[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := c.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if t1, ok := v.(time.Time); ok {
+			if !t1.Equal(now) {
+				t.Fatalf("Got: %v, Expecting: %v", v, now)
+			}
+			continue
+		}
+
+		if v != values[i] {
+			t.Fatalf("Got: %#v, Expecting: %#v", v, values[i])
+		}
+	}
+
+	if _, err := Cursor("not a cursor").Value(); err != ErrInvalidCursor {
+		t.Fatalf("Got: %v, Expecting: %v", err, ErrInvalidCursor)
+	}
+}
@@ -21,6 +21,10 @@
 
 package db
 
+import (
+	"context"
+)
+
 // Database is an interface that defines methods that must be satisfied by
 // all database adapters.
 type Database interface {
@@ -43,6 +47,9 @@ type Database interface {
 	// Ping returns an error if the database manager could not be reached.
 	Ping() error
 
+	// PingContext is like Ping but it gives up when ctx is done.
+	PingContext(ctx context.Context) error
+
 	// Close closes the currently active connection to the database and clears
 	// caches.
 	Close() error
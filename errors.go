@@ -23,6 +23,8 @@ package db
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 // Error messages.
@@ -49,4 +51,113 @@ var (
 	ErrMissingConnURL           = errors.New(`upper: missing DSN`)
 	ErrNotImplemented           = errors.New(`upper: call not implemented`)
 	ErrAlreadyWithinTransaction = errors.New(`upper: already within a transaction`)
+	ErrSerializationFailure     = errors.New(`upper: could not serialize transaction, it may be retried`)
+	ErrInvalidCursor            = errors.New(`upper: invalid cursor`)
+	ErrCircuitOpen              = errors.New(`upper: circuit breaker is open, the database server can't be reached`)
+	ErrQueryTimeout             = errors.New(`upper: statement took longer than its time limit`)
+	ErrDryRun                   = errors.New(`upper: statement was not run, the session is in dry-run mode`)
+	ErrLockNotHeld              = errors.New(`upper: advisory lock is not held by this session`)
+	ErrMissingTenant            = errors.New(`upper: the context of the statement has no tenant for a table scoped by tenant`)
+	ErrTenantColumn             = errors.New(`upper: the tenant column of a table scoped by tenant can't be set`)
+	ErrUnknownScope             = errors.New(`upper: unknown scope`)
+	ErrReadOnly                 = errors.New(`upper: statement may change the database, the session is read-only`)
 )
+
+// QueryError wraps the errors returned by the database when running a
+// statement and records the statement that caused them. The original error
+// can be retrieved with Unwrap().
+type QueryError struct {
+	// Adapter is the name of the adapter that ran the statement.
+	Adapter string
+	// Query is the compiled statement, its arguments are left out so they
+	// don't end up in logs.
+	Query string
+	// Duration is the time it took for the statement to fail.
+	Duration time.Duration
+	// Err is the original error.
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%v (adapter: %s, query: %q, duration: %v)", e.Err, e.Adapter, e.Query, e.Duration)
+}
+
+// Unwrap returns the original error.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// ErrUniqueViolation is returned when a statement would store a duplicated
+// value in a column (or set of columns) that has a unique constraint or a
+// unique index.
+type ErrUniqueViolation struct {
+	// Constraint is the name of the constraint or index, if the database
+	// reports it.
+	Constraint string
+	// Column is the name of the column (or a comma separated list of columns),
+	// if the database reports it.
+	Column string
+	// Err is the error returned by the driver.
+	Err error
+}
+
+func (e *ErrUniqueViolation) Error() string {
+	return constraintErrorString("unique", e.Constraint, e.Err)
+}
+
+// Unwrap returns the error returned by the driver.
+func (e *ErrUniqueViolation) Unwrap() error {
+	return e.Err
+}
+
+// ErrForeignKeyViolation is returned when a statement would leave a row
+// referencing a row that does not exist.
+type ErrForeignKeyViolation struct {
+	// Constraint is the name of the constraint, if the database reports it.
+	Constraint string
+	// Column is the name of the column, if the database reports it.
+	Column string
+	// Err is the error returned by the driver.
+	Err error
+}
+
+func (e *ErrForeignKeyViolation) Error() string {
+	return constraintErrorString("foreign key", e.Constraint, e.Err)
+}
+
+// Unwrap returns the error returned by the driver.
+func (e *ErrForeignKeyViolation) Unwrap() error {
+	return e.Err
+}
+
+// ErrCheckViolation is returned when a statement would store a row that does
+// not satisfy a CHECK constraint.
+type ErrCheckViolation struct {
+	// Constraint is the name of the constraint, if the database reports it.
+	Constraint string
+	// Column is the name of the column, if the database reports it.
+	Column string
+	// Err is the error returned by the driver.
+	Err error
+}
+
+func (e *ErrCheckViolation) Error() string {
+	return constraintErrorString("check", e.Constraint, e.Err)
+}
+
+// Unwrap returns the error returned by the driver.
+func (e *ErrCheckViolation) Unwrap() error {
+	return e.Err
+}
+
+func constraintErrorString(kind string, constraint string, err error) string {
+	s := `upper: ` + kind + ` constraint`
+	if constraint != "" {
+		s = s + ` "` + constraint + `"`
+	}
+	s = s + ` violated`
+	if err != nil {
+		s = s + `: ` + err.Error()
+	}
+	return s
+}
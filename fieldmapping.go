@@ -0,0 +1,77 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"unicode"
+)
+
+// FieldMapping tells SQL sessions how the fields of structs are mapped to
+// columns, see Settings.SetFieldMapping. Mappings must not be modified once
+// they're set, a new one is set instead.
+type FieldMapping struct {
+	// Tag is the name of the struct tag that holds the name of the column of
+	// a field and its options, "db" if it's empty.
+	Tag string
+
+	// NameMapper names the columns of the fields that are not tagged, their
+	// Go names are used as they are if it's nil.
+	NameMapper NameMapper
+}
+
+// NameMapper returns the name of the column of a struct field given the Go
+// name of the field.
+type NameMapper func(fieldName string) string
+
+// SnakeCase is a NameMapper that maps field names like "CreatedAt" and
+// "HTTPStatus" into "created_at" and "http_status".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	out := make([]rune, 0, len(runes)+4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) && runes[i-1] != '_' {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// CamelCase is a NameMapper that maps field names like "CreatedAt" and
+// "HTTPStatus" into "createdAt" and "httpStatus".
+func CamelCase(name string) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsUpper(r) {
+			break
+		}
+		// The last capital of a leading acronym starts the next word.
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(r)
+	}
+	return string(runes)
+}
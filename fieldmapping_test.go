@@ -0,0 +1,29 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestNameMappers(t *testing.T) {
+	tests := []struct {
+		in    string
+		snake string
+		camel string
+	}{
+		{"ID", "id", "id"},
+		{"Name", "name", "name"},
+		{"CreatedAt", "created_at", "createdAt"},
+		{"HTTPStatus", "http_status", "httpStatus"},
+		{"UserID", "user_id", "userID"},
+		{"Already_Snake", "already_snake", "already_Snake"},
+		{"lower", "lower", "lower"},
+	}
+	for _, test := range tests {
+		if got := SnakeCase(test.in); got != test.snake {
+			t.Errorf("SnakeCase(%q): expecting %q, got %q", test.in, test.snake, got)
+		}
+		if got := CamelCase(test.in); got != test.camel {
+			t.Errorf("CamelCase(%q): expecting %q, got %q", test.in, test.camel, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+// FullTextValue represents a full-text search on a column. This is an
+// exported interface but it's rarely used directly, you may want to use the
+// `db.FullText()` function instead.
+type FullTextValue interface {
+	// Column returns the name of the column to search on.
+	Column() string
+
+	// Query returns the text to search for.
+	Query() string
+
+	// Rank returns a sort expression that puts the rows that best match the
+	// search first.
+	Rank() FullTextRankValue
+}
+
+// FullTextRankValue represents the relevance of a full-text search, it can be
+// passed to OrderBy().
+type FullTextRankValue interface {
+	// FullText returns the search the rank was derived from.
+	FullText() FullTextValue
+}
+
+// FullText represents a full-text search for query on the given column, it
+// can be used as a condition on Where() and its rank can be passed to
+// OrderBy(). Query is interpreted by the database, on MSSQL it must be a
+// valid CONTAINS search condition.
+//
+// Examples:
+//
+//	// to_tsvector("body") @@ plainto_tsquery('cats') on PostgreSQL
+//	search := db.FullText("body", "cats")
+//	q := sess.SelectFrom("posts").Where(search).OrderBy(search.Rank())
+//
+//	// MATCH(`body`) AGAINST('cats' IN NATURAL LANGUAGE MODE) on MySQL
+//	res := col.Find(db.FullText("body", "cats"))
+func FullText(column string, query string) FullTextValue {
+	return &fullText{column: column, query: query}
+}
+
+type fullText struct {
+	column string
+	query  string
+}
+
+func (f *fullText) Column() string {
+	return f.column
+}
+
+func (f *fullText) Query() string {
+	return f.query
+}
+
+func (f *fullText) Rank() FullTextRankValue {
+	return &fullTextRank{f}
+}
+
+type fullTextRank struct {
+	fullText *fullText
+}
+
+func (r *fullTextRank) FullText() FullTextValue {
+	return r.fullText
+}
+
+var _ FullTextValue = &fullText{}
+
+var _ FullTextRankValue = &fullTextRank{}
@@ -0,0 +1,169 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// IDGenerator gives values to application-assigned primary keys. SQL sessions
+// consult the generator of a table on insert, when the item has no value for
+// its only primary key, see Settings.SetIDGenerators.
+type IDGenerator interface {
+	// NextID returns the key of the next row inserted into the given table.
+	NextID(table string) (interface{}, error)
+}
+
+// IDGeneratorFunc is a function that implements IDGenerator.
+type IDGeneratorFunc func(table string) (interface{}, error)
+
+// NextID calls fn.
+func (fn IDGeneratorFunc) NextID(table string) (interface{}, error) {
+	return fn(table)
+}
+
+// Sequencer is implemented by SQL sessions on adapters with sequences, like
+// PostgreSQL.
+type Sequencer interface {
+	// NextSequenceValue advances the given sequence and returns its new
+	// value.
+	NextSequenceValue(name string) (int64, error)
+}
+
+// Sequence returns a generator of keys that are read from the given database
+// sequence:
+//
+//	generators.Register("accounts", db.Sequence(sess, "account_ids"))
+func Sequence(seq Sequencer, name string) IDGenerator {
+	return IDGeneratorFunc(func(string) (interface{}, error) {
+		return seq.NextSequenceValue(name)
+	})
+}
+
+// ErrInvalidSnowflakeNode is returned by NewSnowflake when the node is out of
+// range.
+var ErrInvalidSnowflakeNode = errors.New(`upper: snowflake node must be between 0 and 1023`)
+
+// snowflakeEpoch is the time snowflake IDs count milliseconds from,
+// 2020-01-01 00:00:00 UTC.
+const snowflakeEpoch = 1577836800000
+
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	snowflakeMaxNode  = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSeq   = 1<<snowflakeSeqBits - 1
+)
+
+// Snowflake generates unique 63-bit integer keys that sort by their creation
+// time without asking the database: 41 bits of milliseconds since 2020, 10
+// bits of node and 12 bits of sequence within the millisecond. Each process
+// that inserts into the same table must use a distinct node. It's safe for
+// concurrent use.
+type Snowflake struct {
+	mu   sync.Mutex
+	node int64
+	last int64
+	seq  int64
+	now  func() time.Time
+}
+
+// NewSnowflake returns a snowflake generator for the given node, between 0
+// and 1023.
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > snowflakeMaxNode {
+		return nil, ErrInvalidSnowflakeNode
+	}
+	return &Snowflake{node: node, now: time.Now}, nil
+}
+
+// NextID returns a new int64 key, the table is ignored.
+func (s *Snowflake) NextID(string) (interface{}, error) {
+	return s.Next(), nil
+}
+
+// Next returns a new key, it waits for the next millisecond if 4096 keys
+// were already generated within the current one.
+func (s *Snowflake) Next() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ms := s.millis()
+	if ms < s.last {
+		// The clock went backwards, keep counting from the last millisecond.
+		ms = s.last
+	}
+	if ms == s.last {
+		s.seq = (s.seq + 1) & snowflakeMaxSeq
+		if s.seq == 0 {
+			for ms <= s.last {
+				time.Sleep(time.Millisecond / 10)
+				ms = s.millis()
+			}
+		}
+	} else {
+		s.seq = 0
+	}
+	s.last = ms
+
+	return ms<<(snowflakeNodeBits+snowflakeSeqBits) | s.node<<snowflakeSeqBits | s.seq
+}
+
+func (s *Snowflake) millis() int64 {
+	return s.now().UnixNano()/int64(time.Millisecond) - snowflakeEpoch
+}
+
+// IDGenerators is a registry of ID generators by table name, see
+// Settings.SetIDGenerators. It's safe for concurrent use.
+type IDGenerators struct {
+	mu         sync.RWMutex
+	generators map[string]IDGenerator
+}
+
+// NewIDGenerators returns an empty registry of ID generators.
+func NewIDGenerators() *IDGenerators {
+	return &IDGenerators{generators: map[string]IDGenerator{}}
+}
+
+// Register sets the generator of the keys of the given table, the table must
+// have a single primary key. A nil generator removes it.
+func (g *IDGenerators) Register(table string, gen IDGenerator) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if gen == nil {
+		delete(g.generators, table)
+		return
+	}
+	g.generators[table] = gen
+}
+
+// Lookup returns the generator of the given table, nil if there's none.
+func (g *IDGenerators) Lookup(table string) IDGenerator {
+	if g == nil {
+		return nil
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.generators[table]
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnowflake(t *testing.T) {
+	_, err := NewSnowflake(1024)
+	assert.Equal(t, ErrInvalidSnowflakeNode, err)
+
+	s, err := NewSnowflake(5)
+	assert.NoError(t, err)
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	a, b := s.Next(), s.Next()
+	ms := now.UnixNano()/int64(time.Millisecond) - snowflakeEpoch
+	assert.Equal(t, ms<<22|5<<12, a)
+	assert.Equal(t, a+1, b)
+
+	// The clock going backwards doesn't repeat keys.
+	now = now.Add(-time.Second)
+	assert.Equal(t, b+1, s.Next())
+
+	id, err := s.NextID("accounts")
+	assert.NoError(t, err)
+	assert.Equal(t, b+2, id)
+}
+
+func TestSnowflakeConcurrent(t *testing.T) {
+	s, err := NewSnowflake(0)
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	seen := map[int64]bool{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				id := s.Next()
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 8000, len(seen))
+}
+
+type fakeSequencer map[string]int64
+
+func (s fakeSequencer) NextSequenceValue(name string) (int64, error) {
+	if _, ok := s[name]; !ok {
+		return 0, errors.New("no such sequence")
+	}
+	s[name]++
+	return s[name], nil
+}
+
+func TestIDGenerators(t *testing.T) {
+	var generators *IDGenerators
+	assert.Nil(t, generators.Lookup("accounts"))
+
+	seq := fakeSequencer{"account_ids": 41}
+
+	generators = NewIDGenerators()
+	generators.Register("accounts", Sequence(seq, "account_ids"))
+	generators.Register("tokens", Sequence(seq, "token_ids"))
+
+	id, err := generators.Lookup("accounts").NextID("accounts")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	_, err = generators.Lookup("tokens").NextID("tokens")
+	assert.Error(t, err)
+
+	generators.Register("tokens", nil)
+	assert.Nil(t, generators.Lookup("tokens"))
+}
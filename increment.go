@@ -0,0 +1,40 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+// Increment returns a condition that can be given to Updater.Set() or
+// Result.Update() to add n to the current value of the column, the addition
+// is done by the database so concurrent updates are not lost.
+//
+// Example:
+//
+//	// UPDATE "posts" SET "views" = "views" + $1 WHERE "id" = $2
+//	sess.Update("posts").Set(db.Increment("views", 1)).Where("id", 1)
+func Increment(column string, n interface{}) Cond {
+	return Cond{column: Raw(":column + ?", n)}
+}
+
+// Decrement is like Increment but it subtracts n from the current value of
+// the column.
+func Decrement(column string, n interface{}) Cond {
+	return Cond{column: Raw(":column - ?", n)}
+}
@@ -27,23 +27,28 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
 	"upper.io/db.v3/internal/cache/hashstructure"
 )
 
 const defaultCapacity = 128
 
-// Cache holds a map of volatile key -> values.
+// Cache holds a map of volatile key -> values, the least recently used values
+// are evicted when the cache is full.
 type Cache struct {
 	cache    map[string]*list.Element
 	li       *list.List
 	capacity int
+	maxAge   time.Duration
+	onEvict  func()
 	mu       sync.RWMutex
 }
 
 type item struct {
-	key   string
-	value interface{}
+	key     string
+	value   interface{}
+	written time.Time
 }
 
 // NewCacheWithCapacity initializes a new caching space with the given
@@ -80,15 +85,21 @@ func (c *Cache) Read(h Hashable) (string, bool) {
 }
 
 // ReadRaw attempts to retrieve a cached value as an interface{}, if the value
-// does not exists returns nil and false.
+// does not exists, or it's older than the maximum age, returns nil and false.
 func (c *Cache) ReadRaw(h Hashable) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	data, ok := c.cache[h.Hash()]
-	if ok {
-		return data.Value.(*item).value, true
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.cache[h.Hash()]
+	if !ok {
+		return nil, false
 	}
-	return nil, false
+	it := el.Value.(*item)
+	if c.maxAge > 0 && time.Since(it.written) > c.maxAge {
+		c.evict(el)
+		return nil, false
+	}
+	c.li.MoveToFront(el)
+	return it.value, true
 }
 
 // Write stores a value in memory. If the value already exists its overwritten.
@@ -100,18 +111,68 @@ func (c *Cache) Write(h Hashable, value interface{}) {
 
 	if el, ok := c.cache[key]; ok {
 		el.Value.(*item).value = value
+		el.Value.(*item).written = time.Now()
 		c.li.MoveToFront(el)
 		return
 	}
 
-	c.cache[key] = c.li.PushFront(&item{key, value})
+	c.cache[key] = c.li.PushFront(&item{key, value, time.Now()})
+	c.trim()
+}
+
+// SetCapacity changes the maximum number of values the cache holds, values
+// that don't fit anymore are evicted.
+func (c *Cache) SetCapacity(capacity int) error {
+	if capacity < 1 {
+		return errors.New("Capacity must be greater than zero.")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	c.trim()
+	return nil
+}
+
+// SetMaxAge sets how long a value stays in the cache after being written, a
+// zero duration means values don't expire.
+func (c *Cache) SetMaxAge(maxAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxAge = maxAge
+}
+
+// OnEvict sets a function that is called each time a value is evicted, either
+// because the cache is full or because the value expired. The function runs
+// while the cache is locked, so it must not use the cache.
+func (c *Cache) OnEvict(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onEvict = fn
+}
+
+// Len returns the number of values in the cache.
+func (c *Cache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.li.Len()
+}
 
+func (c *Cache) trim() {
 	for c.li.Len() > c.capacity {
-		el := c.li.Remove(c.li.Back())
-		delete(c.cache, el.(*item).key)
-		if p, ok := el.(*item).value.(HasOnPurge); ok {
-			p.OnPurge()
-		}
+		c.evict(c.li.Back())
+	}
+}
+
+func (c *Cache) evict(el *list.Element) {
+	it := c.li.Remove(el).(*item)
+	delete(c.cache, it.key)
+	if p, ok := it.value.(HasOnPurge); ok {
+		p.OnPurge()
+	}
+	if c.onEvict != nil {
+		c.onEvict()
 	}
 }
 
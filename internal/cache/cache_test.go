@@ -24,6 +24,7 @@ package cache
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 var c *Cache
@@ -71,6 +72,58 @@ func TestCacheReadExistentValue(t *testing.T) {
 	}
 }
 
+func TestCacheEviction(t *testing.T) {
+	z, err := NewCacheWithCapacity(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evictions := 0
+	z.OnEvict(func() {
+		evictions++
+	})
+
+	a, b, c := cacheableT{"a"}, cacheableT{"b"}, cacheableT{"c"}
+
+	z.Write(&a, "a")
+	z.Write(&b, "b")
+
+	// Reading a makes b the least recently used value.
+	if _, ok := z.Read(&a); !ok {
+		t.Fatal("Expecting true.")
+	}
+
+	z.Write(&c, "c")
+	if _, ok := z.Read(&b); ok {
+		t.Fatal("Expecting b to be evicted.")
+	}
+	if _, ok := z.Read(&a); !ok {
+		t.Fatal("Expecting a to be kept.")
+	}
+	if evictions != 1 {
+		t.Fatalf("Expecting 1 eviction, got %d.", evictions)
+	}
+
+	if err := z.SetCapacity(0); err == nil {
+		t.Fatal("Expecting an error.")
+	}
+	if err := z.SetCapacity(1); err != nil {
+		t.Fatal(err)
+	}
+	if z.Len() != 1 || evictions != 2 {
+		t.Fatalf("Expecting 1 value and 2 evictions, got %d and %d.", z.Len(), evictions)
+	}
+
+	z.SetMaxAge(time.Millisecond)
+	time.Sleep(time.Millisecond * 5)
+	if _, ok := z.Read(&a); ok {
+		t.Fatal("Expecting a to be expired.")
+	}
+	if z.Len() != 0 || evictions != 3 {
+		t.Fatalf("Expecting 0 values and 3 evictions, got %d and %d.", z.Len(), evictions)
+	}
+}
+
 func BenchmarkNewCache(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		NewCache()
@@ -26,3 +26,18 @@ func FastForward(curr Immutable) (interface{}, error) {
 	err = curr.Fn(in)
 	return in, err
 }
+
+// FastForwardFrom is like FastForward but Fn methods are applied on the given
+// base instead of a new Base.
+func FastForwardFrom(curr Immutable, base interface{}) (interface{}, error) {
+	prev := curr.Prev()
+	if prev == nil {
+		return base, nil
+	}
+	in, err := FastForwardFrom(prev, base)
+	if err != nil {
+		return nil, err
+	}
+	err = curr.Fn(in)
+	return in, err
+}
@@ -0,0 +1,151 @@
+package sqladapter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+const defaultProbeInterval = time.Second
+
+// circuitBreaker counts consecutive connection failures of a session, it is
+// shared by all the clones of a session.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	config   sqlbuilder.CircuitBreaker
+	failures int
+	open     bool
+
+	done     chan struct{}
+	shutdown bool
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{done: make(chan struct{})}
+}
+
+func (b *circuitBreaker) configure(config sqlbuilder.CircuitBreaker) {
+	b.mu.Lock()
+	b.config = config
+	b.failures = 0
+	wasOpen := b.open
+	if config.Threshold < 1 {
+		b.open = false
+	}
+	b.mu.Unlock()
+
+	if wasOpen && config.Threshold < 1 {
+		b.notify(false)
+	}
+}
+
+func (b *circuitBreaker) isOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// allow returns db.ErrCircuitOpen if the circuit is open.
+func (b *circuitBreaker) allow() error {
+	if b.isOpen() {
+		return db.ErrCircuitOpen
+	}
+	return nil
+}
+
+// record counts err if it is a connection failure and resets the count if
+// there was no error, other errors don't change it. The circuit opens when
+// the count reaches the threshold and ping is called in the background until
+// it succeeds.
+func (b *circuitBreaker) record(err error, isConnErr func(error) bool, ping func(context.Context) error) {
+	b.mu.Lock()
+	if b.config.Threshold < 1 || b.open || b.shutdown {
+		b.mu.Unlock()
+		return
+	}
+	if err == nil {
+		b.failures = 0
+		b.mu.Unlock()
+		return
+	}
+	if !isConnErr(err) {
+		b.mu.Unlock()
+		return
+	}
+	b.failures++
+	if b.failures < b.config.Threshold {
+		b.mu.Unlock()
+		return
+	}
+	b.open = true
+	interval := b.config.ProbeInterval
+	b.mu.Unlock()
+
+	if interval <= 0 {
+		interval = defaultProbeInterval
+	}
+
+	b.notify(true)
+	go b.probe(ping, interval)
+}
+
+func (b *circuitBreaker) probe(ping func(context.Context) error, interval time.Duration) {
+	for {
+		select {
+		case <-time.After(interval):
+		case <-b.done:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := ping(ctx)
+		cancel()
+
+		if err == nil {
+			b.mu.Lock()
+			wasOpen := b.open
+			b.open = false
+			b.failures = 0
+			b.mu.Unlock()
+
+			if wasOpen {
+				b.notify(false)
+			}
+			return
+		}
+	}
+}
+
+func (b *circuitBreaker) notify(open bool) {
+	b.mu.Lock()
+	fn := b.config.OnStateChange
+	b.mu.Unlock()
+
+	if fn != nil {
+		fn(open)
+	}
+}
+
+// close stops probing, it's called when the session is closed.
+func (b *circuitBreaker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.shutdown {
+		b.shutdown = true
+		close(b.done)
+	}
+}
+
+// SetCircuitBreaker configures the circuit breaker of the session and its
+// clones.
+func (d *database) SetCircuitBreaker(config sqlbuilder.CircuitBreaker) {
+	d.breaker.configure(config)
+}
+
+// CircuitOpen returns true if the circuit breaker of the session is open.
+func (d *database) CircuitOpen() bool {
+	return d.breaker.isOpen()
+}
@@ -0,0 +1,122 @@
+package sqladapter
+
+import (
+	"context"
+	"reflect"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/immutable"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// chunks returns a copy of the result set for each chunk of the values of
+// its first db.InChunks condition, with the condition replaced by an IN of
+// the chunk, or the result set itself if it has none.
+func (r *Result) chunks() ([]*Result, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	ff, err := immutable.FastForward(r)
+	if err != nil {
+		return nil, err
+	}
+	res := ff.(*result)
+
+	for i := range res.conds {
+		for j := range res.conds[i] {
+			cond, ok := res.conds[i][j].(db.Cond)
+			if !ok {
+				continue
+			}
+			for _, key := range cond.Keys() {
+				chunked, ok := cond[key].(db.ChunkedComparison)
+				if !ok {
+					continue
+				}
+				return r.splitChunks(i, j, cond, key, chunked.Chunks()), nil
+			}
+		}
+	}
+
+	return []*Result{r}, nil
+}
+
+// unchunked returns db.ErrUnsupported if the values of the db.InChunks
+// condition of the result set span more than one chunk, for the methods that
+// can't merge the results of a query per chunk.
+func (r *Result) unchunked() error {
+	chunks, err := r.chunks()
+	if err != nil {
+		return err
+	}
+	if len(chunks) > 1 {
+		return db.ErrUnsupported
+	}
+	return nil
+}
+
+// splitChunks returns a copy of the result set for each of the given chunks,
+// the key of the condition at conds[i][j] is compared with an IN of the
+// chunk.
+func (r *Result) splitChunks(i, j int, cond db.Cond, key interface{}, chunks [][]interface{}) []*Result {
+	results := make([]*Result, len(chunks))
+	for n := range chunks {
+		in := make(db.Cond, len(cond))
+		for k, v := range cond {
+			in[k] = v
+		}
+		in[key] = db.In(chunks[n])
+
+		results[n] = r.frame(func(res *result) error {
+			conds := make([][]interface{}, len(res.conds))
+			copy(conds, res.conds)
+			conds[i] = append([]interface{}(nil), conds[i]...)
+			conds[i][j] = in
+			res.conds = conds
+			return nil
+		})
+	}
+	return results
+}
+
+// unpaged returns db.ErrUnsupported if the result set is delimited by Limit,
+// Offset or Paginate, the items of the queries of many chunks can't be
+// delimited once merged.
+func (r *Result) unpaged() error {
+	res, err := r.fastForward()
+	if err != nil {
+		return err
+	}
+	if res.limit > 0 || res.offset > 0 || res.pageSize > 0 {
+		return db.ErrUnsupported
+	}
+	return nil
+}
+
+// allChunks fetches the items of every chunk into dst, which must be a
+// pointer to a slice.
+func (r *Result) allChunks(ctx context.Context, chunks []*Result, dst interface{}) error {
+	if err := r.unpaged(); err != nil {
+		return err
+	}
+
+	dstv := reflect.ValueOf(dst)
+	if dstv.Kind() != reflect.Ptr || dstv.IsNil() {
+		return sqlbuilder.ErrExpectingPointer
+	}
+	if dstv.Elem().Kind() != reflect.Slice {
+		return sqlbuilder.ErrExpectingSlicePointer
+	}
+
+	items := reflect.MakeSlice(dstv.Elem().Type(), 0, 0)
+	for _, chunk := range chunks {
+		part := reflect.New(dstv.Elem().Type())
+		if err := chunk.AllContext(ctx, part.Interface()); err != nil {
+			return err
+		}
+		items = reflect.AppendSlice(items, part.Elem())
+	}
+	dstv.Elem().Set(items)
+	return nil
+}
@@ -0,0 +1,97 @@
+package sqladapter
+
+import (
+	"database/sql"
+	"sync/atomic"
+
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// replicaSet holds the read-only replicas of a session.
+type replicaSet struct {
+	sessions []*sql.DB
+	policy   sqlbuilder.ReplicaPolicy
+	next     uint64
+}
+
+// pick returns the replica that should run the next query.
+func (r *replicaSet) pick() *sql.DB {
+	if r.policy == sqlbuilder.LeastLoaded {
+		best, bestInUse := r.sessions[0], compat.InUse(r.sessions[0])
+		for _, sess := range r.sessions[1:] {
+			if inUse := compat.InUse(sess); inUse < bestInUse {
+				best, bestInUse = sess, inUse
+			}
+		}
+		return best
+	}
+	n := atomic.AddUint64(&r.next, 1)
+	return r.sessions[(n-1)%uint64(len(r.sessions))]
+}
+
+func (r *replicaSet) close() error {
+	var err error
+	for _, sess := range r.sessions {
+		if cErr := sess.Close(); cErr != nil && err == nil {
+			err = cErr
+		}
+	}
+	return err
+}
+
+// replica returns the replica that should run the given statement, or nil if
+// the statement must run on the primary server.
+func (d *database) replica(stmt *exql.Statement) *sql.DB {
+	d.sessMu.RLock()
+	replicas, usePrimary := d.replicas, d.usePrimary
+	d.sessMu.RUnlock()
+
+	if replicas == nil || usePrimary || d.Transaction() != nil {
+		return nil
+	}
+	switch stmt.Type {
+	case exql.Select, exql.Count:
+		return replicas.pick()
+	}
+	return nil
+}
+
+// replicaSet returns the replicas of the session, if any.
+func (d *database) replicaSet() *replicaSet {
+	d.sessMu.RLock()
+	defer d.sessMu.RUnlock()
+	return d.replicas
+}
+
+// BindReplicas sets the read-only replicas the session sends SELECT
+// statements to.
+func (d *database) BindReplicas(policy sqlbuilder.ReplicaPolicy, sessions ...*sql.DB) error {
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	for _, sess := range sessions {
+		if err := sess.Ping(); err != nil {
+			return err
+		}
+		sess.SetConnMaxLifetime(d.ConnMaxLifetime())
+		compat.SetConnMaxIdleTime(sess, d.ConnMaxIdleTime())
+		sess.SetMaxIdleConns(d.MaxIdleConns())
+		sess.SetMaxOpenConns(d.MaxOpenConns())
+	}
+
+	d.sessMu.Lock()
+	d.replicas = &replicaSet{sessions: sessions, policy: policy}
+	d.sessMu.Unlock()
+
+	return nil
+}
+
+// UsePrimary makes the session send every statement to the primary server.
+func (d *database) UsePrimary() {
+	d.sessMu.Lock()
+	d.usePrimary = true
+	d.sessMu.Unlock()
+}
@@ -0,0 +1,69 @@
+package sqladapter
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	"upper.io/db.v3"
+)
+
+// codecError is bound in place of a value whose codec failed to encode it,
+// the statement fails with err once the driver reads it.
+type codecError struct {
+	err error
+}
+
+func (e codecError) Value() (driver.Value, error) {
+	return nil, e.err
+}
+
+// encodeArgs returns the given arguments with the values of the types that
+// have a codec encoded by it, args is not modified.
+func encodeArgs(codecs *db.Codecs, args []interface{}) []interface{} {
+	if codecs == nil {
+		return args
+	}
+
+	var encoded []interface{}
+	for i := range args {
+		if args[i] == nil {
+			continue
+		}
+
+		v := reflect.ValueOf(args[i])
+		codec := codecs.Lookup(v.Type())
+		if codec == nil && v.Kind() == reflect.Ptr {
+			if codec = codecs.Lookup(v.Type().Elem()); codec != nil && codec.Encode != nil {
+				if v.IsNil() {
+					// Nil pointers are NULL.
+					v = reflect.Value{}
+				} else {
+					v = v.Elem()
+				}
+			}
+		}
+		if codec == nil || codec.Encode == nil {
+			continue
+		}
+
+		if encoded == nil {
+			encoded = make([]interface{}, len(args))
+			copy(encoded, args)
+		}
+		if !v.IsValid() {
+			encoded[i] = nil
+			continue
+		}
+		value, err := codec.Encode(v.Interface())
+		if err != nil {
+			encoded[i] = codecError{err}
+			continue
+		}
+		encoded[i] = value
+	}
+
+	if encoded == nil {
+		return args
+	}
+	return encoded
+}
@@ -0,0 +1,169 @@
+package sqladapter
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+type readCollapsedKey struct{}
+
+// readCollapsed returns true if the given context belongs to the query that
+// runs on behalf of identical concurrent reads.
+func readCollapsed(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	collapsed, _ := ctx.Value(readCollapsedKey{}).(bool)
+	return collapsed
+}
+
+// detachedContext keeps the values of its parent but not its deadline or
+// cancellation, the query of collapsed reads runs on behalf of every caller
+// and not only the one that started it.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// readCall is a read in flight, the rows are ready once done is closed.
+type readCall struct {
+	done    chan struct{}
+	rows    *cachedRows
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// readGroup keeps the reads in flight by their query and arguments.
+type readGroup struct {
+	mu    sync.Mutex
+	calls map[string]*readCall
+}
+
+// do runs fn unless there's a call with the same key in flight, in that case
+// it waits for it and returns its rows. fn runs within a context detached
+// from the one of the caller, which is cancelled once no caller waits for
+// its rows anymore.
+func (g *readGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (*cachedRows, error)) (*cachedRows, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*readCall)
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		queryCtx, cancel := context.WithCancel(detachedContext{ctx})
+		c = &readCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = c
+
+		go func() {
+			c.rows, c.err = fn(queryCtx)
+
+			g.mu.Lock()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+
+			cancel()
+			close(c.done)
+		}()
+	}
+	c.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.rows, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			c.cancel()
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// readKey returns the key of a read of the given query and arguments, the
+// arguments are keyed by the values the driver receives. Reads with arguments
+// that can't be converted into driver values are not collapsed.
+func readKey(name string, query string, args []interface{}) (string, bool) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s\x00%s", name, query)
+	for _, arg := range args {
+		value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return "", false
+		}
+		switch v := value.(type) {
+		case nil:
+			b.WriteString("\x00n")
+		case int64:
+			fmt.Fprintf(&b, "\x00i%d", v)
+		case float64:
+			fmt.Fprintf(&b, "\x00f%x", math.Float64bits(v))
+		case bool:
+			fmt.Fprintf(&b, "\x00b%t", v)
+		case []byte:
+			fmt.Fprintf(&b, "\x00x%d:%s", len(v), v)
+		case string:
+			fmt.Fprintf(&b, "\x00s%d:%s", len(v), v)
+		case time.Time:
+			fmt.Fprintf(&b, "\x00t%s %s", v.Format(time.RFC3339Nano), v.Location())
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// collapsedQuery runs the given statement once for all the identical reads
+// that run at the same time and returns its rows to each one of them.
+// Statements that may change the database are run as usual.
+func (d *database) collapsedQuery(ctx context.Context, stmt *exql.Statement, args []interface{}) (*sql.Rows, error) {
+	ctx = context.WithValue(ctx, readCollapsedKey{}, true)
+
+	query, compiledArgs := d.compileStatement(stmt, args)
+	if writes(stmt, query) {
+		return d.StatementQuery(ctx, stmt, args...)
+	}
+
+	key, ok := readKey(d.Name(), query, compiledArgs)
+	if !ok {
+		return d.StatementQuery(ctx, stmt, args...)
+	}
+	rows, err := d.reads.do(ctx, key, func(ctx context.Context) (*cachedRows, error) {
+		live, err := d.StatementQuery(ctx, stmt, args...)
+		if err != nil {
+			return nil, err
+		}
+		return readRows(live)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replayRows(ctx, rows)
+}
@@ -1,18 +1,30 @@
 package sqladapter
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"time"
 
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter/exql"
 	"upper.io/db.v3/lib/reflectx"
+	"upper.io/db.v3/lib/sqlbuilder"
 )
 
 var mapper = reflectx.NewMapper("db")
 
-var errMissingPrimaryKeys = errors.New("Table %q has no primary keys")
+// copyFromMaxArguments caps the number of placeholders of the multi-row
+// INSERT statements CopyFrom falls back to, 999 is SQLite's default limit.
+const copyFromMaxArguments = 999
+
+var (
+	errMissingPrimaryKeys     = errors.New("Table has no primary keys")
+	errMissingPrimaryKeyValue = errors.New("ID has no value for primary key")
+	errUnknownPrimaryKey      = errors.New("ID has a value for a column that is not a primary key")
+	errExpectingCompositeID   = errors.New("Table has a composite primary key, expecting a db.ID")
+)
 
 // Collection represents a SQL table.
 type Collection interface {
@@ -28,8 +40,8 @@ type PartialCollection interface {
 	// Name returns the name of the table.
 	Name() string
 
-	// Insert inserts a new item into the collection.
-	Insert(interface{}) (interface{}, error)
+	// InsertContext inserts a new item into the collection.
+	InsertContext(context.Context, interface{}) (interface{}, error)
 }
 
 // BaseCollection provides logic for methods that can be shared across all SQL
@@ -41,25 +53,113 @@ type BaseCollection interface {
 	// Find creates and returns a new result set.
 	Find(conds ...interface{}) db.Result
 
+	// FindByIDs creates and returns a new result set of the items with the
+	// given primary key values.
+	FindByIDs(ids interface{}, chunkSize int) db.Result
+
 	// Truncate removes all items on the collection.
-	Truncate() error
+	Truncate(...db.TruncateOption) error
+
+	// TruncateContext removes all items on the collection.
+	TruncateContext(context.Context, ...db.TruncateOption) error
+
+	// Insert inserts a new item into the collection.
+	Insert(interface{}) (interface{}, error)
 
 	// InsertReturning inserts a new item and updates it with the
 	// actual values from the database.
 	InsertReturning(interface{}) error
 
+	// InsertReturningContext inserts a new item and updates it with the
+	// actual values from the database.
+	InsertReturningContext(context.Context, interface{}) error
+
+	// InsertReturningAll inserts a slice of items and updates each one of them
+	// with the actual values from the database.
+	InsertReturningAll(interface{}) error
+
+	// InsertReturningAllContext inserts a slice of items and updates each one
+	// of them with the actual values from the database.
+	InsertReturningAllContext(context.Context, interface{}) error
+
+	// CopyFrom inserts a slice of items using the fastest method available.
+	CopyFrom(interface{}) error
+
+	// CopyFromContext inserts a slice of items using the fastest method
+	// available.
+	CopyFromContext(context.Context, interface{}) error
+
 	// UpdateReturning updates an item and returns the actual values from the
 	// database.
 	UpdateReturning(interface{}) error
 
+	// UpdateReturningContext updates an item and returns the actual values
+	// from the database.
+	UpdateReturningContext(context.Context, interface{}) error
+
+	// Upsert inserts an item or updates the existing one that has the same
+	// primary keys.
+	Upsert(interface{}) error
+
+	// UpsertContext inserts an item or updates the existing one that has the
+	// same primary keys.
+	UpsertContext(context.Context, interface{}) error
+
 	// PrimaryKeys returns the table's primary keys.
 	PrimaryKeys() []string
+
+	// AssignID gives the only primary key of the table the next value of its
+	// ID generator, if it has one and the key has no value among the given
+	// columns of an insert that runs within ctx.
+	AssignID(ctx context.Context, columnNames []string, columnValues []interface{}) ([]string, []interface{}, error)
+
+	// InsertValues maps an item into the columns and values of an insert into
+	// the table, its ID and its tenant are assigned by AssignID.
+	InsertValues(ctx context.Context, item interface{}) ([]string, []interface{}, error)
+
+	// BulkRows maps all items into rows of values that follow the same
+	// columns, see the BulkRows function, each row is given its ID and its
+	// tenant by AssignID.
+	BulkRows(ctx context.Context, items []interface{}) ([]string, [][]interface{}, error)
+}
+
+// BatchInserter is implemented by collections that are able to insert many
+// items with a single statement.
+type BatchInserter interface {
+	// InsertBatchContext inserts all items and returns their IDs, in the same
+	// order.
+	InsertBatchContext(context.Context, []interface{}) ([]interface{}, error)
+}
+
+// BulkLoader is implemented by collections that are able to load many items
+// using a protocol that is faster than INSERT statements.
+type BulkLoader interface {
+	// BulkLoadContext inserts all items, they're expected to have the same
+	// columns.
+	BulkLoadContext(context.Context, []interface{}) error
+}
+
+// RowInserter is implemented by collections that are able to insert an item
+// and read back the inserted row with a single statement.
+type RowInserter interface {
+	// InsertRowContext inserts item and scans the inserted row into dst.
+	InsertRowContext(ctx context.Context, item interface{}, dst interface{}) error
 }
 
 type condsFilter interface {
 	FilterConds(...interface{}) []interface{}
 }
 
+// hasTruncateOptions allows the adapter to truncate tables with options,
+// collections of adapters without it can't be truncated with options.
+type hasTruncateOptions interface {
+	// TruncateStatements returns the statements that run the given Truncate
+	// statement of the named table with its options, like a DELETE on
+	// databases that can't truncate tables referenced by foreign keys, or
+	// db.ErrUnsupported.
+	TruncateStatements(table string, stmt *exql.Statement) ([]*exql.Statement, error)
+}
+
 // collection is the implementation of Collection.
 type collection struct {
 	BaseCollection
@@ -85,16 +185,152 @@ func (c *collection) PrimaryKeys() []string {
 	return c.pk
 }
 
-func (c *collection) filterConds(conds ...interface{}) []interface{} {
+// AssignID gives the only primary key of the collection the next value of the
+// ID generator of the table, if it has one and the key has no value among the
+// given columns of an insert that runs within ctx. The tenant column of tables
+// scoped by tenant is set to the tenant of ctx as well.
+func (c *collection) AssignID(ctx context.Context, columnNames []string, columnValues []interface{}) ([]string, []interface{}, error) {
+	columnNames, columnValues, err := sqlbuilder.AssignScope(ctx, c.Database(), c.Name(), columnNames, columnValues)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gen := c.Database().IDGenerators().Lookup(c.Name())
+	if gen == nil || len(c.pk) != 1 {
+		return columnNames, columnValues, nil
+	}
+
+	i := 0
+	for ; i < len(columnNames); i++ {
+		if columnNames[i] == c.pk[0] {
+			break
+		}
+	}
+	if i < len(columnNames) && !isEmptyKey(columnValues[i]) {
+		return columnNames, columnValues, nil
+	}
+
+	id, err := gen.NextID(c.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if i == len(columnNames) {
+		return append(columnNames, c.pk[0]), append(columnValues, id), nil
+	}
+	values := make([]interface{}, len(columnValues))
+	copy(values, columnValues)
+	values[i] = id
+	return columnNames, values, nil
+}
+
+// InsertValues maps an item into the columns and values of an insert into the
+// collection with the options of the session, its ID and its tenant are
+// assigned by AssignID.
+func (c *collection) InsertValues(ctx context.Context, item interface{}) ([]string, []interface{}, error) {
+	now := c.Database().Clock()()
+	options := c.Database().MapOptions()
+	options.AutoNow, options.AutoNowAdd = now, now
+
+	columnNames, columnValues, err := sqlbuilder.Map(item, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.AssignID(ctx, columnNames, columnValues)
+}
+
+// BulkRows maps all items into rows of values that follow the same columns
+// with the options of the session, see the BulkRows function. Each row is
+// given its ID and its tenant by AssignID, which adds the same columns to
+// every row.
+func (c *collection) BulkRows(ctx context.Context, items []interface{}) ([]string, [][]interface{}, error) {
+	columns, rows, err := BulkRows(items, c.Database().Clock()(), c.Database().MapOptions(), c.Database().Codecs())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rowColumns []string
+	for i := range rows {
+		if rowColumns, rows[i], err = c.AssignID(ctx, columns, rows[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(rows) > 0 {
+		columns = rowColumns
+	}
+	return columns, rows, nil
+}
+
+// isEmptyKey reports whether v, the value of a key on insert, is nil, DEFAULT
+// or a zero value.
+func isEmptyKey(v interface{}) bool {
+	switch v.(type) {
+	case nil, *exql.Raw, exql.Raw:
+		return true
+	}
+	return reflect.DeepEqual(v, reflect.Zero(reflect.TypeOf(v)).Interface())
+}
+
+func (c *collection) filterConds(conds ...interface{}) ([]interface{}, error) {
+	if len(conds) == 1 {
+		if id, ok := conds[0].(db.ID); ok {
+			cond, err := c.keyCond(id)
+			if err != nil {
+				return nil, err
+			}
+			conds[0] = cond
+			return conds, nil
+		}
+	}
 	if tr, ok := c.PartialCollection.(condsFilter); ok {
-		return tr.FilterConds(conds...)
+		return tr.FilterConds(conds...), nil
 	}
 	if len(conds) == 1 && len(c.pk) == 1 {
 		if id := conds[0]; IsKeyValue(id) {
-			conds[0] = db.Cond{c.pk[0]: db.Eq(id)}
+			conds[0] = db.Cond{c.pk[0]: db.Eq(keyValue(id))}
 		}
 	}
-	return conds
+	return conds, nil
+}
+
+// keyCond maps the given ID into a condition that matches each one of the
+// primary keys of the collection. The ID can be a db.ID, a db.Cond as
+// returned by Insert() for composite keys or a single value when the
+// collection has only one primary key.
+func (c *collection) keyCond(id interface{}) (db.Cond, error) {
+	var values map[string]interface{}
+
+	switch t := id.(type) {
+	case db.ID:
+		values = t
+	case db.Cond:
+		values = make(map[string]interface{}, len(t))
+		for k, v := range t {
+			values[fmt.Sprintf("%v", k)] = v
+		}
+	default:
+		if len(c.pk) != 1 {
+			return nil, fmt.Errorf("%w: table %q, got %T", errExpectingCompositeID, c.Name(), id)
+		}
+		return db.Cond{c.pk[0]: db.Eq(keyValue(id))}, nil
+	}
+
+	cond := db.Cond{}
+	for _, pk := range c.pk {
+		v, ok := values[pk]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", errMissingPrimaryKeyValue, pk)
+		}
+		cond[pk] = db.Eq(keyValue(v))
+	}
+	if len(values) != len(cond) {
+		for k := range values {
+			if _, ok := cond[k]; !ok {
+				return nil, fmt.Errorf("%w: %q", errUnknownPrimaryKey, k)
+			}
+		}
+	}
+	return cond, nil
 }
 
 // Find creates a result set with the given conditions.
@@ -104,11 +340,66 @@ func (c *collection) Find(conds ...interface{}) db.Result {
 		res.setErr(c.err)
 		return res
 	}
-	return NewResult(
+	if len(conds) == 1 && IsExample(conds[0]) {
+		cond, err := sqlbuilder.Example(conds[0], nil)
+		if err != nil {
+			res := &Result{}
+			res.setErr(err)
+			return res
+		}
+		conds[0] = cond
+	}
+	var key interface{}
+	if len(conds) == 1 {
+		if _, ok := conds[0].(db.ID); ok || (len(c.pk) == 1 && IsKeyValue(conds[0])) {
+			key = conds[0]
+		}
+	}
+	conds, err := c.filterConds(conds...)
+	if err != nil {
+		res := &Result{}
+		res.setErr(err)
+		return res
+	}
+	var res db.Result = NewResult(
 		c.Database(),
 		c.Name(),
-		c.filterConds(conds...),
+		conds,
 	)
+	if key != nil {
+		res = res.(*Result).byKey(key)
+	}
+	if column := c.Database().SoftDeletes().Column(c.Name()); column != "" {
+		res = res.SoftDelete(column)
+	}
+	if scopes := c.Database().Scopes().Defaults(c.Name()); len(scopes) > 0 {
+		for _, apply := range scopes {
+			res = apply(res)
+		}
+		if scoped, ok := res.(*Result); ok {
+			res = scoped.scoped(1)
+		}
+	}
+	return res
+}
+
+// FindByIDs defines a new result set with the items that have any of the
+// given primary key values, it's fetched in chunks of chunkSize ids.
+func (c *collection) FindByIDs(ids interface{}, chunkSize int) db.Result {
+	if c.err != nil {
+		return c.Find()
+	}
+	if len(c.pk) != 1 {
+		res := &Result{}
+		if len(c.pk) == 0 {
+			res.setErr(fmt.Errorf("%w: table %q", errMissingPrimaryKeys, c.Name()))
+		} else {
+			// Composite keys can't be compared with IN.
+			res.setErr(db.ErrUnsupported)
+		}
+		return res
+	}
+	return c.Find(db.Cond{c.pk[0]: db.InChunks(ids, chunkSize)})
 }
 
 // Exists returns true if the collection exists.
@@ -119,8 +410,19 @@ func (c *collection) Exists() bool {
 	return true
 }
 
+// Insert inserts an item into the collection.
+func (c *collection) Insert(item interface{}) (interface{}, error) {
+	return c.InsertContext(c.Database().Context(), item)
+}
+
 // InsertReturning inserts an item and updates the given variable reference.
 func (c *collection) InsertReturning(item interface{}) error {
+	return c.InsertReturningContext(c.Database().Context(), item)
+}
+
+// InsertReturningContext is like InsertReturning but the queries run within
+// the given context.
+func (c *collection) InsertReturningContext(ctx context.Context, item interface{}) error {
 	if item == nil || reflect.TypeOf(item).Kind() != reflect.Ptr {
 		return fmt.Errorf("Expecting a pointer but got %T", item)
 	}
@@ -131,7 +433,7 @@ func (c *collection) InsertReturning(item interface{}) error {
 		if !c.Exists() {
 			return db.ErrCollectionDoesNotExist
 		}
-		return fmt.Errorf(errMissingPrimaryKeys.Error(), c.Name())
+		return fmt.Errorf("%w: table %q", errMissingPrimaryKeys, c.Name())
 	}
 
 	var tx DatabaseTx
@@ -143,7 +445,7 @@ func (c *collection) InsertReturning(item interface{}) error {
 	} else {
 		// Not within a transaction, let's create one.
 		var err error
-		tx, err = c.Database().NewDatabaseTx(c.Database().Context())
+		tx, err = c.Database().NewDatabaseTx(ctx)
 		if err != nil {
 			return err
 		}
@@ -152,14 +454,23 @@ func (c *collection) InsertReturning(item interface{}) error {
 
 	// Allocate a clone of item.
 	newItem := reflect.New(reflect.ValueOf(item).Elem().Type()).Interface()
-	var newItemFieldMap map[string]reflect.Value
-
-	itemValue := reflect.ValueOf(item)
 
 	col := tx.(Database).Collection(c.Name())
 
+	var cond db.Cond
+	var id interface{}
+	var err error
+
+	if ri, ok := col.(RowInserter); ok {
+		// Insert item and read the inserted row at once.
+		if err = ri.InsertRowContext(ctx, item, newItem); err != nil {
+			goto cancel
+		}
+		goto copyBack
+	}
+
 	// Insert item as is and grab the returning ID.
-	id, err := col.Insert(item)
+	id, err = col.InsertContext(ctx, item)
 	if err != nil {
 		goto cancel
 	}
@@ -169,29 +480,17 @@ func (c *collection) InsertReturning(item interface{}) error {
 	}
 
 	// Fetch the row that was just interted into newItem
-	err = col.Find(id).One(newItem)
+	cond, err = c.keyCond(id)
+	if err != nil {
+		goto cancel
+	}
+	err = col.Find(cond).OneContext(ctx, newItem)
 	if err != nil {
 		goto cancel
 	}
 
-	switch reflect.ValueOf(newItem).Elem().Kind() {
-	case reflect.Struct:
-		// Get valid fields from newItem to overwrite those that are on item.
-		newItemFieldMap = mapper.ValidFieldMap(reflect.ValueOf(newItem))
-		for fieldName := range newItemFieldMap {
-			mapper.FieldByName(itemValue, fieldName).Set(newItemFieldMap[fieldName])
-		}
-	case reflect.Map:
-		newItemV := reflect.ValueOf(newItem).Elem()
-		itemV := reflect.ValueOf(item)
-		if itemV.Kind() == reflect.Ptr {
-			itemV = itemV.Elem()
-		}
-		for _, keyV := range newItemV.MapKeys() {
-			itemV.SetMapIndex(keyV, newItemV.MapIndex(keyV))
-		}
-	default:
-		err = fmt.Errorf("InsertReturning: expecting a pointer to map or struct, got %T", newItem)
+copyBack:
+	if err = copyItem(c.Database().Mapper(), item, newItem); err != nil {
 		goto cancel
 	}
 
@@ -215,7 +514,294 @@ cancel:
 	return err
 }
 
+// InsertReturningAll inserts all the given items and updates each one of them
+// with the actual values from the database.
+func (c *collection) InsertReturningAll(items interface{}) error {
+	return c.InsertReturningAllContext(c.Database().Context(), items)
+}
+
+// InsertReturningAllContext is like InsertReturningAll but the queries run
+// within the given context.
+func (c *collection) InsertReturningAllContext(ctx context.Context, items interface{}) error {
+	itemsV := reflect.ValueOf(items)
+	if items == nil || itemsV.Kind() != reflect.Slice {
+		return fmt.Errorf("Expecting a slice of pointers but got %T", items)
+	}
+
+	list := make([]interface{}, itemsV.Len())
+	for i := range list {
+		item := itemsV.Index(i).Interface()
+		if item == nil || reflect.TypeOf(item).Kind() != reflect.Ptr {
+			return fmt.Errorf("Expecting a slice of pointers but got %T", items)
+		}
+		list[i] = item
+	}
+
+	if len(list) == 0 {
+		return nil
+	}
+
+	// Grab primary keys
+	pks := c.PrimaryKeys()
+	if len(pks) == 0 {
+		if !c.Exists() {
+			return db.ErrCollectionDoesNotExist
+		}
+		return fmt.Errorf("%w: table %q", errMissingPrimaryKeys, c.Name())
+	}
+
+	var tx DatabaseTx
+	inTx := false
+
+	if currTx := c.Database().Transaction(); currTx != nil {
+		tx = NewDatabaseTx(c.Database())
+		inTx = true
+	} else {
+		// Not within a transaction, let's create one.
+		var err error
+		tx, err = c.Database().NewDatabaseTx(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.(Database).Close()
+	}
+
+	err := func() error {
+		col := tx.(Database).Collection(c.Name())
+
+		_, _, scoped, err := c.tenant(ctx)
+		if err != nil {
+			return err
+		}
+
+		var ids []interface{}
+		if bi, ok := col.(BatchInserter); ok && !scoped {
+			// Insert all items at once.
+			var err error
+			if ids, err = bi.InsertBatchContext(ctx, list); err != nil {
+				return err
+			}
+			for i := range list {
+				notifyChange(ctx, tx.(Database), &db.ChangeEvent{
+					Collection: c.Name(),
+					Operation:  db.ChangeInsert,
+					Key:        ids[i],
+					After:      list[i],
+				})
+			}
+		} else {
+			ids = make([]interface{}, len(list))
+			for i := range list {
+				var err error
+				if ids[i], err = col.InsertContext(ctx, list[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		for i := range list {
+			if ids[i] == nil {
+				return fmt.Errorf("InsertReturningAll: Could not get a valid ID after inserting. Does the %q table have a primary key?", c.Name())
+			}
+
+			// Fetch the row that was just inserted and hydrate the item.
+			newItem := reflect.New(reflect.ValueOf(list[i]).Elem().Type()).Interface()
+			cond, err := c.keyCond(ids[i])
+			if err != nil {
+				return err
+			}
+			if err := col.Find(cond).OneContext(ctx, newItem); err != nil {
+				return err
+			}
+			if err := copyItem(c.Database().Mapper(), list[i], newItem); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	if err != nil {
+		if !inTx {
+			tx.Rollback()
+		}
+		return err
+	}
+
+	if !inTx {
+		return tx.Commit()
+	}
+	return nil
+}
+
+// CopyFrom inserts all the given items using the fastest method available.
+func (c *collection) CopyFrom(rows interface{}) error {
+	return c.CopyFromContext(c.Database().Context(), rows)
+}
+
+// CopyFromContext is like CopyFrom but the queries run within the given
+// context.
+func (c *collection) CopyFromContext(ctx context.Context, rows interface{}) error {
+	rowsV := reflect.ValueOf(rows)
+	if rows == nil || rowsV.Kind() != reflect.Slice {
+		return fmt.Errorf("Expecting a slice but got %T", rows)
+	}
+
+	list := make([]interface{}, rowsV.Len())
+	for i := range list {
+		list[i] = rowsV.Index(i).Interface()
+	}
+
+	if len(list) == 0 {
+		return nil
+	}
+
+	var tx DatabaseTx
+	inTx := false
+
+	if currTx := c.Database().Transaction(); currTx != nil {
+		tx = NewDatabaseTx(c.Database())
+		inTx = true
+	} else {
+		// Not within a transaction, let's create one.
+		var err error
+		tx, err = c.Database().NewDatabaseTx(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.(Database).Close()
+	}
+
+	err := func() error {
+		if bl, ok := tx.(Database).Collection(c.Name()).(BulkLoader); ok {
+			return bl.BulkLoadContext(ctx, list)
+		}
+
+		columns, rows, err := c.BulkRows(ctx, list)
+		if err != nil {
+			return err
+		}
+
+		size := len(rows)
+		if len(columns) > 0 && size*len(columns) > copyFromMaxArguments {
+			size = copyFromMaxArguments / len(columns)
+			if size < 1 {
+				size = 1
+			}
+		}
+
+		for start := 0; start < len(rows); start += size {
+			end := start + size
+			if end > len(rows) {
+				end = len(rows)
+			}
+			q := tx.(Database).InsertInto(c.Name()).Columns(columns...)
+			for i := start; i < end; i++ {
+				q = q.Values(rows[i]...)
+			}
+			if _, err := q.ExecContext(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	if err != nil {
+		if !inTx {
+			tx.Rollback()
+		}
+		return err
+	}
+
+	for i := range list {
+		notifyChange(ctx, tx.(Database), &db.ChangeEvent{
+			Collection: c.Name(),
+			Operation:  db.ChangeInsert,
+			After:      list[i],
+		})
+	}
+
+	if !inTx {
+		return tx.Commit()
+	}
+	return nil
+}
+
+// BulkRows maps all items into rows of values that follow the same columns,
+// for statements that can't ask for the default value of a column (like COPY
+// or multi-row inserts on some databases). Columns that are left to their
+// default values on every item, like IDs tagged with omitempty, are dropped.
+// Items are mapped with the Mapper and NullZero of the given options, if any,
+// and values of the types that have a codec are encoded by it.
+func BulkRows(items []interface{}, now time.Time, mapOptions *sqlbuilder.MapOptions, codecs *db.Codecs) ([]string, [][]interface{}, error) {
+	options := &sqlbuilder.MapOptions{
+		IncludeZeroed: true,
+		IncludeNil:    true,
+		AutoNow:       now,
+		AutoNowAdd:    now,
+	}
+	if mapOptions != nil {
+		options.Mapper, options.NullZero, options.NewUUID = mapOptions.Mapper, mapOptions.NullZero, mapOptions.NewUUID
+	}
+
+	var columns []string
+	values := make([][]interface{}, len(items))
+	defaults := make(map[int]int)
+
+	for i := range items {
+		itemColumns, itemValues, err := sqlbuilder.Map(items[i], options)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i == 0 {
+			columns = itemColumns
+		} else if !reflect.DeepEqual(columns, itemColumns) {
+			return nil, nil, fmt.Errorf("Expecting columns %v but item %d has %v", columns, i, itemColumns)
+		}
+		for j := range itemValues {
+			if raw, ok := itemValues[j].(*exql.Raw); ok && raw.Value == "DEFAULT" {
+				defaults[j]++
+			}
+		}
+		values[i] = encodeArgs(codecs, itemValues)
+	}
+
+	keep := make([]int, 0, len(columns))
+	for j := range columns {
+		switch defaults[j] {
+		case 0:
+			keep = append(keep, j)
+		case len(items):
+			// Let the database fill this column in.
+		default:
+			return nil, nil, fmt.Errorf("Column %q has a default value on some items only", columns[j])
+		}
+	}
+
+	keptColumns := make([]string, len(keep))
+	for k, j := range keep {
+		keptColumns[k] = columns[j]
+	}
+
+	rows := make([][]interface{}, len(values))
+	for i := range values {
+		rows[i] = make([]interface{}, len(keep))
+		for k, j := range keep {
+			rows[i][k] = values[i][j]
+		}
+	}
+
+	return keptColumns, rows, nil
+}
+
 func (c *collection) UpdateReturning(item interface{}) error {
+	return c.UpdateReturningContext(c.Database().Context(), item)
+}
+
+// UpdateReturningContext updates an item and refreshes it with the values
+// stored in the database, the queries run within the given context.
+func (c *collection) UpdateReturningContext(ctx context.Context, item interface{}) error {
 	if item == nil || reflect.TypeOf(item).Kind() != reflect.Ptr {
 		return fmt.Errorf("Expecting a pointer but got %T", item)
 	}
@@ -226,7 +812,7 @@ func (c *collection) UpdateReturning(item interface{}) error {
 		if !c.Exists() {
 			return db.ErrCollectionDoesNotExist
 		}
-		return fmt.Errorf(errMissingPrimaryKeys.Error(), c.Name())
+		return fmt.Errorf("%w: table %q", errMissingPrimaryKeys, c.Name())
 	}
 
 	var tx DatabaseTx
@@ -238,7 +824,7 @@ func (c *collection) UpdateReturning(item interface{}) error {
 	} else {
 		// Not within a transaction, let's create one.
 		var err error
-		tx, err = c.Database().NewDatabaseTx(c.Database().Context())
+		tx, err = c.Database().NewDatabaseTx(ctx)
 		if err != nil {
 			return err
 		}
@@ -247,33 +833,31 @@ func (c *collection) UpdateReturning(item interface{}) error {
 
 	// Allocate a clone of item.
 	defaultItem := reflect.New(reflect.ValueOf(item).Elem().Type()).Interface()
-	var defaultItemFieldMap map[string]reflect.Value
 
 	itemValue := reflect.ValueOf(item)
 
+	m := c.Database().Mapper()
+
 	conds := db.Cond{}
 	for _, pk := range pks {
-		conds[pk] = db.Eq(mapper.FieldByName(itemValue, pk).Interface())
+		conds[pk] = db.Eq(m.FieldByName(itemValue, pk).Interface())
 	}
 
 	col := tx.(Database).Collection(c.Name())
 
-	err := col.Find(conds).Update(item)
+	err := col.Find(conds).UpdateContext(ctx, item)
 	if err != nil {
 		goto cancel
 	}
 
-	if err = col.Find(conds).One(defaultItem); err != nil {
+	if err = col.Find(conds).OneContext(ctx, defaultItem); err != nil {
 		goto cancel
 	}
 
 	switch reflect.ValueOf(defaultItem).Elem().Kind() {
 	case reflect.Struct:
 		// Get valid fields from defaultItem to overwrite those that are on item.
-		defaultItemFieldMap = mapper.ValidFieldMap(reflect.ValueOf(defaultItem))
-		for fieldName := range defaultItemFieldMap {
-			mapper.FieldByName(itemValue, fieldName).Set(defaultItemFieldMap[fieldName])
-		}
+		copyFields(m, itemValue, reflect.ValueOf(defaultItem))
 	case reflect.Map:
 		defaultItemV := reflect.ValueOf(defaultItem).Elem()
 		itemV := reflect.ValueOf(item)
@@ -306,14 +890,135 @@ cancel:
 	return err
 }
 
+// copyItem overwrites the map or struct item points to with the values of
+// src, which must be a pointer to the same type.
+func copyItem(m *reflectx.Mapper, item interface{}, src interface{}) error {
+	switch reflect.ValueOf(src).Elem().Kind() {
+	case reflect.Struct:
+		// Get valid fields from src to overwrite those that are on item.
+		copyFields(m, reflect.ValueOf(item), reflect.ValueOf(src))
+	case reflect.Map:
+		srcV := reflect.ValueOf(src).Elem()
+		itemV := reflect.ValueOf(item)
+		if itemV.Kind() == reflect.Ptr {
+			itemV = itemV.Elem()
+		}
+		for _, keyV := range srcV.MapKeys() {
+			itemV.SetMapIndex(keyV, srcV.MapIndex(keyV))
+		}
+	default:
+		return fmt.Errorf("Expecting a pointer to map or struct, got %T", src)
+	}
+	return nil
+}
+
+// Upsert inserts an item or updates the one that has the same primary keys.
+func (c *collection) Upsert(item interface{}) error {
+	return c.UpsertContext(c.Database().Context(), item)
+}
+
+// UpsertContext is like Upsert but the query runs within the given context.
+func (c *collection) UpsertContext(ctx context.Context, item interface{}) error {
+	_, _, scoped, err := c.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	if scoped {
+		// The conflicting row could belong to another tenant.
+		return db.ErrUnsupported
+	}
+
+	pks := c.PrimaryKeys()
+	if len(pks) == 0 {
+		if !c.Exists() {
+			return db.ErrCollectionDoesNotExist
+		}
+		return fmt.Errorf("%w: table %q", errMissingPrimaryKeys, c.Name())
+	}
+
+	q := c.Database().InsertInto(c.Name()).
+		Values(item).
+		OnConflict(pks...).
+		DoUpdate()
+
+	if _, err := q.ExecContext(ctx); err != nil {
+		return err
+	}
+
+	notifyChange(ctx, c.Database(), &db.ChangeEvent{
+		Collection: c.Name(),
+		Operation:  db.ChangeUpsert,
+		After:      item,
+	})
+	return nil
+}
+
 // Truncate deletes all rows from the table.
-func (c *collection) Truncate() error {
-	stmt := exql.Statement{
+func (c *collection) Truncate(opts ...db.TruncateOption) error {
+	return c.TruncateContext(c.Database().Context(), opts...)
+}
+
+// TruncateContext is like Truncate but the queries run within the given
+// context.
+func (c *collection) TruncateContext(ctx context.Context, opts ...db.TruncateOption) error {
+	_, _, scoped, err := c.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	if scoped {
+		// TRUNCATE would delete the rows of every tenant.
+		return db.ErrUnsupported
+	}
+
+	stmt := &exql.Statement{
 		Type:  exql.Truncate,
 		Table: exql.TableWithName(c.Name()),
 	}
-	if _, err := c.Database().Exec(&stmt); err != nil {
-		return err
+	for _, opt := range opts {
+		switch opt {
+		case db.TruncateCascade:
+			stmt.Cascade = true
+		case db.TruncateRestartIdentity:
+			stmt.RestartIdentity = true
+		}
+	}
+
+	stmts := []*exql.Statement{stmt}
+	if stmt.Cascade || stmt.RestartIdentity {
+		truncater, ok := c.Database().(hasTruncateOptions)
+		if !ok {
+			return db.ErrUnsupported
+		}
+		if stmts, err = truncater.TruncateStatements(c.Name(), stmt); err != nil {
+			return err
+		}
+	}
+
+	for i := range stmts {
+		if _, err := c.Database().ExecContext(ctx, stmts[i]); err != nil {
+			return err
+		}
 	}
+
+	notifyChange(ctx, c.Database(), &db.ChangeEvent{
+		Collection: c.Name(),
+		Operation:  db.ChangeDelete,
+	})
 	return nil
 }
+
+// copyFields sets the fields of the struct dst to the valid fields of src, a
+// struct of the same type, both may be pointers. Fields are walked straight
+// from the mapping of the type, rather than through a map of field values.
+func copyFields(m *reflectx.Mapper, dst reflect.Value, src reflect.Value) {
+	dst, src = reflect.Indirect(dst), reflect.Indirect(src)
+	for _, fi := range m.TypeMap(src.Type()).Names {
+		if _, writeOnly := fi.Options["writeonly"]; writeOnly {
+			// These are never scanned, dst keeps its own.
+			continue
+		}
+		if v := reflectx.ValidFieldByIndexes(src, fi.Index); v.IsValid() {
+			reflectx.FieldByIndexes(dst, fi.Index).Set(v)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// +build !go1.9
+
+package compat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Conn is a connection of the pool of a session.
+type Conn interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	Close() error
+}
+
+// SessionConn is not supported before go1.9.
+func SessionConn(ctx context.Context, sess *sql.DB) (Conn, error) {
+	return nil, errors.New("upper: advisory locks require go1.9")
+}
@@ -0,0 +1,20 @@
+// +build go1.9
+
+package compat
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Conn is a connection of the pool of a session.
+type Conn interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	Close() error
+}
+
+// SessionConn takes a connection from the pool of the given session, the
+// connection is returned to the pool when it's closed.
+func SessionConn(ctx context.Context, sess *sql.DB) (Conn, error) {
+	return sess.Conn(ctx)
+}
@@ -0,0 +1,26 @@
+// +build !go1.10
+
+package compat
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+)
+
+// OpenDB opens a database with the given driver. Connection hooks are not
+// supported before go1.10, so the DSN is resolved once and neither onConnect
+// nor retry are ever called.
+func OpenDB(driverName string, dsn func(context.Context) (string, error), onConnect func(context.Context, driver.Conn) error, retry func(context.Context, func() error) error) (*sql.DB, error) {
+	s, err := dsn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return sql.Open(driverName, s)
+}
+
+// ConnExec is not supported before go1.10.
+func ConnExec(ctx context.Context, conn driver.Conn, query string, args []interface{}) (driver.Result, error) {
+	return nil, errors.New("upper: connection hooks require go1.10")
+}
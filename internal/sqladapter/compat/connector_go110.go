@@ -0,0 +1,153 @@
+// +build go1.10
+
+package compat
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+)
+
+// connector opens the connections of a pool, the DSN is resolved for each
+// new connection and onConnect is called after it's opened and before it's
+// added to the pool. Each connection is opened within retry.
+type connector struct {
+	driver    driver.Driver
+	dsn       func(context.Context) (string, error)
+	onConnect func(context.Context, driver.Conn) error
+	retry     func(context.Context, func() error) error
+
+	mu      sync.Mutex
+	lastDSN string
+	last    driver.Connector
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var conn driver.Conn
+	err := c.retry(ctx, func() error {
+		var err error
+		conn, err = c.connect(ctx)
+		return err
+	})
+	return conn, err
+}
+
+func (c *connector) connect(ctx context.Context) (driver.Conn, error) {
+	dsn, err := c.dsn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	base, err := c.connectorFor(dsn)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.onConnect(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// connectorFor returns the connector of the driver for the given DSN, it's
+// kept until the DSN changes.
+func (c *connector) connectorFor(dsn string) (driver.Connector, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.last != nil && c.lastDSN == dsn {
+		return c.last, nil
+	}
+
+	var base driver.Connector = dsnConnector{driver: c.driver, dsn: dsn}
+	if d, ok := c.driver.(driver.DriverContext); ok {
+		var err error
+		if base, err = d.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+
+	c.lastDSN, c.last = dsn, base
+	return base, nil
+}
+
+// dsnConnector is the connector of drivers that don't implement
+// driver.DriverContext.
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// OpenDB opens a database with the given driver, dsn is called to get the
+// DSN of each new connection of the pool and onConnect with the connection.
+// Connections are opened within retry, which may try again when they fail.
+func OpenDB(driverName string, dsn func(context.Context) (string, error), onConnect func(context.Context, driver.Conn) error, retry func(context.Context, func() error) error) (*sql.DB, error) {
+	sess, err := sql.Open(driverName, "")
+	if err != nil {
+		return nil, err
+	}
+	drv := sess.Driver()
+	sess.Close()
+
+	return sql.OpenDB(&connector{driver: drv, dsn: dsn, onConnect: onConnect, retry: retry}), nil
+}
+
+// ConnExec runs a statement that doesn't return rows on a connection that is
+// not part of the pool yet.
+func ConnExec(ctx context.Context, conn driver.Conn, query string, args []interface{}) (driver.Result, error) {
+	values := make([]driver.NamedValue, len(args))
+	for i := range args {
+		v, err := driver.DefaultParameterConverter.ConvertValue(args[i])
+		if err != nil {
+			return nil, err
+		}
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		res, err := execer.ExecContext(ctx, query, values)
+		if err != driver.ErrSkip {
+			return res, err
+		}
+	}
+
+	var (
+		stmt driver.Stmt
+		err  error
+	)
+	if preparer, ok := conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, values)
+	}
+
+	plain := make([]driver.Value, len(values))
+	for i := range values {
+		plain[i] = values[i].Value
+	}
+	return stmt.Exec(plain)
+}
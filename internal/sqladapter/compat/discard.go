@@ -0,0 +1,9 @@
+// +build !go1.13
+
+package compat
+
+// DiscardConn closes a connection taken by SessionConn, connections can't be
+// kept from going back to the pool before go1.13.
+func DiscardConn(conn Conn) error {
+	return conn.Close()
+}
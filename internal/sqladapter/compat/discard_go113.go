@@ -0,0 +1,23 @@
+// +build go1.13
+
+package compat
+
+import (
+	"database/sql"
+	"database/sql/driver"
+)
+
+// DiscardConn closes a connection taken by SessionConn and the physical
+// connection under it, which does not go back to the pool.
+func DiscardConn(conn Conn) error {
+	c, ok := conn.(*sql.Conn)
+	if !ok {
+		return conn.Close()
+	}
+	// The pool closes connections that are reported as bad.
+	c.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	c.Close()
+	return nil
+}
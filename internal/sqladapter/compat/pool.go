@@ -0,0 +1,13 @@
+// +build !go1.15
+
+package compat
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SetConnMaxIdleTime does nothing, sql.DB does not close idle connections
+// based on their idle time before go1.15.
+func SetConnMaxIdleTime(sess *sql.DB, t time.Duration) {
+}
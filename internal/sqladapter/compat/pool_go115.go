@@ -0,0 +1,14 @@
+// +build go1.15
+
+package compat
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SetConnMaxIdleTime sets the maximum amount of time a connection may be
+// idle.
+func SetConnMaxIdleTime(sess *sql.DB, t time.Duration) {
+	sess.SetConnMaxIdleTime(t)
+}
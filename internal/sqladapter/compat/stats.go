@@ -0,0 +1,14 @@
+// +build !go1.11
+
+package compat
+
+import (
+	"database/sql"
+)
+
+// InUse returns the number of connections of the pool that are currently in
+// use. sql.DBStats does not tell idle connections apart before go1.11, so all
+// open connections are counted.
+func InUse(sess *sql.DB) int {
+	return sess.Stats().OpenConnections
+}
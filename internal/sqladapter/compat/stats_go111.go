@@ -0,0 +1,13 @@
+// +build go1.11
+
+package compat
+
+import (
+	"database/sql"
+)
+
+// InUse returns the number of connections of the pool that are currently in
+// use.
+func InUse(sess *sql.DB) int {
+	return sess.Stats().InUse
+}
@@ -0,0 +1,122 @@
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"sync/atomic"
+
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// connectHooks holds the hooks installed with OnConnect and the pools that
+// run them, they're shared by all the clones of a session.
+type connectHooks struct {
+	mu       sync.RWMutex
+	hooks    []func(ctx context.Context, conn sqlbuilder.Conn) error
+	sessions []*sql.DB
+}
+
+// add installs a hook and returns the pools that run it.
+func (h *connectHooks) add(fn func(ctx context.Context, conn sqlbuilder.Conn) error) []*sql.DB {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hooks = append(h.hooks, fn)
+	return h.sessions
+}
+
+func (h *connectHooks) open(driverName string, dsn func(context.Context) (string, error), retry func(context.Context, func() error) error) (*sql.DB, error) {
+	sess, err := compat.OpenDB(driverName, dsn, h.run, retry)
+	if err != nil {
+		return nil, err
+	}
+	h.mu.Lock()
+	h.sessions = append(h.sessions, sess)
+	h.mu.Unlock()
+	return sess, nil
+}
+
+// forget stops tracking a pool that is being closed.
+func (h *connectHooks) forget(sess *sql.DB) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := range h.sessions {
+		if h.sessions[i] == sess {
+			h.sessions = append(h.sessions[:i], h.sessions[i+1:]...)
+			return
+		}
+	}
+}
+
+// run calls the hooks with the given connection, in the order they were
+// installed, until one of them fails.
+func (h *connectHooks) run(ctx context.Context, conn driver.Conn) error {
+	h.mu.RLock()
+	hooks := h.hooks
+	h.mu.RUnlock()
+
+	c := &hookConn{conn: conn}
+	for _, fn := range hooks {
+		if err := fn(ctx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hookConn is the sqlbuilder.Conn hooks receive.
+type hookConn struct {
+	conn driver.Conn
+}
+
+func (c *hookConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return compat.ConnExec(ctx, c.conn, query, args)
+}
+
+func (c *hookConn) Driver() interface{} {
+	return c.conn
+}
+
+var _ = sqlbuilder.Conn(&hookConn{})
+
+// OpenSession opens a *sql.DB with the given driver and DSN, new connections
+// of its pool run the hooks installed with OnConnect and use the credentials
+// of the CredentialsProvider of the session. Connections opened again after
+// the pool lost them are retried according to the ReconnectPolicy of the
+// session.
+func (d *database) OpenSession(driverName, dsn string) (*sql.DB, error) {
+	return d.connectHooks.open(driverName, d.connDSN(dsn), d.reconnect())
+}
+
+// reconnect returns the function the pool of a session opens its connections
+// within. The first connection is retried by WaitForConnection, the ones
+// opened after it succeeded are retried according to the ReconnectPolicy of
+// the session.
+func (d *database) reconnect() func(context.Context, func() error) error {
+	var connected int32
+	return func(ctx context.Context, connect func() error) error {
+		if atomic.LoadInt32(&connected) == 0 {
+			err := connect()
+			if err == nil {
+				atomic.StoreInt32(&connected, 1)
+			}
+			return err
+		}
+		return d.retryConnect(ctx, connect)
+	}
+}
+
+// OnConnect installs a hook that runs on new connections of the session and
+// its clones. Idle connections are closed, so they're opened again with the
+// hook.
+func (d *database) OnConnect(fn func(ctx context.Context, conn sqlbuilder.Conn) error) {
+	for _, sess := range d.connectHooks.add(fn) {
+		sess.SetMaxIdleConns(0)
+		sess.SetMaxIdleConns(d.MaxIdleConns())
+	}
+}
@@ -0,0 +1,58 @@
+package sqladapter
+
+import (
+	"reflect"
+
+	"upper.io/db.v3"
+)
+
+// ConnectionOptions returns the options of the given connection URL, a
+// struct with an Options field or a pointer to one, like the ConnectionURL
+// of each adapter. It returns nil if the connection URL has no options.
+func ConnectionOptions(connURL db.ConnectionURL) map[string]string {
+	v := reflect.ValueOf(connURL)
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	field := v.FieldByName("Options")
+	if !field.IsValid() {
+		return nil
+	}
+	options, _ := field.Interface().(map[string]string)
+	return options
+}
+
+// HostDSNs returns one DSN per host of the given connection URL, a struct
+// with a Host field that may list many comma-separated hosts, or a pointer
+// to one. Other connection URLs have a single DSN.
+func HostDSNs(connURL db.ConnectionURL) []string {
+	v := reflect.ValueOf(connURL)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return []string{connURL.String()}
+	}
+	u := reflect.New(v.Type()).Elem()
+	u.Set(v)
+
+	host := u.FieldByName("Host")
+	if host.Kind() != reflect.String {
+		return []string{connURL.String()}
+	}
+	hosts := SplitHosts(host.String())
+	if len(hosts) < 2 {
+		return []string{connURL.String()}
+	}
+
+	dsns := make([]string, len(hosts))
+	for i := range hosts {
+		host.SetString(hosts[i])
+		dsns[i] = u.Interface().(db.ConnectionURL).String()
+	}
+	return dsns
+}
@@ -0,0 +1,115 @@
+package sqladapter
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"upper.io/db.v3"
+)
+
+// hasURLParser allows the adapter to parse its DSNs, so that their
+// credentials can be replaced.
+type hasURLParser interface {
+	// ParseURL parses the given DSN into a connection URL of the adapter.
+	ParseURL(dsn string) (db.ConnectionURL, error)
+}
+
+// WithCredentials returns a copy of the given connection URL with the given
+// user and password, the user is kept if it's empty. The connection URL must
+// be a struct with User and Password fields, or a pointer to one, like the
+// ConnectionURL of each adapter.
+func WithCredentials(connURL db.ConnectionURL, user string, password string) (db.ConnectionURL, error) {
+	v := reflect.Indirect(reflect.ValueOf(connURL))
+	if v.Kind() != reflect.Struct {
+		return nil, db.ErrUnsupported
+	}
+	u := reflect.New(v.Type()).Elem()
+	u.Set(v)
+
+	userField, passwordField := u.FieldByName("User"), u.FieldByName("Password")
+	if userField.Kind() != reflect.String || passwordField.Kind() != reflect.String {
+		return nil, db.ErrUnsupported
+	}
+	if user != "" {
+		userField.SetString(user)
+	}
+	passwordField.SetString(password)
+	return u.Interface().(db.ConnectionURL), nil
+}
+
+// credentialsCache keeps the credentials given by the CredentialsProvider of
+// a session until they expire, it's shared by all the clones of the session.
+type credentialsCache struct {
+	mu       sync.Mutex
+	provider db.CredentialsProvider
+	creds    db.Credentials
+	expires  time.Time
+}
+
+func (c *credentialsCache) set(provider db.CredentialsProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.provider = provider
+	c.expires = time.Time{}
+}
+
+// get returns the credentials of the provider, false is returned if there's
+// no provider.
+func (c *credentialsCache) get(ctx context.Context) (db.Credentials, bool, error) {
+	if c == nil {
+		return db.Credentials{}, false, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider == nil {
+		return db.Credentials{}, false, nil
+	}
+
+	if time.Now().Before(c.expires) {
+		return c.creds, true, nil
+	}
+
+	creds, err := c.provider.Credentials(ctx)
+	if err != nil {
+		return db.Credentials{}, true, err
+	}
+	c.creds, c.expires = creds, time.Now().Add(creds.TTL)
+	return creds, true, nil
+}
+
+// SetCredentialsProvider defines where the credentials of new connections of
+// the session and its clones come from.
+func (d *database) SetCredentialsProvider(provider db.CredentialsProvider) {
+	d.Settings.SetCredentialsProvider(provider)
+	d.credentials.set(provider)
+}
+
+// connDSN returns the function that gives the DSN of each new connection of a
+// session, with the credentials of the CredentialsProvider if there's one.
+func (d *database) connDSN(dsn string) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		creds, ok, err := d.credentials.get(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return dsn, nil
+		}
+		parser, ok := d.PartialDatabase.(hasURLParser)
+		if !ok {
+			return "", db.ErrUnsupported
+		}
+		connURL, err := parser.ParseURL(dsn)
+		if err != nil {
+			return "", err
+		}
+		if connURL, err = WithCredentials(connURL, creds.User, creds.Secret()); err != nil {
+			return "", err
+		}
+		return connURL.String(), nil
+	}
+}
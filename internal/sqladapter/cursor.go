@@ -0,0 +1,224 @@
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+// hasCursors allows the adapter to read the rows of statements through
+// server-side cursors.
+type hasCursors interface {
+	// DeclareCursorQuery returns the statement that declares a cursor with
+	// the given name for query.
+	DeclareCursorQuery(name string, query string) string
+
+	// FetchCursorQuery returns the statement that reads the next size rows
+	// of the given cursor.
+	FetchCursorQuery(name string, size int) string
+
+	// CloseCursorQuery returns the statement that closes the given cursor.
+	CloseCursorQuery(name string) string
+}
+
+// StatementCursor compiles the given statement and declares a server-side
+// cursor with the given name for it, the rows returned are fetched from the
+// cursor fetchSize at a time as they're read. The cursor is declared within
+// the transaction of the session, or else within one that ends when the rows
+// are closed.
+func (d *database) StatementCursor(ctx context.Context, stmt *exql.Statement, name string, fetchSize int, args ...interface{}) (*sql.Rows, error) {
+	if err := d.checkStatement(ctx, stmt, args, false); err != nil {
+		return nil, err
+	}
+
+	cursors, ok := d.PartialDatabase.(hasCursors)
+	if !ok {
+		return nil, db.ErrUnsupported
+	}
+
+	if d.dryRun(ctx, stmt, args, false) {
+		return nil, db.ErrDryRun
+	}
+
+	query, args := d.compileStatement(stmt, args)
+
+	c := &cursorRows{
+		d:         d,
+		ctx:       ctx,
+		cursors:   cursors,
+		name:      name,
+		fetchSize: fetchSize,
+	}
+
+	if tx := d.Transaction(); tx != nil {
+		c.tx = tx.(*baseTx).Tx
+	} else {
+		d.sessMu.RLock()
+		sess := d.sess
+		d.sessMu.RUnlock()
+		if sess == nil {
+			return nil, db.ErrNotConnected
+		}
+		tx, err := compat.BeginTx(sess, ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		c.tx, c.ownTx = tx, true
+	}
+
+	if _, err := c.run(stmt, cursors.DeclareCursorQuery(name, query), args); err != nil {
+		return nil, c.end(err)
+	}
+	// The first rows are fetched right away, they tell the columns.
+	if err := c.fetch(); err != nil {
+		c.closed = true
+		return nil, c.end(err)
+	}
+
+	rows, err := driverRows(ctx, c)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return rows, nil
+}
+
+// cursorRows are the driver rows of a server-side cursor, they're read in
+// chunks of fetchSize rows.
+type cursorRows struct {
+	d       *database
+	ctx     context.Context
+	cursors hasCursors
+
+	tx    *sql.Tx
+	ownTx bool
+
+	name      string
+	fetchSize int
+
+	chunk  *cachedRows
+	pos    int
+	last   bool
+	closed bool
+}
+
+func (c *cursorRows) Columns() []string {
+	return c.chunk.Columns
+}
+
+func (c *cursorRows) Next(dest []driver.Value) error {
+	if c.pos >= len(c.chunk.Values) {
+		if c.last {
+			return io.EOF
+		}
+		if err := c.fetch(); err != nil {
+			return err
+		}
+		if len(c.chunk.Values) == 0 {
+			return io.EOF
+		}
+	}
+	for i, value := range c.chunk.Values[c.pos] {
+		dest[i] = value
+	}
+	c.pos++
+	return nil
+}
+
+func (c *cursorRows) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	query := c.cursors.CloseCursorQuery(c.name)
+	_, err := c.run(exql.RawSQL(query), query, nil)
+	return c.end(err)
+}
+
+// fetch reads the next chunk of rows of the cursor.
+func (c *cursorRows) fetch() error {
+	query := c.cursors.FetchCursorQuery(c.name, c.fetchSize)
+	chunk, err := c.run(exql.RawSQL(query), query, nil)
+	if err != nil {
+		return err
+	}
+	c.chunk, c.pos = chunk, 0
+	c.last = len(chunk.Values) < c.fetchSize
+	return nil
+}
+
+// end ends the transaction the cursor was declared within if the cursor
+// began it, it's rolled back if err is not nil.
+func (c *cursorRows) end(err error) error {
+	if !c.ownTx {
+		return err
+	}
+	if err != nil {
+		c.tx.Rollback()
+		return err
+	}
+	return c.tx.Commit()
+}
+
+// run runs one of the statements of the cursor and reads its rows, the
+// statement goes through the circuit breaker, the middleware and the metrics
+// of the session like the ones of StatementQuery do. stmt is the statement
+// the query was compiled from.
+func (c *cursorRows) run(stmt *exql.Statement, query string, args []interface{}) (rows *cachedRows, err error) {
+	d := c.d
+
+	// Statements fail fast while the circuit breaker is open.
+	if err = d.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	defer func(start time.Time) {
+		err = d.queryError(query, start, err)
+	}(time.Now())
+
+	deadline := d.queryDeadline(c.ctx)
+	defer deadline.release()
+	defer func() {
+		err = deadline.err(err)
+	}()
+	ctx := deadline.ctx
+
+	defer func() {
+		d.breaker.record(err, d.isRetryableConnError, d.PingContext)
+	}()
+
+	defer func(start time.Time) {
+		d.metrics.observe(statementTypeName(stmt), start, err)
+	}(time.Now())
+
+	if d.logging() {
+		defer func(start time.Time) {
+			d.logStatement(nil, &db.QueryStatus{
+				TxID:    d.txID,
+				SessID:  d.sessID,
+				Query:   query,
+				Args:    args,
+				Err:     err,
+				Start:   start,
+				End:     time.Now(),
+				Context: ctx,
+			})
+		}(time.Now())
+	}
+
+	err = d.runStatement(ctx, stmt, &query, &args, func(ctx context.Context) error {
+		live, err := compat.QueryContext(c.tx, ctx, query, args)
+		if err != nil {
+			return err
+		}
+		rows, err = readRows(live)
+		return err
+	})
+	return
+}
@@ -3,7 +3,10 @@ package sqladapter
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"math"
+	"net"
+	"reflect"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -13,6 +16,7 @@ import (
 	"upper.io/db.v3/internal/cache"
 	"upper.io/db.v3/internal/sqladapter/compat"
 	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/reflectx"
 	"upper.io/db.v3/lib/sqlbuilder"
 )
 
@@ -32,10 +36,27 @@ type hasStatementExec interface {
 	StatementExec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
+// hasAdapter allows the adapter to report its name in query errors.
+type hasAdapter interface {
+	Adapter() string
+}
+
+// hasReplicaLag allows the adapter to measure the replication lag of a
+// replica.
+type hasReplicaLag interface {
+	ReplicaLag(ctx context.Context, sess *sql.DB) (time.Duration, error)
+}
+
 type hasConvertValues interface {
 	ConvertValues(values []interface{}) []interface{}
 }
 
+// hasOutParamsRow allows the adapter to read the OUT parameters of stored
+// procedures from the row they return, rather than as sql.Out arguments.
+type hasOutParamsRow interface {
+	OutParamsRow() bool
+}
+
 // Database represents a SQL database.
 type Database interface {
 	PartialDatabase
@@ -72,7 +93,8 @@ type PartialDatabase interface {
 	ConnectionURL() db.ConnectionURL
 
 	// Err wraps specific database errors (given in string form) and transforms them
-	// into error values.
+	// into error values, it's applied to the errors returned by StatementExec
+	// and StatementQuery.
 	Err(in error) (out error)
 
 	// NewDatabaseTx begins a transaction block and returns a new
@@ -94,6 +116,18 @@ type BaseDatabase interface {
 	// Ping checks if the database server is reachable.
 	Ping() error
 
+	// PingContext is like Ping but it gives up when ctx is done.
+	PingContext(ctx context.Context) error
+
+	// HealthCheck checks the primary server and the replicas of the session.
+	HealthCheck(ctx context.Context) sqlbuilder.HealthReport
+
+	// SetCircuitBreaker configures the circuit breaker of the session.
+	SetCircuitBreaker(sqlbuilder.CircuitBreaker)
+
+	// CircuitOpen returns true if the circuit breaker of the session is open.
+	CircuitOpen() bool
+
 	// ClearCache clears all caches the session is using
 	ClearCache()
 
@@ -116,6 +150,23 @@ type BaseDatabase interface {
 	// BindTx binds a transaction to the current session.
 	BindTx(context.Context, *sql.Tx) error
 
+	// BindReplicas sets the read-only replicas the session sends SELECT
+	// statements to, using the given policy to pick one of them.
+	BindReplicas(sqlbuilder.ReplicaPolicy, ...*sql.DB) error
+
+	// UsePrimary makes the session send every statement to the primary
+	// server, even if it has replicas.
+	UsePrimary()
+
+	// UseReadOnly makes the session and its clones refuse the statements that
+	// may change the database with db.ErrReadOnly.
+	UseReadOnly()
+
+	// BindSavepoint creates a savepoint within the given transaction and binds
+	// it to the current session, this is how nested transactions are
+	// implemented.
+	BindSavepoint(context.Context, BaseTx) error
+
 	// Returns the current transaction the session is using.
 	Transaction() BaseTx
 
@@ -134,16 +185,106 @@ type BaseDatabase interface {
 
 	// SetTxOptions sets default TxOptions for the session.
 	SetTxOptions(txOptions sql.TxOptions)
+
+	// Metrics returns a snapshot of the query and connection pool statistics
+	// of the session.
+	Metrics() sqlbuilder.Metrics
+
+	// Stats returns the statistics of the connection pool of the session.
+	Stats() sql.DBStats
+
+	// Schema describes the tables of the database.
+	Schema() (*sqlbuilder.Schema, error)
+
+	// NextSequenceValue advances the given sequence and returns its new
+	// value.
+	NextSequenceValue(name string) (int64, error)
+
+	// AdvisoryLock waits until the lock of the given key is free and takes
+	// it.
+	AdvisoryLock(ctx context.Context, key string) error
+
+	// TryAdvisoryLock takes the lock of the given key if it's free.
+	TryAdvisoryLock(ctx context.Context, key string) (bool, error)
+
+	// Unlock releases the lock of the given key.
+	Unlock(ctx context.Context, key string) error
+
+	// RunInTx runs fn with a session whose statements run within a
+	// transaction, on the same connection.
+	RunInTx(ctx context.Context, fn func(sess interface{}) error) error
+
+	// CommitPrepared commits the prepared transaction with the given global
+	// id.
+	CommitPrepared(gid string) error
+
+	// RollbackPrepared rolls back the prepared transaction with the given
+	// global id.
+	RollbackPrepared(gid string) error
+
+	// PreparedTransactions returns the global ids of the prepared
+	// transactions that were not finished yet.
+	PreparedTransactions() ([]string, error)
+
+	// Mapper returns the mapper of the fields of structs to columns the
+	// session uses.
+	Mapper() *reflectx.Mapper
+
+	// MapOptions returns the options the session maps structs to columns
+	// with.
+	MapOptions() *sqlbuilder.MapOptions
+
+	// NewUUID returns the value of a zero field of type t tagged with "uuid"
+	// on insert, or nil if it's left to the default value of the column.
+	NewUUID(t reflect.Type) (interface{}, error)
+
+	// TableScope returns the tenant column of the given table and the tenant
+	// of ctx, or of the session context if ctx carries none, see db.Tenancy.
+	TableScope(ctx context.Context, table string) (column string, tenant interface{}, scoped bool, err error)
+
+	// Use installs statement middleware on the session.
+	Use(middleware func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler)
+
+	// SetStatementPolicy sets the policy that decides whether statements may
+	// run.
+	SetStatementPolicy(sqlbuilder.StatementPolicy)
+
+	// OnConnect installs a hook that runs on new connections of the sessions
+	// opened with OpenSession.
+	OnConnect(hook func(ctx context.Context, conn sqlbuilder.Conn) error)
+
+	// OpenSession opens a *sql.DB with the given driver and DSN, new
+	// connections of its pool run the hooks installed with OnConnect.
+	OpenSession(driverName, dsn string) (*sql.DB, error)
+
+	// OpenFailover opens a session with the first of the given DSNs, one per
+	// host, that satisfies the TargetSessionAttrsOption of options.
+	OpenFailover(driverName string, dsns []string, options map[string]string) (*sql.DB, error)
 }
 
 // NewBaseDatabase provides a BaseDatabase given a PartialDatabase
 func NewBaseDatabase(p PartialDatabase) BaseDatabase {
+	settings := db.NewSettings()
 	d := &database{
-		Settings:          db.NewSettings(),
+		Settings:          settings,
 		PartialDatabase:   p,
 		cachedCollections: cache.NewCache(),
 		cachedStatements:  cache.NewCache(),
+		metrics:           newMetrics(),
+		middleware:        &middleware{},
+		policy:            &statementPolicy{},
+		connectHooks:      &connectHooks{},
+		breaker:           newCircuitBreaker(),
+		credentials:       &credentialsCache{provider: settings.CredentialsProvider()},
+		reads:             &readGroup{},
+		locks:             &advisoryLocks{},
+		fieldMappers:      &fieldMappers{},
 	}
+	// d.metrics is read on each eviction, clones replace it with the metrics
+	// of their parent session.
+	d.cachedStatements.OnEvict(func() {
+		d.metrics.cacheEviction()
+	})
 	return d
 }
 
@@ -163,7 +304,14 @@ type database struct {
 
 	name   string
 	sess   *sql.DB
-	sessMu sync.Mutex
+	sessMu sync.RWMutex
+
+	// connectMu is held by the connection attempts of WaitForConnection.
+	connectMu sync.Mutex
+
+	replicas   *replicaSet
+	usePrimary bool
+	readOnly   bool
 
 	psMu sync.Mutex
 
@@ -173,6 +321,17 @@ type database struct {
 	cachedStatements  *cache.Cache
 	cachedCollections *cache.Cache
 
+	metrics *metrics
+
+	middleware   *middleware
+	policy       *statementPolicy
+	connectHooks *connectHooks
+	breaker      *circuitBreaker
+	credentials  *credentialsCache
+	reads        *readGroup
+	locks        *advisoryLocks
+	fieldMappers *fieldMappers
+
 	template *exql.Template
 }
 
@@ -214,6 +373,15 @@ func (d *database) TxOptions() *sql.TxOptions {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	if d.readOnly {
+		// Transactions of read-only sessions are opened READ ONLY.
+		txOptions := sql.TxOptions{ReadOnly: true}
+		if d.txOptions != nil {
+			txOptions.Isolation = d.txOptions.Isolation
+		}
+		return &txOptions
+	}
+
 	if d.txOptions == nil {
 		return nil
 	}
@@ -225,10 +393,32 @@ func (d *database) BindTx(ctx context.Context, t *sql.Tx) error {
 	d.sessMu.Lock()
 	defer d.sessMu.Unlock()
 
-	d.baseTx = newBaseTx(t)
+	tx := newBaseTx(t).(*baseTx)
+	d.baseTx = tx
 	if err := d.Ping(); err != nil {
 		return err
 	}
+	if err := d.beginGlobal(ctx, tx); err != nil {
+		t.Rollback()
+		return err
+	}
+
+	d.SetContext(ctx)
+	d.txID = newBaseTxID()
+	return nil
+}
+
+// BindSavepoint creates a savepoint within the given transaction and binds it
+// into *database
+func (d *database) BindSavepoint(ctx context.Context, parent BaseTx) error {
+	d.sessMu.Lock()
+	defer d.sessMu.Unlock()
+
+	baseTx, err := newSavepointTx(ctx, parent)
+	if err != nil {
+		return err
+	}
+	d.baseTx = baseTx
 
 	d.SetContext(ctx)
 	d.txID = newBaseTxID()
@@ -241,6 +431,24 @@ func (d *database) Transaction() BaseTx {
 	return d.baseTx
 }
 
+// Metrics returns a snapshot of the query and connection pool statistics of
+// the session.
+func (d *database) Metrics() sqlbuilder.Metrics {
+	m := d.metrics.snapshot()
+	if sess := d.Session(); sess != nil {
+		m.Pool = sess.Stats()
+	}
+	return m
+}
+
+// Stats returns the statistics of the connection pool of the session.
+func (d *database) Stats() sql.DBStats {
+	if sess := d.Session(); sess != nil {
+		return sess.Stats()
+	}
+	return sql.DBStats{}
+}
+
 // Name returns the database named
 func (d *database) Name() string {
 	d.mu.Lock()
@@ -283,6 +491,52 @@ func (d *database) Ping() error {
 	return nil
 }
 
+// PingContext is like Ping but it gives up when ctx is done.
+func (d *database) PingContext(ctx context.Context) error {
+	if sess := d.Session(); sess != nil {
+		return compat.PingContext(sess, ctx)
+	}
+	return nil
+}
+
+// HealthCheck pings the primary server and the replicas of the session, the
+// replication lag of replicas is measured by adapters that support it.
+func (d *database) HealthCheck(ctx context.Context) sqlbuilder.HealthReport {
+	var report sqlbuilder.HealthReport
+
+	if d.Session() == nil {
+		report.PingErr = db.ErrNotConnected
+		return report
+	}
+
+	start := time.Now()
+	report.PingErr = d.PingContext(ctx)
+	report.Latency = time.Since(start)
+
+	replicas := d.replicaSet()
+
+	if replicas == nil {
+		return report
+	}
+
+	report.Replicas = make([]sqlbuilder.ReplicaHealth, len(replicas.sessions))
+	for i, sess := range replicas.sessions {
+		r := &report.Replicas[i]
+
+		start := time.Now()
+		if r.Err = compat.PingContext(sess, ctx); r.Err != nil {
+			continue
+		}
+		r.Latency = time.Since(start)
+
+		if lag, ok := d.PartialDatabase.(hasReplicaLag); ok {
+			r.Lag, r.Err = lag.ReplicaLag(ctx, sess)
+		}
+	}
+
+	return report
+}
+
 // SetConnMaxLifetime sets the maximum amount of time a connection may be
 // reused.
 func (d *database) SetConnMaxLifetime(t time.Duration) {
@@ -290,6 +544,25 @@ func (d *database) SetConnMaxLifetime(t time.Duration) {
 	if sess := d.Session(); sess != nil {
 		sess.SetConnMaxLifetime(d.Settings.ConnMaxLifetime())
 	}
+	if replicas := d.replicaSet(); replicas != nil {
+		for _, sess := range replicas.sessions {
+			sess.SetConnMaxLifetime(d.Settings.ConnMaxLifetime())
+		}
+	}
+}
+
+// SetConnMaxIdleTime sets the maximum amount of time a connection may be
+// idle.
+func (d *database) SetConnMaxIdleTime(t time.Duration) {
+	d.Settings.SetConnMaxIdleTime(t)
+	if sess := d.Session(); sess != nil {
+		compat.SetConnMaxIdleTime(sess, d.Settings.ConnMaxIdleTime())
+	}
+	if replicas := d.replicaSet(); replicas != nil {
+		for _, sess := range replicas.sessions {
+			compat.SetConnMaxIdleTime(sess, d.Settings.ConnMaxIdleTime())
+		}
+	}
 }
 
 // SetMaxIdleConns sets the maximum number of connections in the idle
@@ -299,6 +572,11 @@ func (d *database) SetMaxIdleConns(n int) {
 	if sess := d.Session(); sess != nil {
 		sess.SetMaxIdleConns(d.MaxIdleConns())
 	}
+	if replicas := d.replicaSet(); replicas != nil {
+		for _, sess := range replicas.sessions {
+			sess.SetMaxIdleConns(d.MaxIdleConns())
+		}
+	}
 }
 
 // SetMaxOpenConns sets the maximum number of open connections to the
@@ -308,6 +586,25 @@ func (d *database) SetMaxOpenConns(n int) {
 	if sess := d.Session(); sess != nil {
 		sess.SetMaxOpenConns(d.MaxOpenConns())
 	}
+	if replicas := d.replicaSet(); replicas != nil {
+		for _, sess := range replicas.sessions {
+			sess.SetMaxOpenConns(d.MaxOpenConns())
+		}
+	}
+}
+
+// SetPreparedStatementCacheSize sets the maximum number of prepared statements
+// the session keeps.
+func (d *database) SetPreparedStatementCacheSize(n int) {
+	d.Settings.SetPreparedStatementCacheSize(n)
+	d.cachedStatements.SetCapacity(d.Settings.PreparedStatementCacheSize())
+}
+
+// SetPreparedStatementCacheMaxAge sets how long a prepared statement is
+// reused.
+func (d *database) SetPreparedStatementCacheMaxAge(t time.Duration) {
+	d.Settings.SetPreparedStatementCacheMaxAge(t)
+	d.cachedStatements.SetMaxAge(t)
 }
 
 // ClearCache removes all caches.
@@ -329,6 +626,8 @@ func (d *database) NewClone(p PartialDatabase, checkConn bool) (BaseDatabase, er
 
 	nd.name = d.name
 	nd.sess = d.sess
+	nd.replicas = d.replicaSet()
+	nd.readOnly = d.isReadOnly()
 
 	if checkConn {
 		if err := nd.Ping(); err != nil {
@@ -338,17 +637,73 @@ func (d *database) NewClone(p PartialDatabase, checkConn bool) (BaseDatabase, er
 
 	nd.sessID = newSessionID()
 
+	// Clones report their statistics to the parent session and share its
+	// middleware, statement policy, connection hooks, circuit breaker, the
+	// reads in flight, the advisory locks it holds and its field mappers.
+	nd.metrics = d.metrics
+	nd.middleware = d.middleware
+	nd.policy = d.policy
+	nd.connectHooks = d.connectHooks
+	nd.breaker = d.breaker
+	nd.reads = d.reads
+	nd.locks = d.locks
+	nd.fieldMappers = d.fieldMappers
+
 	// New transaction should inherit parent settings
 	copySettings(d, nd)
 
+	// Clones share the credentials of the parent session too, copying its
+	// settings gave them a cache of their own.
+	nd.credentials = d.credentials
+
 	return nd, nil
 }
 
+// RunInTx runs fn with a session whose statements run within a transaction,
+// so they can depend on the state of the connection, like MySQL's user
+// variables do. The transaction of the session is used if there's one,
+// otherwise a new one is committed if fn returns no error.
+func (d *database) RunInTx(ctx context.Context, fn func(sess interface{}) error) error {
+	if d.Transaction() != nil {
+		return fn(d)
+	}
+
+	d.sessMu.RLock()
+	sess := d.sess
+	d.sessMu.RUnlock()
+	if sess == nil {
+		return db.ErrNotConnected
+	}
+
+	clone, err := d.NewClone(d.PartialDatabase, false)
+	if err != nil {
+		return err
+	}
+	defer clone.Close()
+
+	sqlTx, err := compat.BeginTx(sess, ctx, d.TxOptions())
+	if err != nil {
+		return err
+	}
+	if err := clone.BindTx(ctx, sqlTx); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+
+	tx := clone.Transaction()
+	if err := fn(clone); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 // Close terminates the current database session
 func (d *database) Close() error {
 	defer func() {
 		d.sessMu.Lock()
 		d.sess = nil
+		d.replicas = nil
 		d.baseTx = nil
 		d.sessMu.Unlock()
 	}()
@@ -362,7 +717,17 @@ func (d *database) Close() error {
 
 		tx := d.Transaction()
 		if tx == nil {
-			// Not within a transaction.
+			// Not within a transaction, new connections of the pools won't
+			// run the hooks installed with OnConnect anymore.
+			if replicas := d.replicaSet(); replicas != nil {
+				for _, sess := range replicas.sessions {
+					d.connectHooks.forget(sess)
+				}
+				replicas.close()
+			}
+			d.connectHooks.forget(d.sess)
+			d.breaker.close()
+			d.locks.close()
 			return d.sess.Close()
 		}
 
@@ -396,9 +761,17 @@ func (d *database) Collection(name string) db.Collection {
 func (d *database) StatementPrepare(ctx context.Context, stmt *exql.Statement) (sqlStmt *sql.Stmt, err error) {
 	var query string
 
-	if d.Settings.LoggingEnabled() {
+	if err := d.checkStatement(ctx, stmt, nil, false); err != nil {
+		return nil, err
+	}
+
+	defer func(start time.Time) {
+		d.metrics.observe(metricsPrepare, start, err)
+	}(time.Now())
+
+	if d.logging() {
 		defer func(start time.Time) {
-			d.Logger().Log(&db.QueryStatus{
+			d.logStatement(nil, &db.QueryStatus{
 				TxID:    d.txID,
 				SessID:  d.sessID,
 				Query:   query,
@@ -422,6 +795,15 @@ func (d *database) StatementPrepare(ctx context.Context, stmt *exql.Statement) (
 	return
 }
 
+// OutParamsRow returns true if the OUT parameters of stored procedures are
+// read from the row they return, see sqlbuilder.Call.
+func (d *database) OutParamsRow() bool {
+	if p, ok := d.PartialDatabase.(hasOutParamsRow); ok {
+		return p.OutParamsRow()
+	}
+	return false
+}
+
 // ConvertValues converts native values into driver specific values.
 func (d *database) ConvertValues(values []interface{}) []interface{} {
 	if converter, ok := d.PartialDatabase.(hasConvertValues); ok {
@@ -435,7 +817,47 @@ func (d *database) ConvertValues(values []interface{}) []interface{} {
 func (d *database) StatementExec(ctx context.Context, stmt *exql.Statement, args ...interface{}) (res sql.Result, err error) {
 	var query string
 
-	if d.Settings.LoggingEnabled() {
+	if err := d.checkStatement(ctx, stmt, args, true); err != nil {
+		return nil, err
+	}
+
+	// Statements are only logged in dry-run mode.
+	if d.dryRun(ctx, stmt, args, true) {
+		return dryRunResult{}, nil
+	}
+
+	// Cached results of the table are not read again once it's written.
+	if d.ResultCache() != nil {
+		defer d.invalidateResults(ctx, stmt)
+	}
+
+	// Statements fail fast while the circuit breaker is open.
+	if err = d.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	// Errors are translated and wrapped after the original error has been
+	// logged.
+	defer func(start time.Time) {
+		err = d.queryError(query, start, err)
+	}(time.Now())
+
+	deadline := d.queryDeadline(ctx)
+	defer func() {
+		err = deadline.err(err)
+		deadline.release()
+	}()
+	ctx = deadline.ctx
+
+	defer func() {
+		d.breaker.record(err, d.isRetryableConnError, d.PingContext)
+	}()
+
+	defer func(start time.Time) {
+		d.metrics.observe(statementTypeName(stmt), start, err)
+	}(time.Now())
+
+	if d.logging() {
 		defer func(start time.Time) {
 
 			status := db.QueryStatus{
@@ -459,19 +881,14 @@ func (d *database) StatementExec(ctx context.Context, stmt *exql.Statement, args
 				}
 			}
 
-			d.Logger().Log(&status)
+			d.logStatement(stmt, &status)
 		}(time.Now())
 	}
 
-	if execer, ok := d.PartialDatabase.(hasStatementExec); ok {
-		query, args = d.compileStatement(stmt, args)
-		res, err = execer.StatementExec(ctx, query, args...)
-		return
-	}
-
+	execer, hasExecer := d.PartialDatabase.(hasStatementExec)
 	tx := d.Transaction()
 
-	if d.Settings.PreparedStatementCacheEnabled() && tx == nil {
+	if !hasExecer && tx == nil && d.preparedStatementCacheEnabled(ctx) {
 		var p *Stmt
 		if p, query, args, err = d.prepareStatement(ctx, stmt, args); err != nil {
 			return nil, err
@@ -483,12 +900,17 @@ func (d *database) StatementExec(ctx context.Context, stmt *exql.Statement, args
 	}
 
 	query, args = d.compileStatement(stmt, args)
-	if tx != nil {
-		res, err = compat.ExecContext(tx.(*baseTx), ctx, query, args)
-		return
-	}
-
-	res, err = compat.ExecContext(d.sess, ctx, query, args)
+	err = d.runStatement(ctx, stmt, &query, &args, func(ctx context.Context) error {
+		switch {
+		case hasExecer:
+			res, err = execer.StatementExec(ctx, query, args...)
+		case tx != nil:
+			res, err = compat.ExecContext(tx.(*baseTx), ctx, query, args)
+		default:
+			res, err = compat.ExecContext(d.sess, ctx, query, args)
+		}
+		return err
+	})
 	return
 }
 
@@ -496,9 +918,72 @@ func (d *database) StatementExec(ctx context.Context, stmt *exql.Statement, args
 func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, args ...interface{}) (rows *sql.Rows, err error) {
 	var query string
 
-	if d.Settings.LoggingEnabled() {
+	if err := d.checkStatement(ctx, stmt, args, false); err != nil {
+		return nil, err
+	}
+
+	// Statements that may change the database are only logged in dry-run
+	// mode, there are no rows to return.
+	if d.dryRun(ctx, stmt, args, false) {
+		return nil, db.ErrDryRun
+	}
+
+	// Results are read through the result cache of the session, if any.
+	if table := resultCacheTable(ctx); table != "" && d.ResultCache() != nil && d.Transaction() == nil {
+		return d.cachedQuery(ctx, table, stmt, args)
+	}
+
+	// Identical reads that run at the same time share their rows.
+	if d.CollapseReads() && !readCollapsed(ctx) && d.Transaction() == nil {
+		switch stmt.Type {
+		case exql.Select, exql.SQL:
+			return d.collapsedQuery(ctx, stmt, args)
+		}
+	}
+
+	// Cached results of the table are not read again once it's written.
+	if d.ResultCache() != nil {
+		defer func() {
+			if writes(stmt, query) {
+				d.invalidateResults(ctx, stmt)
+			}
+		}()
+	}
+
+	// Statements fail fast while the circuit breaker is open.
+	if err = d.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	// Errors are translated and wrapped after the original error has been
+	// logged.
+	defer func(start time.Time) {
+		err = d.queryError(query, start, err)
+	}(time.Now())
+
+	// Rows are read with the context of the deadline, it's released once
+	// they're closed.
+	deadline := d.queryDeadline(ctx)
+	defer func() {
+		if err = deadline.err(err); err != nil {
+			deadline.release()
+			return
+		}
+		rows, err = deadline.rows(rows)
+	}()
+	ctx = deadline.ctx
+
+	defer func() {
+		d.breaker.record(err, d.isRetryableConnError, d.PingContext)
+	}()
+
+	defer func(start time.Time) {
+		d.metrics.observe(statementTypeName(stmt), start, err)
+	}(time.Now())
+
+	if d.logging() {
 		defer func(start time.Time) {
-			d.Logger().Log(&db.QueryStatus{
+			d.logStatement(stmt, &db.QueryStatus{
 				TxID:    d.txID,
 				SessID:  d.sessID,
 				Query:   query,
@@ -511,9 +996,12 @@ func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, arg
 		}(time.Now())
 	}
 
+	// Prepared statements are bound to the primary server and are not used on
+	// replicas.
+	replica := d.replica(stmt)
 	tx := d.Transaction()
 
-	if d.Settings.PreparedStatementCacheEnabled() && tx == nil {
+	if replica == nil && tx == nil && d.preparedStatementCacheEnabled(ctx) {
 		var p *Stmt
 		if p, query, args, err = d.prepareStatement(ctx, stmt, args); err != nil {
 			return nil, err
@@ -525,14 +1013,18 @@ func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, arg
 	}
 
 	query, args = d.compileStatement(stmt, args)
-	if tx != nil {
-		rows, err = compat.QueryContext(tx.(*baseTx), ctx, query, args)
-		return
-	}
-
-	rows, err = compat.QueryContext(d.sess, ctx, query, args)
+	err = d.runStatement(ctx, stmt, &query, &args, func(ctx context.Context) error {
+		switch {
+		case replica != nil:
+			rows, err = compat.QueryContext(replica, ctx, query, args)
+		case tx != nil:
+			rows, err = compat.QueryContext(tx.(*baseTx), ctx, query, args)
+		default:
+			rows, err = compat.QueryContext(d.sess, ctx, query, args)
+		}
+		return err
+	})
 	return
-
 }
 
 // StatementQueryRow compiles and executes a statement that returns at most one
@@ -540,9 +1032,48 @@ func (d *database) StatementQuery(ctx context.Context, stmt *exql.Statement, arg
 func (d *database) StatementQueryRow(ctx context.Context, stmt *exql.Statement, args ...interface{}) (row *sql.Row, err error) {
 	var query string
 
-	if d.Settings.LoggingEnabled() {
+	if err := d.checkStatement(ctx, stmt, args, false); err != nil {
+		return nil, err
+	}
+
+	// Statements that may change the database are only logged in dry-run
+	// mode, there are no rows to return.
+	if d.dryRun(ctx, stmt, args, false) {
+		return nil, db.ErrDryRun
+	}
+
+	// Cached results of the table are not read again once it's written.
+	if d.ResultCache() != nil {
+		defer func() {
+			if writes(stmt, query) {
+				d.invalidateResults(ctx, stmt)
+			}
+		}()
+	}
+
+	// Errors of the statement are only known once the row is scanned, so
+	// they're not counted by the circuit breaker.
+	if err = d.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	// The row is scanned with the context of the deadline, it's released
+	// once the row is scanned.
+	deadline := d.queryDeadline(ctx)
+	defer func() {
+		if err = deadline.err(err); err != nil {
+			deadline.release()
+		}
+	}()
+	ctx = deadline.ctx
+
+	defer func(start time.Time) {
+		d.metrics.observe(statementTypeName(stmt), start, err)
+	}(time.Now())
+
+	if d.logging() {
 		defer func(start time.Time) {
-			d.Logger().Log(&db.QueryStatus{
+			d.logStatement(stmt, &db.QueryStatus{
 				TxID:    d.txID,
 				SessID:  d.sessID,
 				Query:   query,
@@ -555,29 +1086,68 @@ func (d *database) StatementQueryRow(ctx context.Context, stmt *exql.Statement,
 		}(time.Now())
 	}
 
+	replica := d.replica(stmt)
 	tx := d.Transaction()
 
-	if d.Settings.PreparedStatementCacheEnabled() && tx == nil {
+	if replica == nil && tx == nil && d.preparedStatementCacheEnabled(ctx) {
 		var p *Stmt
 		if p, query, args, err = d.prepareStatement(ctx, stmt, args); err != nil {
 			return nil, err
 		}
 		defer p.Close()
 
-		row = compat.PreparedQueryRowContext(p, ctx, args)
+		row, err = deadline.preparedQueryRow(ctx, p, args)
 		return
 	}
 
 	query, args = d.compileStatement(stmt, args)
-	if tx != nil {
-		row = compat.QueryRowContext(tx.(*baseTx), ctx, query, args)
-		return
+	err = d.runStatement(ctx, stmt, &query, &args, func(ctx context.Context) error {
+		switch {
+		case replica != nil:
+			row, err = deadline.queryRow(ctx, replica, query, args)
+		case tx != nil:
+			row, err = deadline.queryRow(ctx, tx.(*baseTx), query, args)
+		default:
+			row, err = deadline.queryRow(ctx, d.sess, query, args)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	row = compat.QueryRowContext(d.sess, ctx, query, args)
 	return
 }
 
+// Use installs statement middleware on the session and its clones.
+func (d *database) Use(fn func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler) {
+	d.middleware.use(fn)
+}
+
+// preparedStatementCacheEnabled tells whether statements run with the given
+// context can use the prepared statement cache, which is bypassed while there
+// is middleware installed as it may rewrite statements.
+func (d *database) preparedStatementCacheEnabled(ctx context.Context) bool {
+	return d.Settings.PreparedStatementCacheEnabled() && d.middleware.empty() && !sqlbuilder.PreparedStatementCacheDisabled(ctx)
+}
+
+// runStatement passes the compiled query and its arguments through the
+// middleware chain before calling fn, which runs them. Changes made by
+// middleware are written back into query and args.
+func (d *database) runStatement(ctx context.Context, stmt *exql.Statement, query *string, args *[]interface{}, fn func(context.Context) error) error {
+	if d.middleware.empty() {
+		return fn(ctx)
+	}
+	s := &sqlbuilder.Statement{
+		Type:  statementTypeName(stmt),
+		Query: *query,
+		Args:  *args,
+	}
+	return d.middleware.run(ctx, s, func(ctx context.Context, s *sqlbuilder.Statement) error {
+		*query, *args = s.Query, s.Args
+		return fn(ctx)
+	})
+}
+
 // Driver returns the underlying *sql.DB or *sql.Tx instance.
 func (d *database) Driver() interface{} {
 	if tx := d.Transaction(); tx != nil {
@@ -587,8 +1157,15 @@ func (d *database) Driver() interface{} {
 	return d.sess
 }
 
+// StatementCompile returns the query and arguments that are sent to the
+// database server to run the given statement.
+func (d *database) StatementCompile(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
+	return d.compileStatement(stmt, args)
+}
+
 // compileStatement compiles the given statement into a string.
 func (d *database) compileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
+	args = encodeArgs(d.Codecs(), args)
 	if converter, ok := d.PartialDatabase.(hasConvertValues); ok {
 		args = converter.ConvertValues(args)
 	}
@@ -611,11 +1188,14 @@ func (d *database) prepareStatement(ctx context.Context, stmt *exql.Statement, a
 		// The statement was cached.
 		ps, err := pc.(*Stmt).Open()
 		if err == nil {
+			d.metrics.cacheHit()
 			_, args = d.compileStatement(stmt, args)
 			return ps, ps.query, args, nil
 		}
 	}
 
+	d.metrics.cacheMiss()
+
 	query, args := d.compileStatement(stmt, args)
 	sqlStmt, err := func(query *string) (*sql.Stmt, error) {
 		if tx != nil {
@@ -635,50 +1215,115 @@ func (d *database) prepareStatement(ctx context.Context, stmt *exql.Statement, a
 	return p, p.query, args, nil
 }
 
-var waitForConnMu sync.Mutex
+// queryError translates err with the adapter's Err() and wraps it into a
+// *db.QueryError along with the query that caused it.
+func (d *database) queryError(query string, start time.Time, err error) error {
+	if err == nil {
+		return nil
+	}
+	qErr := &db.QueryError{
+		Query:    query,
+		Duration: time.Since(start),
+		Err:      d.PartialDatabase.Err(err),
+	}
+	if a, ok := d.PartialDatabase.(hasAdapter); ok {
+		qErr.Adapter = a.Adapter()
+	}
+	return qErr
+}
 
 // WaitForConnection tries to execute the given connectFn function, if
-// connectFn returns an error, then WaitForConnection will keep trying until
-// connectFn returns nil. Maximum waiting time is 5s after having acquired the
-// lock.
+// connectFn returns an error that is worth retrying, WaitForConnection keeps
+// trying according to the ReconnectPolicy of the session.
 func (d *database) WaitForConnection(connectFn func() error) error {
-	// This lock ensures first-come, first-served and prevents opening too many
-	// file descriptors.
-	waitForConnMu.Lock()
-	defer waitForConnMu.Unlock()
+	return d.retryConnect(d.Context(), func() error {
+		// Attempts of the session are made one at a time, which prevents
+		// opening too many file descriptors. The lock is not held while
+		// waiting for the next attempt.
+		d.connectMu.Lock()
+		defer d.connectMu.Unlock()
+		return connectFn()
+	})
+}
 
-	// Minimum waiting time.
-	waitTime := time.Millisecond * 10
+// retryConnect runs connect and retries it according to the ReconnectPolicy
+// of the session, until ctx is done.
+func (d *database) retryConnect(ctx context.Context, connect func() error) error {
+	policy := d.ReconnectPolicy()
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = d.isRetryableConnError
+	}
 
-	// Waitig 5 seconds for a successful connection.
-	for timeStart := time.Now(); time.Now().Sub(timeStart) < time.Second*5; {
-		err := connectFn()
+	timeStart := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := connect()
 		if err == nil {
 			return nil // Connected!
 		}
 
-		// Only attempt to reconnect if the error is too many clients.
-		if d.PartialDatabase.Err(err) == db.ErrTooManyClients {
-			// Sleep and try again if, and only if, the server replied with a "too
-			// many clients" error.
-			time.Sleep(waitTime)
-			if waitTime < time.Millisecond*500 {
-				// Wait a bit more next time.
-				waitTime = waitTime * 2
+		// Return errors that are not worth retrying immediately.
+		if !retryable(err) {
+			return err
+		}
+
+		wait := policy.Backoff(attempt)
+		if (policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts) ||
+			(policy.MaxWait > 0 && time.Since(timeStart)+wait > policy.MaxWait) {
+			if d.isTooManyClients(err) {
+				return db.ErrGivingUpTryingToConnect
 			}
-			continue
+			return err
 		}
 
-		// Return any other error immediately.
-		return err
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// isRetryableConnError tells whether a connection error is worth retrying
+// when the ReconnectPolicy of the session doesn't say.
+func (d *database) isRetryableConnError(err error) bool {
+	if err == driver.ErrBadConn {
+		return true
 	}
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	return d.isTooManyClients(err)
+}
 
-	return db.ErrGivingUpTryingToConnect
+func (d *database) isTooManyClients(err error) bool {
+	if d.PartialDatabase == nil {
+		return err == db.ErrTooManyClients
+	}
+	return d.PartialDatabase.Err(err) == db.ErrTooManyClients
 }
 
 // ReplaceWithDollarSign turns a SQL statament with '?' placeholders into
 // dollar placeholders, like $1, $2, ..., $n
 func ReplaceWithDollarSign(in string) string {
+	return replacePlaceholders(in, "$")
+}
+
+// ReplaceWithBindVariables turns a SQL statament with '?' placeholders into
+// numbered bind variables, like :1, :2, ..., :n
+func ReplaceWithBindVariables(in string) string {
+	return replacePlaceholders(in, ":")
+}
+
+// replacePlaceholders replaces each '?' with the given prefix followed by the
+// position of the placeholder, '??' is an escaped '?'.
+func replacePlaceholders(in string, prefix string) string {
 	buf := []byte(in)
 	out := make([]byte, 0, len(buf))
 
@@ -692,7 +1337,7 @@ func ReplaceWithDollarSign(in string) string {
 			if k < t && buf[k] == '?' {
 				i = k
 			} else {
-				out = append(out, []byte("$"+strconv.Itoa(j))...)
+				out = append(out, []byte(prefix+strconv.Itoa(j))...)
 				j++
 			}
 		}
@@ -707,9 +1352,29 @@ func copySettings(from BaseDatabase, into BaseDatabase) {
 	into.SetLogging(from.LoggingEnabled())
 	into.SetLogger(from.Logger())
 	into.SetPreparedStatementCache(from.PreparedStatementCacheEnabled())
+	into.SetPreparedStatementCacheSize(from.PreparedStatementCacheSize())
+	into.SetPreparedStatementCacheMaxAge(from.PreparedStatementCacheMaxAge())
 	into.SetConnMaxLifetime(from.ConnMaxLifetime())
+	into.SetConnMaxIdleTime(from.ConnMaxIdleTime())
 	into.SetMaxIdleConns(from.MaxIdleConns())
 	into.SetMaxOpenConns(from.MaxOpenConns())
+	into.SetReconnectPolicy(from.ReconnectPolicy())
+	into.SetCredentialsProvider(from.CredentialsProvider())
+	into.SetMaxQueryDuration(from.MaxQueryDuration())
+	into.SetSlowQueryThreshold(from.SlowQueryThreshold())
+	into.SetExplainSlowQueries(from.ExplainSlowQueries())
+	into.SetDryRun(from.DryRun())
+	into.SetResultCache(from.ResultCache())
+	into.SetCollapseReads(from.CollapseReads())
+	into.SetFieldMapping(from.FieldMapping())
+	into.SetCodecs(from.Codecs())
+	into.SetIDGenerators(from.IDGenerators())
+	into.SetChangeListener(from.ChangeListener())
+	into.SetTenancy(from.Tenancy())
+	into.SetSoftDeletes(from.SoftDeletes())
+	into.SetScopes(from.Scopes())
+	into.SetZeroValuePolicy(from.ZeroValuePolicy())
+	into.SetClock(from.Clock())
 
 	txOptions := from.TxOptions()
 	if txOptions != nil {
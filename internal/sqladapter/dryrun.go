@@ -0,0 +1,71 @@
+package sqladapter
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+// readOnlyPrefixes are the keywords raw SQL statements that only read start
+// with.
+var readOnlyPrefixes = []string{"SELECT", "WITH", "SHOW", "EXPLAIN", "DESCRIBE", "DESC", "PRAGMA", "VALUES"}
+
+// dryRunResult is the result of the statements that are not run in dry-run
+// mode, they affect no rows.
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) {
+	return 0, nil
+}
+
+func (dryRunResult) RowsAffected() (int64, error) {
+	return 0, nil
+}
+
+// dryRun returns true if the given statement is not run because the session
+// is in dry-run mode, it's compiled and logged instead. Exec statements are
+// never run, other statements only if they may change the database.
+func (d *database) dryRun(ctx context.Context, stmt *exql.Statement, args []interface{}, exec bool) bool {
+	if !d.DryRun() {
+		return false
+	}
+
+	query, args := d.compileStatement(stmt, args)
+	if !exec && !writes(stmt, query) {
+		return false
+	}
+
+	now := time.Now()
+	d.Logger().Log(&db.QueryStatus{
+		TxID:    d.txID,
+		SessID:  d.sessID,
+		Query:   query,
+		Args:    args,
+		Start:   now,
+		End:     now,
+		Context: ctx,
+		DryRun:  true,
+	})
+	return true
+}
+
+// writes returns true if the given statement may change the database. Raw
+// SQL statements are assumed to write unless they start with one of the
+// readOnlyPrefixes.
+func writes(stmt *exql.Statement, query string) bool {
+	switch stmt.Type {
+	case exql.Select, exql.Count:
+		return false
+	case exql.SQL:
+		query = strings.ToUpper(strings.TrimSpace(query))
+		for _, prefix := range readOnlyPrefixes {
+			if strings.HasPrefix(query, prefix) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// hasTableRowsEstimate allows the adapter to estimate the number of rows of
+// a table from the statistics of the database.
+type hasTableRowsEstimate interface {
+	// TableRowsEstimateQuery returns the statement that selects the estimated
+	// number of rows of the given table.
+	TableRowsEstimateQuery(table string) (string, []interface{})
+}
+
+// hasPlanRowsEstimate allows the adapter to estimate the number of rows of a
+// query from its plan.
+type hasPlanRowsEstimate interface {
+	// PlanRows returns the number of rows the given plan, in JSON format,
+	// estimates its query returns.
+	PlanRows(plan string) (uint64, error)
+}
+
+// estimateCount returns the estimated number of items of the set, it's taken
+// from the statistics of the table if the set has no conditions, or from the
+// plan of the query that selects them otherwise.
+func (r *Result) estimateCount(ctx context.Context) (uint64, error) {
+	if err := r.Err(); err != nil {
+		return 0, err
+	}
+
+	res, err := r.fastForward()
+	if err != nil {
+		return 0, r.setErr(err)
+	}
+
+	conditional := false
+	for i := range res.conds {
+		if len(res.conds[i]) > 0 {
+			conditional = true
+		}
+	}
+
+	if sess, ok := r.SQLBuilder().(hasTableScope); ok {
+		// The statistics of a scoped table count the rows of every tenant.
+		_, _, scoped, err := sess.TableScope(ctx, res.table)
+		if err != nil {
+			return 0, r.setErr(err)
+		}
+		conditional = conditional || scoped
+	}
+
+	if e, ok := r.SQLBuilder().(hasTableRowsEstimate); ok && !conditional {
+		query, args := e.TableRowsEstimateQuery(res.table)
+		row, err := r.SQLBuilder().QueryRowContext(ctx, query, args...)
+		if err != nil {
+			return 0, r.setErr(err)
+		}
+
+		var rows sql.NullInt64
+		if err := row.Scan(&rows); err != nil {
+			if err == sql.ErrNoRows {
+				return 0, r.setErr(db.ErrCollectionDoesNotExist)
+			}
+			return 0, r.setErr(err)
+		}
+		if rows.Int64 < 0 {
+			// Tables that were never analyzed have no estimate.
+			return 0, nil
+		}
+		return uint64(rows.Int64), nil
+	}
+
+	e, ok := r.SQLBuilder().(hasPlanRowsEstimate)
+	if !ok {
+		return 0, db.ErrUnsupported
+	}
+
+	sel := r.SQLBuilder().Select(db.Raw("1")).
+		From(res.table)
+
+	for i := range res.conds {
+		sel = sel.And(filter(res.conds[i])...)
+	}
+
+	plan, err := sel.Explain(sqlbuilder.WithExplainJSON(ctx))
+	if err != nil {
+		return 0, r.setErr(err)
+	}
+
+	rows, err := e.PlanRows(plan)
+	if err != nil {
+		return 0, r.setErr(err)
+	}
+	return rows, nil
+}
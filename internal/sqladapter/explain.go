@@ -0,0 +1,131 @@
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// hasExplain allows the adapter to get the plan of statements.
+type hasExplain interface {
+	// ExplainQuery returns the statement that shows the plan of query,
+	// without running it.
+	ExplainQuery(query string) string
+}
+
+// hasExplainJSON allows the adapter to get the plan of statements in JSON
+// format.
+type hasExplainJSON interface {
+	// ExplainJSONQuery is like ExplainQuery but the plan is shown in JSON
+	// format.
+	ExplainJSONQuery(query string) string
+}
+
+// StatementExplain compiles the given statement and returns the plan the
+// database would use to run it, without running it. The plan is taken from
+// the same server and transaction the statement would run on.
+func (d *database) StatementExplain(ctx context.Context, stmt *exql.Statement, args ...interface{}) (plan string, err error) {
+	explainer, ok := d.PartialDatabase.(hasExplain)
+	if !ok {
+		return "", db.ErrUnsupported
+	}
+
+	explainQuery := explainer.ExplainQuery
+	if sqlbuilder.ExplainJSON(ctx) {
+		jsonExplainer, ok := d.PartialDatabase.(hasExplainJSON)
+		if !ok {
+			return "", db.ErrUnsupported
+		}
+		explainQuery = jsonExplainer.ExplainJSONQuery
+	}
+
+	query, args := d.compileStatement(stmt, args)
+	query = explainQuery(query)
+
+	defer func(start time.Time) {
+		err = d.queryError(query, start, err)
+	}(time.Now())
+
+	deadline := d.queryDeadline(ctx)
+	defer deadline.release()
+	defer func() {
+		err = deadline.err(err)
+	}()
+	ctx = deadline.ctx
+
+	if d.logging() {
+		defer func(start time.Time) {
+			d.logStatement(nil, &db.QueryStatus{
+				TxID:    d.txID,
+				SessID:  d.sessID,
+				Query:   query,
+				Args:    args,
+				Err:     err,
+				Start:   start,
+				End:     time.Now(),
+				Context: ctx,
+			})
+		}(time.Now())
+	}
+
+	var rows *sql.Rows
+	switch replica, tx := d.replica(stmt), d.Transaction(); {
+	case replica != nil:
+		rows, err = compat.QueryContext(replica, ctx, query, args)
+	case tx != nil:
+		rows, err = compat.QueryContext(tx.(*baseTx), ctx, query, args)
+	default:
+		rows, err = compat.QueryContext(d.sess, ctx, query, args)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	return formatRows(rows)
+}
+
+// formatRows returns the given rows as lines of tab-separated values, the
+// first line holds the names of the columns if there's more than one.
+func formatRows(rows *sql.Rows) (string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var lines []string
+	if len(columns) > 1 {
+		lines = append(lines, strings.Join(columns, "\t"))
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	fields := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		for i := range values {
+			fields[i] = values[i].String
+			if !values[i].Valid {
+				fields[i] = "NULL"
+			}
+		}
+		lines = append(lines, strings.Join(fields, "\t"))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
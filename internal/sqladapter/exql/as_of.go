@@ -0,0 +1,39 @@
+package exql
+
+import (
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+// AsOf represents an AS OF SYSTEM TIME clause, which makes a SELECT statement
+// read the data as it was at the given time.
+type AsOf struct {
+	// Timestamp is an already escaped expression.
+	Timestamp string
+	hash      hash
+}
+
+var _ = Fragment(&AsOf{})
+
+// Hash returns a unique identifier for the struct.
+func (a *AsOf) Hash() string {
+	return a.hash.Hash(a)
+}
+
+// Compile transforms the AsOf into an equivalent SQL representation.
+func (a *AsOf) Compile(layout *Template) (compiled string, err error) {
+	if layout.AsOfLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(a); ok {
+		return z, nil
+	}
+
+	compiled = strings.TrimSpace(mustParse(layout.AsOfLayout, a.Timestamp))
+
+	layout.Write(a, compiled)
+
+	return
+}
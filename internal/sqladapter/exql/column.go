@@ -24,6 +24,12 @@ func ColumnWithName(name string) *Column {
 	return &Column{Name: name}
 }
 
+// ColumnWithAlias creates and returns a Column that's the given SQL expression,
+// as is, under the given alias.
+func ColumnWithAlias(expr string, alias string) *Column {
+	return &Column{Name: RawValue(expr), Alias: alias}
+}
+
 // Hash returns a unique identifier for the struct.
 func (c *Column) Hash() string {
 	return c.hash.Hash(c)
@@ -35,7 +41,10 @@ func (c *Column) Compile(layout *Template) (compiled string, err error) {
 		return z, nil
 	}
 
-	alias := c.Alias
+	var alias string
+	if c.Alias != "" {
+		alias = mustParse(layout.IdentifierQuote, Raw{Value: c.Alias})
+	}
 
 	switch value := c.Name.(type) {
 	case string:
@@ -74,6 +74,20 @@ func TestColumnRaw(t *testing.T) {
 	}
 }
 
+func TestColumnWithAlias(t *testing.T) {
+	column := ColumnWithAlias("count(1)", "_t")
+
+	s, err := column.Compile(defaultTemplate)
+	if err != nil {
+		t.Fatal()
+	}
+
+	e := `count(1) AS "_t"`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
+
 func BenchmarkColumnWithName(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_ = ColumnWithName("a")
@@ -0,0 +1,67 @@
+package exql
+
+import (
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+// Set operators that combine the results of two queries.
+const (
+	Union     = "UNION"
+	UnionAll  = "UNION ALL"
+	Intersect = "INTERSECT"
+	Except    = "EXCEPT"
+)
+
+type compoundTermT struct {
+	Operator string
+	Query    string
+}
+
+// Compound represents two or more queries combined with set operators like
+// UNION, INTERSECT or EXCEPT.
+type Compound struct {
+	Terms []*CompoundTerm
+	hash  hash
+}
+
+// CompoundTerm represents a query that is part of a Compound. Operator is the
+// set operator that joins the query with the preceding one, it's empty for the
+// first term.
+type CompoundTerm struct {
+	Operator string
+	Query    Fragment
+}
+
+var _ = Fragment(&Compound{})
+
+// Hash returns a unique identifier for the struct.
+func (c *Compound) Hash() string {
+	return c.hash.Hash(c)
+}
+
+// Compile transforms the Compound into an equivalent SQL representation.
+func (c *Compound) Compile(layout *Template) (compiled string, err error) {
+	if layout.CompoundLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(c); ok {
+		return z, nil
+	}
+
+	data := make([]compoundTermT, len(c.Terms))
+	for i := range c.Terms {
+		data[i].Operator = c.Terms[i].Operator
+		if data[i].Query, err = c.Terms[i].Query.Compile(layout); err != nil {
+			return "", err
+		}
+	}
+
+	compiled = strings.TrimSpace(mustParse(layout.CompoundLayout, data))
+
+	layout.Write(c, compiled)
+
+	return
+}
@@ -0,0 +1,337 @@
+package exql
+
+import (
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+// ColumnType represents a portable column type that each template translates
+// into its own dialect by means of Template.ColumnTypes.
+type ColumnType string
+
+// Values for ColumnType.
+const (
+	TypeBoolean    = ColumnType("boolean")
+	TypeInteger    = ColumnType("integer")
+	TypeBigInteger = ColumnType("biginteger")
+	TypeFloat      = ColumnType("float")
+	TypeDecimal    = ColumnType("decimal")
+	TypeString     = ColumnType("string")
+	TypeText       = ColumnType("text")
+	TypeTimestamp  = ColumnType("timestamp")
+	TypeDate       = ColumnType("date")
+	TypeBinary     = ColumnType("binary")
+	TypeJSON       = ColumnType("json")
+)
+
+type columnTypeT struct {
+	Size      int
+	Precision int
+	Scale     int
+}
+
+type columnDefinitionT struct {
+	Name          string
+	Type          string
+	NotNull       bool
+	Default       string
+	PrimaryKey    bool
+	AutoIncrement bool
+	Unique        bool
+	References    string
+}
+
+type constraintT struct {
+	Name       string
+	Columns    string
+	References string
+}
+
+const (
+	primaryKeyLayout = `{{if .Name}}CONSTRAINT {{.Name}} {{end}}PRIMARY KEY ({{.Columns}})`
+	uniqueLayout     = `{{if .Name}}CONSTRAINT {{.Name}} {{end}}UNIQUE ({{.Columns}})`
+	foreignKeyLayout = `{{if .Name}}CONSTRAINT {{.Name}} {{end}}FOREIGN KEY ({{.Columns}}) REFERENCES {{.References}}`
+	referenceLayout  = `{{.Name}} ({{.Columns}})`
+)
+
+// ColumnDefinition represents the definition of a column in a CREATE TABLE or
+// ALTER TABLE statement.
+type ColumnDefinition struct {
+	Name *Column
+	Type ColumnType
+	// Size is the length of string columns.
+	Size int
+	// Precision and Scale are used by decimal columns.
+	Precision int
+	Scale     int
+	NotNull   bool
+	// Default is an already escaped literal that is used as the default value
+	// of the column.
+	Default       Fragment
+	PrimaryKey    bool
+	AutoIncrement bool
+	Unique        bool
+	References    *Reference
+	hash          hash
+}
+
+var _ = Fragment(&ColumnDefinition{})
+
+// Hash returns a unique identifier for the struct.
+func (c *ColumnDefinition) Hash() string {
+	return c.hash.Hash(c)
+}
+
+// Compile transforms the ColumnDefinition into an equivalent SQL
+// representation.
+func (c *ColumnDefinition) Compile(layout *Template) (compiled string, err error) {
+	if layout.ColumnDefinitionLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(c); ok {
+		return z, nil
+	}
+
+	typeLayout, ok := layout.ColumnTypes[c.Type]
+	if !ok {
+		return "", db.ErrUnsupported
+	}
+
+	data := columnDefinitionT{
+		Type:          mustParse(typeLayout, columnTypeT{Size: c.Size, Precision: c.Precision, Scale: c.Scale}),
+		NotNull:       c.NotNull,
+		PrimaryKey:    c.PrimaryKey,
+		AutoIncrement: c.AutoIncrement,
+		Unique:        c.Unique,
+	}
+
+	if data.Name, err = c.Name.Compile(layout); err != nil {
+		return "", err
+	}
+
+	if data.Default, err = layout.doCompile(c.Default); err != nil {
+		return "", err
+	}
+
+	if data.References, err = layout.doCompile(c.References); err != nil {
+		return "", err
+	}
+
+	compiled = strings.Join(strings.Fields(mustParse(layout.ColumnDefinitionLayout, data)), " ")
+
+	layout.Write(c, compiled)
+
+	return
+}
+
+// Reference represents the target of a foreign key.
+type Reference struct {
+	Table   *Column
+	Columns *Columns
+	hash    hash
+}
+
+var _ = Fragment(&Reference{})
+
+// Hash returns a unique identifier for the struct.
+func (r *Reference) Hash() string {
+	return r.hash.Hash(r)
+}
+
+// Compile transforms the Reference into an equivalent SQL representation.
+func (r *Reference) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(r); ok {
+		return z, nil
+	}
+
+	data := constraintT{}
+
+	if data.Name, err = r.Table.Compile(layout); err != nil {
+		return "", err
+	}
+
+	if data.Columns, err = layout.doCompile(r.Columns); err != nil {
+		return "", err
+	}
+
+	compiled = mustParse(referenceLayout, data)
+
+	layout.Write(r, compiled)
+
+	return
+}
+
+// ConstraintType is the kind of a table constraint.
+type ConstraintType uint8
+
+// Values for ConstraintType.
+const (
+	PrimaryKeyConstraint = ConstraintType(iota + 1)
+	UniqueConstraint
+	ForeignKeyConstraint
+)
+
+// Constraint represents a table constraint in a CREATE TABLE statement.
+type Constraint struct {
+	Type ConstraintType
+	// Name is optional.
+	Name    *Column
+	Columns *Columns
+	// References is only used by foreign keys.
+	References *Reference
+	hash       hash
+}
+
+var _ = Fragment(&Constraint{})
+
+// Hash returns a unique identifier for the struct.
+func (c *Constraint) Hash() string {
+	return c.hash.Hash(c)
+}
+
+// Compile transforms the Constraint into an equivalent SQL representation.
+func (c *Constraint) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(c); ok {
+		return z, nil
+	}
+
+	data := constraintT{}
+
+	if data.Name, err = layout.doCompile(c.Name); err != nil {
+		return "", err
+	}
+
+	if data.Columns, err = layout.doCompile(c.Columns); err != nil {
+		return "", err
+	}
+
+	if data.References, err = layout.doCompile(c.References); err != nil {
+		return "", err
+	}
+
+	switch c.Type {
+	case PrimaryKeyConstraint:
+		compiled = mustParse(primaryKeyLayout, data)
+	case UniqueConstraint:
+		compiled = mustParse(uniqueLayout, data)
+	case ForeignKeyConstraint:
+		compiled = mustParse(foreignKeyLayout, data)
+	default:
+		return "", errUnknownTemplateType
+	}
+
+	layout.Write(c, compiled)
+
+	return
+}
+
+// AddColumn represents an ALTER TABLE action that adds a column.
+type AddColumn struct {
+	Column *ColumnDefinition
+	hash   hash
+}
+
+var _ = Fragment(&AddColumn{})
+
+// Hash returns a unique identifier for the struct.
+func (a *AddColumn) Hash() string {
+	return a.hash.Hash(a)
+}
+
+// Compile transforms the AddColumn into an equivalent SQL representation.
+func (a *AddColumn) Compile(layout *Template) (compiled string, err error) {
+	if layout.AddColumnLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(a); ok {
+		return z, nil
+	}
+
+	column, err := a.Column.Compile(layout)
+	if err != nil {
+		return "", err
+	}
+
+	compiled = strings.TrimSpace(mustParse(layout.AddColumnLayout, column))
+
+	layout.Write(a, compiled)
+
+	return
+}
+
+// DropColumn represents an ALTER TABLE action that removes a column.
+type DropColumn struct {
+	Name *Column
+	hash hash
+}
+
+var _ = Fragment(&DropColumn{})
+
+// Hash returns a unique identifier for the struct.
+func (d *DropColumn) Hash() string {
+	return d.hash.Hash(d)
+}
+
+// Compile transforms the DropColumn into an equivalent SQL representation.
+func (d *DropColumn) Compile(layout *Template) (compiled string, err error) {
+	if layout.DropColumnLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(d); ok {
+		return z, nil
+	}
+
+	name, err := d.Name.Compile(layout)
+	if err != nil {
+		return "", err
+	}
+
+	compiled = strings.TrimSpace(mustParse(layout.DropColumnLayout, name))
+
+	layout.Write(d, compiled)
+
+	return
+}
+
+// Definitions represents a list of column definitions, constraints or
+// ALTER TABLE actions.
+type Definitions struct {
+	Definitions []Fragment
+	hash        hash
+}
+
+var _ = Fragment(&Definitions{})
+
+// JoinDefinitions creates and returns a list of definitions.
+func JoinDefinitions(definitions ...Fragment) *Definitions {
+	return &Definitions{Definitions: definitions}
+}
+
+// Hash returns a unique identifier for the struct.
+func (d *Definitions) Hash() string {
+	return d.hash.Hash(d)
+}
+
+// Compile transforms the Definitions into an equivalent SQL representation.
+func (d *Definitions) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(d); ok {
+		return z, nil
+	}
+
+	chunks := make([]string, len(d.Definitions))
+	for i := range d.Definitions {
+		if chunks[i], err = d.Definitions[i].Compile(layout); err != nil {
+			return "", err
+		}
+	}
+
+	compiled = strings.Join(chunks, layout.IdentifierSeparator)
+
+	layout.Write(d, compiled)
+
+	return
+}
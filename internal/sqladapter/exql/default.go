@@ -21,6 +21,14 @@ const (
 	defaultTableAliasLayout    = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	defaultColumnAliasLayout   = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	defaultSortByColumnLayout  = `{{.Column}} {{.Order}}`
+	defaultWindowLayout        = `{{.Function}} OVER ({{.Definition}})`
+	defaultJSONPathLayout      = `{{.Column}}{{range .Segments}}{{if .Last}}->>{{else}}->{{end}}{{if .Index}}{{.Key}}{{else}}'{{.Key}}'{{end}}{{end}}`
+	defaultFullTextLayout      = `to_tsvector({{.Column}}) @@ plainto_tsquery(?)`
+	defaultFullTextRankLayout  = `ts_rank(to_tsvector({{.Column}}), plainto_tsquery(?)) DESC`
+	defaultDistinctOnLayout    = `DISTINCT ON ({{.Columns}})`
+	defaultLockLayout          = `{{if .Update}}FOR UPDATE{{else}}FOR SHARE{{end}}{{if .SkipLocked}} SKIP LOCKED{{end}}{{if .NoWait}} NOWAIT{{end}}`
+	defaultUpdateFromLayout    = `{{if .Tables}}FROM {{.Tables}}{{end}} {{.Joins}}`
+	defaultDeleteUsingLayout   = `{{if .Tables}}USING {{.Tables}}{{end}} {{.Joins}}`
 
 	defaultOrderByLayout = `
     {{if .SortColumns}}
@@ -62,27 +70,45 @@ const (
     {{end}}
   `
 
+	defaultWithLayout = `
+    WITH {{if .Recursive}}RECURSIVE {{end}}{{.CTEs}}
+  `
+
+	defaultCompoundLayout = `
+    {{range .}}{{if .Operator}} {{.Operator}} {{end}}({{.Query}}){{end}}
+  `
+
 	defaultSelectLayout = `
-    SELECT
-      {{if .Distinct}}
-        DISTINCT
-      {{end}}
+    {{if .With}}
+      {{.With}}
+    {{end}}
 
-      {{if .Columns}}
-        {{.Columns}}
-      {{else}}
-        *
-      {{end}}
+    {{if .Compound}}
+      {{.Compound}}
+    {{else}}
+      SELECT
+        {{if .DistinctOn}}
+          {{.DistinctOn}}
+        {{else if .Distinct}}
+          DISTINCT
+        {{end}}
 
-      {{if .Table}}
-        FROM {{.Table}}
-      {{end}}
+        {{if .Columns}}
+          {{.Columns}}
+        {{else}}
+          *
+        {{end}}
 
-      {{.Joins}}
+        {{if .Table}}
+          FROM {{.Table}}
+        {{end}}
 
-      {{.Where}}
+        {{.Joins}}
 
-      {{.GroupBy}}
+        {{.Where}}
+
+        {{.GroupBy}}
+    {{end}}
 
       {{.OrderBy}}
 
@@ -93,10 +119,15 @@ const (
       {{if .Offset}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{if .Lock}}
+        {{.Lock}}
+      {{end}}
   `
 	defaultDeleteLayout = `
     DELETE
       FROM {{.Table}}
+      {{.From}}
       {{.Where}}
     {{if .Limit}}
       LIMIT {{.Limit}}
@@ -104,12 +135,19 @@ const (
     {{if .Offset}}
       OFFSET {{.Offset}}
     {{end}}
+    {{if .Returning}}
+      RETURNING {{.Returning}}
+    {{end}}
   `
 	defaultUpdateLayout = `
     UPDATE
       {{.Table}}
     SET {{.ColumnValues}}
+      {{.From}}
       {{ .Where }}
+    {{if .Returning}}
+      RETURNING {{.Returning}}
+    {{end}}
   `
 
 	defaultCountLayout = `
@@ -130,15 +168,40 @@ const (
 	defaultInsertLayout = `
     INSERT INTO {{.Table}}
       {{if .Columns }}({{.Columns}}){{end}}
-    VALUES
+    {{if .Query}}
+      {{.Query}}
+    {{else}}
+      VALUES
       {{.Values}}
+    {{end}}
+    {{if .OnConflict}}
+      {{.OnConflict}}
+    {{end}}
     {{if .Returning}}
       RETURNING {{.Returning}}
     {{end}}
   `
 
+	defaultOnConflictLayout = `
+    ON CONFLICT
+      {{if .Target}}({{range $i, $c := .Target}}{{if $i}}, {{end}}{{$c}}{{end}}){{end}}
+    {{if .Update}}
+      DO UPDATE SET {{.Update}}
+    {{else}}
+      DO NOTHING
+    {{end}}
+  `
+
+	defaultExcludedColumn = `EXCLUDED.{{.}}`
+
 	defaultTruncateLayout = `
     TRUNCATE TABLE {{.Table}}
+    {{if .RestartIdentity}}
+      RESTART IDENTITY
+    {{end}}
+    {{if .Cascade}}
+      CASCADE
+    {{end}}
   `
 
 	defaultDropDatabaseLayout = `
@@ -149,6 +212,31 @@ const (
     DROP TABLE {{.Table}}
   `
 
+	defaultCreateTableLayout = `
+    CREATE TABLE {{.Table}} ({{.Definitions}})
+  `
+
+	defaultAlterTableLayout = `
+    ALTER TABLE {{.Table}} {{.Definitions}}
+  `
+
+	defaultCreateIndexLayout = `
+    CREATE {{if .Unique}}UNIQUE {{end}}INDEX {{.Index}} ON {{.Table}} ({{.Columns}})
+  `
+
+	defaultAddColumnLayout = `ADD COLUMN {{.}}`
+
+	defaultDropColumnLayout = `DROP COLUMN {{.}}`
+
+	defaultColumnDefinitionLayout = `
+    {{.Name}} {{.Type}}
+    {{if .PrimaryKey}}PRIMARY KEY{{end}}
+    {{if .NotNull}}NOT NULL{{end}}
+    {{if .Unique}}UNIQUE{{end}}
+    {{if .Default}}DEFAULT {{.Default}}{{end}}
+    {{if .References}}REFERENCES {{.References}}{{end}}
+  `
+
 	defaultGroupByColumnLayout = `{{.Column}}`
 
 	defaultGroupByLayout = `
@@ -158,37 +246,71 @@ const (
   `
 )
 
+var defaultColumnTypes = map[ColumnType]string{
+	TypeBoolean:    `BOOLEAN`,
+	TypeInteger:    `INTEGER`,
+	TypeBigInteger: `BIGINT`,
+	TypeFloat:      `DOUBLE PRECISION`,
+	TypeDecimal:    `NUMERIC({{.Precision}}, {{.Scale}})`,
+	TypeString:     `VARCHAR({{.Size}})`,
+	TypeText:       `TEXT`,
+	TypeTimestamp:  `TIMESTAMP`,
+	TypeDate:       `DATE`,
+	TypeBinary:     `BLOB`,
+	TypeJSON:       `TEXT`,
+}
+
 var defaultTemplate = &Template{
-	AndKeyword:          defaultAndKeyword,
-	AscKeyword:          defaultAscKeyword,
-	AssignmentOperator:  defaultAssignmentOperator,
-	ClauseGroup:         defaultClauseGroup,
-	ClauseOperator:      defaultClauseOperator,
-	ColumnAliasLayout:   defaultColumnAliasLayout,
-	ColumnSeparator:     defaultColumnSeparator,
-	ColumnValue:         defaultColumnValue,
-	CountLayout:         defaultCountLayout,
-	DeleteLayout:        defaultDeleteLayout,
-	DescKeyword:         defaultDescKeyword,
-	DropDatabaseLayout:  defaultDropDatabaseLayout,
-	DropTableLayout:     defaultDropTableLayout,
-	GroupByLayout:       defaultGroupByLayout,
-	IdentifierQuote:     defaultIdentifierQuote,
-	IdentifierSeparator: defaultIdentifierSeparator,
-	InsertLayout:        defaultInsertLayout,
-	JoinLayout:          defaultJoinLayout,
-	OnLayout:            defaultOnLayout,
-	OrKeyword:           defaultOrKeyword,
-	OrderByLayout:       defaultOrderByLayout,
-	SelectLayout:        defaultSelectLayout,
-	SortByColumnLayout:  defaultSortByColumnLayout,
-	TableAliasLayout:    defaultTableAliasLayout,
-	TruncateLayout:      defaultTruncateLayout,
-	UpdateLayout:        defaultUpdateLayout,
-	UsingLayout:         defaultUsingLayout,
-	ValueQuote:          defaultValueQuote,
-	ValueSeparator:      defaultValueSeparator,
-	WhereLayout:         defaultWhereLayout,
+	AddColumnLayout:        defaultAddColumnLayout,
+	AlterTableLayout:       defaultAlterTableLayout,
+	AndKeyword:             defaultAndKeyword,
+	AscKeyword:             defaultAscKeyword,
+	AssignmentOperator:     defaultAssignmentOperator,
+	ClauseGroup:            defaultClauseGroup,
+	ClauseOperator:         defaultClauseOperator,
+	ColumnAliasLayout:      defaultColumnAliasLayout,
+	ColumnDefinitionLayout: defaultColumnDefinitionLayout,
+	ColumnSeparator:        defaultColumnSeparator,
+	ColumnValue:            defaultColumnValue,
+	CompoundLayout:         defaultCompoundLayout,
+	CountLayout:            defaultCountLayout,
+	CreateIndexLayout:      defaultCreateIndexLayout,
+	CreateTableLayout:      defaultCreateTableLayout,
+	DeleteLayout:           defaultDeleteLayout,
+	DeleteUsingLayout:      defaultDeleteUsingLayout,
+	DescKeyword:            defaultDescKeyword,
+	DropColumnLayout:       defaultDropColumnLayout,
+	DropDatabaseLayout:     defaultDropDatabaseLayout,
+	DropTableLayout:        defaultDropTableLayout,
+	ExcludedColumn:         defaultExcludedColumn,
+	GroupByLayout:          defaultGroupByLayout,
+	IdentifierQuote:        defaultIdentifierQuote,
+	IdentifierSeparator:    defaultIdentifierSeparator,
+	InsertLayout:           defaultInsertLayout,
+	JoinLayout:             defaultJoinLayout,
+	JSONPathLayout:         defaultJSONPathLayout,
+	FullTextLayout:         defaultFullTextLayout,
+	FullTextRankLayout:     defaultFullTextRankLayout,
+	DistinctOnLayout:       defaultDistinctOnLayout,
+	LockLayout:             defaultLockLayout,
+	OnConflictLayout:       defaultOnConflictLayout,
+	OnLayout:               defaultOnLayout,
+	OrKeyword:              defaultOrKeyword,
+	OrderByLayout:          defaultOrderByLayout,
+	SelectLayout:           defaultSelectLayout,
+	SortByColumnLayout:     defaultSortByColumnLayout,
+	TableAliasLayout:       defaultTableAliasLayout,
+	TruncateLayout:         defaultTruncateLayout,
+	UpdateFromLayout:       defaultUpdateFromLayout,
+	UpdateLayout:           defaultUpdateLayout,
+	UsingLayout:            defaultUsingLayout,
+	ValueQuote:             defaultValueQuote,
+	ValueSeparator:         defaultValueSeparator,
+	WhereLayout:            defaultWhereLayout,
+	WindowLayout:           defaultWindowLayout,
+	WithLayout:             defaultWithLayout,
+
+	ColumnTypes: defaultColumnTypes,
 
 	Cache: cache.NewCache(),
 }
@@ -0,0 +1,47 @@
+package exql
+
+import (
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+type distinctOnT struct {
+	Columns string
+}
+
+// DistinctOn represents a DISTINCT ON clause, which keeps only the first row
+// of each set of rows with the same value of Columns.
+type DistinctOn struct {
+	Columns *Columns
+	hash    hash
+}
+
+var _ = Fragment(&DistinctOn{})
+
+// Hash returns a unique identifier for the struct.
+func (d *DistinctOn) Hash() string {
+	return d.hash.Hash(d)
+}
+
+// Compile transforms the DistinctOn into an equivalent SQL representation.
+func (d *DistinctOn) Compile(layout *Template) (compiled string, err error) {
+	if layout.DistinctOnLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(d); ok {
+		return z, nil
+	}
+
+	var data distinctOnT
+	if data.Columns, err = layout.doCompile(d.Columns); err != nil {
+		return "", err
+	}
+
+	compiled = strings.TrimSpace(mustParse(layout.DistinctOnLayout, data))
+
+	layout.Write(d, compiled)
+
+	return
+}
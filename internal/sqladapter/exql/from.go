@@ -0,0 +1,92 @@
+package exql
+
+import (
+	"upper.io/db.v3"
+)
+
+type fromT struct {
+	Tables string
+	Joins  string
+}
+
+// UpdateFrom represents the tables an UPDATE statement reads from besides the
+// updated one, along with the tables joined to them.
+type UpdateFrom struct {
+	Tables *Columns
+	Joins  *Joins
+	hash   hash
+}
+
+var _ = Fragment(&UpdateFrom{})
+
+// Hash returns a unique identifier for the struct.
+func (u *UpdateFrom) Hash() string {
+	return u.hash.Hash(u)
+}
+
+// Compile transforms the UpdateFrom into an equivalent SQL representation.
+func (u *UpdateFrom) Compile(layout *Template) (compiled string, err error) {
+	if layout.UpdateFromLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(u); ok {
+		return z, nil
+	}
+
+	data, err := compileFrom(layout, u.Tables, u.Joins)
+	if err != nil {
+		return "", err
+	}
+
+	compiled = mustParse(layout.UpdateFromLayout, data)
+
+	layout.Write(u, compiled)
+
+	return
+}
+
+// DeleteUsing represents the tables a DELETE statement reads from besides
+// the one rows are deleted from, along with the tables joined to them.
+type DeleteUsing struct {
+	Tables *Columns
+	Joins  *Joins
+	hash   hash
+}
+
+var _ = Fragment(&DeleteUsing{})
+
+// Hash returns a unique identifier for the struct.
+func (d *DeleteUsing) Hash() string {
+	return d.hash.Hash(d)
+}
+
+// Compile transforms the DeleteUsing into an equivalent SQL representation.
+func (d *DeleteUsing) Compile(layout *Template) (compiled string, err error) {
+	if layout.DeleteUsingLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(d); ok {
+		return z, nil
+	}
+
+	data, err := compileFrom(layout, d.Tables, d.Joins)
+	if err != nil {
+		return "", err
+	}
+
+	compiled = mustParse(layout.DeleteUsingLayout, data)
+
+	layout.Write(d, compiled)
+
+	return
+}
+
+func compileFrom(layout *Template, tables *Columns, joins *Joins) (data fromT, err error) {
+	if data.Tables, err = layout.doCompile(tables); err != nil {
+		return
+	}
+	data.Joins, err = layout.doCompile(joins)
+	return
+}
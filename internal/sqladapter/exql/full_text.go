@@ -0,0 +1,65 @@
+package exql
+
+import (
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+type fullTextT struct {
+	Column string
+}
+
+// FullText represents a full-text search on a column, the text to search for
+// is expected as a placeholder argument.
+type FullText struct {
+	Column *Column
+	Rank   bool
+	hash   hash
+}
+
+var _ = Fragment(&FullText{})
+
+// FullTextWithColumn creates and returns a FullText search on the given
+// column.
+func FullTextWithColumn(column string) *FullText {
+	return &FullText{Column: ColumnWithName(column)}
+}
+
+// FullTextRankWithColumn creates and returns an expression that sorts the
+// rows that best match a FullText search on the given column first.
+func FullTextRankWithColumn(column string) *FullText {
+	return &FullText{Column: ColumnWithName(column), Rank: true}
+}
+
+// Hash returns a unique identifier for the struct.
+func (f *FullText) Hash() string {
+	return f.hash.Hash(f)
+}
+
+// Compile transforms the FullText into an equivalent SQL representation.
+func (f *FullText) Compile(layout *Template) (compiled string, err error) {
+	text := layout.FullTextLayout
+	if f.Rank {
+		text = layout.FullTextRankLayout
+	}
+
+	if text == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(f); ok {
+		return z, nil
+	}
+
+	var data fullTextT
+	if data.Column, err = f.Column.Compile(layout); err != nil {
+		return "", err
+	}
+
+	compiled = strings.TrimSpace(mustParse(text, data))
+
+	layout.Write(f, compiled)
+
+	return
+}
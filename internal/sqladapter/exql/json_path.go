@@ -0,0 +1,102 @@
+package exql
+
+import (
+	"regexp"
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+var (
+	reJSONPathIndex = regexp.MustCompile(`^[0-9]+$`)
+	reJSONPathKey   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+type jsonPathT struct {
+	Column   string
+	Keys     []string
+	Segments []jsonPathSegment
+	Path     string
+}
+
+// jsonPathSegment is a key of a JSON path, escaped to be embedded into a
+// single quoted SQL string, or an array index.
+type jsonPathSegment struct {
+	Key   string
+	Index bool
+	Last  bool
+}
+
+// JSONPath represents the value at the given path of a JSON column.
+type JSONPath struct {
+	Column *Column
+	Keys   []string
+	hash   hash
+}
+
+var _ = Fragment(&JSONPath{})
+
+// JSONPathWithColumn creates and returns a JSONPath that reads the value at
+// keys from the given column, numeric keys are array indexes.
+func JSONPathWithColumn(column string, keys ...string) *JSONPath {
+	return &JSONPath{Column: ColumnWithName(column), Keys: keys}
+}
+
+// Hash returns a unique identifier for the struct.
+func (j *JSONPath) Hash() string {
+	return j.hash.Hash(j)
+}
+
+// Compile transforms the JSONPath into an equivalent SQL representation.
+func (j *JSONPath) Compile(layout *Template) (compiled string, err error) {
+	if len(j.Keys) == 0 {
+		return j.Column.Compile(layout)
+	}
+
+	if layout.JSONPathLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(j); ok {
+		return z, nil
+	}
+
+	data := jsonPathT{
+		Keys:     make([]string, len(j.Keys)),
+		Segments: make([]jsonPathSegment, len(j.Keys)),
+	}
+
+	if data.Column, err = j.Column.Compile(layout); err != nil {
+		return "", err
+	}
+
+	path := "$"
+	for i, key := range j.Keys {
+		data.Keys[i] = escapeString(key)
+		data.Segments[i] = jsonPathSegment{
+			Key:   data.Keys[i],
+			Index: reJSONPathIndex.MatchString(key),
+			Last:  i == len(j.Keys)-1,
+		}
+		switch {
+		case data.Segments[i].Index:
+			path = path + "[" + key + "]"
+		case reJSONPathKey.MatchString(key):
+			path = path + "." + key
+		default:
+			path = path + `."` + strings.Replace(key, `"`, `\"`, -1) + `"`
+		}
+	}
+	data.Path = escapeString(path)
+
+	compiled = strings.TrimSpace(mustParse(layout.JSONPathLayout, data))
+
+	layout.Write(j, compiled)
+
+	return
+}
+
+// escapeString escapes s to be embedded into a single quoted SQL string.
+func escapeString(s string) string {
+	return strings.Replace(s, `'`, `''`, -1)
+}
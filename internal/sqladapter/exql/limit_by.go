@@ -0,0 +1,82 @@
+package exql
+
+import (
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+type limitByT struct {
+	Limit   int
+	Columns string
+}
+
+// LimitBy represents a LIMIT n BY clause, which keeps at most Limit rows for
+// each distinct value of Columns.
+type LimitBy struct {
+	Limit   int
+	Columns *Columns
+	hash    hash
+}
+
+var _ = Fragment(&LimitBy{})
+
+// Hash returns a unique identifier for the struct.
+func (l *LimitBy) Hash() string {
+	return l.hash.Hash(l)
+}
+
+// Compile transforms the LimitBy into an equivalent SQL representation.
+func (l *LimitBy) Compile(layout *Template) (compiled string, err error) {
+	if layout.LimitByLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(l); ok {
+		return z, nil
+	}
+
+	data := limitByT{
+		Limit: l.Limit,
+	}
+
+	if data.Columns, err = layout.doCompile(l.Columns); err != nil {
+		return "", err
+	}
+
+	compiled = strings.TrimSpace(mustParse(layout.LimitByLayout, data))
+
+	layout.Write(l, compiled)
+
+	return
+}
+
+// Final represents the FINAL modifier of a table, which makes a SELECT
+// statement merge the rows that the storage engine has not merged yet.
+type Final struct {
+	hash hash
+}
+
+var _ = Fragment(&Final{})
+
+// Hash returns a unique identifier for the struct.
+func (f *Final) Hash() string {
+	return f.hash.Hash(f)
+}
+
+// Compile transforms the Final into an equivalent SQL representation.
+func (f *Final) Compile(layout *Template) (compiled string, err error) {
+	if layout.FinalLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(f); ok {
+		return z, nil
+	}
+
+	compiled = strings.TrimSpace(layout.FinalLayout)
+
+	layout.Write(f, compiled)
+
+	return
+}
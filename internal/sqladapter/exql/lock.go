@@ -0,0 +1,67 @@
+package exql
+
+import (
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+// LockMode represents the kind of lock that is acquired on the rows that are
+// read by a SELECT statement.
+type LockMode uint8
+
+// Possible values for LockMode.
+const (
+	LockForUpdate = LockMode(iota + 1)
+	LockForShare
+)
+
+type lockT struct {
+	Update     bool
+	Share      bool
+	SkipLocked bool
+	NoWait     bool
+}
+
+// Lock represents a row locking clause, like FOR UPDATE.
+type Lock struct {
+	Mode LockMode
+	// SkipLocked is true when rows that are locked by other transactions must
+	// be skipped instead of waited for.
+	SkipLocked bool
+	// NoWait is true when the statement must fail instead of waiting for rows
+	// that are locked by other transactions.
+	NoWait bool
+	hash   hash
+}
+
+var _ = Fragment(&Lock{})
+
+// Hash returns a unique identifier for the struct.
+func (l *Lock) Hash() string {
+	return l.hash.Hash(l)
+}
+
+// Compile transforms the Lock into an equivalent SQL representation.
+func (l *Lock) Compile(layout *Template) (compiled string, err error) {
+	if layout.LockLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(l); ok {
+		return z, nil
+	}
+
+	data := lockT{
+		Update:     l.Mode == LockForUpdate,
+		Share:      l.Mode == LockForShare,
+		SkipLocked: l.SkipLocked,
+		NoWait:     l.NoWait,
+	}
+
+	compiled = strings.TrimSpace(mustParse(layout.LockLayout, data))
+
+	layout.Write(l, compiled)
+
+	return
+}
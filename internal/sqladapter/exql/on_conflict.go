@@ -0,0 +1,119 @@
+package exql
+
+import (
+	"errors"
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+var errConflictTargetRequired = errors.New("updating conflicting rows requires the columns given to OnConflict() on this database")
+
+// OnConflict represents the clause that turns an INSERT statement into an
+// upsert.
+type OnConflict struct {
+	// Target holds the columns that identify a conflicting row.
+	Target *Columns
+	// Columns holds the columns that are being inserted.
+	Columns *Columns
+	// Update is true when conflicting rows must be updated instead of being
+	// left untouched.
+	Update bool
+	// ColumnValues holds the assignments that are applied to conflicting rows.
+	// If Update is true and ColumnValues is nil, every inserted column that is
+	// not part of Target is overwritten with its new value.
+	ColumnValues *ColumnValues
+	hash         hash
+}
+
+var _ = Fragment(&OnConflict{})
+
+type onConflictT struct {
+	Target  []string
+	Columns []string
+	Update  string
+}
+
+// Hash returns a unique identifier for the struct.
+func (oc *OnConflict) Hash() string {
+	return oc.hash.Hash(oc)
+}
+
+// Compile transforms the OnConflict into an equivalent SQL representation.
+func (oc *OnConflict) Compile(layout *Template) (compiled string, err error) {
+	if layout.OnConflictLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(oc); ok {
+		return z, nil
+	}
+
+	data := onConflictT{}
+
+	if data.Target, err = compileEach(layout, oc.Target); err != nil {
+		return "", err
+	}
+
+	if data.Columns, err = compileEach(layout, oc.Columns); err != nil {
+		return "", err
+	}
+
+	if oc.Update && len(data.Target) == 0 && layout.ConflictTargetNeeded {
+		return "", errConflictTargetRequired
+	}
+
+	if oc.Update {
+		if oc.ColumnValues != nil {
+			data.Update, err = oc.ColumnValues.Compile(layout)
+			if err != nil {
+				return "", err
+			}
+		} else {
+			data.Update = oc.defaultUpdate(layout, data.Target, data.Columns)
+		}
+	}
+
+	compiled = strings.TrimSpace(mustParse(layout.OnConflictLayout, data))
+
+	layout.Write(oc, compiled)
+
+	return
+}
+
+// defaultUpdate assigns the proposed value to every inserted column that is
+// not part of the conflict target.
+func (oc *OnConflict) defaultUpdate(layout *Template, target []string, columns []string) string {
+	out := make([]string, 0, len(columns))
+
+next:
+	for _, column := range columns {
+		for _, t := range target {
+			if column == t {
+				continue next
+			}
+		}
+		data := columnValueT{
+			Column:   column,
+			Operator: layout.AssignmentOperator,
+			Value:    mustParse(layout.ExcludedColumn, column),
+		}
+		out = append(out, strings.TrimSpace(mustParse(layout.ColumnValue, data)))
+	}
+
+	return strings.Join(out, layout.IdentifierSeparator)
+}
+
+func compileEach(layout *Template, columns *Columns) ([]string, error) {
+	if columns == nil {
+		return nil, nil
+	}
+	out := make([]string, len(columns.Columns))
+	for i := range columns.Columns {
+		var err error
+		if out[i], err = columns.Columns[i].Compile(layout); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
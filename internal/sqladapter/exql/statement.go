@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 
+	"upper.io/db.v3"
 	"upper.io/db.v3/internal/cache"
 )
 
@@ -17,13 +18,30 @@ type Statement struct {
 	Database     Fragment
 	Columns      Fragment
 	Values       Fragment
+	Query        Fragment
 	Distinct     bool
+	DistinctOn   Fragment
 	ColumnValues Fragment
 	OrderBy      Fragment
 	GroupBy      Fragment
 	Joins        Fragment
+	From         Fragment
 	Where        Fragment
 	Returning    Fragment
+	OnConflict   Fragment
+	With         Fragment
+	Compound     Fragment
+	Lock         Fragment
+	AsOf         Fragment
+	Final        Fragment
+	LimitBy      Fragment
+	Definitions  Fragment
+	Index        Fragment
+	Unique       bool
+
+	// Cascade and RestartIdentity are options of Truncate statements.
+	Cascade         bool
+	RestartIdentity bool
 
 	Limit
 	Offset
@@ -39,13 +57,29 @@ type statementT struct {
 	Database     string
 	Columns      string
 	Values       string
+	Query        string
 	Distinct     bool
+	DistinctOn   string
 	ColumnValues string
 	OrderBy      string
 	GroupBy      string
 	Where        string
 	Joins        string
+	From         string
 	Returning    string
+	OnConflict   string
+	With         string
+	Compound     string
+	Lock         string
+	AsOf         string
+	Final        string
+	LimitBy      string
+	Definitions  string
+	Index        string
+	Unique       bool
+
+	Cascade         bool
+	RestartIdentity bool
 	Limit
 	Offset
 }
@@ -95,6 +129,10 @@ func (s *Statement) Compile(layout *Template) (compiled string, err error) {
 		Limit:    s.Limit,
 		Offset:   s.Offset,
 		Distinct: s.Distinct,
+		Unique:   s.Unique,
+
+		Cascade:         s.Cascade,
+		RestartIdentity: s.RestartIdentity,
 	}
 
 	data.Table, err = layout.doCompile(s.Table)
@@ -117,6 +155,16 @@ func (s *Statement) Compile(layout *Template) (compiled string, err error) {
 		return "", err
 	}
 
+	data.Query, err = layout.doCompile(s.Query)
+	if err != nil {
+		return "", err
+	}
+
+	data.DistinctOn, err = layout.doCompile(s.DistinctOn)
+	if err != nil {
+		return "", err
+	}
+
 	data.ColumnValues, err = layout.doCompile(s.ColumnValues)
 	if err != nil {
 		return "", err
@@ -147,6 +195,56 @@ func (s *Statement) Compile(layout *Template) (compiled string, err error) {
 		return "", err
 	}
 
+	data.From, err = layout.doCompile(s.From)
+	if err != nil {
+		return "", err
+	}
+
+	data.OnConflict, err = layout.doCompile(s.OnConflict)
+	if err != nil {
+		return "", err
+	}
+
+	data.With, err = layout.doCompile(s.With)
+	if err != nil {
+		return "", err
+	}
+
+	data.Compound, err = layout.doCompile(s.Compound)
+	if err != nil {
+		return "", err
+	}
+
+	data.Lock, err = layout.doCompile(s.Lock)
+	if err != nil {
+		return "", err
+	}
+
+	data.AsOf, err = layout.doCompile(s.AsOf)
+	if err != nil {
+		return "", err
+	}
+
+	data.Final, err = layout.doCompile(s.Final)
+	if err != nil {
+		return "", err
+	}
+
+	data.LimitBy, err = layout.doCompile(s.LimitBy)
+	if err != nil {
+		return "", err
+	}
+
+	data.Definitions, err = layout.doCompile(s.Definitions)
+	if err != nil {
+		return "", err
+	}
+
+	data.Index, err = layout.doCompile(s.Index)
+	if err != nil {
+		return "", err
+	}
+
 	switch s.Type {
 	case Truncate:
 		compiled = mustParse(layout.TruncateLayout, data)
@@ -159,11 +257,37 @@ func (s *Statement) Compile(layout *Template) (compiled string, err error) {
 	case Select:
 		compiled = mustParse(layout.SelectLayout, data)
 	case Delete:
+		if data.Returning != "" && !hasReturning(layout.DeleteLayout) {
+			return "", db.ErrUnsupported
+		}
 		compiled = mustParse(layout.DeleteLayout, data)
 	case Update:
+		if data.Returning != "" && !hasReturning(layout.UpdateLayout) {
+			return "", db.ErrUnsupported
+		}
 		compiled = mustParse(layout.UpdateLayout, data)
 	case Insert:
 		compiled = mustParse(layout.InsertLayout, data)
+	case CreateTable:
+		if layout.CreateTableLayout == "" {
+			return "", db.ErrUnsupported
+		}
+		compiled = mustParse(layout.CreateTableLayout, data)
+	case AlterTable:
+		if layout.AlterTableLayout == "" {
+			return "", db.ErrUnsupported
+		}
+		compiled = mustParse(layout.AlterTableLayout, data)
+	case CreateIndex:
+		if layout.CreateIndexLayout == "" {
+			return "", db.ErrUnsupported
+		}
+		compiled = mustParse(layout.CreateIndexLayout, data)
+	case Call:
+		if layout.CallLayout == "" {
+			return "", db.ErrUnsupported
+		}
+		compiled = mustParse(layout.CallLayout, data)
 	default:
 		return "", errUnknownTemplateType
 	}
@@ -174,6 +298,12 @@ func (s *Statement) Compile(layout *Template) (compiled string, err error) {
 	return s.Amend(compiled), nil
 }
 
+// hasReturning reports whether the given layout is able to render a RETURNING
+// clause.
+func hasReturning(layout string) bool {
+	return strings.Contains(layout, "{{.Returning}}")
+}
+
 // RawSQL represents a raw SQL statement.
 func RawSQL(s string) *Statement {
 	return &Statement{
@@ -4,6 +4,9 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/cache"
 )
 
 var (
@@ -35,6 +38,20 @@ func TestTruncateTable(t *testing.T) {
 	if s != e {
 		t.Fatalf("Got: %s, Expecting: %s", s, e)
 	}
+
+	stmt = Statement{
+		Type:            Truncate,
+		Table:           TableWithName("table_name"),
+		Cascade:         true,
+		RestartIdentity: true,
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `TRUNCATE TABLE "table_name" RESTART IDENTITY CASCADE`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
 }
 
 func TestDropTable(t *testing.T) {
@@ -673,6 +690,424 @@ func TestSelectFieldsFromWhereLimitOffset(t *testing.T) {
 	}
 }
 
+func TestSelectWith(t *testing.T) {
+	var s, e string
+
+	stmt := Statement{
+		Type:  Select,
+		Table: TableWithName("recent"),
+		With: &With{
+			CTEs: []*CTE{
+				{
+					Name:  ColumnWithName("recent"),
+					Query: RawValue(`SELECT * FROM "posts" WHERE "id" > 10`),
+				},
+			},
+		},
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `WITH "recent" AS (SELECT * FROM "posts" WHERE "id" > 10) SELECT * FROM "recent"`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	stmt = Statement{
+		Type:    Select,
+		Table:   TableWithName("t"),
+		Columns: JoinColumns(ColumnWithName("n")),
+		With: &With{
+			Recursive: true,
+			CTEs: []*CTE{
+				{
+					Name:    ColumnWithName("t"),
+					Columns: JoinColumns(ColumnWithName("n")),
+					Query:   RawValue(`SELECT 1 UNION ALL SELECT n+1 FROM t WHERE n < 10`),
+				},
+			},
+		},
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `WITH RECURSIVE "t" ("n") AS (SELECT 1 UNION ALL SELECT n+1 FROM t WHERE n < 10) SELECT "n" FROM "t"`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.WithLayout = ""
+	layout.Cache = cache.NewCache()
+
+	if _, err := stmt.Compile(&layout); err != db.ErrUnsupported {
+		t.Fatalf("Got: %v, Expecting: %v", err, db.ErrUnsupported)
+	}
+}
+
+func TestSelectCompound(t *testing.T) {
+	var s, e string
+
+	stmt := Statement{
+		Type: Select,
+		Compound: &Compound{
+			Terms: []*CompoundTerm{
+				{Query: RawValue(`SELECT "name" FROM "artist"`)},
+				{Operator: Union, Query: RawValue(`SELECT "name" FROM "band"`)},
+				{Operator: Except, Query: RawValue(`SELECT "name" FROM "banned"`)},
+			},
+		},
+		OrderBy: JoinWithOrderBy(
+			JoinSortColumns(
+				&SortColumn{Column: &Column{Name: "name"}},
+			),
+		),
+		Limit: 10,
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `(SELECT "name" FROM "artist") UNION (SELECT "name" FROM "band") EXCEPT (SELECT "name" FROM "banned") ORDER BY "name" LIMIT 10`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.CompoundLayout = ""
+	layout.Cache = cache.NewCache()
+
+	if _, err := stmt.Compile(&layout); err != db.ErrUnsupported {
+		t.Fatalf("Got: %v, Expecting: %v", err, db.ErrUnsupported)
+	}
+}
+
+func TestSelectLock(t *testing.T) {
+	var s, e string
+
+	stmt := Statement{
+		Type:  Select,
+		Table: TableWithName("jobs"),
+		Limit: 1,
+		Lock:  &Lock{Mode: LockForUpdate, SkipLocked: true},
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `SELECT * FROM "jobs" LIMIT 1 FOR UPDATE SKIP LOCKED`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	stmt = Statement{
+		Type:  Select,
+		Table: TableWithName("jobs"),
+		Lock:  &Lock{Mode: LockForShare, NoWait: true},
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `SELECT * FROM "jobs" FOR SHARE NOWAIT`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.LockLayout = ""
+	layout.Cache = cache.NewCache()
+
+	if _, err := stmt.Compile(&layout); err != db.ErrUnsupported {
+		t.Fatalf("Got: %v, Expecting: %v", err, db.ErrUnsupported)
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	var s, e string
+
+	stmt := Statement{
+		Type:  CreateTable,
+		Table: TableWithName("books"),
+		Definitions: JoinDefinitions(
+			&ColumnDefinition{Name: ColumnWithName("id"), Type: TypeInteger, PrimaryKey: true},
+			&ColumnDefinition{Name: ColumnWithName("title"), Type: TypeString, Size: 80, NotNull: true, Default: RawValue("''")},
+			&ColumnDefinition{Name: ColumnWithName("price"), Type: TypeDecimal, Precision: 10, Scale: 2},
+			&Constraint{
+				Type:    ForeignKeyConstraint,
+				Columns: JoinColumns(ColumnWithName("author_id")),
+				References: &Reference{
+					Table:   ColumnWithName("authors"),
+					Columns: JoinColumns(ColumnWithName("id")),
+				},
+			},
+		),
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `CREATE TABLE "books" ("id" INTEGER PRIMARY KEY, "title" VARCHAR(80) NOT NULL DEFAULT '', "price" NUMERIC(10, 2), FOREIGN KEY ("author_id") REFERENCES "authors" ("id"))`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	stmt = Statement{
+		Type:  AlterTable,
+		Table: TableWithName("books"),
+		Definitions: JoinDefinitions(
+			&AddColumn{Column: &ColumnDefinition{Name: ColumnWithName("isbn"), Type: TypeString, Size: 13, Unique: true}},
+			&DropColumn{Name: ColumnWithName("price")},
+		),
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `ALTER TABLE "books" ADD COLUMN "isbn" VARCHAR(13) UNIQUE, DROP COLUMN "price"`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	stmt = Statement{
+		Type:    CreateIndex,
+		Index:   ColumnWithName("books_title_idx"),
+		Table:   TableWithName("books"),
+		Columns: JoinColumns(ColumnWithName("title"), ColumnWithName("author_id")),
+		Unique:  true,
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `CREATE UNIQUE INDEX "books_title_idx" ON "books" ("title", "author_id")`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	_, err := (&ColumnDefinition{Name: ColumnWithName("id"), Type: ColumnType("uuid")}).Compile(defaultTemplate)
+	if err != db.ErrUnsupported {
+		t.Fatalf("Expecting db.ErrUnsupported, got: %v", err)
+	}
+}
+
+func TestSelectAsOf(t *testing.T) {
+	stmt := Statement{
+		Type:  Select,
+		Table: TableWithName("jobs"),
+		AsOf:  &AsOf{Timestamp: "'-10s'"},
+	}
+
+	_, err := stmt.Compile(defaultTemplate)
+	if err != db.ErrUnsupported {
+		t.Fatalf("Expecting db.ErrUnsupported, got: %v", err)
+	}
+
+	layout := *defaultTemplate
+	layout.AsOfLayout = `AS OF SYSTEM TIME {{.}}`
+	layout.SelectLayout = `SELECT * FROM {{.Table}} {{.AsOf}}`
+	layout.Cache = cache.NewCache()
+
+	s := mustTrim(stmt.Compile(&layout))
+	e := `SELECT * FROM "jobs" AS OF SYSTEM TIME '-10s'`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
+
+func TestSelectFinalLimitBy(t *testing.T) {
+	stmt := Statement{
+		Type:    Select,
+		Table:   TableWithName("visits"),
+		Final:   &Final{},
+		LimitBy: &LimitBy{Limit: 3, Columns: JoinColumns(ColumnWithName("user_id"))},
+	}
+
+	_, err := stmt.Compile(defaultTemplate)
+	if err != db.ErrUnsupported {
+		t.Fatalf("Expecting db.ErrUnsupported, got: %v", err)
+	}
+
+	layout := *defaultTemplate
+	layout.FinalLayout = `FINAL`
+	layout.LimitByLayout = `LIMIT {{.Limit}} BY {{.Columns}}`
+	layout.SelectLayout = `SELECT * FROM {{.Table}} {{.Final}} {{.LimitBy}}`
+	layout.Cache = cache.NewCache()
+
+	s := mustTrim(stmt.Compile(&layout))
+	e := `SELECT * FROM "visits" FINAL LIMIT 3 BY "user_id"`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
+
+func TestSelectJSONPath(t *testing.T) {
+	stmt := Statement{
+		Type:  Select,
+		Table: TableWithName("users"),
+		Where: WhereConditions(
+			&ColumnValue{Column: JSONPathWithColumn("data", "city"), Operator: "=", Value: NewValue(RawValue("'Paris'"))},
+			&ColumnValue{Column: JSONPathWithColumn("data", "address", "zip"), Operator: "=", Value: NewValue(RawValue("'75001'"))},
+			&ColumnValue{Column: JSONPathWithColumn("data"), Operator: "IS NOT", Value: NewValue(RawValue("NULL"))},
+		),
+	}
+
+	s := mustTrim(stmt.Compile(defaultTemplate))
+	e := `SELECT * FROM "users" WHERE ("data"->>'city' = 'Paris' AND "data"->'address'->>'zip' = '75001' AND "data" IS NOT NULL)`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	// Numeric keys are array indexes and quotes in keys are escaped.
+	indexed := Statement{
+		Type:  Select,
+		Table: TableWithName("users"),
+		Where: WhereConditions(
+			&ColumnValue{Column: JSONPathWithColumn("data", "tags", "0"), Operator: "=", Value: NewValue(RawValue("'go'"))},
+			&ColumnValue{Column: JSONPathWithColumn("data", "first name", "it's"), Operator: "=", Value: NewValue(RawValue("1"))},
+		),
+	}
+
+	s = mustTrim(indexed.Compile(defaultTemplate))
+	e = `SELECT * FROM "users" WHERE ("data"->'tags'->>0 = 'go' AND "data"->'first name'->>'it''s' = 1)`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.JSONPathLayout = `JSON_EXTRACT({{.Column}}, '{{.Path}}')`
+	layout.Cache = cache.NewCache()
+
+	stmt.Where = WhereConditions(
+		&ColumnValue{Column: JSONPathWithColumn("data", "tags", "0"), Operator: "=", Value: NewValue(RawValue("'go'"))},
+		&ColumnValue{Column: JSONPathWithColumn("data", "first name", "it's"), Operator: "=", Value: NewValue(RawValue("1"))},
+	)
+
+	s = mustTrim(stmt.Compile(&layout))
+	e = `SELECT * FROM "users" WHERE (JSON_EXTRACT("data", '$.tags[0]') = 'go' AND JSON_EXTRACT("data", '$."first name"."it''s"') = 1)`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout.JSONPathLayout = ""
+	layout.Cache = cache.NewCache()
+
+	_, err := stmt.Compile(&layout)
+	if err != db.ErrUnsupported {
+		t.Fatalf("Expecting db.ErrUnsupported, got: %v", err)
+	}
+}
+
+func TestSelectFullText(t *testing.T) {
+	stmt := Statement{
+		Type:  Select,
+		Table: TableWithName("posts"),
+		Where: WhereConditions(
+			FullTextWithColumn("body"),
+		),
+		OrderBy: JoinWithOrderBy(
+			JoinSortColumns(
+				&SortColumn{Column: FullTextRankWithColumn("body")},
+			),
+		),
+	}
+
+	s := mustTrim(stmt.Compile(defaultTemplate))
+	e := `SELECT * FROM "posts" WHERE (to_tsvector("body") @@ plainto_tsquery(?)) ORDER BY ts_rank(to_tsvector("body"), plainto_tsquery(?)) DESC`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.FullTextRankLayout = ""
+	layout.Cache = cache.NewCache()
+
+	_, err := stmt.Compile(&layout)
+	if err != db.ErrUnsupported {
+		t.Fatalf("Expecting db.ErrUnsupported, got: %v", err)
+	}
+}
+
+func TestSelectDistinctOn(t *testing.T) {
+	stmt := Statement{
+		Type:       Select,
+		Table:      TableWithName("visits"),
+		Columns:    JoinColumns(ColumnWithName("user_id"), ColumnWithName("created_at")),
+		DistinctOn: &DistinctOn{Columns: JoinColumns(ColumnWithName("user_id"))},
+		OrderBy: JoinWithOrderBy(
+			JoinSortColumns(
+				&SortColumn{Column: ColumnWithName("user_id"), Order: Ascendent},
+				&SortColumn{Column: ColumnWithName("created_at"), Order: Descendent},
+			),
+		),
+	}
+
+	s := mustTrim(stmt.Compile(defaultTemplate))
+	e := `SELECT DISTINCT ON ("user_id") "user_id", "created_at" FROM "visits" ORDER BY "user_id" ASC, "created_at" DESC`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.DistinctOnLayout = ""
+	layout.Cache = cache.NewCache()
+
+	_, err := stmt.Compile(&layout)
+	if err != db.ErrUnsupported {
+		t.Fatalf("Expecting db.ErrUnsupported, got: %v", err)
+	}
+}
+
+func TestUpdateFromDeleteUsing(t *testing.T) {
+	stmt := Statement{
+		Type:  Update,
+		Table: TableWithName("posts"),
+		ColumnValues: JoinColumnValues(
+			&ColumnValue{Column: ColumnWithName("author_name"), Operator: "=", Value: NewValue(RawValue("users.name"))},
+		),
+		From: &UpdateFrom{Tables: JoinColumns(TableWithName("users"))},
+		Where: WhereConditions(
+			&ColumnValue{Column: ColumnWithName("posts.user_id"), Operator: "=", Value: NewValue(RawValue("users.id"))},
+		),
+	}
+
+	s := mustTrim(stmt.Compile(defaultTemplate))
+	e := `UPDATE "posts" SET "author_name" = users.name FROM "users" WHERE ("posts"."user_id" = users.id)`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	stmt = Statement{
+		Type:  Delete,
+		Table: TableWithName("posts"),
+		From: &DeleteUsing{
+			Tables: JoinColumns(TableWithName("users")),
+			Joins: JoinConditions(&Join{
+				Table: TableWithName("teams"),
+				Using: UsingColumns(ColumnWithName("team_id")),
+			}),
+		},
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `DELETE FROM "posts" USING "users" JOIN "teams" USING ("team_id")`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.DeleteUsingLayout = ""
+	layout.Cache = cache.NewCache()
+
+	_, err := stmt.Compile(&layout)
+	if err != db.ErrUnsupported {
+		t.Fatalf("Expecting db.ErrUnsupported, got: %v", err)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	var s, e string
 	var stmt Statement
@@ -735,6 +1170,65 @@ func TestUpdate(t *testing.T) {
 	}
 }
 
+func TestUpdateReturning(t *testing.T) {
+	var s, e string
+
+	stmt := Statement{
+		Type:  Update,
+		Table: TableWithName("table_name"),
+		ColumnValues: JoinColumnValues(
+			&ColumnValue{Column: &Column{Name: "foo"}, Operator: "=", Value: NewValue(76)},
+		),
+		Where: WhereConditions(
+			&ColumnValue{Column: &Column{Name: "baz"}, Operator: "=", Value: NewValue(99)},
+		),
+		Returning: ReturningColumns(ColumnWithName("*")),
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `UPDATE "table_name" SET "foo" = '76' WHERE ("baz" = '99') RETURNING *`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.UpdateLayout = `UPDATE {{.Table}} SET {{.ColumnValues}} {{.Where}}`
+	layout.Cache = cache.NewCache()
+
+	if _, err := stmt.Compile(&layout); err != db.ErrUnsupported {
+		t.Fatalf("Got: %v, Expecting: %v", err, db.ErrUnsupported)
+	}
+}
+
+func TestDeleteReturning(t *testing.T) {
+	var s, e string
+
+	stmt := Statement{
+		Type:  Delete,
+		Table: TableWithName("table_name"),
+		Where: WhereConditions(
+			&ColumnValue{Column: &Column{Name: "baz"}, Operator: "=", Value: NewValue(99)},
+		),
+		Returning: ReturningColumns(ColumnWithName("id"), ColumnWithName("baz")),
+	}
+
+	s = mustTrim(stmt.Compile(defaultTemplate))
+	e = `DELETE FROM "table_name" WHERE ("baz" = '99') RETURNING "id", "baz"`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.DeleteLayout = `DELETE FROM {{.Table}} {{.Where}}`
+	layout.Cache = cache.NewCache()
+
+	if _, err := stmt.Compile(&layout); err != db.ErrUnsupported {
+		t.Fatalf("Got: %v, Expecting: %v", err, db.ErrUnsupported)
+	}
+}
+
 func TestInsert(t *testing.T) {
 	var s, e string
 	var stmt Statement
@@ -826,6 +1320,64 @@ func TestInsertReturning(t *testing.T) {
 	}
 }
 
+func TestInsertOnConflict(t *testing.T) {
+	var s, e string
+
+	columns := JoinColumns(
+		&Column{Name: "id"},
+		&Column{Name: "foo"},
+		&Column{Name: "bar"},
+	)
+
+	upsert := func(onConflict *OnConflict) *Statement {
+		return &Statement{
+			Type:    Insert,
+			Table:   TableWithName("table_name"),
+			Columns: columns,
+			Values: NewValueGroup(
+				&Value{V: 1},
+				&Value{V: "2"},
+				&Value{V: 3},
+			),
+			OnConflict: onConflict,
+		}
+	}
+
+	s = mustTrim(upsert(&OnConflict{
+		Target:  JoinColumns(&Column{Name: "id"}),
+		Columns: columns,
+		Update:  true,
+	}).Compile(defaultTemplate))
+	e = `INSERT INTO "table_name" ("id", "foo", "bar") VALUES ('1', '2', '3') ON CONFLICT ("id") DO UPDATE SET "foo" = EXCLUDED."foo", "bar" = EXCLUDED."bar"`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	s = mustTrim(upsert(&OnConflict{
+		Target:  JoinColumns(&Column{Name: "id"}),
+		Columns: columns,
+		Update:  true,
+		ColumnValues: JoinColumnValues(
+			&ColumnValue{Column: ColumnWithName("bar"), Operator: "=", Value: NewValue(RawValue(`"bar" + 1`))},
+		),
+	}).Compile(defaultTemplate))
+	e = `INSERT INTO "table_name" ("id", "foo", "bar") VALUES ('1', '2', '3') ON CONFLICT ("id") DO UPDATE SET "bar" = "bar" + 1`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	s = mustTrim(upsert(&OnConflict{
+		Columns: columns,
+	}).Compile(defaultTemplate))
+	e = `INSERT INTO "table_name" ("id", "foo", "bar") VALUES ('1', '2', '3') ON CONFLICT DO NOTHING`
+
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
+
 func TestRawSQLStatement(t *testing.T) {
 	stmt := RawSQL(`SELECT * FROM "foo" ORDER BY "bar"`)
 
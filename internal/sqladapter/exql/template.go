@@ -24,6 +24,10 @@ const (
 	Select
 	Update
 	Delete
+	CreateTable
+	AlterTable
+	CreateIndex
+	Call
 
 	SQL
 )
@@ -41,37 +45,76 @@ var (
 
 // Template is an SQL template.
 type Template struct {
-	AndKeyword          string
-	AscKeyword          string
-	AssignmentOperator  string
-	ClauseGroup         string
-	ClauseOperator      string
-	ColumnAliasLayout   string
-	ColumnSeparator     string
-	ColumnValue         string
-	CountLayout         string
-	DeleteLayout        string
-	DescKeyword         string
-	DropDatabaseLayout  string
-	DropTableLayout     string
-	GroupByLayout       string
-	IdentifierQuote     string
-	IdentifierSeparator string
-	InsertLayout        string
-	JoinLayout          string
-	OnLayout            string
-	OrKeyword           string
-	OrderByLayout       string
-	SelectLayout        string
-	SortByColumnLayout  string
-	TableAliasLayout    string
-	TruncateLayout      string
-	UpdateLayout        string
-	UsingLayout         string
-	ValueQuote          string
-	ValueSeparator      string
-	WhereLayout         string
-
+	AddColumnLayout        string
+	AlterTableLayout       string
+	AndKeyword             string
+	AsOfLayout             string
+	AscKeyword             string
+	AssignmentOperator     string
+	CallLayout             string
+	CallOutLayout          string
+	ClauseGroup            string
+	ClauseOperator         string
+	ColumnAliasLayout      string
+	ColumnDefinitionLayout string
+	ColumnSeparator        string
+	ColumnValue            string
+	CompoundLayout         string
+	CountLayout            string
+	CreateIndexLayout      string
+	CreateTableLayout      string
+	DeleteLayout           string
+	DeleteUsingLayout      string
+	DescKeyword            string
+	DistinctOnLayout       string
+	DropColumnLayout       string
+	DropDatabaseLayout     string
+	DropTableLayout        string
+	ExcludedColumn         string
+	FinalLayout            string
+	FullTextLayout         string
+	FullTextRankLayout     string
+	GroupByLayout          string
+	IdentifierQuote        string
+	IdentifierSeparator    string
+	InsertLayout           string
+	JSONPathLayout         string
+	JoinLayout             string
+	LimitByLayout          string
+	LockLayout             string
+	OnConflictLayout       string
+	OnLayout               string
+	OrKeyword              string
+	OrderByLayout          string
+	SelectLayout           string
+	SortByColumnLayout     string
+	TableAliasLayout       string
+	TruncateLayout         string
+	UpdateFromLayout       string
+	UpdateLayout           string
+	UsingLayout            string
+	ValueQuote             string
+	ValueSeparator         string
+	WhereLayout            string
+	WindowLayout           string
+	WithLayout             string
+
+	// CallOutVariables is set if OUT parameters are passed to procedures as
+	// user variables, like MySQL does, CallOutLayout is then a fmt layout
+	// that's given the position of the parameter to name its variable.
+	CallOutVariables bool
+
+	// ConflictTargetNeeded is set if conflicting rows can only be
+	// updated when the columns that identify them are given, like PostgreSQL
+	// requires for ON CONFLICT DO UPDATE.
+	ConflictTargetNeeded bool
+
+	// UpdateFromFirst is set if UpdateLayout places the tables of
+	// UpdateFromLayout before the SET clause, like MySQL does, so joins can be
+	// used without other tables.
+	UpdateFromFirst bool
+
+	ColumnTypes        map[ColumnType]string
 	ComparisonOperator map[db.ComparisonOperator]string
 
 	*cache.Cache
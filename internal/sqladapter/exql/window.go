@@ -0,0 +1,84 @@
+package exql
+
+import (
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+type windowT struct {
+	Function   string
+	Definition string
+}
+
+// Window represents a window function call: a function that is evaluated over
+// a set of rows related to the current row.
+type Window struct {
+	Function    Fragment
+	PartitionBy *Columns
+	OrderBy     *OrderBy
+	// Frame holds the frame clause, like "ROWS BETWEEN 1 PRECEDING AND CURRENT
+	// ROW".
+	Frame string
+	Alias string
+	hash  hash
+}
+
+var _ = Fragment(&Window{})
+
+// Hash returns a unique identifier for the struct.
+func (w *Window) Hash() string {
+	return w.hash.Hash(w)
+}
+
+// Compile transforms the Window into an equivalent SQL representation.
+func (w *Window) Compile(layout *Template) (compiled string, err error) {
+	if layout.WindowLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(w); ok {
+		return z, nil
+	}
+
+	data := windowT{}
+
+	if data.Function, err = w.Function.Compile(layout); err != nil {
+		return "", err
+	}
+
+	chunks := []string{}
+
+	if w.PartitionBy != nil {
+		partitionBy, err := w.PartitionBy.Compile(layout)
+		if err != nil {
+			return "", err
+		}
+		chunks = append(chunks, "PARTITION BY "+partitionBy)
+	}
+
+	if w.OrderBy != nil {
+		orderBy, err := w.OrderBy.Compile(layout)
+		if err != nil {
+			return "", err
+		}
+		chunks = append(chunks, strings.TrimSpace(orderBy))
+	}
+
+	if w.Frame != "" {
+		chunks = append(chunks, w.Frame)
+	}
+
+	data.Definition = strings.Join(chunks, " ")
+
+	compiled = strings.TrimSpace(mustParse(layout.WindowLayout, data))
+
+	if w.Alias != "" {
+		alias := mustParse(layout.IdentifierQuote, Raw{Value: w.Alias})
+		compiled = mustParse(layout.ColumnAliasLayout, columnT{compiled, alias})
+	}
+
+	layout.Write(w, compiled)
+
+	return
+}
@@ -0,0 +1,65 @@
+package exql
+
+import (
+	"testing"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/cache"
+)
+
+func TestWindow(t *testing.T) {
+	w := &Window{
+		Function: RawValue("ROW_NUMBER()"),
+	}
+
+	s := mustTrim(w.Compile(defaultTemplate))
+	e := `ROW_NUMBER() OVER ()`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
+
+func TestWindowPartitionByOrderBy(t *testing.T) {
+	w := &Window{
+		Function:    RawValue("RANK()"),
+		PartitionBy: JoinColumns(&Column{Name: "department"}),
+		OrderBy: JoinWithOrderBy(
+			JoinSortColumns(
+				&SortColumn{Column: &Column{Name: "salary"}, Order: Descendent},
+			),
+		),
+		Alias: "rank",
+	}
+
+	s := mustTrim(w.Compile(defaultTemplate))
+	e := `RANK() OVER (PARTITION BY "department" ORDER BY "salary" DESC) AS "rank"`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+}
+
+func TestWindowFrame(t *testing.T) {
+	w := &Window{
+		Function: RawValue("AVG(price)"),
+		OrderBy: JoinWithOrderBy(
+			JoinSortColumns(
+				&SortColumn{Column: &Column{Name: "day"}},
+			),
+		),
+		Frame: "ROWS BETWEEN 6 PRECEDING AND CURRENT ROW",
+	}
+
+	s := mustTrim(w.Compile(defaultTemplate))
+	e := `AVG(price) OVER (ORDER BY "day" ROWS BETWEEN 6 PRECEDING AND CURRENT ROW)`
+	if s != e {
+		t.Fatalf("Got: %s, Expecting: %s", s, e)
+	}
+
+	layout := *defaultTemplate
+	layout.WindowLayout = ""
+	layout.Cache = cache.NewCache()
+
+	if _, err := w.Compile(&layout); err != db.ErrUnsupported {
+		t.Fatalf("Got: %v, Expecting: %v", err, db.ErrUnsupported)
+	}
+}
@@ -0,0 +1,109 @@
+package exql
+
+import (
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+type withT struct {
+	Recursive bool
+	CTEs      string
+}
+
+type cteT struct {
+	Name    string
+	Columns string
+	Query   string
+}
+
+const cteLayout = `{{.Name}}{{if .Columns}} ({{.Columns}}){{end}} AS ({{.Query}})`
+
+// With represents a WITH clause that defines one or more common table
+// expressions.
+type With struct {
+	// Recursive is true when the CTEs are allowed to refer to themselves.
+	Recursive bool
+	CTEs      []*CTE
+	hash      hash
+}
+
+var _ = Fragment(&With{})
+
+// Hash returns a unique identifier for the struct.
+func (w *With) Hash() string {
+	return w.hash.Hash(w)
+}
+
+// Compile transforms the With into an equivalent SQL representation.
+func (w *With) Compile(layout *Template) (compiled string, err error) {
+	if layout.WithLayout == "" {
+		return "", db.ErrUnsupported
+	}
+
+	if z, ok := layout.Read(w); ok {
+		return z, nil
+	}
+
+	chunks := make([]string, len(w.CTEs))
+	for i := range w.CTEs {
+		if chunks[i], err = w.CTEs[i].Compile(layout); err != nil {
+			return "", err
+		}
+	}
+
+	data := withT{
+		Recursive: w.Recursive,
+		CTEs:      strings.Join(chunks, layout.IdentifierSeparator),
+	}
+
+	compiled = strings.TrimSpace(mustParse(layout.WithLayout, data))
+
+	layout.Write(w, compiled)
+
+	return
+}
+
+// CTE represents a common table expression: a named query that can be
+// referenced by the statement that follows the WITH clause.
+type CTE struct {
+	Name *Column
+	// Columns optionally renames the columns of the query.
+	Columns *Columns
+	Query   Fragment
+	hash    hash
+}
+
+var _ = Fragment(&CTE{})
+
+// Hash returns a unique identifier for the struct.
+func (c *CTE) Hash() string {
+	return c.hash.Hash(c)
+}
+
+// Compile transforms the CTE into an equivalent SQL representation.
+func (c *CTE) Compile(layout *Template) (compiled string, err error) {
+	if z, ok := layout.Read(c); ok {
+		return z, nil
+	}
+
+	data := cteT{}
+
+	if data.Name, err = c.Name.Compile(layout); err != nil {
+		return "", err
+	}
+
+	if data.Columns, err = layout.doCompile(c.Columns); err != nil {
+		return "", err
+	}
+
+	if data.Query, err = c.Query.Compile(layout); err != nil {
+		return "", err
+	}
+
+	compiled = mustParse(cteLayout, data)
+
+	layout.Write(c, compiled)
+
+	return
+}
@@ -0,0 +1,136 @@
+package sqladapter
+
+import (
+	"context"
+	"reflect"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+var (
+	beforeDeleteHookType = reflect.TypeOf((*db.BeforeDeleteHook)(nil)).Elem()
+	afterDeleteHookType  = reflect.TypeOf((*db.AfterDeleteHook)(nil)).Elem()
+)
+
+// InsertWithHooks runs the given insert function between the BeforeInsert and
+// AfterInsert hooks of item, if any. Hooks receive sess, which should be the
+// session or the transaction the item is inserted into table with, and its
+// change listener is notified once the item is inserted. The ID of the item
+// is returned along with the error of its AfterInsert hook, the item is
+// stored anyway unless the transaction it was inserted with is rolled back.
+func InsertWithHooks(ctx context.Context, sess db.Database, table string, item interface{}, insert func() (interface{}, error)) (interface{}, error) {
+	if hook, ok := item.(db.BeforeInsertHook); ok {
+		if err := hook.BeforeInsert(sess); err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := insert()
+	if err != nil {
+		return nil, err
+	}
+
+	if hook, ok := item.(db.AfterInsertHook); ok {
+		err = hook.AfterInsert(sess)
+	}
+
+	notifyChange(ctx, sess, &db.ChangeEvent{
+		Collection: table,
+		Operation:  db.ChangeInsert,
+		Key:        id,
+		After:      item,
+	})
+
+	return id, err
+}
+
+// UpdateWithHooks is like InsertWithHooks but it runs the BeforeUpdate and
+// AfterUpdate hooks of item around the given update function.
+func UpdateWithHooks(sess db.Database, item interface{}, update func() error) error {
+	if hook, ok := item.(db.BeforeUpdateHook); ok {
+		if err := hook.BeforeUpdate(sess); err != nil {
+			return err
+		}
+	}
+
+	if err := update(); err != nil {
+		return err
+	}
+
+	if hook, ok := item.(db.AfterUpdateHook); ok {
+		return hook.AfterUpdate(sess)
+	}
+
+	return nil
+}
+
+// DeleteWithHooks is like UpdateWithHooks but it runs the BeforeDelete and
+// AfterDelete hooks of each of the given items, a pointer to a slice, around
+// the given delete function. The AfterDelete hooks run on the items the slice
+// holds once the function returns.
+func DeleteWithHooks(sess db.Database, items interface{}, delete func() error) error {
+	err := eachItem(items, func(item interface{}) error {
+		if hook, ok := item.(db.BeforeDeleteHook); ok {
+			return hook.BeforeDelete(sess)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := delete(); err != nil {
+		return err
+	}
+
+	return eachItem(items, func(item interface{}) error {
+		if hook, ok := item.(db.AfterDeleteHook); ok {
+			return hook.AfterDelete(sess)
+		}
+		return nil
+	})
+}
+
+// eachItem calls fn with a pointer to each item of the slice items points to.
+func eachItem(items interface{}, fn func(item interface{}) error) error {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return sqlbuilder.ErrExpectingSlicePointer
+	}
+	v = v.Elem()
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		if item.Kind() != reflect.Ptr {
+			item = item.Addr()
+		}
+		if err := fn(item.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasDeleteHooks reports whether items of type t, or pointers to them, have
+// any delete hook.
+func hasDeleteHooks(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr {
+		t = reflect.PtrTo(t)
+	}
+	return t.Implements(beforeDeleteHookType) || t.Implements(afterDeleteHookType)
+}
+
+// notifyChange gives ev, a write made within ctx, to the change listener of
+// sess, if any.
+func notifyChange(ctx context.Context, sess db.Database, ev *db.ChangeEvent) {
+	if sess == nil {
+		return
+	}
+	listener := sess.ChangeListener()
+	if listener == nil {
+		return
+	}
+	ev.Session = sess
+	ev.Context = ctx
+	listener.OnChange(ev)
+}
@@ -0,0 +1,275 @@
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// advisoryLockTable is the table that keeps the advisory locks of databases
+// without advisory locks of their own.
+const advisoryLockTable = "upper_advisory_locks"
+
+// advisoryLockPollInterval is how often AdvisoryLock tries to take a lock
+// kept in advisoryLockTable while it's held by someone else.
+var advisoryLockPollInterval = 100 * time.Millisecond
+
+// hasAdvisoryLocks allows the adapter to use the advisory locks of the
+// database, which are held by the connection that takes them.
+type hasAdvisoryLocks interface {
+	// AdvisoryLockQuery returns the statement that waits until the lock of
+	// the given key is free and takes it.
+	AdvisoryLockQuery(key string) (string, []interface{})
+
+	// TryAdvisoryLockQuery returns the statement that takes the lock of the
+	// given key if it's free, its only row tells whether it was taken.
+	TryAdvisoryLockQuery(key string) (string, []interface{})
+
+	// AdvisoryUnlockQuery returns the statement that releases the lock of the
+	// given key, its only row tells whether it was held.
+	AdvisoryUnlockQuery(key string) (string, []interface{})
+}
+
+// advisoryLocks are the locks held by a session and its clones, locks of
+// databases with advisory locks are kept with the connection that holds
+// them, locks kept in advisoryLockTable have a nil connection.
+type advisoryLocks struct {
+	mu   sync.Mutex
+	held map[string]compat.Conn
+}
+
+func (l *advisoryLocks) add(key string, conn compat.Conn) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held == nil {
+		l.held = make(map[string]compat.Conn)
+	}
+	l.held[key] = conn
+}
+
+// remove forgets the lock of the given key before it's released, so that
+// whoever takes it next is not forgotten.
+func (l *advisoryLocks) remove(key string) (compat.Conn, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	conn, ok := l.held[key]
+	delete(l.held, key)
+	return conn, ok
+}
+
+// close forgets every lock, the connections that hold them go back to the
+// pool, and the locks are released once the pool is closed.
+func (l *advisoryLocks) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.held {
+		if conn != nil {
+			conn.Close()
+		}
+	}
+	l.held = nil
+}
+
+// AdvisoryLock waits until the lock of the given key is free and takes it.
+func (d *database) AdvisoryLock(ctx context.Context, key string) error {
+	locker, ok := d.PartialDatabase.(hasAdvisoryLocks)
+	if !ok {
+		for {
+			locked, err := d.tryTableLock(ctx, key)
+			if err != nil || locked {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(advisoryLockPollInterval):
+			}
+		}
+	}
+
+	query, args := locker.AdvisoryLockQuery(key)
+	var result interface{}
+	conn, err := d.lockConn(ctx, query, args, &result)
+	if err != nil {
+		return err
+	}
+	d.locks.add(key, conn)
+	return nil
+}
+
+// TryAdvisoryLock takes the lock of the given key if it's free, it returns
+// false if it's held by someone else.
+func (d *database) TryAdvisoryLock(ctx context.Context, key string) (bool, error) {
+	locker, ok := d.PartialDatabase.(hasAdvisoryLocks)
+	if !ok {
+		return d.tryTableLock(ctx, key)
+	}
+
+	query, args := locker.TryAdvisoryLockQuery(key)
+	var locked sql.NullBool
+	conn, err := d.lockConn(ctx, query, args, &locked)
+	if err != nil {
+		return false, err
+	}
+	if !locked.Bool {
+		conn.Close()
+		return false, nil
+	}
+	d.locks.add(key, conn)
+	return true, nil
+}
+
+// Unlock releases the lock of the given key, it returns db.ErrLockNotHeld if
+// the lock was not taken by the session.
+func (d *database) Unlock(ctx context.Context, key string) error {
+	conn, ok := d.locks.remove(key)
+	if !ok {
+		return db.ErrLockNotHeld
+	}
+
+	if conn == nil {
+		res, err := d.PartialDatabase.DeleteFrom(advisoryLockTable).
+			Where("lock_key = ?", key).
+			ExecContext(ctx)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return db.ErrLockNotHeld
+		}
+		return nil
+	}
+
+	query, args := d.PartialDatabase.(hasAdvisoryLocks).AdvisoryUnlockQuery(key)
+
+	var released sql.NullBool
+	if err := d.connQueryRow(ctx, conn, exql.RawSQL(query), args, &released); err != nil {
+		// The lock may still be held by the connection, which must not go
+		// back to the pool.
+		compat.DiscardConn(conn)
+		return err
+	}
+	conn.Close()
+
+	if !released.Bool {
+		return db.ErrLockNotHeld
+	}
+	return nil
+}
+
+// lockConn runs the given lock statement on a connection of its own and
+// scans its result into dest, the connection must be kept until the lock is
+// released.
+func (d *database) lockConn(ctx context.Context, query string, args []interface{}, dest interface{}) (compat.Conn, error) {
+	d.sessMu.RLock()
+	sess := d.sess
+	d.sessMu.RUnlock()
+	if sess == nil {
+		return nil, db.ErrNotConnected
+	}
+
+	conn, err := compat.SessionConn(ctx, sess)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.connQueryRow(ctx, conn, exql.RawSQL(query), args, dest); err != nil {
+		// The lock may have been taken even if its result was not read.
+		compat.DiscardConn(conn)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connQueryRow runs a statement that returns one row on the given connection
+// and scans the row into dest. The statement is checked, passed through the
+// middleware, logged and measured like the ones of the session.
+func (d *database) connQueryRow(ctx context.Context, conn compat.Conn, stmt *exql.Statement, args []interface{}, dest interface{}) (err error) {
+	var query string
+
+	if err := d.checkStatement(ctx, stmt, args, false); err != nil {
+		return err
+	}
+
+	defer func(start time.Time) {
+		err = d.queryError(query, start, err)
+	}(time.Now())
+
+	deadline := d.queryDeadline(ctx)
+	defer deadline.release()
+	defer func() {
+		err = deadline.err(err)
+	}()
+	ctx = deadline.ctx
+
+	defer func(start time.Time) {
+		d.metrics.observe(statementTypeName(stmt), start, err)
+	}(time.Now())
+
+	if d.logging() {
+		defer func(start time.Time) {
+			d.logStatement(stmt, &db.QueryStatus{
+				TxID:    d.txID,
+				SessID:  d.sessID,
+				Query:   query,
+				Args:    args,
+				Err:     err,
+				Start:   start,
+				End:     time.Now(),
+				Context: ctx,
+			})
+		}(time.Now())
+	}
+
+	query, args = d.compileStatement(stmt, args)
+	return d.runStatement(ctx, stmt, &query, &args, func(ctx context.Context) error {
+		return conn.QueryRowContext(ctx, query, args...).Scan(dest)
+	})
+}
+
+// tryTableLock takes the lock of the given key by adding a row to
+// advisoryLockTable, which is created if it doesn't exist, the lock is held
+// by someone else if there's a row already.
+func (d *database) tryTableLock(ctx context.Context, key string) (bool, error) {
+	if err := d.PartialDatabase.TableExists(advisoryLockTable); err != nil {
+		_, err := d.PartialDatabase.CreateTable(advisoryLockTable).
+			Columns(sqlbuilder.NewColumn("lock_key", sqlbuilder.String(255)).PrimaryKey()).
+			ExecContext(ctx)
+		if err != nil {
+			// The table may have been created by someone else meanwhile.
+			if d.PartialDatabase.TableExists(advisoryLockTable) != nil {
+				return false, err
+			}
+		}
+	}
+
+	_, err := d.PartialDatabase.InsertInto(advisoryLockTable).
+		Values(map[string]interface{}{"lock_key": key}).
+		ExecContext(ctx)
+	if err == nil {
+		d.locks.add(key, nil)
+		return true, nil
+	}
+
+	// The row may not have been added for reasons other than the lock being
+	// held.
+	iter := d.PartialDatabase.Select("lock_key").
+		From(advisoryLockTable).
+		Where("lock_key = ?", key).
+		IteratorContext(ctx)
+	defer iter.Close()
+
+	if iter.Next() {
+		return false, nil
+	}
+	if iterErr := iter.Err(); iterErr != nil {
+		return false, iterErr
+	}
+	return false, err
+}
@@ -0,0 +1,88 @@
+package sqladapter
+
+import (
+	"reflect"
+	"sync"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/reflectx"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// fieldMappers keeps the mapper of the field mapping of a session, so that
+// the types it has seen are not mapped again. It's shared with the clones of
+// the session.
+type fieldMappers struct {
+	mu      sync.Mutex
+	mapping *db.FieldMapping
+	mapper  *reflectx.Mapper
+}
+
+// get returns the mapper of the given mapping, the mapper is replaced when
+// the mapping is.
+func (f *fieldMappers) get(fm *db.FieldMapping) *reflectx.Mapper {
+	if fm == nil {
+		return mapper
+	}
+	if f == nil {
+		return fieldMapper(fm)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.mapping != fm {
+		f.mapping, f.mapper = fm, fieldMapper(fm)
+	}
+	return f.mapper
+}
+
+// Mapper returns the mapper of the fields of structs to columns the session
+// uses, as set with SetFieldMapping.
+func (d *database) Mapper() *reflectx.Mapper {
+	return d.fieldMappers.get(d.FieldMapping())
+}
+
+// MapOptions returns the options the session maps structs to columns with.
+func (d *database) MapOptions() *sqlbuilder.MapOptions {
+	return &sqlbuilder.MapOptions{
+		Mapper:   d.Mapper(),
+		NullZero: d.ZeroValuePolicy() == db.ZeroAsNull,
+		NewUUID:  d.NewUUID,
+	}
+}
+
+// hasServerUUIDs is implemented by databases that leave the fields tagged with
+// "uuid" to the default value of their columns, like gen_random_uuid().
+type hasServerUUIDs interface {
+	ServerUUIDs() bool
+}
+
+// NewUUID returns the value of a zero field of type t tagged with "uuid" on
+// insert: a new UUIDv7 generated client-side, or nil if the database gives
+// keys a value of its own.
+func (d *database) NewUUID(t reflect.Type) (interface{}, error) {
+	if s, ok := d.PartialDatabase.(hasServerUUIDs); ok && s.ServerUUIDs() {
+		return nil, nil
+	}
+	return sqlbuilder.NewUUID(t)
+}
+
+// fieldMapper returns a new mapper of the given mapping.
+func fieldMapper(fm *db.FieldMapping) *reflectx.Mapper {
+	if fm == nil {
+		return mapper
+	}
+
+	tag := fm.Tag
+	if tag == "" {
+		tag = "db"
+	}
+	var m *reflectx.Mapper
+	if fm.NameMapper != nil {
+		m = reflectx.NewMapperFunc(tag, fm.NameMapper)
+	} else {
+		m = reflectx.NewMapper(tag)
+	}
+	return m
+}
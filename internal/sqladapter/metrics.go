@@ -0,0 +1,96 @@
+package sqladapter
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// metricsPrepare is the key used to count statements that are only prepared.
+const metricsPrepare = "prepare"
+
+var statementTypeNames = map[exql.Type]string{
+	exql.Truncate:     "truncate",
+	exql.DropTable:    "drop",
+	exql.DropDatabase: "drop",
+	exql.Count:        "count",
+	exql.Insert:       "insert",
+	exql.Select:       "select",
+	exql.Update:       "update",
+	exql.Delete:       "delete",
+	exql.Call:         "call",
+	exql.SQL:          "raw",
+}
+
+// metrics collects statistics about the statements a session executes, it is
+// shared by all the clones of a session.
+type metrics struct {
+	mu         sync.Mutex
+	statements map[string]sqlbuilder.StatementMetrics
+
+	cacheHits      uint64
+	cacheMisses    uint64
+	cacheEvictions uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		statements: make(map[string]sqlbuilder.StatementMetrics),
+	}
+}
+
+// observe records the execution of a statement.
+func (m *metrics) observe(name string, start time.Time, err error) {
+	elapsed := time.Since(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.statements[name]
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.Duration += elapsed
+	m.statements[name] = s
+}
+
+func (m *metrics) cacheHit() {
+	atomic.AddUint64(&m.cacheHits, 1)
+}
+
+func (m *metrics) cacheMiss() {
+	atomic.AddUint64(&m.cacheMisses, 1)
+}
+
+func (m *metrics) cacheEviction() {
+	atomic.AddUint64(&m.cacheEvictions, 1)
+}
+
+func (m *metrics) snapshot() sqlbuilder.Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statements := make(map[string]sqlbuilder.StatementMetrics, len(m.statements))
+	for name := range m.statements {
+		statements[name] = m.statements[name]
+	}
+
+	return sqlbuilder.Metrics{
+		Statements:                   statements,
+		PreparedStatementCacheHits:   atomic.LoadUint64(&m.cacheHits),
+		PreparedStatementCacheMisses: atomic.LoadUint64(&m.cacheMisses),
+
+		PreparedStatementCacheEvictions: atomic.LoadUint64(&m.cacheEvictions),
+	}
+}
+
+func statementTypeName(stmt *exql.Statement) string {
+	if name, ok := statementTypeNames[stmt.Type]; ok {
+		return name
+	}
+	return "other"
+}
@@ -0,0 +1,40 @@
+package sqladapter
+
+import (
+	"context"
+	"sync"
+
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// middleware holds the statement middleware installed on a session, it is
+// shared by all the clones of a session.
+type middleware struct {
+	mu    sync.RWMutex
+	chain []func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler
+}
+
+func (m *middleware) use(fn func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.chain = append(m.chain, fn)
+}
+
+func (m *middleware) empty() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.chain) == 0
+}
+
+// run passes stmt through the middleware chain, the first middleware that was
+// installed runs first and h runs last.
+func (m *middleware) run(ctx context.Context, stmt *sqlbuilder.Statement, h sqlbuilder.StatementHandler) error {
+	m.mu.RLock()
+	chain := m.chain
+	m.mu.RUnlock()
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i](h)
+	}
+	return h(ctx, stmt)
+}
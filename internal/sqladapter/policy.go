@@ -0,0 +1,97 @@
+package sqladapter
+
+import (
+	"context"
+	"sync"
+
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// statementPolicy holds the statement policy of a session, it's shared by
+// all the clones of the session.
+type statementPolicy struct {
+	mu     sync.RWMutex
+	policy sqlbuilder.StatementPolicy
+}
+
+func (p *statementPolicy) set(policy sqlbuilder.StatementPolicy) {
+	p.mu.Lock()
+	p.policy = policy
+	p.mu.Unlock()
+}
+
+func (p *statementPolicy) get() sqlbuilder.StatementPolicy {
+	if p == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.policy
+}
+
+// SetStatementPolicy sets the policy that decides whether the statements of
+// the session and its clones may run, a nil policy allows every statement.
+func (d *database) SetStatementPolicy(policy sqlbuilder.StatementPolicy) {
+	d.policy.set(policy)
+}
+
+// checkStatement returns an error if the given statement may not run on the
+// session, either because the session is read-only or because its statement
+// policy refuses it. Every statement is checked before it runs, exec tells
+// whether it returns no rows.
+func (d *database) checkStatement(ctx context.Context, stmt *exql.Statement, args []interface{}, exec bool) error {
+	if err := d.checkReadOnly(stmt, args, exec); err != nil {
+		return err
+	}
+	return d.checkPolicy(ctx, stmt, args)
+}
+
+// checkPolicy returns the error of the statement policy of the session if it
+// refuses the given statement.
+func (d *database) checkPolicy(ctx context.Context, stmt *exql.Statement, args []interface{}) error {
+	policy := d.policy.get()
+	if policy == nil {
+		return nil
+	}
+
+	query, args := d.compileStatement(stmt, args)
+	return policy.Check(ctx, &sqlbuilder.Statement{
+		Type:  statementTypeName(stmt),
+		Query: query,
+		Args:  args,
+		Where: hasConditions(stmt.Where),
+	})
+}
+
+// hasConditions reports whether the given WHERE clause has any condition.
+func hasConditions(f exql.Fragment) bool {
+	var conds []exql.Fragment
+	switch t := f.(type) {
+	case nil:
+		return false
+	case *exql.Where:
+		if t == nil {
+			return false
+		}
+		conds = t.Conditions
+	case *exql.And:
+		if t == nil {
+			return false
+		}
+		conds = t.Conditions
+	case *exql.Or:
+		if t == nil {
+			return false
+		}
+		conds = t.Conditions
+	default:
+		return true
+	}
+	for _, cond := range conds {
+		if hasConditions(cond) {
+			return true
+		}
+	}
+	return false
+}
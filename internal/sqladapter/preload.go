@@ -0,0 +1,272 @@
+package sqladapter
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/reflectx"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// Kinds of relations.
+const (
+	relationHasMany   = "hasMany"
+	relationHasOne    = "hasOne"
+	relationBelongsTo = "belongsTo"
+)
+
+// relation represents the `relation` tag of a struct field.
+type relation struct {
+	kind  string
+	table string
+	fk    string
+	key   string
+}
+
+func parseRelation(tag string) (*relation, error) {
+	rel := &relation{key: "id"}
+
+	for _, option := range strings.Split(tag, ",") {
+		chunks := strings.SplitN(strings.TrimSpace(option), ":", 2)
+		if len(chunks) != 2 || chunks[1] == "" {
+			return nil, fmt.Errorf("Invalid relation tag %q", tag)
+		}
+		switch chunks[0] {
+		case relationHasMany, relationHasOne, relationBelongsTo:
+			rel.kind, rel.table = chunks[0], chunks[1]
+		case "fk":
+			rel.fk = chunks[1]
+		case "key":
+			rel.key = chunks[1]
+		default:
+			return nil, fmt.Errorf("Invalid relation tag %q", tag)
+		}
+	}
+
+	if rel.kind == "" || rel.fk == "" {
+		return nil, fmt.Errorf("Invalid relation tag %q, expecting a kind of relation and a fk", tag)
+	}
+
+	return rel, nil
+}
+
+// columns returns the column of the item that holds the key of the relation
+// and the column of the related items that is matched against it.
+func (rel *relation) columns() (local string, remote string) {
+	if rel.kind == relationBelongsTo {
+		return rel.fk, rel.key
+	}
+	return rel.key, rel.fk
+}
+
+// relationKey returns the value of a key column and a string that identifies
+// it, ok is false if the value is NULL or v is not valid.
+func relationKey(v reflect.Value) (id string, value interface{}, ok bool) {
+	if !v.IsValid() {
+		return "", nil, false
+	}
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil, false
+		}
+		v = v.Elem()
+	}
+
+	value = v.Interface()
+	if valuer, isValuer := value.(driver.Valuer); isValuer {
+		var err error
+		if value, err = valuer.Value(); err != nil || value == nil {
+			return "", nil, false
+		}
+	}
+
+	// Keys are compared by their string form, so an int64 column can be matched
+	// against an int one.
+	return fmt.Sprintf("%v", value), value, true
+}
+
+// preload loads the given relations of the items dst points to, dst is a
+// pointer to a struct or to a slice of structs or pointers to structs.
+func preload(ctx context.Context, builder sqlbuilder.SQLBuilder, dst interface{}, relations []string) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("Expecting a pointer but got %T", dst)
+	}
+	v = v.Elem()
+
+	var items []reflect.Value
+
+	if v.Kind() == reflect.Slice {
+		items = make([]reflect.Value, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if item.Kind() == reflect.Ptr {
+				if item.IsNil() {
+					continue
+				}
+				item = item.Elem()
+			}
+			items = append(items, item)
+		}
+		v = reflect.New(v.Type().Elem()).Elem()
+		if v.Kind() == reflect.Ptr {
+			v = reflect.New(v.Type().Elem()).Elem()
+		}
+	} else {
+		items = []reflect.Value{v}
+	}
+
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("Relations can only be preloaded into structs, got %T", dst)
+	}
+
+	return preloadRelations(ctx, builder, v.Type(), items, relations)
+}
+
+func preloadRelations(ctx context.Context, builder sqlbuilder.SQLBuilder, t reflect.Type, items []reflect.Value, relations []string) error {
+	// Nested relations are loaded along with the field they start from.
+	names := []string{}
+	nested := map[string][]string{}
+
+	for _, relation := range relations {
+		chunks := strings.SplitN(relation, ".", 2)
+		if _, ok := nested[chunks[0]]; !ok {
+			names = append(names, chunks[0])
+			nested[chunks[0]] = []string{}
+		}
+		if len(chunks) > 1 {
+			nested[chunks[0]] = append(nested[chunks[0]], chunks[1])
+		}
+	}
+
+	for _, name := range names {
+		if err := preloadRelation(ctx, builder, t, items, name, nested[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func preloadRelation(ctx context.Context, builder sqlbuilder.SQLBuilder, t reflect.Type, items []reflect.Value, name string, nested []string) error {
+	field, ok := t.FieldByName(name)
+	if !ok || field.Tag.Get("relation") == "" {
+		return fmt.Errorf("%v has no relation named %q", t, name)
+	}
+
+	rel, err := parseRelation(field.Tag.Get("relation"))
+	if err != nil {
+		return err
+	}
+
+	elemType := field.Type
+	if rel.kind == relationHasMany {
+		if elemType.Kind() != reflect.Slice {
+			return fmt.Errorf("Expecting a slice for the %q relation of %v, got %v", name, t, field.Type)
+		}
+		elemType = elemType.Elem()
+	}
+
+	relatedType := elemType
+	if relatedType.Kind() == reflect.Ptr {
+		relatedType = relatedType.Elem()
+	}
+	if relatedType.Kind() != reflect.Struct {
+		return fmt.Errorf("Expecting a struct for the %q relation of %v, got %v", name, t, field.Type)
+	}
+
+	local, remote := rel.columns()
+
+	m := mapper
+	if b, ok := builder.(interface {
+		Mapper() *reflectx.Mapper
+	}); ok {
+		m = b.Mapper()
+	}
+
+	localField, ok := m.TypeMap(t).Names[local]
+	if !ok {
+		return fmt.Errorf("%v has no %q column", t, local)
+	}
+	remoteField, ok := m.TypeMap(relatedType).Names[remote]
+	if !ok {
+		return fmt.Errorf("%v has no %q column", relatedType, remote)
+	}
+
+	keys := []interface{}{}
+	itemKeys := make([]string, len(items))
+	seen := map[string]bool{}
+
+	for i := range items {
+		id, value, ok := relationKey(reflectx.FieldByIndexesReadOnly(items[i], localField.Index))
+		if !ok {
+			continue
+		}
+		itemKeys[i] = id
+		if !seen[id] {
+			seen[id] = true
+			keys = append(keys, value)
+		}
+	}
+
+	related := reflect.New(reflect.SliceOf(relatedType))
+	if len(keys) > 0 {
+		err := builder.SelectFrom(rel.table).
+			Where(db.Cond{remote: keys}).
+			IteratorContext(ctx).
+			All(related.Interface())
+		if err != nil {
+			return err
+		}
+	}
+	related = related.Elem()
+
+	if len(nested) > 0 {
+		relatedItems := make([]reflect.Value, related.Len())
+		for i := range relatedItems {
+			relatedItems[i] = related.Index(i)
+		}
+		if err := preloadRelations(ctx, builder, relatedType, relatedItems, nested); err != nil {
+			return err
+		}
+	}
+
+	groups := map[string][]reflect.Value{}
+	for i := 0; i < related.Len(); i++ {
+		item := related.Index(i)
+		if id, _, ok := relationKey(reflectx.FieldByIndexesReadOnly(item, remoteField.Index)); ok {
+			if elemType.Kind() == reflect.Ptr {
+				item = item.Addr()
+			}
+			groups[id] = append(groups[id], item)
+		}
+	}
+
+	for i := range items {
+		var matches []reflect.Value
+		if seen[itemKeys[i]] {
+			matches = groups[itemKeys[i]]
+		}
+
+		fld := items[i].FieldByIndex(field.Index)
+
+		if rel.kind == relationHasMany {
+			values := reflect.MakeSlice(field.Type, 0, len(matches))
+			values = reflect.Append(values, matches...)
+			fld.Set(values)
+			continue
+		}
+
+		if len(matches) == 0 {
+			fld.Set(reflect.Zero(field.Type))
+			continue
+		}
+		fld.Set(matches[0])
+	}
+
+	return nil
+}
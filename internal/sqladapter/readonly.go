@@ -0,0 +1,38 @@
+package sqladapter
+
+import (
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+// UseReadOnly makes the session refuse the statements that may change the
+// database, its transactions are opened READ ONLY.
+func (d *database) UseReadOnly() {
+	d.mu.Lock()
+	d.readOnly = true
+	d.mu.Unlock()
+}
+
+func (d *database) isReadOnly() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.readOnly
+}
+
+// checkReadOnly returns db.ErrReadOnly if the session is read-only and the
+// given statement may change the database. Exec statements are always
+// refused.
+func (d *database) checkReadOnly(stmt *exql.Statement, args []interface{}, exec bool) error {
+	if !d.isReadOnly() {
+		return nil
+	}
+	if exec {
+		return db.ErrReadOnly
+	}
+
+	query, _ := d.compileStatement(stmt, args)
+	if writes(stmt, query) {
+		return db.ErrReadOnly
+	}
+	return nil
+}
@@ -22,14 +22,27 @@
 package sqladapter
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
 	"sync"
 	"sync/atomic"
 
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/immutable"
+	"upper.io/db.v3/internal/sqladapter/exql"
 	"upper.io/db.v3/lib/sqlbuilder"
 )
 
+// hasExistsQuery allows the adapter to tell whether a set has items on
+// databases that can't select EXISTS as a value.
+type hasExistsQuery interface {
+	// ExistsQuery returns the query that selects a single true value if the
+	// given query has rows, it may also select no rows at all otherwise.
+	ExistsQuery(sqlbuilder.Selector) sqlbuilder.Selector
+}
+
 type Result struct {
 	builder sqlbuilder.SQLBuilder
 
@@ -60,6 +73,21 @@ type result struct {
 	orderBy []interface{}
 	groupBy []interface{}
 	conds   [][]interface{}
+	preload []string
+
+	softDelete string
+	deleted    deletedScope
+
+	// scopeConds are the conditions of the default scopes, which Where
+	// doesn't replace.
+	scopeConds [][]interface{}
+
+	// model is the type of the items of the result set, see Model.
+	model reflect.Type
+
+	// key is the primary key the result set was found by, if any, it's
+	// given to the change listener.
+	key interface{}
 }
 
 func filter(conds []interface{}) []interface{} {
@@ -79,6 +107,43 @@ func (r *Result) frame(fn func(*result) error) *Result {
 	return &Result{prev: r, fn: fn}
 }
 
+// session returns the session the result was created with, it's given to the
+// hooks of the items the result is updated with.
+func (r *Result) session() db.Database {
+	sess, _ := r.SQLBuilder().(db.Database)
+	return sess
+}
+
+// bind returns a copy of the result set whose statements run on the given
+// session.
+func (r *Result) bind(builder sqlbuilder.SQLBuilder) *Result {
+	if r.prev == nil {
+		return &Result{builder: builder}
+	}
+	return &Result{prev: r.prev.bind(builder), fn: r.fn}
+}
+
+// inTx runs fn with a copy of the result set whose statements run within a
+// transaction, the one of the session if there's one, otherwise a new one
+// that's committed if fn returns no error.
+func (r *Result) inTx(ctx context.Context, fn func(tx *Result) error) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+	if sess, ok := r.SQLBuilder().(interface {
+		Transaction() BaseTx
+	}); ok && sess.Transaction() != nil {
+		return fn(r)
+	}
+	starter, ok := r.SQLBuilder().(TxStarter)
+	if !ok {
+		return fn(r)
+	}
+	return RunTx(starter, ctx, func(tx sqlbuilder.Tx) error {
+		return fn(r.bind(tx))
+	})
+}
+
 func (r *Result) SQLBuilder() sqlbuilder.SQLBuilder {
 	if r.prev == nil {
 		return r.builder
@@ -86,6 +151,16 @@ func (r *Result) SQLBuilder() sqlbuilder.SQLBuilder {
 	return r.prev.SQLBuilder()
 }
 
+// context returns the context of the session the result was created from.
+func (r *Result) context() context.Context {
+	if sess, ok := r.SQLBuilder().(interface {
+		Context() context.Context
+	}); ok {
+		return sess.Context()
+	}
+	return context.Background()
+}
+
 func (r *Result) from(table string) *Result {
 	return r.frame(func(res *result) error {
 		res.table = table
@@ -96,6 +171,27 @@ func (r *Result) from(table string) *Result {
 func (r *Result) where(conds []interface{}) *Result {
 	return r.frame(func(res *result) error {
 		res.conds = [][]interface{}{conds}
+		res.key = nil
+		return nil
+	})
+}
+
+// byKey records the primary key the result set was found by.
+func (r *Result) byKey(key interface{}) *Result {
+	return r.frame(func(res *result) error {
+		res.key = key
+		return nil
+	})
+}
+
+// scoped moves the conditions added to the result set after the first n
+// ones, like the ones of its default scopes, to the conditions of its scope.
+func (r *Result) scoped(n int) *Result {
+	return r.frame(func(res *result) error {
+		if len(res.conds) > n {
+			res.scopeConds = append(res.scopeConds, res.conds[n:]...)
+			res.conds = append([][]interface{}(nil), res.conds[:n]...)
+		}
 		return nil
 	})
 }
@@ -205,6 +301,46 @@ func (r *Result) OrderBy(fields ...interface{}) db.Result {
 	})
 }
 
+// Preload determines which relations are loaded along with the items fetched
+// by One and All.
+func (r *Result) Preload(relations ...string) db.Result {
+	return r.frame(func(res *result) error {
+		res.preload = append(res.preload, relations...)
+		return nil
+	})
+}
+
+// Scope applies the named scopes of the collection to the result set.
+func (r *Result) Scope(names ...string) db.Result {
+	if r.Err() != nil {
+		return r
+	}
+	res, err := r.fastForward()
+	if err != nil {
+		return r.frame(func(*result) error {
+			return err
+		})
+	}
+
+	var scopes *db.Scopes
+	if sess := r.session(); sess != nil {
+		scopes = sess.Scopes()
+	}
+
+	var scoped db.Result = r
+	for _, name := range names {
+		scope := scopes.Lookup(res.table, name)
+		if scope == nil {
+			err := fmt.Errorf("%w: %q", db.ErrUnknownScope, name)
+			return r.frame(func(*result) error {
+				return err
+			})
+		}
+		scoped = scope(scoped)
+	}
+	return scoped
+}
+
 // Select determines which fields to return.
 func (r *Result) Select(fields ...interface{}) db.Result {
 	return r.frame(func(res *result) error {
@@ -213,6 +349,16 @@ func (r *Result) Select(fields ...interface{}) db.Result {
 	})
 }
 
+// Compile returns the query and arguments that fetch the items of the result
+// set.
+func (r *Result) Compile() (string, []interface{}, error) {
+	query, err := r.buildPaginator()
+	if err != nil {
+		return "", nil, r.setErr(err)
+	}
+	return query.Compile()
+}
+
 // String satisfies fmt.Stringer
 func (r *Result) String() string {
 	query, err := r.buildPaginator()
@@ -224,36 +370,226 @@ func (r *Result) String() string {
 
 // All dumps all Results into a pointer to an slice of structs or maps.
 func (r *Result) All(dst interface{}) error {
-	query, err := r.buildPaginator()
+	return r.AllContext(r.context(), dst)
+}
+
+// AllContext is like All but the query runs within the given context.
+func (r *Result) AllContext(ctx context.Context, dst interface{}) error {
+	chunks, err := r.chunks()
 	if err != nil {
 		return r.setErr(err)
 	}
-	err = query.Iterator().All(dst)
+	if len(chunks) > 1 {
+		return r.setErr(r.allChunks(ctx, chunks, dst))
+	}
+
+	query, err := chunks[0].buildPaginator()
+	if err != nil {
+		return r.setErr(err)
+	}
+	err = query.IteratorContext(r.cached(ctx)).All(dst)
+	if err == nil {
+		err = r.preload(ctx, dst)
+	}
 	return r.setErr(err)
 }
 
 // One fetches only one Result from the set.
 func (r *Result) One(dst interface{}) error {
-	query, err := r.buildPaginator()
+	return r.OneContext(r.context(), dst)
+}
+
+// OneContext is like One but the query runs within the given context.
+func (r *Result) OneContext(ctx context.Context, dst interface{}) error {
+	chunks, err := r.chunks()
 	if err != nil {
 		return r.setErr(err)
 	}
-	err = query.Iterator().One(dst)
+	if len(chunks) > 1 {
+		if err := r.unpaged(); err != nil {
+			return r.setErr(err)
+		}
+		for _, chunk := range chunks {
+			if err := chunk.OneContext(ctx, dst); err != db.ErrNoMoreRows {
+				return r.setErr(err)
+			}
+		}
+		return r.setErr(db.ErrNoMoreRows)
+	}
+
+	query, err := chunks[0].buildPaginator()
+	if err != nil {
+		return r.setErr(err)
+	}
+	err = query.IteratorContext(r.cached(ctx)).One(dst)
+	if err == nil {
+		err = r.preload(ctx, dst)
+	}
 	return r.setErr(err)
 }
 
+// ForEach calls fn once for each result, see db.Result.
+func (r *Result) ForEach(fn func(scan func(dest ...interface{}) error) error) error {
+	return r.ForEachContext(r.context(), fn)
+}
+
+// ForEachContext is like ForEach but the query runs within the given context.
+// Relations given to Preload are not loaded.
+func (r *Result) ForEachContext(ctx context.Context, fn func(scan func(dest ...interface{}) error) error) error {
+	if err := r.unchunked(); err != nil {
+		return r.setErr(err)
+	}
+	query, err := r.buildPaginator()
+	if err != nil {
+		return r.setErr(err)
+	}
+	return r.setErr(query.IteratorContext(r.cached(ctx)).ForEach(fn))
+}
+
+// Batch streams the results in slices of up to size items, see db.Result.
+// Rows are read from the iterator of the query as batches are received, and
+// the relations given to Preload are loaded for each batch. Errors are sent
+// as the last value of the channel.
+func (r *Result) Batch(ctx context.Context, size int, sliceOfStructs interface{}) <-chan interface{} {
+	sliceType := reflect.TypeOf(sliceOfStructs)
+	if sliceType != nil && sliceType.Kind() == reflect.Ptr {
+		sliceType = sliceType.Elem()
+	}
+	if sliceType == nil || sliceType.Kind() != reflect.Slice {
+		return batchErr(r.setErr(db.ErrUnsupportedDestination))
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	if err := r.unchunked(); err != nil {
+		return batchErr(r.setErr(err))
+	}
+	query, err := r.buildPaginator()
+	if err != nil {
+		return batchErr(r.setErr(err))
+	}
+
+	batches := make(chan interface{})
+	go func() {
+		defer close(batches)
+
+		fail := func(err error) {
+			r.setErr(err)
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case batches <- err:
+			case <-ctx.Done():
+			}
+		}
+
+		iter := query.IteratorContext(ctx)
+		defer iter.Close()
+
+		elemType := sliceType.Elem()
+		batch := reflect.New(sliceType)
+		batch.Elem().Set(reflect.MakeSlice(sliceType, 0, size))
+
+		send := func() bool {
+			if err := r.preload(ctx, batch.Interface()); err != nil {
+				fail(err)
+				return false
+			}
+			select {
+			case batches <- batch.Elem().Interface():
+			case <-ctx.Done():
+				r.setErr(ctx.Err())
+				return false
+			}
+			batch = reflect.New(sliceType)
+			batch.Elem().Set(reflect.MakeSlice(sliceType, 0, size))
+			return true
+		}
+
+		for {
+			var item reflect.Value
+			if elemType.Kind() == reflect.Ptr {
+				item = reflect.New(elemType.Elem())
+			} else {
+				item = reflect.New(elemType)
+			}
+			if !iter.Next(item.Interface()) {
+				break
+			}
+			if elemType.Kind() != reflect.Ptr {
+				item = item.Elem()
+			}
+			batch.Elem().Set(reflect.Append(batch.Elem(), item))
+			if batch.Elem().Len() == size && !send() {
+				return
+			}
+		}
+
+		if err := iter.Err(); err != nil && err != db.ErrNoMoreRows {
+			fail(err)
+			return
+		}
+		if batch.Elem().Len() > 0 {
+			send()
+		}
+	}()
+
+	return batches
+}
+
+// batchErr returns a closed channel that holds err as its only value.
+func batchErr(err error) <-chan interface{} {
+	batches := make(chan interface{}, 1)
+	batches <- err
+	close(batches)
+	return batches
+}
+
+// cached returns ctx marked so that the rows of the result are read through
+// the result cache of the session, if any.
+func (r *Result) cached(ctx context.Context) context.Context {
+	res, err := r.fastForward()
+	if err != nil {
+		return ctx
+	}
+	return withResultCache(ctx, res.table)
+}
+
+// preload loads the relations given to Preload into dst.
+func (r *Result) preload(ctx context.Context, dst interface{}) error {
+	res, err := r.fastForward()
+	if err != nil {
+		return err
+	}
+	if len(res.preload) == 0 {
+		return nil
+	}
+	return preload(ctx, r.SQLBuilder(), dst, res.preload)
+}
+
 // Next fetches the next Result from the set.
 func (r *Result) Next(dst interface{}) bool {
+	return r.NextContext(r.context(), dst)
+}
+
+// NextContext is like Next but the query runs within the given context.
+func (r *Result) NextContext(ctx context.Context, dst interface{}) bool {
 	r.iterMu.Lock()
 	defer r.iterMu.Unlock()
 
 	if r.iter == nil {
+		if err := r.unchunked(); err != nil {
+			r.setErr(err)
+			return false
+		}
 		query, err := r.buildPaginator()
 		if err != nil {
 			r.setErr(err)
 			return false
 		}
-		r.iter = query.Iterator()
+		r.iter = query.IteratorContext(ctx)
 	}
 
 	if r.iter.Next(dst) {
@@ -269,13 +605,97 @@ func (r *Result) Next(dst interface{}) bool {
 
 // Delete deletes all matching items from the collection.
 func (r *Result) Delete() error {
-	query, err := r.buildDelete()
+	return r.DeleteContext(r.context())
+}
+
+// DeleteContext is like Delete but the query runs within the given context.
+func (r *Result) DeleteContext(ctx context.Context) error {
+	res, err := r.fastForward()
 	if err != nil {
 		return r.setErr(err)
 	}
 
-	_, err = query.Exec()
-	return r.setErr(err)
+	if res.model != nil && hasDeleteHooks(res.model) {
+		// The items are fetched so that their hooks can run, within the
+		// transaction they're deleted in.
+		err = r.inTx(ctx, func(tx *Result) error {
+			items := reflect.New(reflect.SliceOf(res.model)).Interface()
+			if err := tx.AllContext(ctx, items); err != nil {
+				return err
+			}
+			return DeleteWithHooks(tx.session(), items, func() error {
+				return tx.deleteChunks(ctx)
+			})
+		})
+	} else {
+		err = r.deleteChunks(ctx)
+	}
+	if err != nil {
+		return r.setErr(err)
+	}
+
+	r.notifyChange(ctx, db.ChangeDelete, nil, nil)
+	return nil
+}
+
+// deleteChunks removes, or soft deletes, the items of each chunk of the
+// result set.
+func (r *Result) deleteChunks(ctx context.Context) error {
+	chunks, err := r.chunks()
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if err := chunk.execDelete(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Model sets the type of the items of the result set, their delete hooks run
+// when it's deleted.
+func (r *Result) Model(item interface{}) db.Result {
+	t := reflect.TypeOf(item)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return r.frame(func(res *result) error {
+		res.model = t
+		return nil
+	})
+}
+
+// DeleteReturning deletes all matching items from the collection and dumps
+// them into a pointer to an slice of structs or maps.
+func (r *Result) DeleteReturning(dst interface{}) error {
+	return r.DeleteReturningContext(r.context(), dst)
+}
+
+// DeleteReturningContext is like DeleteReturning but the query runs within
+// the given context.
+func (r *Result) DeleteReturningContext(ctx context.Context, dst interface{}) error {
+	var err error
+	if t := reflect.TypeOf(dst); t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice && hasDeleteHooks(t.Elem().Elem()) {
+		// The items are fetched so that their BeforeDelete hooks can run,
+		// within the transaction they're deleted in.
+		err = r.inTx(ctx, func(tx *Result) error {
+			if err := tx.AllContext(ctx, dst); err != nil {
+				return err
+			}
+			return DeleteWithHooks(tx.session(), dst, func() error {
+				return tx.deleteReturning(ctx, dst)
+			})
+		})
+	} else {
+		err = r.deleteReturning(ctx, dst)
+	}
+	if err != nil {
+		return r.setErr(err)
+	}
+
+	r.notifyChange(ctx, db.ChangeDelete, dst, nil)
+	return nil
 }
 
 // Close closes the Result set.
@@ -289,22 +709,160 @@ func (r *Result) Close() error {
 // Update updates matching items from the collection with values of the given
 // map or struct.
 func (r *Result) Update(values interface{}) error {
-	query, err := r.buildUpdate(values)
+	return r.UpdateContext(r.context(), values)
+}
+
+// UpdateContext is like Update but the query runs within the given context.
+func (r *Result) UpdateContext(ctx context.Context, values interface{}) error {
+	var before interface{}
+	err := UpdateWithHooks(r.session(), values, func() error {
+		chunks, err := r.chunks()
+		if err != nil {
+			return err
+		}
+		if before, err = r.beforeUpdate(ctx); err != nil {
+			return err
+		}
+		for _, chunk := range chunks {
+			query, err := chunk.buildUpdate(ctx, values)
+			if err != nil {
+				return err
+			}
+			if _, err = query.ExecContext(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return r.setErr(err)
 	}
 
-	_, err = query.Exec()
-	return r.setErr(err)
+	r.notifyChange(ctx, db.ChangeUpdate, before, values)
+	return nil
+}
+
+// UpdateReturning updates matching items from the collection with values of
+// the given pointer to map or struct and overwrites it with the updated item.
+func (r *Result) UpdateReturning(ptr interface{}) error {
+	return r.UpdateReturningContext(r.context(), ptr)
+}
+
+// UpdateReturningContext is like UpdateReturning but the query runs within
+// the given context.
+func (r *Result) UpdateReturningContext(ctx context.Context, ptr interface{}) error {
+	if ptr == nil || reflect.TypeOf(ptr).Kind() != reflect.Ptr {
+		return r.setErr(fmt.Errorf("Expecting a pointer but got %T", ptr))
+	}
+
+	var before interface{}
+	err := UpdateWithHooks(r.session(), ptr, func() error {
+		err := r.unchunked()
+		if err != nil {
+			return err
+		}
+		if before, err = r.beforeUpdate(ctx); err != nil {
+			return err
+		}
+		query, err := r.buildUpdate(ctx, ptr)
+		if err != nil {
+			return err
+		}
+		return query.Returning("*").IteratorContext(ctx).One(ptr)
+	})
+	if err != nil {
+		return r.setErr(err)
+	}
+
+	r.notifyChange(ctx, db.ChangeUpdate, before, ptr)
+	return nil
+}
+
+// beforeUpdate returns the items of the result set as they are before it's
+// updated, if the change listener of the session wants them.
+func (r *Result) beforeUpdate(ctx context.Context) (interface{}, error) {
+	sess := r.session()
+	if sess == nil {
+		return nil, nil
+	}
+	listener, ok := sess.ChangeListener().(db.BeforeChangeListener)
+	if !ok {
+		return nil, nil
+	}
+	res, err := r.fastForward()
+	if err != nil {
+		return nil, err
+	}
+	if !listener.WantsBefore(res.table) {
+		return nil, nil
+	}
+
+	var items []map[string]interface{}
+	if err := r.AllContext(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// notifyChange tells the change listener of the session that the result set
+// was written.
+func (r *Result) notifyChange(ctx context.Context, op db.ChangeOperation, before interface{}, after interface{}) {
+	res, err := r.fastForward()
+	if err != nil {
+		return
+	}
+
+	var conds []interface{}
+	for i := range res.conds {
+		conds = append(conds, res.conds[i]...)
+	}
+
+	notifyChange(ctx, r.session(), &db.ChangeEvent{
+		Collection: res.table,
+		Operation:  op,
+		Key:        res.key,
+		Conditions: conds,
+		Before:     before,
+		After:      after,
+	})
+}
+
+func (r *Result) NextPageCursor(items interface{}) (db.Cursor, error) {
+	if err := r.unchunked(); err != nil {
+		return "", r.setErr(err)
+	}
+	query, err := r.buildPaginator()
+	if err != nil {
+		return "", r.setErr(err)
+	}
+	return query.NextPageCursor(items)
+}
+
+func (r *Result) PrevPageCursor(items interface{}) (db.Cursor, error) {
+	if err := r.unchunked(); err != nil {
+		return "", r.setErr(err)
+	}
+	query, err := r.buildPaginator()
+	if err != nil {
+		return "", r.setErr(err)
+	}
+	return query.PrevPageCursor(items)
 }
 
 func (r *Result) TotalPages() (uint, error) {
+	return r.TotalPagesContext(r.context())
+}
+
+func (r *Result) TotalPagesContext(ctx context.Context) (uint, error) {
+	if err := r.unchunked(); err != nil {
+		return 0, r.setErr(err)
+	}
 	query, err := r.buildPaginator()
 	if err != nil {
 		return 0, r.setErr(err)
 	}
 
-	total, err := query.TotalPages()
+	total, err := query.TotalPagesContext(ctx)
 	if err != nil {
 		return 0, r.setErr(err)
 	}
@@ -313,12 +871,19 @@ func (r *Result) TotalPages() (uint, error) {
 }
 
 func (r *Result) TotalEntries() (uint64, error) {
+	return r.TotalEntriesContext(r.context())
+}
+
+func (r *Result) TotalEntriesContext(ctx context.Context) (uint64, error) {
+	if err := r.unchunked(); err != nil {
+		return 0, r.setErr(err)
+	}
 	query, err := r.buildPaginator()
 	if err != nil {
 		return 0, r.setErr(err)
 	}
 
-	total, err := query.TotalEntries()
+	total, err := query.TotalEntriesContext(ctx)
 	if err != nil {
 		return 0, r.setErr(err)
 	}
@@ -328,34 +893,76 @@ func (r *Result) TotalEntries() (uint64, error) {
 
 // Exists returns true if at least one item on the collection exists.
 func (r *Result) Exists() (bool, error) {
-	query, err := r.buildCount()
+	return r.ExistsContext(r.context())
+}
+
+// ExistsContext is like Exists but the query runs within the given context.
+// It selects EXISTS over the first matching row only, so the database stops
+// looking as soon as it finds one instead of counting or fetching them.
+func (r *Result) ExistsContext(ctx context.Context) (bool, error) {
+	chunks, err := r.chunks()
 	if err != nil {
 		return false, r.setErr(err)
 	}
+	if len(chunks) > 1 {
+		for _, chunk := range chunks {
+			if exists, err := chunk.ExistsContext(ctx); exists || err != nil {
+				return exists, r.setErr(err)
+			}
+		}
+		return false, nil
+	}
 
-	query = query.Limit(1)
+	query, err := chunks[0].buildExists()
+	if err != nil {
+		return false, r.setErr(err)
+	}
 
-	value := struct {
-		Exists uint64 `db:"_t"`
-	}{}
+	row, err := query.QueryRowContext(ctx)
+	if err != nil {
+		return false, r.setErr(err)
+	}
 
-	if err := query.One(&value); err != nil {
-		if err == db.ErrNoMoreRows {
+	var exists bool
+	if err := row.Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
 			return false, nil
 		}
 		return false, r.setErr(err)
 	}
+	return exists, nil
+}
 
-	if value.Exists > 0 {
-		return true, nil
+// Count counts the elements on the set.
+func (r *Result) Count(opts ...db.CountOption) (uint64, error) {
+	return r.CountContext(r.context(), opts...)
+}
+
+// CountContext is like Count but the query runs within the given context.
+func (r *Result) CountContext(ctx context.Context, opts ...db.CountOption) (uint64, error) {
+	chunks, err := r.chunks()
+	if err != nil {
+		return 0, r.setErr(err)
+	}
+	if len(chunks) > 1 {
+		var count uint64
+		for _, chunk := range chunks {
+			n, err := chunk.CountContext(ctx, opts...)
+			if err != nil {
+				return 0, r.setErr(err)
+			}
+			count += n
+		}
+		return count, nil
 	}
 
-	return false, nil
-}
+	for _, opt := range opts {
+		if opt == db.CountEstimate {
+			return chunks[0].estimateCount(ctx)
+		}
+	}
 
-// Count counts the elements on the set.
-func (r *Result) Count() (uint64, error) {
-	query, err := r.buildCount()
+	query, err := chunks[0].buildCount()
 	if err != nil {
 		return 0, r.setErr(err)
 	}
@@ -363,7 +970,7 @@ func (r *Result) Count() (uint64, error) {
 	counter := struct {
 		Count uint64 `db:"_t"`
 	}{}
-	if err := query.One(&counter); err != nil {
+	if err := query.OneContext(ctx, &counter); err != nil {
 		if err == db.ErrNoMoreRows {
 			return 0, nil
 		}
@@ -373,6 +980,101 @@ func (r *Result) Count() (uint64, error) {
 	return counter.Count, nil
 }
 
+// Sum returns the sum of the values of the given column.
+func (r *Result) Sum(column string) (float64, error) {
+	return r.SumContext(r.context(), column)
+}
+
+// SumContext is like Sum but the query runs within the given context.
+func (r *Result) SumContext(ctx context.Context, column string) (float64, error) {
+	var sum sql.NullFloat64
+	if err := r.aggregate(ctx, "SUM(_v)", column, &sum); err != nil {
+		return 0, err
+	}
+	return sum.Float64, nil
+}
+
+// Avg returns the average of the values of the given column.
+func (r *Result) Avg(column string) (float64, error) {
+	return r.AvgContext(r.context(), column)
+}
+
+// AvgContext is like Avg but the query runs within the given context.
+func (r *Result) AvgContext(ctx context.Context, column string) (float64, error) {
+	var avg sql.NullFloat64
+	if err := r.aggregate(ctx, "AVG(_v)", column, &avg); err != nil {
+		return 0, err
+	}
+	return avg.Float64, nil
+}
+
+// Min scans the smallest value of the given column into dst.
+func (r *Result) Min(column string, dst interface{}) error {
+	return r.MinContext(r.context(), column, dst)
+}
+
+// MinContext is like Min but the query runs within the given context.
+func (r *Result) MinContext(ctx context.Context, column string, dst interface{}) error {
+	return r.aggregate(ctx, "MIN(_v)", column, dst)
+}
+
+// Max scans the greatest value of the given column into dst.
+func (r *Result) Max(column string, dst interface{}) error {
+	return r.MaxContext(r.context(), column, dst)
+}
+
+// MaxContext is like Max but the query runs within the given context.
+func (r *Result) MaxContext(ctx context.Context, column string, dst interface{}) error {
+	return r.aggregate(ctx, "MAX(_v)", column, dst)
+}
+
+// CountDistinct counts the distinct values of the given column.
+func (r *Result) CountDistinct(column string) (uint64, error) {
+	return r.CountDistinctContext(r.context(), column)
+}
+
+// CountDistinctContext is like CountDistinct but the query runs within the
+// given context.
+func (r *Result) CountDistinctContext(ctx context.Context, column string) (uint64, error) {
+	var count uint64
+	if err := r.aggregate(ctx, "COUNT(DISTINCT _v)", column, &count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// Explain returns the plan of the query that fetches the items of the result
+// set.
+func (r *Result) Explain(ctx context.Context) (string, error) {
+	if err := r.unchunked(); err != nil {
+		return "", r.setErr(err)
+	}
+	query, err := r.buildPaginator()
+	if err != nil {
+		return "", r.setErr(err)
+	}
+	return query.Explain(ctx)
+}
+
+// aggregate scans the value of the given aggregate expression over the _v
+// column into dst.
+func (r *Result) aggregate(ctx context.Context, expr string, column string, dst interface{}) error {
+	if err := r.unchunked(); err != nil {
+		return r.setErr(err)
+	}
+	query, err := r.buildAggregate(expr, column)
+	if err != nil {
+		return r.setErr(err)
+	}
+
+	row, err := query.QueryRowContext(ctx)
+	if err != nil {
+		return r.setErr(err)
+	}
+
+	return r.setErr(row.Scan(dst))
+}
+
 func (r *Result) buildPaginator() (sqlbuilder.Paginator, error) {
 	if err := r.Err(); err != nil {
 		return nil, err
@@ -409,6 +1111,46 @@ func (r *Result) buildPaginator() (sqlbuilder.Paginator, error) {
 	return pag, nil
 }
 
+// deleteReturning removes, or soft deletes, the items of the result set and
+// dumps them into dst.
+func (r *Result) deleteReturning(ctx context.Context, dst interface{}) error {
+	if err := r.unchunked(); err != nil {
+		return err
+	}
+	soft, err := r.buildSoftDelete(ctx)
+	if err != nil {
+		return err
+	}
+	if soft != nil {
+		return soft.Returning("*").IteratorContext(ctx).All(dst)
+	}
+
+	query, err := r.buildDelete()
+	if err != nil {
+		return err
+	}
+	return query.Returning("*").IteratorContext(ctx).All(dst)
+}
+
+// execDelete removes the items of the result set, or soft deletes them.
+func (r *Result) execDelete(ctx context.Context) error {
+	soft, err := r.buildSoftDelete(ctx)
+	if err != nil {
+		return err
+	}
+	if soft != nil {
+		_, err = soft.ExecContext(ctx)
+		return err
+	}
+
+	query, err := r.buildDelete()
+	if err != nil {
+		return err
+	}
+	_, err = query.ExecContext(ctx)
+	return err
+}
+
 func (r *Result) buildDelete() (sqlbuilder.Deleter, error) {
 	if err := r.Err(); err != nil {
 		return nil, err
@@ -429,7 +1171,7 @@ func (r *Result) buildDelete() (sqlbuilder.Deleter, error) {
 	return del, nil
 }
 
-func (r *Result) buildUpdate(values interface{}) (sqlbuilder.Updater, error) {
+func (r *Result) buildUpdate(ctx context.Context, values interface{}) (sqlbuilder.Updater, error) {
 	if err := r.Err(); err != nil {
 		return nil, err
 	}
@@ -439,6 +1181,10 @@ func (r *Result) buildUpdate(values interface{}) (sqlbuilder.Updater, error) {
 		return nil, err
 	}
 
+	if values, err = r.withoutTenant(ctx, res.table, values); err != nil {
+		return nil, err
+	}
+
 	upd := r.SQLBuilder().Update(res.table).
 		Set(values).
 		Limit(res.limit)
@@ -460,7 +1206,7 @@ func (r *Result) buildCount() (sqlbuilder.Selector, error) {
 		return nil, err
 	}
 
-	sel := r.SQLBuilder().Select(db.Raw("count(1) AS _t")).
+	sel := r.SQLBuilder().Select(exql.ColumnWithAlias("count(1)", "_t")).
 		From(res.table).
 		GroupBy(res.groupBy...)
 
@@ -471,6 +1217,74 @@ func (r *Result) buildCount() (sqlbuilder.Selector, error) {
 	return sel, nil
 }
 
+// buildExists returns the query that tells whether the set has items, an
+// EXISTS over the first matching row unless the adapter has its own.
+func (r *Result) buildExists() (sqlbuilder.Selector, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	res, err := r.fastForward()
+	if err != nil {
+		return nil, err
+	}
+
+	sel := r.SQLBuilder().Select(db.Raw("1")).
+		From(res.table).
+		GroupBy(res.groupBy...).
+		Limit(1)
+
+	for i := range res.conds {
+		sel = sel.And(filter(res.conds[i])...)
+	}
+
+	if e, ok := r.SQLBuilder().(hasExistsQuery); ok {
+		return e.ExistsQuery(sel), nil
+	}
+	return r.SQLBuilder().Select(db.Raw("EXISTS ? AS _t", sel)), nil
+}
+
+// buildAggregate wraps the items of the set, with the given column renamed
+// to _v, into a subquery, so the aggregate expression is computed over the
+// current page only.
+func (r *Result) buildAggregate(expr string, column string) (sqlbuilder.Selector, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	res, err := r.fastForward()
+	if err != nil {
+		return nil, err
+	}
+
+	sel := r.SQLBuilder().Select(column + " AS _v").
+		From(res.table)
+
+	for i := range res.conds {
+		sel = sel.And(filter(res.conds[i])...)
+	}
+
+	limit, offset := res.limit, res.offset
+	if res.pageSize > 0 {
+		limit = int(res.pageSize)
+		if res.pageNumber > 1 {
+			offset = int(res.pageSize * (res.pageNumber - 1))
+		}
+	}
+
+	// The order only matters when the set is delimited, some databases don't
+	// allow ORDER BY on subqueries otherwise.
+	if limit > 0 || offset > 0 {
+		sel = sel.Limit(limit).
+			Offset(offset).
+			OrderBy(res.orderBy...)
+	}
+
+	return r.SQLBuilder().Select(exql.ColumnWithAlias(expr, "_t")).
+		From(sel).
+		As("_a"), nil
+}
+
 func (r *Result) Prev() immutable.Immutable {
 	if r == nil {
 		return nil
@@ -494,7 +1308,16 @@ func (r *Result) fastForward() (*result, error) {
 	if err != nil {
 		return nil, err
 	}
-	return ff.(*result), nil
+	res := ff.(*result)
+	res.conds = append(res.conds, res.scopeConds...)
+	deleted, err := res.deletedConds()
+	if err != nil {
+		return nil, err
+	}
+	if len(deleted) > 0 {
+		res.conds = append(res.conds, deleted)
+	}
+	return res, nil
 }
 
 var _ = immutable.Immutable(&Result{})
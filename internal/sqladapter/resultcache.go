@@ -0,0 +1,339 @@
+package sqladapter
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+var (
+	rowsDBOnce sync.Once
+	rowsDB     *sql.DB
+)
+
+func init() {
+	gob.Register(time.Time{})
+}
+
+// cachedRows are the rows of a result read ahead, as they're kept in the
+// result cache and shared by collapsed reads.
+type cachedRows struct {
+	Columns []string
+	Values  [][]interface{}
+}
+
+type resultCacheTableKey struct{}
+
+// withResultCache returns a context that marks the query of a result of the
+// given table as one to read through the result cache of the session.
+func withResultCache(ctx context.Context, table string) context.Context {
+	return context.WithValue(ctx, resultCacheTableKey{}, table)
+}
+
+// resultCacheTable returns the table given to withResultCache, if any.
+func resultCacheTable(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	table, _ := ctx.Value(resultCacheTableKey{}).(string)
+	return table
+}
+
+// allTablesVersion is the name the version of every table is kept with, it
+// changes when statements whose table is not known are run.
+const allTablesVersion = "*"
+
+// reTableRef matches the tables named after the FROM and JOIN keywords of a
+// query, along with the ones listed after them.
+var (
+	reTableRef  = regexp.MustCompile("(?i)\\b(?:FROM|JOIN)\\s+([\\w.\"`\\[\\]]+)")
+	reTableList = regexp.MustCompile("(?i)^(?:\\s+(?:AS\\s+)?[\\w\"`\\[\\]]+)?\\s*,\\s*([\\w.\"`\\[\\]]+)")
+)
+
+// referencedTables returns the names of the tables the given query reads
+// from, including the ones of its joins and subqueries.
+func referencedTables(query string) []string {
+	var tables []string
+	for _, m := range reTableRef.FindAllStringSubmatchIndex(query, -1) {
+		tables = append(tables, cacheTableName(query[m[2]:m[3]]))
+		for rest := query[m[3]:]; ; {
+			l := reTableList.FindStringSubmatchIndex(rest)
+			if l == nil {
+				break
+			}
+			tables = append(tables, cacheTableName(rest[l[2]:l[3]]))
+			rest = rest[l[3]:]
+		}
+	}
+	return tables
+}
+
+// newResultVersion returns a version that no other session is likely to
+// choose.
+func newResultVersion() []byte {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return []byte(strconv.FormatInt(time.Now().UnixNano(), 36))
+	}
+	return []byte(hex.EncodeToString(b))
+}
+
+// resultVersionKey returns the key the version of the given table is kept
+// with in the result cache.
+func (d *database) resultVersionKey(table string) string {
+	return fmt.Sprintf("upper:%s:version:%s", d.Name(), table)
+}
+
+// resultVersion returns the version of the given table. Versions are kept in
+// the result cache itself, so that the sessions of every process that shares
+// it see the writes of each other, a table that has none is given a new one.
+func (d *database) resultVersion(ctx context.Context, resultCache db.ResultCache, table string) string {
+	key := d.resultVersionKey(table)
+	if version, ok := resultCache.Get(ctx, key); ok {
+		return string(version)
+	}
+	version := newResultVersion()
+	resultCache.Set(ctx, key, version)
+	return string(version)
+}
+
+// writtenTable returns the name of the table the given statement writes to,
+// or allTablesVersion if it's not known. Raw statements and procedures may
+// write to any table.
+func writtenTable(stmt *exql.Statement) string {
+	if t, ok := stmt.Table.(*exql.Table); ok && stmt.Type != exql.SQL && stmt.Type != exql.Call {
+		if name, ok := t.Name.(string); ok {
+			return cacheTableName(name)
+		}
+	}
+	return allTablesVersion
+}
+
+// cacheTableName returns the name of the given table without its alias,
+// schema or quotes, so that all the ways of naming a table have the same
+// generation.
+func cacheTableName(name string) string {
+	if fields := strings.Fields(name); len(fields) > 0 {
+		name = fields[0]
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(strings.Trim(name, "\"`[]"))
+}
+
+// invalidateResults gives a new version to the table the given statement
+// writes to, which changes the keys of the cached results that read it. The
+// version changes again once the transaction the statement ran within is
+// committed, results read meanwhile by other sessions are out of date.
+func (d *database) invalidateResults(ctx context.Context, stmt *exql.Statement) {
+	resultCache := d.ResultCache()
+	key := d.resultVersionKey(writtenTable(stmt))
+
+	resultCache.Set(ctx, key, newResultVersion())
+	if tx, ok := d.baseTx.(*baseTx); ok {
+		tx.AfterCommit(func() {
+			resultCache.Set(context.Background(), key, newResultVersion())
+		})
+	}
+}
+
+// resultCacheKey returns the key of the result of the given query of table,
+// which holds the versions of every table the query reads from.
+func (d *database) resultCacheKey(ctx context.Context, resultCache db.ResultCache, table string, query string, args []interface{}) string {
+	tables := append(referencedTables(query), cacheTableName(table), allTablesVersion)
+	sort.Strings(tables)
+
+	h := sha256.New()
+	io.WriteString(h, query)
+	fmt.Fprintf(h, "\x00%#v", args)
+	for i := range tables {
+		if i > 0 && tables[i] == tables[i-1] {
+			continue
+		}
+		fmt.Fprintf(h, "\x00%s=%s", tables[i], d.resultVersion(ctx, resultCache, tables[i]))
+	}
+	return fmt.Sprintf("upper:%s:%s:%x", d.Name(), cacheTableName(table), h.Sum(nil))
+}
+
+// cachedQuery returns the rows of the given statement from the result cache
+// of the session, the statement is run and its rows are stored if they're not
+// there.
+func (d *database) cachedQuery(ctx context.Context, table string, stmt *exql.Statement, args []interface{}) (*sql.Rows, error) {
+	resultCache := d.ResultCache()
+
+	query, compiledArgs := d.compileStatement(stmt, args)
+	key := d.resultCacheKey(ctx, resultCache, table, query, compiledArgs)
+
+	if value, ok := resultCache.Get(ctx, key); ok {
+		var rows cachedRows
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&rows); err == nil {
+			return replayRows(ctx, &rows)
+		}
+	}
+
+	live, err := d.StatementQuery(withResultCache(ctx, ""), stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := readRows(live)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows with values gob can't encode are not cached.
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rows); err == nil {
+		resultCache.Set(ctx, key, buf.Bytes())
+	}
+	return replayRows(ctx, rows)
+}
+
+// readRows reads and closes the given rows.
+func readRows(rows *sql.Rows) (*cachedRows, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &cachedRows{Columns: columns}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		cached.Values = append(cached.Values, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return cached, nil
+}
+
+// replayRows returns the given rows as *sql.Rows, so they're mapped like the
+// rows of the database.
+func replayRows(ctx context.Context, rows *cachedRows) (*sql.Rows, error) {
+	return driverRows(ctx, &cachedRowsIter{rows: rows})
+}
+
+// driverRows returns the given driver rows as *sql.Rows.
+func driverRows(ctx context.Context, rows driver.Rows) (*sql.Rows, error) {
+	return compat.QueryContext(iterRowsDB(), ctx, "", []interface{}{iterRowsArg{rows}})
+}
+
+// driverRow returns the first of the given driver rows as *sql.Row, the
+// rows are closed once it's scanned.
+func driverRow(ctx context.Context, rows driver.Rows) (*sql.Row, error) {
+	return compat.QueryRowContext(iterRowsDB(), ctx, "", []interface{}{iterRowsArg{rows}}), nil
+}
+
+// iterRowsDB returns the database driverRows reads from. It's opened with a
+// connector of this package rather than a registered driver, its queries
+// return the rows given to them as their only argument.
+func iterRowsDB() *sql.DB {
+	rowsDBOnce.Do(func() {
+		rowsDB = sql.OpenDB(iterRowsConnector{})
+	})
+	return rowsDB
+}
+
+// iterRowsArg wraps the driver rows passed to the queries of iterRowsDB.
+type iterRowsArg struct {
+	rows driver.Rows
+}
+
+type iterRowsConnector struct{}
+
+func (iterRowsConnector) Connect(context.Context) (driver.Conn, error) {
+	return iterRowsConn{}, nil
+}
+
+func (iterRowsConnector) Driver() driver.Driver {
+	return iterRowsDriver{}
+}
+
+type iterRowsDriver struct{}
+
+func (iterRowsDriver) Open(string) (driver.Conn, error) {
+	return iterRowsConn{}, nil
+}
+
+type iterRowsConn struct{}
+
+func (iterRowsConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, db.ErrUnsupported
+}
+
+func (iterRowsConn) Close() error {
+	return nil
+}
+
+func (iterRowsConn) Begin() (driver.Tx, error) {
+	return nil, db.ErrUnsupported
+}
+
+// CheckNamedValue lets iterRowsArg through as it is.
+func (iterRowsConn) CheckNamedValue(v *driver.NamedValue) error {
+	if _, ok := v.Value.(iterRowsArg); ok {
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (iterRowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 1 {
+		return nil, db.ErrUnsupported
+	}
+	arg, ok := args[0].Value.(iterRowsArg)
+	if !ok {
+		return nil, db.ErrUnsupported
+	}
+	return arg.rows, nil
+}
+
+type cachedRowsIter struct {
+	rows *cachedRows
+	pos  int
+}
+
+func (r *cachedRowsIter) Columns() []string {
+	return r.rows.Columns
+}
+
+func (r *cachedRowsIter) Close() error {
+	return nil
+}
+
+func (r *cachedRowsIter) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows.Values) {
+		return io.EOF
+	}
+	for i, value := range r.rows.Values[r.pos] {
+		dest[i] = value
+	}
+	r.pos++
+	return nil
+}
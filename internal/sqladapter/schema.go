@@ -0,0 +1,97 @@
+package sqladapter
+
+import (
+	"sort"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// hasDescribeTable allows the adapter to describe the structure of its
+// tables.
+type hasDescribeTable interface {
+	DescribeTable(name string) (*sqlbuilder.Table, error)
+}
+
+// Schema describes all the tables of the database, sorted by name.
+func (d *database) Schema() (*sqlbuilder.Schema, error) {
+	describer, ok := d.PartialDatabase.(hasDescribeTable)
+	if !ok {
+		return nil, db.ErrUnsupported
+	}
+
+	names, err := d.PartialDatabase.Collections()
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	schema := &sqlbuilder.Schema{
+		Tables: make([]sqlbuilder.Table, 0, len(names)),
+	}
+	for _, name := range names {
+		table, err := describer.DescribeTable(name)
+		if err != nil {
+			return nil, err
+		}
+		schema.Tables = append(schema.Tables, *table)
+	}
+
+	return schema, nil
+}
+
+// IndexColumn is a column of an index, adapters query one IndexColumn per
+// column of each index and group them with GroupIndexes.
+type IndexColumn struct {
+	Index  string `db:"index_name"`
+	Column string `db:"column_name"`
+	Unique bool   `db:"is_unique"`
+}
+
+// GroupIndexes groups the given columns by index, columns must be sorted by
+// index and by their position within the index.
+func GroupIndexes(columns []IndexColumn) []sqlbuilder.Index {
+	var indexes []sqlbuilder.Index
+	for _, column := range columns {
+		if n := len(indexes); n > 0 && indexes[n-1].Name == column.Index {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column.Column)
+			continue
+		}
+		indexes = append(indexes, sqlbuilder.Index{
+			Name:    column.Index,
+			Columns: []string{column.Column},
+			Unique:  column.Unique,
+		})
+	}
+	return indexes
+}
+
+// ForeignKeyColumn is a column of a foreign key constraint, adapters query one
+// ForeignKeyColumn per column of each constraint and group them with
+// GroupForeignKeys.
+type ForeignKeyColumn struct {
+	Constraint string `db:"constraint_name"`
+	Column     string `db:"column_name"`
+	RefTable   string `db:"ref_table"`
+	RefColumn  string `db:"ref_column"`
+}
+
+// GroupForeignKeys groups the given columns by constraint, columns must be
+// sorted by constraint and by their position within the constraint.
+func GroupForeignKeys(columns []ForeignKeyColumn) []sqlbuilder.ForeignKey {
+	var foreignKeys []sqlbuilder.ForeignKey
+	for _, column := range columns {
+		if n := len(foreignKeys); n > 0 && foreignKeys[n-1].Name == column.Constraint {
+			foreignKeys[n-1].Columns = append(foreignKeys[n-1].Columns, column.Column)
+			foreignKeys[n-1].RefColumns = append(foreignKeys[n-1].RefColumns, column.RefColumn)
+			continue
+		}
+		foreignKeys = append(foreignKeys, sqlbuilder.ForeignKey{
+			Name:       column.Constraint,
+			Columns:    []string{column.Column},
+			RefTable:   column.RefTable,
+			RefColumns: []string{column.RefColumn},
+		})
+	}
+	return foreignKeys
+}
@@ -0,0 +1,31 @@
+package sqladapter
+
+import (
+	"upper.io/db.v3"
+)
+
+// hasSequences allows the adapter to read the values of sequences.
+type hasSequences interface {
+	// NextSequenceValueQuery returns the statement that advances the given
+	// sequence and returns its new value.
+	NextSequenceValueQuery(name string) string
+}
+
+// NextSequenceValue advances the given sequence and returns its new value.
+func (d *database) NextSequenceValue(name string) (int64, error) {
+	sequences, ok := d.PartialDatabase.(hasSequences)
+	if !ok {
+		return 0, db.ErrUnsupported
+	}
+
+	row, err := d.PartialDatabase.QueryRowContext(d.Context(), sequences.NextSequenceValueQuery(name))
+	if err != nil {
+		return 0, err
+	}
+
+	var value int64
+	if err := row.Scan(&value); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
@@ -0,0 +1,85 @@
+package sqladapter
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+// explainTimeout limits how long getting the plan of a slow statement may
+// take.
+const explainTimeout = 5 * time.Second
+
+// logging returns true if statements are logged, either because logging is
+// enabled or because the session logs slow statements.
+func (d *database) logging() bool {
+	return d.Settings.LoggingEnabled() || d.SlowQueryThreshold() > 0
+}
+
+// logStatement sends status to the logger of the session. When the session
+// has a SlowQueryThreshold only the statements that take longer than it are
+// logged, along with their plan if the session explains them.
+func (d *database) logStatement(stmt *exql.Statement, status *db.QueryStatus) {
+	if threshold := d.SlowQueryThreshold(); threshold > 0 {
+		if status.Duration() < threshold {
+			return
+		}
+		status.Slow = true
+		if d.ExplainSlowQueries() && status.Err == nil && explainable(stmt, status.Query) {
+			status.Plan = d.explain(status.Query, status.Args)
+		}
+	}
+	d.Logger().Log(status)
+}
+
+// explainable returns true if the plan of the given statement can be
+// explained.
+func explainable(stmt *exql.Statement, query string) bool {
+	if stmt == nil {
+		return false
+	}
+	switch stmt.Type {
+	case exql.Select, exql.Count, exql.Update, exql.Delete:
+		return true
+	case exql.SQL:
+		query = strings.ToUpper(strings.TrimSpace(query))
+		return strings.HasPrefix(query, "SELECT") || strings.HasPrefix(query, "WITH")
+	}
+	return false
+}
+
+// explain returns the plan of the given query, or the error that prevented
+// getting it. Statements that run within transactions are not explained, they
+// may depend on changes other connections can't see.
+func (d *database) explain(query string, args []interface{}) string {
+	explainer, ok := d.PartialDatabase.(hasExplain)
+	if !ok || d.Transaction() != nil {
+		return ""
+	}
+
+	d.sessMu.RLock()
+	sess := d.sess
+	d.sessMu.RUnlock()
+	if sess == nil {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+	defer cancel()
+
+	rows, err := compat.QueryContext(sess, ctx, explainer.ExplainQuery(query), args)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	defer rows.Close()
+
+	plan, err := formatRows(rows)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return plan
+}
@@ -0,0 +1,91 @@
+package sqladapter
+
+import (
+	"context"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// deletedScope tells which items of a soft deleted result set are included.
+type deletedScope int
+
+const (
+	// withoutDeleted skips the soft deleted items, it's the default.
+	withoutDeleted deletedScope = iota
+	// withDeleted includes the soft deleted items, see Unscoped.
+	withDeleted
+	// onlyDeleted only includes the soft deleted items, see OnlyDeleted.
+	onlyDeleted
+)
+
+// SoftDelete makes the result set skip the items that have a value on the
+// given column, and Delete set it to the current time instead of removing
+// them.
+func (r *Result) SoftDelete(column string) db.Result {
+	return r.frame(func(res *result) error {
+		res.softDelete = column
+		return nil
+	})
+}
+
+// Unscoped returns a result set that includes the soft deleted items, calling
+// Delete on it removes them permanently.
+func (r *Result) Unscoped() db.Result {
+	return r.frame(func(res *result) error {
+		res.deleted = withDeleted
+		return nil
+	})
+}
+
+// OnlyDeleted returns a result set that only includes the soft deleted items.
+func (r *Result) OnlyDeleted() db.Result {
+	return r.frame(func(res *result) error {
+		res.deleted = onlyDeleted
+		return nil
+	})
+}
+
+// deletedConds returns the conditions that select the items of the soft
+// delete scope of the result set, they're kept when the conditions of the set
+// are replaced with Where.
+func (res *result) deletedConds() ([]interface{}, error) {
+	if res.softDelete == "" {
+		if res.deleted == onlyDeleted {
+			return nil, db.ErrUnsupported
+		}
+		return nil, nil
+	}
+	switch res.deleted {
+	case withDeleted:
+		return nil, nil
+	case onlyDeleted:
+		return []interface{}{db.Cond{res.softDelete: db.IsNotNull()}}, nil
+	}
+	return []interface{}{db.Cond{res.softDelete: db.IsNull()}}, nil
+}
+
+// buildSoftDelete returns the statement that soft deletes the items of the
+// result set, or nil if they must be removed.
+func (r *Result) buildSoftDelete(ctx context.Context) (sqlbuilder.Updater, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	res, err := r.fastForward()
+	if err != nil {
+		return nil, err
+	}
+	if res.softDelete == "" || res.deleted == withDeleted {
+		return nil, nil
+	}
+
+	now := time.Now
+	if sess, ok := r.SQLBuilder().(interface {
+		Clock() func() time.Time
+	}); ok {
+		now = sess.Clock()
+	}
+	return r.buildUpdate(ctx, map[string]interface{}{res.softDelete: now()})
+}
@@ -24,21 +24,105 @@ package sqladapter
 
 import (
 	"database/sql/driver"
+	"reflect"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/exql"
 )
 
 // IsKeyValue reports whether v is a valid value for a primary key that can be
-// used with Find(pKey).
+// used with Find(pKey). Strings are key values only if they're UUIDs.
 func IsKeyValue(v interface{}) bool {
 	if v == nil {
 		return true
 	}
-	switch v.(type) {
+	switch t := v.(type) {
 	case int64, int, uint, uint64,
 		[]int64, []int, []uint, []uint64,
 		[]byte, []string,
 		[]interface{},
 		driver.Valuer:
 		return true
+	case string:
+		_, err := db.ParseUUID(t)
+		return err == nil
+	}
+	return isUUIDArray(reflect.TypeOf(v))
+}
+
+// isUUIDArray reports whether t is a 16-byte array, like db.UUID.
+func isUUIDArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Len() == 16 && t.Elem().Kind() == reflect.Uint8
+}
+
+// keyValue returns the value the given key is bound as, 16-byte arrays that
+// can't be bound by themselves are bound as a db.UUID.
+func keyValue(v interface{}) interface{} {
+	if _, ok := v.(driver.Valuer); ok || v == nil {
+		return v
+	}
+	if t := reflect.TypeOf(v); isUUIDArray(t) {
+		var u db.UUID
+		reflect.Copy(reflect.ValueOf(&u).Elem(), reflect.ValueOf(v))
+		return u
+	}
+	return v
+}
+
+// ReturnedKey returns the value of a key read back after an insert, UUIDs
+// that drivers read as text are returned as a db.UUID so they can be bound to
+// Find(id).
+func ReturnedKey(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		if u, err := db.ParseUUID(string(b)); err == nil {
+			return u
+		}
+	}
+	return v
+}
+
+// InsertedKey returns the value given to the only primary key of a table
+// among the columns of an insert, like a client-side generated UUID or a key
+// of an ID generator, integers are returned as int64. It returns false if the
+// table has no single key or if the value was left to the default of the
+// column, zero integers are read with LastInsertId().
+func InsertedKey(pKey []string, columnNames []string, columnValues []interface{}) (interface{}, bool) {
+	if len(pKey) != 1 {
+		return nil, false
+	}
+	for i := range columnNames {
+		if columnNames[i] != pKey[0] {
+			continue
+		}
+		switch columnValues[i].(type) {
+		case nil, *exql.Raw, exql.Raw, db.RawValue:
+			return nil, false
+		}
+		v := reflect.ValueOf(columnValues[i])
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return v.Int(), v.Int() != 0
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(v.Uint()), v.Uint() != 0
+		}
+		return columnValues[i], true
+	}
+	return nil, false
+}
+
+// IsExample reports whether v is a struct or a pointer to struct that can be
+// used with Find(&item) to match rows by the non-zero values of its fields.
+func IsExample(v interface{}) bool {
+	switch v.(type) {
+	case db.Constraints, db.Compound, db.RawValue, db.Function, db.Marshaler, driver.Valuer:
+		return false
+	}
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
-	return false
+	return t.Kind() == reflect.Struct && t.PkgPath() != "time"
 }
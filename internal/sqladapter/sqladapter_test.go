@@ -1,9 +1,25 @@
 package sqladapter
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
 )
 
 func TestReplaceWithDollarSign(t *testing.T) {
@@ -37,3 +53,2656 @@ func TestReplaceWithDollarSign(t *testing.T) {
 		assert.Equal(t, test.out, ReplaceWithDollarSign(test.in))
 	}
 }
+
+func TestReplaceWithBindVariables(t *testing.T) {
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{
+			`SELECT ?`,
+			`SELECT :1`,
+		},
+		{
+			`SELECT ? FROM ? WHERE ?`,
+			`SELECT :1 FROM :2 WHERE :3`,
+		},
+		{
+			`SELECT ??? FROM ? WHERE ??`,
+			`SELECT ?:1 FROM :2 WHERE ?`,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.out, ReplaceWithBindVariables(test.in))
+	}
+}
+
+func TestIsExample(t *testing.T) {
+	type item struct {
+		Name string `db:"name"`
+	}
+
+	assert.True(t, IsExample(item{}))
+	assert.True(t, IsExample(&item{}))
+
+	assert.False(t, IsExample(nil))
+	assert.False(t, IsExample(1))
+	assert.False(t, IsExample(db.Cond{"name": "Flea"}))
+	assert.False(t, IsExample(db.And(db.Cond{"name": "Flea"})))
+	assert.False(t, IsExample(db.Raw("name = ?", "Flea")))
+	assert.False(t, IsExample(time.Now()))
+}
+
+func TestIsKeyValue(t *testing.T) {
+	u := db.UUID{1}
+
+	assert.True(t, IsKeyValue(1))
+	assert.True(t, IsKeyValue(u))
+	assert.True(t, IsKeyValue([16]byte(u)))
+	assert.True(t, IsKeyValue("0189d4a2-7b3c-7def-8a01-23456789abcd"))
+
+	assert.False(t, IsKeyValue("name = 'Flea'"))
+	assert.False(t, IsKeyValue([4]byte{}))
+}
+
+func TestInsertedKey(t *testing.T) {
+	u := db.UUID{1}
+
+	id, ok := InsertedKey([]string{"id"}, []string{"id", "name"}, []interface{}{u, "Flea"})
+	assert.True(t, ok)
+	assert.Equal(t, u, id)
+
+	id, ok = InsertedKey([]string{"id"}, []string{"id", "name"}, []interface{}{uint(3), "Flea"})
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), id)
+
+	_, ok = InsertedKey([]string{"id"}, []string{"id", "name"}, []interface{}{0, "Flea"})
+	assert.False(t, ok)
+
+	_, ok = InsertedKey([]string{"id"}, []string{"name"}, []interface{}{"Flea"})
+	assert.False(t, ok)
+
+	_, ok = InsertedKey([]string{"org_id", "user_id"}, []string{"org_id", "user_id"}, []interface{}{u, u})
+	assert.False(t, ok)
+
+	assert.Equal(t, u, ReturnedKey([]byte(u.String())))
+	assert.Equal(t, int64(1), ReturnedKey(int64(1)))
+}
+
+type fakePartialCollection struct {
+	PartialCollection
+}
+
+func (fakePartialCollection) Name() string { return "members" }
+
+func TestKeyCond(t *testing.T) {
+	c := &collection{
+		PartialCollection: fakePartialCollection{},
+		pk:                []string{"org_id", "user_id"},
+	}
+
+	cond, err := c.keyCond(db.ID{"org_id": 1, "user_id": 2})
+	assert.NoError(t, err)
+	assert.Equal(t, db.Cond{"org_id": db.Eq(1), "user_id": db.Eq(2)}, cond)
+
+	cond, err = c.keyCond(db.Cond{"org_id": 1, "user_id": 2})
+	assert.NoError(t, err)
+	assert.Equal(t, db.Cond{"org_id": db.Eq(1), "user_id": db.Eq(2)}, cond)
+
+	_, err = c.keyCond(db.ID{"org_id": 1})
+	assert.True(t, errors.Is(err, errMissingPrimaryKeyValue))
+	assert.Equal(t, `ID has no value for primary key "user_id"`, err.Error())
+
+	_, err = c.keyCond(db.ID{"org_id": 1, "user_id": 2, "name": "Flea"})
+	assert.True(t, errors.Is(err, errUnknownPrimaryKey))
+	assert.Equal(t, `ID has a value for a column that is not a primary key: "name"`, err.Error())
+
+	_, err = c.keyCond(1)
+	assert.True(t, errors.Is(err, errExpectingCompositeID))
+	assert.Equal(t, `Table has a composite primary key, expecting a db.ID: table "members", got int`, err.Error())
+
+	c.pk = []string{"id"}
+
+	cond, err = c.keyCond("a6b4")
+	assert.NoError(t, err)
+	assert.Equal(t, db.Cond{"id": db.Eq("a6b4")}, cond)
+
+	cond, err = c.keyCond([16]byte{1})
+	assert.NoError(t, err)
+	assert.Equal(t, db.Cond{"id": db.Eq(db.UUID{1})}, cond)
+}
+
+type fakeIDCollection struct {
+	fakePartialCollection
+	d Database
+}
+
+func (c fakeIDCollection) Database() Database { return c.d }
+
+func TestAssignID(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+	c := &collection{
+		PartialCollection: fakeIDCollection{d: d},
+		pk:                []string{"id"},
+	}
+	ctx := context.Background()
+
+	columns, values, err := c.AssignID(ctx, []string{"name"}, []interface{}{"Flea"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name"}, columns)
+	assert.Equal(t, []interface{}{"Flea"}, values)
+
+	var next int64
+	generators := db.NewIDGenerators()
+	generators.Register("members", db.IDGeneratorFunc(func(table string) (interface{}, error) {
+		next++
+		return next, nil
+	}))
+	d.SetIDGenerators(generators)
+
+	columns, values, err = c.AssignID(ctx, []string{"name"}, []interface{}{"Flea"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "id"}, columns)
+	assert.Equal(t, []interface{}{"Flea", int64(1)}, values)
+
+	given := []interface{}{0, "Flea"}
+	_, values, err = c.AssignID(ctx, []string{"id", "name"}, given)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(2), "Flea"}, values)
+	assert.Equal(t, []interface{}{0, "Flea"}, given)
+
+	_, values, err = c.AssignID(ctx, []string{"id", "name"}, []interface{}{7, "Flea"})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{7, "Flea"}, values)
+
+	columns, values, err = c.InsertValues(ctx, map[string]interface{}{"name": "Flea"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "id"}, columns)
+	assert.Equal(t, []interface{}{"Flea", int64(3)}, values)
+
+	// Each row of a bulk insert is given its own ID.
+	columns, rows, err := c.BulkRows(ctx, []interface{}{
+		map[string]interface{}{"name": "Flea"},
+		map[string]interface{}{"name": "Slash"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "id"}, columns)
+	assert.Equal(t, [][]interface{}{{"Flea", int64(4)}, {"Slash", int64(5)}}, rows)
+
+	c.pk = []string{"org_id", "user_id"}
+	columns, _, err = c.AssignID(ctx, []string{"name"}, []interface{}{"Flea"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name"}, columns)
+}
+
+func TestTenantScope(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+	c := &collection{
+		PartialCollection: fakeIDCollection{d: d},
+		pk:                []string{"id"},
+	}
+
+	tenancy := db.NewTenancy("")
+	d.SetTenancy(tenancy)
+	ctx := context.Background()
+
+	// The table is not scoped yet.
+	columns, values, err := c.AssignID(ctx, []string{"name"}, []interface{}{"Flea"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name"}, columns)
+	assert.Equal(t, []interface{}{"Flea"}, values)
+
+	tenancy.Register("members")
+
+	_, _, err = c.AssignID(ctx, []string{"name"}, []interface{}{"Flea"})
+	assert.Equal(t, db.ErrMissingTenant, err)
+	_, _, _, err = d.TableScope(ctx, "members")
+	assert.Equal(t, db.ErrMissingTenant, err)
+
+	// The tenant can be given to each statement.
+	columns, values, err = c.AssignID(db.WithTenant(ctx, 9), []string{"name"}, []interface{}{"Flea"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "tenant_id"}, columns)
+	assert.Equal(t, []interface{}{"Flea", 9}, values)
+
+	_, tenant, scoped, err := d.TableScope(db.WithTenant(ctx, 9), "members")
+	assert.NoError(t, err)
+	assert.True(t, scoped)
+	assert.Equal(t, 9, tenant)
+
+	d.ctx = db.WithTenant(context.Background(), 7)
+
+	columns, values, err = c.AssignID(ctx, []string{"name"}, []interface{}{"Flea"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"name", "tenant_id"}, columns)
+	assert.Equal(t, []interface{}{"Flea", 7}, values)
+
+	given := []interface{}{8, "Flea"}
+	_, values, err = c.AssignID(ctx, []string{"tenant_id", "name"}, given)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{7, "Flea"}, values)
+	assert.Equal(t, []interface{}{8, "Flea"}, given)
+
+	// The SQL builder restricts the statements on the table to the tenant.
+	column, tenant, scoped, err := d.TableScope(ctx, "members")
+	assert.NoError(t, err)
+	assert.True(t, scoped)
+	assert.Equal(t, "tenant_id", column)
+	assert.Equal(t, 7, tenant)
+
+	// The tenant of the statement takes precedence over the one of the
+	// session.
+	_, tenant, _, err = d.TableScope(db.WithTenant(ctx, 9), "members")
+	assert.NoError(t, err)
+	assert.Equal(t, 9, tenant)
+
+	// TRUNCATE would delete the rows of every tenant.
+	assert.Equal(t, db.ErrUnsupported, c.TruncateContext(ctx, db.TruncateCascade))
+
+	_, _, scoped, err = d.TableScope(ctx, "artists")
+	assert.NoError(t, err)
+	assert.False(t, scoped)
+
+	d.ctx = db.WithoutTenant(context.Background())
+
+	_, _, scoped, err = d.TableScope(ctx, "members")
+	assert.NoError(t, err)
+	assert.False(t, scoped)
+	assert.NoError(t, c.Find().Err())
+}
+
+func TestScopes(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+	c := &collection{
+		PartialCollection: fakeIDCollection{d: d},
+		pk:                []string{"id"},
+	}
+
+	scopes := db.NewScopes()
+	scopes.Default("members", func(res db.Result) db.Result {
+		return res.And(db.Cond{"status !=": "archived"})
+	})
+	scopes.Register("", "recent", func(res db.Result) db.Result {
+		return res.OrderBy("-created_at").Limit(10)
+	})
+	d.SetScopes(scopes)
+
+	res, err := c.Find(db.Cond{"id": 1}).Scope("recent").(*Result).fastForward()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{db.Cond{"id": 1}},
+		{db.Cond{"status !=": "archived"}},
+	}, res.conds)
+	assert.Equal(t, []interface{}{"-created_at"}, res.orderBy)
+	assert.Equal(t, 10, res.limit)
+
+	// Replacing the conditions keeps the ones of the default scopes.
+	res, err = c.Find(db.Cond{"id": 1}).Where(db.Cond{"id": 2}).(*Result).fastForward()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{db.Cond{"id": 2}},
+		{db.Cond{"status !=": "archived"}},
+	}, res.conds)
+
+	_, err = c.Find().Scope("missing").(*Result).fastForward()
+	assert.True(t, errors.Is(err, db.ErrUnknownScope))
+	assert.Equal(t, `upper: unknown scope: "missing"`, err.Error())
+}
+
+func TestSoftDelete(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+	c := &collection{
+		PartialCollection: fakeIDCollection{d: d},
+		pk:                []string{"id"},
+	}
+
+	softDeletes := db.NewSoftDeletes()
+	softDeletes.Register("members", "deleted_at")
+	d.SetSoftDeletes(softDeletes)
+
+	// Replacing the conditions keeps the soft delete scope.
+	res, err := c.Find(db.Cond{"id": 1}).Where(db.Cond{"id": 2}).(*Result).fastForward()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{db.Cond{"id": 2}},
+		{db.Cond{"deleted_at": db.IsNull()}},
+	}, res.conds)
+
+	res, err = c.Find(db.Cond{"id": 1}).Unscoped().(*Result).fastForward()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{db.Cond{"id": 1}},
+	}, res.conds)
+
+	res, err = c.Find(db.Cond{"id": 1}).OnlyDeleted().(*Result).fastForward()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{db.Cond{"id": 1}},
+		{db.Cond{"deleted_at": db.IsNotNull()}},
+	}, res.conds)
+
+	softDeletes.Register("members", "")
+
+	res, err = c.Find(db.Cond{"id": 1}).(*Result).fastForward()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{db.Cond{"id": 1}},
+	}, res.conds)
+
+	res, err = c.Find(db.Cond{"id": 1}).SoftDelete("removed_at").(*Result).fastForward()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{
+		{db.Cond{"id": 1}},
+		{db.Cond{"removed_at": db.IsNull()}},
+	}, res.conds)
+
+	_, err = c.Find().OnlyDeleted().(*Result).fastForward()
+	assert.Equal(t, db.ErrUnsupported, err)
+}
+
+func TestFindByIDs(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+	c := &collection{
+		PartialCollection: fakeIDCollection{d: d},
+		pk:                []string{"id"},
+	}
+
+	res := c.FindByIDs([]int{1, 2, 3, 4, 5}, 2).And(db.Cond{"status": "active"}).(*Result)
+	chunks, err := res.chunks()
+	assert.NoError(t, err)
+	if assert.Equal(t, 3, len(chunks)) {
+		for i, ids := range [][]int{{1, 2}, {3, 4}, {5}} {
+			chunk, err := chunks[i].fastForward()
+			assert.NoError(t, err)
+			assert.Equal(t, [][]interface{}{
+				{db.Cond{"id": db.In(ids)}},
+				{db.Cond{"status": "active"}},
+			}, chunk.conds)
+		}
+	}
+
+	// The conditions of the result set itself are left as they are.
+	ff, err := res.fastForward()
+	assert.NoError(t, err)
+	_, ok := ff.conds[0][0].(db.Cond)["id"].(db.ChunkedComparison)
+	assert.True(t, ok)
+
+	// Results of many chunks can't be iterated, paginated or aggregated.
+	assert.Equal(t, db.ErrUnsupported, res.unchunked())
+	assert.False(t, res.Next(&struct{}{}))
+	assert.Equal(t, db.ErrUnsupported, res.Err())
+	_, err = c.FindByIDs([]int{1, 2, 3}, 2).Sum("amount")
+	assert.Equal(t, db.ErrUnsupported, err)
+	_, err = c.FindByIDs([]int{1, 2, 3}, 2).TotalEntries()
+	assert.Equal(t, db.ErrUnsupported, err)
+	assert.Equal(t, db.ErrUnsupported, c.FindByIDs([]int{1, 2, 3}, 2).Paginate(10).One(&struct{}{}))
+	assert.NoError(t, c.FindByIDs([]int{1, 2}, 2).(*Result).unchunked())
+
+	// Results without InChunks are not split.
+	res = c.Find(db.Cond{"id": db.In([]int{1, 2, 3})}).(*Result)
+	chunks, err = res.chunks()
+	assert.NoError(t, err)
+	assert.Equal(t, []*Result{res}, chunks)
+
+	c.pk = []string{"id", "code"}
+	assert.Equal(t, db.ErrUnsupported, c.FindByIDs([]int{1}, 0).Err())
+
+	c.pk = nil
+	assert.True(t, errors.Is(c.FindByIDs([]int{1}, 0).Err(), errMissingPrimaryKeys))
+}
+
+func TestNextSequenceValue(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+	_, err := d.NextSequenceValue("account_ids")
+	assert.Equal(t, db.ErrUnsupported, err)
+}
+
+type hookedItem struct {
+	calls    []string
+	err      error
+	afterErr error
+}
+
+func (h *hookedItem) BeforeInsert(db.Database) error {
+	h.calls = append(h.calls, "BeforeInsert")
+	return h.err
+}
+
+func (h *hookedItem) AfterInsert(db.Database) error {
+	h.calls = append(h.calls, "AfterInsert")
+	return h.afterErr
+}
+
+func (h *hookedItem) BeforeUpdate(db.Database) error {
+	h.calls = append(h.calls, "BeforeUpdate")
+	return h.err
+}
+
+func (h *hookedItem) AfterUpdate(db.Database) error {
+	h.calls = append(h.calls, "AfterUpdate")
+	return nil
+}
+
+func (h *hookedItem) BeforeDelete(db.Database) error {
+	h.calls = append(h.calls, "BeforeDelete")
+	return h.err
+}
+
+func (h *hookedItem) AfterDelete(db.Database) error {
+	h.calls = append(h.calls, "AfterDelete")
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	item := &hookedItem{}
+
+	id, err := InsertWithHooks(context.Background(), nil, "items", item, func() (interface{}, error) {
+		item.calls = append(item.calls, "insert")
+		return int64(1), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	err = UpdateWithHooks(nil, item, func() error {
+		item.calls = append(item.calls, "update")
+		return nil
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"BeforeInsert", "insert", "AfterInsert", "BeforeUpdate", "update", "AfterUpdate"}, item.calls)
+
+	errInvalid := errors.New("invalid item")
+	item = &hookedItem{err: errInvalid}
+
+	_, err = InsertWithHooks(context.Background(), nil, "items", item, func() (interface{}, error) {
+		item.calls = append(item.calls, "insert")
+		return nil, nil
+	})
+	assert.Equal(t, errInvalid, err)
+
+	err = UpdateWithHooks(nil, item, func() error {
+		item.calls = append(item.calls, "update")
+		return nil
+	})
+	assert.Equal(t, errInvalid, err)
+
+	assert.Equal(t, []string{"BeforeInsert", "BeforeUpdate"}, item.calls)
+
+	err = UpdateWithHooks(nil, hookedItem{}, func() error {
+		return errDeadlock
+	})
+	assert.Equal(t, errDeadlock, err)
+
+	// The ID of an item that's already inserted is not lost.
+	item = &hookedItem{afterErr: errInvalid}
+	id, err = InsertWithHooks(context.Background(), nil, "items", item, func() (interface{}, error) {
+		return int64(2), nil
+	})
+	assert.Equal(t, errInvalid, err)
+	assert.Equal(t, int64(2), id)
+}
+
+func TestDeleteHooks(t *testing.T) {
+	assert.True(t, hasDeleteHooks(reflect.TypeOf(hookedItem{})))
+	assert.True(t, hasDeleteHooks(reflect.TypeOf(&hookedItem{})))
+	assert.False(t, hasDeleteHooks(reflect.TypeOf(struct{}{})))
+
+	var deleted []string
+	items := []hookedItem{{}, {}}
+	err := DeleteWithHooks(nil, &items, func() error {
+		deleted = append(deleted, "delete")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"delete"}, deleted)
+	for i := range items {
+		assert.Equal(t, []string{"BeforeDelete", "AfterDelete"}, items[i].calls)
+	}
+
+	errInvalid := errors.New("invalid item")
+	ptrs := []*hookedItem{{}, {err: errInvalid}}
+	err = DeleteWithHooks(nil, &ptrs, func() error {
+		t.Fatal("items must not be deleted if a BeforeDelete hook fails")
+		return nil
+	})
+	assert.Equal(t, errInvalid, err)
+	assert.Equal(t, []string{"BeforeDelete"}, ptrs[1].calls)
+
+	err = DeleteWithHooks(nil, &items, func() error {
+		return errDeadlock
+	})
+	assert.Equal(t, errDeadlock, err)
+
+	err = DeleteWithHooks(nil, items, func() error { return nil })
+	assert.Equal(t, sqlbuilder.ErrExpectingSlicePointer, err)
+}
+
+// fakeResultTxStarter begins transactions that record how they end.
+type fakeResultTxStarter struct {
+	sqlbuilder.SQLBuilder
+	tx *fakeResultTx
+}
+
+func (s *fakeResultTxStarter) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	s.tx = &fakeResultTx{}
+	return s.tx, nil
+}
+
+type fakeResultTx struct {
+	txSession
+	committed  bool
+	rolledBack bool
+}
+
+func (tx *fakeResultTx) Commit() error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeResultTx) Rollback() error {
+	tx.rolledBack = true
+	return nil
+}
+
+func (tx *fakeResultTx) Close() error {
+	return nil
+}
+
+func TestResultInTx(t *testing.T) {
+	ctx := context.Background()
+	starter := &fakeResultTxStarter{}
+	r := NewResult(starter, "members", []interface{}{db.Cond{"id": 1}})
+
+	// The result set is bound to a new transaction.
+	err := r.inTx(ctx, func(tx *Result) error {
+		assert.Equal(t, sqlbuilder.SQLBuilder(starter.tx), tx.SQLBuilder())
+		res, err := tx.fastForward()
+		assert.NoError(t, err)
+		assert.Equal(t, "members", res.table)
+		assert.Equal(t, [][]interface{}{{db.Cond{"id": 1}}}, res.conds)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, starter.tx.committed)
+
+	err = r.inTx(ctx, func(tx *Result) error {
+		return errDeadlock
+	})
+	assert.Equal(t, errDeadlock, err)
+	assert.True(t, starter.tx.rolledBack)
+	assert.False(t, starter.tx.committed)
+
+	// The errors of the result set are returned before beginning one.
+	r.setErr(errDeadlock)
+	starter.tx = nil
+	err = r.inTx(ctx, func(tx *Result) error {
+		t.Fatal("fn must not run if the result set has an error")
+		return nil
+	})
+	assert.Equal(t, errDeadlock, err)
+	assert.Nil(t, starter.tx)
+}
+
+func TestChangeListener(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+
+	var events []*db.ChangeEvent
+	d.SetChangeListener(db.ChangeListenerFunc(func(ev *db.ChangeEvent) {
+		events = append(events, ev)
+	}))
+
+	item := &hookedItem{}
+	_, err := InsertWithHooks(context.Background(), d, "items", item, func() (interface{}, error) {
+		return int64(1), nil
+	})
+	assert.NoError(t, err)
+
+	_, err = InsertWithHooks(context.Background(), d, "items", item, func() (interface{}, error) {
+		return nil, errDeadlock
+	})
+	assert.Equal(t, errDeadlock, err)
+
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, "items", events[0].Collection)
+		assert.Equal(t, db.ChangeInsert, events[0].Operation)
+		assert.Equal(t, int64(1), events[0].Key)
+		assert.Equal(t, item, events[0].After)
+		assert.Equal(t, d, events[0].Session)
+	}
+
+	// Updates and deletes carry the conditions of the result set, and the
+	// primary key it was found by.
+	events = nil
+	res := NewResult(d, "items", []interface{}{db.Cond{"id": db.Eq(int64(1))}}).byKey(int64(1))
+	res.notifyChange(context.Background(), db.ChangeDelete, nil, nil)
+	res.Where(db.Cond{"name": "Joe"}).(*Result).notifyChange(context.Background(), db.ChangeUpdate, nil, nil)
+	if assert.Equal(t, 2, len(events)) {
+		assert.Equal(t, db.ChangeDelete, events[0].Operation)
+		assert.Equal(t, int64(1), events[0].Key)
+		assert.Equal(t, []interface{}{db.Cond{"id": db.Eq(int64(1))}}, events[0].Conditions)
+
+		assert.Equal(t, db.ChangeUpdate, events[1].Operation)
+		assert.Nil(t, events[1].Key)
+		assert.Equal(t, []interface{}{db.Cond{"name": "Joe"}}, events[1].Conditions)
+	}
+
+	d.SetChangeListener(nil)
+	_, err = InsertWithHooks(context.Background(), d, "items", item, func() (interface{}, error) {
+		return int64(2), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(events))
+}
+
+func TestGroupIndexes(t *testing.T) {
+	indexes := GroupIndexes([]IndexColumn{
+		{"idx_name", "name", false},
+		{"idx_org_email", "org_id", true},
+		{"idx_org_email", "email", true},
+	})
+	assert.Equal(t, []sqlbuilder.Index{
+		{Name: "idx_name", Columns: []string{"name"}},
+		{Name: "idx_org_email", Columns: []string{"org_id", "email"}, Unique: true},
+	}, indexes)
+
+	foreignKeys := GroupForeignKeys([]ForeignKeyColumn{
+		{"fk_member", "org_id", "members", "org_id"},
+		{"fk_member", "user_id", "members", "user_id"},
+		{"fk_publication", "publication_id", "publication", "id"},
+	})
+	assert.Equal(t, []sqlbuilder.ForeignKey{
+		{Name: "fk_member", Columns: []string{"org_id", "user_id"}, RefTable: "members", RefColumns: []string{"org_id", "user_id"}},
+		{Name: "fk_publication", Columns: []string{"publication_id"}, RefTable: "publication", RefColumns: []string{"id"}},
+	}, foreignKeys)
+}
+
+func TestEncodeArgs(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+
+	codecs := db.NewCodecs()
+	codecs.Register(point{}, db.Codec{
+		Encode: func(v interface{}) (interface{}, error) {
+			p := v.(point)
+			if p.X < 0 {
+				return nil, errors.New("negative point")
+			}
+			return fmt.Sprintf("%d,%d", p.X, p.Y), nil
+		},
+	})
+
+	args := []interface{}{1, point{1, 2}, &point{3, 4}, (*point)(nil), nil}
+	assert.Equal(t, args, encodeArgs(nil, args))
+	assert.Equal(t, []interface{}{1, "1,2", "3,4", nil, nil}, encodeArgs(codecs, args))
+	assert.Equal(t, point{1, 2}, args[1])
+
+	plain := []interface{}{1, "a"}
+	assert.Equal(t, plain, encodeArgs(codecs, plain))
+
+	encoded := encodeArgs(codecs, []interface{}{point{-1, 0}})
+	_, err := encoded[0].(driver.Valuer).Value()
+	assert.Error(t, err)
+	assert.Equal(t, "negative point", err.Error())
+
+	// Codecs without Encode leave values as they are.
+	type label string
+	codecs.Register(label(""), db.Codec{})
+	assert.Equal(t, []interface{}{label("a"), (*label)(nil)}, encodeArgs(codecs, []interface{}{label("a"), (*label)(nil)}))
+}
+
+func TestBulkRows(t *testing.T) {
+	type book struct {
+		ID        int64     `db:"id,omitempty"`
+		Title     string    `db:"title"`
+		Pages     int       `db:"pages,omitempty"`
+		CreatedAt time.Time `db:"created_at,auto_now_add"`
+	}
+
+	now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	columns, rows, err := BulkRows([]interface{}{
+		book{Title: "Rayuela", Pages: 600},
+		&book{Title: "Ficciones", Pages: 200},
+	}, now, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"created_at", "pages", "title"}, columns)
+	assert.Equal(t, [][]interface{}{
+		{now, 600, "Rayuela"},
+		{now, 200, "Ficciones"},
+	}, rows)
+
+	_, _, err = BulkRows([]interface{}{
+		book{Title: "Rayuela", Pages: 600},
+		book{Title: "Ficciones"},
+	}, now, nil, nil)
+	assert.Error(t, err)
+	assert.Equal(t, `Column "pages" has a default value on some items only`, err.Error())
+
+	_, _, err = BulkRows([]interface{}{
+		map[string]interface{}{"title": "Rayuela"},
+		map[string]interface{}{"name": "Ficciones"},
+	}, now, nil, nil)
+	assert.Error(t, err)
+
+	{
+		type page struct {
+			BookID     int64
+			PageNumber int `col:"number"`
+		}
+		columns, rows, err := BulkRows([]interface{}{
+			page{BookID: 1, PageNumber: 7},
+		}, now, &sqlbuilder.MapOptions{Mapper: fieldMapper(&db.FieldMapping{Tag: "col", NameMapper: db.SnakeCase})}, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"book_id", "number"}, columns)
+		assert.Equal(t, [][]interface{}{{int64(1), 7}}, rows)
+	}
+}
+
+func TestFieldMapper(t *testing.T) {
+	f := &fieldMappers{}
+	assert.True(t, f.get(nil) == mapper)
+
+	fm := &db.FieldMapping{NameMapper: db.CamelCase}
+	m := f.get(fm)
+	assert.True(t, m == f.get(fm))
+	assert.Equal(t, "db", m.TagName())
+
+	type artist struct {
+		ArtistID int64
+		Name     string `db:"artist_name"`
+	}
+	names := m.TypeMap(reflect.TypeOf(artist{})).Names
+	for _, name := range []string{"artistID", "artist_name"} {
+		_, ok := names[name]
+		assert.True(t, ok, name)
+	}
+
+	// Only the mapper of the current mapping is kept.
+	other := &db.FieldMapping{Tag: "col"}
+	assert.Equal(t, "col", f.get(other).TagName())
+	assert.True(t, f.mapping == other)
+	assert.False(t, m == f.get(fm))
+
+	// Clones share the mappers of their session.
+	d := NewBaseDatabase(fakeCompiler{}).(*database)
+	d.SetFieldMapping(fm)
+	clone, err := d.NewClone(fakeCompiler{}, false)
+	assert.NoError(t, err)
+	assert.True(t, d.Mapper() == clone.(*database).Mapper())
+}
+
+var errDeadlock = errors.New("deadlock detected")
+
+// txSession is embedded by fakeTx, sqlbuilder.Tx can't be embedded directly
+// because it has a Tx method.
+type txSession interface {
+	sqlbuilder.Tx
+}
+
+type fakeTx struct {
+	txSession
+}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+func (fakeTx) Close() error    { return nil }
+
+type fakeTxStarter struct {
+	attempts int
+}
+
+func (f *fakeTxStarter) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	f.attempts++
+	return fakeTx{}, nil
+}
+
+func (f *fakeTxStarter) Err(err error) error {
+	if err == errDeadlock {
+		return db.ErrSerializationFailure
+	}
+	return err
+}
+
+func TestTxWithRetry(t *testing.T) {
+	policy := sqlbuilder.RetryPolicy{MaxRetries: 3}
+
+	failures := func(n int, err error) func(sqlbuilder.Tx) error {
+		return func(sqlbuilder.Tx) error {
+			if n > 0 {
+				n--
+				return err
+			}
+			return nil
+		}
+	}
+
+	{
+		d := &fakeTxStarter{}
+		err := TxWithRetry(d, context.Background(), failures(2, errDeadlock), policy)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, d.attempts)
+	}
+
+	{
+		d := &fakeTxStarter{}
+		err := TxWithRetry(d, context.Background(), failures(10, errDeadlock), policy)
+		assert.Equal(t, errDeadlock, err)
+		assert.Equal(t, 4, d.attempts)
+	}
+
+	{
+		d := &fakeTxStarter{}
+		qErr := &db.QueryError{Query: "UPDATE accounts SET balance = 0", Err: db.ErrSerializationFailure}
+		err := TxWithRetry(d, context.Background(), failures(2, qErr), policy)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, d.attempts)
+	}
+
+	{
+		d := &fakeTxStarter{}
+		wrapped := fmt.Errorf("transfer: %w", &db.QueryError{Err: errDeadlock})
+		err := TxWithRetry(d, context.Background(), failures(2, wrapped), policy)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, d.attempts)
+	}
+
+	{
+		d := &fakeTxStarter{}
+		errOther := errors.New("unique constraint")
+		err := TxWithRetry(d, context.Background(), failures(2, errOther), policy)
+		assert.Equal(t, errOther, err)
+		assert.Equal(t, 1, d.attempts)
+	}
+
+	{
+		d := &fakeTxStarter{}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := TxWithRetry(d, ctx, failures(2, errDeadlock), sqlbuilder.DefaultRetryPolicy)
+		assert.Equal(t, context.Canceled, err)
+		assert.Equal(t, 1, d.attempts)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := sqlbuilder.RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	for retry, max := range []time.Duration{10, 20, 40, 50, 50} {
+		max = max * time.Millisecond
+		backoff := RetryBackoff(policy, retry)
+		assert.True(t, backoff >= max/2 && backoff <= max)
+	}
+
+	// A policy with no InitialBackoff still waits between retries.
+	for retry := 0; retry < 100; retry++ {
+		assert.True(t, RetryBackoff(sqlbuilder.RetryPolicy{}, retry) > 0)
+	}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+
+func init() {
+	sql.Register("sqladapter_fake", fakeDriver{})
+}
+
+func TestReplicaRouting(t *testing.T) {
+	sessions := make([]*sql.DB, 3)
+	for i := range sessions {
+		sess, err := sql.Open("sqladapter_fake", "")
+		assert.NoError(t, err)
+		sessions[i] = sess
+	}
+
+	d := &database{Settings: db.NewSettings(), replicas: &replicaSet{sessions: sessions, policy: sqlbuilder.RoundRobin}}
+
+	selectStmt := &exql.Statement{Type: exql.Select}
+	for i := 0; i < 6; i++ {
+		assert.True(t, sessions[i%3] == d.replica(selectStmt))
+	}
+
+	assert.Nil(t, d.replica(&exql.Statement{Type: exql.Insert}))
+	assert.Nil(t, d.replica(&exql.Statement{Type: exql.SQL}))
+
+	d.replicas.policy = sqlbuilder.LeastLoaded
+	assert.True(t, sessions[0] == d.replica(&exql.Statement{Type: exql.Count}))
+
+	// Replicas can be replaced while statements pick them.
+	replacement, err := sql.Open("sqladapter_fake_tx", "")
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			d.replica(selectStmt)
+		}
+	}()
+	assert.NoError(t, d.BindReplicas(sqlbuilder.RoundRobin, replacement))
+	wg.Wait()
+	assert.True(t, replacement == d.replica(selectStmt))
+
+	d.UsePrimary()
+	assert.Nil(t, d.replica(selectStmt))
+}
+
+func TestMiddleware(t *testing.T) {
+	m := &middleware{}
+	assert.True(t, m.empty())
+
+	calls := []string{}
+	tag := func(name string) func(sqlbuilder.StatementHandler) sqlbuilder.StatementHandler {
+		return func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler {
+			return func(ctx context.Context, stmt *sqlbuilder.Statement) error {
+				calls = append(calls, name)
+				stmt.Query = stmt.Query + " /* " + name + " */"
+				return next(ctx, stmt)
+			}
+		}
+	}
+	m.use(tag("a"))
+	m.use(tag("b"))
+	assert.False(t, m.empty())
+
+	var query string
+	err := m.run(context.Background(), &sqlbuilder.Statement{Type: "select", Query: "SELECT 1"}, func(ctx context.Context, stmt *sqlbuilder.Statement) error {
+		query = stmt.Query
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, calls)
+	assert.Equal(t, "SELECT 1 /* a */ /* b */", query)
+
+	errDenied := errors.New("denied")
+	m.use(func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler {
+		return func(ctx context.Context, stmt *sqlbuilder.Statement) error {
+			return errDenied
+		}
+	})
+
+	err = m.run(context.Background(), &sqlbuilder.Statement{Type: "delete", Query: "DELETE FROM users"}, func(ctx context.Context, stmt *sqlbuilder.Statement) error {
+		t.Fatal("Statement was not expected to run.")
+		return nil
+	})
+	assert.Equal(t, errDenied, err)
+}
+
+func TestSetPoolOptions(t *testing.T) {
+	settings := db.NewSettings()
+
+	err := SetPoolOptions(settings, map[string]string{
+		MaxOpenConnsOption:    "20",
+		MaxIdleConnsOption:    "5",
+		ConnMaxLifetimeOption: "1h",
+		ConnMaxIdleTimeOption: "5m",
+		"sslmode":             "disable",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 20, settings.MaxOpenConns())
+	assert.Equal(t, 5, settings.MaxIdleConns())
+	assert.Equal(t, time.Hour, settings.ConnMaxLifetime())
+	assert.Equal(t, 5*time.Minute, settings.ConnMaxIdleTime())
+
+	// Nothing is changed if an option is not valid.
+	err = SetPoolOptions(settings, map[string]string{
+		MaxOpenConnsOption:    "10",
+		ConnMaxIdleTimeOption: "forever",
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 20, settings.MaxOpenConns())
+	assert.Equal(t, 5*time.Minute, settings.ConnMaxIdleTime())
+
+	assert.True(t, IsPoolOption(ConnMaxIdleTimeOption))
+	assert.False(t, IsPoolOption("sslmode"))
+}
+
+type fakeConn struct {
+	execs *[]string
+}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fakeConn) Close() error { return nil }
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	*c.execs = append(*c.execs, query)
+	return driver.RowsAffected(0), nil
+}
+
+type fakeConnDriver struct {
+	execs *[]string
+}
+
+func (d fakeConnDriver) Open(name string) (driver.Conn, error) {
+	fakeConnDSNs = append(fakeConnDSNs, name)
+	if name == "down" {
+		return nil, errors.New("connection refused")
+	}
+	return fakeConn{execs: d.execs}, nil
+}
+
+var (
+	fakeConnExecs []string
+	fakeConnDSNs  []string
+)
+
+func init() {
+	sql.Register("sqladapter_fake_conn", fakeConnDriver{execs: &fakeConnExecs})
+}
+
+func TestOnConnect(t *testing.T) {
+	d := &database{Settings: db.NewSettings(), connectHooks: &connectHooks{}}
+
+	sess, err := d.OpenSession("sqladapter_fake_conn", "")
+	assert.NoError(t, err)
+	defer sess.Close()
+
+	assert.NoError(t, sess.Ping())
+	assert.Empty(t, fakeConnExecs)
+
+	d.OnConnect(func(ctx context.Context, conn sqlbuilder.Conn) error {
+		_, err := conn.ExecContext(ctx, "SET search_path TO billing")
+		return err
+	})
+
+	// The idle connection was closed and the new one runs the hook.
+	assert.NoError(t, sess.Ping())
+	assert.Equal(t, []string{"SET search_path TO billing"}, fakeConnExecs)
+
+	assert.NoError(t, sess.Ping())
+	assert.Equal(t, 1, len(fakeConnExecs))
+
+	errDenied := errors.New("denied")
+	d.OnConnect(func(ctx context.Context, conn sqlbuilder.Conn) error {
+		return errDenied
+	})
+	assert.Equal(t, errDenied, sess.Ping())
+
+	// Closed sessions are forgotten.
+	d = NewBaseDatabase(fakeCompiler{}).(*database)
+	d.sess, err = d.OpenSession("sqladapter_fake_conn", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(d.connectHooks.sessions))
+	assert.NoError(t, d.Close())
+	assert.Equal(t, 0, len(d.connectHooks.sessions))
+}
+
+type fakePrimaryCheck struct {
+	PartialDatabase
+	checks int
+}
+
+// IsPrimary reports the second server it checks as the primary one.
+func (p *fakePrimaryCheck) IsPrimary(ctx context.Context, sess *sql.DB) (bool, error) {
+	p.checks++
+	return p.checks == 2, nil
+}
+
+func TestOpenFailover(t *testing.T) {
+	assert.Equal(t, []string{"db1:5432", "db2"}, SplitHosts("db1:5432, db2,"))
+	assert.Nil(t, SplitHosts(""))
+
+	assert.True(t, IsAdapterOption(TargetSessionAttrsOption))
+	assert.True(t, IsAdapterOption(MaxOpenConnsOption))
+	assert.False(t, IsAdapterOption("sslmode"))
+
+	{
+		d := &database{Settings: db.NewSettings(), connectHooks: &connectHooks{}}
+
+		sess, err := d.OpenFailover("sqladapter_fake_conn", []string{"down", "db2", "db3"}, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, sess.Ping())
+		assert.Equal(t, 1, len(d.connectHooks.sessions))
+		sess.Close()
+
+		_, err = d.OpenFailover("sqladapter_fake_conn", []string{"down", "down"}, nil)
+		assert.Equal(t, "connection refused", err.Error())
+		assert.Equal(t, 1, len(d.connectHooks.sessions))
+
+		_, err = d.OpenFailover("sqladapter_fake_conn", []string{"db1"}, map[string]string{TargetSessionAttrsOption: TargetPrimary})
+		assert.Equal(t, db.ErrUnsupported, err)
+
+		_, err = d.OpenFailover("sqladapter_fake_conn", []string{"db1"}, map[string]string{TargetSessionAttrsOption: "standby"})
+		assert.Error(t, err)
+	}
+
+	{
+		p := &fakePrimaryCheck{}
+		d := &database{PartialDatabase: p, Settings: db.NewSettings(), connectHooks: &connectHooks{}}
+
+		options := map[string]string{TargetSessionAttrsOption: TargetReadWrite}
+
+		sess, err := d.OpenFailover("sqladapter_fake_conn", []string{"down", "db2", "db3", "db4"}, options)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, p.checks)
+		sess.Close()
+
+		_, err = d.OpenFailover("sqladapter_fake_conn", []string{"db1"}, options)
+		assert.Equal(t, errNoPrimary, err)
+	}
+}
+
+type fakeCredentials struct {
+	PartialDatabase
+}
+
+type fakeURL struct {
+	DSN      string
+	User     string
+	Password string
+}
+
+func (u fakeURL) String() string {
+	if u.User == "" && u.Password == "" {
+		return u.DSN
+	}
+	return u.DSN + " user=" + u.User + " password=" + u.Password
+}
+
+type fakeHostURL struct {
+	Host string
+}
+
+func (u fakeHostURL) String() string {
+	return u.Host
+}
+
+type fakeOptionsURL struct {
+	Options map[string]string
+}
+
+func (u fakeOptionsURL) String() string {
+	return fmt.Sprint(u.Options)
+}
+
+func (fakeCredentials) Err(err error) error {
+	return err
+}
+
+func (fakeCredentials) ParseURL(dsn string) (db.ConnectionURL, error) {
+	return fakeURL{DSN: dsn}, nil
+}
+
+func TestConnectionOptions(t *testing.T) {
+	options := map[string]string{"sslmode": "disable"}
+
+	assert.Equal(t, options, ConnectionOptions(fakeOptionsURL{Options: options}))
+	assert.Equal(t, options, ConnectionOptions(&fakeOptionsURL{Options: options}))
+	assert.Nil(t, ConnectionOptions((*fakeOptionsURL)(nil)))
+	assert.Nil(t, ConnectionOptions(fakeURL{DSN: "host=db1"}))
+	assert.Nil(t, ConnectionOptions(nil))
+}
+
+func TestHostDSNs(t *testing.T) {
+	assert.Equal(t, []string{"db1", "db2:5433"}, HostDSNs(fakeHostURL{Host: "db1,db2:5433"}))
+	assert.Equal(t, []string{"db1", "db2"}, HostDSNs(&fakeHostURL{Host: "db1, db2"}))
+	assert.Equal(t, []string{"db1"}, HostDSNs(fakeHostURL{Host: "db1"}))
+	assert.Equal(t, []string{"host=db1"}, HostDSNs(fakeURL{DSN: "host=db1"}))
+}
+
+func TestCredentialsProvider(t *testing.T) {
+	calls := 0
+	provider := db.CredentialsProviderFunc(func(ctx context.Context) (db.Credentials, error) {
+		calls++
+		return db.Credentials{
+			User:  "app",
+			Token: fmt.Sprintf("token%d", calls),
+			TTL:   time.Hour,
+		}, nil
+	})
+
+	d := &database{
+		PartialDatabase: fakeCredentials{},
+		Settings:        db.NewSettings(),
+		connectHooks:    &connectHooks{},
+		credentials:     &credentialsCache{},
+	}
+	d.SetCredentialsProvider(provider)
+
+	sess, err := d.OpenSession("sqladapter_fake_conn", "host=db1")
+	assert.NoError(t, err)
+	defer sess.Close()
+
+	fakeConnDSNs = nil
+	sess.SetMaxIdleConns(0)
+
+	// Credentials are kept until they expire.
+	assert.NoError(t, sess.Ping())
+	assert.NoError(t, sess.Ping())
+	assert.Equal(t, []string{"host=db1 user=app password=token1", "host=db1 user=app password=token1"}, fakeConnDSNs)
+	assert.Equal(t, 1, calls)
+
+	// Clones share the credentials of the session.
+	clone, err := d.NewClone(fakeCredentials{}, false)
+	assert.NoError(t, err)
+	assert.True(t, clone.(*database).credentials == d.credentials)
+	assert.NoError(t, sess.Ping())
+	assert.Equal(t, 1, calls)
+
+	// A new provider is asked right away.
+	d.SetCredentialsProvider(provider)
+	assert.NoError(t, sess.Ping())
+	assert.Equal(t, "host=db1 user=app password=token2", fakeConnDSNs[3])
+
+	errExpired := errors.New("lease expired")
+	d.SetCredentialsProvider(db.CredentialsProviderFunc(func(ctx context.Context) (db.Credentials, error) {
+		return db.Credentials{}, errExpired
+	}))
+	assert.Equal(t, errExpired, sess.Ping())
+
+	// Adapters that can't replace credentials don't accept a provider.
+	d.PartialDatabase = nil
+	d.SetCredentialsProvider(provider)
+	assert.Equal(t, db.ErrUnsupported, sess.Ping())
+
+	d.SetCredentialsProvider(nil)
+	assert.NoError(t, sess.Ping())
+	assert.Equal(t, "host=db1", fakeConnDSNs[len(fakeConnDSNs)-1])
+}
+
+func TestHealthCheck(t *testing.T) {
+	{
+		d := &database{}
+		report := d.HealthCheck(context.Background())
+		assert.Equal(t, db.ErrNotConnected, report.PingErr)
+		assert.False(t, report.Healthy())
+	}
+
+	primary, err := sql.Open("sqladapter_fake_conn", "")
+	assert.NoError(t, err)
+	defer primary.Close()
+
+	broken, err := sql.Open("sqladapter_fake", "")
+	assert.NoError(t, err)
+	defer broken.Close()
+
+	{
+		d := &database{sess: primary}
+		report := d.HealthCheck(context.Background())
+		assert.NoError(t, report.PingErr)
+		assert.True(t, report.Healthy())
+		assert.Empty(t, report.Replicas)
+	}
+
+	{
+		d := &database{sess: primary, replicas: &replicaSet{sessions: []*sql.DB{primary, broken}}}
+		report := d.HealthCheck(context.Background())
+		assert.NoError(t, report.PingErr)
+		assert.Equal(t, 2, len(report.Replicas))
+		assert.NoError(t, report.Replicas[0].Err)
+		assert.Error(t, report.Replicas[1].Err)
+		assert.False(t, report.Healthy())
+		assert.Equal(t, report.Replicas[1].Err, report.Err())
+	}
+
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		d := &database{sess: primary}
+		assert.Equal(t, context.Canceled, d.PingContext(ctx))
+	}
+}
+
+func TestWaitForConnection(t *testing.T) {
+	errRefused := errors.New("connection refused")
+	errAuth := errors.New("password authentication failed")
+
+	failures := func(n int, err error) func() error {
+		return func() error {
+			if n > 0 {
+				n--
+				return err
+			}
+			return nil
+		}
+	}
+
+	newDatabase := func(policy db.ReconnectPolicy) *database {
+		d := &database{Settings: db.NewSettings()}
+		d.SetReconnectPolicy(policy)
+		return d
+	}
+
+	retryable := func(err error) bool {
+		return err == errRefused
+	}
+
+	{
+		attempts := []int{}
+		d := newDatabase(db.ReconnectPolicy{
+			MaxAttempts:    5,
+			InitialBackoff: time.Millisecond,
+			Retryable:      retryable,
+			OnRetry: func(attempt int, err error, wait time.Duration) {
+				assert.Equal(t, errRefused, err)
+				attempts = append(attempts, attempt)
+			},
+		})
+		assert.NoError(t, d.WaitForConnection(failures(2, errRefused)))
+		assert.Equal(t, []int{1, 2}, attempts)
+	}
+
+	{
+		d := newDatabase(db.ReconnectPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Retryable:      retryable,
+		})
+		assert.Equal(t, errRefused, d.WaitForConnection(failures(5, errRefused)))
+	}
+
+	{
+		d := newDatabase(db.ReconnectPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Retryable:      retryable,
+		})
+		assert.Equal(t, errAuth, d.WaitForConnection(failures(5, errAuth)))
+	}
+
+	{
+		// Too many clients errors are retried by default.
+		d := newDatabase(db.ReconnectPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+		})
+		assert.NoError(t, d.WaitForConnection(failures(2, db.ErrTooManyClients)))
+		assert.Equal(t, db.ErrGivingUpTryingToConnect, d.WaitForConnection(failures(5, db.ErrTooManyClients)))
+		assert.NoError(t, d.WaitForConnection(failures(2, driver.ErrBadConn)))
+	}
+
+	{
+		// Connections the pool opens again are retried too, the first one is
+		// left to WaitForConnection.
+		fails, retries := 0, 0
+		d := &database{
+			PartialDatabase: fakeCredentials{},
+			Settings:        db.NewSettings(),
+			connectHooks:    &connectHooks{},
+			credentials:     &credentialsCache{},
+		}
+		d.SetReconnectPolicy(db.ReconnectPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Retryable:      retryable,
+			OnRetry: func(int, error, time.Duration) {
+				retries++
+			},
+		})
+		d.SetCredentialsProvider(db.CredentialsProviderFunc(func(ctx context.Context) (db.Credentials, error) {
+			if fails > 0 {
+				fails--
+				return db.Credentials{}, errRefused
+			}
+			return db.Credentials{User: "app"}, nil
+		}))
+
+		sess, err := d.OpenSession("sqladapter_fake_conn", "db1")
+		assert.NoError(t, err)
+		defer sess.Close()
+		sess.SetMaxIdleConns(0)
+
+		fails = 1
+		assert.Equal(t, errRefused, sess.Ping())
+		assert.Equal(t, 0, retries)
+		assert.NoError(t, sess.Ping())
+
+		fails = 2
+		assert.NoError(t, sess.Ping())
+		assert.Equal(t, 2, retries)
+
+		fails = 5
+		assert.Equal(t, errRefused, sess.Ping())
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	errConn := errors.New("connection refused")
+	isConnErr := func(err error) bool {
+		return err == errConn
+	}
+
+	pings := 0
+	ping := func(ctx context.Context) error {
+		pings++
+		if pings < 3 {
+			return errConn
+		}
+		return nil
+	}
+
+	states := make(chan bool, 2)
+
+	b := newCircuitBreaker()
+	defer b.close()
+
+	// Disabled by default.
+	for i := 0; i < 5; i++ {
+		b.record(errConn, isConnErr, ping)
+	}
+	assert.NoError(t, b.allow())
+
+	b.configure(sqlbuilder.CircuitBreaker{
+		Threshold:     2,
+		ProbeInterval: time.Millisecond,
+		OnStateChange: func(open bool) {
+			states <- open
+		},
+	})
+
+	// Failures must be consecutive.
+	b.record(errConn, isConnErr, ping)
+	b.record(nil, isConnErr, ping)
+	b.record(errConn, isConnErr, ping)
+	assert.NoError(t, b.allow())
+
+	// Other errors don't count.
+	b.record(errors.New("syntax error"), isConnErr, ping)
+	assert.NoError(t, b.allow())
+
+	b.record(errConn, isConnErr, ping)
+	assert.Equal(t, db.ErrCircuitOpen, b.allow())
+
+	select {
+	case open := <-states:
+		assert.True(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("expecting the circuit to open")
+	}
+
+	select {
+	case open := <-states:
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("expecting the circuit to close")
+	}
+
+	assert.NoError(t, b.allow())
+	assert.Equal(t, 3, pings)
+}
+
+func TestQueryDeadline(t *testing.T) {
+	d := &database{Settings: db.NewSettings()}
+
+	{
+		ctx := context.Background()
+		deadline := d.queryDeadline(ctx)
+		assert.True(t, ctx == deadline.ctx)
+		assert.Equal(t, context.DeadlineExceeded, deadline.err(context.DeadlineExceeded))
+		deadline.release()
+	}
+
+	d.SetMaxQueryDuration(time.Millisecond)
+
+	{
+		deadline := d.queryDeadline(context.Background())
+		<-deadline.ctx.Done()
+		assert.Equal(t, db.ErrQueryTimeout, deadline.err(errors.New("pq: canceling statement due to user request")))
+		assert.NoError(t, deadline.err(nil))
+		deadline.release()
+	}
+
+	{
+		// The timeout of the context replaces the one of the session.
+		deadline := d.queryDeadline(sqlbuilder.WithQueryTimeout(context.Background(), time.Hour))
+		dl, ok := deadline.ctx.Deadline()
+		assert.True(t, ok)
+		assert.True(t, time.Until(dl) > time.Minute)
+		deadline.release()
+	}
+
+	{
+		// Errors caused by the context of the statement are left as they are.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		deadline := d.queryDeadline(sqlbuilder.WithQueryTimeout(ctx, time.Millisecond))
+		<-ctx.Done()
+		<-deadline.ctx.Done()
+		assert.Equal(t, context.DeadlineExceeded, deadline.err(context.DeadlineExceeded))
+		deadline.release()
+	}
+
+	cached := &cachedRows{Columns: []string{"id"}, Values: [][]interface{}{{int64(1)}, {int64(2)}}}
+
+	{
+		// Rows release the deadline once they're closed.
+		deadline := d.queryDeadline(sqlbuilder.WithQueryTimeout(context.Background(), time.Hour))
+		live, err := replayRows(context.Background(), cached)
+		assert.NoError(t, err)
+		rows, err := deadline.rows(live)
+		assert.NoError(t, err)
+
+		read, err := readRows(rows)
+		assert.NoError(t, err)
+		assert.Equal(t, cached, read)
+		assert.Equal(t, context.Canceled, deadline.ctx.Err())
+	}
+
+	{
+		// Rows read as a row release the deadline once it's scanned.
+		deadline := d.queryDeadline(sqlbuilder.WithQueryTimeout(context.Background(), time.Hour))
+		live, err := replayRows(context.Background(), cached)
+		assert.NoError(t, err)
+		row, err := deadline.row(context.Background(), live)
+		assert.NoError(t, err)
+		assert.NoError(t, deadline.ctx.Err())
+
+		var id int64
+		assert.NoError(t, row.Scan(&id))
+		assert.Equal(t, int64(1), id)
+		assert.Equal(t, context.Canceled, deadline.ctx.Err())
+	}
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	var logged []*db.QueryStatus
+
+	d := &database{Settings: db.NewSettings()}
+	d.SetLogger(db.QueryLoggerFunc(func(q *db.QueryStatus) {
+		logged = append(logged, q)
+	}))
+	assert.False(t, d.logging())
+
+	d.SetSlowQueryThreshold(100 * time.Millisecond)
+	assert.True(t, d.logging())
+
+	start := time.Now()
+	stmt := &exql.Statement{Type: exql.Select}
+
+	d.logStatement(stmt, &db.QueryStatus{Query: "SELECT 1", Start: start, End: start.Add(10 * time.Millisecond)})
+	assert.Empty(t, logged)
+
+	d.logStatement(stmt, &db.QueryStatus{Query: "SELECT 2", Start: start, End: start.Add(time.Second)})
+	assert.Equal(t, 1, len(logged))
+	assert.Equal(t, "SELECT 2", logged[0].Query)
+	assert.True(t, logged[0].Slow)
+	assert.Empty(t, logged[0].Plan)
+
+	d.SetSlowQueryThreshold(0)
+	d.SetLogging(true)
+	d.logStatement(stmt, &db.QueryStatus{Query: "SELECT 3", Start: start, End: start})
+	assert.Equal(t, 2, len(logged))
+	assert.False(t, logged[1].Slow)
+
+	assert.True(t, explainable(&exql.Statement{Type: exql.SQL}, " with t AS (SELECT 1) SELECT * FROM t"))
+	assert.False(t, explainable(&exql.Statement{Type: exql.SQL}, "VACUUM"))
+	assert.False(t, explainable(&exql.Statement{Type: exql.Insert}, "INSERT INTO t VALUES (1)"))
+	assert.False(t, explainable(nil, "SELECT 1"))
+}
+
+type fakeExplain struct {
+	PartialDatabase
+}
+
+func (fakeExplain) ExplainQuery(query string) string {
+	return "EXPLAIN " + query
+}
+
+func TestStatementExplain(t *testing.T) {
+	ctx := context.Background()
+	assert.False(t, sqlbuilder.ExplainJSON(ctx))
+	assert.True(t, sqlbuilder.ExplainJSON(sqlbuilder.WithExplainJSON(ctx)))
+
+	stmt := &exql.Statement{Type: exql.Select}
+
+	d := &database{PartialDatabase: fakeCredentials{}, Settings: db.NewSettings()}
+	_, err := d.StatementExplain(ctx, stmt)
+	assert.Equal(t, db.ErrUnsupported, err)
+
+	d = &database{PartialDatabase: fakeExplain{}, Settings: db.NewSettings()}
+	_, err = d.StatementExplain(sqlbuilder.WithExplainJSON(ctx), stmt)
+	assert.Equal(t, db.ErrUnsupported, err)
+}
+
+// fakeLockConn is a connection of a database whose advisory locks are held
+// by connections, like the ones of PostgreSQL.
+type fakeLockConn struct {
+	fakeConn
+	locks *fakeLockTable
+}
+
+type fakeLockTable struct {
+	mu    sync.Mutex
+	owner map[string]*fakeLockConn
+}
+
+func (c *fakeLockConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	key := args[0].Value.(string)
+
+	c.locks.mu.Lock()
+	defer c.locks.mu.Unlock()
+
+	owner, held := c.locks.owner[key]
+	var result bool
+	switch query {
+	case "LOCK ?":
+		if held {
+			return nil, errors.New("the fake driver can't wait for locks")
+		}
+		fallthrough
+	case "TRY LOCK ?":
+		if !held {
+			c.locks.owner[key] = c
+		}
+		result = !held
+	case "UNLOCK ?":
+		if owner == c {
+			delete(c.locks.owner, key)
+		}
+		result = owner == c
+	default:
+		return nil, errors.New("the fake driver can't run " + query)
+	}
+	return &cachedRowsIter{rows: &cachedRows{
+		Columns: []string{"result"},
+		Values:  [][]interface{}{{result}},
+	}}, nil
+}
+
+type fakeLockDriver struct {
+	locks *fakeLockTable
+}
+
+func (d fakeLockDriver) Open(name string) (driver.Conn, error) {
+	return &fakeLockConn{fakeConn: fakeConn{execs: new([]string)}, locks: d.locks}, nil
+}
+
+func init() {
+	sql.Register("sqladapter_fake_locks", fakeLockDriver{locks: &fakeLockTable{owner: map[string]*fakeLockConn{}}})
+}
+
+type fakeLocker struct {
+	fakeCompiler
+}
+
+func (fakeLocker) AdvisoryLockQuery(key string) (string, []interface{}) {
+	return "LOCK ?", []interface{}{key}
+}
+
+func (fakeLocker) TryAdvisoryLockQuery(key string) (string, []interface{}) {
+	return "TRY LOCK ?", []interface{}{key}
+}
+
+func (fakeLocker) Err(err error) error {
+	return err
+}
+
+func (fakeLocker) AdvisoryUnlockQuery(key string) (string, []interface{}) {
+	if key == "broken" {
+		return "BROKEN UNLOCK ?", []interface{}{key}
+	}
+	return "UNLOCK ?", []interface{}{key}
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	ctx := context.Background()
+
+	sess, err := sql.Open("sqladapter_fake_locks", "")
+	assert.NoError(t, err)
+	defer sess.Close()
+
+	d := &database{PartialDatabase: fakeLocker{}, Settings: db.NewSettings(), sess: sess, locks: &advisoryLocks{}, metrics: newMetrics(), middleware: &middleware{}}
+	other := &database{PartialDatabase: fakeLocker{}, Settings: db.NewSettings(), sess: sess, locks: &advisoryLocks{}, metrics: newMetrics(), middleware: &middleware{}}
+
+	assert.NoError(t, d.AdvisoryLock(ctx, "billing"))
+
+	// The lock is held by a connection of its own.
+	locked, err := other.TryAdvisoryLock(ctx, "billing")
+	assert.NoError(t, err)
+	assert.False(t, locked)
+	assert.Equal(t, db.ErrLockNotHeld, other.Unlock(ctx, "billing"))
+
+	locked, err = d.TryAdvisoryLock(ctx, "reports")
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	assert.NoError(t, d.Unlock(ctx, "billing"))
+	assert.Equal(t, db.ErrLockNotHeld, d.Unlock(ctx, "billing"))
+
+	locked, err = other.TryAdvisoryLock(ctx, "billing")
+	assert.NoError(t, err)
+	assert.True(t, locked)
+	assert.NoError(t, other.Unlock(ctx, "billing"))
+	assert.NoError(t, d.Unlock(ctx, "reports"))
+
+	// Only the connections that hold locks are kept out of the pool.
+	assert.Equal(t, 0, sess.Stats().OpenConnections-sess.Stats().Idle)
+
+	// Lock statements go through the middleware of the session.
+	var statements []string
+	d.Use(func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler {
+		return func(ctx context.Context, stmt *sqlbuilder.Statement) error {
+			statements = append(statements, stmt.Query)
+			return next(ctx, stmt)
+		}
+	})
+	assert.NoError(t, d.AdvisoryLock(ctx, "broken"))
+	assert.Equal(t, []string{"LOCK ?"}, statements)
+
+	// The connection of a lock that may not have been released is closed
+	// rather than put back into the pool.
+	open := sess.Stats().OpenConnections
+	assert.Error(t, d.Unlock(ctx, "broken"))
+	assert.Equal(t, open-1, sess.Stats().OpenConnections)
+}
+
+func TestTwoPhaseCommitUnsupported(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+
+	assert.Equal(t, db.ErrUnsupported, d.CommitPrepared("order-42"))
+	assert.Equal(t, db.ErrUnsupported, d.RollbackPrepared("order-42"))
+
+	_, err := d.PreparedTransactions()
+	assert.Equal(t, db.ErrUnsupported, err)
+
+	// The transaction is left open.
+	tx := &baseTx{}
+	w := &databaseTx{Database: d, BaseTx: tx}
+	assert.Equal(t, db.ErrUnsupported, w.PrepareTwoPhase("order-42"))
+	assert.Equal(t, int32(0), tx.done)
+}
+
+// fakeXA prepares global transactions the way MySQL does, with XA
+// statements.
+type fakeXA struct{}
+
+type fakeGlobalCompiler struct {
+	fakeCompiler
+	fakeXA
+}
+
+// fakeGlobalDatabase is the session an adapter with XA transactions wraps.
+type fakeGlobalDatabase struct {
+	*database
+	fakeXA
+}
+
+func (fakeXA) BeginGlobalQueries(gid string) []string {
+	return []string{"COMMIT", "XA START " + gid}
+}
+
+func (fakeXA) EndGlobalQuery(gid string) string {
+	return "XA END " + gid
+}
+
+func (fakeXA) CommitGlobalQuery(gid string) string {
+	return "XA COMMIT " + gid + " ONE PHASE"
+}
+
+func (fakeXA) RollbackGlobalQuery(gid string) string {
+	return "XA ROLLBACK " + gid
+}
+
+func (fakeXA) PrepareTransactionQuery(gid string) string {
+	return "XA PREPARE " + gid
+}
+
+func (fakeXA) CommitPreparedQuery(gid string) string {
+	return "XA COMMIT " + gid
+}
+
+func (fakeXA) RollbackPreparedQuery(gid string) string {
+	return "XA ROLLBACK " + gid
+}
+
+func (fakeXA) PreparedTransactionsQuery() string {
+	return "XA RECOVER"
+}
+
+func (fakeGlobalCompiler) ExecContext(ctx context.Context, query interface{}, args ...interface{}) (sql.Result, error) {
+	fakeTxExecs = append(fakeTxExecs, query.(string))
+	return driver.RowsAffected(1), nil
+}
+
+func TestTwoPhaseCommitGlobal(t *testing.T) {
+	sess, err := sql.Open("sqladapter_fake_tx", "record")
+	assert.NoError(t, err)
+	defer sess.Close()
+
+	begin := func(ctx context.Context) *databaseTx {
+		clone := NewBaseDatabase(fakeGlobalCompiler{}).(*database)
+		clone.name, clone.sess = "orders", sess
+		sqlTx, err := sess.Begin()
+		assert.NoError(t, err)
+		assert.NoError(t, clone.BindTx(ctx, sqlTx))
+		return &databaseTx{Database: fakeGlobalDatabase{database: clone}, BaseTx: clone.baseTx}
+	}
+	ctx := sqlbuilder.WithTwoPhase(context.Background(), "order-42")
+
+	// The transaction is global from its start and it's prepared with the
+	// same global id.
+	fakeTxExecs = nil
+	tx := begin(ctx)
+	assert.NoError(t, tx.PrepareTwoPhase("order-42"))
+	assert.Equal(t, []string{"COMMIT", "XA START order-42", "XA END order-42", "XA PREPARE order-42"}, fakeTxExecs)
+
+	d := &database{PartialDatabase: fakeGlobalCompiler{}, Settings: db.NewSettings()}
+	assert.NoError(t, d.CommitPrepared("order-42"))
+	assert.Equal(t, "XA COMMIT order-42", fakeTxExecs[len(fakeTxExecs)-1])
+
+	// Global transactions that are not prepared are committed in one phase.
+	fakeTxExecs = nil
+	tx = begin(ctx)
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"COMMIT", "XA START order-42", "XA END order-42", "XA COMMIT order-42 ONE PHASE"}, fakeTxExecs)
+
+	fakeTxExecs = nil
+	tx = begin(ctx)
+	assert.NoError(t, tx.Rollback())
+	assert.Equal(t, []string{"COMMIT", "XA START order-42", "XA END order-42", "XA ROLLBACK order-42"}, fakeTxExecs)
+
+	// A transaction can't be prepared with another global id, nor if it was
+	// not begun as a global one.
+	tx = begin(ctx)
+	err = tx.PrepareTwoPhase("order-43")
+	assert.True(t, errors.Is(err, errNotGlobal))
+	assert.NoError(t, tx.Rollback())
+
+	tx = begin(context.Background())
+	err = tx.PrepareTwoPhase("order-42")
+	assert.True(t, errors.Is(err, errNotGlobal))
+	assert.NoError(t, tx.Rollback())
+}
+
+// fakeTxConn is a connection that begins transactions, which fail to commit
+// if commitErr is set.
+type fakeTxConn struct {
+	fakeConn
+	commitErr error
+}
+
+func (c fakeTxConn) Begin() (driver.Tx, error) {
+	return fakeDriverTx{commitErr: c.commitErr}, nil
+}
+
+type fakeDriverTx struct {
+	commitErr error
+}
+
+func (tx fakeDriverTx) Commit() error   { return tx.commitErr }
+func (tx fakeDriverTx) Rollback() error { return nil }
+
+type fakeTxDriver struct{}
+
+func (fakeTxDriver) Open(name string) (driver.Conn, error) {
+	conn := fakeTxConn{fakeConn: fakeConn{execs: new([]string)}}
+	if name == "record" {
+		conn.execs = &fakeTxExecs
+	}
+	if name == "commit-fails" {
+		conn.commitErr = errors.New("could not commit")
+	}
+	return conn, nil
+}
+
+// fakeTxExecs records the statements of the connections opened with the
+// "record" DSN.
+var fakeTxExecs []string
+
+func init() {
+	sql.Register("sqladapter_fake_tx", fakeTxDriver{})
+}
+
+func TestTxHooks(t *testing.T) {
+	begin := func(dsn string) *baseTx {
+		sess, err := sql.Open("sqladapter_fake_tx", dsn)
+		assert.NoError(t, err)
+		tx, err := sess.Begin()
+		assert.NoError(t, err)
+		return newBaseTx(tx).(*baseTx)
+	}
+
+	var calls []string
+	hook := func(name string) func() {
+		return func() {
+			calls = append(calls, name)
+		}
+	}
+
+	tx := begin("")
+	tx.AfterCommit(hook("commit 1"))
+	tx.AfterCommit(hook("commit 2"))
+	tx.AfterRollback(hook("rollback"))
+	assert.Equal(t, 0, len(calls))
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"commit 1", "commit 2"}, calls)
+
+	// Hooks run just once.
+	assert.Error(t, tx.Commit())
+	assert.Error(t, tx.Rollback())
+	assert.Equal(t, []string{"commit 1", "commit 2"}, calls)
+
+	calls = nil
+	tx = begin("")
+	tx.AfterCommit(hook("commit"))
+	tx.AfterRollback(hook("rollback"))
+	assert.NoError(t, tx.Rollback())
+	assert.Equal(t, []string{"rollback"}, calls)
+
+	calls = nil
+	tx = begin("commit-fails")
+	tx.AfterCommit(hook("commit"))
+	tx.AfterRollback(hook("rollback"))
+	assert.Error(t, tx.Commit())
+	assert.Equal(t, []string{"rollback"}, calls)
+
+	// Hooks of nested transactions wait for the outcome of the top-level
+	// one.
+	calls = nil
+	tx = begin("")
+	released, err := newSavepointTx(context.Background(), tx)
+	assert.NoError(t, err)
+	released.AfterCommit(hook("released"))
+	assert.NoError(t, released.Commit())
+
+	rolledBack, err := newSavepointTx(context.Background(), tx)
+	assert.NoError(t, err)
+	rolledBack.AfterCommit(hook("discarded"))
+	rolledBack.AfterRollback(hook("rolled back"))
+	assert.NoError(t, rolledBack.Rollback())
+	assert.Equal(t, []string{"rolled back"}, calls)
+
+	assert.NoError(t, tx.Commit())
+	assert.Equal(t, []string{"rolled back", "released"}, calls)
+}
+
+type fakeCompiler struct {
+	PartialDatabase
+}
+
+func (fakeCompiler) CompileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
+	return stmt.SQL, args
+}
+
+func TestDryRun(t *testing.T) {
+	var logged []*db.QueryStatus
+
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+	d.SetLogger(db.QueryLoggerFunc(func(q *db.QueryStatus) {
+		logged = append(logged, q)
+	}))
+
+	ctx := context.Background()
+	deleteAll := exql.RawSQL("DELETE FROM artist")
+
+	assert.False(t, d.dryRun(ctx, deleteAll, nil, true))
+	assert.Empty(t, logged)
+
+	d.SetDryRun(true)
+
+	assert.True(t, d.dryRun(ctx, deleteAll, nil, true))
+	assert.True(t, d.dryRun(ctx, deleteAll, nil, false))
+	assert.False(t, d.dryRun(ctx, exql.RawSQL(" select * FROM artist"), nil, false))
+
+	assert.Equal(t, 2, len(logged))
+	assert.Equal(t, "DELETE FROM artist", logged[0].Query)
+	assert.True(t, logged[0].DryRun)
+
+	res, err := d.StatementExec(ctx, exql.RawSQL("DROP TABLE artist"))
+	assert.NoError(t, err)
+	rowsAffected, err := res.RowsAffected()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), rowsAffected)
+
+	_, err = d.StatementQuery(ctx, exql.RawSQL("UPDATE artist SET name = ? RETURNING id"), "Ozzie")
+	assert.Equal(t, db.ErrDryRun, err)
+	assert.Equal(t, []interface{}{"Ozzie"}, logged[len(logged)-1].Args)
+
+	assert.False(t, writes(&exql.Statement{Type: exql.Select}, ""))
+	assert.True(t, writes(&exql.Statement{Type: exql.Update}, ""))
+	assert.True(t, writes(&exql.Statement{Type: exql.Insert}, ""))
+}
+
+func TestReadOnly(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings()}
+	d.SetTxOptions(sql.TxOptions{Isolation: sql.LevelSerializable})
+
+	assert.NoError(t, d.checkReadOnly(exql.RawSQL("DELETE FROM artist"), nil, true))
+	assert.False(t, d.TxOptions().ReadOnly)
+
+	d.UseReadOnly()
+
+	ctx := context.Background()
+	_, err := d.StatementExec(ctx, exql.RawSQL("DELETE FROM artist"))
+	assert.Equal(t, db.ErrReadOnly, err)
+	_, err = d.StatementExec(ctx, exql.RawSQL("SET search_path TO public"))
+	assert.Equal(t, db.ErrReadOnly, err)
+	_, err = d.StatementQuery(ctx, exql.RawSQL("UPDATE artist SET name = ? RETURNING id"), "Ozzie")
+	assert.Equal(t, db.ErrReadOnly, err)
+	_, err = d.StatementQueryRow(ctx, &exql.Statement{Type: exql.Insert})
+	assert.Equal(t, db.ErrReadOnly, err)
+	_, err = d.StatementPrepare(ctx, &exql.Statement{Type: exql.Update})
+	assert.Equal(t, db.ErrReadOnly, err)
+	_, err = d.StatementCursor(ctx, exql.RawSQL("DELETE FROM artist RETURNING id"), "c", 10)
+	assert.Equal(t, db.ErrReadOnly, err)
+	err = d.connQueryRow(ctx, nil, exql.RawSQL("INSERT INTO locks VALUES (?) RETURNING id"), []interface{}{"key"}, nil)
+	assert.Equal(t, db.ErrReadOnly, err)
+	assert.NoError(t, d.checkReadOnly(exql.RawSQL("SELECT * FROM artist"), nil, false))
+	assert.NoError(t, d.checkReadOnly(&exql.Statement{Type: exql.Select}, nil, false))
+
+	assert.Equal(t, &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true}, d.TxOptions())
+}
+
+func TestStatementPolicy(t *testing.T) {
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings(), policy: &statementPolicy{}}
+
+	var checked []*sqlbuilder.Statement
+	d.SetStatementPolicy(sqlbuilder.StatementPolicyFunc(func(ctx context.Context, stmt *sqlbuilder.Statement) error {
+		checked = append(checked, stmt)
+		if stmt.Type == "delete" && !stmt.Where {
+			return &sqlbuilder.PolicyError{Reason: "missing WHERE", Statement: stmt}
+		}
+		return nil
+	}))
+
+	ctx := context.Background()
+	deleteAll := &exql.Statement{Type: exql.Delete, SQL: "DELETE FROM artist"}
+
+	_, err := d.StatementExec(ctx, deleteAll)
+	_, ok := err.(*sqlbuilder.PolicyError)
+	assert.True(t, ok)
+
+	deleteOne := &exql.Statement{
+		Type:  exql.Delete,
+		SQL:   "DELETE FROM artist WHERE id = ?",
+		Where: exql.WhereConditions(exql.JoinWithAnd(&exql.ColumnValue{})),
+	}
+	assert.NoError(t, d.checkPolicy(ctx, deleteOne, []interface{}{1}))
+
+	assert.Equal(t, 2, len(checked))
+	assert.Equal(t, "DELETE FROM artist WHERE id = ?", checked[1].Query)
+	assert.Equal(t, []interface{}{1}, checked[1].Args)
+	assert.True(t, checked[1].Where)
+
+	// Statements are checked whichever way they run.
+	_, err = d.StatementPrepare(ctx, deleteAll)
+	_, ok = err.(*sqlbuilder.PolicyError)
+	assert.True(t, ok)
+	_, err = d.StatementCursor(ctx, deleteAll, "c", 10)
+	_, ok = err.(*sqlbuilder.PolicyError)
+	assert.True(t, ok)
+	assert.Equal(t, 4, len(checked))
+
+	assert.False(t, hasConditions(exql.WhereConditions(exql.JoinWithOr())))
+	assert.False(t, hasConditions((*exql.Where)(nil)))
+
+	d.SetStatementPolicy(nil)
+	assert.NoError(t, d.checkPolicy(ctx, deleteAll, nil))
+}
+
+func TestResultCache(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t,
+		[]string{"artist", "publication", "tag", "genre", "label"},
+		referencedTables(`SELECT * FROM "artist" AS a JOIN public.publication p ON p.author_id = a.id WHERE a.id IN (SELECT artist_id FROM tag t, genre WHERE t.id = 1) AND EXISTS (SELECT 1 FROM [label])`),
+	)
+
+	resultCache, err := db.NewMemoryResultCache(100, 0)
+	assert.NoError(t, err)
+
+	// Versions are kept in the result cache, so sessions that share it see
+	// the writes of each other.
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings(), name: "music"}
+	d.SetResultCache(resultCache)
+	other := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings(), name: "music"}
+	other.SetResultCache(resultCache)
+
+	query := `SELECT * FROM artist JOIN publication ON publication.author_id = artist.id`
+	keyOf := func() string {
+		return other.resultCacheKey(ctx, resultCache, "artist", query, nil)
+	}
+
+	key := keyOf()
+	assert.Equal(t, key, keyOf())
+
+	d.invalidateResults(ctx, &exql.Statement{Type: exql.Insert, Table: exql.TableWithName("genre")})
+	assert.Equal(t, key, keyOf())
+
+	// Writes to joined tables change the keys of the results that read them.
+	d.invalidateResults(ctx, &exql.Statement{Type: exql.Insert, Table: exql.TableWithName(`public."Publication" AS p`)})
+	assert.NotEqual(t, key, keyOf())
+
+	key = keyOf()
+	d.invalidateResults(ctx, &exql.Statement{Type: exql.Update, Table: exql.TableWithName("artist")})
+	assert.NotEqual(t, key, keyOf())
+
+	key = keyOf()
+	d.invalidateResults(ctx, exql.RawSQL("TRUNCATE genre"))
+	assert.NotEqual(t, key, keyOf())
+
+	now := time.Now().UTC().Truncate(time.Second)
+	rows := &cachedRows{
+		Columns: []string{"id", "name", "bio", "created_at"},
+		Values: [][]interface{}{
+			{int64(1), "Ozzie", []byte("bio"), now},
+			{int64(2), "Flea", nil, now},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(rows))
+
+	var decoded cachedRows
+	assert.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+
+	replayed, err := replayRows(context.Background(), &decoded)
+	assert.NoError(t, err)
+
+	read, err := readRows(replayed)
+	assert.NoError(t, err)
+	assert.Equal(t, rows, read)
+
+	// Rows are replayed without registering a driver.
+	for _, name := range sql.Drivers() {
+		assert.False(t, strings.HasPrefix(name, "upper"))
+	}
+}
+
+func TestCollapseReads(t *testing.T) {
+	g := &readGroup{}
+	rows := &cachedRows{Columns: []string{"id"}}
+
+	var calls int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	read := func(ctx context.Context) (*cachedRows, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		select {
+		case <-release:
+			return rows, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// The first read is cancelled once the others joined it, they still get
+	// the rows.
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	results := make([]*cachedRows, 5)
+	errs := make([]error, 5)
+	for i := range results {
+		ctx := context.Background()
+		if i == 0 {
+			ctx = leaderCtx
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = g.do(ctx, "SELECT 1", read)
+		}(i)
+		if i == 0 {
+			<-started
+		}
+	}
+
+	// Let the other reads join the first one.
+	time.Sleep(50 * time.Millisecond)
+	cancelLeader()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, context.Canceled, errs[0])
+	for i := 1; i < len(results); i++ {
+		assert.NoError(t, errs[i])
+		assert.True(t, rows == results[i])
+	}
+	assert.Equal(t, 0, len(g.calls))
+
+	// Reads that are not concurrent are not collapsed.
+	_, err := g.do(context.Background(), "SELECT 1", func(context.Context) (*cachedRows, error) {
+		return nil, db.ErrNoMoreRows
+	})
+	assert.Equal(t, db.ErrNoMoreRows, err)
+
+	// The query is cancelled once no read waits for it.
+	ctx, cancel := context.WithCancel(context.Background())
+	queryErr := make(chan error, 1)
+	go func() {
+		_, err := g.do(ctx, "SELECT 2", func(ctx context.Context) (*cachedRows, error) {
+			<-ctx.Done()
+			queryErr <- ctx.Err()
+			return nil, ctx.Err()
+		})
+		assert.Equal(t, context.Canceled, err)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	assert.Equal(t, context.Canceled, <-queryErr)
+
+	ctx = context.Background()
+	assert.False(t, readCollapsed(ctx))
+	assert.True(t, readCollapsed(context.WithValue(ctx, readCollapsedKey{}, true)))
+}
+
+func TestReadKey(t *testing.T) {
+	key := func(args ...interface{}) string {
+		k, ok := readKey("test", "SELECT ?", args)
+		assert.True(t, ok)
+		return k
+	}
+
+	assert.Equal(t, key(int64(1)), key(1))
+	assert.Equal(t, key(sql.NullInt64{Int64: 1, Valid: true}), key(1))
+	assert.False(t, key(1) == key("1"))
+	assert.False(t, key(1.0) == key(1))
+	assert.False(t, key([]byte("a")) == key("a"))
+	assert.False(t, key("a", "b") == key("a\x00sb"))
+
+	utc := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.False(t, key(utc) == key(utc.Add(time.Nanosecond)))
+
+	// Values the driver can't receive as they are are not collapsed.
+	_, ok := readKey("test", "SELECT ?", []interface{}{struct{}{}})
+	assert.False(t, ok)
+}
+
+// fakeCursorConn reads the numbers 1 to 3 from any cursor.
+type fakeCursorConn struct {
+	fakeTxConn
+	fetched *int
+}
+
+func (c fakeCursorConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows := &cachedRows{Columns: []string{"n"}}
+	if strings.HasPrefix(query, "FETCH") {
+		for *c.fetched < 3 && len(rows.Values) < 2 {
+			*c.fetched++
+			rows.Values = append(rows.Values, []interface{}{int64(*c.fetched)})
+		}
+	}
+	return &cachedRowsIter{rows: rows}, nil
+}
+
+type fakeCursorDriver struct{}
+
+func (fakeCursorDriver) Open(name string) (driver.Conn, error) {
+	return fakeCursorConn{fakeTxConn: fakeTxConn{fakeConn: fakeConn{execs: new([]string)}}, fetched: new(int)}, nil
+}
+
+func init() {
+	sql.Register("sqladapter_fake_cursor", fakeCursorDriver{})
+}
+
+type fakeCursors struct {
+	fakeCompiler
+}
+
+func (fakeCursors) DeclareCursorQuery(name string, query string) string {
+	return "DECLARE " + name + " CURSOR FOR " + query
+}
+
+func (fakeCursors) FetchCursorQuery(name string, size int) string {
+	return fmt.Sprintf("FETCH %d FROM %s", size, name)
+}
+
+func (fakeCursors) CloseCursorQuery(name string) string {
+	return "CLOSE " + name
+}
+
+func TestStatementCursor(t *testing.T) {
+	ctx := context.Background()
+	stmt := &exql.Statement{Type: exql.Select}
+
+	d := &database{PartialDatabase: fakeCredentials{}, Settings: db.NewSettings()}
+	_, err := d.StatementCursor(ctx, stmt, "artists", 10)
+	assert.Equal(t, db.ErrUnsupported, err)
+
+	sess, err := sql.Open("sqladapter_fake_cursor", "")
+	assert.NoError(t, err)
+	sess.SetMaxOpenConns(1)
+
+	// The statements of the cursor go through the middleware of the session.
+	d = NewBaseDatabase(fakeCursors{}).(*database)
+	d.name, d.sess = "music", sess
+	var statements []string
+	d.Use(func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler {
+		return func(ctx context.Context, stmt *sqlbuilder.Statement) error {
+			statements = append(statements, stmt.Type+": "+stmt.Query)
+			return next(ctx, stmt)
+		}
+	})
+
+	rows, err := d.StatementCursor(ctx, &exql.Statement{Type: exql.Select, SQL: "SELECT n FROM numbers"}, "numbers", 2)
+	assert.NoError(t, err)
+	var numbers []int64
+	for rows.Next() {
+		var n int64
+		assert.NoError(t, rows.Scan(&n))
+		numbers = append(numbers, n)
+	}
+	assert.NoError(t, rows.Err())
+	assert.NoError(t, rows.Close())
+
+	assert.Equal(t, []int64{1, 2, 3}, numbers)
+	assert.Equal(t, []string{
+		"select: DECLARE numbers CURSOR FOR SELECT n FROM numbers",
+		"raw: FETCH 2 FROM numbers",
+		"raw: FETCH 2 FROM numbers",
+		"raw: CLOSE numbers",
+	}, statements)
+}
+
+func TestIteratorEmbedded(t *testing.T) {
+	// Embedded pointers must be exported to be allocated.
+	type Base struct {
+		ID int64 `db:"id"`
+	}
+	type address struct {
+		City string `db:"city"`
+	}
+	type artist struct {
+		*Base
+		Name    string   `db:"name"`
+		Address *address `db:"address,inline"`
+	}
+
+	rows, err := replayRows(context.Background(), &cachedRows{
+		Columns: []string{"id", "name", "city"},
+		Values:  [][]interface{}{{int64(1), "Ozzie", "Birmingham"}, {int64(2), "Flea", "Melbourne"}},
+	})
+	assert.NoError(t, err)
+
+	var artists []artist
+	assert.NoError(t, sqlbuilder.NewIterator(rows).All(&artists))
+	assert.Equal(t, []artist{
+		{Base: &Base{ID: 1}, Name: "Ozzie", Address: &address{City: "Birmingham"}},
+		{Base: &Base{ID: 2}, Name: "Flea", Address: &address{City: "Melbourne"}},
+	}, artists)
+}
+
+func TestIteratorWriteOnly(t *testing.T) {
+	type account struct {
+		ID       int64  `db:"id"`
+		Password string `db:"password,writeonly"`
+	}
+
+	rows, err := replayRows(context.Background(), &cachedRows{
+		Columns: []string{"id", "password"},
+		Values:  [][]interface{}{{int64(1), "hash"}},
+	})
+	assert.NoError(t, err)
+
+	var accounts []account
+	assert.NoError(t, sqlbuilder.NewIterator(rows).All(&accounts))
+	assert.Equal(t, []account{{ID: 1}}, accounts)
+
+	// Fields tagged with writeonly keep their values when items are copied.
+	item := account{Password: "secret"}
+	assert.NoError(t, copyItem(mapper, &item, &accounts[0]))
+	assert.Equal(t, account{ID: 1, Password: "secret"}, item)
+}
+
+func TestIteratorForEach(t *testing.T) {
+	ctx := context.Background()
+	cached := &cachedRows{
+		Columns: []string{"id", "name"},
+		Values:  [][]interface{}{{int64(1), "Ozzie"}, {int64(2), "Flea"}},
+	}
+
+	type artist struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	rows, err := replayRows(ctx, cached)
+	assert.NoError(t, err)
+
+	var (
+		a     artist
+		names []string
+	)
+	err = sqlbuilder.NewIterator(rows).ForEach(func(scan func(dest ...interface{}) error) error {
+		if err := scan(&a); err != nil {
+			return err
+		}
+		names = append(names, a.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Ozzie", "Flea"}, names)
+	assert.Equal(t, artist{ID: 2, Name: "Flea"}, a)
+
+	rows, err = replayRows(ctx, cached)
+	assert.NoError(t, err)
+
+	var ids []int64
+	err = sqlbuilder.NewIterator(rows).ForEach(func(scan func(dest ...interface{}) error) error {
+		var id int64
+		var row map[string]interface{}
+		if err := scan(&id, new(string)); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		return scan(&row)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+}
+
+// resultSetsIter replays several result sets, like the ones of a stored
+// procedure.
+type resultSetsIter struct {
+	*cachedRowsIter
+	sets []*cachedRows
+}
+
+func (r *resultSetsIter) HasNextResultSet() bool {
+	return len(r.sets) > 0
+}
+
+func (r *resultSetsIter) NextResultSet() error {
+	if len(r.sets) == 0 {
+		return io.EOF
+	}
+	r.cachedRowsIter = &cachedRowsIter{rows: r.sets[0]}
+	r.sets = r.sets[1:]
+	return nil
+}
+
+func TestIteratorNextResultSet(t *testing.T) {
+	type artist struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	rows, err := driverRows(context.Background(), &resultSetsIter{
+		cachedRowsIter: &cachedRowsIter{rows: &cachedRows{
+			Columns: []string{"id", "name"},
+			Values:  [][]interface{}{{int64(1), "Ozzie"}, {int64(2), "Flea"}},
+		}},
+		sets: []*cachedRows{
+			{Columns: []string{"total"}, Values: [][]interface{}{{int64(2)}}},
+		},
+	})
+	assert.NoError(t, err)
+
+	iter := sqlbuilder.NewIterator(rows)
+	defer iter.Close()
+
+	var (
+		a     artist
+		names []string
+	)
+	for iter.Next(&a) {
+		names = append(names, a.Name)
+	}
+	assert.NoError(t, iter.Err())
+	assert.Equal(t, []string{"Ozzie", "Flea"}, names)
+
+	assert.True(t, iter.NextResultSet())
+
+	var total int64
+	assert.NoError(t, iter.NextScan(&total))
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, db.ErrNoMoreRows, iter.NextScan(&total))
+
+	assert.False(t, iter.NextResultSet())
+	assert.NoError(t, iter.Err())
+}
+
+type benchmarkArtist struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	Active    bool      `db:"active"`
+}
+
+func benchmarkRows(n int) *cachedRows {
+	rows := &cachedRows{Columns: []string{"id", "name", "created_at", "active", "unmapped"}}
+	for i := 0; i < n; i++ {
+		rows.Values = append(rows.Values, []interface{}{int64(i), "Ozzie", time.Now(), true, nil})
+	}
+	return rows
+}
+
+func BenchmarkIteratorAll(b *testing.B) {
+	ctx := context.Background()
+	cached := benchmarkRows(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows, err := replayRows(ctx, cached)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var artists []benchmarkArtist
+		if err := sqlbuilder.NewIterator(rows).All(&artists); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIteratorForEach(b *testing.B) {
+	ctx := context.Background()
+	cached := benchmarkRows(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rows, err := replayRows(ctx, cached)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var artist benchmarkArtist
+		err = sqlbuilder.NewIterator(rows).ForEach(func(scan func(dest ...interface{}) error) error {
+			return scan(&artist)
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCopyItem(b *testing.B) {
+	src := &benchmarkArtist{ID: 1, Name: "Ozzie", CreatedAt: time.Now(), Active: true}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var item benchmarkArtist
+		if err := copyItem(mapper, &item, src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseRelation(t *testing.T) {
+	rel, err := parseRelation("hasMany:orders,fk:user_id")
+	assert.NoError(t, err)
+	assert.Equal(t, &relation{kind: relationHasMany, table: "orders", fk: "user_id", key: "id"}, rel)
+
+	local, remote := rel.columns()
+	assert.Equal(t, "id", local)
+	assert.Equal(t, "user_id", remote)
+
+	rel, err = parseRelation("belongsTo:users, fk:owner_code, key:code")
+	assert.NoError(t, err)
+
+	local, remote = rel.columns()
+	assert.Equal(t, "owner_code", local)
+	assert.Equal(t, "code", remote)
+
+	for _, tag := range []string{"", "hasMany:orders", "fk:user_id", "hasMany:orders,fk:", "manyToMany:tags,fk:tag_id"} {
+		_, err := parseRelation(tag)
+		assert.Error(t, err, tag)
+	}
+}
+
+func TestPreload(t *testing.T) {
+	type order struct {
+		ID     int64 `db:"id"`
+		UserID int64 `db:"user_id"`
+	}
+
+	type user struct {
+		ID     *int64  `db:"id"`
+		Orders []order `db:"-" relation:"hasMany:orders,fk:user_id"`
+		Best   *order  `db:"-" relation:"hasOne:orders,fk:user_id"`
+		Name   string  `db:"name"`
+	}
+
+	// Items without keys are not looked up.
+	users := []user{{Orders: []order{{ID: 1}}, Best: &order{ID: 1}}}
+	assert.NoError(t, preload(context.Background(), nil, &users, []string{"Orders", "Best"}))
+	assert.Equal(t, []order{}, users[0].Orders)
+	assert.Nil(t, users[0].Best)
+
+	assert.Error(t, preload(context.Background(), nil, &users, []string{"Name"}))
+	assert.Error(t, preload(context.Background(), nil, &users, []string{"Orders.User"}))
+	assert.Error(t, preload(context.Background(), nil, users, []string{"Orders"}))
+	assert.Error(t, preload(context.Background(), nil, &[]int{1}, []string{"Orders"}))
+}
+
+func TestRunInTx(t *testing.T) {
+	sess, err := sql.Open("sqladapter_fake_tx", "")
+	assert.NoError(t, err)
+
+	d := &database{PartialDatabase: fakeCompiler{}, Settings: db.NewSettings(), name: "music", sess: sess}
+
+	// Statements of fn run within a transaction of their own.
+	var tx BaseTx
+	err = d.RunInTx(context.Background(), func(s interface{}) error {
+		tx = s.(*database).Transaction()
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, tx)
+	assert.True(t, tx.Committed())
+	assert.Nil(t, d.Transaction())
+
+	// The transaction is rolled back if fn fails.
+	errFailed := errors.New("failed")
+	err = d.RunInTx(context.Background(), func(s interface{}) error {
+		tx = s.(*database).Transaction()
+		return errFailed
+	})
+	assert.Equal(t, errFailed, err)
+	assert.False(t, tx.Committed())
+
+	// Sessions within a transaction run fn on their own.
+	err = d.RunInTx(context.Background(), func(s interface{}) error {
+		inner := s.(*database)
+		return inner.RunInTx(context.Background(), func(s interface{}) error {
+			assert.Equal(t, inner, s)
+			return nil
+		})
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,78 @@
+package sqladapter
+
+import (
+	"context"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// hasTableScope is implemented by the sessions that scope tables by tenant.
+type hasTableScope interface {
+	TableScope(ctx context.Context, table string) (column string, tenant interface{}, scoped bool, err error)
+	MapOptions() *sqlbuilder.MapOptions
+	Clock() func() time.Time
+}
+
+// TableScope returns the tenant column of the given table and the tenant of
+// ctx, or of the session context if ctx carries none, scoped is false if the
+// table is not scoped by tenant or if the scope was lifted with
+// db.WithoutTenant. The SQL builder of the session restricts the statements
+// on scoped tables to the rows of the tenant of the context they run within.
+func (d *database) TableScope(ctx context.Context, table string) (column string, tenant interface{}, scoped bool, err error) {
+	tenancy := d.Tenancy()
+	if !tenancy.Scoped(table) {
+		return "", nil, false, nil
+	}
+
+	for _, c := range []context.Context{ctx, d.Context()} {
+		if db.Unscoped(c) {
+			return "", nil, false, nil
+		}
+		if tenant, ok := db.Tenant(c); ok {
+			return tenancy.Column(), tenant, true, nil
+		}
+	}
+	return "", nil, false, db.ErrMissingTenant
+}
+
+// tenant returns the tenant column of the collection and the tenant of ctx,
+// see TableScope.
+func (c *collection) tenant(ctx context.Context) (column string, tenant interface{}, scoped bool, err error) {
+	return c.Database().TableScope(ctx, c.Name())
+}
+
+// withoutTenant returns the values of an update of the given table without
+// its tenant column if it's scoped by tenant, the rows can't be moved to
+// another tenant.
+func (r *Result) withoutTenant(ctx context.Context, table string, values interface{}) (interface{}, error) {
+	sess, ok := r.SQLBuilder().(hasTableScope)
+	if !ok {
+		return values, nil
+	}
+	column, _, scoped, err := sess.TableScope(ctx, table)
+	if !scoped {
+		return values, err
+	}
+
+	options := sess.MapOptions()
+	options.AutoNow = sess.Clock()()
+	// Keys are given on insert only.
+	options.NewUUID = nil
+	names, vals, err := sqlbuilder.Map(values, options)
+	if err != nil {
+		if err == sqlbuilder.ErrExpectingPointerToEitherMapOrStruct {
+			return values, nil
+		}
+		return nil, err
+	}
+
+	without := make(map[string]interface{}, len(names))
+	for i := range names {
+		if names[i] != column {
+			without[names[i]] = vals[i]
+		}
+	}
+	return without, nil
+}
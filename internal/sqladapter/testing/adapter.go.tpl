@@ -3,7 +3,10 @@
 package ADAPTER
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -12,11 +15,14 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"upper.io/db.v3"
+	"upper.io/db.v3/lib/dbtest"
+	"upper.io/db.v3/lib/migrate"
 	"upper.io/db.v3/lib/sqlbuilder"
 )
 
@@ -404,6 +410,129 @@ func TestInsertReturningWithinTransaction(t *testing.T) {
 	assert.NoError(t, sess.Close())
 }
 
+func TestInsertReturningAll(t *testing.T) {
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+
+	err := artist.Truncate()
+	assert.NoError(t, err)
+
+	type artistT struct {
+		ID   int    `db:"id,omitempty"`
+		Name string `db:"name"`
+	}
+
+	items := []*artistT{
+		{Name: "Ozzie"},
+		{Name: "Flea"},
+		{Name: "Slash"},
+	}
+	err = artist.InsertReturningAll(items)
+	assert.NoError(t, err)
+
+	for _, item := range items {
+		assert.NotZero(t, item.ID, "Must not be zero after inserting")
+
+		var stored artistT
+		err = artist.Find(item.ID).One(&stored)
+		assert.NoError(t, err)
+		assert.Equal(t, *item, stored)
+	}
+
+	err = artist.InsertReturningAll([]artistT{{Name: "Janus"}})
+	assert.Error(t, err, "Should not happen, using pointers should be enforced")
+
+	err = artist.InsertReturningAll(items[0])
+	assert.Error(t, err, "Should not happen, using a slice should be enforced")
+
+	// Counting elements, must be exactly 3 elements.
+	count, err := artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), count, "Expecting 3 elements")
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestUpsert(t *testing.T) {
+	if Adapter == "ql" || Adapter == "mssql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+
+	err := artist.Truncate()
+	assert.NoError(t, err)
+
+	err = artist.Upsert(artistType{Name: "Ozzie"})
+	assert.NoError(t, err)
+
+	var item artistType
+	err = artist.Find().One(&item)
+	assert.NoError(t, err)
+	assert.NotZero(t, item.ID)
+	assert.Equal(t, "Ozzie", item.Name)
+
+	// Same ID, the existing row must be updated.
+	err = artist.Upsert(artistType{ID: item.ID, Name: "Ozzy"})
+	assert.NoError(t, err)
+
+	count, err := artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count, "Expecting 1 element")
+
+	err = artist.Find(item.ID).One(&item)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ozzy", item.Name)
+
+	// Conflicting rows are left untouched with DoNothing.
+	_, err = sess.InsertInto("artist").
+		Values(artistType{ID: item.ID, Name: "John"}).
+		OnConflict("id").
+		DoNothing().
+		Exec()
+	assert.NoError(t, err)
+
+	err = artist.Find(item.ID).One(&item)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ozzy", item.Name)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestContextCanceled(t *testing.T) {
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := artist.InsertContext(ctx, artistType{Name: "Ozzie"})
+	assert.Error(t, err)
+
+	_, err = artist.Find().CountContext(ctx)
+	assert.Error(t, err)
+
+	var items []artistType
+	err = artist.Find().AllContext(ctx, &items)
+	assert.Error(t, err)
+
+	err = artist.Find().UpdateContext(ctx, map[string]interface{}{"name": "Ozzy"})
+	assert.Error(t, err)
+
+	// Methods without a context keep using the session's context.
+	_, err = artist.Find().Count()
+	assert.NoError(t, err)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
 func TestInsertIntoArtistsTable(t *testing.T) {
 	sess := mustOpen()
 
@@ -880,6 +1009,54 @@ func TestUpdate(t *testing.T) {
 	assert.NoError(t, sess.Close())
 }
 
+func TestUpdateReturningAndDeleteReturning(t *testing.T) {
+	if Adapter != "postgresql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+
+	err := artist.Truncate()
+	assert.NoError(t, err)
+
+	type artistT struct {
+		ID   int64  `db:"id,omitempty"`
+		Name string `db:"name"`
+	}
+
+	for _, name := range []string{"Ozzie", "Flea", "Slash"} {
+		_, err = artist.Insert(artistT{Name: name})
+		assert.NoError(t, err)
+	}
+
+	var ozzie artistT
+	err = artist.Find(db.Cond{"name": "Ozzie"}).One(&ozzie)
+	assert.NoError(t, err)
+
+	item := artistT{Name: "Ozzy"}
+	err = artist.Find(ozzie.ID).UpdateReturning(&item)
+	assert.NoError(t, err)
+	assert.Equal(t, ozzie.ID, item.ID, "Must be read back from the database")
+	assert.Equal(t, "Ozzy", item.Name)
+
+	err = artist.Find(ozzie.ID).UpdateReturning(artistT{Name: "Ozzie"})
+	assert.Error(t, err, "Should not happen, using a pointer should be enforced")
+
+	var deleted []artistT
+	err = artist.Find(db.Cond{"name": []string{"Ozzy", "Slash"}}).DeleteReturning(&deleted)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(deleted))
+
+	count, err := artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count, "Expecting 1 element")
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
 func TestFunction(t *testing.T) {
 	sess := mustOpen()
 
@@ -1086,6 +1263,14 @@ func TestCompositeKeys(t *testing.T) {
 		assert.NoError(t, err)
 
 		assert.Equal(t, item2.SomeVal, item.SomeVal)
+
+		var item3 itemWithCompoundKey
+		err = compositeKeys.Find(db.ID{"code": item.Code, "user_id": item.UserID}).One(&item3)
+		assert.NoError(t, err)
+		assert.Equal(t, item, item3)
+
+		err = compositeKeys.Find(db.ID{"code": item.Code}).One(&item3)
+		assert.Error(t, err)
 	}
 
 	{
@@ -1099,6 +1284,7 @@ func TestCompositeKeys(t *testing.T) {
 
 		err := compositeKeys.InsertReturning(&item)
 		assert.NoError(t, err)
+		assert.Equal(t, "Some value", item.SomeVal)
 	}
 
 	assert.NoError(t, cleanUpCheck(sess))
@@ -1233,6 +1419,61 @@ func TestTransactionsAndRollback(t *testing.T) {
 	assert.NoError(t, sess.Close())
 }
 
+func TestNestedTransactions(t *testing.T) {
+	if Adapter == "ql" || Adapter == "mssql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	err := sess.Collection("artist").Truncate()
+	assert.NoError(t, err)
+
+	tx, err := sess.NewTx(nil)
+	assert.NoError(t, err)
+	defer tx.Close()
+
+	_, err = tx.Collection("artist").Insert(artistType{1, "First"})
+	assert.NoError(t, err)
+
+	// A nested transaction that is rolled back must not affect the outer one.
+	err = tx.Tx(nil, func(nested sqlbuilder.Tx) error {
+		artist := nested.Collection("artist")
+
+		_, err := artist.Insert(artistType{2, "Second"})
+		assert.NoError(t, err)
+
+		// Will fail.
+		_, err = artist.Insert(artistType{1, "Duplicated"})
+		assert.Error(t, err)
+
+		return err
+	})
+	assert.Error(t, err)
+
+	// A nested transaction that is committed keeps its changes.
+	err = tx.Tx(nil, func(nested sqlbuilder.Tx) error {
+		_, err := nested.Collection("artist").Insert(artistType{3, "Third"})
+		return err
+	})
+	assert.NoError(t, err)
+
+	count, err := tx.Collection("artist").Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+
+	err = tx.Commit()
+	assert.NoError(t, err)
+
+	var artists []artistType
+	err = sess.Collection("artist").Find().OrderBy("id").All(&artists)
+	assert.NoError(t, err)
+	assert.Equal(t, []artistType{{1, "First"}, {3, "Third"}}, artists)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
 func TestDataTypes(t *testing.T) {
 	if Adapter == "ql" {
 		t.Skip("Currently not supported.")
@@ -1699,6 +1940,26 @@ func TestPaginator(t *testing.T) {
 			}
 			resultPaginator = resultPaginator.PrevPage(items[0].ID)
 		}
+
+		// Using encoded cursors.
+		resultPaginator = resultPaginator.Cursor(cursorColumn).Page(1)
+		for i := 0; ; i++ {
+			var items []artistType
+
+			err = resultPaginator.All(&items)
+			assert.NoError(t, err)
+
+			if len(items) < 1 {
+				break
+			}
+
+			assert.Equal(t, fmt.Sprintf("artist-%d", fifteenResults*i), items[0].Name)
+
+			cursor, err := resultPaginator.NextPageCursor(&items)
+			assert.NoError(t, err)
+
+			resultPaginator = resultPaginator.NextPage(cursor)
+		}
 	}
 
 	{
@@ -1833,6 +2094,1599 @@ func TestSQLBuilder(t *testing.T) {
 	assert.NoError(t, sess.Close())
 }
 
+func TestSelectWithCTE(t *testing.T) {
+	if Adapter == "ql" || Adapter == "mysql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	err := sess.Collection("artist").Truncate()
+	assert.NoError(t, err)
+
+	for _, name := range []string{"Ozzie", "Flea", "Slash"} {
+		_, err = sess.Collection("artist").Insert(map[string]string{"name": name})
+		assert.NoError(t, err)
+	}
+
+	var artists []artistType
+	err = sess.Select("name").
+		With("short_names", sess.Select("name").From("artist").Where("name <> ?", "Ozzie")).
+		From("short_names").
+		OrderBy("name").
+		All(&artists)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(artists))
+	assert.Equal(t, "Flea", artists[0].Name)
+
+	var numbers []struct {
+		N int `db:"n"`
+	}
+	err = sess.Select("n").
+		WithRecursive("t(n)", db.Raw("SELECT 1 UNION ALL SELECT n + 1 FROM t WHERE n < ?", 5)).
+		From("t").
+		All(&numbers)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(numbers))
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestSelectUnion(t *testing.T) {
+	if Adapter == "ql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	err := sess.Collection("artist").Truncate()
+	assert.NoError(t, err)
+
+	for _, name := range []string{"Ozzie", "Flea", "Slash", "Tom"} {
+		_, err = sess.Collection("artist").Insert(map[string]string{"name": name})
+		assert.NoError(t, err)
+	}
+
+	q := sess.Select("name").From("artist").Where("name = ?", "Tom").
+		Union(sess.Select("name").From("artist").Where("name IN ?", []string{"Flea", "Slash", "Tom"})).
+		OrderBy("name")
+
+	var artists []artistType
+	err = q.All(&artists)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(artists))
+	assert.Equal(t, "Flea", artists[0].Name)
+	assert.Equal(t, "Tom", artists[2].Name)
+
+	err = q.Limit(1).Offset(1).All(&artists)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(artists))
+	assert.Equal(t, "Slash", artists[0].Name)
+
+	total, err := q.Paginate(2).TotalEntries()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), total)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestSelectWindowFunction(t *testing.T) {
+	if Adapter == "ql" || Adapter == "mysql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	err := sess.Collection("artist").Truncate()
+	assert.NoError(t, err)
+
+	for _, name := range []string{"Ozzie", "Flea", "Slash"} {
+		_, err = sess.Collection("artist").Insert(map[string]string{"name": name})
+		assert.NoError(t, err)
+	}
+
+	var rows []struct {
+		Name string `db:"name"`
+		Rank int    `db:"rank"`
+	}
+	err = sess.Select(
+		"name",
+		sqlbuilder.Over(db.Func("ROW_NUMBER")).OrderBy("-name").As("rank"),
+	).From("artist").OrderBy("name").All(&rows)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, "Flea", rows[0].Name)
+	assert.Equal(t, 3, rows[0].Rank)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestSelectForUpdate(t *testing.T) {
+	if Adapter == "ql" || Adapter == "sqlite" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	err := sess.Collection("artist").Truncate()
+	assert.NoError(t, err)
+
+	_, err = sess.Collection("artist").Insert(artistType{Name: "Ozzie"})
+	assert.NoError(t, err)
+
+	err = sess.Tx(nil, func(tx sqlbuilder.Tx) error {
+		var artist artistType
+		if err := tx.SelectFrom("artist").Where("name = ?", "Ozzie").ForUpdate().One(&artist); err != nil {
+			return err
+		}
+		_, err := tx.Update("artist").Set("name", "Ozzy").Where("id = ?", artist.ID).Exec()
+		return err
+	})
+	assert.NoError(t, err)
+
+	count, err := sess.Collection("artist").Find("name", "Ozzy").Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestFindByExample(t *testing.T) {
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+
+	err := artist.Truncate()
+	assert.NoError(t, err)
+
+	for _, name := range []string{"Ozzie", "Flea", "Slash"} {
+		_, err = artist.Insert(artistType{Name: name})
+		assert.NoError(t, err)
+	}
+
+	var item artistType
+	err = artist.Find(&artistType{Name: "Flea"}).One(&item)
+	assert.NoError(t, err)
+	assert.Equal(t, "Flea", item.Name)
+
+	count, err := artist.Find(artistType{}).Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), count)
+
+	cond, err := sqlbuilder.Example(&artistType{ID: item.ID}, &sqlbuilder.MapOptions{IncludeZeroed: true})
+	assert.NoError(t, err)
+
+	count, err = artist.Find(cond).Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), count)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+type publicationWithAuthor struct {
+	ID       int64            `db:"id,omitempty"`
+	Title    string           `db:"title"`
+	AuthorID int64            `db:"author_id"`
+	Author   *artistWithWorks `db:"-" relation:"belongsTo:artist,fk:author_id"`
+}
+
+type artistWithWorks struct {
+	ID    int64                   `db:"id,omitempty"`
+	Name  string                  `db:"name"`
+	Works []publicationWithAuthor `db:"-" relation:"hasMany:publication,fk:author_id"`
+}
+
+func TestPreload(t *testing.T) {
+	if Adapter == "ql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+	publication := sess.Collection("publication")
+
+	assert.NoError(t, artist.Truncate())
+	assert.NoError(t, publication.Truncate())
+
+	works := map[string][]string{
+		"Borges":   {"Ficciones", "El Aleph"},
+		"Cortazar": {"Rayuela"},
+		"Rulfo":    {},
+	}
+	for _, name := range []string{"Borges", "Cortazar", "Rulfo"} {
+		id, err := artist.Insert(artistType{Name: name})
+		assert.NoError(t, err)
+		for _, title := range works[name] {
+			_, err := publication.Insert(publicationWithAuthor{Title: title, AuthorID: id.(int64)})
+			assert.NoError(t, err)
+		}
+	}
+
+	var artists []artistWithWorks
+	err := artist.Find().OrderBy("name").Preload("Works.Author").All(&artists)
+	assert.NoError(t, err)
+
+	if assert.Equal(t, 3, len(artists)) {
+		for _, a := range artists {
+			assert.Equal(t, len(works[a.Name]), len(a.Works), a.Name)
+			for _, w := range a.Works {
+				assert.Contains(t, works[a.Name], w.Title)
+				if assert.NotNil(t, w.Author) {
+					assert.Equal(t, a.Name, w.Author.Name)
+				}
+			}
+		}
+	}
+
+	var book publicationWithAuthor
+	err = publication.Find(db.Cond{"title": "Rayuela"}).Preload("Author").One(&book)
+	assert.NoError(t, err)
+	if assert.NotNil(t, book.Author) {
+		assert.Equal(t, "Cortazar", book.Author.Name)
+		assert.Nil(t, book.Author.Works)
+	}
+
+	err = artist.Find().Preload("Publications").All(&artists)
+	assert.Error(t, err)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestSelectInlineStructs(t *testing.T) {
+	if Adapter == "ql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+	publication := sess.Collection("publication")
+
+	assert.NoError(t, artist.Truncate())
+	assert.NoError(t, publication.Truncate())
+
+	id, err := artist.Insert(artistType{Name: "Borges"})
+	assert.NoError(t, err)
+
+	_, err = publication.Insert(publicationWithAuthor{Title: "Ficciones", AuthorID: id.(int64)})
+	assert.NoError(t, err)
+
+	type publicationRow struct {
+		ID       int64  `db:"id"`
+		Title    string `db:"title"`
+		AuthorID int64  `db:"author_id"`
+	}
+
+	var rows []struct {
+		Author      artistType     `db:",inline=a"`
+		Publication publicationRow `db:",inline=p"`
+	}
+
+	err = sess.Select("a.*", "p.*").
+		From("artist AS a").
+		Join("publication AS p").On("p.author_id = a.id").
+		All(&rows)
+	assert.NoError(t, err)
+
+	if assert.Equal(t, 1, len(rows)) {
+		assert.Equal(t, id.(int64), rows[0].Author.ID)
+		assert.Equal(t, "Borges", rows[0].Author.Name)
+		assert.NotZero(t, rows[0].Publication.ID)
+		assert.Equal(t, "Ficciones", rows[0].Publication.Title)
+		assert.Equal(t, id.(int64), rows[0].Publication.AuthorID)
+	}
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestAggregates(t *testing.T) {
+	if Adapter == "ql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	ids := []int64{}
+	for _, name := range []string{"Ozzie", "Flea", "Slash", "Flea"} {
+		id, err := artist.Insert(artistType{Name: name})
+		assert.NoError(t, err)
+		ids = append(ids, id.(int64))
+	}
+
+	{
+		sum, err := artist.Find().Sum("id")
+		assert.NoError(t, err)
+		assert.Equal(t, float64(ids[0]+ids[1]+ids[2]+ids[3]), sum)
+
+		avg, err := artist.Find(db.Cond{"name": "Flea"}).Avg("id")
+		assert.NoError(t, err)
+		assert.Equal(t, float64(ids[1]+ids[3])/2, avg)
+
+		count, err := artist.Find().CountDistinct("name")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(3), count)
+	}
+
+	{
+		var min, max sql.NullInt64
+		assert.NoError(t, artist.Find().Min("id", &min))
+		assert.Equal(t, ids[0], min.Int64)
+
+		assert.NoError(t, artist.Find().Max("id", &max))
+		assert.Equal(t, ids[3], max.Int64)
+
+		var name string
+		assert.NoError(t, artist.Find().Max("name", &name))
+		assert.Equal(t, "Slash", name)
+	}
+
+	{
+		// Only the items of the page are aggregated.
+		sum, err := artist.Find().OrderBy("-id").Paginate(2).Page(2).Sum("id")
+		assert.NoError(t, err)
+		assert.Equal(t, float64(ids[0]+ids[1]), sum)
+
+		count, err := artist.Find().OrderBy("id").Limit(2).CountDistinct("name")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), count)
+	}
+
+	{
+		sum, err := artist.Find(db.Cond{"name": "Nobody"}).Sum("id")
+		assert.NoError(t, err)
+		assert.Equal(t, float64(0), sum)
+
+		var max sql.NullInt64
+		assert.NoError(t, artist.Find(db.Cond{"name": "Nobody"}).Max("id", &max))
+		assert.False(t, max.Valid)
+	}
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestCountEstimate(t *testing.T) {
+	if Adapter != "postgresql" && Adapter != "mysql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	for _, name := range []string{"Ozzie", "Flea", "Slash"} {
+		_, err := artist.Insert(artistType{Name: name})
+		assert.NoError(t, err)
+	}
+
+	// Statistics are gathered by ANALYZE, estimates are approximate but they
+	// can't be zero once the table has rows.
+	if Adapter == "mysql" {
+		_, err := sess.Exec("ANALYZE TABLE artist")
+		assert.NoError(t, err)
+	} else {
+		_, err := sess.Exec("ANALYZE artist")
+		assert.NoError(t, err)
+	}
+
+	count, err := artist.Find().Count(db.CountEstimate)
+	assert.NoError(t, err)
+	assert.True(t, count > 0)
+
+	count, err = artist.Find(db.Cond{"name": "Flea"}).Count(db.CountEstimate)
+	assert.NoError(t, err)
+	assert.True(t, count > 0)
+
+	count, err = artist.Find(db.Cond{"name": "Flea"}).OrderBy("id").Count(db.CountEstimate)
+	assert.NoError(t, err)
+	assert.True(t, count > 0)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestFindByIDs(t *testing.T) {
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	ids := []interface{}{}
+	for _, name := range []string{"Ozzie", "Flea", "Slash", "Chrono"} {
+		id, err := artist.Insert(artistType{Name: name})
+		assert.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	var artists []artistType
+	err := artist.FindByIDs(ids[1:], 2).All(&artists)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(artists))
+
+	count, err := artist.FindByIDs(ids, 3).Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), count)
+
+	var one artistType
+	err = artist.FindByIDs(ids[3:], 1).One(&one)
+	assert.NoError(t, err)
+	assert.Equal(t, "Chrono", one.Name)
+
+	err = artist.Find(db.Cond{"id": db.InChunks(ids, 1)}).Delete()
+	assert.NoError(t, err)
+
+	exists, err := artist.Find().Exists()
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestExists(t *testing.T) {
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	exists, err := artist.Find().Exists()
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	for _, name := range []string{"Ozzie", "Flea"} {
+		_, err := artist.Insert(artistType{Name: name})
+		assert.NoError(t, err)
+	}
+
+	exists, err = artist.Find().Exists()
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = artist.Find(db.Cond{"name": "Flea"}).Exists()
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = artist.Find(db.Cond{"name": "Nobody"}).Exists()
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// Grouped sets tell whether they have groups, like Count does.
+	exists, err = artist.Find().Group("name").Exists()
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = artist.Find(db.Cond{"name": "Nobody"}).Group("name").Exists()
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestInsertFromSelect(t *testing.T) {
+	if Adapter == "ql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	for _, name := range []string{"Ozzie", "Flea", "Slash"} {
+		_, err := artist.Insert(artistType{Name: name})
+		assert.NoError(t, err)
+	}
+
+	sel := sess.Select("name").From("artist").Where("name <> ?", "Slash")
+	_, err := sess.InsertInto("artist").Columns("name").ValuesFromSelect(sel).Exec()
+	assert.NoError(t, err)
+
+	count, err := artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), count)
+
+	count, err = artist.Find("name", "Flea").Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestUniqueViolation(t *testing.T) {
+	if Adapter == "ql" || Adapter == "mssql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	id, err := artist.Insert(artistType{Name: "Ozzie"})
+	assert.NoError(t, err)
+
+	_, err = artist.Insert(map[string]interface{}{"id": id, "name": "Flea"})
+	assert.Error(t, err)
+
+	var uErr *db.ErrUniqueViolation
+	if assert.True(t, errors.As(err, &uErr)) {
+		assert.NotNil(t, uErr.Err)
+		assert.Contains(t, uErr.Error(), "unique constraint")
+	}
+
+	_, err = sess.InsertInto("artist").Values(map[string]interface{}{"id": id, "name": "Slash"}).Exec()
+	assert.True(t, errors.As(err, &uErr))
+
+	count, err := artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestQueryError(t *testing.T) {
+	sess := mustOpen()
+
+	_, err := sess.Exec("SELECT * FROM unknown_table WHERE id = ?", 1)
+	assert.Error(t, err)
+
+	var qErr *db.QueryError
+	if assert.True(t, errors.As(err, &qErr)) {
+		assert.Equal(t, Adapter, qErr.Adapter)
+		assert.Contains(t, qErr.Query, "unknown_table")
+		assert.NotNil(t, qErr.Err)
+		assert.Contains(t, qErr.Error(), qErr.Err.Error())
+	}
+
+	_, err = sess.Query("SELECT * FROM unknown_table")
+	assert.True(t, errors.As(err, &qErr))
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestStatementMiddleware(t *testing.T) {
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	_, err := artist.Insert(artistType{Name: "Ozzie"})
+	assert.NoError(t, err)
+
+	errReadOnly := errors.New("read only")
+
+	types := []string{}
+	sess.Use(func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler {
+		return func(ctx context.Context, stmt *sqlbuilder.Statement) error {
+			types = append(types, stmt.Type)
+			if stmt.Type == "delete" {
+				return errReadOnly
+			}
+			return next(ctx, stmt)
+		}
+	})
+
+	count, err := artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	err = artist.Find().Delete()
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, errReadOnly))
+
+	// Middleware is shared by transactions.
+	err = sess.Tx(nil, func(tx sqlbuilder.Tx) error {
+		_, err := tx.DeleteFrom("artist").Exec()
+		return err
+	})
+	assert.True(t, errors.Is(err, errReadOnly))
+
+	count, err = artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	assert.Contains(t, types, "select")
+	assert.Contains(t, types, "delete")
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestPreparedStatementCacheControls(t *testing.T) {
+	sess := mustOpen()
+
+	sess.SetPreparedStatementCache(true)
+	sess.SetPreparedStatementCacheSize(2)
+	assert.Equal(t, 2, sess.PreparedStatementCacheSize())
+
+	countQuery := func(ctx context.Context, i int) {
+		var count map[string]uint64
+		err := sess.Select(db.Raw(fmt.Sprintf("count(%d) AS c", i))).From("artist").OneContext(ctx, &count)
+		assert.NoError(t, err)
+	}
+
+	before := sess.Metrics()
+	for i := 0; i < 5; i++ {
+		countQuery(context.Background(), i)
+	}
+	countQuery(context.Background(), 4)
+
+	after := sess.Metrics()
+	assert.Equal(t, uint64(5), after.PreparedStatementCacheMisses-before.PreparedStatementCacheMisses)
+	assert.Equal(t, uint64(1), after.PreparedStatementCacheHits-before.PreparedStatementCacheHits)
+	assert.Equal(t, uint64(3), after.PreparedStatementCacheEvictions-before.PreparedStatementCacheEvictions)
+
+	// Statements can opt out of the cache.
+	before = after
+	countQuery(sqlbuilder.WithoutPreparedStatementCache(context.Background()), 10)
+
+	after = sess.Metrics()
+	assert.Equal(t, before.PreparedStatementCacheMisses, after.PreparedStatementCacheMisses)
+	assert.Equal(t, before.PreparedStatementCacheHits, after.PreparedStatementCacheHits)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestPoolSettings(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	sess.SetMaxOpenConns(3)
+	sess.SetConnMaxIdleTime(time.Minute)
+
+	assert.Equal(t, time.Minute, sess.ConnMaxIdleTime())
+	assert.Equal(t, 3, sess.Stats().MaxOpenConnections)
+
+	_, err := sess.Collection("artist").Find().Count()
+	assert.NoError(t, err)
+	assert.True(t, sess.Stats().OpenConnections > 0)
+}
+
+func TestOnConnect(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	var connects int32
+	sess.OnConnect(func(ctx context.Context, conn sqlbuilder.Conn) error {
+		assert.NotNil(t, conn.Driver())
+		atomic.AddInt32(&connects, 1)
+		return nil
+	})
+
+	_, err := sess.Collection("artist").Find().Count()
+	assert.NoError(t, err)
+	assert.True(t, atomic.LoadInt32(&connects) > 0)
+
+	errDenied := errors.New("denied")
+	sess.OnConnect(func(ctx context.Context, conn sqlbuilder.Conn) error {
+		return errDenied
+	})
+
+	_, err = sess.Collection("artist").Find().Count()
+	assert.True(t, errors.Is(err, errDenied))
+}
+
+func TestHealthCheck(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	assert.NoError(t, sess.PingContext(context.Background()))
+
+	report := sess.HealthCheck(context.Background())
+	assert.True(t, report.Healthy())
+	assert.NoError(t, report.Err())
+	assert.True(t, report.Latency > 0)
+	assert.Empty(t, report.Replicas)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report = sess.HealthCheck(ctx)
+	assert.False(t, report.Healthy())
+	assert.True(t, errors.Is(report.Err(), context.Canceled))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	assert.False(t, sess.CircuitOpen())
+
+	sess.SetCircuitBreaker(sqlbuilder.CircuitBreaker{
+		Threshold:     3,
+		ProbeInterval: 100 * time.Millisecond,
+	})
+
+	// Statement errors that are not connection failures don't open the
+	// circuit.
+	for i := 0; i < 5; i++ {
+		_, err := sess.Exec(`SELECT * FROM table_that_does_not_exist`)
+		assert.Error(t, err)
+	}
+	assert.False(t, sess.CircuitOpen())
+
+	_, err := sess.Collection("artist").Find().Count()
+	assert.NoError(t, err)
+
+	sess.SetCircuitBreaker(sqlbuilder.CircuitBreaker{})
+}
+
+func TestQueryTimeout(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	var artists []map[string]interface{}
+
+	err := sess.SelectFrom("artist").Timeout(time.Nanosecond).All(&artists)
+	assert.True(t, errors.Is(err, db.ErrQueryTimeout))
+
+	err = sess.SelectFrom("artist").Timeout(time.Minute).All(&artists)
+	assert.NoError(t, err)
+
+	sess.SetMaxQueryDuration(time.Nanosecond)
+
+	_, err = sess.Collection("artist").Find().Count()
+	assert.True(t, errors.Is(err, db.ErrQueryTimeout))
+
+	// The timeout of a query replaces the one of the session.
+	err = sess.SelectFrom("artist").Timeout(time.Minute).All(&artists)
+	assert.NoError(t, err)
+
+	// Statements that are cancelled by their own context are not timeouts.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = sess.ExecContext(ctx, `DELETE FROM artist WHERE id = ?`, -1)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	var logged []*db.QueryStatus
+	sess.SetLogger(db.QueryLoggerFunc(func(q *db.QueryStatus) {
+		logged = append(logged, q)
+	}))
+
+	sess.SetSlowQueryThreshold(time.Hour)
+	sess.SetExplainSlowQueries(true)
+
+	var artists []map[string]interface{}
+	assert.NoError(t, sess.SelectFrom("artist").All(&artists))
+	assert.Empty(t, logged)
+
+	sess.SetSlowQueryThreshold(time.Nanosecond)
+
+	assert.NoError(t, sess.SelectFrom("artist").All(&artists))
+	assert.Equal(t, 1, len(logged))
+	assert.True(t, logged[0].Slow)
+
+	if Adapter == "postgresql" || Adapter == "mysql" {
+		assert.NotEmpty(t, logged[0].Plan)
+		assert.False(t, strings.HasPrefix(logged[0].Plan, "error:"), logged[0].Plan)
+	}
+
+	sess.SetSlowQueryThreshold(0)
+	sess.SetLogger(nil)
+}
+
+func TestExplain(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	ctx := context.Background()
+
+	q := sess.SelectFrom("artist").Where("id = ?", 1)
+	plan, err := q.Explain(ctx)
+
+	if Adapter != "postgresql" && Adapter != "mysql" {
+		assert.Equal(t, db.ErrUnsupported, err)
+		return
+	}
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, plan)
+
+	plan, err = q.Explain(sqlbuilder.WithExplainJSON(ctx))
+	assert.NoError(t, err)
+	var tree interface{}
+	assert.NoError(t, json.Unmarshal([]byte(plan), &tree), plan)
+
+	plan, err = sess.Collection("artist").Find(db.Cond{"id": 1}).Explain(ctx)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, plan)
+}
+
+func TestDryRun(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	artist := sess.Collection("artist")
+
+	before, err := artist.Find().Count()
+	assert.NoError(t, err)
+
+	var logged []*db.QueryStatus
+	sess.SetLogger(db.QueryLoggerFunc(func(q *db.QueryStatus) {
+		logged = append(logged, q)
+	}))
+	sess.SetDryRun(true)
+
+	_, err = artist.Insert(map[string]string{"name": "Dry Run"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, artist.Find().Update(map[string]string{"name": "Dry Run"}))
+	assert.NoError(t, artist.Find().Delete())
+
+	_, err = sess.Exec("DELETE FROM artist")
+	assert.NoError(t, err)
+
+	after, err := artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	n, err := artist.Find(db.Cond{"name": "Dry Run"}).Count()
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+
+	dryRuns := 0
+	for _, q := range logged {
+		if q.DryRun {
+			dryRuns++
+		}
+	}
+	assert.True(t, dryRuns >= 4)
+
+	sess.SetDryRun(false)
+	sess.SetLogger(nil)
+}
+
+func TestResultCache(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	resultCache, err := db.NewMemoryResultCache(100, 0)
+	assert.NoError(t, err)
+
+	queries := 0
+	sess.SetLogger(db.QueryLoggerFunc(func(q *db.QueryStatus) {
+		queries++
+	}))
+	sess.SetResultCache(resultCache)
+
+	artist := sess.Collection("artist")
+	res := artist.Find().OrderBy("id")
+
+	var first []artistType
+	assert.NoError(t, res.All(&first))
+
+	seen := queries
+
+	var second []artistType
+	assert.NoError(t, res.All(&second))
+	assert.Equal(t, first, second)
+	assert.Equal(t, seen, queries)
+
+	_, err = artist.Insert(artistType{Name: "Cached"})
+	assert.NoError(t, err)
+
+	var third []artistType
+	assert.NoError(t, res.All(&third))
+	assert.Equal(t, len(first)+1, len(third))
+
+	var cached artistType
+	assert.NoError(t, artist.Find(db.Cond{"name": "Cached"}).One(&cached))
+	assert.Equal(t, "Cached", cached.Name)
+
+	sess.SetResultCache(nil)
+	sess.SetLogger(nil)
+}
+
+type beforeChangeListener struct {
+	events []*db.ChangeEvent
+}
+
+func (l *beforeChangeListener) OnChange(ev *db.ChangeEvent) {
+	l.events = append(l.events, ev)
+}
+
+func (l *beforeChangeListener) WantsBefore(collection string) bool {
+	return collection == "artist"
+}
+
+func TestBeforeChangeListener(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	artist := sess.Collection("artist")
+	id, err := artist.Insert(artistType{Name: "Before"})
+	assert.NoError(t, err)
+
+	listener := &beforeChangeListener{}
+	sess.SetChangeListener(listener)
+	defer sess.SetChangeListener(nil)
+
+	err = artist.Find(id).Update(map[string]interface{}{"name": "After"})
+	assert.NoError(t, err)
+
+	if assert.Equal(t, 1, len(listener.events)) {
+		ev := listener.events[0]
+		assert.Equal(t, db.ChangeUpdate, ev.Operation)
+
+		before, ok := ev.Before.([]map[string]interface{})
+		if assert.True(t, ok) && assert.Equal(t, 1, len(before)) {
+			assert.Equal(t, "Before", fmt.Sprintf("%s", before[0]["name"]))
+		}
+		assert.Equal(t, map[string]interface{}{"name": "After"}, ev.After)
+	}
+}
+
+func TestCollapseReads(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	sess.SetCollapseReads(true)
+	defer sess.SetCollapseReads(false)
+
+	var expected []artistType
+	assert.NoError(t, sess.Collection("artist").Find().OrderBy("id").All(&expected))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var artists []artistType
+			assert.NoError(t, sess.Collection("artist").Find().OrderBy("id").All(&artists))
+			assert.Equal(t, expected, artists)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWrapTx(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	before, err := sess.Collection("artist").Find().Count()
+	assert.NoError(t, err)
+
+	t.Run("Insert", func(t *testing.T) {
+		tx := dbtest.WrapTx(t, sess)
+
+		_, err := tx.Collection("artist").Insert(artistType{Name: "Rolled Back"})
+		assert.NoError(t, err)
+
+		n, err := tx.Collection("artist").Find().Count()
+		assert.NoError(t, err)
+		assert.Equal(t, before+1, n)
+	})
+
+	after, err := sess.Collection("artist").Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestBatch(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	res := sess.Collection("artist").Find().OrderBy("id")
+
+	var all []artistType
+	assert.NoError(t, res.All(&all))
+
+	var batched []artistType
+	for batch := range res.Batch(context.Background(), 2, []artistType(nil)) {
+		artists := batch.([]artistType)
+		assert.True(t, len(artists) > 0 && len(artists) <= 2)
+		batched = append(batched, artists...)
+	}
+	assert.NoError(t, res.Err())
+	assert.Equal(t, all, batched)
+
+	// Errors are sent as the last value of the channel.
+	var values []interface{}
+	for batch := range res.Batch(context.Background(), 2, artistType{}) {
+		values = append(values, batch)
+	}
+	assert.Equal(t, []interface{}{db.ErrUnsupportedDestination}, values)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := res.Batch(ctx, 1, []*artistType(nil))
+	if len(all) > 1 {
+		<-batches
+	}
+	cancel()
+	for range batches {
+	}
+}
+
+func TestCursor(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	q := sess.SelectFrom("artist").OrderBy("id")
+
+	var all []artistType
+	assert.NoError(t, q.All(&all))
+
+	var fetched []artistType
+	err := q.Cursor("artist_cursor", 2).All(&fetched)
+
+	if Adapter != "postgresql" {
+		assert.Equal(t, db.ErrUnsupported, err)
+		return
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, all, fetched)
+
+	// Within a transaction the cursor is declared within it.
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		iter := tx.SelectFrom("artist").OrderBy("id").Cursor("artist_cursor", 1).Iterator()
+		defer iter.Close()
+
+		var artist artistType
+		for i := range all {
+			if !iter.Next(&artist) {
+				return iter.Err()
+			}
+			assert.Equal(t, all[i], artist)
+		}
+		assert.False(t, iter.Next(&artist))
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestScanDestinations(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+	for _, name := range []string{"Ozzie", "Flea"} {
+		_, err := artist.Insert(artistType{Name: name})
+		assert.NoError(t, err)
+	}
+
+	res := artist.Find().OrderBy("id")
+
+	var all []artistType
+	assert.NoError(t, res.All(&all))
+
+	var ids []int64
+	assert.NoError(t, res.Select("id").All(&ids))
+	assert.Equal(t, len(all), len(ids))
+
+	var names []string
+	assert.NoError(t, sess.Select("name").From("artist").OrderBy("id").All(&names))
+	for i := range all {
+		assert.Equal(t, all[i].ID, ids[i])
+		assert.Equal(t, all[i].Name, names[i])
+	}
+
+	var rows []map[string]interface{}
+	assert.NoError(t, res.All(&rows))
+	assert.Equal(t, len(all), len(rows))
+
+	row := map[string]interface{}{}
+	assert.NoError(t, res.One(&row))
+	assert.Contains(t, row, "name")
+
+	var name *string
+	assert.NoError(t, res.Select("name").One(&name))
+	if assert.NotNil(t, name) {
+		assert.Equal(t, all[0].Name, *name)
+	}
+
+	err := res.All(&names)
+	assert.Equal(t, sqlbuilder.ErrExpectingSingleColumn, err)
+}
+
+func TestIteratorColumns(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	iter := sess.Select("id", "name").From("artist").Iterator()
+
+	columns := iter.Columns()
+	assert.NoError(t, iter.Err())
+	if assert.Equal(t, 2, len(columns)) {
+		assert.Equal(t, "id", strings.ToLower(columns[0].Name))
+		assert.Equal(t, "name", strings.ToLower(columns[1].Name))
+		for _, column := range columns {
+			assert.NotNil(t, column.ScanType)
+		}
+	}
+
+	assert.NoError(t, iter.Close())
+	assert.Nil(t, iter.Columns())
+}
+
+func TestForEach(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	res := sess.Collection("artist").Find().OrderBy("id")
+
+	var all []artistType
+	assert.NoError(t, res.All(&all))
+
+	var (
+		artist  artistType
+		fetched []artistType
+	)
+	err := res.ForEach(func(scan func(dest ...interface{}) error) error {
+		if err := scan(&artist); err != nil {
+			return err
+		}
+		fetched = append(fetched, artist)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, all, fetched)
+
+	var ids []int64
+	err = sess.Select("id").From("artist").OrderBy("id").Iterator().ForEach(func(scan func(dest ...interface{}) error) error {
+		var id int64
+		if err := scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, len(all), len(ids))
+
+	stop := errors.New("stop")
+	calls := 0
+	err = res.ForEach(func(scan func(dest ...interface{}) error) error {
+		calls++
+		return stop
+	})
+	if len(all) > 0 {
+		assert.Equal(t, stop, err)
+		assert.Equal(t, 1, calls)
+	}
+}
+
+func TestFieldMapping(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	sess.SetFieldMapping(&db.FieldMapping{Tag: "json"})
+
+	type jsonArtist struct {
+		ID   int64  `json:"id,omitempty"`
+		Name string `json:"name"`
+	}
+
+	item := jsonArtist{Name: "Ozzie"}
+	assert.NoError(t, artist.InsertReturning(&item))
+	assert.NotZero(t, item.ID)
+
+	var fetched jsonArtist
+	assert.NoError(t, artist.Find(item.ID).One(&fetched))
+	assert.Equal(t, item, fetched)
+
+	sess.SetFieldMapping(&db.FieldMapping{NameMapper: db.SnakeCase})
+
+	type snakeArtist struct {
+		ID   int64
+		Name string
+	}
+
+	var snaked []snakeArtist
+	assert.NoError(t, sess.SelectFrom("artist").All(&snaked))
+	assert.Equal(t, []snakeArtist{{ID: item.ID, Name: "Ozzie"}}, snaked)
+
+	var named snakeArtist
+	row, err := sess.QueryRow(`SELECT name FROM artist WHERE name = :name`, snakeArtist{Name: "Ozzie"})
+	assert.NoError(t, err)
+	assert.NoError(t, row.Scan(&named.Name))
+	assert.Equal(t, "Ozzie", named.Name)
+
+	sess.SetFieldMapping(nil)
+	assert.Nil(t, sess.FieldMapping())
+}
+
+func TestCodecs(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	type fullName struct {
+		First string
+		Last  string
+	}
+
+	codecs := db.NewCodecs()
+	codecs.Register(fullName{}, db.Codec{
+		Encode: func(v interface{}) (interface{}, error) {
+			name := v.(fullName)
+			return name.First + " " + name.Last, nil
+		},
+		Decode: func(src interface{}, dst interface{}) error {
+			name := dst.(*fullName)
+			if src == nil {
+				*name = fullName{}
+				return nil
+			}
+			parts := strings.SplitN(fmt.Sprintf("%s", src), " ", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid name %q", src)
+			}
+			name.First, name.Last = parts[0], parts[1]
+			return nil
+		},
+	})
+	sess.SetCodecs(codecs)
+
+	type namedArtist struct {
+		ID   int64    `db:"id,omitempty"`
+		Name fullName `db:"name"`
+	}
+
+	ozzie := fullName{"Ozzy", "Osbourne"}
+	_, err := artist.Insert(namedArtist{Name: ozzie})
+	assert.NoError(t, err)
+	_, err = artist.Insert(map[string]interface{}{"name": &fullName{"Ronnie", "Dio"}})
+	assert.NoError(t, err)
+
+	var item namedArtist
+	assert.NoError(t, artist.Find(db.Cond{"name": ozzie}).One(&item))
+	assert.Equal(t, ozzie, item.Name)
+
+	var names []fullName
+	assert.NoError(t, sess.Select("name").From("artist").OrderBy("id").All(&names))
+	assert.Equal(t, []fullName{ozzie, {"Ronnie", "Dio"}}, names)
+
+	var name fullName
+	assert.NoError(t, sess.Select("name").From("artist").Where("name = ?", ozzie).Iterator().ScanOne(&name))
+	assert.Equal(t, ozzie, name)
+
+	var raw string
+	assert.NoError(t, sess.Select("name").From("artist").Where("name = ?", ozzie).Iterator().ScanOne(&raw))
+	assert.Equal(t, "Ozzy Osbourne", raw)
+}
+
+func TestZeroValuePolicy(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	type nullableArtist struct {
+		ID   int64          `db:"id,omitempty"`
+		Name sql.NullString `db:"name"`
+	}
+
+	_, err := artist.Insert(artistType{})
+	assert.NoError(t, err)
+
+	sess.SetZeroValuePolicy(db.ZeroAsNull)
+	assert.Equal(t, db.ZeroAsNull, sess.ZeroValuePolicy())
+
+	_, err = artist.Insert(artistType{})
+	assert.NoError(t, err)
+
+	var items []nullableArtist
+	assert.NoError(t, artist.Find().OrderBy("id").All(&items))
+	assert.Equal(t, 2, len(items))
+	assert.True(t, items[0].Name.Valid)
+	assert.False(t, items[1].Name.Valid)
+
+	assert.NoError(t, artist.Find(items[0].ID).Update(struct {
+		Name string `db:"name"`
+	}{}))
+	count, err := artist.Find(db.Cond{"name IS": nil}).Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+}
+
+func TestCompile(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	driver := sess.Driver().(*sql.DB)
+
+	query, args, err := sess.SelectFrom("artist").Where("id IN ?", []int{1, 2}).Compile()
+	assert.NoError(t, err)
+
+	rows, err := driver.Query(query, args...)
+	assert.NoError(t, err)
+	assert.NoError(t, rows.Close())
+
+	query, args, err = sess.Collection("artist").Find(db.Cond{"id": 1}).Compile()
+	assert.NoError(t, err)
+
+	rows, err = driver.Query(query, args...)
+	assert.NoError(t, err)
+	assert.NoError(t, rows.Close())
+}
+
+func TestNamedParameters(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	artist := sess.Collection("artist")
+
+	_, err := artist.Insert(map[string]string{"name": "Named Parameter"})
+	assert.NoError(t, err)
+	defer artist.Find(db.Cond{"name": "Named Parameter"}).Delete()
+
+	params := struct {
+		Name string `db:"name"`
+	}{"Named Parameter"}
+
+	row, err := sess.QueryRow(`SELECT COUNT(*) FROM artist WHERE name = :name`, params)
+	assert.NoError(t, err)
+
+	var count int
+	assert.NoError(t, row.Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+type artistWithHooks struct {
+	ID   int64  `db:"id,omitempty"`
+	Name string `db:"name"`
+}
+
+func (a *artistWithHooks) BeforeInsert(sess db.Database) error {
+	if a.Name == "" {
+		return errors.New("name is required")
+	}
+	a.Name = strings.ToUpper(a.Name[:1]) + a.Name[1:]
+	return nil
+}
+
+func (a *artistWithHooks) BeforeUpdate(sess db.Database) error {
+	a.Name = strings.ToUpper(a.Name)
+	return nil
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	sess := mustOpen()
+
+	artist := sess.Collection("artist")
+
+	err := artist.Truncate()
+	assert.NoError(t, err)
+
+	_, err = artist.Insert(&artistWithHooks{})
+	assert.Error(t, err)
+
+	item := artistWithHooks{Name: "flea"}
+	err = artist.InsertReturning(&item)
+	assert.NoError(t, err)
+	assert.Equal(t, "Flea", item.Name)
+
+	item.Name = "slash"
+	err = artist.UpdateReturning(&item)
+	assert.NoError(t, err)
+	assert.Equal(t, "SLASH", item.Name)
+
+	count, err := artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestMigrate(t *testing.T) {
+	if Adapter == "ql" {
+		t.Skip("Currently not supported.")
+	}
+
+	sess := mustOpen()
+
+	m := migrate.New(
+		&migrate.Migration{
+			Version: 1,
+			Name:    "create_migrated",
+			Up:      migrate.SQL(`CREATE TABLE migrated (id INTEGER NOT NULL PRIMARY KEY)`),
+			Down:    migrate.SQL(`DROP TABLE migrated`),
+		},
+		&migrate.Migration{
+			Version: 2,
+			Name:    "seed_migrated",
+			Up:      migrate.SQL(`INSERT INTO migrated (id) VALUES (1)`),
+			Down:    migrate.SQL(`DELETE FROM migrated`),
+		},
+	)
+	m.Table = "migrate_test"
+
+	defer func() {
+		sess.Exec(`DROP TABLE migrate_test`)
+		sess.Exec(`DROP TABLE migrate_test_lock`)
+	}()
+
+	assert.NoError(t, m.Migrate(sess))
+
+	count, err := sess.Collection("migrated").Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	// Applied migrations are skipped.
+	assert.NoError(t, m.Migrate(sess))
+
+	status, err := m.Status(sess)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(status))
+	for i := range status {
+		assert.True(t, status[i].Applied)
+	}
+
+	assert.NoError(t, m.Rollback(sess, 2))
+	assert.False(t, sess.Collection("migrated").Exists())
+
+	status, err = m.Status(sess)
+	assert.NoError(t, err)
+	for i := range status {
+		assert.False(t, status[i].Applied)
+	}
+
+	// The error of a migration is wrapped.
+	errFailed := errors.New("failed")
+	failing := migrate.New(&migrate.Migration{
+		Version: 3,
+		Name:    "fail",
+		Up: func(sqlbuilder.Tx) error {
+			return errFailed
+		},
+	})
+	failing.Table = m.Table
+	assert.True(t, errors.Is(failing.Migrate(sess), errFailed))
+
+	// The lock of a runner that crashed is removed with Unlock.
+	_, err = sess.InsertInto("migrate_test_lock").Values(map[string]interface{}{"id": 1}).Exec()
+	assert.NoError(t, err)
+	assert.Equal(t, migrate.ErrLocked, m.Migrate(sess))
+	assert.NoError(t, m.Unlock(sess))
+	assert.NoError(t, m.Migrate(sess))
+	assert.NoError(t, m.Rollback(sess, 2))
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestSchema(t *testing.T) {
+	sess := mustOpen()
+
+	schema, err := sess.Schema()
+	if Adapter == "ql" || Adapter == "mssql" {
+		assert.Equal(t, db.ErrUnsupported, err)
+		assert.NoError(t, sess.Close())
+		return
+	}
+	assert.NoError(t, err)
+
+	artist := schema.Table("artist")
+	if assert.NotNil(t, artist) {
+		assert.Equal(t, []string{"id"}, artist.PrimaryKey)
+
+		columns := make([]string, 0, len(artist.Columns))
+		for _, column := range artist.Columns {
+			columns = append(columns, column.Name)
+		}
+		assert.Equal(t, []string{"id", "name"}, columns)
+	}
+
+	assert.Nil(t, schema.Table("does_not_exist"))
+
+	assert.NoError(t, cleanUpCheck(sess))
+	assert.NoError(t, sess.Close())
+}
+
+func TestCreateTable(t *testing.T) {
+	sess := mustOpen()
+
+	_, err := sess.CreateTable("ddl_books").Columns(
+		sqlbuilder.NewColumn("id", sqlbuilder.Integer).PrimaryKey().AutoIncrement(),
+		sqlbuilder.NewColumn("title", sqlbuilder.String(80)).NotNull(),
+		sqlbuilder.NewColumn("available", sqlbuilder.Boolean).Default(true),
+	).Exec()
+	if Adapter == "ql" {
+		assert.Equal(t, db.ErrUnsupported, err)
+		assert.NoError(t, sess.Close())
+		return
+	}
+	assert.NoError(t, err)
+
+	defer func() {
+		_, err := sess.Exec(`DROP TABLE ` + sess.Collection("ddl_books").Name())
+		assert.NoError(t, err)
+		assert.NoError(t, sess.Close())
+	}()
+
+	_, err = sess.AlterTable("ddl_books").AddColumn(sqlbuilder.NewColumn("pages", sqlbuilder.Integer)).Exec()
+	assert.NoError(t, err)
+
+	_, err = sess.CreateIndex("ddl_books_title_idx").On("ddl_books", "title").Unique().Exec()
+	assert.NoError(t, err)
+
+	sess.ClearCache()
+
+	_, err = sess.InsertInto("ddl_books").Values(map[string]interface{}{"title": "Rayuela", "pages": 600}).Exec()
+	assert.NoError(t, err)
+
+	_, err = sess.InsertInto("ddl_books").Values(map[string]interface{}{"title": "Rayuela"}).Exec()
+	assert.Error(t, err)
+
+	var book struct {
+		Title string `db:"title"`
+		Pages int    `db:"pages"`
+	}
+	assert.NoError(t, sess.SelectFrom("ddl_books").One(&book))
+	assert.Equal(t, "Rayuela", book.Title)
+	assert.Equal(t, 600, book.Pages)
+}
+
+func TestCopyFrom(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	artist := sess.Collection("artist")
+	assert.NoError(t, artist.Truncate())
+
+	artists := make([]artistType, 50)
+	for i := range artists {
+		artists[i] = artistType{Name: fmt.Sprintf("copied-%d", i)}
+	}
+
+	assert.NoError(t, artist.CopyFrom(artists))
+
+	count, err := artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(len(artists)), count)
+
+	var last artistType
+	assert.NoError(t, artist.Find(db.Cond{"name": "copied-49"}).One(&last))
+	assert.NotZero(t, last.ID)
+
+	assert.NoError(t, artist.CopyFrom([]map[string]interface{}{
+		{"name": "copied-map-1"},
+		{"name": "copied-map-2"},
+	}))
+
+	count, err = artist.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(len(artists)+2), count)
+
+	assert.NoError(t, artist.CopyFrom([]artistType{}))
+	assert.Error(t, artist.CopyFrom(artistType{Name: "not a slice"}))
+
+	assert.NoError(t, artist.Truncate())
+	assert.NoError(t, cleanUpCheck(sess))
+}
+
 func TestExhaustConnectionPool(t *testing.T) {
 	if Adapter == "ql" {
 		t.Skip("Currently not supported.")
@@ -0,0 +1,171 @@
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// queryDeadline is the time limit of a statement.
+type queryDeadline struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// queryDeadline returns the deadline of a statement run with ctx, set by
+// sqlbuilder.WithQueryTimeout or else by the MaxQueryDuration of the session.
+func (d *database) queryDeadline(ctx context.Context) *queryDeadline {
+	timeout := sqlbuilder.QueryTimeout(ctx)
+	if timeout <= 0 {
+		timeout = d.MaxQueryDuration()
+	}
+	if timeout <= 0 {
+		return &queryDeadline{parent: ctx, ctx: ctx}
+	}
+	child, cancel := context.WithTimeout(ctx, timeout)
+	return &queryDeadline{parent: ctx, ctx: child, cancel: cancel}
+}
+
+// err returns db.ErrQueryTimeout if err was caused by the deadline, rather
+// than by the context the statement was run with.
+func (q *queryDeadline) err(err error) error {
+	if err != nil && q.cancel != nil && q.ctx.Err() == context.DeadlineExceeded && q.parent.Err() == nil {
+		return db.ErrQueryTimeout
+	}
+	return err
+}
+
+// release releases the resources of the deadline, rows read with its context
+// are closed.
+func (q *queryDeadline) release() {
+	if q.cancel != nil {
+		q.cancel()
+	}
+}
+
+// rows returns the given rows of a statement run with the deadline, which is
+// released once they're closed.
+func (q *queryDeadline) rows(rows *sql.Rows) (*sql.Rows, error) {
+	if q.cancel == nil {
+		return rows, nil
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		q.release()
+		return nil, err
+	}
+	return driverRows(q.ctx, &deadlineRows{rows: rows, types: types, deadline: q})
+}
+
+// rowQueryer runs statements that return rows, like *sql.DB or *sql.Tx.
+type rowQueryer interface {
+	compat.Queryer
+	compat.RowQueryer
+}
+
+// preparedRowQueryer runs prepared statements that return rows, like
+// *sql.Stmt.
+type preparedRowQueryer interface {
+	compat.PreparedQueryer
+	compat.PreparedRowQueryer
+}
+
+// queryRow runs a statement that returns at most one row with the deadline,
+// which is released once the row is scanned.
+func (q *queryDeadline) queryRow(ctx context.Context, p rowQueryer, query string, args []interface{}) (*sql.Row, error) {
+	if q.cancel == nil {
+		return compat.QueryRowContext(p, ctx, query, args), nil
+	}
+	rows, err := compat.QueryContext(p, ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return q.row(ctx, rows)
+}
+
+// preparedQueryRow is like queryRow for prepared statements.
+func (q *queryDeadline) preparedQueryRow(ctx context.Context, p preparedRowQueryer, args []interface{}) (*sql.Row, error) {
+	if q.cancel == nil {
+		return compat.PreparedQueryRowContext(p, ctx, args), nil
+	}
+	rows, err := compat.PreparedQueryContext(p, ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	return q.row(ctx, rows)
+}
+
+// row returns the first of the given rows as *sql.Row, the deadline is
+// released once it's scanned.
+func (q *queryDeadline) row(ctx context.Context, rows *sql.Rows) (*sql.Row, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	return driverRow(ctx, &deadlineRows{rows: rows, types: types, deadline: q})
+}
+
+// deadlineRows are the driver rows of a statement run with a deadline, the
+// deadline is released once they're closed.
+type deadlineRows struct {
+	rows     *sql.Rows
+	types    []*sql.ColumnType
+	deadline *queryDeadline
+}
+
+func (r *deadlineRows) Columns() []string {
+	columns := make([]string, len(r.types))
+	for i := range r.types {
+		columns[i] = r.types[i].Name()
+	}
+	return columns
+}
+
+func (r *deadlineRows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return r.deadline.err(err)
+		}
+		return io.EOF
+	}
+	values := make([]interface{}, len(dest))
+	for i := range values {
+		values[i] = &dest[i]
+	}
+	return r.rows.Scan(values...)
+}
+
+func (r *deadlineRows) Close() error {
+	err := r.rows.Close()
+	r.deadline.release()
+	return err
+}
+
+func (r *deadlineRows) ColumnTypeDatabaseTypeName(i int) string {
+	return r.types[i].DatabaseTypeName()
+}
+
+func (r *deadlineRows) ColumnTypeScanType(i int) reflect.Type {
+	return r.types[i].ScanType()
+}
+
+func (r *deadlineRows) ColumnTypeNullable(i int) (nullable, ok bool) {
+	return r.types[i].Nullable()
+}
+
+func (r *deadlineRows) ColumnTypeLength(i int) (length int64, ok bool) {
+	return r.types[i].Length()
+}
+
+func (r *deadlineRows) ColumnTypePrecisionScale(i int) (precision, scale int64, ok bool) {
+	return r.types[i].DecimalSize()
+}
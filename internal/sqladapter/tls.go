@@ -0,0 +1,13 @@
+package sqladapter
+
+import (
+	"fmt"
+
+	"upper.io/db.v3"
+)
+
+// TLSConfigKey returns the name the *tls.Config of c is registered with on
+// drivers that take them by name, like the MySQL and ClickHouse ones.
+func TLSConfigKey(c *db.TLSConfig) string {
+	return fmt.Sprintf("upper_%p", c)
+}
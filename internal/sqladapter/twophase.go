@@ -0,0 +1,172 @@
+package sqladapter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// errNotGlobal is returned by PrepareTwoPhase when the adapter requires the
+// global id to be given when the transaction begins and it was not, or it was
+// a different one.
+var errNotGlobal = errors.New("upper: transaction was not begun with WithTwoPhase for this global id")
+
+// hasTwoPhaseCommit allows the adapter to prepare transactions for a
+// two-phase commit and to finish them later from any session.
+type hasTwoPhaseCommit interface {
+	// PrepareTransactionQuery returns the statement that prepares the current
+	// transaction with the given global id.
+	PrepareTransactionQuery(gid string) string
+
+	// CommitPreparedQuery returns the statement that commits the prepared
+	// transaction with the given global id.
+	CommitPreparedQuery(gid string) string
+
+	// RollbackPreparedQuery returns the statement that rolls back the
+	// prepared transaction with the given global id.
+	RollbackPreparedQuery(gid string) string
+
+	// PreparedTransactionsQuery returns the statement that lists the global
+	// ids of the prepared transactions of the database.
+	PreparedTransactionsQuery() string
+}
+
+// hasGlobalTransactions is implemented by adapters whose transactions have to
+// be global from their start to be prepared for a two-phase commit, like the
+// XA transactions of MySQL.
+type hasGlobalTransactions interface {
+	// BeginGlobalQueries returns the statements that turn the transaction
+	// that was just begun into a global one with the given id.
+	BeginGlobalQueries(gid string) []string
+
+	// EndGlobalQuery returns the statement that ends the work of the global
+	// transaction, before it's prepared, committed or rolled back.
+	EndGlobalQuery(gid string) string
+
+	// CommitGlobalQuery returns the statement that commits the global
+	// transaction without preparing it.
+	CommitGlobalQuery(gid string) string
+
+	// RollbackGlobalQuery returns the statement that rolls back the global
+	// transaction.
+	RollbackGlobalQuery(gid string) string
+}
+
+// globalTx holds the id and the statements that finish a global transaction.
+type globalTx struct {
+	gid      string
+	end      string
+	commit   string
+	rollback string
+}
+
+// beginGlobal turns the transaction into a global one if ctx was returned by
+// sqlbuilder.WithTwoPhase and the adapter requires it.
+func (d *database) beginGlobal(ctx context.Context, tx *baseTx) error {
+	gid, ok := sqlbuilder.TwoPhaseID(ctx)
+	if !ok {
+		return nil
+	}
+	global, ok := d.PartialDatabase.(hasGlobalTransactions)
+	if !ok {
+		return nil
+	}
+	for _, stmt := range global.BeginGlobalQueries(gid) {
+		if _, err := tx.Tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	tx.global = &globalTx{
+		gid:      gid,
+		end:      global.EndGlobalQuery(gid),
+		commit:   global.CommitGlobalQuery(gid),
+		rollback: global.RollbackGlobalQuery(gid),
+	}
+	return nil
+}
+
+// PrepareTwoPhase prepares the transaction for a two-phase commit with the
+// given global id and closes it, the prepared transaction outlives the session
+// until it's finished with CommitPrepared or RollbackPrepared.
+func (w *databaseTx) PrepareTwoPhase(gid string) error {
+	committer, ok := w.Database.(hasTwoPhaseCommit)
+	if !ok {
+		return db.ErrUnsupported
+	}
+	tx, ok := w.BaseTx.(*baseTx)
+	if !ok || tx.savepoint != "" {
+		// Nested transactions can't be prepared on their own.
+		return db.ErrUnsupported
+	}
+	if _, ok := w.Database.(hasGlobalTransactions); ok {
+		if tx.global == nil || tx.global.gid != gid {
+			return fmt.Errorf("%w: %q", errNotGlobal, gid)
+		}
+	}
+
+	defer w.Database.Close()
+	return tx.prepare(committer.PrepareTransactionQuery(gid))
+}
+
+// CommitPrepared commits the prepared transaction with the given global id.
+func (d *database) CommitPrepared(gid string) error {
+	committer, ok := d.PartialDatabase.(hasTwoPhaseCommit)
+	if !ok {
+		return db.ErrUnsupported
+	}
+	_, err := d.PartialDatabase.ExecContext(d.Context(), committer.CommitPreparedQuery(gid))
+	return err
+}
+
+// RollbackPrepared rolls back the prepared transaction with the given global
+// id.
+func (d *database) RollbackPrepared(gid string) error {
+	committer, ok := d.PartialDatabase.(hasTwoPhaseCommit)
+	if !ok {
+		return db.ErrUnsupported
+	}
+	_, err := d.PartialDatabase.ExecContext(d.Context(), committer.RollbackPreparedQuery(gid))
+	return err
+}
+
+// PreparedTransactions returns the global ids of the transactions that were
+// prepared and not finished yet, the ones a coordinator has to recover. The
+// global id is the last column of the rows, which is the only one but on MySQL.
+func (d *database) PreparedTransactions() ([]string, error) {
+	committer, ok := d.PartialDatabase.(hasTwoPhaseCommit)
+	if !ok {
+		return nil, db.ErrUnsupported
+	}
+
+	rows, err := d.PartialDatabase.QueryContext(d.Context(), committer.PreparedTransactionsQuery())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var gids []string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		gids = append(gids, string(values[len(values)-1]))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return gids, nil
+}
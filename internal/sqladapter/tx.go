@@ -24,9 +24,16 @@ package sqladapter
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/compat"
 	"upper.io/db.v3/lib/sqlbuilder"
 )
 
@@ -36,6 +43,10 @@ type DatabaseTx interface {
 	PartialDatabase
 
 	BaseTx
+
+	// PrepareTwoPhase prepares the transaction for a two-phase commit with
+	// the given global id.
+	PrepareTwoPhase(gid string) error
 }
 
 // BaseTx provides logic for methods that can be shared across all SQL
@@ -45,6 +56,13 @@ type BaseTx interface {
 
 	// Committed returns true if the transaction was already commited.
 	Committed() bool
+
+	// AfterCommit adds a function to run once the transaction is committed.
+	AfterCommit(fn func())
+
+	// AfterRollback adds a function to run once the transaction is rolled
+	// back.
+	AfterRollback(fn func())
 }
 
 type databaseTx struct {
@@ -63,12 +81,44 @@ func NewDatabaseTx(db Database) DatabaseTx {
 type baseTx struct {
 	*sql.Tx
 	committed atomic.Value
+
+	// savepoint is the name of the savepoint that delimits a nested
+	// transaction, it is empty for top-level transactions.
+	savepoint string
+	done      int32
+
+	// parent is the transaction a savepoint was created within.
+	parent *baseTx
+
+	// global is set if the transaction was begun as a global one, it's
+	// finished with statements of its own before the sql.Tx is.
+	global *globalTx
+
+	hooksMu    sync.Mutex
+	onCommit   []func()
+	onRollback []func()
 }
 
 func newBaseTx(tx *sql.Tx) BaseTx {
 	return &baseTx{Tx: tx}
 }
 
+// newSavepointTx creates a savepoint within the given transaction and returns
+// a BaseTx that releases it on Commit() and rolls back to it on Rollback().
+func newSavepointTx(ctx context.Context, parent BaseTx) (BaseTx, error) {
+	ptx, ok := parent.(*baseTx)
+	if !ok {
+		return nil, db.ErrUnsupported
+	}
+
+	savepoint := fmt.Sprintf("__upper_sp_%d", newBaseTxID())
+	if _, err := compat.ExecContext(ptx.Tx, ctx, "SAVEPOINT "+savepoint, nil); err != nil {
+		return nil, err
+	}
+
+	return &baseTx{Tx: ptx.Tx, savepoint: savepoint, parent: ptx}, nil
+}
+
 func (b *baseTx) Committed() bool {
 	committed := b.committed.Load()
 	if committed != nil {
@@ -78,14 +128,148 @@ func (b *baseTx) Committed() bool {
 }
 
 func (b *baseTx) Commit() (err error) {
+	if b.savepoint != "" {
+		if err := b.endSavepoint("RELEASE SAVEPOINT "); err != nil {
+			return err
+		}
+		b.committed.Store(struct{}{})
+
+		// The outcome of the nested transaction is the one of its parent.
+		onCommit, onRollback := b.takeHooks()
+		b.parent.hooksMu.Lock()
+		b.parent.onCommit = append(b.parent.onCommit, onCommit...)
+		b.parent.onRollback = append(b.parent.onRollback, onRollback...)
+		b.parent.hooksMu.Unlock()
+		return nil
+	}
+
+	if b.global != nil {
+		if err := b.endGlobal(b.global.commit); err != nil {
+			b.Tx.Exec(b.global.rollback)
+			b.Tx.Rollback()
+			_, onRollback := b.takeHooks()
+			runHooks(onRollback)
+			return err
+		}
+	}
+
 	err = b.Tx.Commit()
+	onCommit, onRollback := b.takeHooks()
 	if err != nil {
+		// A transaction that fails to commit is rolled back.
+		if err != sql.ErrTxDone {
+			runHooks(onRollback)
+		}
 		return err
 	}
 	b.committed.Store(struct{}{})
+
+	runHooks(onCommit)
 	return nil
 }
 
+// AfterCommit adds a function to run once the top-level transaction is
+// committed. Functions added to a nested transaction that is rolled back are
+// discarded.
+func (b *baseTx) AfterCommit(fn func()) {
+	b.hooksMu.Lock()
+	b.onCommit = append(b.onCommit, fn)
+	b.hooksMu.Unlock()
+}
+
+// AfterRollback adds a function to run once the transaction is rolled back,
+// either by Rollback or because Commit failed. Functions added to a nested
+// transaction run when it's rolled back or when its parent is.
+func (b *baseTx) AfterRollback(fn func()) {
+	b.hooksMu.Lock()
+	b.onRollback = append(b.onRollback, fn)
+	b.hooksMu.Unlock()
+}
+
+// takeHooks returns the functions added with AfterCommit and AfterRollback
+// and forgets them, so they're run just once.
+func (b *baseTx) takeHooks() (onCommit []func(), onRollback []func()) {
+	b.hooksMu.Lock()
+	defer b.hooksMu.Unlock()
+	onCommit, onRollback = b.onCommit, b.onRollback
+	b.onCommit, b.onRollback = nil, nil
+	return onCommit, onRollback
+}
+
+func runHooks(hooks []func()) {
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+func (b *baseTx) Rollback() error {
+	var err error
+	if b.savepoint != "" {
+		err = b.endSavepoint("ROLLBACK TO SAVEPOINT ")
+	} else {
+		if b.global != nil {
+			if gerr := b.endGlobal(b.global.rollback); gerr != nil && gerr != sql.ErrTxDone {
+				b.Tx.Rollback()
+				err = gerr
+			}
+		}
+		if err == nil {
+			err = b.Tx.Rollback()
+		}
+	}
+	if err == sql.ErrTxDone {
+		return err
+	}
+
+	_, onRollback := b.takeHooks()
+	runHooks(onRollback)
+	return err
+}
+
+// prepare runs the statement that prepares the transaction for a two-phase
+// commit, which detaches it from its connection, the sql.Tx is rolled back
+// afterwards to release the connection.
+func (b *baseTx) prepare(stmt string) error {
+	if !atomic.CompareAndSwapInt32(&b.done, 0, 1) {
+		return sql.ErrTxDone
+	}
+	var err error
+	if b.global != nil {
+		err = b.endGlobal(stmt)
+	} else {
+		_, err = b.Tx.Exec(stmt)
+	}
+	if err != nil {
+		b.Tx.Rollback()
+		_, onRollback := b.takeHooks()
+		runHooks(onRollback)
+		return err
+	}
+
+	// The outcome of a prepared transaction is not known yet.
+	b.takeHooks()
+	return b.Tx.Rollback()
+}
+
+// endGlobal ends the work of the global transaction and runs the given
+// statement on it.
+func (b *baseTx) endGlobal(stmt string) error {
+	if _, err := b.Tx.Exec(b.global.end); err != nil {
+		return err
+	}
+	_, err := b.Tx.Exec(stmt)
+	return err
+}
+
+// endSavepoint runs the given statement on the savepoint, just once.
+func (b *baseTx) endSavepoint(stmt string) error {
+	if !atomic.CompareAndSwapInt32(&b.done, 0, 1) {
+		return sql.ErrTxDone
+	}
+	_, err := b.Tx.Exec(stmt + b.savepoint)
+	return err
+}
+
 func (w *databaseTx) Commit() error {
 	defer w.Database.Close() // Automatic close on commit.
 	return w.BaseTx.Commit()
@@ -96,8 +280,14 @@ func (w *databaseTx) Rollback() error {
 	return w.BaseTx.Rollback()
 }
 
+// TxStarter is implemented by sessions that are able to begin transactions,
+// such as sqlbuilder.Database and sqlbuilder.Tx.
+type TxStarter interface {
+	NewTx(ctx context.Context) (sqlbuilder.Tx, error)
+}
+
 // RunTx creates a transaction context and runs fn within it.
-func RunTx(d sqlbuilder.Database, ctx context.Context, fn func(tx sqlbuilder.Tx) error) error {
+func RunTx(d TxStarter, ctx context.Context, fn func(tx sqlbuilder.Tx) error) error {
 	tx, err := d.NewTx(ctx)
 	if err != nil {
 		return err
@@ -111,6 +301,78 @@ func RunTx(d sqlbuilder.Database, ctx context.Context, fn func(tx sqlbuilder.Tx)
 	return tx.Commit()
 }
 
+// minRetryBackoff is the backoff used by TxWithRetry when the policy has no
+// InitialBackoff, retrying with no delay at all would just run into the same
+// conflict again.
+const minRetryBackoff = time.Millisecond
+
+// TxWithRetry is like RunTx but it runs fn again, within a new transaction,
+// whenever the transaction fails because of a serialization failure or a
+// deadlock. Retries are delayed with an exponential backoff as defined by
+// policy.
+func TxWithRetry(d TxStarter, ctx context.Context, fn func(tx sqlbuilder.Tx) error, policy sqlbuilder.RetryPolicy) error {
+	for retries := 0; ; retries++ {
+		err := RunTx(d, ctx, fn)
+		if err == nil || retries >= policy.MaxRetries || !IsSerializationFailure(d, err) {
+			return err
+		}
+
+		var done <-chan struct{}
+		if ctx != nil {
+			done = ctx.Done()
+		}
+
+		select {
+		case <-time.After(RetryBackoff(policy, retries)):
+		case <-done:
+			return ctx.Err()
+		}
+	}
+}
+
+// RetryBackoff returns how long to wait before the given retry, counting from
+// zero. The backoff starts at policy.InitialBackoff, or at one millisecond if
+// that is zero, it doubles on each retry up to policy.MaxBackoff and a random
+// jitter of up to half of it is taken off, so transactions that conflicted
+// with each other don't retry in lockstep.
+func RetryBackoff(policy sqlbuilder.RetryPolicy, retry int) time.Duration {
+	backoff := policy.InitialBackoff
+	if backoff < minRetryBackoff {
+		backoff = minRetryBackoff
+	}
+	for i := 0; i < retry; i++ {
+		if policy.MaxBackoff > 0 && backoff >= policy.MaxBackoff {
+			break
+		}
+		if backoff > math.MaxInt64/2 {
+			break
+		}
+		backoff = backoff * 2
+	}
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	return backoff - time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// IsSerializationFailure reports whether err, or any error it wraps, is
+// db.ErrSerializationFailure or is translated into it by the Err method of d,
+// which means that the transaction can be retried.
+func IsSerializationFailure(d TxStarter, err error) bool {
+	e, _ := d.(interface {
+		Err(error) error
+	})
+	for ; err != nil; err = errors.Unwrap(err) {
+		if err == db.ErrSerializationFailure {
+			return true
+		}
+		if e != nil && e.Err(err) == db.ErrSerializationFailure {
+			return true
+		}
+	}
+	return false
+}
+
 var (
 	_ = BaseTx(&baseTx{})
 	_ = DatabaseTx(&databaseTx{})
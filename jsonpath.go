@@ -0,0 +1,76 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"strings"
+)
+
+// JSONPathValue represents a path into a JSON column. This is an exported
+// interface but it's rarely used directly, you may want to use the
+// `db.JSONPath()` function instead.
+type JSONPathValue interface {
+	// Column returns the name of the JSON column.
+	Column() string
+
+	// Keys returns the keys that lead to the value within the column.
+	Keys() []string
+
+	// String returns the path as given to db.JSONPath.
+	String() string
+}
+
+// JSONPath represents the value at the given path of a JSON column. The path
+// is separated by dots, the first element is the name of the column and
+// numeric elements are array indexes. JSONPath can be used as a db.Cond key.
+//
+// Examples:
+//
+//	// "data"->'address'->>'city' = 'Paris' on PostgreSQL
+//	db.Cond{db.JSONPath("data.address.city"): "Paris"}
+//
+//	// JSON_EXTRACT(`data`, '$.tags[0]') <> 'draft' on SQLite
+//	db.Cond{db.JSONPath("data.tags.0"): db.NotEq("draft")}
+func JSONPath(path string) JSONPathValue {
+	chunks := strings.Split(path, ".")
+	return &jsonPath{path: path, column: chunks[0], keys: chunks[1:]}
+}
+
+type jsonPath struct {
+	path   string
+	column string
+	keys   []string
+}
+
+func (j *jsonPath) Column() string {
+	return j.column
+}
+
+func (j *jsonPath) Keys() []string {
+	return j.keys
+}
+
+func (j *jsonPath) String() string {
+	return j.path
+}
+
+var _ JSONPathValue = &jsonPath{}
@@ -0,0 +1,322 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package audit records who changed what on the collections of SQL sessions.
+//
+// A Logger is a change listener that writes a record of every insert, update
+// and delete made through the collections of a session into an audit table,
+// the actor of a change is taken from the context the write was made within:
+//
+//	logger := audit.New("", func(ev *db.ChangeEvent, err error) {
+//		log.Printf("could not record %s on %q: %v", ev.Operation, ev.Collection, err)
+//	})
+//	if err := logger.CreateTable(sess); err != nil {
+//		...
+//	}
+//	sess.SetChangeListener(logger)
+//
+//	ctx := audit.WithActor(req.Context(), user.Email)
+//	err := sess.Tx(ctx, func(tx sqlbuilder.Tx) error {
+//		return tx.Collection("accounts").Find(id).UpdateContext(ctx, changes)
+//	})
+//
+// Records of writes made within a transaction are written with the
+// transaction, so they're only kept if it's committed. Writes made outside a
+// transaction are committed on their own, and so are their records, right
+// after them.
+//
+// Updates are recorded along with the values the updated columns had before,
+// the items of the result set are read right before each update.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// DefaultTable is the name of the audit table used by loggers that don't set
+// one.
+const DefaultTable = "audit_log"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that carries the given actor, writes made
+// within it are recorded as made by the actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor carried by ctx, if any.
+func Actor(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok
+}
+
+// Record is a record of the audit table.
+type Record struct {
+	ID uint64 `db:"id,omitempty"`
+
+	// Actor is who made the change, it's empty if the context of the write
+	// carried no actor.
+	Actor string `db:"actor"`
+
+	// Collection and Operation tell which collection was written and how.
+	Collection string `db:"collection"`
+	Operation  string `db:"operation"`
+
+	// Key is the JSON encoded primary key of the inserted item or, for
+	// updates and deletes, the conditions of the result set that was written.
+	Key string `db:"record_key"`
+
+	// Changes is a JSON object with the values of the change, the values of
+	// the deleted items, or the ones the updated columns had, are under
+	// "before" and the values the items were inserted or updated with are
+	// under "after".
+	Changes string `db:"changes"`
+
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// Logger is a db.ChangeListener that writes the changes it's notified of
+// into an audit table.
+type Logger struct {
+	table   string
+	onError func(ev *db.ChangeEvent, err error)
+}
+
+var _ = db.BeforeChangeListener(&Logger{})
+
+// New returns a logger that records changes into the given table, or into
+// DefaultTable if it's empty. The given function is called with the changes
+// that can't be recorded, it must not be nil.
+func New(table string, onError func(ev *db.ChangeEvent, err error)) *Logger {
+	if onError == nil {
+		panic("audit: nil error handler")
+	}
+	if table == "" {
+		table = DefaultTable
+	}
+	return &Logger{table: table, onError: onError}
+}
+
+// Table returns the name of the audit table.
+func (l *Logger) Table() string {
+	return l.table
+}
+
+// CreateTable creates the audit table if it doesn't exist.
+func (l *Logger) CreateTable(sess sqlbuilder.Database) error {
+	if sess.Collection(l.table).Exists() {
+		return nil
+	}
+
+	_, err := sess.CreateTable(l.table).Columns(
+		sqlbuilder.NewColumn("id", sqlbuilder.BigInteger).PrimaryKey().AutoIncrement(),
+		sqlbuilder.NewColumn("actor", sqlbuilder.String(255)).NotNull().Default(""),
+		sqlbuilder.NewColumn("collection", sqlbuilder.String(255)).NotNull(),
+		sqlbuilder.NewColumn("operation", sqlbuilder.String(16)).NotNull(),
+		sqlbuilder.NewColumn("record_key", sqlbuilder.Text).NotNull(),
+		sqlbuilder.NewColumn("changes", sqlbuilder.Text).NotNull(),
+		sqlbuilder.NewColumn("created_at", sqlbuilder.Timestamp).NotNull(),
+	).Exec()
+	if err != nil {
+		return err
+	}
+
+	// The collection was cached when it didn't exist.
+	sess.ClearCache()
+	return nil
+}
+
+// WantsBefore reports whether the items of the given collection must be read
+// before they're updated, they are unless it's the audit table.
+func (l *Logger) WantsBefore(collection string) bool {
+	return collection != l.table
+}
+
+// OnChange records the given change with the session it was made with.
+func (l *Logger) OnChange(ev *db.ChangeEvent) {
+	if ev.Collection == l.table {
+		return
+	}
+	if err := l.record(ev); err != nil {
+		l.onError(ev, err)
+	}
+}
+
+// record inserts the record of the given change within a transaction of its
+// own, which is nested with a savepoint if the change was made within a
+// transaction, so that a failed insert doesn't abort it.
+func (l *Logger) record(ev *db.ChangeEvent) error {
+	sess, ok := ev.Session.(interface {
+		Tx(ctx context.Context, fn func(sess sqlbuilder.Tx) error) error
+	})
+	if !ok {
+		return db.ErrUnsupported
+	}
+
+	rec, err := NewRecord(ev)
+	if err != nil {
+		return err
+	}
+
+	ctx := ev.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return sess.Tx(ctx, func(tx sqlbuilder.Tx) error {
+		_, err := tx.InsertInto(l.table).Values(rec).ExecContext(ctx)
+		return err
+	})
+}
+
+// NewRecord returns the record of the given change.
+func NewRecord(ev *db.ChangeEvent) (*Record, error) {
+	rec := &Record{
+		Collection: ev.Collection,
+		Operation:  string(ev.Operation),
+		CreatedAt:  time.Now(),
+	}
+	rec.Actor, _ = Actor(ev.Context)
+	if ev.Session != nil {
+		rec.CreatedAt = ev.Session.Clock()()
+	}
+
+	var key interface{} = ev.Key
+	if key == nil {
+		key = jsonValue(ev.Conditions)
+	}
+	buf, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	rec.Key = string(buf)
+
+	changes := map[string]interface{}{}
+	if ev.After != nil {
+		changes["after"] = columns(ev.Session, ev.After)
+	}
+	if ev.Before != nil {
+		before := columns(ev.Session, ev.Before)
+		if ev.Operation == db.ChangeUpdate {
+			before = updatedColumns(before, changes["after"])
+		}
+		changes["before"] = before
+	}
+	if buf, err = json.Marshal(changes); err != nil {
+		return nil, err
+	}
+	rec.Changes = string(buf)
+
+	return rec, nil
+}
+
+// columns maps item into its column values the way the session would, each
+// item of a slice, like the ones of deleted items, is mapped on its own.
+// Items that are not maps or structs are kept as they are.
+func columns(sess db.Database, item interface{}) interface{} {
+	if v := reflect.Indirect(reflect.ValueOf(item)); v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = columns(sess, v.Index(i).Interface())
+		}
+		return items
+	}
+
+	var options *sqlbuilder.MapOptions
+	if mapper, ok := sess.(interface {
+		MapOptions() *sqlbuilder.MapOptions
+	}); ok {
+		options = mapper.MapOptions()
+	}
+
+	names, values, err := sqlbuilder.Map(item, options)
+	if err != nil {
+		return item
+	}
+	m := make(map[string]interface{}, len(names))
+	for i := range names {
+		m[names[i]] = jsonValue(values[i])
+	}
+	return m
+}
+
+// updatedColumns reduces the values of the items of a result set, as they
+// were before it was updated, to the columns the update set.
+func updatedColumns(before interface{}, after interface{}) interface{} {
+	set, ok := after.(map[string]interface{})
+	if !ok {
+		return before
+	}
+	items, ok := before.([]interface{})
+	if !ok {
+		return before
+	}
+	for i := range items {
+		item, ok := items[i].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		values := make(map[string]interface{}, len(set))
+		for column := range set {
+			if value, ok := item[column]; ok {
+				values[column] = value
+			}
+		}
+		items[i] = values
+	}
+	return items
+}
+
+// jsonValue returns a value that can be encoded as JSON in place of
+// conditions and SQL expressions, comparisons are reduced to their values.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		values := make([]interface{}, len(t))
+		for i := range t {
+			values[i] = jsonValue(t[i])
+		}
+		return values
+	case db.Cond:
+		m := make(map[string]interface{}, len(t))
+		for k, v := range t {
+			m[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return m
+	case db.Comparison:
+		return jsonValue(t.Value())
+	case db.RawValue:
+		return t.String()
+	case db.Function:
+		return fmt.Sprintf("%s(%v)", t.Name(), t.Arguments())
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+)
+
+func TestActor(t *testing.T) {
+	_, ok := Actor(context.Background())
+	assert.False(t, ok)
+
+	actor, ok := Actor(WithActor(context.Background(), "flea@example.com"))
+	assert.True(t, ok)
+	assert.Equal(t, "flea@example.com", actor)
+
+	onError := func(*db.ChangeEvent, error) {}
+	assert.Equal(t, DefaultTable, New("", onError).Table())
+	assert.Equal(t, "changes", New("changes", onError).Table())
+	assert.True(t, New("", onError).WantsBefore("accounts"))
+	assert.False(t, New("", onError).WantsBefore(DefaultTable))
+}
+
+func TestNewRecord(t *testing.T) {
+	type account struct {
+		ID   int64  `db:"id,omitempty"`
+		Name string `db:"name"`
+	}
+
+	rec, err := NewRecord(&db.ChangeEvent{
+		Collection: "accounts",
+		Operation:  db.ChangeInsert,
+		Key:        int64(3),
+		After:      &account{ID: 3, Name: "Flea"},
+		Context:    WithActor(context.Background(), "admin"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", rec.Actor)
+	assert.Equal(t, "accounts", rec.Collection)
+	assert.Equal(t, "insert", rec.Operation)
+	assert.Equal(t, "3", rec.Key)
+	assert.Equal(t, `{"after":{"id":3,"name":"Flea"}}`, rec.Changes)
+
+	rec, err = NewRecord(&db.ChangeEvent{
+		Collection: "accounts",
+		Operation:  db.ChangeUpdate,
+		Conditions: []interface{}{db.Cond{"id": db.Eq(3)}},
+		After:      map[string]interface{}{"name": "Anthony"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "", rec.Actor)
+	assert.Equal(t, `[{"id":3}]`, rec.Key)
+	assert.Equal(t, `{"after":{"name":"Anthony"}}`, rec.Changes)
+
+	rec, err = NewRecord(&db.ChangeEvent{
+		Collection: "accounts",
+		Operation:  db.ChangeDelete,
+		Before:     &[]account{{ID: 3, Name: "Anthony"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", rec.Key)
+	assert.Equal(t, `{"before":[{"id":3,"name":"Anthony"}]}`, rec.Changes)
+
+	// Updated items are reduced to the columns the update set.
+	rec, err = NewRecord(&db.ChangeEvent{
+		Collection: "accounts",
+		Operation:  db.ChangeUpdate,
+		Conditions: []interface{}{db.Cond{"id": db.Eq(3)}},
+		Before:     []map[string]interface{}{{"id": int64(3), "name": "Anthony"}},
+		After:      map[string]interface{}{"name": "Flea"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"after":{"name":"Flea"},"before":[{"name":"Anthony"}]}`, rec.Changes)
+}
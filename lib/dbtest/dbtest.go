@@ -0,0 +1,66 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package dbtest provides helpers for the integration tests of code that uses
+// SQL sessions.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// Session is either a sqlbuilder.Database or a sqlbuilder.Tx.
+type Session interface {
+	Context() context.Context
+	NewTx(ctx context.Context) (sqlbuilder.Tx, error)
+}
+
+// WrapTx returns a transaction of sess that is rolled back when the test and
+// its subtests finish, so that the changes made by the test are not seen by
+// other tests:
+//
+//	func TestSignUp(t *testing.T) {
+//		tx := dbtest.WrapTx(t, sess)
+//		...
+//	}
+//
+// sess may be a transaction returned by WrapTx, like the one of a parent
+// test, nested transactions are delimited by savepoints. The test fails if
+// the transaction can't be created.
+func WrapTx(t testing.TB, sess Session) sqlbuilder.Tx {
+	t.Helper()
+
+	tx, err := sess.NewTx(sess.Context())
+	if err != nil {
+		t.Fatalf("dbtest: can't begin transaction: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			t.Errorf("dbtest: can't roll back transaction: %v", err)
+		}
+	})
+	return tx
+}
@@ -0,0 +1,50 @@
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// builderTx is embedded as sqlbuilder.Tx has a Tx method.
+type builderTx = sqlbuilder.Tx
+
+type fakeTx struct {
+	builderTx
+
+	name       string
+	rolledBack *[]string
+}
+
+func (tx *fakeTx) Context() context.Context {
+	return context.Background()
+}
+
+func (tx *fakeTx) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	return &fakeTx{name: tx.name + "/savepoint", rolledBack: tx.rolledBack}, nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	*tx.rolledBack = append(*tx.rolledBack, tx.name)
+	return nil
+}
+
+func TestWrapTx(t *testing.T) {
+	var rolledBack []string
+	sess := &fakeTx{name: "sess", rolledBack: &rolledBack}
+
+	t.Run("parent", func(t *testing.T) {
+		tx := WrapTx(t, sess)
+		assert.Equal(t, "sess/savepoint", tx.(*fakeTx).name)
+
+		t.Run("child", func(t *testing.T) {
+			tx := WrapTx(t, tx)
+			assert.Equal(t, "sess/savepoint/savepoint", tx.(*fakeTx).name)
+		})
+		assert.Equal(t, []string{"sess/savepoint/savepoint"}, rolledBack)
+	})
+
+	assert.Equal(t, []string{"sess/savepoint/savepoint", "sess/savepoint"}, rolledBack)
+}
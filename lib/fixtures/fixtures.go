@@ -0,0 +1,421 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package fixtures loads the rows of test fixture files into the collections
+// of a session.
+//
+// Each file has the rows of the collection named after it, by label, like
+// testdata/fixtures/artist.json:
+//
+//	{
+//	  "ozzie": {"name": "Ozzie"},
+//	  "flea": {"name": "Flea", "id": 42}
+//	}
+//
+// Rows without a primary key get a deterministic one derived from their
+// collection and label, and files are templates (see text/template) where the
+// key of another row is known with the "id" helper, like
+// testdata/fixtures/publication.json:
+//
+//	{
+//	  "paranoid": {"title": "Paranoid", "author_id": {{ id "artist" "ozzie" }}}
+//	}
+//
+// Collections are loaded after the ones they refer to with "id", and emptied
+// before being loaded:
+//
+//	loader, err := fixtures.FromDir("testdata/fixtures")
+//	...
+//	if err := loader.Load(sess); err != nil {
+//		...
+//	}
+//
+// JSON files are supported, other formats can be added with RegisterFormat.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// DefaultPrimaryKey is the column deterministic keys are set to by loaders
+// that don't set one.
+const DefaultPrimaryKey = "id"
+
+// maxID is the upper bound of deterministic keys, they fit in a 32-bit
+// signed integer column.
+const maxID = 1<<30 - 1
+
+// Common error messages.
+var (
+	ErrUnknownFormat = errors.New(`fixtures: unknown format of file`)
+	ErrCycle         = errors.New(`fixtures: collections refer to each other`)
+	ErrInvalidRows   = errors.New(`fixtures: expecting rows by label in file`)
+)
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]func([]byte, interface{}) error{
+		".json": unmarshalJSON,
+	}
+)
+
+// RegisterFormat adds a format of fixture files, files with the given
+// extension are decoded with unmarshal, which is given a pointer to an
+// interface{} value like json.Unmarshal is. Rows may be decoded into maps
+// keyed by strings or by interface{} values.
+func RegisterFormat(ext string, unmarshal func(data []byte, v interface{}) error) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[strings.ToLower(ext)] = unmarshal
+}
+
+func format(name string) func([]byte, interface{}) error {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	return formats[strings.ToLower(filepath.Ext(name))]
+}
+
+func unmarshalJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// ID returns the deterministic key of the row with the given label of
+// collection.
+func ID(collection string, label string) int64 {
+	return int64(crc32.ChecksumIEEE([]byte(collection+"/"+label))%maxID) + 1
+}
+
+// Session is a db.Database, a sqlbuilder.Tx or anything else with
+// collections. Fixtures are loaded within a transaction on sessions that can
+// run them, like sqlbuilder.Database and sqlbuilder.Tx.
+type Session interface {
+	Collection(name string) db.Collection
+}
+
+type txSession interface {
+	Context() context.Context
+	Tx(ctx context.Context, fn func(sess sqlbuilder.Tx) error) error
+}
+
+// Loader loads a set of fixture files.
+type Loader struct {
+	// PrimaryKey is the column deterministic keys are set to,
+	// DefaultPrimaryKey is used if empty.
+	PrimaryKey string
+
+	// Cleanup empties a collection before its rows are loaded, collections
+	// are truncated if nil. Databases that don't truncate the tables other
+	// tables refer to, like PostgreSQL, can delete their rows instead:
+	//
+	//	loader.Cleanup = func(c db.Collection) error {
+	//		return c.Find().Delete()
+	//	}
+	Cleanup func(c db.Collection) error
+
+	files []string
+	funcs template.FuncMap
+}
+
+// New returns a Loader of the given files.
+func New(files ...string) *Loader {
+	return &Loader{files: files}
+}
+
+// FromDir returns a Loader of the files of the given directory that have a
+// registered format, other files are ignored.
+func FromDir(dir string) (*Loader, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || format(entry.Name()) == nil {
+			continue
+		}
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+	return New(files...), nil
+}
+
+// Funcs adds template helpers to the ones files can use.
+func (l *Loader) Funcs(funcs template.FuncMap) *Loader {
+	if l.funcs == nil {
+		l.funcs = template.FuncMap{}
+	}
+	for name, fn := range funcs {
+		l.funcs[name] = fn
+	}
+	return l
+}
+
+// fixture is the rows of a collection, by label.
+type fixture struct {
+	collection string
+	rows       map[string]map[string]interface{}
+	deps       map[string]bool
+}
+
+// Load empties the collections of the fixtures and loads their rows, in an
+// order that loads collections after the ones they refer to.
+func (l *Loader) Load(sess Session) error {
+	fixtures, err := l.parse()
+	if err != nil {
+		return err
+	}
+	return run(sess, func(sess Session) error {
+		if err := l.clean(sess, fixtures); err != nil {
+			return err
+		}
+		for _, f := range fixtures {
+			c := sess.Collection(f.collection)
+			for _, label := range labels(f.rows) {
+				if _, err := c.Insert(f.rows[label]); err != nil {
+					return fmt.Errorf("fixtures: can't load %s %q: %v", f.collection, label, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Clean empties the collections of the fixtures.
+func (l *Loader) Clean(sess Session) error {
+	fixtures, err := l.parse()
+	if err != nil {
+		return err
+	}
+	return run(sess, func(sess Session) error {
+		return l.clean(sess, fixtures)
+	})
+}
+
+// clean empties the collections of fixtures, collections that refer to
+// others are emptied first.
+func (l *Loader) clean(sess Session, fixtures []*fixture) error {
+	cleanup := l.Cleanup
+	if cleanup == nil {
+		cleanup = func(c db.Collection) error {
+			return c.Truncate()
+		}
+	}
+	for i := len(fixtures) - 1; i >= 0; i-- {
+		if err := cleanup(sess.Collection(fixtures[i].collection)); err != nil {
+			return fmt.Errorf("fixtures: can't clean %s: %v", fixtures[i].collection, err)
+		}
+	}
+	return nil
+}
+
+// run calls fn within a transaction if sess can run them.
+func run(sess Session, fn func(sess Session) error) error {
+	if txSess, ok := sess.(txSession); ok {
+		return txSess.Tx(txSess.Context(), func(tx sqlbuilder.Tx) error {
+			return fn(tx)
+		})
+	}
+	return fn(sess)
+}
+
+// parse reads the files of the loader and returns their fixtures in the order
+// they must be loaded.
+func (l *Loader) parse() ([]*fixture, error) {
+	primaryKey := l.PrimaryKey
+	if primaryKey == "" {
+		primaryKey = DefaultPrimaryKey
+	}
+	now := time.Now().UTC()
+
+	fixtures := make([]*fixture, 0, len(l.files))
+	for _, file := range l.files {
+		unmarshal := format(file)
+		if unmarshal == nil {
+			return nil, fmt.Errorf("%w %q", ErrUnknownFormat, file)
+		}
+
+		f := &fixture{
+			collection: strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)),
+			deps:       map[string]bool{},
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		tpl, err := template.New(filepath.Base(file)).Funcs(template.FuncMap{
+			"id": func(collection string, label string) int64 {
+				if collection != f.collection {
+					f.deps[collection] = true
+				}
+				return ID(collection, label)
+			},
+			"now": func() string {
+				return now.Format(time.RFC3339Nano)
+			},
+		}).Funcs(l.funcs).Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("fixtures: %v", err)
+		}
+
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, nil); err != nil {
+			return nil, fmt.Errorf("fixtures: %v", err)
+		}
+
+		var decoded interface{}
+		if err := unmarshal(buf.Bytes(), &decoded); err != nil {
+			return nil, fmt.Errorf("fixtures: can't decode %q: %v", file, err)
+		}
+
+		rows, ok := toMap(decoded)
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrInvalidRows, file)
+		}
+		f.rows = make(map[string]map[string]interface{}, len(rows))
+		for label, v := range rows {
+			row, ok := toMap(v)
+			if !ok {
+				return nil, fmt.Errorf("%w %q", ErrInvalidRows, file)
+			}
+			if _, ok := row[primaryKey]; !ok {
+				row[primaryKey] = ID(f.collection, label)
+			}
+			f.rows[label] = row
+		}
+
+		fixtures = append(fixtures, f)
+	}
+
+	return sortFixtures(fixtures)
+}
+
+// sortFixtures sorts fixtures so that each one comes after the ones it
+// depends on, the order of the files is kept otherwise.
+func sortFixtures(fixtures []*fixture) ([]*fixture, error) {
+	byCollection := make(map[string]*fixture, len(fixtures))
+	for _, f := range fixtures {
+		byCollection[f.collection] = f
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	sorted := make([]*fixture, 0, len(fixtures))
+
+	var visit func(f *fixture, path []string) error
+	visit = func(f *fixture, path []string) error {
+		switch state[f.collection] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrCycle, strings.Join(append(path, f.collection), " -> "))
+		}
+		state[f.collection] = visiting
+
+		deps := make([]string, 0, len(f.deps))
+		for dep := range f.deps {
+			deps = append(deps, dep)
+		}
+		sort.Strings(deps)
+
+		for _, dep := range deps {
+			if depFixture, ok := byCollection[dep]; ok {
+				if err := visit(depFixture, append(path, f.collection)); err != nil {
+					return err
+				}
+			}
+		}
+
+		state[f.collection] = visited
+		sorted = append(sorted, f)
+		return nil
+	}
+
+	for _, f := range fixtures {
+		if err := visit(f, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// toMap returns v as a map with string keys, numbers decoded from JSON are
+// turned into int64 or float64 values.
+func toMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		for k := range m {
+			m[k] = normalize(m[k])
+		}
+		return m, true
+	case map[interface{}]interface{}:
+		// As decoded by formats whose maps can have keys of any type.
+		out := make(map[string]interface{}, len(m))
+		for k := range m {
+			out[fmt.Sprintf("%v", k)] = normalize(m[k])
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func normalize(v interface{}) interface{} {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+		return n.String()
+	}
+	return v
+}
+
+func labels(rows map[string]map[string]interface{}) []string {
+	labels := make([]string, 0, len(rows))
+	for label := range rows {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
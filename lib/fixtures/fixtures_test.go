@@ -0,0 +1,122 @@
+package fixtures
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+	"upper.io/db.v3/mocks"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "fixtures")
+	assert.NoError(t, err)
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"publication.json": `{
+			"paranoid": {"title": "Paranoid", "author_id": {{ id "artist" "ozzie" }}, "created_at": "{{ now }}"},
+			"blood": {"title": {{ upper "Blood Sugar" | printf "%q" }}, "author_id": {{ id "artist" "flea" }}}
+		}`,
+		"artist.json": `{
+			"ozzie": {"name": "Ozzie"},
+			"flea": {"name": "Flea", "id": 42}
+		}`,
+		"README.md": `Not a fixture.`,
+	})
+	defer os.RemoveAll(dir)
+
+	loader, err := FromDir(dir)
+	assert.NoError(t, err)
+	loader.Funcs(template.FuncMap{"upper": strings.ToUpper})
+
+	fixtures, err := loader.parse()
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(fixtures)) {
+		assert.Equal(t, "artist", fixtures[0].collection)
+		assert.Equal(t, "publication", fixtures[1].collection)
+	}
+
+	sess := mocks.New()
+	assert.NoError(t, sess.Fixture("artist", []map[string]interface{}{{"name": "Stale"}}))
+
+	assert.NoError(t, loader.Load(sess))
+
+	var artists []struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	assert.NoError(t, sess.Collection("artist").Find().OrderBy("name").All(&artists))
+	if assert.Equal(t, 2, len(artists)) {
+		assert.Equal(t, int64(42), artists[0].ID)
+		assert.Equal(t, ID("artist", "ozzie"), artists[1].ID)
+	}
+
+	var blood struct {
+		Title    string `db:"title"`
+		AuthorID int64  `db:"author_id"`
+	}
+	assert.NoError(t, sess.Collection("publication").Find(db.Cond{"id": ID("publication", "blood")}).One(&blood))
+	assert.Equal(t, "BLOOD SUGAR", blood.Title)
+	assert.Equal(t, ID("artist", "flea"), blood.AuthorID)
+
+	assert.NoError(t, loader.Clean(sess))
+	assert.Equal(t, 0, len(sess.Rows("artist")))
+	assert.Equal(t, 0, len(sess.Rows("publication")))
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.json":    `{"x": {"b_id": {{ id "b" "y" }}}}`,
+		"b.json":    `{"y": {"a_id": {{ id "a" "x" }}}}`,
+		"c.json":    `["not", "rows"]`,
+		"d.unknown": `{}`,
+		"e.json":    `{"x": {"missing": {{ missing }}}}`,
+		"f.json":    `{"x": {"id": 1}}`,
+		"g.json":    `{"x": "not a row"}`,
+	})
+	defer os.RemoveAll(dir)
+
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	_, err := New(path("a.json"), path("b.json")).parse()
+	assert.True(t, errors.Is(err, ErrCycle))
+	assert.Contains(t, err.Error(), "a -> b -> a")
+
+	_, err = New(path("d.unknown")).parse()
+	assert.True(t, errors.Is(err, ErrUnknownFormat))
+	assert.Equal(t, fmt.Sprintf("fixtures: unknown format of file %q", path("d.unknown")), err.Error())
+
+	for _, name := range []string{"c.json", "g.json"} {
+		_, err := New(path(name)).parse()
+		assert.True(t, errors.Is(err, ErrInvalidRows), name)
+	}
+
+	_, err = New(path("e.json")).parse()
+	assert.Error(t, err)
+
+	fixtures, err := New(path("f.json")).parse()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), fixtures[0].rows["x"]["id"])
+}
+
+func TestID(t *testing.T) {
+	assert.Equal(t, ID("artist", "ozzie"), ID("artist", "ozzie"))
+	assert.NotEqual(t, ID("artist", "ozzie"), ID("artist", "flea"))
+	assert.NotEqual(t, ID("artist", "ozzie"), ID("publication", "ozzie"))
+	assert.True(t, ID("artist", "ozzie") > 0)
+}
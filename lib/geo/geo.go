@@ -0,0 +1,140 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package geo provides two-dimensional spatial values that can be encoded
+// to and decoded from WKB, WKT and GeoJSON.
+//
+// Adapters that support spatial types wrap Geometry into a type that knows
+// how to bind and scan it, see postgresql.Geometry and mysql.Geometry:
+//
+//	location := geo.Geometry{SRID: 4326, Shape: geo.Point{X: 2.35, Y: 48.85}}
+//	err := col.Find(db.Cond{"area": postgresql.ContainsGeometry(location)}).All(&shops)
+package geo
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Shape types.
+const (
+	TypePoint              = "Point"
+	TypeLineString         = "LineString"
+	TypePolygon            = "Polygon"
+	TypeMultiPoint         = "MultiPoint"
+	TypeMultiLineString    = "MultiLineString"
+	TypeMultiPolygon       = "MultiPolygon"
+	TypeGeometryCollection = "GeometryCollection"
+)
+
+// Error messages.
+var (
+	ErrInvalidWKB           = errors.New(`geo: invalid WKB`)
+	ErrInvalidWKT           = errors.New(`geo: invalid WKT`)
+	ErrInvalidGeoJSON       = errors.New(`geo: invalid GeoJSON`)
+	ErrUnsupportedShape     = errors.New(`geo: unsupported shape`)
+	ErrUnsupportedDimension = errors.New(`geo: only two-dimensional coordinates are supported`)
+)
+
+// Shape is the common interface of Point, LineString, Polygon,
+// MultiPoint, MultiLineString, MultiPolygon and GeometryCollection.
+type Shape interface {
+	// Type returns the name of the shape, like "Point" or "Polygon".
+	Type() string
+}
+
+// Point is a single position.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// LineString is a curve made of straight segments between points.
+type LineString []Point
+
+// Polygon is a surface made of an exterior ring, followed by zero or more
+// interior rings. Rings are closed LineStrings.
+type Polygon []LineString
+
+// MultiPoint is a collection of points.
+type MultiPoint []Point
+
+// MultiLineString is a collection of line strings.
+type MultiLineString []LineString
+
+// MultiPolygon is a collection of polygons.
+type MultiPolygon []Polygon
+
+// GeometryCollection is a heterogeneous collection of shapes.
+type GeometryCollection []Shape
+
+// Type returns "Point".
+func (Point) Type() string { return TypePoint }
+
+// Type returns "LineString".
+func (LineString) Type() string { return TypeLineString }
+
+// Type returns "Polygon".
+func (Polygon) Type() string { return TypePolygon }
+
+// Type returns "MultiPoint".
+func (MultiPoint) Type() string { return TypeMultiPoint }
+
+// Type returns "MultiLineString".
+func (MultiLineString) Type() string { return TypeMultiLineString }
+
+// Type returns "MultiPolygon".
+func (MultiPolygon) Type() string { return TypeMultiPolygon }
+
+// Type returns "GeometryCollection".
+func (GeometryCollection) Type() string { return TypeGeometryCollection }
+
+// Geometry is a shape together with the identifier of its spatial reference
+// system (SRID), an SRID of zero means the reference system is unknown. The
+// zero value represents NULL.
+type Geometry struct {
+	SRID  int
+	Shape Shape
+}
+
+// IsZero returns true if the geometry has no shape.
+func (g Geometry) IsZero() bool {
+	return g.Shape == nil
+}
+
+// String returns the geometry as EWKT, that is WKT prefixed by "SRID=n;"
+// when the SRID is not zero.
+func (g Geometry) String() string {
+	if g.SRID != 0 {
+		return "SRID=" + strconv.Itoa(g.SRID) + ";" + g.WKT()
+	}
+	return g.WKT()
+}
+
+var (
+	_ Shape = Point{}
+	_ Shape = LineString{}
+	_ Shape = Polygon{}
+	_ Shape = MultiPoint{}
+	_ Shape = MultiLineString{}
+	_ Shape = MultiPolygon{}
+	_ Shape = GeometryCollection{}
+)
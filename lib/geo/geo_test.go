@@ -0,0 +1,148 @@
+package geo
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testShapes = []struct {
+	shape Shape
+	wkt   string
+}{
+	{Point{X: 1, Y: -2.5}, `POINT(1 -2.5)`},
+	{LineString{{0, 0}, {1, 1}, {2, 0}}, `LINESTRING(0 0,1 1,2 0)`},
+	{LineString{}, `LINESTRING EMPTY`},
+	{Polygon{{{0, 0}, {4, 0}, {4, 4}, {0, 0}}, {{1, 1}, {2, 1}, {2, 2}, {1, 1}}}, `POLYGON((0 0,4 0,4 4,0 0),(1 1,2 1,2 2,1 1))`},
+	{MultiPoint{{1, 2}, {3, 4}}, `MULTIPOINT((1 2),(3 4))`},
+	{MultiLineString{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}}, `MULTILINESTRING((0 0,1 1),(2 2,3 3))`},
+	{MultiPolygon{{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}}, `MULTIPOLYGON(((0 0,1 0,1 1,0 0)))`},
+	{GeometryCollection{Point{X: 1, Y: 2}, LineString{{0, 0}, {1, 1}}}, `GEOMETRYCOLLECTION(POINT(1 2),LINESTRING(0 0,1 1))`},
+	{GeometryCollection{}, `GEOMETRYCOLLECTION EMPTY`},
+}
+
+func TestWKB(t *testing.T) {
+	for _, tc := range testShapes {
+		for _, srid := range []int{0, 4326} {
+			g := Geometry{SRID: srid, Shape: tc.shape}
+
+			b, err := g.EWKB()
+			assert.NoError(t, err)
+
+			decoded, err := ParseWKB(b)
+			assert.NoError(t, err)
+			assert.Equal(t, g, decoded)
+
+			b, err = g.WKB()
+			assert.NoError(t, err)
+
+			decoded, err = ParseWKB(b)
+			assert.NoError(t, err)
+			assert.Equal(t, Geometry{Shape: tc.shape}, decoded)
+		}
+	}
+
+	{
+		// SELECT ST_AsEWKB('SRID=4326;POINT(1 2)'::geometry) on PostGIS.
+		b, _ := hex.DecodeString("0101000020e6100000000000000000f03f0000000000000040")
+		g, err := ParseWKB(b)
+		assert.NoError(t, err)
+		assert.Equal(t, Geometry{SRID: 4326, Shape: Point{X: 1, Y: 2}}, g)
+
+		encoded, err := g.EWKB()
+		assert.NoError(t, err)
+		assert.Equal(t, b, encoded)
+	}
+
+	{
+		// Big-endian WKB.
+		b, _ := hex.DecodeString("00000000013ff00000000000004000000000000000")
+		g, err := ParseWKB(b)
+		assert.NoError(t, err)
+		assert.Equal(t, Geometry{Shape: Point{X: 1, Y: 2}}, g)
+	}
+
+	{
+		// Three-dimensional point.
+		b, _ := hex.DecodeString("01e9030000000000000000f03f00000000000000400000000000000840")
+		_, err := ParseWKB(b)
+		assert.Equal(t, ErrUnsupportedDimension, err)
+	}
+
+	for _, b := range [][]byte{nil, {0x02}, {0x01, 0x01, 0x00}, {0x01, 0x02, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff}} {
+		_, err := ParseWKB(b)
+		assert.Equal(t, ErrInvalidWKB, err)
+	}
+
+	_, err := Geometry{}.WKB()
+	assert.Equal(t, ErrUnsupportedShape, err)
+}
+
+func TestWKT(t *testing.T) {
+	for _, tc := range testShapes {
+		g := Geometry{Shape: tc.shape}
+		assert.Equal(t, tc.wkt, g.WKT())
+
+		decoded, err := ParseWKT(tc.wkt)
+		assert.NoError(t, err)
+		assert.Equal(t, g, decoded)
+	}
+
+	{
+		g, err := ParseWKT(` srid=3857; multipoint ( 1 2 , 3.5 4e2 ) `)
+		assert.NoError(t, err)
+		assert.Equal(t, Geometry{SRID: 3857, Shape: MultiPoint{{1, 2}, {3.5, 400}}}, g)
+		assert.Equal(t, `SRID=3857;MULTIPOINT((1 2),(3.5 400))`, g.String())
+	}
+
+	{
+		_, err := ParseWKT(`POINT(1 2 3)`)
+		assert.Equal(t, ErrUnsupportedDimension, err)
+	}
+
+	for _, s := range []string{``, `POINT`, `POINT(1)`, `POINT(1 2`, `POINT(1 2) x`, `CIRCLE(1 2)`, `SRID=x;POINT(1 2)`, `LINESTRING(0 0,)`} {
+		_, err := ParseWKT(s)
+		assert.Equal(t, ErrInvalidWKT, err, s)
+	}
+}
+
+func TestGeoJSON(t *testing.T) {
+	for _, tc := range testShapes {
+		g := Geometry{Shape: tc.shape}
+
+		b, err := json.Marshal(g)
+		assert.NoError(t, err)
+
+		var decoded Geometry
+		assert.NoError(t, json.Unmarshal(b, &decoded))
+		assert.Equal(t, g, decoded)
+	}
+
+	{
+		b, err := json.Marshal(Geometry{SRID: 4326, Shape: Polygon{{{0, 0}, {1, 0}, {1, 1}, {0, 0}}}})
+		assert.NoError(t, err)
+		assert.Equal(t, `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`, string(b))
+	}
+
+	{
+		g := Geometry{SRID: 4326}
+		assert.NoError(t, json.Unmarshal([]byte(`{"type": "Point", "coordinates": [2.35, 48.85]}`), &g))
+		assert.Equal(t, Geometry{SRID: 4326, Shape: Point{X: 2.35, Y: 48.85}}, g)
+
+		assert.NoError(t, json.Unmarshal([]byte(`null`), &g))
+		assert.True(t, g.IsZero())
+
+		b, err := json.Marshal(g)
+		assert.NoError(t, err)
+		assert.Equal(t, `null`, string(b))
+	}
+
+	{
+		var g Geometry
+		assert.Equal(t, ErrUnsupportedDimension, json.Unmarshal([]byte(`{"type": "Point", "coordinates": [1, 2, 3]}`), &g))
+		assert.Equal(t, ErrUnsupportedShape, json.Unmarshal([]byte(`{"type": "Circle", "coordinates": [1, 2]}`), &g))
+		assert.Equal(t, ErrInvalidGeoJSON, json.Unmarshal([]byte(`{"type": "Point"}`), &g))
+	}
+}
@@ -0,0 +1,210 @@
+package geo
+
+import (
+	"encoding/json"
+)
+
+type geoJSON struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates,omitempty"`
+	Geometries  []*geoJSON      `json:"geometries,omitempty"`
+}
+
+// MarshalJSON encodes the geometry as a GeoJSON geometry object, the SRID is
+// not included. The zero value is encoded as null.
+func (g Geometry) MarshalJSON() ([]byte, error) {
+	if g.Shape == nil {
+		return []byte("null"), nil
+	}
+	v, err := toGeoJSON(g.Shape)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a GeoJSON geometry object, the SRID is left
+// untouched.
+func (g *Geometry) UnmarshalJSON(b []byte) error {
+	var v *geoJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v == nil {
+		g.Shape = nil
+		return nil
+	}
+	shape, err := fromGeoJSON(v)
+	if err != nil {
+		return err
+	}
+	g.Shape = shape
+	return nil
+}
+
+func pointToGeoJSON(p Point) []float64 {
+	return []float64{p.X, p.Y}
+}
+
+func pointsToGeoJSON(points []Point) [][]float64 {
+	coords := make([][]float64, len(points))
+	for i := range points {
+		coords[i] = pointToGeoJSON(points[i])
+	}
+	return coords
+}
+
+func ringsToGeoJSON(rings []LineString) [][][]float64 {
+	coords := make([][][]float64, len(rings))
+	for i := range rings {
+		coords[i] = pointsToGeoJSON(rings[i])
+	}
+	return coords
+}
+
+func toGeoJSON(s Shape) (*geoJSON, error) {
+	var coords interface{}
+
+	switch v := s.(type) {
+	case Point:
+		coords = pointToGeoJSON(v)
+	case LineString:
+		coords = pointsToGeoJSON(v)
+	case Polygon:
+		coords = ringsToGeoJSON(v)
+	case MultiPoint:
+		coords = pointsToGeoJSON(v)
+	case MultiLineString:
+		coords = ringsToGeoJSON(v)
+	case MultiPolygon:
+		polygons := make([][][][]float64, len(v))
+		for i := range v {
+			polygons[i] = ringsToGeoJSON(v[i])
+		}
+		coords = polygons
+	case GeometryCollection:
+		geometries := make([]*geoJSON, len(v))
+		for i := range v {
+			geometry, err := toGeoJSON(v[i])
+			if err != nil {
+				return nil, err
+			}
+			geometries[i] = geometry
+		}
+		return &geoJSON{Type: TypeGeometryCollection, Geometries: geometries}, nil
+	default:
+		return nil, ErrUnsupportedShape
+	}
+
+	b, err := json.Marshal(coords)
+	if err != nil {
+		return nil, err
+	}
+	return &geoJSON{Type: s.Type(), Coordinates: b}, nil
+}
+
+func pointFromGeoJSON(coords []float64) (Point, error) {
+	if len(coords) > 2 {
+		return Point{}, ErrUnsupportedDimension
+	}
+	if len(coords) < 2 {
+		return Point{}, ErrInvalidGeoJSON
+	}
+	return Point{X: coords[0], Y: coords[1]}, nil
+}
+
+func pointsFromGeoJSON(coords [][]float64) ([]Point, error) {
+	points := make([]Point, len(coords))
+	for i := range coords {
+		point, err := pointFromGeoJSON(coords[i])
+		if err != nil {
+			return nil, err
+		}
+		points[i] = point
+	}
+	return points, nil
+}
+
+func ringsFromGeoJSON(coords [][][]float64) ([]LineString, error) {
+	rings := make([]LineString, len(coords))
+	for i := range coords {
+		points, err := pointsFromGeoJSON(coords[i])
+		if err != nil {
+			return nil, err
+		}
+		rings[i] = points
+	}
+	return rings, nil
+}
+
+func fromGeoJSON(v *geoJSON) (Shape, error) {
+	if v.Type == TypeGeometryCollection {
+		shapes := make(GeometryCollection, 0, len(v.Geometries))
+		for i := range v.Geometries {
+			if v.Geometries[i] == nil {
+				return nil, ErrInvalidGeoJSON
+			}
+			shape, err := fromGeoJSON(v.Geometries[i])
+			if err != nil {
+				return nil, err
+			}
+			shapes = append(shapes, shape)
+		}
+		return shapes, nil
+	}
+
+	if len(v.Coordinates) == 0 {
+		return nil, ErrInvalidGeoJSON
+	}
+
+	switch v.Type {
+	case TypePoint:
+		var coords []float64
+		if err := json.Unmarshal(v.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		return pointFromGeoJSON(coords)
+	case TypeLineString, TypeMultiPoint:
+		var coords [][]float64
+		if err := json.Unmarshal(v.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		points, err := pointsFromGeoJSON(coords)
+		if err != nil {
+			return nil, err
+		}
+		if v.Type == TypeMultiPoint {
+			return MultiPoint(points), nil
+		}
+		return LineString(points), nil
+	case TypePolygon, TypeMultiLineString:
+		var coords [][][]float64
+		if err := json.Unmarshal(v.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		rings, err := ringsFromGeoJSON(coords)
+		if err != nil {
+			return nil, err
+		}
+		if v.Type == TypeMultiLineString {
+			return MultiLineString(rings), nil
+		}
+		return Polygon(rings), nil
+	case TypeMultiPolygon:
+		var coords [][][][]float64
+		if err := json.Unmarshal(v.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		polygons := make(MultiPolygon, len(coords))
+		for i := range coords {
+			rings, err := ringsFromGeoJSON(coords[i])
+			if err != nil {
+				return nil, err
+			}
+			polygons[i] = rings
+		}
+		return polygons, nil
+	}
+
+	return nil, ErrUnsupportedShape
+}
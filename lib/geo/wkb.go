@@ -0,0 +1,305 @@
+package geo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+const (
+	wkbBigEndian    = 0
+	wkbLittleEndian = 1
+
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+// ParseWKB decodes a geometry in WKB or in PostGIS' EWKB format, the SRID is
+// only set when given by EWKB.
+func ParseWKB(b []byte) (Geometry, error) {
+	d := &wkbDecoder{b: b}
+
+	shape, srid := d.shape(0)
+	if d.err != nil {
+		return Geometry{}, d.err
+	}
+	if len(d.b) > 0 {
+		return Geometry{}, ErrInvalidWKB
+	}
+
+	return Geometry{SRID: srid, Shape: shape}, nil
+}
+
+// WKB encodes the geometry as little-endian WKB, the SRID is not included.
+func (g Geometry) WKB() ([]byte, error) {
+	e := &wkbEncoder{}
+	if err := e.shape(g.Shape, 0); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// EWKB encodes the geometry as little-endian EWKB, the SRID is included when
+// it's not zero.
+func (g Geometry) EWKB() ([]byte, error) {
+	e := &wkbEncoder{}
+	if err := e.shape(g.Shape, g.SRID); err != nil {
+		return nil, err
+	}
+	return e.buf.Bytes(), nil
+}
+
+type wkbDecoder struct {
+	b     []byte
+	order binary.ByteOrder
+	err   error
+}
+
+func (d *wkbDecoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.b) < n {
+		d.err = ErrInvalidWKB
+		return nil
+	}
+	chunk := d.b[:n]
+	d.b = d.b[n:]
+	return chunk
+}
+
+func (d *wkbDecoder) uint32() uint32 {
+	chunk := d.next(4)
+	if chunk == nil {
+		return 0
+	}
+	return d.order.Uint32(chunk)
+}
+
+// count reads the number of elements that follow, each one of them takes at
+// least size bytes.
+func (d *wkbDecoder) count(size int) int {
+	n := d.uint32()
+	if d.err == nil && uint64(n)*uint64(size) > uint64(len(d.b)) {
+		d.err = ErrInvalidWKB
+		return 0
+	}
+	return int(n)
+}
+
+func (d *wkbDecoder) point() Point {
+	chunk := d.next(16)
+	if chunk == nil {
+		return Point{}
+	}
+	return Point{
+		X: math.Float64frombits(d.order.Uint64(chunk[:8])),
+		Y: math.Float64frombits(d.order.Uint64(chunk[8:])),
+	}
+}
+
+func (d *wkbDecoder) lineString() LineString {
+	n := d.count(16)
+	points := make(LineString, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		points = append(points, d.point())
+	}
+	return points
+}
+
+func (d *wkbDecoder) polygon() Polygon {
+	n := d.count(4)
+	rings := make(Polygon, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		rings = append(rings, d.lineString())
+	}
+	return rings
+}
+
+// shape reads a shape with its header, if want is not zero the shape must be
+// of that WKB type.
+func (d *wkbDecoder) shape(want uint32) (Shape, int) {
+	order := d.next(1)
+	if order == nil {
+		return nil, 0
+	}
+	switch order[0] {
+	case wkbBigEndian:
+		d.order = binary.BigEndian
+	case wkbLittleEndian:
+		d.order = binary.LittleEndian
+	default:
+		d.err = ErrInvalidWKB
+		return nil, 0
+	}
+
+	t := d.uint32()
+
+	var srid int
+	if t&ewkbSRID != 0 {
+		srid = int(d.uint32())
+	}
+	if d.err != nil {
+		return nil, 0
+	}
+	if t&(ewkbZ|ewkbM) != 0 || t&0xffff > wkbGeometryCollection {
+		d.err = ErrUnsupportedDimension
+		return nil, 0
+	}
+	t = t & 0xffff
+
+	if want != 0 && t != want {
+		d.err = ErrInvalidWKB
+		return nil, 0
+	}
+
+	switch t {
+	case wkbPoint:
+		return d.point(), srid
+	case wkbLineString:
+		return d.lineString(), srid
+	case wkbPolygon:
+		return d.polygon(), srid
+	case wkbMultiPoint:
+		n := d.count(21)
+		points := make(MultiPoint, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			point, _ := d.shape(wkbPoint)
+			if point != nil {
+				points = append(points, point.(Point))
+			}
+		}
+		return points, srid
+	case wkbMultiLineString:
+		n := d.count(9)
+		lines := make(MultiLineString, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			line, _ := d.shape(wkbLineString)
+			if line != nil {
+				lines = append(lines, line.(LineString))
+			}
+		}
+		return lines, srid
+	case wkbMultiPolygon:
+		n := d.count(9)
+		polygons := make(MultiPolygon, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			polygon, _ := d.shape(wkbPolygon)
+			if polygon != nil {
+				polygons = append(polygons, polygon.(Polygon))
+			}
+		}
+		return polygons, srid
+	case wkbGeometryCollection:
+		n := d.count(5)
+		shapes := make(GeometryCollection, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			shape, _ := d.shape(0)
+			if shape != nil {
+				shapes = append(shapes, shape)
+			}
+		}
+		return shapes, srid
+	}
+
+	d.err = ErrInvalidWKB
+	return nil, 0
+}
+
+type wkbEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *wkbEncoder) uint32(n uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], n)
+	e.buf.Write(b[:])
+}
+
+func (e *wkbEncoder) point(p Point) {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], math.Float64bits(p.X))
+	binary.LittleEndian.PutUint64(b[8:], math.Float64bits(p.Y))
+	e.buf.Write(b[:])
+}
+
+func (e *wkbEncoder) lineString(l LineString) {
+	e.uint32(uint32(len(l)))
+	for i := range l {
+		e.point(l[i])
+	}
+}
+
+func (e *wkbEncoder) polygon(p Polygon) {
+	e.uint32(uint32(len(p)))
+	for i := range p {
+		e.lineString(p[i])
+	}
+}
+
+func (e *wkbEncoder) header(t uint32, srid int) {
+	e.buf.WriteByte(wkbLittleEndian)
+	if srid != 0 {
+		e.uint32(t | ewkbSRID)
+		e.uint32(uint32(srid))
+		return
+	}
+	e.uint32(t)
+}
+
+// shape writes s with its header, the SRID is only written when it's not
+// zero.
+func (e *wkbEncoder) shape(s Shape, srid int) error {
+	switch v := s.(type) {
+	case Point:
+		e.header(wkbPoint, srid)
+		e.point(v)
+	case LineString:
+		e.header(wkbLineString, srid)
+		e.lineString(v)
+	case Polygon:
+		e.header(wkbPolygon, srid)
+		e.polygon(v)
+	case MultiPoint:
+		e.header(wkbMultiPoint, srid)
+		e.uint32(uint32(len(v)))
+		for i := range v {
+			e.header(wkbPoint, 0)
+			e.point(v[i])
+		}
+	case MultiLineString:
+		e.header(wkbMultiLineString, srid)
+		e.uint32(uint32(len(v)))
+		for i := range v {
+			e.header(wkbLineString, 0)
+			e.lineString(v[i])
+		}
+	case MultiPolygon:
+		e.header(wkbMultiPolygon, srid)
+		e.uint32(uint32(len(v)))
+		for i := range v {
+			e.header(wkbPolygon, 0)
+			e.polygon(v[i])
+		}
+	case GeometryCollection:
+		e.header(wkbGeometryCollection, srid)
+		e.uint32(uint32(len(v)))
+		for i := range v {
+			if err := e.shape(v[i], 0); err != nil {
+				return err
+			}
+		}
+	default:
+		return ErrUnsupportedShape
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package geo
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// WKT encodes the geometry as WKT, the SRID is not included. The zero value
+// is encoded as an empty string.
+func (g Geometry) WKT() string {
+	if g.Shape == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	writeWKT(&buf, g.Shape)
+	return buf.String()
+}
+
+// ParseWKT decodes a geometry in WKT or in PostGIS' EWKT format, that is WKT
+// prefixed by "SRID=n;".
+func ParseWKT(s string) (Geometry, error) {
+	var g Geometry
+
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(strings.ToUpper(s), "SRID=") {
+		i := strings.Index(s, ";")
+		if i < 0 {
+			return Geometry{}, ErrInvalidWKT
+		}
+		srid, err := strconv.Atoi(s[5:i])
+		if err != nil {
+			return Geometry{}, ErrInvalidWKT
+		}
+		g.SRID, s = srid, s[i+1:]
+	}
+
+	p := &wktParser{s: s}
+	g.Shape = p.shape()
+	if p.err == nil && p.peek() != 0 {
+		p.err = ErrInvalidWKT
+	}
+	if p.err != nil {
+		return Geometry{}, p.err
+	}
+
+	return g, nil
+}
+
+func writeWKTPoint(buf *bytes.Buffer, p Point) {
+	buf.WriteString(strconv.FormatFloat(p.X, 'f', -1, 64))
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatFloat(p.Y, 'f', -1, 64))
+}
+
+func writeWKTPoints(buf *bytes.Buffer, points []Point) {
+	buf.WriteByte('(')
+	for i := range points {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeWKTPoint(buf, points[i])
+	}
+	buf.WriteByte(')')
+}
+
+func writeWKTRings(buf *bytes.Buffer, rings []LineString) {
+	buf.WriteByte('(')
+	for i := range rings {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeWKTPoints(buf, rings[i])
+	}
+	buf.WriteByte(')')
+}
+
+func writeWKT(buf *bytes.Buffer, s Shape) {
+	buf.WriteString(strings.ToUpper(s.Type()))
+
+	switch v := s.(type) {
+	case Point:
+		buf.WriteByte('(')
+		writeWKTPoint(buf, v)
+		buf.WriteByte(')')
+		return
+	case LineString:
+		if len(v) > 0 {
+			writeWKTPoints(buf, v)
+			return
+		}
+	case Polygon:
+		if len(v) > 0 {
+			writeWKTRings(buf, v)
+			return
+		}
+	case MultiPoint:
+		if len(v) > 0 {
+			buf.WriteByte('(')
+			for i := range v {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				writeWKTPoints(buf, v[i:i+1])
+			}
+			buf.WriteByte(')')
+			return
+		}
+	case MultiLineString:
+		if len(v) > 0 {
+			writeWKTRings(buf, v)
+			return
+		}
+	case MultiPolygon:
+		if len(v) > 0 {
+			buf.WriteByte('(')
+			for i := range v {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				writeWKTRings(buf, v[i])
+			}
+			buf.WriteByte(')')
+			return
+		}
+	case GeometryCollection:
+		if len(v) > 0 {
+			buf.WriteByte('(')
+			for i := range v {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				writeWKT(buf, v[i])
+			}
+			buf.WriteByte(')')
+			return
+		}
+	}
+
+	buf.WriteString(" EMPTY")
+}
+
+type wktParser struct {
+	s   string
+	err error
+}
+
+// peek skips whitespace and returns the next character, or 0 at the end of
+// the input.
+func (p *wktParser) peek() byte {
+	p.s = strings.TrimLeft(p.s, " \t\r\n")
+	if p.err != nil || p.s == "" {
+		return 0
+	}
+	return p.s[0]
+}
+
+func (p *wktParser) expect(c byte) {
+	if p.peek() != c {
+		if p.err == nil {
+			p.err = ErrInvalidWKT
+		}
+		return
+	}
+	p.s = p.s[1:]
+}
+
+// accept consumes c if it's the next character.
+func (p *wktParser) accept(c byte) bool {
+	if p.peek() != c {
+		return false
+	}
+	p.s = p.s[1:]
+	return true
+}
+
+func (p *wktParser) token(valid func(byte) bool) string {
+	p.peek()
+	i := 0
+	for i < len(p.s) && valid(p.s[i]) {
+		i++
+	}
+	token := p.s[:i]
+	p.s = p.s[i:]
+	return token
+}
+
+func (p *wktParser) word() string {
+	return strings.ToUpper(p.token(func(c byte) bool {
+		return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	}))
+}
+
+func (p *wktParser) number() float64 {
+	token := p.token(func(c byte) bool {
+		return (c >= '0' && c <= '9') || c == '.' || c == '-' || c == '+' || c == 'e' || c == 'E'
+	})
+	f, err := strconv.ParseFloat(token, 64)
+	if err != nil && p.err == nil {
+		p.err = ErrInvalidWKT
+	}
+	return f
+}
+
+func (p *wktParser) point() Point {
+	pt := Point{X: p.number(), Y: p.number()}
+	// A third number would be a Z or M coordinate.
+	if c := p.peek(); (c >= '0' && c <= '9') || c == '.' || c == '-' || c == '+' {
+		p.err = ErrUnsupportedDimension
+	}
+	return pt
+}
+
+// empty consumes an EMPTY keyword, it returns false if the next token is an
+// opening parenthesis.
+func (p *wktParser) empty() bool {
+	if p.peek() == '(' {
+		return false
+	}
+	if p.word() != "EMPTY" && p.err == nil {
+		p.err = ErrInvalidWKT
+	}
+	return true
+}
+
+// list parses a parenthesized, comma separated list of elements.
+func (p *wktParser) list(elem func()) {
+	p.expect('(')
+	for p.err == nil {
+		elem()
+		if !p.accept(',') {
+			break
+		}
+	}
+	p.expect(')')
+}
+
+func (p *wktParser) points() []Point {
+	points := []Point{}
+	p.list(func() {
+		points = append(points, p.point())
+	})
+	return points
+}
+
+func (p *wktParser) rings() []LineString {
+	rings := []LineString{}
+	p.list(func() {
+		rings = append(rings, p.points())
+	})
+	return rings
+}
+
+func (p *wktParser) shape() Shape {
+	switch p.word() {
+	case "POINT":
+		if p.empty() {
+			p.err = ErrInvalidWKT
+			return nil
+		}
+		points := p.points()
+		if len(points) != 1 {
+			if p.err == nil {
+				p.err = ErrInvalidWKT
+			}
+			return nil
+		}
+		return points[0]
+	case "LINESTRING":
+		if p.empty() {
+			return LineString{}
+		}
+		return LineString(p.points())
+	case "POLYGON":
+		if p.empty() {
+			return Polygon{}
+		}
+		return Polygon(p.rings())
+	case "MULTIPOINT":
+		points := MultiPoint{}
+		if p.empty() {
+			return points
+		}
+		p.list(func() {
+			// Both MULTIPOINT((1 2),(3 4)) and MULTIPOINT(1 2,3 4) are valid.
+			if p.accept('(') {
+				points = append(points, p.point())
+				p.expect(')')
+				return
+			}
+			points = append(points, p.point())
+		})
+		return points
+	case "MULTILINESTRING":
+		if p.empty() {
+			return MultiLineString{}
+		}
+		return MultiLineString(p.rings())
+	case "MULTIPOLYGON":
+		polygons := MultiPolygon{}
+		if p.empty() {
+			return polygons
+		}
+		p.list(func() {
+			polygons = append(polygons, p.rings())
+		})
+		return polygons
+	case "GEOMETRYCOLLECTION":
+		shapes := GeometryCollection{}
+		if p.empty() {
+			return shapes
+		}
+		p.list(func() {
+			if shape := p.shape(); shape != nil {
+				shapes = append(shapes, shape)
+			}
+		})
+		return shapes
+	}
+
+	if p.err == nil {
+		p.err = ErrInvalidWKT
+	}
+	return nil
+}
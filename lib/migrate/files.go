@@ -0,0 +1,65 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+var reMigrationFile = regexp.MustCompile(`^(\d+)_(.*)\.(up|down)\.sql$`)
+
+var errUnexpectedFile = errors.New(`migrate: expecting VERSION_NAME.up.sql or VERSION_NAME.down.sql files`)
+
+// FromDir loads the migrations of the given directory. Each migration is
+// defined by a VERSION_NAME.up.sql file and an optional VERSION_NAME.down.sql
+// file, like 0001_create_users.up.sql and 0001_create_users.down.sql. Each
+// file is executed as a single statement, drivers that don't accept many
+// statements at once require a file per statement.
+func FromDir(dir string) ([]*Migration, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint64]*Migration{}
+	var migrations []*Migration
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".sql" {
+			continue
+		}
+
+		match := reMigrationFile.FindStringSubmatch(file.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w, got %q", errUnexpectedFile, file.Name())
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		buf, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+			migrations = append(migrations, migration)
+		}
+
+		if match[3] == "up" {
+			migration.Up = SQL(string(buf))
+		} else {
+			migration.Down = SQL(string(buf))
+		}
+	}
+
+	return migrations, nil
+}
@@ -0,0 +1,402 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package migrate provides versioned schema migrations for SQL sessions.
+//
+// Migrations are identified by a version number and have an up function that
+// applies them and an optional down function that reverts them, functions
+// can be written in Go or loaded from SQL files with FromDir. Applied
+// migrations are recorded in a tracking table:
+//
+//	migrate.Register(&migrate.Migration{
+//		Version: 1,
+//		Name:    "create_users",
+//		Up:      migrate.SQL(`CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(60))`),
+//		Down:    migrate.SQL(`DROP TABLE users`),
+//	})
+//
+//	if err := migrate.Migrate(sess); err != nil {
+//		...
+//	}
+//
+// Runners of the same database take turns through a lock row that's removed
+// once they're done. The lock has no timeout: the row of a runner that
+// crashed stays there and the next runners fail with ErrLocked, until it's
+// removed with Unlock.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// DefaultTable is the name of the tracking table used by migrators that don't
+// set one.
+const DefaultTable = "schema_migrations"
+
+// Common error messages.
+var (
+	ErrLocked           = errors.New(`migrate: migrations are being run by another runner, or one left its lock behind, see Unlock`)
+	ErrIrreversible     = errors.New(`migrate: migration has no down function`)
+	ErrDuplicateVersion = errors.New(`migrate: duplicate migration version`)
+	ErrUnknownVersion   = errors.New(`migrate: applied migration is unknown`)
+	ErrMissingUp        = errors.New(`migrate: migration has no up function`)
+)
+
+// Session is either a sqlbuilder.Database or a sqlbuilder.Tx. Each migration
+// runs within a transaction created with Tx, migrations that run on a
+// transaction are delimited by savepoints.
+type Session interface {
+	db.Database
+	sqlbuilder.SQLBuilder
+
+	Context() context.Context
+	Tx(ctx context.Context, fn func(sess sqlbuilder.Tx) error) error
+}
+
+// Migration represents a versioned change to the schema.
+type Migration struct {
+	// Version identifies the migration, migrations are applied in ascending
+	// order of version and rolled back in descending order.
+	Version uint64
+	// Name is a human readable description of the migration.
+	Name string
+
+	// Up applies the migration.
+	Up func(tx sqlbuilder.Tx) error
+	// Down reverts the migration, migrations without Down can't be rolled
+	// back.
+	Down func(tx sqlbuilder.Tx) error
+}
+
+// SQL returns a migration function that executes the given statements in
+// order.
+func SQL(statements ...string) func(tx sqlbuilder.Tx) error {
+	return func(tx sqlbuilder.Tx) error {
+		for i := range statements {
+			if _, err := tx.Exec(statements[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// MigrationStatus describes a migration and whether it was applied.
+type MigrationStatus struct {
+	Version   uint64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type record struct {
+	Version   uint64 `db:"version"`
+	Name      string `db:"name"`
+	AppliedAt string `db:"applied_at"`
+}
+
+// Migrator runs a set of migrations.
+type Migrator struct {
+	// Table is the name of the tracking table, DefaultTable is used if empty.
+	// A table with the same name and the "_lock" suffix is used to prevent
+	// concurrent runs.
+	Table string
+
+	migrations []*Migration
+}
+
+// New returns a Migrator for the given migrations.
+func New(migrations ...*Migration) *Migrator {
+	m := &Migrator{}
+	m.Register(migrations...)
+	return m
+}
+
+// Register adds migrations to the set.
+func (m *Migrator) Register(migrations ...*Migration) {
+	m.migrations = append(m.migrations, migrations...)
+	sort.SliceStable(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+}
+
+func (m *Migrator) table() string {
+	if m.Table == "" {
+		return DefaultTable
+	}
+	return m.Table
+}
+
+func (m *Migrator) validate() error {
+	for i := range m.migrations {
+		if m.migrations[i].Up == nil {
+			return fmt.Errorf("%w: %d", ErrMissingUp, m.migrations[i].Version)
+		}
+		if i > 0 && m.migrations[i].Version == m.migrations[i-1].Version {
+			return fmt.Errorf("%w: %d", ErrDuplicateVersion, m.migrations[i].Version)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version uint64) *Migration {
+	i := sort.Search(len(m.migrations), func(i int) bool {
+		return m.migrations[i].Version >= version
+	})
+	if i < len(m.migrations) && m.migrations[i].Version == version {
+		return m.migrations[i]
+	}
+	return nil
+}
+
+// Migrate applies all the migrations that were not applied yet.
+func (m *Migrator) Migrate(sess Session) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+
+	return m.locked(sess, func() error {
+		applied, err := m.applied(sess)
+		if err != nil {
+			return err
+		}
+
+		done := make(map[uint64]bool, len(applied))
+		for i := range applied {
+			done[applied[i].Version] = true
+		}
+
+		for _, migration := range m.migrations {
+			if done[migration.Version] {
+				continue
+			}
+			err := sess.Tx(sess.Context(), func(tx sqlbuilder.Tx) error {
+				if err := migration.Up(tx); err != nil {
+					return err
+				}
+				_, err := tx.InsertInto(m.table()).Values(record{
+					Version:   migration.Version,
+					Name:      migration.Name,
+					AppliedAt: time.Now().UTC().Format(time.RFC3339Nano),
+				}).Exec()
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migrate: migration %d failed: %w", migration.Version, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Rollback reverts the last n applied migrations.
+func (m *Migrator) Rollback(sess Session, n int) error {
+	if err := m.validate(); err != nil {
+		return err
+	}
+
+	return m.locked(sess, func() error {
+		applied, err := m.applied(sess)
+		if err != nil {
+			return err
+		}
+
+		for i := len(applied) - 1; i >= 0 && n > 0; i, n = i-1, n-1 {
+			migration := m.find(applied[i].Version)
+			if migration == nil {
+				return fmt.Errorf("%w: %d", ErrUnknownVersion, applied[i].Version)
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("%w: %d", ErrIrreversible, migration.Version)
+			}
+			err := sess.Tx(sess.Context(), func(tx sqlbuilder.Tx) error {
+				if err := migration.Down(tx); err != nil {
+					return err
+				}
+				_, err := tx.DeleteFrom(m.table()).Where("version", migration.Version).Exec()
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migrate: rollback of migration %d failed: %w", migration.Version, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Status returns the status of every known migration, and of the applied
+// migrations that are unknown to the migrator, sorted by version.
+func (m *Migrator) Status(sess Session) ([]MigrationStatus, error) {
+	if err := m.createTables(sess); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(sess)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*MigrationStatus)
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = &MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+	}
+	for i := range applied {
+		status, ok := byVersion[applied[i].Version]
+		if !ok {
+			status = &MigrationStatus{Version: applied[i].Version, Name: applied[i].Name}
+			byVersion[applied[i].Version] = status
+		}
+		status.Applied = true
+		status.AppliedAt, _ = time.Parse(time.RFC3339Nano, applied[i].AppliedAt)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(byVersion))
+	for _, status := range byVersion {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+func (m *Migrator) applied(sess Session) ([]record, error) {
+	var applied []record
+	err := sess.SelectFrom(m.table()).OrderBy("version").All(&applied)
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (m *Migrator) createTables(sess Session) error {
+	created := false
+	if !sess.Collection(m.table()).Exists() {
+		_, err := sess.CreateTable(m.table()).Columns(
+			sqlbuilder.NewColumn("version", sqlbuilder.BigInteger).NotNull().PrimaryKey(),
+			sqlbuilder.NewColumn("name", sqlbuilder.String(255)).NotNull(),
+			sqlbuilder.NewColumn("applied_at", sqlbuilder.String(64)).NotNull(),
+		).Exec()
+		if err != nil {
+			return err
+		}
+		created = true
+	}
+	if !sess.Collection(m.lockTable()).Exists() {
+		_, err := sess.CreateTable(m.lockTable()).Columns(
+			sqlbuilder.NewColumn("id", sqlbuilder.Integer).NotNull().PrimaryKey(),
+		).Exec()
+		if err != nil {
+			return err
+		}
+		created = true
+	}
+	if created {
+		// Collections that were looked up before the tables existed are cached.
+		sess.ClearCache()
+	}
+	return nil
+}
+
+func (m *Migrator) lockTable() string {
+	return m.table() + "_lock"
+}
+
+// locked runs fn while holding the lock of the migrator, the lock is a row in
+// the lock table so it's shared by all the runners of the same database. The
+// row is left behind if the runner crashes, see Unlock.
+func (m *Migrator) locked(sess Session, fn func() error) error {
+	if err := m.createTables(sess); err != nil {
+		return err
+	}
+
+	_, err := sess.InsertInto(m.lockTable()).Values(map[string]interface{}{"id": 1}).Exec()
+	if err != nil {
+		var count struct {
+			N int `db:"n"`
+		}
+		if sess.Select(db.Raw("COUNT(1) AS n")).From(m.lockTable()).One(&count) == nil && count.N > 0 {
+			return ErrLocked
+		}
+		return err
+	}
+	defer m.unlock(sess)
+
+	return fn()
+}
+
+func (m *Migrator) unlock(sess Session) error {
+	_, err := sess.DeleteFrom(m.lockTable()).Where("id", 1).Exec()
+	return err
+}
+
+// Unlock removes the lock left by a runner that didn't finish, like one that
+// crashed while running migrations. The lock has no timeout, so it's up to the
+// caller to make sure no other runner is running migrations.
+func (m *Migrator) Unlock(sess Session) error {
+	if err := m.createTables(sess); err != nil {
+		return err
+	}
+	return m.unlock(sess)
+}
+
+var defaultMigrator = New()
+
+// Register adds migrations to the default set, it's meant to be called from
+// init functions.
+func Register(migrations ...*Migration) {
+	defaultMigrator.Register(migrations...)
+}
+
+// Migrate applies the migrations of the default set that were not applied
+// yet.
+func Migrate(sess Session) error {
+	return defaultMigrator.Migrate(sess)
+}
+
+// Rollback reverts the last n applied migrations of the default set.
+func Rollback(sess Session, n int) error {
+	return defaultMigrator.Rollback(sess, n)
+}
+
+// Status returns the status of the migrations of the default set.
+func Status(sess Session) ([]MigrationStatus, error) {
+	return defaultMigrator.Status(sess)
+}
+
+// Unlock removes the lock left by a runner of the default set that didn't
+// finish.
+func Unlock(sess Session) error {
+	return defaultMigrator.Unlock(sess)
+}
@@ -0,0 +1,73 @@
+package migrate
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+func TestFromDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrations")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"0002_add_email.up.sql":      `ALTER TABLE users ADD COLUMN email VARCHAR(60)`,
+		"0001_create_users.up.sql":   `CREATE TABLE users (id INTEGER PRIMARY KEY)`,
+		"0001_create_users.down.sql": `DROP TABLE users`,
+		"README.md":                  `Not a migration.`,
+	}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	migrations, err := FromDir(dir)
+	assert.NoError(t, err)
+
+	m := New(migrations...)
+	assert.NoError(t, m.validate())
+
+	if assert.Equal(t, 2, len(m.migrations)) {
+		assert.Equal(t, uint64(1), m.migrations[0].Version)
+		assert.Equal(t, "create_users", m.migrations[0].Name)
+		assert.NotNil(t, m.migrations[0].Down)
+
+		assert.Equal(t, uint64(2), m.migrations[1].Version)
+		assert.Equal(t, "add_email", m.migrations[1].Name)
+		assert.Nil(t, m.migrations[1].Down)
+	}
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "create_posts.up.sql"), []byte(``), 0644))
+
+	_, err = FromDir(dir)
+	assert.True(t, errors.Is(err, errUnexpectedFile))
+}
+
+func TestValidate(t *testing.T) {
+	noop := func(tx sqlbuilder.Tx) error {
+		return nil
+	}
+
+	m := New(
+		&Migration{Version: 2, Up: noop},
+		&Migration{Version: 1, Up: noop},
+	)
+	assert.NoError(t, m.validate())
+	assert.Equal(t, uint64(2), m.find(2).Version)
+	assert.Nil(t, m.find(3))
+
+	m.Register(&Migration{Version: 2, Up: noop})
+	err := m.validate()
+	assert.True(t, errors.Is(err, ErrDuplicateVersion))
+	assert.Equal(t, "migrate: duplicate migration version: 2", err.Error())
+
+	m = New(&Migration{Version: 3})
+	err = m.validate()
+	assert.True(t, errors.Is(err, ErrMissingUp))
+	assert.Equal(t, "migrate: migration has no up function: 3", err.Error())
+}
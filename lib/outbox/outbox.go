@@ -0,0 +1,231 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package outbox implements the transactional outbox pattern for SQL
+// sessions.
+//
+// Events are written into an outbox table within the same transaction as the
+// changes they describe, so they're stored if and only if the changes are
+// committed. A consumer polls the table and hands the events to a handler,
+// like one that publishes them to a message broker:
+//
+//	box := outbox.New(sess, "")
+//
+//	err := sess.Tx(ctx, func(tx sqlbuilder.Tx) error {
+//		if _, err := tx.Collection("orders").Insert(order); err != nil {
+//			return err
+//		}
+//		return box.Publish(tx, "order.created", payload)
+//	})
+//	...
+//
+//	err = box.Consume(ctx, func(ctx context.Context, event *outbox.Event) error {
+//		return broker.Send(ctx, event.Topic, event.Payload)
+//	})
+//
+// Delivery is at-least-once: an event is removed once its handler returns
+// nil, and it's handed out again if the consumer stops before that. Consumers
+// claim events with SELECT ... FOR UPDATE SKIP LOCKED, so several consumers
+// can poll the same table without handing out the same event twice, which
+// requires a database with row locks: PostgreSQL, CockroachDB, MySQL 8.0,
+// MSSQL or Oracle.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// DefaultTable is the name of the outbox table used by outboxes that don't
+// set one.
+const DefaultTable = "outbox"
+
+// Defaults of BatchSize and PollInterval.
+const (
+	DefaultBatchSize    = 100
+	DefaultPollInterval = time.Second
+)
+
+// Session is either a sqlbuilder.Database or a sqlbuilder.Tx. Events are
+// consumed within transactions created with Tx.
+type Session interface {
+	db.Database
+	sqlbuilder.SQLBuilder
+
+	Tx(ctx context.Context, fn func(sess sqlbuilder.Tx) error) error
+}
+
+// Event is a record of the outbox table.
+type Event struct {
+	ID        int64     `db:"id,omitempty"`
+	Topic     string    `db:"topic"`
+	Payload   []byte    `db:"payload"`
+	CreatedAt time.Time `db:"created_at"`
+
+	// Attempts is the number of times the event was handed to a handler
+	// that failed to handle it.
+	Attempts int `db:"attempts"`
+}
+
+// Handler handles an event, events are removed from the outbox once their
+// handler returns nil.
+type Handler func(ctx context.Context, event *Event) error
+
+// Outbox writes events into an outbox table and consumes them.
+type Outbox struct {
+	// BatchSize is the maximum number of events claimed at once by Consume,
+	// DefaultBatchSize is used if it's zero.
+	BatchSize int
+
+	// PollInterval is how long Consume waits before polling again once the
+	// outbox is empty, DefaultPollInterval is used if it's zero.
+	PollInterval time.Duration
+
+	sess  Session
+	table string
+}
+
+// New returns an Outbox that consumes the events of the given table, or of
+// DefaultTable if table is empty, with the given session.
+func New(sess Session, table string) *Outbox {
+	if table == "" {
+		table = DefaultTable
+	}
+	return &Outbox{sess: sess, table: table}
+}
+
+// Table returns the name of the outbox table.
+func (o *Outbox) Table() string {
+	return o.table
+}
+
+func (o *Outbox) batchSize() int {
+	if o.BatchSize <= 0 {
+		return DefaultBatchSize
+	}
+	return o.BatchSize
+}
+
+func (o *Outbox) pollInterval() time.Duration {
+	if o.PollInterval <= 0 {
+		return DefaultPollInterval
+	}
+	return o.PollInterval
+}
+
+// CreateTable creates the outbox table if it doesn't exist.
+func (o *Outbox) CreateTable() error {
+	if o.sess.Collection(o.table).Exists() {
+		return nil
+	}
+
+	_, err := o.sess.CreateTable(o.table).Columns(
+		sqlbuilder.NewColumn("id", sqlbuilder.BigInteger).PrimaryKey().AutoIncrement(),
+		sqlbuilder.NewColumn("topic", sqlbuilder.String(255)).NotNull(),
+		sqlbuilder.NewColumn("payload", sqlbuilder.Binary),
+		sqlbuilder.NewColumn("created_at", sqlbuilder.Timestamp).NotNull(),
+		sqlbuilder.NewColumn("attempts", sqlbuilder.Integer).NotNull().Default(0),
+	).Exec()
+	if err != nil {
+		return err
+	}
+
+	// The collection was cached when it didn't exist.
+	o.sess.ClearCache()
+	return nil
+}
+
+// Publish writes an event into the outbox within the given transaction, the
+// event is consumed once the transaction is committed.
+func (o *Outbox) Publish(tx sqlbuilder.Tx, topic string, payload []byte) error {
+	_, err := tx.InsertInto(o.table).Values(Event{
+		Topic:     topic,
+		Payload:   payload,
+		CreatedAt: tx.Clock()().UTC(),
+	}).Exec()
+	return err
+}
+
+// Consume hands the events of the outbox to handler in the order they were
+// published, polling for new ones until ctx is done, in which case the error
+// of ctx is returned. Events that handler fails to handle stay in the outbox
+// with their Attempts increased, and Consume returns the error of handler.
+func (o *Outbox) Consume(ctx context.Context, handler Handler) error {
+	for {
+		n, err := o.consumeBatch(ctx, handler)
+		if err != nil {
+			return err
+		}
+		if n == o.batchSize() {
+			// There may be more events waiting.
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(o.pollInterval()):
+		}
+	}
+}
+
+// consumeBatch claims a batch of events and hands them to handler within a
+// transaction, the events are locked until it ends.
+func (o *Outbox) consumeBatch(ctx context.Context, handler Handler) (int, error) {
+	var (
+		n          int
+		handlerErr error
+	)
+	err := o.sess.Tx(ctx, func(tx sqlbuilder.Tx) error {
+		var events []Event
+		err := tx.SelectFrom(o.table).
+			OrderBy("id").
+			Limit(o.batchSize()).
+			ForUpdate().
+			SkipLocked().
+			All(&events)
+		if err != nil {
+			return err
+		}
+		n = len(events)
+
+		for i := range events {
+			if handlerErr = handler(ctx, &events[i]); handlerErr != nil {
+				_, err := tx.Update(o.table).
+					Set("attempts", events[i].Attempts+1).
+					Where("id", events[i].ID).
+					Exec()
+				return err
+			}
+			if _, err := tx.DeleteFrom(o.table).Where("id", events[i].ID).Exec(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, handlerErr
+}
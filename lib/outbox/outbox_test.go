@@ -0,0 +1,236 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// fakeSession keeps the outbox table in memory, its transactions discard the
+// changes made to the table if they fail.
+type fakeSession struct {
+	Session
+
+	events []Event
+	lastID int64
+	now    time.Time
+}
+
+func (s *fakeSession) Tx(ctx context.Context, fn func(sqlbuilder.Tx) error) error {
+	events := append([]Event(nil), s.events...)
+	if err := fn(&fakeTx{sess: s}); err != nil {
+		s.events = events
+		return err
+	}
+	return nil
+}
+
+// txSession is embedded under another name, sqlbuilder.Tx has a Tx method.
+type txSession = sqlbuilder.Tx
+
+type fakeTx struct {
+	txSession
+	sess *fakeSession
+}
+
+func (tx *fakeTx) Clock() func() time.Time {
+	return func() time.Time { return tx.sess.now }
+}
+
+func (tx *fakeTx) InsertInto(table string) sqlbuilder.Inserter {
+	return &fakeInserter{sess: tx.sess}
+}
+
+func (tx *fakeTx) SelectFrom(table ...interface{}) sqlbuilder.Selector {
+	return &fakeSelector{sess: tx.sess}
+}
+
+func (tx *fakeTx) Update(table string) sqlbuilder.Updater {
+	return &fakeUpdater{sess: tx.sess}
+}
+
+func (tx *fakeTx) DeleteFrom(table string) sqlbuilder.Deleter {
+	return &fakeDeleter{sess: tx.sess}
+}
+
+type fakeInserter struct {
+	sqlbuilder.Inserter
+	sess  *fakeSession
+	event Event
+}
+
+func (i *fakeInserter) Values(values ...interface{}) sqlbuilder.Inserter {
+	i.event = values[0].(Event)
+	return i
+}
+
+func (i *fakeInserter) Exec() (sql.Result, error) {
+	i.sess.lastID++
+	i.event.ID = i.sess.lastID
+	i.sess.events = append(i.sess.events, i.event)
+	return driver.RowsAffected(1), nil
+}
+
+type fakeSelector struct {
+	sqlbuilder.Selector
+	sess  *fakeSession
+	limit int
+}
+
+func (s *fakeSelector) OrderBy(...interface{}) sqlbuilder.Selector { return s }
+func (s *fakeSelector) ForUpdate() sqlbuilder.Selector             { return s }
+func (s *fakeSelector) SkipLocked() sqlbuilder.Selector            { return s }
+
+func (s *fakeSelector) Limit(n int) sqlbuilder.Selector {
+	s.limit = n
+	return s
+}
+
+func (s *fakeSelector) All(dest interface{}) error {
+	events := s.sess.events
+	if len(events) > s.limit {
+		events = events[:s.limit]
+	}
+	*dest.(*[]Event) = append([]Event(nil), events...)
+	return nil
+}
+
+type fakeUpdater struct {
+	sqlbuilder.Updater
+	sess     *fakeSession
+	attempts int
+	id       int64
+}
+
+func (u *fakeUpdater) Set(values ...interface{}) sqlbuilder.Updater {
+	u.attempts = values[1].(int)
+	return u
+}
+
+func (u *fakeUpdater) Where(conds ...interface{}) sqlbuilder.Updater {
+	u.id = conds[1].(int64)
+	return u
+}
+
+func (u *fakeUpdater) Exec() (sql.Result, error) {
+	for i := range u.sess.events {
+		if u.sess.events[i].ID == u.id {
+			u.sess.events[i].Attempts = u.attempts
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+type fakeDeleter struct {
+	sqlbuilder.Deleter
+	sess *fakeSession
+	id   int64
+}
+
+func (d *fakeDeleter) Where(conds ...interface{}) sqlbuilder.Deleter {
+	d.id = conds[1].(int64)
+	return d
+}
+
+func (d *fakeDeleter) Exec() (sql.Result, error) {
+	for i := range d.sess.events {
+		if d.sess.events[i].ID == d.id {
+			d.sess.events = append(d.sess.events[:i], d.sess.events[i+1:]...)
+			break
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestNew(t *testing.T) {
+	o := New(nil, "")
+	assert.Equal(t, DefaultTable, o.Table())
+	assert.Equal(t, DefaultBatchSize, o.batchSize())
+	assert.Equal(t, DefaultPollInterval, o.pollInterval())
+
+	o = New(nil, "events")
+	o.BatchSize = 10
+	o.PollInterval = 50 * time.Millisecond
+	assert.Equal(t, "events", o.Table())
+	assert.Equal(t, 10, o.batchSize())
+	assert.Equal(t, 50*time.Millisecond, o.pollInterval())
+}
+
+func TestPublish(t *testing.T) {
+	sess := &fakeSession{now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	o := New(sess, "")
+
+	err := sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		return o.Publish(tx, "order.created", []byte(`{"id":1}`))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{ID: 1, Topic: "order.created", Payload: []byte(`{"id":1}`), CreatedAt: sess.now}}, sess.events)
+
+	// Events are not stored if the transaction fails.
+	errFailed := errors.New("could not create order")
+	err = sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+		if err := o.Publish(tx, "order.created", []byte(`{"id":2}`)); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	assert.Equal(t, errFailed, err)
+	assert.Equal(t, 1, len(sess.events))
+}
+
+func TestConsume(t *testing.T) {
+	sess := &fakeSession{}
+	o := New(sess, "")
+	o.BatchSize = 2
+	o.PollInterval = time.Millisecond
+
+	for _, topic := range []string{"a", "b", "c"} {
+		err := sess.Tx(context.Background(), func(tx sqlbuilder.Tx) error {
+			return o.Publish(tx, topic, nil)
+		})
+		assert.NoError(t, err)
+	}
+
+	// Events are handed out in the order they were published, the ones
+	// that fail stay in the outbox and Consume stops.
+	errUnavailable := errors.New("broker unavailable")
+	var topics []string
+	err := o.Consume(context.Background(), func(ctx context.Context, event *Event) error {
+		topics = append(topics, event.Topic)
+		if event.Topic == "b" {
+			return errUnavailable
+		}
+		return nil
+	})
+	assert.Equal(t, errUnavailable, err)
+	assert.Equal(t, []string{"a", "b"}, topics)
+	assert.Equal(t, 2, len(sess.events))
+	assert.Equal(t, "b", sess.events[0].Topic)
+	assert.Equal(t, 1, sess.events[0].Attempts)
+
+	// Failed events are retried, and the outbox is polled until ctx is
+	// done.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topics = nil
+	var attempts []int
+	err = o.Consume(ctx, func(ctx context.Context, event *Event) error {
+		topics = append(topics, event.Topic)
+		attempts = append(attempts, event.Attempts)
+		if event.Topic == "c" {
+			cancel()
+		}
+		return nil
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []string{"b", "c"}, topics)
+	assert.Equal(t, []int{1, 0}, attempts)
+	assert.Equal(t, 0, len(sess.events))
+}
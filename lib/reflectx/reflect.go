@@ -62,6 +62,8 @@ func (f StructMap) GetByTraversal(index []int) *FieldInfo {
 // mapping and a function to provide a basic mapping of fields to names.
 type Mapper struct {
 	cache      map[reflect.Type]*StructMap
+	plans      map[reflect.Type]map[string][]*FieldInfo
+	planKey    []byte
 	tagName    string
 	tagMapFunc func(string) string
 	mapFunc    func(string) string
@@ -100,6 +102,11 @@ func NewMapperFunc(tagName string, f func(string) string) *Mapper {
 	}
 }
 
+// TagName returns the name of the struct tag the mapper obeys.
+func (m *Mapper) TagName() string {
+	return m.tagName
+}
+
 // TypeMap returns a mapping of field strings to int slices representing
 // the traversal down the struct to reach the field.
 func (m *Mapper) TypeMap(t reflect.Type) *StructMap {
@@ -113,6 +120,50 @@ func (m *Mapper) TypeMap(t reflect.Type) *StructMap {
 	return mapping
 }
 
+// ColumnFields returns the fields of the struct type t (or pointer to it) that
+// the given column names are mapped to, in the same order, nil for the names
+// that are not mapped. Results are cached per type and set of names, they
+// must not be modified.
+func (m *Mapper) ColumnFields(t reflect.Type, columns []string) []*FieldInfo {
+	t = Deref(t)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.planKey = m.planKey[:0]
+	for _, column := range columns {
+		m.planKey = append(m.planKey, column...)
+		m.planKey = append(m.planKey, 0)
+	}
+
+	plans := m.plans[t]
+	if fields, ok := plans[string(m.planKey)]; ok {
+		return fields
+	}
+
+	mapping, ok := m.cache[t]
+	if !ok {
+		mapping = getMapping(t, m.tagName, m.mapFunc, m.tagMapFunc)
+		m.cache[t] = mapping
+	}
+
+	fields := make([]*FieldInfo, len(columns))
+	for i, column := range columns {
+		fields[i] = mapping.Names[column]
+	}
+
+	if plans == nil {
+		if m.plans == nil {
+			m.plans = make(map[reflect.Type]map[string][]*FieldInfo)
+		}
+		plans = make(map[string][]*FieldInfo)
+		m.plans[t] = plans
+	}
+	plans[string(m.planKey)] = fields
+
+	return fields
+}
+
 // FieldMap returns the mapper's mapping of field names to reflect values.  Panics
 // if v's Kind is not Struct, or v is not Indirectable to a struct kind.
 func (m *Mapper) FieldMap(v reflect.Value) map[string]reflect.Value {
@@ -238,10 +289,15 @@ func ValidFieldByIndexes(v reflect.Value, indexes []int) reflect.Value {
 
 // FieldByIndexesReadOnly returns a value for a particular struct traversal,
 // but is not concerned with allocating nil pointers because the value is
-// going to be used for reading and not setting.
+// going to be used for reading and not setting. The returned value is not
+// valid if the field is within a nil embedded pointer.
 func FieldByIndexesReadOnly(v reflect.Value, indexes []int) reflect.Value {
 	for _, i := range indexes {
-		v = reflect.Indirect(v).Field(i)
+		v = reflect.Indirect(v)
+		if !v.IsValid() {
+			return reflect.Value{}
+		}
+		v = v.Field(i)
 	}
 	return v
 }
@@ -295,6 +351,16 @@ func apnd(is []int, i int) []int {
 	return x
 }
 
+// inlinePath returns the path of the fields of a struct tagged with
+// inline=prefix, which are mapped as "prefix.name" instead of being flattened
+// into their parent.
+func inlinePath(pp string, prefix string) string {
+	if pp == "" {
+		return prefix
+	}
+	return pp + "." + prefix
+}
+
 // getMapping returns a mapping for the t type, using the tagName, mapFunc and
 // tagMapFunc to determine the canonical names of fields.
 func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc func(string) string) *StructMap {
@@ -368,10 +434,17 @@ func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc func(string)
 				continue
 			}
 
-			// bfs search of anonymous embedded structs
-			if f.Anonymous {
+			_, inline := fi.Options["inline"]
+			isStruct := Deref(f.Type).Kind() == reflect.Struct
+
+			// bfs search of anonymous embedded structs, and of named structs
+			// tagged with inline, their fields are flattened into their parent
+			// or else prefixed with the value of inline.
+			if f.Anonymous || (inline && isStruct) {
 				pp := tq.pp
-				if tag != "" {
+				if prefix := fi.Options["inline"]; prefix != "" {
+					pp = inlinePath(tq.pp, prefix)
+				} else if tag != "" && name != "" && !inline {
 					pp = fi.Path
 				}
 
@@ -383,7 +456,7 @@ func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc func(string)
 					nChildren = ft.NumField()
 				}
 				fi.Children = make([]*FieldInfo, nChildren)
-				queue = append(queue, typeQueue{Deref(f.Type), &fi, pp})
+				queue = append(queue, typeQueue{ft, &fi, pp})
 			} else if fi.Zero.Kind() == reflect.Struct || (fi.Zero.Kind() == reflect.Ptr && fi.Zero.Type().Elem().Kind() == reflect.Struct) {
 				fi.Index = apnd(tq.fi.Index, fieldPos)
 				fi.Children = make([]*FieldInfo, Deref(f.Type).NumField())
@@ -247,6 +247,105 @@ func TestInlineStruct(t *testing.T) {
 	}
 }
 
+func TestInlinePrefixStruct(t *testing.T) {
+	m := NewMapperFunc("db", strings.ToLower)
+
+	type User struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	type Account struct {
+		ID     int `db:"id"`
+		UserID int `db:"user_id"`
+	}
+	type row struct {
+		User     `db:",inline=u"`
+		Account  *Account `db:",inline=a"`
+		Position int      `db:"position"`
+	}
+	// row columns: (u.id u.name a.id a.user_id position)
+
+	r := row{User: User{ID: 1, Name: "Joe"}, Account: &Account{ID: 2, UserID: 1}, Position: 3}
+	rv := reflect.ValueOf(r)
+
+	fields := m.TypeMap(reflect.TypeOf(r))
+	for _, name := range []string{"u.id", "u.name", "a.id", "a.user_id", "position"} {
+		if _, ok := fields.Names[name]; !ok {
+			t.Errorf("Expecting %q to be mapped", name)
+		}
+	}
+	if len(fields.Names) != 5 {
+		t.Errorf("Expecting 5 names, got %d", len(fields.Names))
+	}
+
+	v := m.FieldByName(rv, "u.name")
+	if v.Interface().(string) != r.User.Name {
+		t.Errorf("Expecting %s, got %s", r.User.Name, v.Interface().(string))
+	}
+	v = m.FieldByName(rv, "a.user_id")
+	if ival(v) != r.Account.UserID {
+		t.Errorf("Expecting %v, got %v", r.Account.UserID, ival(v))
+	}
+}
+
+func TestInlineNamedStruct(t *testing.T) {
+	m := NewMapper("db")
+
+	type Address struct {
+		Street string `db:"street"`
+		City   string `db:"city"`
+	}
+	type Base struct {
+		ID int64 `db:"id"`
+	}
+	type Contact struct {
+		Phone string  `db:"phone"`
+		Home  Address `db:",inline"`
+	}
+	type person struct {
+		*Base
+		Name    string   `db:"name"`
+		Work    *Address `db:"work,inline=w"`
+		Contact Contact  `db:"contact,inline"`
+		Other   struct {
+			Home Address `db:",inline"`
+		} `db:"other"`
+	}
+	// person columns: (id name w.street w.city phone street city other.street other.city)
+
+	fields := m.TypeMap(reflect.TypeOf(person{}))
+	for _, name := range []string{"id", "name", "w.street", "w.city", "phone", "street", "city", "other.street", "other.city"} {
+		if _, ok := fields.Names[name]; !ok {
+			t.Errorf("Expecting %q to be mapped", name)
+		}
+	}
+	for _, name := range []string{"work", "contact", "other."} {
+		if _, ok := fields.Names[name]; ok {
+			t.Errorf("Expecting %q not to be mapped", name)
+		}
+	}
+
+	var p person
+	pv := reflect.ValueOf(&p)
+
+	m.FieldByName(pv, "id").SetInt(1)
+	m.FieldByName(pv, "w.city").SetString("Lima")
+	m.FieldByName(pv, "street").SetString("Main St")
+	if p.Base == nil || p.ID != 1 {
+		t.Errorf("Expecting embedded pointer to be allocated")
+	}
+	if p.Work == nil || p.Work.City != "Lima" {
+		t.Errorf("Expecting inline pointer to be allocated")
+	}
+	if p.Contact.Home.Street != "Main St" {
+		t.Errorf("Expecting %q, got %q", "Main St", p.Contact.Home.Street)
+	}
+
+	if v := FieldByIndexesReadOnly(reflect.ValueOf(person{}), fields.Names["id"].Index); v.IsValid() {
+		t.Errorf("Expecting field within nil pointer not to be valid")
+	}
+}
+
 func TestFieldsEmbedded(t *testing.T) {
 	m := NewMapper("db")
 
@@ -437,6 +536,35 @@ func TestFieldMap(t *testing.T) {
 	}
 }
 
+func TestColumnFields(t *testing.T) {
+	type Foo struct {
+		A int
+		B int `db:"bee"`
+	}
+
+	m := NewMapperFunc("db", strings.ToLower)
+
+	fields := m.ColumnFields(reflect.TypeOf(&Foo{}), []string{"bee", "x", "a"})
+	if len(fields) != 3 {
+		t.Fatalf("Expecting %d fields, got %d", 3, len(fields))
+	}
+	if fields[0].Name != "bee" || fields[1] != nil || fields[2].Name != "a" {
+		t.Errorf("Unexpected fields %v", fields)
+	}
+
+	// The same set of columns is given the same plan.
+	again := m.ColumnFields(reflect.TypeOf(Foo{}), []string{"bee", "x", "a"})
+	if &again[0] != &fields[0] {
+		t.Errorf("Expecting a cached plan")
+	}
+
+	// Column names can't be mixed up by the cache key.
+	other := m.ColumnFields(reflect.TypeOf(Foo{}), []string{"bee", "xa"})
+	if len(other) != 2 || other[1] != nil {
+		t.Errorf("Unexpected fields %v", other)
+	}
+}
+
 func TestTagNameMapping(t *testing.T) {
 	type Strategy struct {
 		StrategyID   string `protobuf:"bytes,1,opt,name=strategy_id" json:"strategy_id,omitempty"`
@@ -585,3 +713,15 @@ func BenchmarkFieldByIndexL4(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkColumnFields(b *testing.B) {
+	m := NewMapper("db")
+	t := reflect.TypeOf(E4{})
+	columns := []string{"A", "B", "C", "D"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if len(m.ColumnFields(t, columns)) != len(columns) {
+			b.Fatal("Wrong number of fields.")
+		}
+	}
+}
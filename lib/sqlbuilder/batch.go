@@ -1,12 +1,19 @@
 package sqlbuilder
 
+import (
+	"sync"
+)
+
 // BatchInserter provides a helper that can be used to do massive insertions in
 // batches.
 type BatchInserter struct {
 	inserter *inserter
 	size     int
 	values   chan []interface{}
-	err      error
+	errors   chan error
+
+	mu  sync.Mutex
+	err error
 }
 
 func newBatchInserter(inserter *inserter, size int) *BatchInserter {
@@ -17,6 +24,7 @@ func newBatchInserter(inserter *inserter, size int) *BatchInserter {
 		inserter: inserter,
 		size:     size,
 		values:   make(chan []interface{}, size),
+		errors:   make(chan error, 1),
 	}
 	return b
 }
@@ -46,14 +54,26 @@ func (b *BatchInserter) nextQuery() *inserter {
 
 // NextResult is useful when using PostgreSQL and Returning(), it dumps the
 // next slice of results to dst, which can mean having the IDs of all inserted
-// elements in the batch.
+// elements in the batch. If the statement fails, the values that are still
+// pending are discarded until Done() is called and false is returned.
 func (b *BatchInserter) NextResult(dst interface{}) bool {
 	clone := b.nextQuery()
 	if clone == nil {
 		return false
 	}
-	b.err = clone.Iterator().All(dst)
-	return (b.err == nil)
+	if err := clone.Iterator().All(dst); err != nil {
+		b.setErr(err)
+		b.drain()
+		return false
+	}
+	return true
+}
+
+// drain discards pending values so producers blocked on Values() can reach
+// Done().
+func (b *BatchInserter) drain() {
+	for range b.values {
+	}
 }
 
 // Done means that no more elements are going to be added.
@@ -61,7 +81,10 @@ func (b *BatchInserter) Done() {
 	close(b.values)
 }
 
-// Wait blocks until the whole batch is executed.
+// Wait blocks until the whole batch is executed. Values are flushed as
+// multi-row INSERT statements of up to size rows each. If one of the
+// statements fails, the values that are still pending are discarded until
+// Done() is called and the error is returned.
 func (b *BatchInserter) Wait() error {
 	for {
 		q := b.nextQuery()
@@ -69,7 +92,8 @@ func (b *BatchInserter) Wait() error {
 			break
 		}
 		if _, err := q.Exec(); err != nil {
-			b.err = err
+			b.setErr(err)
+			b.drain()
 			break
 		}
 	}
@@ -78,5 +102,33 @@ func (b *BatchInserter) Wait() error {
 
 // Err returns any error while executing the batch.
 func (b *BatchInserter) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	return b.err
 }
+
+// Errors returns a channel that receives the error that stopped the batch, if
+// any. Producers can use it to stop pushing values as soon as a statement
+// fails:
+//
+//  for i := range items {
+//    select {
+//    case <-batch.Errors():
+//      return
+//    default:
+//      batch.Values(items[i])
+//    }
+//  }
+func (b *BatchInserter) Errors() <-chan error {
+	return b.errors
+}
+
+func (b *BatchInserter) setErr(err error) {
+	b.mu.Lock()
+	b.err = err
+	b.mu.Unlock()
+	select {
+	case b.errors <- err:
+	default:
+	}
+}
@@ -0,0 +1,175 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+var errFakeSessionUnsupported = errors.New("fake session: unsupported")
+
+// fakeSession records the statements that would be sent to the database.
+type fakeSession struct {
+	mu      sync.Mutex
+	t       *exql.Template
+	queries []string
+	args    [][]interface{}
+	execErr error
+	ctx     context.Context
+	clock   func() time.Time
+
+	zeroValuePolicy db.ZeroValuePolicy
+}
+
+func (s *fakeSession) StatementExec(ctx context.Context, stmt *exql.Statement, args ...interface{}) (sql.Result, error) {
+	query, err := stmt.Compile(s.t)
+	if err != nil {
+		return nil, err
+	}
+	query, args = Preprocess(query, args)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+	if s.execErr != nil {
+		return nil, s.execErr
+	}
+	s.queries = append(s.queries, prepareQueryForDisplay(query))
+	s.args = append(s.args, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeSession) StatementPrepare(ctx context.Context, stmt *exql.Statement) (*sql.Stmt, error) {
+	return nil, errFakeSessionUnsupported
+}
+
+func (s *fakeSession) StatementQuery(ctx context.Context, stmt *exql.Statement, args ...interface{}) (*sql.Rows, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+	return nil, errFakeSessionUnsupported
+}
+
+func (s *fakeSession) StatementQueryRow(ctx context.Context, stmt *exql.Statement, args ...interface{}) (*sql.Row, error) {
+	query, err := stmt.Compile(s.t)
+	if err != nil {
+		return nil, err
+	}
+	query, args = Preprocess(query, args)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctx = ctx
+	s.queries = append(s.queries, prepareQueryForDisplay(query))
+	s.args = append(s.args, args)
+	return nil, errFakeSessionUnsupported
+}
+
+func (s *fakeSession) Context() context.Context {
+	return context.Background()
+}
+
+func (s *fakeSession) Clock() func() time.Time {
+	if s.clock == nil {
+		return time.Now
+	}
+	return s.clock
+}
+
+func (s *fakeSession) ZeroValuePolicy() db.ZeroValuePolicy {
+	return s.zeroValuePolicy
+}
+
+func (s *fakeSession) NewUUID(t reflect.Type) (interface{}, error) {
+	return NewUUID(t)
+}
+
+func newFakeBuilder() (*sqlBuilder, *fakeSession) {
+	sess := &fakeSession{t: &testTemplate}
+	tu := newTemplateWithUtils(&testTemplate)
+	tu.sess = sess
+	return &sqlBuilder{sess: sess, t: tu}, sess
+}
+
+func TestBatchInserter(t *testing.T) {
+	b, sess := newFakeBuilder()
+
+	batch := b.InsertInto("artist").Columns("id", "name").Batch(2)
+	go func() {
+		defer batch.Done()
+		for i := 0; i < 5; i++ {
+			batch.Values(i, "name")
+		}
+	}()
+
+	assert.NoError(t, batch.Wait())
+	assert.Equal(t, []string{
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2), ($3, $4)`,
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2), ($3, $4)`,
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2)`,
+	}, sess.queries)
+	assert.Equal(t, []interface{}{4, "name"}, sess.args[2])
+}
+
+func TestBatchInserterError(t *testing.T) {
+	b, sess := newFakeBuilder()
+	sess.execErr = errors.New("insert failed")
+
+	batch := b.InsertInto("artist").Columns("id").Batch(1)
+	go func() {
+		defer batch.Done()
+		for i := 0; i < 10; i++ {
+			batch.Values(i)
+		}
+	}()
+
+	assert.Equal(t, sess.execErr, batch.Wait())
+	assert.Equal(t, sess.execErr, batch.Err())
+	assert.Equal(t, sess.execErr, <-batch.Errors())
+
+	// Producers can check Err() while the batch is being inserted.
+	batch = b.InsertInto("artist").Columns("id").Batch(1)
+	go func() {
+		defer batch.Done()
+		for i := 0; i < 10 && batch.Err() == nil; i++ {
+			batch.Values(i)
+		}
+	}()
+	assert.Equal(t, sess.execErr, batch.Wait())
+}
+
+func TestBatchInserterNextResultError(t *testing.T) {
+	b, _ := newFakeBuilder()
+
+	batch := b.InsertInto("artist").Columns("id").Returning("id").Batch(1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer batch.Done()
+		for i := 0; i < 10; i++ {
+			batch.Values(i)
+		}
+	}()
+
+	var ids []struct {
+		ID int `db:"id"`
+	}
+	assert.False(t, batch.NextResult(&ids))
+	assert.Equal(t, errFakeSessionUnsupported, batch.Err())
+
+	// The producer is not left blocked on Values().
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("producer blocked after NextResult failed")
+	}
+}
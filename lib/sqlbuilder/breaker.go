@@ -0,0 +1,23 @@
+package sqlbuilder
+
+import (
+	"time"
+)
+
+// CircuitBreaker configures the circuit breaker of a session, see
+// Database.SetCircuitBreaker. After Threshold consecutive connection failures
+// the circuit opens and statements fail with db.ErrCircuitOpen without
+// reaching the database, while the database is pinged in the background
+// every ProbeInterval. The circuit closes after the first successful ping.
+type CircuitBreaker struct {
+	// Threshold is the number of consecutive connection failures that open
+	// the circuit. Zero disables the circuit breaker.
+	Threshold int
+
+	// ProbeInterval is the time between pings while the circuit is open, it
+	// is also the timeout of each ping. Defaults to one second.
+	ProbeInterval time.Duration
+
+	// OnStateChange, if not nil, is called when the circuit opens or closes.
+	OnStateChange func(open bool)
+}
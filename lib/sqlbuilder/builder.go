@@ -25,6 +25,7 @@ package sqlbuilder
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
@@ -33,6 +34,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter/compat"
@@ -44,6 +46,27 @@ import (
 type MapOptions struct {
 	IncludeZeroed bool
 	IncludeNil    bool
+
+	// AutoNow is the value of the fields tagged with "auto_now", fields keep
+	// their own value if AutoNow is zero.
+	AutoNow time.Time
+	// AutoNowAdd is the value of the zero fields tagged with "auto_now_add",
+	// fields keep their own value if AutoNowAdd is zero.
+	AutoNowAdd time.Time
+
+	// Mapper maps the fields of structs to columns, the default one obeys
+	// "db" tags.
+	Mapper *reflectx.Mapper
+
+	// NullZero makes the zero values of fields be NULL, except for the ones
+	// of fields tagged with "keepzero". Zero values of fields tagged with
+	// "nullzero" are NULL either way.
+	NullZero bool
+
+	// NewUUID returns the value of the zero fields tagged with "uuid", given
+	// their type. Fields are left to the default value of their column if
+	// NewUUID is nil or if it returns a nil value.
+	NewUUID func(t reflect.Type) (interface{}, error)
 }
 
 var defaultMapOptions = MapOptions{
@@ -52,7 +75,7 @@ var defaultMapOptions = MapOptions{
 }
 
 type compilable interface {
-	Compile() (string, error)
+	compile() (string, error)
 	Arguments() []interface{}
 }
 
@@ -106,14 +129,49 @@ type sqlBuilder struct {
 	t    *templateWithUtils
 }
 
+type hasStatementCompile interface {
+	StatementCompile(stmt *exql.Statement, args []interface{}) (string, []interface{})
+}
+
+// compileStatement returns the query and arguments the session would send to
+// the database server to run stmt. Builders without a session return the
+// query with its placeholders expanded by Preprocess.
+func (b *sqlBuilder) compileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}, error) {
+	query, err := stmt.Compile(b.t.Template)
+	if err != nil {
+		return "", nil, err
+	}
+	if sess, ok := b.sess.(hasStatementCompile); ok {
+		query, args = sess.StatementCompile(stmt, args)
+		return query, args, nil
+	}
+	query, args = Preprocess(query, args)
+	return query, args, nil
+}
+
+type hasClock interface {
+	Clock() func() time.Time
+}
+
+// now returns the current time according to the clock of the session, if
+// any.
+func (b *sqlBuilder) now() time.Time {
+	if c, ok := b.sess.(hasClock); ok {
+		return c.Clock()()
+	}
+	return time.Now()
+}
+
 // WithSession returns a query builder that is bound to the given database session.
 func WithSession(sess interface{}, t *exql.Template) SQLBuilder {
 	if sqlDB, ok := sess.(*sql.DB); ok {
 		sess = sqlDB
 	}
+	tu := newTemplateWithUtils(t)
+	tu.sess = sess
 	return &sqlBuilder{
 		sess: sess.(exprDB), // Let it panic, it will show the developer an informative error.
-		t:    newTemplateWithUtils(t),
+		t:    tu,
 	}
 }
 
@@ -164,7 +222,11 @@ func (b *sqlBuilder) ExecContext(ctx context.Context, query interface{}, args ..
 	case *exql.Statement:
 		return b.sess.StatementExec(ctx, q, args...)
 	case string:
-		return b.sess.StatementExec(ctx, exql.RawSQL(q), args...)
+		raw, args, err := bindNamed(sessionMapper(b.sess), q, args)
+		if err != nil {
+			return nil, err
+		}
+		return b.sess.StatementExec(ctx, exql.RawSQL(raw), args...)
 	case db.RawValue:
 		return b.ExecContext(ctx, q.Raw(), q.Arguments()...)
 	default:
@@ -181,7 +243,11 @@ func (b *sqlBuilder) QueryContext(ctx context.Context, query interface{}, args .
 	case *exql.Statement:
 		return b.sess.StatementQuery(ctx, q, args...)
 	case string:
-		return b.sess.StatementQuery(ctx, exql.RawSQL(q), args...)
+		raw, args, err := bindNamed(sessionMapper(b.sess), q, args)
+		if err != nil {
+			return nil, err
+		}
+		return b.sess.StatementQuery(ctx, exql.RawSQL(raw), args...)
 	case db.RawValue:
 		return b.QueryContext(ctx, q.Raw(), q.Arguments()...)
 	default:
@@ -198,7 +264,11 @@ func (b *sqlBuilder) QueryRowContext(ctx context.Context, query interface{}, arg
 	case *exql.Statement:
 		return b.sess.StatementQueryRow(ctx, q, args...)
 	case string:
-		return b.sess.StatementQueryRow(ctx, exql.RawSQL(q), args...)
+		raw, args, err := bindNamed(sessionMapper(b.sess), q, args)
+		if err != nil {
+			return nil, err
+		}
+		return b.sess.StatementQueryRow(ctx, exql.RawSQL(raw), args...)
 	case db.RawValue:
 		return b.QueryRowContext(ctx, q.Raw(), q.Arguments()...)
 	default:
@@ -241,7 +311,41 @@ func (b *sqlBuilder) Update(table string) Updater {
 	return qu.setTable(table)
 }
 
+func (b *sqlBuilder) CreateTable(table string) TableCreator {
+	tc := &tableCreator{
+		builder: b,
+	}
+	return tc.setTable(table)
+}
+
+func (b *sqlBuilder) AlterTable(table string) TableAlterer {
+	ta := &tableAlterer{
+		builder: b,
+	}
+	return ta.setTable(table)
+}
+
+func (b *sqlBuilder) CreateIndex(name string) IndexCreator {
+	ic := &indexCreator{
+		builder: b,
+	}
+	return ic.setName(name)
+}
+
+func (b *sqlBuilder) Call(procedure string, args ...interface{}) Caller {
+	return &caller{
+		builder:   b,
+		procedure: procedure,
+		args:      args,
+	}
+}
+
 // Map receives a pointer to map or struct and maps it to columns and values.
+// Struct fields tagged with the "asjson" option (e.g.: `db:"payload,asjson"`)
+// are encoded into JSON, the "json" option is accepted but has no effect. Zero fields tagged with "omitempty" are left out so the
+// default values of their columns apply, fields tagged with "readonly" (e.g.:
+// `db:"created_at,readonly"`) are always left out, they're only scanned. See
+// MapOptions.NullZero for the zero values of the other fields.
 func Map(item interface{}, options *MapOptions) ([]string, []interface{}, error) {
 	var fv fieldValue
 	if options == nil {
@@ -265,7 +369,7 @@ func Map(item interface{}, options *MapOptions) ([]string, []interface{}, error)
 
 	switch itemT.Kind() {
 	case reflect.Struct:
-		fieldMap := mapper.TypeMap(itemT).Names
+		fieldMap := options.mapper().TypeMap(itemT).Names
 		nfields := len(fieldMap)
 
 		fv.values = make([]interface{}, 0, nfields)
@@ -278,11 +382,42 @@ func Map(item interface{}, options *MapOptions) ([]string, []interface{}, error)
 				return nil, nil, errDeprecatedJSONBTag
 			}
 
+			if _, readOnly := fi.Options["readonly"]; readOnly {
+				continue
+			}
+
 			// Field options
 			_, tagOmitEmpty := fi.Options["omitempty"]
 
 			fld := reflectx.FieldByIndexesReadOnly(itemV, fi.Index)
-			if fld.Kind() == reflect.Ptr && fld.IsNil() {
+
+			if _, tagUUID := fi.Options["uuid"]; tagUUID && isZeroUUID(fld, fi) {
+				var id interface{}
+				if options.NewUUID != nil {
+					var err error
+					if id, err = options.NewUUID(fi.Field.Type); err != nil {
+						return nil, nil, err
+					}
+				}
+				if id == nil {
+					// Left to the default value of the column.
+					if !options.IncludeZeroed {
+						continue
+					}
+					id = sqlDefault
+				}
+				fv.fields = append(fv.fields, fi.Name)
+				fv.values = append(fv.values, id)
+				continue
+			}
+
+			if now, ok := autoNow(fld, fi, options); ok {
+				fv.fields = append(fv.fields, fi.Name)
+				fv.values = append(fv.values, now)
+				continue
+			}
+			// Fields within nil embedded pointers are like nil pointers.
+			if !fld.IsValid() || fld.Kind() == reflect.Ptr && fld.IsNil() {
 				if tagOmitEmpty && !options.IncludeNil {
 					continue
 				}
@@ -297,30 +432,21 @@ func Map(item interface{}, options *MapOptions) ([]string, []interface{}, error)
 
 			value := fld.Interface()
 
-			isZero := false
-			if t, ok := fld.Interface().(hasIsZero); ok {
-				if t.IsZero() {
-					isZero = true
-				}
-			} else if fld.Kind() == reflect.Array || fld.Kind() == reflect.Slice {
-				if fld.Len() == 0 {
-					isZero = true
-				}
-			} else if reflect.DeepEqual(fi.Zero.Interface(), value) {
-				isZero = true
-			}
+			isZero := isZeroField(fld, fi.Zero)
 
 			if isZero && tagOmitEmpty && !options.IncludeZeroed {
 				continue
 			}
 
 			fv.fields = append(fv.fields, fi.Name)
-			v, err := marshal(value)
+			v, err := marshalField(value, fi)
 			if err != nil {
 				return nil, nil, err
 			}
 			if isZero && tagOmitEmpty {
 				v = sqlDefault
+			} else if isZero && nullZero(fi, options) {
+				v = nil
 			}
 			fv.values = append(fv.values, v)
 		}
@@ -370,8 +496,15 @@ func columnFragments(columns []interface{}) ([]exql.Fragment, []interface{}, err
 
 	for i := 0; i < l; i++ {
 		switch v := columns[i].(type) {
+		case *windowFunction:
+			wq, err := v.build()
+			if err != nil {
+				return nil, nil, err
+			}
+			f[i] = wq.fragment()
+			args = append(args, wq.arguments()...)
 		case compilable:
-			c, err := v.Compile()
+			c, err := v.compile()
 			if err != nil {
 				return nil, nil, err
 			}
@@ -444,7 +577,7 @@ func (iter *iterator) Scan(dst ...interface{}) error {
 	if err := iter.Err(); err != nil {
 		return err
 	}
-	return iter.cursor.Scan(dst...)
+	return iter.cursor.Scan(decodeValues(sessionCodecs(iter.sess), dst)...)
 }
 
 func (iter *iterator) setErr(err error) error {
@@ -474,6 +607,37 @@ func (iter *iterator) All(dst interface{}) error {
 	return nil
 }
 
+func (iter *iterator) ForEach(fn func(scan func(dest ...interface{}) error) error) error {
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	if iter.cursor == nil {
+		return nil
+	}
+
+	s := &rowScanner{iter: iter}
+	for iter.cursor.Next() {
+		if err := fn(s.scan); err != nil {
+			return err
+		}
+	}
+	return iter.setErr(iter.cursor.Err())
+}
+
+func (iter *iterator) Columns() []ColumnInfo {
+	if iter.Err() != nil || iter.cursor == nil {
+		return nil
+	}
+	columns, err := columnInfo(iter.cursor)
+	if err != nil {
+		iter.setErr(err)
+		return nil
+	}
+	return columns
+}
+
 func (iter *iterator) Err() (err error) {
 	return iter.err
 }
@@ -499,20 +663,24 @@ func (iter *iterator) next(dst ...interface{}) error {
 		return iter.setErr(db.ErrNoMoreRows)
 	}
 
+	// The cursor is kept open once its rows are exhausted, so the next result
+	// set can be read, database/sql closes it when there are no more result
+	// sets.
 	switch len(dst) {
 	case 0:
 		if ok := iter.cursor.Next(); !ok {
-			defer iter.Close()
-			err := iter.cursor.Err()
-			if err == nil {
-				err = db.ErrNoMoreRows
+			if err := iter.cursor.Err(); err != nil {
+				defer iter.Close()
+				return err
 			}
-			return err
+			return db.ErrNoMoreRows
 		}
 		return nil
 	case 1:
 		if err := fetchRow(iter, dst[0]); err != nil {
-			defer iter.Close()
+			if err != db.ErrNoMoreRows {
+				defer iter.Close()
+			}
 			return err
 		}
 		return nil
@@ -521,6 +689,19 @@ func (iter *iterator) next(dst ...interface{}) error {
 	return errors.New("Next does not currently supports more than one parameters")
 }
 
+func (iter *iterator) NextResultSet() bool {
+	if iter.Err() != nil || iter.cursor == nil {
+		return false
+	}
+	if !iter.cursor.NextResultSet() {
+		if err := iter.cursor.Err(); err != nil {
+			iter.setErr(err)
+		}
+		return false
+	}
+	return true
+}
+
 func (iter *iterator) Close() (err error) {
 	if iter.cursor != nil {
 		err = iter.cursor.Close()
@@ -529,6 +710,92 @@ func (iter *iterator) Close() (err error) {
 	return err
 }
 
+// Example receives a struct or a pointer to struct and maps its fields into a
+// db.Cond that matches the rows that have the same values:
+//
+//	cond, err := sqlbuilder.Example(&User{Status: "active", Plan: "pro"}, nil)
+//	...
+//	res := col.Find(cond)
+//
+// Fields that hold zero values are left out of the condition unless
+// options.IncludeZeroed is true, nil pointers are left out unless
+// options.IncludeNil is true, in which case they're matched with IS NULL.
+func Example(item interface{}, options *MapOptions) (db.Cond, error) {
+	if options == nil {
+		options = &defaultMapOptions
+	}
+
+	itemV := reflect.ValueOf(item)
+	if itemV.Kind() == reflect.Ptr {
+		itemV = itemV.Elem()
+	}
+	if itemV.Kind() != reflect.Struct {
+		return nil, ErrExpectingMapOrStruct
+	}
+
+	cond := db.Cond{}
+	for _, fi := range options.mapper().TypeMap(itemV.Type()).Names {
+		if _, hasJSONBTag := fi.Options["jsonb"]; hasJSONBTag {
+			return nil, errDeprecatedJSONBTag
+		}
+
+		fld := reflectx.FieldByIndexesReadOnly(itemV, fi.Index)
+		if !fld.IsValid() || fld.Kind() == reflect.Ptr && fld.IsNil() {
+			if options.IncludeNil {
+				cond[fi.Name] = db.IsNull()
+			}
+			continue
+		}
+
+		if isZeroField(fld, fi.Zero) && !options.IncludeZeroed {
+			continue
+		}
+
+		v, err := marshalField(fld.Interface(), fi)
+		if err != nil {
+			return nil, err
+		}
+		cond[fi.Name] = db.Eq(v)
+	}
+
+	return cond, nil
+}
+
+// autoNow returns the time the given field must be set to if it's tagged with
+// either auto_now or auto_now_add.
+func autoNow(fld reflect.Value, fi *reflectx.FieldInfo, options *MapOptions) (time.Time, bool) {
+	if _, ok := fi.Options["auto_now"]; ok && !options.AutoNow.IsZero() {
+		return options.AutoNow, true
+	}
+	if _, ok := fi.Options["auto_now_add"]; ok && !options.AutoNowAdd.IsZero() {
+		if !fld.IsValid() || fld.Kind() == reflect.Ptr && fld.IsNil() || isZeroField(fld, fi.Zero) {
+			return options.AutoNowAdd, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nullZero returns true if the zero value of the given field is NULL.
+func nullZero(fi *reflectx.FieldInfo, options *MapOptions) bool {
+	if _, ok := fi.Options["nullzero"]; ok {
+		return true
+	}
+	if _, ok := fi.Options["keepzero"]; ok {
+		return false
+	}
+	return options.NullZero
+}
+
+func isZeroField(fld reflect.Value, zero reflect.Value) bool {
+	if t, ok := fld.Interface().(hasIsZero); ok {
+		return t.IsZero()
+	}
+	if fld.Kind() == reflect.Array || fld.Kind() == reflect.Slice {
+		return fld.Len() == 0
+	}
+	return reflect.DeepEqual(zero.Interface(), fld.Interface())
+}
+
 func marshal(v interface{}) (interface{}, error) {
 	if m, isMarshaler := v.(db.Marshaler); isMarshaler {
 		var err error
@@ -539,6 +806,19 @@ func marshal(v interface{}) (interface{}, error) {
 	return v, nil
 }
 
+// marshalField is like marshal but it encodes the value into JSON if the field
+// is tagged with the "asjson" option.
+func marshalField(v interface{}, fi *reflectx.FieldInfo) (interface{}, error) {
+	if _, ok := fi.Options["asjson"]; ok {
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(buf), nil
+	}
+	return marshal(v)
+}
+
 func (fv *fieldValue) Len() int {
 	return len(fv.fields)
 }
@@ -1,9 +1,13 @@
 package sqlbuilder
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"upper.io/db.v3"
@@ -868,6 +872,749 @@ func TestSelect(t *testing.T) {
 	}
 }
 
+func TestSelectWith(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	{
+		q := b.Select().
+			With("recent", b.Select("id", "author_id").From("posts").Where("id > ?", 10)).
+			From("recent").
+			Where("author_id = ?", 3)
+
+		assert.Equal(
+			`WITH "recent" AS (SELECT "id", "author_id" FROM "posts" WHERE (id > $1)) SELECT * FROM "recent" WHERE (author_id = $2)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{10, 3}, q.Arguments())
+	}
+
+	{
+		q := b.Select("a.id", "b.total").
+			With("a", b.Select("id").From("authors")).
+			With("b(author_id, total)", db.Raw("SELECT author_id, count(1) FROM posts WHERE active = ? GROUP BY author_id", true)).
+			From("a").
+			Join("b").On("b.author_id = a.id")
+
+		assert.Equal(
+			`WITH "a" AS (SELECT "id" FROM "authors"), "b" ("author_id", "total") AS (SELECT author_id, count(1) FROM posts WHERE active = $1 GROUP BY author_id) SELECT "a"."id", "b"."total" FROM "a" JOIN "b" ON (b.author_id = a.id)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{true}, q.Arguments())
+	}
+
+	assert.Equal(
+		`WITH RECURSIVE "t" ("n") AS (SELECT 1 UNION ALL SELECT n+1 FROM t WHERE n < $1) SELECT "n" FROM "t"`,
+		b.Select("n").WithRecursive("t(n)", db.Raw("SELECT 1 UNION ALL SELECT n+1 FROM t WHERE n < ?", 10)).From("t").String(),
+	)
+}
+
+func TestSelectCompound(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	{
+		q := b.Select("name").From("artist").Where("id > ?", 1).
+			Union(b.Select("name").From("band").Where("id < ?", 2)).
+			OrderBy("name").
+			Limit(10)
+
+		assert.Equal(
+			`(SELECT "name" FROM "artist" WHERE (id > $1)) UNION (SELECT "name" FROM "band" WHERE (id < $2)) ORDER BY "name" ASC LIMIT 10`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{1, 2}, q.Arguments())
+	}
+
+	{
+		q := b.Select("name").From("artist").OrderBy("id").Limit(3).
+			UnionAll(b.Select("name").From("band")).
+			Intersect(b.Select("name").From("member").Where("active = ?", true)).
+			Except(b.Select("name").From("banned"))
+
+		assert.Equal(
+			`(SELECT "name" FROM "artist" ORDER BY "id" ASC LIMIT 3) UNION ALL (SELECT "name" FROM "band") INTERSECT (SELECT "name" FROM "member" WHERE (active = $1)) EXCEPT (SELECT "name" FROM "banned")`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{true}, q.Arguments())
+	}
+
+	{
+		q := b.Select("id").
+			With("recent", b.Select("id").From("posts").Where("id > ?", 5)).
+			From("recent").
+			Union(b.Select("id").From("drafts").Where("author_id = ?", 7))
+
+		assert.Equal(
+			`WITH "recent" AS (SELECT "id" FROM "posts" WHERE (id > $1)) (SELECT "id" FROM "recent") UNION (SELECT "id" FROM "drafts" WHERE (author_id = $2))`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{5, 7}, q.Arguments())
+	}
+
+	{
+		// Clauses of a single query can't follow a compound statement, it
+		// has to be selected from.
+		q := b.Select("name").From("artist").Union(b.Select("name").From("band"))
+
+		_, _, err := q.Where("name LIKE ?", "A%").Compile()
+		assert.Equal(errCompoundClause, err)
+		_, _, err = q.GroupBy("name").Compile()
+		assert.Equal(errCompoundClause, err)
+
+		assert.Equal(
+			`SELECT * FROM ((SELECT "name" FROM "artist") UNION (SELECT "name" FROM "band")) AS "u" WHERE (name LIKE $1)`,
+			b.SelectFrom(q).As("u").Where("name LIKE ?", "A%").String(),
+		)
+	}
+}
+
+func TestSelectWindow(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	assert.Equal(
+		`SELECT "name", ROW_NUMBER() OVER () AS "n" FROM "employee"`,
+		b.Select("name", Over(db.Func("ROW_NUMBER")).As("n")).From("employee").String(),
+	)
+
+	assert.Equal(
+		`SELECT "name", RANK() OVER (PARTITION BY "department" ORDER BY "salary" DESC) AS "rank" FROM "employee"`,
+		b.Select(
+			"name",
+			Over(db.Func("RANK")).PartitionBy("department").OrderBy("-salary").As("rank"),
+		).From("employee").String(),
+	)
+
+	{
+		q := b.Select(
+			"day",
+			Over(db.Raw("LAG(price, ?)", 1)).OrderBy("day").As("prev"),
+			Over(db.Raw("AVG(price)")).OrderBy("day").Rows(Preceding(6), CurrentRow).As("avg"),
+			Over(db.Raw("SUM(price)")).PartitionBy("product_id").Range(UnboundedPreceding, UnboundedFollowing),
+		).From("price").Where("product_id = ?", 4)
+
+		assert.Equal(
+			`SELECT "day", LAG(price, $1) OVER (ORDER BY "day" ASC) AS "prev", AVG(price) OVER (ORDER BY "day" ASC ROWS BETWEEN 6 PRECEDING AND CURRENT ROW) AS "avg", SUM(price) OVER (PARTITION BY "product_id" RANGE BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING) FROM "price" WHERE (product_id = $2)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{1, 4}, q.Arguments())
+	}
+
+	{
+		_, err := b.Select(Over("id")).From("employee").(*selector).build(nil)
+		assert.Error(err)
+	}
+}
+
+func TestSelectLock(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	assert.Equal(
+		`SELECT * FROM "jobs" WHERE ("status" = $1) LIMIT 1 FOR UPDATE`,
+		b.SelectFrom("jobs").Where(db.Cond{"status": "pending"}).Limit(1).ForUpdate().String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "jobs" LIMIT 1 FOR UPDATE SKIP LOCKED`,
+		b.SelectFrom("jobs").Limit(1).SkipLocked().String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "jobs" FOR SHARE NOWAIT`,
+		b.SelectFrom("jobs").ForShare().NoWait().String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "jobs" FOR SHARE SKIP LOCKED`,
+		b.SelectFrom("jobs").NoWait().SkipLocked().ForShare().String(),
+	)
+}
+
+func TestSelectAsOf(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	_, _, err := b.SelectFrom("jobs").AsOf(-10 * time.Second).Compile()
+	assert.Equal(db.ErrUnsupported, err)
+
+	_, err = b.SelectFrom("jobs").AsOf(db.Raw("now() - ?", "1h")).(*selector).build(nil)
+	assert.Error(err)
+}
+
+func TestSelectFinalLimitBy(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	_, _, err := b.SelectFrom("visits").Final().Compile()
+	assert.Equal(db.ErrUnsupported, err)
+
+	_, _, err = b.SelectFrom("visits").LimitBy(3, "user_id").Compile()
+	assert.Equal(db.ErrUnsupported, err)
+
+	_, err = b.SelectFrom("visits").LimitBy(3).(*selector).build(nil)
+	assert.Equal(errMissingLimitByColumns, err)
+}
+
+func TestSelectJSONPath(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	sel := b.SelectFrom("users").Where(db.Cond{
+		db.JSONPath("data.address.city"): "Paris",
+		db.JSONPath("data.age"):          db.Gte(18),
+	})
+	assert.Equal(
+		`SELECT * FROM "users" WHERE ("data"->'address'->>'city' = $1 AND "data"->>'age' >= $2)`,
+		sel.String(),
+	)
+	assert.Equal([]interface{}{"Paris", 18}, sel.Arguments())
+}
+
+func TestSelectFullText(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	search := db.FullText("body", "cats and dogs")
+
+	sel := b.SelectFrom("posts").Where(search, db.Cond{"draft": false}).OrderBy(search.Rank())
+	assert.Equal(
+		`SELECT * FROM "posts" WHERE (to_tsvector("body") @@ plainto_tsquery($1) AND "draft" = $2) ORDER BY ts_rank(to_tsvector("body"), plainto_tsquery($3)) DESC`,
+		sel.String(),
+	)
+	assert.Equal([]interface{}{"cats and dogs", false, "cats and dogs"}, sel.Arguments())
+}
+
+func TestSelectCustomOperator(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	sel := b.SelectFrom("places").Where(db.Cond{
+		"name":     db.Op("ILIKE", "%cafe%"),
+		"location": db.Op("ST_DWithin(:column, ?, ?)", []interface{}{"POINT(1 2)", 50}),
+	})
+	assert.Equal(
+		`SELECT * FROM "places" WHERE (ST_DWithin("location", $1, $2) AND "name" ILIKE $3)`,
+		sel.String(),
+	)
+	assert.Equal([]interface{}{"POINT(1 2)", 50, "%cafe%"}, sel.Arguments())
+}
+
+func TestSelectInlineColumns(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	type user struct {
+		ID   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+
+	type account struct {
+		ID      int64   `db:"id"`
+		Balance float64 `db:"balance"`
+	}
+
+	type row struct {
+		User    user     `db:",inline=u"`
+		Account *account `db:",inline=a"`
+		Rank    int      `db:"rank"`
+	}
+
+	sel := b.Select("u.*", "a.*", "rank").
+		From("users AS u").
+		Join("accounts AS a").On("a.user_id = u.id").(*selector)
+
+	assert.Equal(
+		`SELECT "u"."id" AS "u.id", "u"."name" AS "u.name", "a"."id" AS "a.id", "a"."balance" AS "a.balance", "rank" FROM "users" AS "u" JOIN "accounts" AS "a" ON (a.user_id = u.id)`,
+		sel.inlineColumns(&[]row{}).String(),
+	)
+
+	assert.Equal(
+		`SELECT "u"."id" AS "u.id", "u"."name" AS "u.name", "a".* FROM "users" AS "u" JOIN "accounts" AS "a" ON (a.user_id = u.id)`,
+		b.Select("u.*", "a.*").
+			From("users AS u").
+			Join("accounts AS a").On("a.user_id = u.id").(*selector).
+			inlineColumns(&struct {
+				User user `db:",inline=u"`
+			}{}).String(),
+	)
+
+	assert.Equal(sel.String(), sel.inlineColumns(&[]user{}).String())
+}
+
+func TestSelectDistinctOn(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	assert.Equal(
+		`SELECT DISTINCT ON ("user_id") "user_id", "created_at" FROM "visits" ORDER BY "created_at" DESC`,
+		b.Select("user_id", "created_at").DistinctOn("user_id").From("visits").OrderBy("-created_at").String(),
+	)
+
+	{
+		sel := b.Select("user_id").
+			DistinctOn("user_id", db.Raw("date_trunc(?, created_at)", "day")).
+			From("visits").
+			Where("user_id", 5)
+		assert.Equal(
+			`SELECT DISTINCT ON ("user_id", date_trunc($1, created_at)) "user_id" FROM "visits" WHERE ("user_id" = $2)`,
+			sel.String(),
+		)
+		assert.Equal([]interface{}{"day", 5}, sel.Arguments())
+	}
+
+	_, err := b.SelectFrom("visits").DistinctOn().(*selector).build(nil)
+	assert.Equal(errMissingDistinctOnColumns, err)
+}
+
+func TestInsertFromSelect(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	{
+		sel := b.Select("id", "title").From("posts").Where("created_at < ?", "2018-01-01")
+		ins := b.InsertInto("archived_posts").Columns("id", "title").ValuesFromSelect(sel).Returning("id")
+		assert.Equal(
+			`INSERT INTO "archived_posts" ("id", "title") SELECT "id", "title" FROM "posts" WHERE (created_at < $1) RETURNING "id"`,
+			ins.String(),
+		)
+		assert.Equal([]interface{}{"2018-01-01"}, ins.Arguments())
+	}
+
+	{
+		sel := b.Select("user_id", db.Raw("count(1)")).From("visits").Where("day", "monday").GroupBy("user_id")
+		ins := b.InsertInto("stats").Columns("user_id", "visits").ValuesFromSelect(sel).
+			OnConflict("user_id").DoUpdate("visits = stats.visits + ?", 1)
+		assert.Equal(
+			`INSERT INTO "stats" ("user_id", "visits") SELECT "user_id", count(1) FROM "visits" WHERE ("day" = $1) GROUP BY "user_id" ON CONFLICT ("user_id") DO UPDATE SET "visits" = stats.visits + $2`,
+			ins.String(),
+		)
+		assert.Equal([]interface{}{"monday", 1}, ins.Arguments())
+	}
+
+	_, err := b.InsertInto("stats").Values(1, 2).ValuesFromSelect(b.SelectFrom("visits")).(*inserter).build(nil)
+	assert.Equal(errValuesAndSelect, err)
+}
+
+func TestUpdateDeleteJoins(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	{
+		upd := b.Update("posts").
+			Set("author_name = users.name").
+			From("users").
+			LeftJoin("teams").On("teams.id = users.team_id AND teams.active = ?", true).
+			Where("posts.user_id = users.id AND posts.id > ?", 10)
+		assert.Equal(
+			`UPDATE "posts" SET "author_name" = users.name FROM "users" LEFT JOIN "teams" ON (teams.id = users.team_id AND teams.active = $1) WHERE (posts.user_id = users.id AND posts.id > $2)`,
+			upd.String(),
+		)
+		assert.Equal([]interface{}{true, 10}, upd.Arguments())
+	}
+
+	{
+		del := b.DeleteFrom("posts").
+			Using("users").
+			Where("posts.user_id = users.id AND users.banned = ?", true).
+			Returning("id")
+		assert.Equal(
+			`DELETE FROM "posts" USING "users" WHERE (posts.user_id = users.id AND users.banned = $1) RETURNING "id"`,
+			del.String(),
+		)
+		assert.Equal([]interface{}{true}, del.Arguments())
+	}
+
+	_, err := b.Update("posts").Set("title", "x").On("a = b").(*updater).build(nil)
+	assert.Error(err)
+}
+
+func TestUpdateIncrement(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	{
+		upd := b.Update("posts").Set(db.Increment("views", 1)).Where("id", 5)
+		assert.Equal(
+			`UPDATE "posts" SET "views" = "views" + $1 WHERE ("id" = $2)`,
+			upd.String(),
+		)
+		assert.Equal([]interface{}{1, 5}, upd.Arguments())
+	}
+
+	{
+		upd := b.Update("posts").
+			Set(db.Increment("views", 1), db.Decrement("stock", 2)).
+			Set("title", "foo")
+		assert.Equal(
+			`UPDATE "posts" SET "views" = "views" + $1, "stock" = "stock" - $2, "title" = $3`,
+			upd.String(),
+		)
+		assert.Equal([]interface{}{1, 2, "foo"}, upd.Arguments())
+	}
+
+	{
+		upd := b.Update("posts").
+			SetExpr("counter", db.Raw("counter + ?", 3)).
+			SetExpr("score", db.Raw(":column * ?", 2)).
+			SetExpr("updated_at", db.Func("NOW"))
+		assert.Equal(
+			`UPDATE "posts" SET "counter" = counter + $1, "score" = "score" * $2, "updated_at" = NOW()`,
+			upd.String(),
+		)
+		assert.Equal([]interface{}{3, 2}, upd.Arguments())
+	}
+}
+
+func TestExample(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	type user struct {
+		ID     int64   `db:"id,omitempty"`
+		Status string  `db:"status"`
+		Plan   string  `db:"plan"`
+		Score  int     `db:"score"`
+		Email  *string `db:"email"`
+	}
+
+	cond, err := Example(&user{Status: "active", Plan: "pro"}, nil)
+	assert.NoError(err)
+	assert.Equal(db.Cond{"status": db.Eq("active"), "plan": db.Eq("pro")}, cond)
+
+	sel := b.SelectFrom("users").Where(cond)
+	assert.Equal(
+		`SELECT * FROM "users" WHERE ("plan" = $1 AND "status" = $2)`,
+		sel.String(),
+	)
+	assert.Equal([]interface{}{"pro", "active"}, sel.Arguments())
+
+	cond, err = Example(user{Status: "active"}, &MapOptions{IncludeZeroed: true, IncludeNil: true})
+	assert.NoError(err)
+	assert.Equal(db.Cond{
+		"id":     db.Eq(int64(0)),
+		"status": db.Eq("active"),
+		"plan":   db.Eq(""),
+		"score":  db.Eq(0),
+		"email":  db.IsNull(),
+	}, cond)
+
+	_, err = Example(map[string]interface{}{"status": "active"}, nil)
+	assert.Equal(ErrExpectingMapOrStruct, err)
+}
+
+func TestAutoNow(t *testing.T) {
+	b, sess := newFakeBuilder()
+	assert := assert.New(t)
+
+	now := time.Date(2018, 3, 12, 10, 4, 5, 0, time.UTC)
+	sess.clock = func() time.Time {
+		return now
+	}
+
+	type post struct {
+		ID        int64      `db:"id,omitempty"`
+		Title     string     `db:"title"`
+		CreatedAt time.Time  `db:"created_at,auto_now_add"`
+		UpdatedAt *time.Time `db:"updated_at,auto_now"`
+	}
+
+	{
+		q := b.InsertInto("posts").Values(post{Title: "Hello"})
+		assert.Equal(
+			`INSERT INTO "posts" ("created_at", "title", "updated_at") VALUES ($1, $2, $3)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{now, "Hello", now}, q.Arguments())
+	}
+
+	{
+		createdAt := now.Add(-time.Hour)
+		q := b.InsertInto("posts").Values(post{Title: "Hello", CreatedAt: createdAt})
+		assert.Equal([]interface{}{createdAt, "Hello", now}, q.Arguments())
+	}
+
+	{
+		createdAt := now.Add(-time.Hour)
+		q := b.Update("posts").Set(post{Title: "Hello", CreatedAt: createdAt}).Where("id = ?", 1)
+		assert.Equal(
+			`UPDATE "posts" SET "created_at" = $1, "title" = $2, "updated_at" = $3 WHERE (id = $4)`,
+			q.String(),
+		)
+		assert.Equal([]interface{}{createdAt, "Hello", now, 1}, q.Arguments())
+	}
+}
+
+func TestMapTagOptions(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	type account struct {
+		ID        int64     `db:"id,omitempty"`
+		Email     string    `db:"email"`
+		Plan      string    `db:"plan,omitempty"`
+		Password  string    `db:"password,writeonly"`
+		CreatedAt time.Time `db:"created_at,readonly"`
+	}
+
+	item := account{Email: "joe@example.com", Password: "secret", CreatedAt: time.Now()}
+
+	q := b.InsertInto("accounts").Values(item)
+	assert.Equal(
+		`INSERT INTO "accounts" ("email", "password") VALUES ($1, $2)`,
+		q.String(),
+	)
+	assert.Equal([]interface{}{"joe@example.com", "secret"}, q.Arguments())
+
+	columns, _, err := Map(item, &MapOptions{IncludeZeroed: true, IncludeNil: true})
+	assert.NoError(err)
+	assert.Equal([]string{"email", "id", "password", "plan"}, columns)
+
+	u := b.Update("accounts").Set(account{Email: "joe@example.com", Plan: "pro"}).Where("id = ?", 1)
+	assert.Equal(
+		`UPDATE "accounts" SET "email" = $1, "password" = $2, "plan" = $3 WHERE (id = $4)`,
+		u.String(),
+	)
+}
+
+func TestMapNullZero(t *testing.T) {
+	assert := assert.New(t)
+
+	type account struct {
+		ID        int64     `db:"id,omitempty"`
+		Email     string    `db:"email,keepzero"`
+		Plan      string    `db:"plan"`
+		Score     int       `db:"score,nullzero"`
+		DeletedAt time.Time `db:"deleted_at"`
+	}
+
+	_, values, err := Map(account{}, nil)
+	assert.NoError(err)
+	assert.Equal([]interface{}{time.Time{}, "", "", nil}, values)
+
+	columns, values, err := Map(account{Plan: "pro"}, &MapOptions{NullZero: true})
+	assert.NoError(err)
+	assert.Equal([]string{"deleted_at", "email", "plan", "score"}, columns)
+	assert.Equal([]interface{}{nil, "", "pro", nil}, values)
+
+	b, sess := newFakeBuilder()
+	sess.zeroValuePolicy = db.ZeroAsNull
+	q := b.InsertInto("accounts").Values(account{Email: "joe@example.com"})
+	assert.Equal([]interface{}{nil, "joe@example.com", nil, nil}, q.Arguments())
+
+	u := b.Update("accounts").Set(account{Plan: "pro"}).Where("id = ?", 1)
+	assert.Equal([]interface{}{nil, "", "pro", nil, 1}, u.Arguments())
+}
+
+func TestMapUUID(t *testing.T) {
+	assert := assert.New(t)
+
+	type account struct {
+		ID   db.UUID `db:"id,pk,uuid"`
+		Name string  `db:"name"`
+	}
+
+	type token struct {
+		ID  string  `db:"id,uuid"`
+		Ref *[]byte `db:"ref,uuid"`
+	}
+
+	options := &MapOptions{NewUUID: NewUUID}
+
+	columns, values, err := Map(account{Name: "Joe"}, options)
+	assert.NoError(err)
+	assert.Equal([]string{"id", "name"}, columns)
+	assert.False(values[0].(db.UUID).IsZero())
+
+	id := db.UUID{1}
+	_, values, err = Map(account{ID: id, Name: "Joe"}, options)
+	assert.NoError(err)
+	assert.Equal([]interface{}{id, "Joe"}, values)
+
+	_, values, err = Map(token{}, options)
+	assert.NoError(err)
+	_, err = db.ParseUUID(values[0].(string))
+	assert.NoError(err)
+	assert.Equal(16, len(*values[1].(*[]byte)))
+
+	// Keys are left to the default value of the column.
+	columns, _, err = Map(account{Name: "Joe"}, nil)
+	assert.NoError(err)
+	assert.Equal([]string{"name"}, columns)
+
+	_, err = NewUUID(reflect.TypeOf(0))
+	assert.Error(err)
+
+	b, _ := newFakeBuilder()
+	q := b.InsertInto("accounts").Values(account{Name: "Joe"})
+	assert.Equal(2, len(q.Arguments()))
+
+	u := b.Update("accounts").Set(account{Name: "Joe"}).Where("name = ?", "Joe")
+	assert.Equal([]interface{}{"Joe", "Joe"}, u.Arguments())
+}
+
+func TestMapJSON(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	type settings struct {
+		Theme string   `json:"theme"`
+		Tags  []string `json:"tags"`
+	}
+
+	type account struct {
+		ID       int64             `db:"id,omitempty"`
+		Settings settings          `db:"settings,asjson"`
+		Labels   map[string]string `db:"labels,asjson,omitempty"`
+		Extra    *settings         `db:"extra,asjson"`
+		Flag     bool              `db:"flag,json"`
+	}
+
+	q := b.InsertInto("accounts").Values(account{
+		Settings: settings{Theme: "dark", Tags: []string{"a", "b"}},
+	})
+	assert.Equal(
+		`INSERT INTO "accounts" ("extra", "flag", "settings") VALUES ($1, $2, $3)`,
+		q.String(),
+	)
+	// The "json" option leaves the value as it is.
+	assert.Equal([]interface{}{nil, false, `{"theme":"dark","tags":["a","b"]}`}, q.Arguments())
+
+	cond, err := Example(account{Labels: map[string]string{"k": "v"}}, nil)
+	assert.NoError(err)
+	assert.Equal(db.Cond{"labels": db.Eq(`{"k":"v"}`)}, cond)
+
+	var dst account
+	assert.NoError(jsonScanner{&dst.Settings}.Scan([]byte(`{"theme":"light","tags":["c"]}`)))
+	assert.Equal(settings{Theme: "light", Tags: []string{"c"}}, dst.Settings)
+
+	assert.NoError(jsonScanner{&dst.Labels}.Scan(`{"x":"y"}`))
+	assert.Equal(map[string]string{"x": "y"}, dst.Labels)
+
+	dst.Extra = &settings{Theme: "dark"}
+	assert.NoError(jsonScanner{&dst.Extra}.Scan(nil))
+	assert.Nil(dst.Extra)
+
+	assert.Error(jsonScanner{&dst.Settings}.Scan(int64(1)))
+}
+
+func TestMapEmbedded(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	type base struct {
+		ID        int64     `db:"id,omitempty"`
+		CreatedAt time.Time `db:"created_at,auto_now_add"`
+	}
+	type address struct {
+		Street string `db:"street"`
+		City   string `db:"city"`
+	}
+	type user struct {
+		*base
+		Name    string   `db:"name"`
+		Address *address `db:"address,inline"`
+	}
+
+	{
+		columns, values, err := Map(user{Name: "Joe"}, nil)
+		assert.NoError(err)
+		assert.Equal([]string{"city", "created_at", "name", "street"}, columns)
+		assert.Equal([]interface{}{nil, nil, "Joe", nil}, values)
+	}
+
+	{
+		now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+		q := b.InsertInto("users").Values(user{Name: "Joe", Address: &address{City: "Lima"}})
+		_, values, err := Map(user{Name: "Joe", Address: &address{City: "Lima"}}, &MapOptions{AutoNowAdd: now})
+		assert.NoError(err)
+		assert.Equal([]interface{}{"Lima", now, "Joe", ""}, values)
+		assert.Equal(
+			`INSERT INTO "users" ("city", "created_at", "name", "street") VALUES ($1, $2, $3, $4)`,
+			q.String(),
+		)
+	}
+
+	{
+		cond, err := Example(user{base: &base{ID: 2}}, nil)
+		assert.NoError(err)
+		assert.Equal(db.Cond{"id": db.Eq(int64(2))}, cond)
+
+		cond, err = Example(user{Name: "Joe"}, &MapOptions{IncludeNil: true})
+		assert.NoError(err)
+		assert.Equal(db.Cond{
+			"id":         db.IsNull(),
+			"created_at": db.IsNull(),
+			"name":       db.Eq("Joe"),
+			"street":     db.IsNull(),
+			"city":       db.IsNull(),
+		}, cond)
+	}
+}
+
+func TestDDL(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	assert.Equal(
+		`CREATE TABLE "books" ("id" SERIAL PRIMARY KEY, "title" VARCHAR(80) NOT NULL DEFAULT 'Don''t', "price" NUMERIC(10, 2) DEFAULT 9.5, "available" BOOLEAN DEFAULT '1', "author_id" BIGINT NOT NULL, FOREIGN KEY ("author_id") REFERENCES "authors" ("id"))`,
+		b.CreateTable("books").Columns(
+			NewColumn("id", Integer).PrimaryKey().AutoIncrement(),
+			NewColumn("title", String(80)).NotNull().Default("Don't"),
+			NewColumn("price", Decimal(10, 2)).Default(9.5),
+			NewColumn("available", Boolean).Default(true),
+			NewColumn("author_id", BigInteger).NotNull().References("authors", "id"),
+		).String(),
+	)
+
+	assert.Equal(
+		`CREATE TABLE "book_tags" ("book_id" INTEGER, "tag" TEXT, "created_at" TIMESTAMP DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY ("book_id", "tag"), UNIQUE ("tag", "created_at"))`,
+		b.CreateTable("book_tags").
+			Columns(
+				NewColumn("book_id", Integer),
+				NewColumn("tag", Text),
+			).
+			Columns(NewColumn("created_at", Timestamp).Default(db.Raw("CURRENT_TIMESTAMP"))).
+			PrimaryKey("book_id", "tag").
+			Unique("tag", "created_at").
+			String(),
+	)
+
+	assert.Equal(
+		`CREATE TABLE "reviews" ("id" BIGSERIAL PRIMARY KEY, "book_id" INTEGER, "edition" INTEGER, FOREIGN KEY ("book_id", "edition") REFERENCES "editions" ("book_id", "number"))`,
+		b.CreateTable("reviews").Columns(
+			NewColumn("id", BigInteger).PrimaryKey().AutoIncrement(),
+			NewColumn("book_id", Integer),
+			NewColumn("edition", Integer),
+		).ForeignKey([]string{"book_id", "edition"}, "editions", "book_id", "number").String(),
+	)
+
+	assert.Equal(
+		`ALTER TABLE "books" ADD COLUMN "meta" JSONB, DROP COLUMN "price"`,
+		b.AlterTable("books").AddColumn(NewColumn("meta", JSON)).DropColumn("price").String(),
+	)
+
+	assert.Equal(
+		`CREATE UNIQUE INDEX "books_title_idx" ON "books" ("title")`,
+		b.CreateIndex("books_title_idx").On("books", "title").Unique().String(),
+	)
+
+	{
+		_, _, err := b.CreateTable("books").Compile()
+		assert.Equal(errMissingTableColumns, err)
+
+		_, _, err = b.CreateIndex("books_title_idx").Compile()
+		assert.Equal(errMissingIndexTable, err)
+
+		_, _, err = b.CreateTable("books").Columns(NewColumn("published", Date).Default(time.Now())).Compile()
+		assert.Error(err)
+
+		_, _, err = b.CreateTable("books").Columns(NewColumn("id", Integer)).ForeignKey([]string{"id"}, "authors").Compile()
+		assert.Equal(errMismatchedReference, err)
+	}
+}
+
 func TestInsert(t *testing.T) {
 	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
 	assert := assert.New(t)
@@ -1083,6 +1830,123 @@ func TestInsert(t *testing.T) {
 	)
 }
 
+func TestUpsert(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+	assert := assert.New(t)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2), ($3, $4) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		b.InsertInto("artist").
+			Columns("id", "name").
+			Values(1, "Chavela Vargas").
+			Values(2, "Alondra de la Parra").
+			OnConflict("id").
+			DoUpdate().
+			String(),
+	)
+
+	{
+		q := b.InsertInto("artist").
+			Values(map[string]interface{}{"id": 12, "name": "Chavela Vargas", "hits": 1}).
+			OnConflict("id").
+			DoUpdate("hits = hits + ?", 1).
+			DoUpdate(map[string]string{"name": "Chavela"})
+
+		assert.Equal(
+			`INSERT INTO "artist" ("hits", "id", "name") VALUES ($1, $2, $3) ON CONFLICT ("id") DO UPDATE SET "hits" = hits + $4, "name" = $5`,
+			q.String(),
+		)
+
+		assert.Equal(
+			[]interface{}{1, 12, "Chavela Vargas", 1, "Chavela"},
+			q.Arguments(),
+		)
+	}
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		b.InsertInto("artist").Columns("id", "name").Values(1, "Chavela Vargas").OnConflict().DoNothing().String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO NOTHING`,
+		b.InsertInto("artist").Columns("id", "name").Values(1, "Chavela Vargas").OnConflict("id").DoUpdate("name", "x").DoNothing().String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name" RETURNING "id"`,
+		b.InsertInto("artist").Columns("id", "name").Values(1, "Chavela Vargas").OnConflict("id").DoUpdate().Returning("id").String(),
+	)
+}
+
+func TestQueryContext(t *testing.T) {
+	type ctxKey struct{}
+
+	b, sess := newFakeBuilder()
+	ctx := context.WithValue(context.Background(), ctxKey{}, "per-call")
+
+	var items []map[string]interface{}
+
+	assert.Error(t, b.SelectFrom("artist").AllContext(ctx, &items))
+	assert.Equal(t, ctx, sess.ctx)
+
+	sess.ctx = nil
+	assert.Error(t, b.SelectFrom("artist").OneContext(ctx, &items))
+	assert.Equal(t, ctx, sess.ctx)
+
+	sess.ctx = nil
+	assert.Error(t, b.SelectFrom("artist").Paginate(10).AllContext(ctx, &items))
+	assert.Equal(t, ctx, sess.ctx)
+
+	sess.ctx = nil
+	_, err := b.SelectFrom("artist").Paginate(10).TotalEntriesContext(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, ctx, sess.ctx)
+
+	sess.ctx = nil
+	_, err = b.SelectFrom("artist").Paginate(10).TotalPagesContext(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, ctx, sess.ctx)
+
+	sess.ctx = nil
+	_, err = b.SelectFrom("artist").Paginate(10).TotalPages()
+	assert.Error(t, err)
+	assert.Equal(t, sess.Context(), sess.ctx)
+}
+
+func TestCompile(t *testing.T) {
+	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
+
+	compact := func(query string) string {
+		return strings.TrimSpace(reInvisibleChars.ReplaceAllString(query, " "))
+	}
+
+	query, args, err := b.SelectFrom("artist").Where("id IN ?", []int{1, 2}).And(db.Cond{"name": "Ozzie"}).Compile()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "artist" WHERE (id IN (?, ?) AND "name" = ?)`, compact(query))
+	assert.Equal(t, []interface{}{1, 2, "Ozzie"}, args)
+
+	query, args, err = b.Update("artist").Set("name", "Ozzie").Where("id", 1).Compile()
+	assert.NoError(t, err)
+	assert.Equal(t, `UPDATE "artist" SET "name" = ? WHERE ("id" = ?)`, compact(query))
+	assert.Equal(t, []interface{}{"Ozzie", 1}, args)
+
+	query, args, err = b.DeleteFrom("artist").Where("id", 1).Compile()
+	assert.NoError(t, err)
+	assert.Equal(t, `DELETE FROM "artist" WHERE ("id" = ?)`, compact(query))
+	assert.Equal(t, []interface{}{1}, args)
+
+	query, args, err = b.InsertInto("artist").Values(map[string]string{"name": "Ozzie"}).Compile()
+	assert.NoError(t, err)
+	assert.Equal(t, `INSERT INTO "artist" ("name") VALUES (?)`, compact(query))
+	assert.Equal(t, []interface{}{"Ozzie"}, args)
+
+	query, args, err = b.SelectFrom("artist").Paginate(10).Page(2).Compile()
+	assert.NoError(t, err)
+	assert.Equal(t, `SELECT * FROM "artist" LIMIT 10 OFFSET 10`, compact(query))
+	assert.Equal(t, 0, len(args))
+}
+
 func TestUpdate(t *testing.T) {
 	b := &sqlBuilder{t: newTemplateWithUtils(&testTemplate)}
 	assert := assert.New(t)
@@ -1099,6 +1963,11 @@ func TestUpdate(t *testing.T) {
 		}).String(),
 	)
 
+	assert.Equal(
+		`UPDATE "artist" SET "name" = $1 WHERE ("id" = $2) RETURNING *`,
+		b.Update("artist").Set("name", "Artist").Where(db.Cond{"id": 1}).Returning("*").String(),
+	)
+
 	{
 		idSlice := []int64{8, 7, 6}
 		q := b.Update("artist").Set(db.Cond{"some_column": 10}).Where(db.Cond{"id": 1}, db.Cond{"another_val": idSlice})
@@ -1256,6 +2125,11 @@ func TestDelete(t *testing.T) {
 		`DELETE FROM "artist" WHERE (id > 5)`,
 		bt.DeleteFrom("artist").Where("id > 5").String(),
 	)
+
+	assert.Equal(
+		`DELETE FROM "artist" WHERE (id > 5) RETURNING "id", "name"`,
+		bt.DeleteFrom("artist").Where("id > 5").Returning("id", "name").String(),
+	)
 }
 
 func TestPaginate(t *testing.T) {
@@ -1337,6 +2211,87 @@ func TestPaginate(t *testing.T) {
 			q.Arguments(),
 		)
 	}
+
+	// Cursor with conditions
+	{
+		q := b.Select().From("artist").Where("name LIKE ?", "A%").Paginate(10).Cursor("id").NextPage(3)
+		assert.Equal(
+			`SELECT * FROM "artist" WHERE (name LIKE $1 AND "id" > $2) ORDER BY "id" ASC LIMIT 10`,
+			q.String(),
+		)
+		assert.Equal(
+			[]interface{}{"A%", 3},
+			q.Arguments(),
+		)
+	}
+
+	// Encoded cursors
+	{
+		type artist struct {
+			ID   int64  `db:"id,omitempty"`
+			Name string `db:"name"`
+		}
+		items := []artist{{ID: 4, Name: "Ozzie"}, {ID: 9, Name: "Flea"}}
+
+		p := b.Select().From("artist").Paginate(10).Cursor("-id")
+
+		next, err := p.NextPageCursor(&items)
+		assert.NoError(err)
+
+		q := p.NextPage(next)
+		assert.Equal(
+			`SELECT * FROM "artist" WHERE ("id" < $1) ORDER BY "id" DESC LIMIT 10`,
+			q.String(),
+		)
+		assert.Equal(
+			[]interface{}{int64(9)},
+			q.Arguments(),
+		)
+
+		prev, err := p.PrevPageCursor([]map[string]interface{}{{"id": 4}})
+		assert.NoError(err)
+		assert.Equal(
+			[]interface{}{int64(4)},
+			p.PrevPage(prev).Arguments(),
+		)
+
+		_, err = p.NextPageCursor(&[]artist{})
+		assert.Equal(db.ErrNoMoreRows, err)
+
+		_, err = p.Cursor("age").NextPageCursor(&items)
+		assert.Equal(errMissingCursorColumn, err)
+
+		_, _, err = p.NextPage(db.Cursor("$$$")).Compile()
+		assert.Equal(db.ErrInvalidCursor, err)
+	}
+}
+
+func TestPaginateTotalEntries(t *testing.T) {
+	b, sess := newFakeBuilder()
+	assert := assert.New(t)
+
+	queries := []Selector{
+		b.SelectFrom("artist").Where("id > ?", 1).OrderBy("name").ForUpdate(),
+		b.Select("genre").From("artist").Where("id > ?", 1).GroupBy("genre").OrderBy("genre"),
+		b.Select().Distinct("genre").From("artist").Where("id > ?", 1),
+		b.Select("genre").From("recent").With("recent", b.SelectFrom("artist").Where("id > ?", 1)).GroupBy("genre"),
+	}
+
+	for i := range queries {
+		_, err := queries[i].Paginate(10).Page(3).TotalEntries()
+		assert.Equal(errFakeSessionUnsupported, err)
+	}
+
+	assert.Equal([]string{
+		`SELECT count(1) AS "_t" FROM "artist" WHERE (id > $1)`,
+		`SELECT count(1) AS "_t" FROM (SELECT "genre" FROM "artist" WHERE (id > $1) GROUP BY "genre") AS "_u"`,
+		`SELECT count(1) AS "_t" FROM (SELECT DISTINCT "genre" FROM "artist" WHERE (id > $1)) AS "_u"`,
+		`WITH "recent" AS (SELECT * FROM "artist" WHERE (id > $1)) SELECT count(1) AS "_t" FROM (SELECT "genre" FROM "recent" GROUP BY "genre") AS "_u"`,
+	}, sess.queries)
+
+	for i := range sess.args {
+		assert.Equal([]interface{}{1}, sess.args[i])
+	}
 }
 
 func BenchmarkDelete1(b *testing.B) {
@@ -1473,3 +2428,146 @@ func stripWhitespace(in string) string {
 	q := reInvisibleChars.ReplaceAllString(in, ` `)
 	return strings.TrimSpace(q)
 }
+
+func TestPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	policy := NewPolicy()
+	assert.NoError(t, policy.Check(ctx, &Statement{Type: "truncate", Query: `TRUNCATE TABLE "artist"`}))
+
+	policy.Forbid("truncate").
+		RequireWhere("update", "delete").
+		ForbidPattern(`(?i)\bpg_sleep\b`)
+
+	err := policy.Check(ctx, &Statement{Type: "truncate", Query: `TRUNCATE TABLE "artist"`})
+	if assert.Error(t, err) {
+		policyErr, ok := err.(*PolicyError)
+		assert.True(t, ok)
+		assert.Equal(t, "truncate statements are forbidden", policyErr.Reason)
+		assert.Equal(t, `upper: statement refused by policy: truncate statements are forbidden: TRUNCATE TABLE "artist"`, err.Error())
+	}
+
+	assert.Error(t, policy.Check(ctx, &Statement{Type: "delete", Query: `DELETE FROM "artist"`}))
+	assert.NoError(t, policy.Check(ctx, &Statement{Type: "delete", Query: `DELETE FROM "artist" WHERE "id" = $1`, Where: true}))
+	assert.Error(t, policy.Check(ctx, &Statement{Type: "select", Query: `SELECT pg_sleep(10)`}))
+
+	// Raw statements are matched by their first keyword.
+	assert.Error(t, policy.Check(ctx, &Statement{Type: "raw", Query: ` delete from artist`}))
+	assert.NoError(t, policy.Check(ctx, &Statement{Type: "raw", Query: `delete from artist where id = 1`}))
+	assert.Error(t, policy.Check(ctx, &Statement{Type: "raw", Query: `truncate artist`}))
+
+	policy.AllowRaw(`^SELECT `)
+	assert.NoError(t, policy.Check(ctx, &Statement{Type: "raw", Query: `SELECT * FROM artist`}))
+	assert.Error(t, policy.Check(ctx, &Statement{Type: "raw", Query: `UPDATE artist SET name = 'x' WHERE id = 1`}))
+	assert.NoError(t, policy.Check(ctx, &Statement{Type: "update", Query: `UPDATE "artist" SET "name" = $1 WHERE "id" = $2`, Where: true}))
+}
+
+// scopedSession scopes the rows of the "account" table by tenant.
+type scopedSession struct {
+	fakeSession
+	tenant interface{}
+}
+
+func (s *scopedSession) TableScope(ctx context.Context, table string) (string, interface{}, bool, error) {
+	if table != "account" {
+		return "", nil, false, nil
+	}
+	if tenant, ok := db.Tenant(ctx); ok {
+		return "tenant_id", tenant, true, nil
+	}
+	if s.tenant == nil {
+		return "", nil, false, db.ErrMissingTenant
+	}
+	return "tenant_id", s.tenant, true, nil
+}
+
+func TestTableScope(t *testing.T) {
+	sess := &scopedSession{fakeSession: fakeSession{t: &testTemplate}, tenant: 7}
+	b := WithSession(sess, &testTemplate)
+
+	compile := func(c interface {
+		Compile() (string, []interface{}, error)
+	}) (string, []interface{}) {
+		query, args, err := c.Compile()
+		assert.NoError(t, err)
+		return stripWhitespace(query), args
+	}
+
+	query, args := compile(b.Select().From("account").Where(db.Cond{"name": "Joe"}))
+	assert.Equal(t, `SELECT * FROM "account" WHERE ("name" = ? AND "tenant_id" = ?)`, query)
+	assert.Equal(t, []interface{}{"Joe", 7}, args)
+
+	query, args = compile(b.Select().From("artist").Where(db.Cond{"name": "Joe"}))
+	assert.Equal(t, `SELECT * FROM "artist" WHERE ("name" = ?)`, query)
+	assert.Equal(t, []interface{}{"Joe"}, args)
+
+	// Joined tables are scoped within the ON clause.
+	query, args = compile(b.Select("p.name").From("artist AS p").
+		LeftJoin("account AS a").On("a.artist_id = p.id").
+		Where(db.Cond{"p.name": "Joe"}))
+	assert.Equal(t, `SELECT "p"."name" FROM "artist" AS "p" LEFT JOIN "account" AS "a" ON (a.artist_id = p.id AND "a"."tenant_id" = ?) WHERE ("p"."name" = ?)`, query)
+	assert.Equal(t, []interface{}{7, "Joe"}, args)
+
+	query, args = compile(b.Select().From("account AS a", "artist AS p"))
+	assert.Equal(t, `SELECT * FROM "account" AS "a", "artist" AS "p" WHERE ("a"."tenant_id" = ?)`, query)
+	assert.Equal(t, []interface{}{7}, args)
+
+	_, _, err := b.Select().From("artist AS p").LeftJoin("account AS a").Using("artist_id").Compile()
+	assert.Equal(t, errScopedOuterJoin, err)
+
+	query, args = compile(b.Update("account").Set("name", "Joe").Where("id", 1))
+	assert.Equal(t, `UPDATE "account" SET "name" = ? WHERE ("id" = ? AND "tenant_id" = ?)`, query)
+	assert.Equal(t, []interface{}{"Joe", 1, 7}, args)
+
+	_, _, err = b.Update("account").Set("tenant_id", 8).Where("id", 1).Compile()
+	assert.Equal(t, db.ErrTenantColumn, err)
+
+	query, args = compile(b.DeleteFrom("account").Where("id", 1))
+	assert.Equal(t, `DELETE FROM "account" WHERE ("id" = ? AND "tenant_id" = ?)`, query)
+	assert.Equal(t, []interface{}{1, 7}, args)
+
+	query, args = compile(b.InsertInto("account").Values(map[string]interface{}{"name": "Joe", "tenant_id": 8}))
+	assert.Equal(t, `INSERT INTO "account" ("name", "tenant_id") VALUES (?, ?)`, query)
+	assert.Equal(t, []interface{}{"Joe", 7}, args)
+
+	query, args = compile(b.InsertInto("account").Columns("name").Values("Joe").Values("Ann"))
+	assert.Equal(t, `INSERT INTO "account" ("name", "tenant_id") VALUES (?, ?), (?, ?)`, query)
+	assert.Equal(t, []interface{}{"Joe", 7, "Ann", 7}, args)
+
+	sess.tenant = nil
+
+	_, _, err = b.Select().From("account").Compile()
+	assert.Equal(t, db.ErrMissingTenant, err)
+
+	_, _, err = b.Select().From("artist").Join("account").On("account.artist_id = artist.id").Compile()
+	assert.Equal(t, db.ErrMissingTenant, err)
+
+	_, _, err = b.InsertInto("account").Values(map[string]interface{}{"name": "Joe"}).Compile()
+	assert.Equal(t, db.ErrMissingTenant, err)
+
+	// Statements are scoped by the tenant of the context they run within.
+	ctx := db.WithTenant(context.Background(), 9)
+
+	_, err = b.Select().From("account").Where(db.Cond{"name": "Joe"}).QueryRowContext(ctx)
+	assert.Equal(t, errFakeSessionUnsupported, err)
+	_, err = b.Update("account").Set("name", "Ann").Where("id", 1).ExecContext(ctx)
+	assert.NoError(t, err)
+	_, err = b.DeleteFrom("account").Where("id", 1).ExecContext(ctx)
+	assert.NoError(t, err)
+	_, err = b.InsertInto("account").Columns("name").Values("Joe").ExecContext(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"Joe", 9}, {"Ann", 1, 9}, {1, 9}, {"Joe", 9}}, sess.args)
+
+	_, err = b.DeleteFrom("account").Where("id", 1).ExecContext(context.Background())
+	assert.Equal(t, db.ErrMissingTenant, err)
+}
+
+func TestOutValues(t *testing.T) {
+	var total int
+	values := []interface{}{12, Out(&total), InOut(&total)}
+
+	assert.Equal(t, []interface{}{12, sql.Out{Dest: &total}, sql.Out{Dest: &total, In: true}}, OutValues(values))
+
+	// The values given are left as they are.
+	assert.Equal(t, Out(&total), values[1])
+}
@@ -0,0 +1,272 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+var errOutParamDest = errors.New("the destination of an OUT parameter must be a non-nil pointer")
+
+// OutParam is an OUT or INOUT argument of a stored procedure, use Out and
+// InOut to create one.
+type OutParam struct {
+	// Dest is a pointer the value of the parameter is written into when the
+	// procedure returns.
+	Dest interface{}
+
+	// In makes the parameter an INOUT one, the value Dest points to is passed
+	// to the procedure.
+	In bool
+}
+
+// Out returns an OUT argument for Call, its value is written into dest, a
+// pointer, when the procedure returns.
+func Out(dest interface{}) OutParam {
+	return OutParam{Dest: dest}
+}
+
+// InOut returns an INOUT argument for Call, the value dest points to is passed
+// to the procedure and replaced by the value it returns.
+func InOut(dest interface{}) OutParam {
+	return OutParam{Dest: dest, In: true}
+}
+
+// value returns the value that is passed to the procedure for p when OUT
+// parameters are returned as a row.
+func (p OutParam) value() interface{} {
+	if !p.In {
+		return nil
+	}
+	return reflect.ValueOf(p.Dest).Elem().Interface()
+}
+
+// OutValues returns a copy of values where the OUT and INOUT arguments of Call
+// are replaced by sql.Out values, it's meant for the ConvertValues method of
+// adapters whose drivers support sql.Out.
+func OutValues(values []interface{}) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i := range values {
+		if p, ok := values[i].(OutParam); ok {
+			converted[i] = sql.Out{Dest: p.Dest, In: p.In}
+			continue
+		}
+		converted[i] = values[i]
+	}
+	return converted
+}
+
+// hasOutParamsRow is implemented by sessions on databases whose procedures
+// return their OUT parameters as a row, like PostgreSQL, rather than setting
+// them as sql.Out arguments.
+type hasOutParamsRow interface {
+	OutParamsRow() bool
+}
+
+// hasRunInTx is implemented by sessions that can run statements within a
+// transaction, so all of them use the same connection. It's required to pass
+// OUT parameters as user variables, see exql.Template.CallOutVariables.
+type hasRunInTx interface {
+	RunInTx(ctx context.Context, fn func(sess interface{}) error) error
+}
+
+type caller struct {
+	builder *sqlBuilder
+
+	procedure string
+	args      []interface{}
+}
+
+var _ = Caller(&caller{})
+
+// outParamsRow reports whether the OUT parameters of the procedure are read
+// from the row it returns.
+func (c *caller) outParamsRow() bool {
+	s, ok := c.builder.sess.(hasOutParamsRow)
+	return ok && s.OutParamsRow()
+}
+
+// outs returns the OUT and INOUT arguments of the call.
+func (c *caller) outs() []OutParam {
+	var outs []OutParam
+	for i := range c.args {
+		if p, ok := c.args[i].(OutParam); ok {
+			outs = append(outs, p)
+		}
+	}
+	return outs
+}
+
+func (c *caller) statement() (*exql.Statement, []interface{}, error) {
+	layout := c.builder.t.Template
+	outParamsRow := c.outParamsRow()
+
+	var args []interface{}
+	var outs int
+	params := make([]exql.Fragment, 0, len(c.args))
+	for i := range c.args {
+		p, ok := c.args[i].(OutParam)
+		if !ok {
+			param, paramArgs := c.builder.t.PlaceholderValue(c.args[i])
+			params, args = append(params, param), append(args, paramArgs...)
+			continue
+		}
+		if layout.CallOutLayout == "" {
+			return nil, nil, db.ErrUnsupported
+		}
+		if v := reflect.ValueOf(p.Dest); v.Kind() != reflect.Ptr || v.IsNil() {
+			return nil, nil, errOutParamDest
+		}
+		if layout.CallOutVariables {
+			// The variable is set and read by ExecContext.
+			params = append(params, exql.RawValue(fmt.Sprintf(layout.CallOutLayout, outs)))
+			outs++
+			continue
+		}
+		params = append(params, exql.RawValue(layout.CallOutLayout))
+		if outParamsRow {
+			args = append(args, p.value())
+		} else {
+			args = append(args, p)
+		}
+	}
+
+	stmt := &exql.Statement{
+		Type:    exql.Call,
+		Table:   exql.TableWithName(c.procedure),
+		Columns: exql.JoinColumns(params...),
+	}
+	return stmt, args, nil
+}
+
+func (c *caller) String() string {
+	s, err := c.compile()
+	if err != nil {
+		panic(err.Error())
+	}
+	return prepareQueryForDisplay(s)
+}
+
+func (c *caller) compile() (string, error) {
+	stmt, _, err := c.statement()
+	if err != nil {
+		return "", err
+	}
+	return stmt.Compile(c.builder.t.Template)
+}
+
+func (c *caller) Compile() (string, []interface{}, error) {
+	stmt, args, err := c.statement()
+	if err != nil {
+		return "", nil, err
+	}
+	return c.builder.compileStatement(stmt, args)
+}
+
+func (c *caller) Exec() (sql.Result, error) {
+	return c.ExecContext(c.builder.sess.Context())
+}
+
+func (c *caller) ExecContext(ctx context.Context) (sql.Result, error) {
+	stmt, args, err := c.statement()
+	if err != nil {
+		return nil, err
+	}
+
+	outs := c.outs()
+	if len(outs) > 0 && c.builder.t.CallOutVariables {
+		return c.execWithVariables(ctx, stmt, args, outs)
+	}
+	if len(outs) == 0 || !c.outParamsRow() {
+		return c.builder.sess.StatementExec(ctx, stmt, args...)
+	}
+
+	// The OUT parameters are the columns of the only row the procedure
+	// returns.
+	rows, err := c.builder.sess.StatementQuery(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+	dests := make([]interface{}, len(outs))
+	for i := range outs {
+		dests[i] = outs[i].Dest
+	}
+	if err := rows.Scan(dests...); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), rows.Close()
+}
+
+// execWithVariables runs the procedure passing its OUT parameters as user
+// variables: the ones of INOUT parameters are set before the call and all of
+// them are read after it, on the same connection.
+func (c *caller) execWithVariables(ctx context.Context, stmt *exql.Statement, args []interface{}, outs []OutParam) (res sql.Result, err error) {
+	runner, ok := c.builder.sess.(hasRunInTx)
+	if !ok {
+		return nil, db.ErrUnsupported
+	}
+
+	names := make([]string, len(outs))
+	dests := make([]interface{}, len(outs))
+	for i := range outs {
+		names[i] = fmt.Sprintf(c.builder.t.CallOutLayout, i)
+		dests[i] = outs[i].Dest
+	}
+
+	err = runner.RunInTx(ctx, func(s interface{}) error {
+		sess := s.(exprDB)
+		for i := range outs {
+			// OUT parameters are set too, a previous call on the connection
+			// could have left a value in their variable.
+			set := exql.RawSQL(fmt.Sprintf("SET %s = ?", names[i]))
+			if _, err := sess.StatementExec(ctx, set, outs[i].value()); err != nil {
+				return err
+			}
+		}
+		if res, err = sess.StatementExec(ctx, stmt, args...); err != nil {
+			return err
+		}
+		row, err := sess.StatementQueryRow(ctx, exql.RawSQL("SELECT "+strings.Join(names, ", ")))
+		if err != nil {
+			return err
+		}
+		return row.Scan(dests...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *caller) Iterator() Iterator {
+	return c.IteratorContext(c.builder.sess.Context())
+}
+
+func (c *caller) IteratorContext(ctx context.Context) Iterator {
+	sess := c.builder.sess
+	stmt, args, err := c.statement()
+	if err != nil {
+		return &iterator{sess, nil, err}
+	}
+	if c.builder.t.CallOutVariables && len(c.outs()) > 0 {
+		// The variables can't be read while the rows are.
+		return &iterator{sess, nil, db.ErrUnsupported}
+	}
+	rows, err := sess.StatementQuery(ctx, stmt, args...)
+	return &iterator{sess, rows, err}
+}
@@ -0,0 +1,64 @@
+package sqlbuilder
+
+import (
+	"database/sql"
+	"reflect"
+
+	"upper.io/db.v3"
+)
+
+// hasCodecs is implemented by sessions that have codecs, see db.Codecs.
+type hasCodecs interface {
+	Codecs() *db.Codecs
+}
+
+// sessionCodecs returns the codecs of the given session, if any.
+func sessionCodecs(sess interface{}) *db.Codecs {
+	if s, ok := sess.(hasCodecs); ok {
+		return s.Codecs()
+	}
+	return nil
+}
+
+// codecScanner decodes values into dst with the codec of its type.
+type codecScanner struct {
+	codec *db.Codec
+	dst   interface{}
+}
+
+func (s codecScanner) Scan(src interface{}) error {
+	return s.codec.Decode(src, s.dst)
+}
+
+var _ sql.Scanner = codecScanner{}
+
+// decodeValues returns the given scan destinations with the pointers to the
+// types that have a codec wrapped to be decoded by it, values is not
+// modified.
+func decodeValues(codecs *db.Codecs, values []interface{}) []interface{} {
+	if codecs == nil {
+		return values
+	}
+
+	var decoded []interface{}
+	for i := range values {
+		t := reflect.TypeOf(values[i])
+		if t == nil || t.Kind() != reflect.Ptr {
+			continue
+		}
+		codec := codecs.Lookup(t.Elem())
+		if codec == nil || codec.Decode == nil {
+			continue
+		}
+		if decoded == nil {
+			decoded = make([]interface{}, len(values))
+			copy(decoded, values)
+		}
+		decoded[i] = codecScanner{codec, values[i]}
+	}
+
+	if decoded == nil {
+		return values
+	}
+	return decoded
+}
@@ -0,0 +1,24 @@
+// +build go1.8
+
+package sqlbuilder
+
+import (
+	"database/sql"
+)
+
+func columnInfo(rows *sql.Rows) ([]ColumnInfo, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]ColumnInfo, len(types))
+	for i, t := range types {
+		columns[i] = ColumnInfo{
+			Name:         t.Name(),
+			DatabaseType: t.DatabaseTypeName(),
+			ScanType:     t.ScanType(),
+		}
+		columns[i].Nullable, columns[i].NullableKnown = t.Nullable()
+	}
+	return columns, nil
+}
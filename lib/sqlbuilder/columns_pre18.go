@@ -0,0 +1,20 @@
+// +build !go1.8
+
+package sqlbuilder
+
+import (
+	"database/sql"
+)
+
+// Column types are not available before Go 1.8, only names are reported.
+func columnInfo(rows *sql.Rows) ([]ColumnInfo, error) {
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]ColumnInfo, len(names))
+	for i, name := range names {
+		columns[i] = ColumnInfo{Name: name}
+	}
+	return columns, nil
+}
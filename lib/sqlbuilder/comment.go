@@ -0,0 +1,97 @@
+package sqlbuilder
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type commentTagsKey struct{}
+
+// WithCommentTags returns a copy of ctx that carries the given tags, they're
+// added to the ones ctx already carries. Statements run with the returned
+// context are tagged with them by the middleware returned by SQLCommenter:
+//
+//	ctx = sqlbuilder.WithCommentTags(ctx, map[string]string{
+//		"traceparent": traceparent,
+//		"route":       "/users/:id",
+//	})
+func WithCommentTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	for k, v := range CommentTags(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, commentTagsKey{}, merged)
+}
+
+// CommentTags returns the tags ctx carries.
+func CommentTags(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	tags, _ := ctx.Value(commentTagsKey{}).(map[string]string)
+	return tags
+}
+
+// SQLCommenter returns statement middleware that appends a comment with the
+// given tags and the ones carried by the context of the statement, following
+// the sqlcommenter format, so statements can be traced back to the code that
+// ran them from tools like pg_stat_statements:
+//
+//	sess.Use(sqlbuilder.SQLCommenter(map[string]string{"application": "billing"}))
+//
+//	// SELECT * FROM "users" /*application='billing',route='%2Fusers%2F%3Aid'*/
+//
+// Tags from the context take precedence over the given ones. Keys and values
+// are URL-encoded, so they can't be used to break out of the comment.
+// Statements that already have a comment are left as they are.
+func SQLCommenter(tags map[string]string) func(next StatementHandler) StatementHandler {
+	return func(next StatementHandler) StatementHandler {
+		return func(ctx context.Context, stmt *Statement) error {
+			if comment := sqlComment(tags, CommentTags(ctx)); comment != "" && !strings.Contains(stmt.Query, "/*") {
+				// The comment goes before the final semicolon, some statements,
+				// like MERGE on SQL Server, require one.
+				query := strings.TrimSpace(stmt.Query)
+				if strings.HasSuffix(query, ";") {
+					stmt.Query = strings.TrimSpace(query[:len(query)-1]) + " " + comment + ";"
+				} else {
+					stmt.Query = query + " " + comment
+				}
+			}
+			return next(ctx, stmt)
+		}
+	}
+}
+
+// sqlComment serializes tags as a comment, keys are sorted.
+func sqlComment(tagSets ...map[string]string) string {
+	tags := map[string]string{}
+	for _, set := range tagSets {
+		for k, v := range set {
+			tags[k] = v
+		}
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = commentEscape(k) + "='" + commentEscape(tags[k]) + "'"
+	}
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
+
+func commentEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
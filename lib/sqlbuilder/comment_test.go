@@ -0,0 +1,48 @@
+package sqlbuilder
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLCommenter(t *testing.T) {
+	run := func(ctx context.Context, query string) string {
+		stmt := &Statement{Type: "select", Query: query}
+		h := SQLCommenter(map[string]string{"application": "billing", "route": "default"})(func(ctx context.Context, stmt *Statement) error {
+			return nil
+		})
+		assert.NoError(t, h(ctx, stmt))
+		return stmt.Query
+	}
+
+	ctx := WithCommentTags(context.Background(), map[string]string{"route": "/users/:id"})
+	ctx = WithCommentTags(ctx, map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"})
+	assert.Equal(t, map[string]string{"route": "/users/:id", "traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}, CommentTags(ctx))
+
+	assert.Equal(t,
+		`SELECT * FROM "users" /*application='billing',route='%2Fusers%2F%3Aid',traceparent='00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01'*/`,
+		run(ctx, `SELECT * FROM "users"`),
+	)
+
+	assert.Equal(t,
+		`SELECT 1 /*application='billing',route='default'*/`,
+		run(context.Background(), `SELECT 1`),
+	)
+
+	assert.Equal(t,
+		`MERGE INTO [t] USING (VALUES (1)) AS s (id) ON 1 = 0 WHEN NOT MATCHED THEN INSERT (id) VALUES (s.id) /*application='billing',route='default'*/;`,
+		run(context.Background(), `MERGE INTO [t] USING (VALUES (1)) AS s (id) ON 1 = 0 WHEN NOT MATCHED THEN INSERT (id) VALUES (s.id);`),
+	)
+
+	// Comments can't be closed from within a tag.
+	ctx = WithCommentTags(context.Background(), map[string]string{"route": "*/ DROP TABLE users; /* it's"})
+	assert.Equal(t,
+		`SELECT 1 /*application='billing',route='%2A%2F%20DROP%20TABLE%20users%3B%20%2F%2A%20it%27s'*/`,
+		run(ctx, `SELECT 1`),
+	)
+
+	// Statements with comments are left untouched.
+	assert.Equal(t, `SELECT /*+ INDEX(users) */ 1`, run(ctx, `SELECT /*+ INDEX(users) */ 1`))
+}
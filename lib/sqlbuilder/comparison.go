@@ -120,6 +120,11 @@ func (ow *operatorWrapper) preprocess() (string, []interface{}) {
 	}
 
 	if strings.Contains(op, ":column") {
+		if n := strings.Count(op, "?"); n > 1 {
+			if values, ok := c.Value().([]interface{}); ok && len(values) == n {
+				args = values
+			}
+		}
 		return strings.Replace(op, ":column", column, -1), args
 	}
 
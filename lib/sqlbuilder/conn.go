@@ -0,0 +1,24 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Conn is a connection that was just opened and is not part of the pool of
+// the session yet, it's passed to the hooks installed with
+// Database.OnConnect:
+//
+//	sess.OnConnect(func(ctx context.Context, conn sqlbuilder.Conn) error {
+//		_, err := conn.ExecContext(ctx, `SET search_path TO billing, public`)
+//		return err
+//	})
+type Conn interface {
+	// ExecContext runs a statement that doesn't return rows on the
+	// connection. The statement is sent as it is, so it must use the
+	// placeholders of the database.
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+
+	// Driver returns the underlying driver.Conn.
+	Driver() interface{}
+}
@@ -25,8 +25,17 @@ func expandQuery(in string, args []interface{}, fn func(interface{}) (string, []
 			k, values = expandQuery(k, values, fn)
 
 			if k != "" {
-				in = in[:i] + k + in[i+1:]
-				i += len(k) - 1
+				start, end := i, i+1
+				if isSlice(args[argn]) {
+					// A slice that is already within parentheses, like in
+					// "IN (?)", takes their place instead of getting a second
+					// pair.
+					if left, right, ok := enclosingParens(in, i); ok {
+						start, end = left, right+1
+					}
+				}
+				in = in[:start] + k + in[end:]
+				i = start + len(k) - 1
 			}
 			if len(values) > 0 {
 				argx = append(argx, values...)
@@ -40,6 +49,36 @@ func expandQuery(in string, args []interface{}, fn func(interface{}) (string, []
 	return in, argx
 }
 
+// isSlice returns true if value is a slice that is expanded into a list of
+// placeholders.
+func isSlice(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if _, ok := value.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(value)
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+// enclosingParens returns the positions of the parentheses that enclose the
+// placeholder at position i of in, only blanks are allowed in between.
+func enclosingParens(in string, i int) (int, int, bool) {
+	left := i - 1
+	for left >= 0 && (in[left] == ' ' || in[left] == '\t' || in[left] == '\n') {
+		left--
+	}
+	right := i + 1
+	for right < len(in) && (in[right] == ' ' || in[right] == '\t' || in[right] == '\n') {
+		right++
+	}
+	if left < 0 || right >= len(in) || in[left] != '(' || in[right] != ')' {
+		return 0, 0, false
+	}
+	return left, right, true
+}
+
 // toInterfaceArguments converts the given value into an array of interfaces.
 func toInterfaceArguments(value interface{}) (args []interface{}, isSlice bool) {
 	v := reflect.ValueOf(value)
@@ -124,7 +163,7 @@ func preprocessFn(arg interface{}) (string, []interface{}) {
 		case db.RawValue:
 			return Preprocess(t.Raw(), t.Arguments())
 		case compilable:
-			c, err := t.Compile()
+			c, err := t.compile()
 			if err == nil {
 				return `(` + c + `)`, t.Arguments()
 			}
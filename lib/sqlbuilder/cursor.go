@@ -0,0 +1,14 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+// hasStatementCursor is implemented by sessions that can read the rows of a
+// statement through a server-side cursor.
+type hasStatementCursor interface {
+	StatementCursor(ctx context.Context, stmt *exql.Statement, name string, fetchSize int, args ...interface{}) (*sql.Rows, error)
+}
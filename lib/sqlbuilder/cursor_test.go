@@ -0,0 +1,25 @@
+package sqlbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+)
+
+func TestSelectorCursor(t *testing.T) {
+	b, sess := newFakeBuilder()
+
+	var artists []map[string]interface{}
+
+	err := b.SelectFrom("artist").Cursor("", 10).All(&artists)
+	assert.Equal(t, errInvalidCursor, err)
+
+	err = b.SelectFrom("artist").Cursor("artists", 0).All(&artists)
+	assert.Equal(t, errInvalidCursor, err)
+
+	// The fake session can't declare cursors, the query is not run.
+	err = b.SelectFrom("artist").Cursor("artists", 10).All(&artists)
+	assert.Equal(t, db.ErrUnsupported, err)
+	assert.Nil(t, sess.ctx)
+}
@@ -0,0 +1,654 @@
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/immutable"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+var (
+	errMissingIndexTable   = errors.New("missing table, use On() to set the table of the index")
+	errMissingIndexColumns = errors.New("an index requires at least one column")
+	errMissingTableColumns = errors.New("a table requires at least one column")
+	errMismatchedReference = errors.New("foreign key columns and referenced columns must be of the same length")
+)
+
+// ColumnType represents a portable column type. Each adapter translates
+// column types into the closest type its database supports.
+type ColumnType struct {
+	t         exql.ColumnType
+	size      int
+	precision int
+	scale     int
+}
+
+// Column types that don't take any parameters.
+var (
+	Boolean    = ColumnType{t: exql.TypeBoolean}
+	Integer    = ColumnType{t: exql.TypeInteger}
+	BigInteger = ColumnType{t: exql.TypeBigInteger}
+	Float      = ColumnType{t: exql.TypeFloat}
+	Text       = ColumnType{t: exql.TypeText}
+	Timestamp  = ColumnType{t: exql.TypeTimestamp}
+	Date       = ColumnType{t: exql.TypeDate}
+	Binary     = ColumnType{t: exql.TypeBinary}
+	JSON       = ColumnType{t: exql.TypeJSON}
+)
+
+// String returns a variable length string column type that holds up to size
+// characters.
+func String(size int) ColumnType {
+	return ColumnType{t: exql.TypeString, size: size}
+}
+
+// Decimal returns an exact numeric column type with the given precision and
+// scale.
+func Decimal(precision int, scale int) ColumnType {
+	return ColumnType{t: exql.TypeDecimal, precision: precision, scale: scale}
+}
+
+// ColumnDefinition describes a column for CreateTable and
+// TableAlterer.AddColumn. Use NewColumn to create a ColumnDefinition, its
+// methods return modified copies:
+//
+//	sqlbuilder.NewColumn("id", sqlbuilder.Integer).PrimaryKey().AutoIncrement()
+//	sqlbuilder.NewColumn("name", sqlbuilder.String(60)).NotNull().Default("")
+type ColumnDefinition struct {
+	name          string
+	columnType    ColumnType
+	notNull       bool
+	hasDefault    bool
+	defaultValue  interface{}
+	primaryKey    bool
+	autoIncrement bool
+	unique        bool
+	refTable      string
+	refColumn     string
+}
+
+// NewColumn returns the definition of a column with the given name and type.
+func NewColumn(name string, columnType ColumnType) ColumnDefinition {
+	return ColumnDefinition{name: name, columnType: columnType}
+}
+
+// NotNull adds a NOT NULL constraint to the column.
+func (c ColumnDefinition) NotNull() ColumnDefinition {
+	c.notNull = true
+	return c
+}
+
+// Default sets the default value of the column. Strings, booleans, numbers,
+// nil and db.RawValue are accepted.
+func (c ColumnDefinition) Default(value interface{}) ColumnDefinition {
+	c.hasDefault, c.defaultValue = true, value
+	return c
+}
+
+// PrimaryKey makes the column the primary key of the table.
+func (c ColumnDefinition) PrimaryKey() ColumnDefinition {
+	c.primaryKey = true
+	return c
+}
+
+// AutoIncrement makes the database generate a sequential value for the column.
+func (c ColumnDefinition) AutoIncrement() ColumnDefinition {
+	c.autoIncrement = true
+	return c
+}
+
+// Unique adds a UNIQUE constraint to the column.
+func (c ColumnDefinition) Unique() ColumnDefinition {
+	c.unique = true
+	return c
+}
+
+// References makes the column a foreign key that points to the given column
+// of another table.
+func (c ColumnDefinition) References(table string, column string) ColumnDefinition {
+	c.refTable, c.refColumn = table, column
+	return c
+}
+
+func (c ColumnDefinition) reference() *exql.Reference {
+	if c.refTable == "" {
+		return nil
+	}
+	return &exql.Reference{
+		Table:   exql.ColumnWithName(c.refTable),
+		Columns: exql.JoinColumns(exql.ColumnWithName(c.refColumn)),
+	}
+}
+
+func (c ColumnDefinition) fragment() (*exql.ColumnDefinition, error) {
+	def := &exql.ColumnDefinition{
+		Name:          exql.ColumnWithName(c.name),
+		Type:          c.columnType.t,
+		Size:          c.columnType.size,
+		Precision:     c.columnType.precision,
+		Scale:         c.columnType.scale,
+		NotNull:       c.notNull,
+		PrimaryKey:    c.primaryKey,
+		AutoIncrement: c.autoIncrement,
+		Unique:        c.unique,
+		References:    c.reference(),
+	}
+	if c.hasDefault {
+		literal, err := sqlLiteral(c.defaultValue)
+		if err != nil {
+			return nil, err
+		}
+		def.Default = exql.RawValue(literal)
+	}
+	return def, nil
+}
+
+// sqlLiteral converts a Go value into an escaped SQL literal, for clauses
+// that can't have placeholders, like the default value of a column.
+func sqlLiteral(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "NULL", nil
+	case db.RawValue:
+		if len(t.Arguments()) > 0 {
+			return "", fmt.Errorf("expecting a literal but got arguments: %q", t.Raw())
+		}
+		return t.Raw(), nil
+	case string:
+		return "'" + strings.Replace(t, "'", "''", -1) + "'", nil
+	case bool:
+		if t {
+			return "'1'", nil
+		}
+		return "'0'", nil
+	case int:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int8:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(t), 10), nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(t), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(t), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(t), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(t), 10), nil
+	case uint64:
+		return strconv.FormatUint(t, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(t), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported type %T for a literal", v)
+}
+
+func columnList(columns []string) *exql.Columns {
+	var fragments []exql.Fragment
+	columnsToFragments(&fragments, columns)
+	return exql.JoinColumns(fragments...)
+}
+
+type tableCreatorQuery struct {
+	table       string
+	columns     []exql.Fragment
+	constraints []exql.Fragment
+}
+
+func (tq *tableCreatorQuery) statement() *exql.Statement {
+	definitions := make([]exql.Fragment, 0, len(tq.columns)+len(tq.constraints))
+	definitions = append(definitions, tq.columns...)
+	definitions = append(definitions, tq.constraints...)
+
+	return &exql.Statement{
+		Type:        exql.CreateTable,
+		Table:       exql.TableWithName(tq.table),
+		Definitions: exql.JoinDefinitions(definitions...),
+	}
+}
+
+type tableCreator struct {
+	builder *sqlBuilder
+
+	fn   func(*tableCreatorQuery) error
+	prev *tableCreator
+}
+
+var _ = immutable.Immutable(&tableCreator{})
+
+func (tc *tableCreator) SQLBuilder() *sqlBuilder {
+	if tc.prev == nil {
+		return tc.builder
+	}
+	return tc.prev.SQLBuilder()
+}
+
+func (tc *tableCreator) template() *exql.Template {
+	return tc.SQLBuilder().t.Template
+}
+
+func (tc *tableCreator) String() string {
+	s, err := tc.compile()
+	if err != nil {
+		panic(err.Error())
+	}
+	return prepareQueryForDisplay(s)
+}
+
+func (tc *tableCreator) setTable(table string) *tableCreator {
+	return tc.frame(func(tq *tableCreatorQuery) error {
+		tq.table = table
+		return nil
+	})
+}
+
+func (tc *tableCreator) frame(fn func(*tableCreatorQuery) error) *tableCreator {
+	return &tableCreator{prev: tc, fn: fn}
+}
+
+func (tc *tableCreator) Columns(columns ...ColumnDefinition) TableCreator {
+	return tc.frame(func(tq *tableCreatorQuery) error {
+		for i := range columns {
+			def, err := columns[i].fragment()
+			if err != nil {
+				return err
+			}
+			// Not every database honours REFERENCES next to a column, so
+			// references become table constraints.
+			if def.References != nil {
+				tq.constraints = append(tq.constraints, &exql.Constraint{
+					Type:       exql.ForeignKeyConstraint,
+					Columns:    exql.JoinColumns(def.Name),
+					References: def.References,
+				})
+				def.References = nil
+			}
+			tq.columns = append(tq.columns, def)
+		}
+		return nil
+	})
+}
+
+func (tc *tableCreator) PrimaryKey(columns ...string) TableCreator {
+	return tc.frame(func(tq *tableCreatorQuery) error {
+		tq.constraints = append(tq.constraints, &exql.Constraint{
+			Type:    exql.PrimaryKeyConstraint,
+			Columns: columnList(columns),
+		})
+		return nil
+	})
+}
+
+func (tc *tableCreator) Unique(columns ...string) TableCreator {
+	return tc.frame(func(tq *tableCreatorQuery) error {
+		tq.constraints = append(tq.constraints, &exql.Constraint{
+			Type:    exql.UniqueConstraint,
+			Columns: columnList(columns),
+		})
+		return nil
+	})
+}
+
+func (tc *tableCreator) ForeignKey(columns []string, table string, refColumns ...string) TableCreator {
+	return tc.frame(func(tq *tableCreatorQuery) error {
+		if len(columns) != len(refColumns) {
+			return errMismatchedReference
+		}
+		tq.constraints = append(tq.constraints, &exql.Constraint{
+			Type:    exql.ForeignKeyConstraint,
+			Columns: columnList(columns),
+			References: &exql.Reference{
+				Table:   exql.ColumnWithName(table),
+				Columns: columnList(refColumns),
+			},
+		})
+		return nil
+	})
+}
+
+func (tc *tableCreator) Exec() (sql.Result, error) {
+	return tc.ExecContext(tc.SQLBuilder().sess.Context())
+}
+
+func (tc *tableCreator) ExecContext(ctx context.Context) (sql.Result, error) {
+	stmt, err := tc.statement()
+	if err != nil {
+		return nil, err
+	}
+	return tc.SQLBuilder().sess.StatementExec(ctx, stmt)
+}
+
+func (tc *tableCreator) statement() (*exql.Statement, error) {
+	tq, err := tc.build()
+	if err != nil {
+		return nil, err
+	}
+	if len(tq.columns) == 0 {
+		return nil, errMissingTableColumns
+	}
+	return tq.statement(), nil
+}
+
+func (tc *tableCreator) build() (*tableCreatorQuery, error) {
+	tq, err := immutable.FastForward(tc)
+	if err != nil {
+		return nil, err
+	}
+	return tq.(*tableCreatorQuery), nil
+}
+
+func (tc *tableCreator) Compile() (string, []interface{}, error) {
+	stmt, err := tc.statement()
+	if err != nil {
+		return "", nil, err
+	}
+	return tc.SQLBuilder().compileStatement(stmt, nil)
+}
+
+func (tc *tableCreator) compile() (string, error) {
+	s, err := tc.statement()
+	if err != nil {
+		return "", err
+	}
+	return s.Compile(tc.template())
+}
+
+func (tc *tableCreator) Prev() immutable.Immutable {
+	if tc == nil {
+		return nil
+	}
+	return tc.prev
+}
+
+func (tc *tableCreator) Fn(in interface{}) error {
+	if tc.fn == nil {
+		return nil
+	}
+	return tc.fn(in.(*tableCreatorQuery))
+}
+
+func (tc *tableCreator) Base() interface{} {
+	return &tableCreatorQuery{}
+}
+
+type tableAltererQuery struct {
+	table   string
+	actions []exql.Fragment
+}
+
+func (aq *tableAltererQuery) statement() *exql.Statement {
+	return &exql.Statement{
+		Type:        exql.AlterTable,
+		Table:       exql.TableWithName(aq.table),
+		Definitions: exql.JoinDefinitions(aq.actions...),
+	}
+}
+
+type tableAlterer struct {
+	builder *sqlBuilder
+
+	fn   func(*tableAltererQuery) error
+	prev *tableAlterer
+}
+
+var _ = immutable.Immutable(&tableAlterer{})
+
+func (ta *tableAlterer) SQLBuilder() *sqlBuilder {
+	if ta.prev == nil {
+		return ta.builder
+	}
+	return ta.prev.SQLBuilder()
+}
+
+func (ta *tableAlterer) template() *exql.Template {
+	return ta.SQLBuilder().t.Template
+}
+
+func (ta *tableAlterer) String() string {
+	s, err := ta.compile()
+	if err != nil {
+		panic(err.Error())
+	}
+	return prepareQueryForDisplay(s)
+}
+
+func (ta *tableAlterer) setTable(table string) *tableAlterer {
+	return ta.frame(func(aq *tableAltererQuery) error {
+		aq.table = table
+		return nil
+	})
+}
+
+func (ta *tableAlterer) frame(fn func(*tableAltererQuery) error) *tableAlterer {
+	return &tableAlterer{prev: ta, fn: fn}
+}
+
+func (ta *tableAlterer) AddColumn(column ColumnDefinition) TableAlterer {
+	return ta.frame(func(aq *tableAltererQuery) error {
+		def, err := column.fragment()
+		if err != nil {
+			return err
+		}
+		aq.actions = append(aq.actions, &exql.AddColumn{Column: def})
+		return nil
+	})
+}
+
+func (ta *tableAlterer) DropColumn(name string) TableAlterer {
+	return ta.frame(func(aq *tableAltererQuery) error {
+		aq.actions = append(aq.actions, &exql.DropColumn{Name: exql.ColumnWithName(name)})
+		return nil
+	})
+}
+
+func (ta *tableAlterer) Exec() (sql.Result, error) {
+	return ta.ExecContext(ta.SQLBuilder().sess.Context())
+}
+
+func (ta *tableAlterer) ExecContext(ctx context.Context) (sql.Result, error) {
+	stmt, err := ta.statement()
+	if err != nil {
+		return nil, err
+	}
+	return ta.SQLBuilder().sess.StatementExec(ctx, stmt)
+}
+
+func (ta *tableAlterer) statement() (*exql.Statement, error) {
+	aq, err := ta.build()
+	if err != nil {
+		return nil, err
+	}
+	return aq.statement(), nil
+}
+
+func (ta *tableAlterer) build() (*tableAltererQuery, error) {
+	aq, err := immutable.FastForward(ta)
+	if err != nil {
+		return nil, err
+	}
+	return aq.(*tableAltererQuery), nil
+}
+
+func (ta *tableAlterer) Compile() (string, []interface{}, error) {
+	stmt, err := ta.statement()
+	if err != nil {
+		return "", nil, err
+	}
+	return ta.SQLBuilder().compileStatement(stmt, nil)
+}
+
+func (ta *tableAlterer) compile() (string, error) {
+	s, err := ta.statement()
+	if err != nil {
+		return "", err
+	}
+	return s.Compile(ta.template())
+}
+
+func (ta *tableAlterer) Prev() immutable.Immutable {
+	if ta == nil {
+		return nil
+	}
+	return ta.prev
+}
+
+func (ta *tableAlterer) Fn(in interface{}) error {
+	if ta.fn == nil {
+		return nil
+	}
+	return ta.fn(in.(*tableAltererQuery))
+}
+
+func (ta *tableAlterer) Base() interface{} {
+	return &tableAltererQuery{}
+}
+
+type indexCreatorQuery struct {
+	name    string
+	table   string
+	columns []string
+	unique  bool
+}
+
+func (iq *indexCreatorQuery) statement() *exql.Statement {
+	return &exql.Statement{
+		Type:    exql.CreateIndex,
+		Index:   exql.ColumnWithName(iq.name),
+		Table:   exql.TableWithName(iq.table),
+		Columns: columnList(iq.columns),
+		Unique:  iq.unique,
+	}
+}
+
+type indexCreator struct {
+	builder *sqlBuilder
+
+	fn   func(*indexCreatorQuery) error
+	prev *indexCreator
+}
+
+var _ = immutable.Immutable(&indexCreator{})
+
+func (ic *indexCreator) SQLBuilder() *sqlBuilder {
+	if ic.prev == nil {
+		return ic.builder
+	}
+	return ic.prev.SQLBuilder()
+}
+
+func (ic *indexCreator) template() *exql.Template {
+	return ic.SQLBuilder().t.Template
+}
+
+func (ic *indexCreator) String() string {
+	s, err := ic.compile()
+	if err != nil {
+		panic(err.Error())
+	}
+	return prepareQueryForDisplay(s)
+}
+
+func (ic *indexCreator) setName(name string) *indexCreator {
+	return ic.frame(func(iq *indexCreatorQuery) error {
+		iq.name = name
+		return nil
+	})
+}
+
+func (ic *indexCreator) frame(fn func(*indexCreatorQuery) error) *indexCreator {
+	return &indexCreator{prev: ic, fn: fn}
+}
+
+func (ic *indexCreator) On(table string, columns ...string) IndexCreator {
+	return ic.frame(func(iq *indexCreatorQuery) error {
+		iq.table, iq.columns = table, columns
+		return nil
+	})
+}
+
+func (ic *indexCreator) Unique() IndexCreator {
+	return ic.frame(func(iq *indexCreatorQuery) error {
+		iq.unique = true
+		return nil
+	})
+}
+
+func (ic *indexCreator) Exec() (sql.Result, error) {
+	return ic.ExecContext(ic.SQLBuilder().sess.Context())
+}
+
+func (ic *indexCreator) ExecContext(ctx context.Context) (sql.Result, error) {
+	stmt, err := ic.statement()
+	if err != nil {
+		return nil, err
+	}
+	return ic.SQLBuilder().sess.StatementExec(ctx, stmt)
+}
+
+func (ic *indexCreator) statement() (*exql.Statement, error) {
+	iq, err := ic.build()
+	if err != nil {
+		return nil, err
+	}
+	if iq.table == "" {
+		return nil, errMissingIndexTable
+	}
+	if len(iq.columns) == 0 {
+		return nil, errMissingIndexColumns
+	}
+	return iq.statement(), nil
+}
+
+func (ic *indexCreator) build() (*indexCreatorQuery, error) {
+	iq, err := immutable.FastForward(ic)
+	if err != nil {
+		return nil, err
+	}
+	return iq.(*indexCreatorQuery), nil
+}
+
+func (ic *indexCreator) Compile() (string, []interface{}, error) {
+	stmt, err := ic.statement()
+	if err != nil {
+		return "", nil, err
+	}
+	return ic.SQLBuilder().compileStatement(stmt, nil)
+}
+
+func (ic *indexCreator) compile() (string, error) {
+	s, err := ic.statement()
+	if err != nil {
+		return "", err
+	}
+	return s.Compile(ic.template())
+}
+
+func (ic *indexCreator) Prev() immutable.Immutable {
+	if ic == nil {
+		return nil
+	}
+	return ic.prev
+}
+
+func (ic *indexCreator) Fn(in interface{}) error {
+	if ic.fn == nil {
+		return nil
+	}
+	return ic.fn(in.(*indexCreatorQuery))
+}
+
+func (ic *indexCreator) Base() interface{} {
+	return &indexCreatorQuery{}
+}
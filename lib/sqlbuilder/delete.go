@@ -12,9 +12,13 @@ type deleterQuery struct {
 	table string
 	limit int
 
+	from fromTables
+
 	where     *exql.Where
 	whereArgs []interface{}
 
+	returning []exql.Fragment
+
 	amendFn func(string) string
 }
 
@@ -30,12 +34,29 @@ func (dq *deleterQuery) and(b *sqlBuilder, terms ...interface{}) error {
 	return nil
 }
 
+// scope restricts the statement to the rows of the scoped tables it deletes
+// from and reads.
+func (dq *deleterQuery) scope(b *sqlBuilder) error {
+	conds, err := dq.from.where(b, dq.table)
+	if err != nil || len(conds) == 0 {
+		return err
+	}
+	return dq.and(b, conds...)
+}
+
 func (dq *deleterQuery) statement() *exql.Statement {
 	stmt := &exql.Statement{
 		Type:  exql.Delete,
 		Table: exql.TableWithName(dq.table),
 	}
 
+	if !dq.from.empty() {
+		stmt.From = &exql.DeleteUsing{
+			Tables: dq.from.tables,
+			Joins:  dq.from.joinConditions(),
+		}
+	}
+
 	if dq.where != nil {
 		stmt.Where = dq.where
 	}
@@ -44,6 +65,10 @@ func (dq *deleterQuery) statement() *exql.Statement {
 		stmt.Limit = exql.Limit(dq.limit)
 	}
 
+	if len(dq.returning) > 0 {
+		stmt.Returning = exql.ReturningColumns(dq.returning...)
+	}
+
 	stmt.SetAmendment(dq.amendFn)
 
 	return stmt
@@ -70,7 +95,7 @@ func (del *deleter) template() *exql.Template {
 }
 
 func (del *deleter) String() string {
-	s, err := del.Compile()
+	s, err := del.compile()
 	if err != nil {
 		panic(err.Error())
 	}
@@ -101,6 +126,30 @@ func (del *deleter) And(terms ...interface{}) Deleter {
 	})
 }
 
+func (del *deleter) Using(tables ...interface{}) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		return dq.from.pushTables(del.SQLBuilder(), tables)
+	})
+}
+
+func (del *deleter) Join(tables ...interface{}) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		return dq.from.pushJoin(del.SQLBuilder(), "", tables)
+	})
+}
+
+func (del *deleter) LeftJoin(tables ...interface{}) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		return dq.from.pushJoin(del.SQLBuilder(), "LEFT", tables)
+	})
+}
+
+func (del *deleter) On(terms ...interface{}) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		return dq.from.on(del.SQLBuilder(), terms)
+	})
+}
+
 func (del *deleter) Limit(limit int) Deleter {
 	return del.frame(func(dq *deleterQuery) error {
 		dq.limit = limit
@@ -108,6 +157,26 @@ func (del *deleter) Limit(limit int) Deleter {
 	})
 }
 
+func (del *deleter) Returning(columns ...string) Deleter {
+	return del.frame(func(dq *deleterQuery) error {
+		columnsToFragments(&dq.returning, columns)
+		return nil
+	})
+}
+
+func (del *deleter) Iterator() Iterator {
+	return del.IteratorContext(del.SQLBuilder().sess.Context())
+}
+
+func (del *deleter) IteratorContext(ctx context.Context) Iterator {
+	dq, err := del.build(ctx)
+	if err != nil {
+		return &iterator{del.SQLBuilder().sess, nil, err}
+	}
+	rows, err := del.SQLBuilder().sess.StatementQuery(ctx, dq.statement(), dq.arguments()...)
+	return &iterator{del.SQLBuilder().sess, rows, err}
+}
+
 func (del *deleter) Amend(fn func(string) string) Deleter {
 	return del.frame(func(dq *deleterQuery) error {
 		dq.amendFn = fn
@@ -116,11 +185,11 @@ func (del *deleter) Amend(fn func(string) string) Deleter {
 }
 
 func (dq *deleterQuery) arguments() []interface{} {
-	return joinArguments(dq.whereArgs)
+	return joinArguments(dq.from.arguments(), dq.whereArgs)
 }
 
 func (del *deleter) Arguments() []interface{} {
-	dq, err := del.build()
+	dq, err := del.build(nil)
 	if err != nil {
 		return nil
 	}
@@ -132,7 +201,7 @@ func (del *deleter) Prepare() (*sql.Stmt, error) {
 }
 
 func (del *deleter) PrepareContext(ctx context.Context) (*sql.Stmt, error) {
-	dq, err := del.build()
+	dq, err := del.build(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -144,7 +213,7 @@ func (del *deleter) Exec() (sql.Result, error) {
 }
 
 func (del *deleter) ExecContext(ctx context.Context) (sql.Result, error) {
-	dq, err := del.build()
+	dq, err := del.build(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -152,22 +221,35 @@ func (del *deleter) ExecContext(ctx context.Context) (sql.Result, error) {
 }
 
 func (del *deleter) statement() (*exql.Statement, error) {
-	iq, err := del.build()
+	iq, err := del.build(nil)
 	if err != nil {
 		return nil, err
 	}
 	return iq.statement(), nil
 }
 
-func (del *deleter) build() (*deleterQuery, error) {
-	dq, err := immutable.FastForward(del)
+func (del *deleter) build(ctx context.Context) (*deleterQuery, error) {
+	// Scopes are the ones of the context the statement runs within.
+	dqi, err := immutable.FastForwardFrom(del, &deleterQuery{from: fromTables{scopes: scopedTables{ctx: ctx}}})
 	if err != nil {
 		return nil, err
 	}
-	return dq.(*deleterQuery), nil
+	dq := dqi.(*deleterQuery)
+	if err := dq.scope(del.SQLBuilder()); err != nil {
+		return nil, err
+	}
+	return dq, nil
+}
+
+func (del *deleter) Compile() (string, []interface{}, error) {
+	dq, err := del.build(nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return del.SQLBuilder().compileStatement(dq.statement(), dq.arguments())
 }
 
-func (del *deleter) Compile() (string, error) {
+func (del *deleter) compile() (string, error) {
 	s, err := del.statement()
 	if err != nil {
 		return "", err
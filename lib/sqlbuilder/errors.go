@@ -11,4 +11,5 @@ var (
 	ErrExpectingSliceMapStruct             = errors.New(`argument must be a slice address of maps or structs`)
 	ErrExpectingMapOrStruct                = errors.New(`argument must be either a map or a struct`)
 	ErrExpectingPointerToEitherMapOrStruct = errors.New(`expecting a pointer to either a map or a struct`)
+	ErrExpectingSingleColumn               = errors.New(`scalar destinations require the query to return a single column`)
 )
@@ -0,0 +1,32 @@
+package sqlbuilder
+
+import (
+	"context"
+
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+// hasStatementExplain is implemented by sessions that can get the plan of a
+// statement.
+type hasStatementExplain interface {
+	StatementExplain(ctx context.Context, stmt *exql.Statement, args ...interface{}) (string, error)
+}
+
+type explainJSONKey struct{}
+
+// WithExplainJSON returns a copy of ctx that makes Explain return the plan in
+// JSON format, on the adapters that support it (PostgreSQL and MySQL):
+//
+//	plan, err := q.Explain(sqlbuilder.WithExplainJSON(ctx))
+func WithExplainJSON(ctx context.Context) context.Context {
+	return context.WithValue(ctx, explainJSONKey{}, true)
+}
+
+// ExplainJSON returns true if ctx was returned by WithExplainJSON.
+func ExplainJSON(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	json, _ := ctx.Value(explainJSONKey{}).(bool)
+	return json
+}
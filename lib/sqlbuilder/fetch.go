@@ -22,7 +22,9 @@
 package sqlbuilder
 
 import (
+	"database/sql"
 	"reflect"
+	"time"
 
 	"upper.io/db.v3"
 	"upper.io/db.v3/lib/reflectx"
@@ -34,6 +36,11 @@ type hasConvertValues interface {
 
 var mapper = reflectx.NewMapper("db")
 
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
 // fetchRow receives a *sql.Rows value and tries to map all the rows into a
 // single struct given by the pointer `dst`.
 func fetchRow(iter *iterator, dst interface{}) error {
@@ -82,7 +89,7 @@ func fetchRow(iter *iterator, dst interface{}) error {
 }
 
 // fetchRows receives a *sql.Rows value and tries to map all the rows into a
-// slice of structs given by the pointer `dst`.
+// slice of structs, maps or scalars given by the pointer `dst`.
 func fetchRows(iter *iterator, dst interface{}) error {
 	var err error
 	rows := iter.cursor
@@ -113,8 +120,9 @@ func fetchRows(iter *iterator, dst interface{}) error {
 
 	reset(dst)
 
+	m := rowMapper{iter: iter, itemT: itemT, columns: columns}
 	for rows.Next() {
-		item, err := fetchResult(iter, itemT, columns)
+		item, err := m.mapRow()
 		if err != nil {
 			return err
 		}
@@ -131,9 +139,53 @@ func fetchRows(iter *iterator, dst interface{}) error {
 }
 
 func fetchResult(iter *iterator, itemT reflect.Type, columns []string) (reflect.Value, error) {
+	m := rowMapper{iter: iter, itemT: itemT, columns: columns}
+	return m.mapRow()
+}
+
+// rowMapper maps the rows of an iterator into new values of itemT, what's
+// needed to map them is worked out with the first row and reused for the
+// next ones.
+type rowMapper struct {
+	iter    *iterator
+	itemT   reflect.Type
+	columns []string
+
+	prepared bool
+	scalar   bool
+	structs  *structScanner
+}
+
+func (m *rowMapper) mapRow() (reflect.Value, error) {
 	var item reflect.Value
-	var err error
-	rows := iter.cursor
+	rows := m.iter.cursor
+	itemT := m.itemT
+
+	if !m.prepared {
+		m.scalar = isScalar(sessionMapper(m.iter.sess), itemT) || sessionCodecs(m.iter.sess).Lookup(itemT) != nil
+		m.prepared = true
+	}
+
+	if m.scalar {
+		// Scalars are scanned from the only column of the row.
+		if len(m.columns) != 1 {
+			return item, ErrExpectingSingleColumn
+		}
+
+		item = reflect.New(itemT)
+		values := decodeValues(sessionCodecs(m.iter.sess), []interface{}{item.Interface()})
+		if converter, ok := m.iter.sess.(hasConvertValues); ok {
+			values = converter.ConvertValues(values)
+		}
+		if err := rows.Scan(values...); err != nil {
+			return item, err
+		}
+
+		if itemT.Kind() == reflect.Ptr {
+			item = item.Elem()
+		}
+		return item, nil
+	}
 
 	objT := itemT
 
@@ -155,45 +207,21 @@ func fetchResult(iter *iterator, itemT reflect.Type, columns []string) (reflect.
 	switch objT.Kind() {
 	case reflect.Struct:
 
-		values := make([]interface{}, len(columns))
-		typeMap := mapper.TypeMap(itemT)
-		fieldMap := typeMap.Names
-
-		for i, k := range columns {
-			fi, ok := fieldMap[k]
-			if !ok {
-				values[i] = new(interface{})
-				continue
-			}
-
-			// Check for deprecated jsonb tag.
-			if _, hasJSONBTag := fi.Options["jsonb"]; hasJSONBTag {
-				return item, errDeprecatedJSONBTag
-			}
-
-			f := reflectx.FieldByIndexes(item, fi.Index)
-			values[i] = f.Addr().Interface()
-
-			if u, ok := values[i].(db.Unmarshaler); ok {
-				values[i] = scanner{u}
+		if m.structs == nil {
+			structs, err := newStructScanner(m.iter, objT, m.columns)
+			if err != nil {
+				return item, err
 			}
+			m.structs = structs
 		}
 
-		if converter, ok := iter.sess.(hasConvertValues); ok {
-			values = converter.ConvertValues(values)
-		}
-
-		if err = rows.Scan(values...); err != nil {
+		m.structs.prepare(item)
+		if err := m.structs.scan(); err != nil {
 			return item, err
 		}
 	case reflect.Map:
 
-		columns, err := rows.Columns()
-		if err != nil {
-			return item, err
-		}
-
-		values := make([]interface{}, len(columns))
+		values := make([]interface{}, len(m.columns))
 		for i := range values {
 			if itemT.Elem().Kind() == reflect.Interface {
 				values[i] = new(interface{})
@@ -202,11 +230,11 @@ func fetchResult(iter *iterator, itemT reflect.Type, columns []string) (reflect.
 			}
 		}
 
-		if err = rows.Scan(values...); err != nil {
+		if err := rows.Scan(decodeValues(sessionCodecs(m.iter.sess), values)...); err != nil {
 			return item, err
 		}
 
-		for i, column := range columns {
+		for i, column := range m.columns {
 			item.SetMapIndex(reflect.ValueOf(column), reflect.Indirect(reflect.ValueOf(values[i])))
 		}
 	}
@@ -214,6 +242,172 @@ func fetchResult(iter *iterator, itemT reflect.Type, columns []string) (reflect.
 	return item, nil
 }
 
+// structScanner scans rows into structs of a type, the fields the columns
+// are mapped to are looked up once.
+type structScanner struct {
+	iter   *iterator
+	typ    reflect.Type
+	fields []*reflectx.FieldInfo
+	codecs []*db.Codec
+
+	values    []interface{}
+	converted []interface{}
+	discard   interface{}
+}
+
+func newStructScanner(iter *iterator, t reflect.Type, columns []string) (*structScanner, error) {
+	fields := sessionMapper(iter.sess).ColumnFields(t, columns)
+	codecs := make([]*db.Codec, len(fields))
+	copied := false
+	for i, fi := range fields {
+		if fi == nil {
+			continue
+		}
+		// Check for deprecated jsonb tag.
+		if _, hasJSONBTag := fi.Options["jsonb"]; hasJSONBTag {
+			return nil, errDeprecatedJSONBTag
+		}
+		// Fields tagged with writeonly are never scanned, fields is the plan
+		// the mapper keeps so it's copied before leaving them out.
+		if _, writeOnly := fi.Options["writeonly"]; writeOnly {
+			if !copied {
+				fields = append([]*reflectx.FieldInfo(nil), fields...)
+				copied = true
+			}
+			fields[i] = nil
+			continue
+		}
+		if codec := sessionCodecs(iter.sess).Lookup(fi.Field.Type); codec != nil && codec.Decode != nil {
+			codecs[i] = codec
+		}
+	}
+	return &structScanner{
+		iter:   iter,
+		typ:    t,
+		fields: fields,
+		codecs: codecs,
+		values: make([]interface{}, len(columns)),
+	}, nil
+}
+
+// prepare makes the next rows be scanned into the fields of the struct item
+// points to, values of columns that are not mapped are discarded.
+func (s *structScanner) prepare(item reflect.Value) {
+	for i, fi := range s.fields {
+		if fi == nil {
+			s.values[i] = &s.discard
+			continue
+		}
+
+		dest := reflectx.FieldByIndexes(item, fi.Index).Addr().Interface()
+		if _, ok := fi.Options["asjson"]; ok {
+			dest = jsonScanner{dest}
+		} else if s.codecs[i] != nil {
+			dest = codecScanner{s.codecs[i], dest}
+		} else if u, ok := dest.(db.Unmarshaler); ok {
+			dest = scanner{u}
+		}
+		s.values[i] = dest
+	}
+
+	s.converted = s.values
+	if converter, ok := s.iter.sess.(hasConvertValues); ok {
+		s.converted = converter.ConvertValues(s.values)
+	}
+}
+
+// scan scans the current row into the struct given to prepare.
+func (s *structScanner) scan() error {
+	return s.iter.cursor.Scan(s.converted...)
+}
+
+// rowScanner reads the rows of an iterator for ForEach, the destinations of
+// the fields of the last struct it was given are kept to read the next rows
+// into the same struct.
+type rowScanner struct {
+	iter    *iterator
+	columns []string
+	dest    interface{}
+	structs *structScanner
+}
+
+func (s *rowScanner) scan(dest ...interface{}) error {
+	rows := s.iter.cursor
+	codecs := sessionCodecs(s.iter.sess)
+	if len(dest) != 1 {
+		return rows.Scan(decodeValues(codecs, dest)...)
+	}
+	if s.dest != nil && dest[0] == s.dest {
+		return s.structs.scan()
+	}
+
+	v := reflect.ValueOf(dest[0])
+	if v.Kind() != reflect.Ptr || v.IsNil() || isScalar(sessionMapper(s.iter.sess), v.Type().Elem()) || codecs.Lookup(v.Type().Elem()) != nil {
+		return rows.Scan(decodeValues(codecs, dest)...)
+	}
+
+	if s.columns == nil {
+		columns, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		s.columns = columns
+	}
+
+	switch v.Elem().Kind() {
+	case reflect.Struct:
+		if s.structs == nil || s.structs.typ != v.Elem().Type() {
+			structs, err := newStructScanner(s.iter, v.Elem().Type(), s.columns)
+			if err != nil {
+				return err
+			}
+			s.structs = structs
+		}
+		s.structs.prepare(v)
+		s.dest = dest[0]
+		return s.structs.scan()
+	case reflect.Map:
+		item, err := fetchResult(s.iter, v.Elem().Type(), s.columns)
+		if err != nil {
+			return err
+		}
+		v.Elem().Set(item)
+		return nil
+	}
+	return ErrExpectingMapOrStruct
+}
+
+// isScalar returns true if values of type t are scanned from a single column,
+// rather than mapped from the columns of a row: basic types, []byte,
+// time.Time, sql.Scanner implementations and pointers to them. Structs with
+// tags of the given mapper are always mapped from the columns of the row.
+func isScalar(m *reflectx.Mapper, t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType || reflect.PtrTo(t).Implements(scannerType) {
+		if t.Kind() != reflect.Struct {
+			return true
+		}
+		for _, fi := range m.TypeMap(t).Index {
+			if fi.Field.Tag.Get(m.TagName()) != "" {
+				return false
+			}
+		}
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Interface,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
 func reset(data interface{}) error {
 	// Resetting element.
 	v := reflect.ValueOf(data).Elem()
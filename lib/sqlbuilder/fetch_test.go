@@ -0,0 +1,80 @@
+package sqlbuilder
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+)
+
+type scannerRow struct {
+	ID int64 `db:"id"`
+}
+
+func (*scannerRow) Scan(interface{}) error {
+	return nil
+}
+
+func TestIsScalar(t *testing.T) {
+	var i int64
+	scalars := []interface{}{
+		int64(0), &i, "", true, 1.5, []byte{}, time.Time{}, &time.Time{},
+		sql.NullString{}, &sql.NullInt64{}, new(interface{}),
+	}
+	for _, v := range scalars {
+		assert.True(t, isScalar(mapper, reflect.TypeOf(v)), reflect.TypeOf(v).String())
+	}
+
+	rows := []interface{}{
+		struct{ Name string }{}, &struct{}{}, map[string]interface{}{}, []string{},
+		// Scanners with db tags are mapped from the columns.
+		scannerRow{}, &scannerRow{},
+	}
+	for _, v := range rows {
+		assert.False(t, isScalar(mapper, reflect.TypeOf(v)), reflect.TypeOf(v).String())
+	}
+}
+
+func TestDecodeValues(t *testing.T) {
+	type point struct {
+		X, Y int
+	}
+
+	codecs := db.NewCodecs()
+	codecs.Register(point{}, db.Codec{
+		Decode: func(src interface{}, dst interface{}) error {
+			if src == nil {
+				*dst.(*point) = point{}
+				return nil
+			}
+			_, err := fmt.Sscanf(string(src.([]byte)), "%d,%d", &dst.(*point).X, &dst.(*point).Y)
+			return err
+		},
+	})
+
+	var (
+		p    point
+		name string
+	)
+	values := []interface{}{&name, &p}
+
+	assert.Equal(t, values, decodeValues(nil, values))
+
+	decoded := decodeValues(codecs, values)
+	assert.Equal(t, &name, decoded[0])
+	assert.Equal(t, &p, values[1])
+
+	assert.NoError(t, decoded[1].(sql.Scanner).Scan([]byte("3,4")))
+	assert.Equal(t, point{3, 4}, p)
+
+	assert.NoError(t, decoded[1].(sql.Scanner).Scan(nil))
+	assert.Equal(t, point{}, p)
+
+	// Codecs without Decode leave values to be scanned as usual.
+	codecs.Register("", db.Codec{})
+	assert.Equal(t, &name, decodeValues(codecs, values)[0])
+}
@@ -0,0 +1,108 @@
+package sqlbuilder
+
+import (
+	"errors"
+
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+// fromTables holds the tables an UPDATE or DELETE statement reads from
+// besides its own table, along with the tables joined to them.
+type fromTables struct {
+	tables     *exql.Columns
+	tablesArgs []interface{}
+
+	joins     []*exql.Join
+	joinsArgs []interface{}
+
+	scopes scopedTables
+}
+
+func (ft *fromTables) pushTables(b *sqlBuilder, tables []interface{}) error {
+	fragments, args, err := columnFragments(tables)
+	if err != nil {
+		return err
+	}
+	if err := ft.scopes.pushTables(b, tables); err != nil {
+		return err
+	}
+	ft.tables = exql.JoinColumns(fragments...)
+	ft.tablesArgs = args
+	return nil
+}
+
+func (ft *fromTables) pushJoin(b *sqlBuilder, t string, tables []interface{}) error {
+	fragments, args, err := columnFragments(tables)
+	if err != nil {
+		return err
+	}
+	join := &exql.Join{
+		Type:  t,
+		Table: exql.JoinColumns(fragments...),
+	}
+	if err := ft.scopes.pushJoin(b, join, tables); err != nil {
+		return err
+	}
+	ft.joins = append(ft.joins, join)
+	ft.joinsArgs = append(ft.joinsArgs, args...)
+	return nil
+}
+
+func (ft *fromTables) on(b *sqlBuilder, terms []interface{}) error {
+	if len(ft.joins) == 0 {
+		return errors.New(`cannot use On() without a preceding Join() expression`)
+	}
+
+	lastJoin := ft.joins[len(ft.joins)-1]
+	if lastJoin.On != nil {
+		return errors.New(`cannot use On() twice with the same Join() expression`)
+	}
+
+	w, a := b.t.toWhereWithArguments(terms)
+	a = ft.scopes.on(b, lastJoin, &w, a)
+	o := exql.On(w)
+
+	lastJoin.On = &o
+	ft.joinsArgs = append(ft.joinsArgs, a...)
+
+	return nil
+}
+
+// where returns the conditions that restrict the rows of the given table of
+// the statement and of the scoped tables it reads from, see scopedTables.
+func (ft *fromTables) where(b *sqlBuilder, table string) ([]interface{}, error) {
+	tables := 1
+	if ft.tables != nil {
+		tables += len(ft.tables.Columns)
+	}
+	conds, err := ft.scopes.where(tables, ft.joins)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := b.tableScope(ft.scopes.ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	if scope != nil {
+		_, alias := splitTableAlias(table)
+		main := scopedTable{alias: alias, scope: scope}
+		conds = append([]interface{}{main.cond(tables > 1 || len(ft.joins) > 0)}, conds...)
+	}
+	return conds, nil
+}
+
+func (ft *fromTables) empty() bool {
+	return ft.tables == nil && len(ft.joins) == 0
+}
+
+func (ft *fromTables) joinConditions() *exql.Joins {
+	if len(ft.joins) == 0 {
+		return nil
+	}
+	return exql.JoinConditions(ft.joins...)
+}
+
+func (ft *fromTables) arguments() []interface{} {
+	return joinArguments(ft.tablesArgs, ft.joinsArgs)
+}
@@ -0,0 +1,66 @@
+package sqlbuilder
+
+import (
+	"time"
+)
+
+// HealthReport is the result of Database.HealthCheck. It can be used to
+// implement readiness probes:
+//
+//	http.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+//		ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+//		defer cancel()
+//
+//		report := sess.HealthCheck(ctx)
+//		if !report.Healthy() {
+//			http.Error(w, report.Err().Error(), http.StatusServiceUnavailable)
+//			return
+//		}
+//		fmt.Fprintf(w, "ok (%v)", report.Latency)
+//	})
+type HealthReport struct {
+	// Latency is the round-trip time of a ping to the primary server.
+	Latency time.Duration
+
+	// PingErr is the error returned by the ping to the primary server, if
+	// any.
+	PingErr error
+
+	// Replicas holds the health of the read-only replicas of the session, in
+	// the order they were given to OpenCluster.
+	Replicas []ReplicaHealth
+}
+
+// ReplicaHealth is the health of a read-only replica.
+type ReplicaHealth struct {
+	// Latency is the round-trip time of a ping to the replica.
+	Latency time.Duration
+
+	// Lag is how far the replica is behind the primary server. It's only
+	// measured by adapters that know how to ask, the others leave it at
+	// zero.
+	Lag time.Duration
+
+	// Err is the error returned while checking the replica, if any.
+	Err error
+}
+
+// Healthy returns true if the primary server and all the replicas were
+// reached.
+func (r HealthReport) Healthy() bool {
+	return r.Err() == nil
+}
+
+// Err returns the first error found while checking the primary server and
+// the replicas.
+func (r HealthReport) Err() error {
+	if r.PingErr != nil {
+		return r.PingErr
+	}
+	for i := range r.Replicas {
+		if r.Replicas[i].Err != nil {
+			return r.Replicas[i].Err
+		}
+	}
+	return nil
+}
@@ -3,29 +3,62 @@ package sqlbuilder
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
 	"upper.io/db.v3/internal/immutable"
 	"upper.io/db.v3/internal/sqladapter/exql"
 )
 
+var (
+	errValuesAndSelect   = errors.New("Values() and ValuesFromSelect() can't be used on the same statement")
+	errScopedInsert      = errors.New("ValuesFromSelect() can't insert into a scoped table")
+	errScopedInsertNames = errors.New("an insert into a scoped table requires the names of its columns")
+)
+
 type inserterQuery struct {
 	table          string
 	enqueuedValues [][]interface{}
 	returning      []exql.Fragment
 	columns        []exql.Fragment
 	values         []*exql.Values
+	query          exql.Fragment
+	queryArgs      []interface{}
 	arguments      []interface{}
 	extra          string
 	amendFn        func(string) string
+
+	onConflict      bool
+	conflictColumns []exql.Fragment
+	conflictUpdate  bool
+	conflictValues  []exql.Fragment
+	conflictArgs    []interface{}
 }
 
-func (iq *inserterQuery) processValues() ([]*exql.Values, []interface{}, error) {
+func (iq *inserterQuery) processValues(now time.Time, options MapOptions, scope *tableScope) ([]*exql.Values, []interface{}, error) {
 	var values []*exql.Values
 	var arguments []interface{}
 
-	var mapOptions *MapOptions
+	// scopeIndex is the position of the column of the scope among the columns
+	// given with Columns(), rows given as lists of values have it set there.
+	scopeIndex := -1
+	if scope != nil && len(iq.columns) > 0 {
+		scopeIndex = len(iq.columns)
+		for i := range iq.columns {
+			if c, ok := iq.columns[i].(*exql.Column); ok && c.Name == scope.column {
+				scopeIndex = i
+			}
+		}
+		if scopeIndex == len(iq.columns) {
+			iq.columns = append(iq.columns, exql.ColumnWithName(scope.column))
+		}
+	}
+
+	mapOptions := &options
+	mapOptions.AutoNow, mapOptions.AutoNowAdd = now, now
 	if len(iq.enqueuedValues) > 1 {
-		mapOptions = &MapOptions{IncludeZeroed: true, IncludeNil: true}
+		mapOptions.IncludeZeroed, mapOptions.IncludeNil = true, true
 	}
 
 	for _, enqueuedValue := range iq.enqueuedValues {
@@ -34,6 +67,10 @@ func (iq *inserterQuery) processValues() ([]*exql.Values, []interface{}, error)
 			ff, vv, err := Map(enqueuedValue[0], mapOptions)
 
 			if err == nil {
+				if scope != nil {
+					ff, vv = scope.assign(ff, vv)
+				}
+
 				// If we didn't have any problem with mapping we can convert it into
 				// columns and values.
 				columns, vals, args, _ := toColumnsValuesAndArguments(ff, vv)
@@ -55,6 +92,20 @@ func (iq *inserterQuery) processValues() ([]*exql.Values, []interface{}, error)
 			}
 		}
 
+		if scope != nil {
+			if scopeIndex < 0 {
+				return nil, nil, errScopedInsertNames
+			}
+			row := make([]interface{}, len(enqueuedValue), len(enqueuedValue)+1)
+			copy(row, enqueuedValue)
+			if scopeIndex < len(row) {
+				row[scopeIndex] = scope.value
+			} else {
+				row = append(row, scope.value)
+			}
+			enqueuedValue = row
+		}
+
 		if len(iq.columns) == 0 || len(enqueuedValue) == len(iq.columns) {
 			arguments = append(arguments, enqueuedValue...)
 
@@ -80,10 +131,26 @@ func (iq *inserterQuery) statement() *exql.Statement {
 		stmt.Values = exql.JoinValueGroups(iq.values...)
 	}
 
+	if iq.query != nil {
+		stmt.Query = iq.query
+	}
+
 	if len(iq.columns) > 0 {
 		stmt.Columns = exql.JoinColumns(iq.columns...)
 	}
 
+	if iq.onConflict {
+		onConflict := &exql.OnConflict{
+			Target:  exql.JoinColumns(iq.conflictColumns...),
+			Columns: exql.JoinColumns(iq.columns...),
+			Update:  iq.conflictUpdate,
+		}
+		if len(iq.conflictValues) > 0 {
+			onConflict.ColumnValues = exql.JoinColumnValues(iq.conflictValues...)
+		}
+		stmt.OnConflict = onConflict
+	}
+
 	if len(iq.returning) > 0 {
 		stmt.Returning = exql.ReturningColumns(iq.returning...)
 	}
@@ -114,7 +181,7 @@ func (ins *inserter) template() *exql.Template {
 }
 
 func (ins *inserter) String() string {
-	s, err := ins.Compile()
+	s, err := ins.compile()
 	if err != nil {
 		panic(err.Error())
 	}
@@ -137,7 +204,7 @@ func (ins *inserter) Amend(fn func(string) string) Inserter {
 }
 
 func (ins *inserter) Arguments() []interface{} {
-	iq, err := ins.build()
+	iq, err := ins.build(nil)
 	if err != nil {
 		return nil
 	}
@@ -151,12 +218,40 @@ func (ins *inserter) Returning(columns ...string) Inserter {
 	})
 }
 
+func (ins *inserter) OnConflict(columns ...string) Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		iq.onConflict = true
+		columnsToFragments(&iq.conflictColumns, columns)
+		return nil
+	})
+}
+
+func (ins *inserter) DoUpdate(terms ...interface{}) Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		iq.onConflict, iq.conflictUpdate = true, true
+		if len(terms) > 0 {
+			cvs, args := ins.SQLBuilder().t.toAssignments(terms, ins.SQLBuilder().now())
+			iq.conflictValues = append(iq.conflictValues, cvs...)
+			iq.conflictArgs = append(iq.conflictArgs, args...)
+		}
+		return nil
+	})
+}
+
+func (ins *inserter) DoNothing() Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		iq.onConflict, iq.conflictUpdate = true, false
+		iq.conflictValues, iq.conflictArgs = nil, nil
+		return nil
+	})
+}
+
 func (ins *inserter) Exec() (sql.Result, error) {
 	return ins.ExecContext(ins.SQLBuilder().sess.Context())
 }
 
 func (ins *inserter) ExecContext(ctx context.Context) (sql.Result, error) {
-	iq, err := ins.build()
+	iq, err := ins.build(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -168,7 +263,7 @@ func (ins *inserter) Prepare() (*sql.Stmt, error) {
 }
 
 func (ins *inserter) PrepareContext(ctx context.Context) (*sql.Stmt, error) {
-	iq, err := ins.build()
+	iq, err := ins.build(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -180,7 +275,7 @@ func (ins *inserter) Query() (*sql.Rows, error) {
 }
 
 func (ins *inserter) QueryContext(ctx context.Context) (*sql.Rows, error) {
-	iq, err := ins.build()
+	iq, err := ins.build(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -192,7 +287,7 @@ func (ins *inserter) QueryRow() (*sql.Row, error) {
 }
 
 func (ins *inserter) QueryRowContext(ctx context.Context) (*sql.Row, error) {
-	iq, err := ins.build()
+	iq, err := ins.build(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -229,28 +324,64 @@ func (ins *inserter) Values(values ...interface{}) Inserter {
 	})
 }
 
+func (ins *inserter) ValuesFromSelect(sel Selector) Inserter {
+	return ins.frame(func(iq *inserterQuery) error {
+		c, ok := sel.(compilable)
+		if !ok {
+			return fmt.Errorf("unexpected argument type %T for ValuesFromSelect() argument", sel)
+		}
+		q, err := c.compile()
+		if err != nil {
+			return err
+		}
+		q, iq.queryArgs = Preprocess(q, c.Arguments())
+		iq.query = exql.RawValue(q)
+		return nil
+	})
+}
+
 func (ins *inserter) statement() (*exql.Statement, error) {
-	iq, err := ins.build()
+	iq, err := ins.build(nil)
 	if err != nil {
 		return nil, err
 	}
 	return iq.statement(), nil
 }
 
-func (ins *inserter) build() (*inserterQuery, error) {
+func (ins *inserter) build(ctx context.Context) (*inserterQuery, error) {
 	iq, err := immutable.FastForward(ins)
 	if err != nil {
 		return nil, err
 	}
 	ret := iq.(*inserterQuery)
-	ret.values, ret.arguments, err = ret.processValues()
+	if ret.query != nil && len(ret.enqueuedValues) > 0 {
+		return nil, errValuesAndSelect
+	}
+	scope, err := ins.SQLBuilder().tableScope(ctx, ret.table)
 	if err != nil {
 		return nil, err
 	}
+	if scope != nil && ret.query != nil {
+		return nil, errScopedInsert
+	}
+	ret.values, ret.arguments, err = ret.processValues(ins.SQLBuilder().now(), sessionMapOptions(ins.SQLBuilder().sess), scope)
+	if err != nil {
+		return nil, err
+	}
+	ret.arguments = append(ret.arguments, ret.queryArgs...)
+	ret.arguments = append(ret.arguments, ret.conflictArgs...)
 	return ret, nil
 }
 
-func (ins *inserter) Compile() (string, error) {
+func (ins *inserter) Compile() (string, []interface{}, error) {
+	iq, err := ins.build(nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return ins.SQLBuilder().compileStatement(iq.statement(), iq.arguments)
+}
+
+func (ins *inserter) compile() (string, error) {
 	s, err := ins.statement()
 	if err != nil {
 		return "", err
@@ -25,6 +25,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"reflect"
+	"time"
+
+	"upper.io/db.v3"
 )
 
 // SQLBuilder defines methods that can be used to build a SQL query with
@@ -82,12 +86,53 @@ type SQLBuilder interface {
 	//  q := sqlbuilder.Update("profile").Set(...).Where(...)
 	Update(table string) Updater
 
+	// CreateTable prepares a TableCreator that creates the given table.
+	//
+	// Example:
+	//
+	//  q := sqlbuilder.CreateTable("books").Columns(
+	//    sqlbuilder.NewColumn("id", sqlbuilder.Integer).PrimaryKey().AutoIncrement(),
+	//    sqlbuilder.NewColumn("title", sqlbuilder.String(80)).NotNull(),
+	//  )
+	CreateTable(table string) TableCreator
+
+	// AlterTable prepares a TableAlterer that modifies the given table.
+	//
+	// Example:
+	//
+	//  q := sqlbuilder.AlterTable("books").DropColumn("isbn")
+	AlterTable(table string) TableAlterer
+
+	// CreateIndex prepares an IndexCreator that creates an index with the
+	// given name.
+	//
+	// Example:
+	//
+	//  q := sqlbuilder.CreateIndex("books_title_idx").On("books", "title")
+	CreateIndex(name string) IndexCreator
+
+	// Call prepares a Caller that runs the given stored procedure with the
+	// given arguments, use Out and InOut for OUT and INOUT parameters.
+	//
+	// Example:
+	//
+	//  var total int
+	//  _, err := sqlbuilder.Call("order_total", orderID, sqlbuilder.Out(&total)).Exec()
+	Call(procedure string, args ...interface{}) Caller
+
 	// Exec executes a SQL query that does not return any rows, like sql.Exec.
 	// Queries can be either strings or upper-db statements.
 	//
 	// Example:
 	//
 	//  sqlbuilder.Exec(`INSERT INTO books (title) VALUES("La Ciudad y los Perros")`)
+	//
+	// String queries may use :name placeholders instead of positional ones
+	// when the only argument is a struct or a map with string keys, the value
+	// of each one is taken from the field tagged with its name, or from the
+	// key with its name. The same goes for Query, QueryRow and Iterator.
+	//
+	//  sqlbuilder.Exec(`UPDATE books SET title = :title WHERE id = :id`, book)
 	Exec(query interface{}, args ...interface{}) (sql.Result, error)
 
 	// ExecContext executes a SQL query that does not return any rows, like sql.ExecContext.
@@ -183,6 +228,18 @@ type Selector interface {
 	// The above statement is equivalent to:
 	//
 	//   s.Columns(sqlbuilder.Func("DATABASE_NAME"))
+	//
+	// When fetching into a struct with fields tagged with inline=alias, an
+	// "alias.*" column is expanded into the columns of that field, so joined
+	// rows can be scanned into nested structs:
+	//
+	//   var rows []struct {
+	//     User    User    `db:",inline=u"`
+	//     Account Account `db:",inline=a"`
+	//   }
+	//
+	//   err := s.Columns("u.*", "a.*").From("users AS u").
+	//     Join("accounts AS a").On("a.user_id = u.id").All(&rows)
 	Columns(columns ...interface{}) Selector
 
 	// From represents a FROM clause and is tipically used after Columns().
@@ -207,9 +264,125 @@ type Selector interface {
 	// different.
 	Distinct(columns ...interface{}) Selector
 
+	// DistinctOn represents a DISTINCT ON clause, which keeps only the first
+	// row of each set of rows with the same value on the given columns:
+	//
+	//   s.Select("user_id", "created_at").DistinctOn("user_id").
+	//     From("visits").OrderBy("user_id", "-created_at")
+	//
+	// Only PostgreSQL and CockroachDB support DISTINCT ON, compiling a
+	// Selector with DistinctOn on any other database fails with
+	// db.ErrUnsupported.
+	DistinctOn(columns ...interface{}) Selector
+
 	// As defines an alias for a table.
 	As(string) Selector
 
+	// With defines a common table expression (CTE) that can be referenced by
+	// name within the rest of the statement. The query can be a Selector or a
+	// db.RawValue.
+	//
+	//   s.With("recent", sess.Select("id").From("posts").Where("created_at > ?", t)).
+	//     Columns("*").From("recent")
+	//
+	// A list of column names can be given along with the name:
+	//
+	//   s.With("totals(author_id, total)", ...)
+	//
+	// Subsequent calls to With() append more CTEs to the WITH clause.
+	With(name string, query interface{}) Selector
+
+	// WithRecursive is like With() but marks the WITH clause as RECURSIVE,
+	// which allows the query to refer to its own name. Recursive queries
+	// usually combine two queries with UNION ALL, so they're typically given
+	// as a db.RawValue:
+	//
+	//   s.WithRecursive("t(n)", db.Raw("SELECT 1 UNION ALL SELECT n+1 FROM t WHERE n < ?", 10)).
+	//     Columns("n").From("t")
+	WithRecursive(name string, query interface{}) Selector
+
+	// Union combines the results of the current query with the results of the
+	// given selector, removing duplicated rows.
+	//
+	//   s.Select("name").From("artists").
+	//     Union(s.Select("name").From("bands")).
+	//     OrderBy("name").
+	//     Limit(10)
+	//
+	// Each query is enclosed in parentheses, calls to OrderBy(), Limit() and
+	// Offset() that follow Union() apply to the whole compound statement.
+	// Clauses that belong to a single query, like Where() or GroupBy(), can't
+	// follow it, the compound statement has to be selected from instead:
+	//
+	//   s.SelectFrom(q.Union(other)).As("u").Where("name LIKE ?", "A%")
+	Union(other Selector) Selector
+
+	// UnionAll is like Union() but keeps duplicated rows.
+	UnionAll(other Selector) Selector
+
+	// Intersect keeps only the rows that are returned by both the current query
+	// and the given selector.
+	Intersect(other Selector) Selector
+
+	// Except keeps only the rows of the current query that are not returned by
+	// the given selector.
+	Except(other Selector) Selector
+
+	// ForUpdate locks the selected rows as if they were going to be updated,
+	// other transactions can't modify or lock them until the current
+	// transaction ends.
+	//
+	//   tx.SelectFrom("jobs").Where("status = ?", "pending").
+	//     Limit(1).
+	//     ForUpdate().
+	//     SkipLocked()
+	//
+	// On MSSQL locks are requested with table hints: WITH (UPDLOCK, ROWLOCK).
+	ForUpdate() Selector
+
+	// ForShare locks the selected rows in shared mode, other transactions can
+	// read them but can't modify them until the current transaction ends.
+	ForShare() Selector
+
+	// AsOf represents an AS OF SYSTEM TIME clause, the query reads the data as
+	// it was at the given time. The timestamp can be a time.Time, a negative
+	// time.Duration relative to the current time, a string or a db.RawValue:
+	//
+	//   s.SelectFrom("accounts").AsOf(-10 * time.Second)
+	//   s.SelectFrom("accounts").AsOf(db.Raw("follower_read_timestamp()"))
+	//
+	// Only CockroachDB supports AS OF SYSTEM TIME, compiling a Selector with
+	// AsOf on any other database fails with db.ErrUnsupported.
+	AsOf(timestamp interface{}) Selector
+
+	// Final represents the FINAL modifier, which makes ClickHouse merge the
+	// rows of the table before reading them:
+	//
+	//   s.SelectFrom("visits").Final()
+	//
+	// Compiling a Selector with Final on any other database fails with
+	// db.ErrUnsupported.
+	Final() Selector
+
+	// LimitBy represents a LIMIT n BY clause, which keeps at most limit rows
+	// for each distinct value of the given columns:
+	//
+	//   s.SelectFrom("visits").OrderBy("-created_at").LimitBy(3, "user_id")
+	//
+	// Only ClickHouse supports LIMIT BY, compiling a Selector with LimitBy on
+	// any other database fails with db.ErrUnsupported.
+	LimitBy(limit int, columns ...string) Selector
+
+	// SkipLocked makes the statement skip rows that are locked by other
+	// transactions instead of waiting for them. Rows are locked for update
+	// unless ForShare() is used.
+	SkipLocked() Selector
+
+	// NoWait makes the statement fail instead of waiting for rows that are
+	// locked by other transactions. Rows are locked for update unless
+	// ForShare() is used.
+	NoWait() Selector
+
 	// Where specifies the conditions that columns must match in order to be
 	// retrieved.
 	//
@@ -346,6 +519,31 @@ type Selector interface {
 	// database server.
 	Amend(func(queryIn string) (queryOut string)) Selector
 
+	// Timeout limits how long the query may take, it fails with
+	// db.ErrQueryTimeout when it's exceeded. It replaces the MaxQueryDuration
+	// of the session, see WithQueryTimeout.
+	//
+	// s.Timeout(2 * time.Second)
+	Timeout(time.Duration) Selector
+
+	// Cursor makes the query read its rows through a server-side cursor with
+	// the given name, fetchSize rows at a time, rather than all at once. The
+	// cursor is declared within the transaction of the session, or within one
+	// of its own that ends when the rows are closed. Iterator, All and One
+	// read the rows as usual. It fails with db.ErrUnsupported if the adapter
+	// doesn't support cursors (only PostgreSQL does).
+	//
+	// iter := s.Cursor("all_artists", 500).Iterator()
+	Cursor(name string, fetchSize int) Selector
+
+	// Explain returns the plan the database would use to run the query, with
+	// the same statement and arguments, without running it. The plan is
+	// returned as text unless ctx was given by WithExplainJSON. It fails with
+	// db.ErrUnsupported if the adapter can't explain queries.
+	//
+	// plan, err := s.Explain(ctx)
+	Explain(ctx context.Context) (string, error)
+
 	// Paginate returns a paginator that can display a paginated lists of items.
 	// Paginators ignore previous Offset and Limit settings. Page numbering
 	// starts at 1.
@@ -369,6 +567,15 @@ type Selector interface {
 	// ResultMapper provides methods to retrieve and map results.
 	ResultMapper
 
+	// AllContext is like All() but the query runs within the given context.
+	AllContext(ctx context.Context, destSlice interface{}) error
+
+	// OneContext is like One() but the query runs within the given context.
+	OneContext(ctx context.Context, dest interface{}) error
+
+	// Compiler provides the Compile method.
+	Compiler
+
 	// fmt.Stringer provides `String() string`, you can use `String()` to compile
 	// the `Selector` into a string.
 	fmt.Stringer
@@ -395,6 +602,17 @@ type Inserter interface {
 	//   i.Values(map[string][string]{"name": "María"})
 	Values(...interface{}) Inserter
 
+	// ValuesFromSelect inserts the rows returned by the given selector instead
+	// of a VALUES clause, the selected columns are matched against Columns()
+	// by position:
+	//
+	//   i.Columns("id", "title").ValuesFromSelect(
+	//     s.Select("id", "title").From("posts").Where("created_at < ?", t),
+	//   )
+	//
+	// ValuesFromSelect can't be combined with Values().
+	ValuesFromSelect(sel Selector) Inserter
+
 	// Arguments returns the arguments that are prepared for this query.
 	Arguments() []interface{}
 
@@ -405,6 +623,28 @@ type Inserter interface {
 	// RETURNING may not be supported by all SQL databases.
 	Returning(columns ...string) Inserter
 
+	// OnConflict turns the INSERT statement into an upsert. The given columns
+	// (usually a primary key or a unique index) identify rows that already
+	// exist, use DoUpdate() or DoNothing() to define what happens to them.
+	//
+	//   i.Values(item).OnConflict("id").DoUpdate()
+	//
+	// OnConflict is compiled into ON CONFLICT on PostgreSQL and SQLite, ON
+	// DUPLICATE KEY UPDATE on MySQL and MERGE on MSSQL. MySQL ignores the
+	// given columns and relies on the table's unique indexes instead.
+	OnConflict(columns ...string) Inserter
+
+	// DoUpdate defines how conflicting rows are updated, it accepts the same
+	// arguments as Updater.Set(). If no arguments are given, every inserted
+	// column that is not part of OnConflict() is overwritten with its new
+	// value.
+	//
+	//   i.Values(item).OnConflict("id").DoUpdate("hits = hits + ?", 1)
+	DoUpdate(terms ...interface{}) Inserter
+
+	// DoNothing leaves conflicting rows untouched.
+	DoNothing() Inserter
+
 	// Iterator provides methods to iterate over the results returned by the
 	// Inserter. This is only possible when using Returning().
 	Iterator() Iterator
@@ -432,6 +672,9 @@ type Inserter interface {
 	// that support such feature (e.g.: queries with Returning).
 	Getter
 
+	// Compiler provides the Compile method.
+	Compiler
+
 	// fmt.Stringer provides `String() string`, you can use `String()` to compile
 	// the `Inserter` into a string.
 	fmt.Stringer
@@ -448,6 +691,31 @@ type Deleter interface {
 	// conditions that have been already set.
 	And(conds ...interface{}) Deleter
 
+	// Using adds tables the statement reads from besides the one rows are
+	// deleted from, so their columns can be used by Where():
+	//
+	//   d.Using("users").Where("posts.user_id = users.id AND users.banned")
+	//
+	// This is compiled into DELETE ... USING on PostgreSQL and into a
+	// multi-table DELETE on MySQL. Compiling a Deleter with Using() or Join()
+	// on any other database fails with db.ErrUnsupported.
+	Using(tables ...interface{}) Deleter
+
+	// Join represents a JOIN clause on the tables given to Using(), on MySQL
+	// it can also be used without Using() to join the table rows are deleted
+	// from.
+	//
+	// See Selector.Join for documentation and usage examples.
+	Join(tables ...interface{}) Deleter
+
+	// LeftJoin is like Join but represents a LEFT JOIN clause.
+	LeftJoin(tables ...interface{}) Deleter
+
+	// On represents the ON condition of the last Join().
+	//
+	// See Selector.On for documentation and usage examples.
+	On(terms ...interface{}) Deleter
+
 	// Limit represents the LIMIT clause.
 	//
 	// See Selector.Limit for documentation and usage examples.
@@ -457,12 +725,30 @@ type Deleter interface {
 	// database server.
 	Amend(func(queryIn string) (queryOut string)) Deleter
 
+	// Returning represents a RETURNING clause.
+	//
+	// RETURNING specifies which columns of the deleted rows should be
+	// returned. Compiling a Deleter with Returning on a database that does not
+	// support it fails with db.ErrUnsupported.
+	Returning(columns ...string) Deleter
+
+	// Iterator provides methods to iterate over the rows returned by the
+	// Deleter. This is only possible when using Returning().
+	Iterator() Iterator
+
+	// IteratorContext provides methods to iterate over the rows returned by
+	// the Deleter. This is only possible when using Returning().
+	IteratorContext(ctx context.Context) Iterator
+
 	// Preparer provides methods for creating prepared statements.
 	Preparer
 
 	// Execer provides the Exec method.
 	Execer
 
+	// Compiler provides the Compile method.
+	Compiler
+
 	// fmt.Stringer provides `String() string`, you can use `String()` to compile
 	// the `Inserter` into a string.
 	fmt.Stringer
@@ -476,6 +762,14 @@ type Updater interface {
 	// Set represents the SET clause.
 	Set(...interface{}) Updater
 
+	// SetExpr sets column to the given value or expression, ":column" within
+	// a db.Raw() expression is replaced by the name of the column:
+	//
+	//   u.SetExpr("counter", db.Raw(":column + ?", n))
+	//
+	// Several columns can be set by chaining calls to SetExpr() and Set().
+	SetExpr(column string, expr interface{}) Updater
+
 	// Where represents the WHERE clause.
 	//
 	// See Selector.Where for documentation and usage examples.
@@ -485,17 +779,59 @@ type Updater interface {
 	// conditions that have been already set.
 	And(conds ...interface{}) Updater
 
+	// From adds tables the statement reads from besides the updated one, so
+	// their columns can be used by Set() and Where():
+	//
+	//   u.Set("author_name = users.name").From("users").Where("posts.user_id = users.id")
+	//
+	// This is compiled into UPDATE ... FROM on PostgreSQL and SQLite and into
+	// a multi-table UPDATE on MySQL. Compiling an Updater with From() or
+	// Join() on any other database fails with db.ErrUnsupported.
+	From(tables ...interface{}) Updater
+
+	// Join represents a JOIN clause on the tables given to From(), on MySQL it
+	// can also be used without From() to join the updated table.
+	//
+	// See Selector.Join for documentation and usage examples.
+	Join(tables ...interface{}) Updater
+
+	// LeftJoin is like Join but represents a LEFT JOIN clause.
+	LeftJoin(tables ...interface{}) Updater
+
+	// On represents the ON condition of the last Join().
+	//
+	// See Selector.On for documentation and usage examples.
+	On(terms ...interface{}) Updater
+
 	// Limit represents the LIMIT parameter.
 	//
 	// See Selector.Limit for documentation and usage examples.
 	Limit(int) Updater
 
+	// Returning represents a RETURNING clause.
+	//
+	// RETURNING specifies which columns of the updated rows should be
+	// returned. Compiling an Updater with Returning on a database that does not
+	// support it fails with db.ErrUnsupported.
+	Returning(columns ...string) Updater
+
+	// Iterator provides methods to iterate over the rows returned by the
+	// Updater. This is only possible when using Returning().
+	Iterator() Iterator
+
+	// IteratorContext provides methods to iterate over the rows returned by
+	// the Updater. This is only possible when using Returning().
+	IteratorContext(ctx context.Context) Iterator
+
 	// Preparer provides methods for creating prepared statements.
 	Preparer
 
 	// Execer provides the Exec method.
 	Execer
 
+	// Compiler provides the Compile method.
+	Compiler
+
 	// fmt.Stringer provides `String() string`, you can use `String()` to compile
 	// the `Inserter` into a string.
 	fmt.Stringer
@@ -508,6 +844,109 @@ type Updater interface {
 	Amend(func(queryIn string) (queryOut string)) Updater
 }
 
+// TableCreator represents a CREATE TABLE statement. Column types, defaults
+// and constraints are translated into the dialect of the database.
+type TableCreator interface {
+	// Columns appends column definitions to the table, see NewColumn.
+	Columns(columns ...ColumnDefinition) TableCreator
+
+	// PrimaryKey adds a PRIMARY KEY constraint on the given columns, use it
+	// for composite keys.
+	PrimaryKey(columns ...string) TableCreator
+
+	// Unique adds a UNIQUE constraint on the given columns.
+	Unique(columns ...string) TableCreator
+
+	// ForeignKey adds a FOREIGN KEY constraint on the given columns that
+	// references refColumns of table.
+	ForeignKey(columns []string, table string, refColumns ...string) TableCreator
+
+	// Execer provides the Exec method.
+	Execer
+
+	// Compiler provides the Compile method.
+	Compiler
+
+	// fmt.Stringer provides `String() string`, you can use `String()` to compile
+	// the `TableCreator` into a string.
+	fmt.Stringer
+}
+
+// TableAlterer represents an ALTER TABLE statement.
+type TableAlterer interface {
+	// AddColumn adds a column to the table.
+	AddColumn(column ColumnDefinition) TableAlterer
+
+	// DropColumn removes a column from the table.
+	DropColumn(name string) TableAlterer
+
+	// Execer provides the Exec method.
+	Execer
+
+	// Compiler provides the Compile method.
+	Compiler
+
+	// fmt.Stringer provides `String() string`, you can use `String()` to compile
+	// the `TableAlterer` into a string.
+	fmt.Stringer
+}
+
+// IndexCreator represents a CREATE INDEX statement.
+type IndexCreator interface {
+	// On sets the table and the columns the index is created on.
+	On(table string, columns ...string) IndexCreator
+
+	// Unique makes the index a UNIQUE index.
+	Unique() IndexCreator
+
+	// Execer provides the Exec method.
+	Execer
+
+	// Compiler provides the Compile method.
+	Compiler
+
+	// fmt.Stringer provides `String() string`, you can use `String()` to compile
+	// the `IndexCreator` into a string.
+	fmt.Stringer
+}
+
+// Caller represents a statement that runs a stored procedure: CALL on most
+// databases, EXEC on SQL Server.
+type Caller interface {
+	// Execer provides the Exec method, OUT parameters are set when it
+	// returns.
+	Execer
+
+	// Iterator runs the procedure and returns an iterator over the rows of
+	// the result set it returns. OUT parameters are set once the iterator is
+	// closed, except on databases that return them as a row, like
+	// PostgreSQL, where that row is the one the iterator reads. Databases
+	// that pass them as user variables, like MySQL, only support them with
+	// Exec.
+	Iterator() Iterator
+
+	// IteratorContext is like Iterator but it runs within the given context.
+	IteratorContext(ctx context.Context) Iterator
+
+	// Compiler provides the Compile method.
+	Compiler
+
+	// fmt.Stringer provides `String() string`, you can use `String()` to compile
+	// the `Caller` into a string.
+	fmt.Stringer
+}
+
+// Compiler provides the Compile method.
+type Compiler interface {
+	// Compile returns the query and the arguments that would be sent to the
+	// database server to run the statement, placeholders included, without
+	// running it. Builders that are not bound to a session return the query
+	// with "?" placeholders.
+	//
+	//  query, args, err := s.Compile()
+	Compile() (query string, args []interface{}, err error)
+}
+
 // Execer provides methods for executing statements that do not return results.
 type Execer interface {
 	// Exec executes a statement and returns sql.Result.
@@ -542,6 +981,36 @@ type Getter interface {
 	QueryRowContext(ctx context.Context) (*sql.Row, error)
 }
 
+// WindowFunction represents a function that is evaluated over a window of rows
+// that are related to the current one. Use Over() to create a WindowFunction
+// and pass it to Selector.Columns().
+type WindowFunction interface {
+	// PartitionBy represents a PARTITION BY clause, rows that share the same
+	// values on the given columns belong to the same window.
+	PartitionBy(columns ...interface{}) WindowFunction
+
+	// OrderBy defines the order of the rows within a window, it accepts the
+	// same arguments as Selector.OrderBy().
+	OrderBy(columns ...interface{}) WindowFunction
+
+	// Rows defines a frame in terms of rows before or after the current one.
+	//
+	//   sqlbuilder.Over(db.Raw("AVG(price)")).OrderBy("day").
+	//     Rows(sqlbuilder.Preceding(6), sqlbuilder.CurrentRow)
+	Rows(start FrameBound, end FrameBound) WindowFunction
+
+	// Range is like Rows() but the frame is defined in terms of the values of
+	// the ORDER BY column.
+	Range(start FrameBound, end FrameBound) WindowFunction
+
+	// As defines an alias for the resulting column.
+	As(alias string) WindowFunction
+
+	// Arguments returns the arguments that are going to be passed along with
+	// the window function.
+	Arguments() []interface{}
+}
+
 // Paginator provides tools for splitting the results of a query into chunks
 // containing a fixed number of items.
 type Paginator interface {
@@ -566,6 +1035,8 @@ type Paginator interface {
 	// Example:
 	//
 	//   p = q.NextPage(items[len(items)-1].ID)
+	//
+	// A db.Cursor, as returned by NextPageCursor(), is also accepted.
 	NextPage(cursorValue interface{}) Paginator
 
 	// PrevPage returns the previous page according to the cursor. It expects a
@@ -575,14 +1046,44 @@ type Paginator interface {
 	// Example:
 	//
 	//   p = q.PrevPage(items[0].ID)
+	//
+	// A db.Cursor, as returned by PrevPageCursor(), is also accepted.
 	PrevPage(cursorValue interface{}) Paginator
 
+	// NextPageCursor returns an encoded cursor with the value the cursor column
+	// has on the last element of items, which is typically the slice that was
+	// filled by All(). The returned cursor can be given to NextPage().
+	//
+	// Example:
+	//
+	//   err = p.All(&items)
+	//   ...
+	//   next, err := p.NextPageCursor(&items)
+	NextPageCursor(items interface{}) (db.Cursor, error)
+
+	// PrevPageCursor is like NextPageCursor() but it encodes the value of the
+	// first element of items and the cursor is meant to be given to
+	// PrevPage().
+	PrevPageCursor(items interface{}) (db.Cursor, error)
+
 	// TotalPages returns the total number of pages in the query.
 	TotalPages() (uint, error)
 
+	// TotalPagesContext is like TotalPages() but the query runs within the
+	// given context.
+	TotalPagesContext(ctx context.Context) (uint, error)
+
 	// TotalEntries returns the total number of entries in the query.
 	TotalEntries() (uint64, error)
 
+	// TotalEntriesContext is like TotalEntries() but the query runs within the
+	// given context.
+	TotalEntriesContext(ctx context.Context) (uint64, error)
+
+	// Explain returns the plan the database would use to run the query of the
+	// current page, see Selector.Explain.
+	Explain(ctx context.Context) (string, error)
+
 	// Preparer provides methods for creating prepared statements.
 	Preparer
 
@@ -601,6 +1102,15 @@ type Paginator interface {
 	// ResultMapper provides methods to retrieve and map results.
 	ResultMapper
 
+	// AllContext is like All() but the query runs within the given context.
+	AllContext(ctx context.Context, destSlice interface{}) error
+
+	// OneContext is like One() but the query runs within the given context.
+	OneContext(ctx context.Context, dest interface{}) error
+
+	// Compiler provides the Compile method.
+	Compiler
+
 	// fmt.Stringer provides `String() string`, you can use `String()` to compile
 	// the `Selector` into a string.
 	fmt.Stringer
@@ -651,9 +1161,71 @@ type Iterator interface {
 	// a pointer to either a map or a struct.
 	Next(dest ...interface{}) bool
 
+	// ForEach calls fn once for each row, fn reads the row with scan. Given a
+	// single pointer to a struct, scan maps the row into it like Next does,
+	// and the fields it maps are looked up once and reused while scan is
+	// given the same struct, so that rows can be read into the same value
+	// without allocations. Otherwise scan is like Scan. The iterator is
+	// closed once ForEach returns, iteration stops when fn returns an error,
+	// which is returned by ForEach.
+	//
+	//  var artist Artist
+	//  err := iter.ForEach(func(scan func(dest ...interface{}) error) error {
+	//    if err := scan(&artist); err != nil {
+	//      return err
+	//    }
+	//    ...
+	//    return nil
+	//  })
+	ForEach(fn func(scan func(dest ...interface{}) error) error) error
+
+	// Columns describes the columns of the rows of the iterator, it returns
+	// nil if the iterator is closed or the columns can't be read, in which
+	// case Err tells why.
+	Columns() []ColumnInfo
+
+	// NextResultSet prepares the iterator to read the rows of the next result
+	// set, like the ones returned by stored procedures or batched statements,
+	// it returns false if there are no more result sets or if an error
+	// happened, in which case Err tells why. The rows of each result set are
+	// read with Next, NextScan or Scan, as One, All, ScanOne and ForEach close
+	// the iterator.
+	//
+	//  iter := sess.Call("artist_stats", 12).Iterator()
+	//  defer iter.Close()
+	//  for iter.Next(&artist) {
+	//    ...
+	//  }
+	//  if iter.NextResultSet() {
+	//    for iter.Next(&album) {
+	//      ...
+	//    }
+	//  }
+	NextResultSet() bool
+
 	// Err returns the last error produced by the cursor.
 	Err() error
 
 	// Close closes the iterator and frees up the cursor.
 	Close() error
 }
+
+// ColumnInfo describes a column of the rows of an Iterator, as reported by
+// the driver.
+type ColumnInfo struct {
+	// Name is the name of the column.
+	Name string
+
+	// DatabaseType is the name of the type of the column in the database,
+	// like "VARCHAR" or "INT4". It's empty if the driver doesn't tell.
+	DatabaseType string
+
+	// Nullable tells whether the column may be NULL, it's only meaningful
+	// if NullableKnown is true.
+	Nullable      bool
+	NullableKnown bool
+
+	// ScanType is the Go type the driver would scan the column into, it's
+	// nil if the driver doesn't tell.
+	ScanType reflect.Type
+}
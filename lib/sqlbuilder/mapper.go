@@ -0,0 +1,50 @@
+package sqlbuilder
+
+import (
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/reflectx"
+)
+
+// hasMapper is implemented by sessions that map the fields of structs to
+// columns with a mapper of their own, see db.FieldMapping.
+type hasMapper interface {
+	Mapper() *reflectx.Mapper
+}
+
+// sessionMapper returns the mapper of the given session, or the default one
+// that obeys "db" tags.
+func sessionMapper(sess interface{}) *reflectx.Mapper {
+	if s, ok := sess.(hasMapper); ok {
+		if m := s.Mapper(); m != nil {
+			return m
+		}
+	}
+	return mapper
+}
+
+// hasZeroValuePolicy is implemented by sessions that may write zero values as
+// NULL, see db.ZeroValuePolicy.
+type hasZeroValuePolicy interface {
+	ZeroValuePolicy() db.ZeroValuePolicy
+}
+
+// sessionMapOptions returns the options structs are mapped with by the given
+// session.
+func sessionMapOptions(sess interface{}) MapOptions {
+	options := MapOptions{Mapper: sessionMapper(sess)}
+	if s, ok := sess.(hasZeroValuePolicy); ok {
+		options.NullZero = s.ZeroValuePolicy() == db.ZeroAsNull
+	}
+	if s, ok := sess.(hasNewUUID); ok {
+		options.NewUUID = s.NewUUID
+	}
+	return options
+}
+
+// mapper returns the mapper of the given options, or the default one.
+func (options *MapOptions) mapper() *reflectx.Mapper {
+	if options.Mapper != nil {
+		return options.Mapper
+	}
+	return mapper
+}
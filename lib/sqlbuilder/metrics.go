@@ -0,0 +1,91 @@
+package sqlbuilder
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// StatementMetrics holds counters for a kind of statement.
+type StatementMetrics struct {
+	// Count is the number of statements that were executed.
+	Count uint64
+
+	// Errors is the number of statements that returned an error.
+	Errors uint64
+
+	// Duration is the total time spent executing statements.
+	Duration time.Duration
+}
+
+// Metrics is a snapshot of the query and connection pool statistics of a
+// session. Transactions and copies of a session share the same metrics.
+type Metrics struct {
+	// Statements holds counters by statement type: "select", "insert",
+	// "update", "delete", "count", "truncate", "drop", "prepare" and "raw".
+	Statements map[string]StatementMetrics
+
+	// PreparedStatementCacheHits and PreparedStatementCacheMisses count the
+	// lookups on the prepared statement cache.
+	PreparedStatementCacheHits   uint64
+	PreparedStatementCacheMisses uint64
+
+	// PreparedStatementCacheEvictions counts the prepared statements that were
+	// closed because the cache was full or because they expired.
+	PreparedStatementCacheEvictions uint64
+
+	// Pool holds the connection pool statistics.
+	Pool sql.DBStats
+}
+
+// WritePrometheus writes the metrics to w in the Prometheus text exposition
+// format, it can be used to serve them from a /metrics endpoint:
+//
+//	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+//		sess.Metrics().WritePrometheus(w)
+//	})
+func (m Metrics) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	types := make([]string, 0, len(m.Statements))
+	for t := range m.Statements {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	writeMetricHeader(bw, "upper_db_queries_total", "counter", "Number of executed statements.")
+	for _, t := range types {
+		fmt.Fprintf(bw, "upper_db_queries_total{type=%q} %d\n", t, m.Statements[t].Count)
+	}
+
+	writeMetricHeader(bw, "upper_db_query_errors_total", "counter", "Number of statements that returned an error.")
+	for _, t := range types {
+		fmt.Fprintf(bw, "upper_db_query_errors_total{type=%q} %d\n", t, m.Statements[t].Errors)
+	}
+
+	writeMetricHeader(bw, "upper_db_query_duration_seconds", "summary", "Time spent executing statements.")
+	for _, t := range types {
+		fmt.Fprintf(bw, "upper_db_query_duration_seconds_sum{type=%q} %g\n", t, m.Statements[t].Duration.Seconds())
+		fmt.Fprintf(bw, "upper_db_query_duration_seconds_count{type=%q} %d\n", t, m.Statements[t].Count)
+	}
+
+	writeMetricHeader(bw, "upper_db_prepared_statement_cache_hits_total", "counter", "Number of prepared statements that were found on the cache.")
+	fmt.Fprintf(bw, "upper_db_prepared_statement_cache_hits_total %d\n", m.PreparedStatementCacheHits)
+
+	writeMetricHeader(bw, "upper_db_prepared_statement_cache_misses_total", "counter", "Number of prepared statements that were not found on the cache.")
+	fmt.Fprintf(bw, "upper_db_prepared_statement_cache_misses_total %d\n", m.PreparedStatementCacheMisses)
+
+	writeMetricHeader(bw, "upper_db_prepared_statement_cache_evictions_total", "counter", "Number of prepared statements that were evicted from the cache.")
+	fmt.Fprintf(bw, "upper_db_prepared_statement_cache_evictions_total %d\n", m.PreparedStatementCacheEvictions)
+
+	writePoolMetrics(bw, m.Pool)
+
+	return bw.Flush()
+}
+
+func writeMetricHeader(w io.Writer, name string, kind string, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
@@ -0,0 +1,29 @@
+// +build go1.11
+
+package sqlbuilder
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+func writePoolMetrics(w io.Writer, stats sql.DBStats) {
+	writeMetricHeader(w, "upper_db_pool_max_open_connections", "gauge", "Maximum number of open connections to the database.")
+	fmt.Fprintf(w, "upper_db_pool_max_open_connections %d\n", stats.MaxOpenConnections)
+
+	writeMetricHeader(w, "upper_db_pool_open_connections", "gauge", "Number of established connections, both in use and idle.")
+	fmt.Fprintf(w, "upper_db_pool_open_connections %d\n", stats.OpenConnections)
+
+	writeMetricHeader(w, "upper_db_pool_in_use_connections", "gauge", "Number of connections currently in use.")
+	fmt.Fprintf(w, "upper_db_pool_in_use_connections %d\n", stats.InUse)
+
+	writeMetricHeader(w, "upper_db_pool_idle_connections", "gauge", "Number of idle connections.")
+	fmt.Fprintf(w, "upper_db_pool_idle_connections %d\n", stats.Idle)
+
+	writeMetricHeader(w, "upper_db_pool_wait_count_total", "counter", "Number of connections waited for.")
+	fmt.Fprintf(w, "upper_db_pool_wait_count_total %d\n", stats.WaitCount)
+
+	writeMetricHeader(w, "upper_db_pool_wait_duration_seconds_total", "counter", "Time blocked waiting for a new connection.")
+	fmt.Fprintf(w, "upper_db_pool_wait_duration_seconds_total %g\n", stats.WaitDuration.Seconds())
+}
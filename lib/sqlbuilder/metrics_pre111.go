@@ -0,0 +1,14 @@
+// +build !go1.11
+
+package sqlbuilder
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+func writePoolMetrics(w io.Writer, stats sql.DBStats) {
+	writeMetricHeader(w, "upper_db_pool_open_connections", "gauge", "Number of established connections, both in use and idle.")
+	fmt.Fprintf(w, "upper_db_pool_open_connections %d\n", stats.OpenConnections)
+}
@@ -0,0 +1,36 @@
+package sqlbuilder
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsWritePrometheus(t *testing.T) {
+	m := Metrics{
+		Statements: map[string]StatementMetrics{
+			"select": {Count: 10, Errors: 1, Duration: 1500 * time.Millisecond},
+			"insert": {Count: 2},
+		},
+		PreparedStatementCacheHits:   7,
+		PreparedStatementCacheMisses: 3,
+
+		PreparedStatementCacheEvictions: 2,
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, m.WritePrometheus(&buf))
+
+	out := buf.String()
+
+	assert.Contains(t, out, "# TYPE upper_db_queries_total counter\n")
+	assert.Contains(t, out, "upper_db_queries_total{type=\"insert\"} 2\nupper_db_queries_total{type=\"select\"} 10\n")
+	assert.Contains(t, out, "upper_db_query_errors_total{type=\"select\"} 1\n")
+	assert.Contains(t, out, "upper_db_query_duration_seconds_sum{type=\"select\"} 1.5\n")
+	assert.Contains(t, out, "upper_db_query_duration_seconds_count{type=\"select\"} 10\n")
+	assert.Contains(t, out, "upper_db_prepared_statement_cache_hits_total 7\n")
+	assert.Contains(t, out, "upper_db_prepared_statement_cache_misses_total 3\n")
+	assert.Contains(t, out, "upper_db_pool_open_connections 0\n")
+}
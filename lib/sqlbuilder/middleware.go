@@ -0,0 +1,36 @@
+package sqlbuilder
+
+import (
+	"context"
+)
+
+// Statement is a compiled statement on its way to the database.
+type Statement struct {
+	// Type is the kind of statement: "select", "insert", "update", "delete",
+	// "count", "truncate", "drop" or "raw", as in Metrics.Statements.
+	Type string
+
+	// Query is the compiled statement, using the placeholders of the
+	// database.
+	Query string
+
+	// Args holds the values of the placeholders.
+	Args []interface{}
+
+	// Where is true if the statement has a WHERE clause, it's only known
+	// for statements built with the SQL builder.
+	Where bool
+}
+
+// StatementHandler sends a statement to the database. Middleware installed
+// with Database.Use() receives the next handler of the chain and returns a
+// handler that may inspect or modify the statement before passing it on, or
+// refuse to run it by returning an error:
+//
+//	sess.Use(func(next sqlbuilder.StatementHandler) sqlbuilder.StatementHandler {
+//		return func(ctx context.Context, stmt *sqlbuilder.Statement) error {
+//			stmt.Query = "/* app=billing */ " + stmt.Query
+//			return next(ctx, stmt)
+//		}
+//	})
+type StatementHandler func(ctx context.Context, stmt *Statement) error
@@ -0,0 +1,149 @@
+package sqlbuilder
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+
+	"upper.io/db.v3/lib/reflectx"
+)
+
+// bindNamed replaces the :name placeholders of the given raw SQL query with
+// positional ones when the only argument is a struct or a map with string
+// keys, the value of each placeholder is taken from the field tagged with its
+// name, or from the key with its name:
+//
+//	sess.Query(`SELECT * FROM artist WHERE name = :name AND id > :id`, artist)
+//
+// Quoted strings and identifiers, and PostgreSQL casts like "::text", are left
+// alone. The query and the arguments are returned unchanged if they don't use
+// named placeholders.
+func bindNamed(m *reflectx.Mapper, query string, args []interface{}) (string, []interface{}, error) {
+	if len(args) != 1 {
+		return query, args, nil
+	}
+
+	source, ok := namedSource(args[0])
+	if !ok {
+		return query, args, nil
+	}
+
+	var (
+		out    []byte
+		values []interface{}
+		quote  byte
+		last   int
+	)
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+			continue
+		case ':':
+		default:
+			continue
+		}
+
+		// "::" is a PostgreSQL cast.
+		if i+1 < len(query) && query[i+1] == ':' {
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j < len(query) && isNameChar(query[j], j == i+1) {
+			j++
+		}
+		if j == i+1 {
+			continue
+		}
+
+		name := query[i+1 : j]
+		value, err := namedValue(m, source, name)
+		if err != nil {
+			return "", nil, err
+		}
+
+		out = append(out, query[last:i]...)
+		out = append(out, '?')
+		values = append(values, value)
+
+		last = j
+		i = j - 1
+	}
+
+	if values == nil {
+		return query, args, nil
+	}
+
+	out = append(out, query[last:]...)
+	return string(out), values, nil
+}
+
+// namedSource returns the struct or map the values of named placeholders are
+// taken from, if arg is one.
+func namedSource(arg interface{}) (reflect.Value, bool) {
+	switch arg.(type) {
+	case nil, driver.Valuer, time.Time, *time.Time:
+		return reflect.Value{}, false
+	}
+
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return v, true
+	case reflect.Map:
+		return v, v.Type().Key().Kind() == reflect.String
+	}
+	return reflect.Value{}, false
+}
+
+// namedValue returns the value of the named placeholder name.
+func namedValue(m *reflectx.Mapper, source reflect.Value, name string) (interface{}, error) {
+	if source.Kind() == reflect.Map {
+		value := source.MapIndex(reflect.ValueOf(name).Convert(source.Type().Key()))
+		if !value.IsValid() {
+			return nil, fmt.Errorf("missing value for named parameter %q", name)
+		}
+		return value.Interface(), nil
+	}
+
+	fi, ok := m.TypeMap(source.Type()).Names[name]
+	if !ok {
+		return nil, fmt.Errorf("missing field for named parameter %q in %v", name, source.Type())
+	}
+	value := reflectx.ValidFieldByIndexes(source, fi.Index)
+	if !value.IsValid() {
+		// The field is within a nil embedded pointer.
+		return nil, nil
+	}
+	return value.Interface(), nil
+}
+
+func isNameChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	}
+	return false
+}
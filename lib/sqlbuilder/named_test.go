@@ -0,0 +1,66 @@
+package sqlbuilder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBindNamed(t *testing.T) {
+	type base struct {
+		ID int64 `db:"id"`
+	}
+	type artist struct {
+		*base
+		Name string `db:"name,omitempty"`
+	}
+
+	{
+		query, args, err := bindNamed(mapper, `SELECT * FROM artist WHERE name = :name AND id > :id`, []interface{}{&artist{base: &base{ID: 5}, Name: "Ozzie"}})
+		assert.NoError(t, err)
+		assert.Equal(t, `SELECT * FROM artist WHERE name = ? AND id > ?`, query)
+		assert.Equal(t, []interface{}{"Ozzie", int64(5)}, args)
+	}
+
+	{
+		// Fields within nil embedded pointers are NULL.
+		_, args, err := bindNamed(mapper, `SELECT :id`, []interface{}{artist{}})
+		assert.NoError(t, err)
+		assert.Equal(t, []interface{}{nil}, args)
+	}
+
+	{
+		query, args, err := bindNamed(mapper, `SELECT ':name', ":name", id::text FROM artist WHERE id IN :ids OR name = :name`, []interface{}{map[string]interface{}{"ids": []int{1, 2}, "name": "Ozzie"}})
+		assert.NoError(t, err)
+		assert.Equal(t, `SELECT ':name', ":name", id::text FROM artist WHERE id IN ? OR name = ?`, query)
+		assert.Equal(t, []interface{}{[]int{1, 2}, "Ozzie"}, args)
+	}
+
+	{
+		_, _, err := bindNamed(mapper, `SELECT :missing`, []interface{}{map[string]string{}})
+		assert.Error(t, err)
+
+		_, _, err = bindNamed(mapper, `SELECT :missing`, []interface{}{artist{}})
+		assert.Error(t, err)
+	}
+
+	{
+		// Positional arguments are left alone.
+		now := time.Now()
+		for _, args := range [][]interface{}{{now}, {1, 2}, {"a"}, nil} {
+			query, bound, err := bindNamed(mapper, `SELECT :a, ?`, args)
+			assert.NoError(t, err)
+			assert.Equal(t, `SELECT :a, ?`, query)
+			assert.Equal(t, args, bound)
+		}
+	}
+
+	{
+		b, sess := newFakeBuilder()
+		_, err := b.Exec(`UPDATE artist SET name = :name WHERE id = :id`, map[string]interface{}{"id": 1, "name": "Ozzie"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{`UPDATE artist SET name = $1 WHERE id = $2`}, sess.queries)
+		assert.Equal(t, [][]interface{}{{"Ozzie", 1}}, sess.args)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"database/sql"
 	"errors"
 	"math"
+	"reflect"
 	"strings"
 
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/immutable"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/reflectx"
 )
 
 var (
@@ -37,13 +40,43 @@ func newPaginator(sel Selector, pageSize uint) Paginator {
 	}).Page(1)
 }
 
-func (pq *paginatorQuery) count() (uint64, error) {
+func (pq *paginatorQuery) context() context.Context {
+	return pq.sel.(*selector).SQLBuilder().sess.Context()
+}
+
+func (pq *paginatorQuery) count(ctx context.Context) (uint64, error) {
 	var count uint64
-	row, err := pq.sel.(*selector).setColumns(db.Raw("count(1) AS _t")).
-		Limit(0).
-		Offset(0).
-		OrderBy(nil).
-		QueryRow()
+
+	// Rows can't be locked by aggregate queries.
+	sel := pq.sel.(*selector).unlocked()
+
+	sq, err := sel.build(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var counter Selector
+	if sq.compound != nil || sq.groupBy != nil || sq.distinct || sq.distinctOn != nil {
+		// Compound statements, groups and distinct rows can't be counted by
+		// replacing the columns of the query, the query is counted as a derived
+		// table instead. Its common table expressions precede the count, as
+		// not every database accepts them within a derived table.
+		derived := sel.OrderBy(nil).Limit(0).Offset(0).(*selector).withCTEs(nil)
+		counter = sel.SQLBuilder().Select(exql.ColumnWithAlias("count(1)", "_t")).
+			From(derived).
+			As("_u").(*selector).
+			withCTEs(sq)
+	} else {
+		counter = sel.setColumns(exql.ColumnWithAlias("count(1)", "_t")).
+			Limit(0).
+			Offset(0).
+			OrderBy(nil)
+	}
+
+	row, err := counter.QueryRowContext(ctx)
+	if err != nil {
+		return 0, err
+	}
 
 	err = row.Scan(&count)
 	if err != nil {
@@ -87,6 +120,10 @@ func (pag *paginator) NextPage(cursorValue interface{}) Paginator {
 		if pq.cursorValue != nil && pq.cursorColumn == "" {
 			return errMissingCursorColumn
 		}
+		cursorValue, err := decodeCursor(cursorValue)
+		if err != nil {
+			return err
+		}
 		pq.cursorValue = cursorValue
 		pq.cursorReverseOrder = false
 		if strings.HasPrefix(pq.cursorColumn, "-") {
@@ -107,6 +144,10 @@ func (pag *paginator) PrevPage(cursorValue interface{}) Paginator {
 		if pq.cursorValue != nil && pq.cursorColumn == "" {
 			return errMissingCursorColumn
 		}
+		cursorValue, err := decodeCursor(cursorValue)
+		if err != nil {
+			return err
+		}
 		pq.cursorValue = cursorValue
 		pq.cursorReverseOrder = true
 		if strings.HasPrefix(pq.cursorColumn, "-") {
@@ -122,13 +163,90 @@ func (pag *paginator) PrevPage(cursorValue interface{}) Paginator {
 	})
 }
 
+func (pag *paginator) NextPageCursor(items interface{}) (db.Cursor, error) {
+	pq, err := pag.build()
+	if err != nil {
+		return "", err
+	}
+	return pq.encodeCursor(items, true)
+}
+
+func (pag *paginator) PrevPageCursor(items interface{}) (db.Cursor, error) {
+	pq, err := pag.build()
+	if err != nil {
+		return "", err
+	}
+	return pq.encodeCursor(items, false)
+}
+
+// encodeCursor encodes the value of the cursor column of either the first or
+// the last element of items.
+func (pq *paginatorQuery) encodeCursor(items interface{}, last bool) (db.Cursor, error) {
+	if pq.cursorColumn == "" {
+		return "", errMissingCursorColumn
+	}
+	column := strings.TrimPrefix(pq.cursorColumn, "-")
+
+	list := reflect.Indirect(reflect.ValueOf(items))
+	if list.Kind() != reflect.Slice {
+		return "", ErrExpectingSlicePointer
+	}
+	if list.Len() == 0 {
+		return "", db.ErrNoMoreRows
+	}
+
+	item := list.Index(0)
+	if last {
+		item = list.Index(list.Len() - 1)
+	}
+	for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+		item = item.Elem()
+	}
+
+	var value reflect.Value
+	switch item.Kind() {
+	case reflect.Map:
+		value = item.MapIndex(reflect.ValueOf(column))
+	case reflect.Struct:
+		if fi, ok := sessionMapper(pq.sel.(*selector).SQLBuilder().sess).TypeMap(item.Type()).Names[column]; ok {
+			value = reflectx.FieldByIndexes(item, fi.Index)
+		}
+	default:
+		return "", ErrExpectingSliceMapStruct
+	}
+
+	if !value.IsValid() {
+		return "", errMissingCursorColumn
+	}
+
+	return db.EncodeCursor(value.Interface())
+}
+
+func decodeCursor(cursorValue interface{}) (interface{}, error) {
+	if c, ok := cursorValue.(db.Cursor); ok {
+		return c.Value()
+	}
+	return cursorValue, nil
+}
+
 func (pag *paginator) TotalPages() (uint, error) {
 	pq, err := pag.build()
 	if err != nil {
 		return 0, err
 	}
+	return pq.totalPages(pq.context())
+}
+
+func (pag *paginator) TotalPagesContext(ctx context.Context) (uint, error) {
+	pq, err := pag.build()
+	if err != nil {
+		return 0, err
+	}
+	return pq.totalPages(ctx)
+}
 
-	count, err := pq.count()
+func (pq *paginatorQuery) totalPages(ctx context.Context) (uint, error) {
+	count, err := pq.count(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -156,6 +274,14 @@ func (pag *paginator) All(dest interface{}) error {
 	return nil
 }
 
+func (pag *paginator) AllContext(ctx context.Context, dest interface{}) error {
+	pq, err := pag.buildWithCursor()
+	if err != nil {
+		return err
+	}
+	return pq.sel.AllContext(ctx, dest)
+}
+
 func (pag *paginator) One(dest interface{}) error {
 	pq, err := pag.buildWithCursor()
 	if err != nil {
@@ -164,6 +290,14 @@ func (pag *paginator) One(dest interface{}) error {
 	return pq.sel.One(dest)
 }
 
+func (pag *paginator) OneContext(ctx context.Context, dest interface{}) error {
+	pq, err := pag.buildWithCursor()
+	if err != nil {
+		return err
+	}
+	return pq.sel.OneContext(ctx, dest)
+}
+
 func (pag *paginator) Iterator() Iterator {
 	pq, err := pag.buildWithCursor()
 	if err != nil {
@@ -198,12 +332,20 @@ func (pag *paginator) Arguments() []interface{} {
 	return pq.sel.Arguments()
 }
 
-func (pag *paginator) Compile() (string, error) {
+func (pag *paginator) Compile() (string, []interface{}, error) {
+	pq, err := pag.buildWithCursor()
+	if err != nil {
+		return "", nil, err
+	}
+	return pq.sel.Compile()
+}
+
+func (pag *paginator) compile() (string, error) {
 	pq, err := pag.buildWithCursor()
 	if err != nil {
 		return "", err
 	}
-	return pq.sel.(*selector).Compile()
+	return pq.sel.(*selector).compile()
 }
 
 func (pag *paginator) Query() (*sql.Rows, error) {
@@ -222,6 +364,14 @@ func (pag *paginator) QueryContext(ctx context.Context) (*sql.Rows, error) {
 	return pq.sel.QueryContext(ctx)
 }
 
+func (pag *paginator) Explain(ctx context.Context) (string, error) {
+	pq, err := pag.buildWithCursor()
+	if err != nil {
+		return "", err
+	}
+	return pq.sel.Explain(ctx)
+}
+
 func (pag *paginator) QueryRow() (*sql.Row, error) {
 	pq, err := pag.buildWithCursor()
 	if err != nil {
@@ -259,7 +409,15 @@ func (pag *paginator) TotalEntries() (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return pq.count()
+	return pq.count(pq.context())
+}
+
+func (pag *paginator) TotalEntriesContext(ctx context.Context) (uint64, error) {
+	pq, err := pag.build()
+	if err != nil {
+		return 0, err
+	}
+	return pq.count(ctx)
 }
 
 func (pag *paginator) build() (*paginatorQuery, error) {
@@ -302,7 +460,7 @@ func (pag *paginator) buildWithCursor() (*paginatorQuery, error) {
 	}
 
 	if pqq.cursorCond != nil {
-		pqq.sel = pqq.sel.Where(pqq.cursorCond).Offset(0)
+		pqq.sel = pqq.sel.And(pqq.cursorCond).Offset(0)
 	}
 
 	if pqq.cursorColumn != "" {
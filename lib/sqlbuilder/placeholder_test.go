@@ -81,3 +81,34 @@ func TestPlaceholderReplace(t *testing.T) {
 		assert.Equal(t, []interface{}{1, 3}, args)
 	}
 }
+
+func TestPlaceholderArrayWithinParens(t *testing.T) {
+	{
+		ret, args := Preprocess("id IN (?) AND name = ?", []interface{}{[]int64{1, 2, 3}, "Ozzie"})
+		assert.Equal(t, "id IN (?, ?, ?) AND name = ?", ret)
+		assert.Equal(t, []interface{}{int64(1), int64(2), int64(3), "Ozzie"}, args)
+	}
+
+	{
+		ret, args := Preprocess("id IN ( ? )", []interface{}{[]int64{}})
+		assert.Equal(t, "id IN (NULL)", ret)
+		assert.Equal(t, 0, len(args))
+	}
+
+	{
+		ret, _ := Preprocess("id IN (?, ?)", []interface{}{[]int{1, 2}, 3})
+		assert.Equal(t, "id IN ((?, ?), ?)", ret)
+	}
+
+	{
+		ret, args := Preprocess("data = (?)", []interface{}{[]byte("abc")})
+		assert.Equal(t, "data = (?)", ret)
+		assert.Equal(t, []interface{}{[]byte("abc")}, args)
+	}
+
+	{
+		ret, args := Preprocess("?", []interface{}{db.Raw("id IN (?)", []int64{4, 5})})
+		assert.Equal(t, "id IN (?, ?)", ret)
+		assert.Equal(t, []interface{}{int64(4), int64(5)}, args)
+	}
+}
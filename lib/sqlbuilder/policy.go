@@ -0,0 +1,163 @@
+package sqlbuilder
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// StatementPolicy decides whether the statements of a session may run, it's
+// consulted with each compiled statement before it's sent to the database,
+// see Database.SetStatementPolicy. A statement is refused if Check returns an
+// error, which is returned as is to the caller, policies should return a
+// *PolicyError.
+type StatementPolicy interface {
+	Check(ctx context.Context, stmt *Statement) error
+}
+
+// StatementPolicyFunc is a function that implements StatementPolicy.
+type StatementPolicyFunc func(ctx context.Context, stmt *Statement) error
+
+// Check calls fn.
+func (fn StatementPolicyFunc) Check(ctx context.Context, stmt *Statement) error {
+	return fn(ctx, stmt)
+}
+
+// PolicyError is the error of statements refused by a StatementPolicy.
+type PolicyError struct {
+	// Reason tells why the statement was refused.
+	Reason string
+
+	// Statement is the statement that was refused.
+	Statement *Statement
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("upper: statement refused by policy: %s: %s", e.Reason, e.Statement.Query)
+}
+
+// Policy is a StatementPolicy made of rules, a statement is refused by the
+// first rule it breaks:
+//
+//	policy := sqlbuilder.NewPolicy().
+//		Forbid("truncate", "drop").
+//		RequireWhere("update", "delete")
+//
+//	sess.SetStatementPolicy(policy)
+//
+// Raw SQL statements are matched against the rules by their first keyword,
+// like "delete", and they're considered to have a WHERE clause if the keyword
+// appears anywhere in them. It's safe for concurrent use.
+type Policy struct {
+	mu           sync.RWMutex
+	forbidden    map[string]bool
+	requireWhere map[string]bool
+	patterns     []*regexp.Regexp
+	allowRaw     []*regexp.Regexp
+}
+
+var _ = StatementPolicy(&Policy{})
+
+var (
+	rawKeyword   = regexp.MustCompile(`^\s*([A-Za-z]+)`)
+	whereKeyword = regexp.MustCompile(`(?i)\bWHERE\b`)
+)
+
+// NewPolicy returns a policy without rules, which allows every statement.
+func NewPolicy() *Policy {
+	return &Policy{
+		forbidden:    map[string]bool{},
+		requireWhere: map[string]bool{},
+	}
+}
+
+// Forbid refuses the statements of the given types, like "truncate" or
+// "drop", see Statement.Type.
+func (p *Policy) Forbid(types ...string) *Policy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range types {
+		p.forbidden[t] = true
+	}
+	return p
+}
+
+// RequireWhere refuses the statements of the given types, like "update" or
+// "delete", that have no WHERE clause.
+func (p *Policy) RequireWhere(types ...string) *Policy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range types {
+		p.requireWhere[t] = true
+	}
+	return p
+}
+
+// ForbidPattern refuses the statements whose query matches the given
+// regular expression.
+func (p *Policy) ForbidPattern(pattern string) *Policy {
+	re := regexp.MustCompile(pattern)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.patterns = append(p.patterns, re)
+	return p
+}
+
+// AllowRaw turns the policy into an allow-list of raw SQL statements, once
+// it's called raw statements are refused unless their query matches one of
+// the given regular expressions.
+func (p *Policy) AllowRaw(patterns ...string) *Policy {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i := range patterns {
+		res[i] = regexp.MustCompile(patterns[i])
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.allowRaw = append(p.allowRaw, res...)
+	return p
+}
+
+// Check returns a *PolicyError if stmt breaks one of the rules of the policy.
+func (p *Policy) Check(ctx context.Context, stmt *Statement) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	refuse := func(reason string) error {
+		return &PolicyError{Reason: reason, Statement: stmt}
+	}
+
+	kind, where := stmt.Type, stmt.Where
+	if kind == "raw" {
+		if len(p.allowRaw) > 0 && !matchesAny(p.allowRaw, stmt.Query) {
+			return refuse("raw statement is not allowed")
+		}
+		if m := rawKeyword.FindStringSubmatch(stmt.Query); m != nil {
+			kind = strings.ToLower(m[1])
+		}
+		where = whereKeyword.MatchString(stmt.Query)
+	}
+
+	if p.forbidden[kind] {
+		return refuse(kind + " statements are forbidden")
+	}
+	if p.requireWhere[kind] && !where {
+		return refuse(kind + " statements require a WHERE clause")
+	}
+	for _, re := range p.patterns {
+		if re.MatchString(stmt.Query) {
+			return refuse("statement matches forbidden pattern " + re.String())
+		}
+	}
+	return nil
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
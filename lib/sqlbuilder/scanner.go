@@ -23,6 +23,9 @@ package sqlbuilder
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
 
 	"upper.io/db.v3"
 )
@@ -36,3 +39,28 @@ func (u scanner) Scan(v interface{}) error {
 }
 
 var _ sql.Scanner = scanner{}
+
+// jsonScanner decodes JSON values into the fields tagged with the "asjson"
+// option, NULL resets the field to its zero value.
+type jsonScanner struct {
+	v interface{}
+}
+
+func (j jsonScanner) Scan(src interface{}) error {
+	var buf []byte
+	switch t := src.(type) {
+	case nil:
+		dst := reflect.ValueOf(j.v).Elem()
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	case []byte:
+		buf = t
+	case string:
+		buf = []byte(t)
+	default:
+		return fmt.Errorf("expecting a JSON value but got %T", src)
+	}
+	return json.Unmarshal(buf, j.v)
+}
+
+var _ sql.Scanner = jsonScanner{}
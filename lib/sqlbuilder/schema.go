@@ -0,0 +1,52 @@
+package sqlbuilder
+
+// Schema describes the tables of a database.
+type Schema struct {
+	Tables []Table
+}
+
+// Table returns the description of the table with the given name, or nil if
+// the schema has no such table.
+func (s *Schema) Table(name string) *Table {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// Table describes the structure of a table.
+type Table struct {
+	Name        string
+	Columns     []Column
+	PrimaryKey  []string
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+}
+
+// Column describes a column of a table.
+type Column struct {
+	Name string
+	// Type is the data type of the column, as reported by the database.
+	Type     string
+	Nullable bool
+	// Default is the expression of the default value of the column, if any.
+	Default *string
+}
+
+// Index describes an index of a table, primary keys are not included.
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// ForeignKey describes a foreign key constraint, RefColumns has the columns of
+// RefTable that Columns refer to, in the same order.
+type ForeignKey struct {
+	Name       string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
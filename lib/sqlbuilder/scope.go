@@ -0,0 +1,252 @@
+package sqlbuilder
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+var errScopedOuterJoin = errors.New("an outer join of a scoped table requires an On() expression")
+
+// hasTableScope is implemented by sessions that restrict the rows statements
+// can read and write on some tables, like the ones scoped by tenant, see
+// db.Tenancy.
+type hasTableScope interface {
+	// TableScope returns the column and the value the rows of the given table
+	// must have for a statement that runs within ctx, scoped is false if the
+	// rows of the table are not restricted.
+	TableScope(ctx context.Context, table string) (column string, value interface{}, scoped bool, err error)
+}
+
+// tableScope is the column and the value the rows of a scoped table must have.
+type tableScope struct {
+	column string
+	value  interface{}
+}
+
+// tableScope returns the scope of the given table, which may be followed by
+// an alias, for a statement that runs within ctx, or nil if the table is not
+// scoped. A nil ctx stands for the context of the session.
+func (b *sqlBuilder) tableScope(ctx context.Context, table string) (*tableScope, error) {
+	if ctx == nil && b.sess != nil {
+		ctx = b.sess.Context()
+	}
+	return sessionTableScope(ctx, b.sess, table)
+}
+
+// sessionTableScope returns the scope of the given table for a statement of
+// sess that runs within ctx, or nil if the table is not scoped.
+func sessionTableScope(ctx context.Context, sess interface{}, table string) (*tableScope, error) {
+	s, ok := sess.(hasTableScope)
+	if !ok {
+		return nil, nil
+	}
+	name, _ := splitTableAlias(table)
+	column, value, scoped, err := s.TableScope(ctx, name)
+	if !scoped {
+		return nil, err
+	}
+	return &tableScope{column: column, value: value}, nil
+}
+
+// AssignScope returns the given columns of a row to insert into table within
+// ctx with the column its rows are restricted by set to the value of the
+// scope, overwriting any other value, see db.Tenancy. The row is left as it
+// is if sess does not restrict the rows of the table.
+func AssignScope(ctx context.Context, sess interface{}, table string, names []string, values []interface{}) ([]string, []interface{}, error) {
+	scope, err := sessionTableScope(ctx, sess, table)
+	if scope == nil {
+		return names, values, err
+	}
+	names, values = scope.assign(names, values)
+	return names, values, nil
+}
+
+// cond returns the condition that matches the rows of the scope on the table
+// with the given name or alias, the column is not qualified if it's empty.
+func (s *tableScope) cond(alias string) db.Cond {
+	if alias == "" {
+		return db.Cond{s.column: db.Eq(s.value)}
+	}
+	return db.Cond{alias + "." + s.column: db.Eq(s.value)}
+}
+
+// assign returns the given columns of a row with the column of the scope set
+// to its value, overwriting any other value.
+func (s *tableScope) assign(names []string, values []interface{}) ([]string, []interface{}) {
+	for i := range names {
+		if names[i] == s.column {
+			vals := make([]interface{}, len(values))
+			copy(vals, values)
+			vals[i] = s.value
+			return names, vals
+		}
+	}
+
+	cols := make([]string, len(names), len(names)+1)
+	copy(cols, names)
+	vals := make([]interface{}, len(values), len(values)+1)
+	copy(vals, values)
+	return append(cols, s.column), append(vals, s.value)
+}
+
+// assigns reports whether any of the given assignments sets the column of the
+// scope.
+func (s *tableScope) assigns(cvs []exql.Fragment) bool {
+	for _, f := range cvs {
+		cv, ok := f.(*exql.ColumnValue)
+		if !ok {
+			continue
+		}
+		if c, ok := cv.Column.(*exql.Column); ok {
+			if name, ok := c.Name.(string); ok && columnName(name) == s.column {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitTableAlias splits a table expression like "name", "name AS alias" or
+// "name alias" into the name of the table and the name statements refer to
+// it by.
+func splitTableAlias(table string) (name string, alias string) {
+	fields := strings.Fields(table)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	return fields[0], fields[len(fields)-1]
+}
+
+// columnName returns the name of a column without the table it's qualified
+// with.
+func columnName(name string) string {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// scopedTable is a scoped table of a statement along with the name the
+// statement refers to it by.
+type scopedTable struct {
+	alias string
+	scope *tableScope
+}
+
+// cond returns the condition that matches the rows of the scope on the table,
+// qualified by its name if the statement reads from other tables.
+func (t scopedTable) cond(qualify bool) db.Cond {
+	if qualify {
+		return t.scope.cond(t.alias)
+	}
+	return t.scope.cond("")
+}
+
+// scopedTables holds the scoped tables a statement reads from.
+type scopedTables struct {
+	// ctx is the context the statement runs within, its scopes are the ones
+	// of this context.
+	ctx context.Context
+	// from holds the scoped tables of the FROM clause.
+	from []scopedTable
+	// joins holds the scoped tables of each join whose conditions are not in
+	// its ON clause yet.
+	joins map[*exql.Join][]scopedTable
+}
+
+// scopedTables returns the scoped tables among the given ones.
+func (b *sqlBuilder) scopedTables(ctx context.Context, tables []interface{}) ([]scopedTable, error) {
+	var scoped []scopedTable
+	for i := range tables {
+		table, ok := tables[i].(string)
+		if !ok {
+			continue
+		}
+		scope, err := b.tableScope(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		if scope != nil {
+			_, alias := splitTableAlias(table)
+			scoped = append(scoped, scopedTable{alias: alias, scope: scope})
+		}
+	}
+	return scoped, nil
+}
+
+// pushTables records the scoped tables of the FROM clause, replacing the ones
+// of any previous tables.
+func (st *scopedTables) pushTables(b *sqlBuilder, tables []interface{}) error {
+	scoped, err := b.scopedTables(st.ctx, tables)
+	if err != nil {
+		return err
+	}
+	st.from = scoped
+	return nil
+}
+
+// pushJoin records the scoped tables of the given join.
+func (st *scopedTables) pushJoin(b *sqlBuilder, join *exql.Join, tables []interface{}) error {
+	scoped, err := b.scopedTables(st.ctx, tables)
+	if err != nil {
+		return err
+	}
+	if len(scoped) > 0 {
+		if st.joins == nil {
+			st.joins = map[*exql.Join][]scopedTable{}
+		}
+		st.joins[join] = scoped
+	}
+	return nil
+}
+
+// on adds the conditions of the scoped tables of join to the given ON clause
+// and its arguments.
+func (st *scopedTables) on(b *sqlBuilder, join *exql.Join, where *exql.Where, args []interface{}) []interface{} {
+	scoped, ok := st.joins[join]
+	if !ok {
+		return args
+	}
+	delete(st.joins, join)
+
+	conds := make([]interface{}, len(scoped))
+	for i := range scoped {
+		conds[i] = scoped[i].cond(true)
+	}
+	w, a := b.t.toWhereWithArguments(conds)
+	where.Append(&w)
+	return append(args, a...)
+}
+
+// where returns the conditions that must be added to the WHERE clause of the
+// statement, the ones of joins that have no ON clause can only be added there
+// if they're inner joins. Columns are qualified if the statement reads from
+// more than one table.
+func (st *scopedTables) where(tables int, joins []*exql.Join) ([]interface{}, error) {
+	qualify := tables > 1 || len(joins) > 0
+
+	var conds []interface{}
+	for i := range st.from {
+		conds = append(conds, st.from[i].cond(qualify))
+	}
+	for _, join := range joins {
+		scoped, ok := st.joins[join]
+		if !ok {
+			continue
+		}
+		switch join.Type {
+		case "", "INNER", "CROSS":
+			for i := range scoped {
+				conds = append(conds, scoped[i].cond(true))
+			}
+		default:
+			return nil, errScopedOuterJoin
+		}
+	}
+	return conds, nil
+}
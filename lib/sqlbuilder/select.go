@@ -5,13 +5,23 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/immutable"
 	"upper.io/db.v3/internal/sqladapter/exql"
 )
 
+var (
+	errMissingLimitByColumns    = errors.New("LIMIT BY requires at least one column")
+	errMissingDistinctOnColumns = errors.New("DISTINCT ON requires at least one column")
+	errInvalidCursor            = errors.New("a cursor requires a name and a positive fetch size")
+	errCompoundClause           = errors.New("only OrderBy(), Limit() and Offset() can follow a compound statement, select from it instead")
+)
+
 type selectorQuery struct {
 	table     *exql.Columns
 	tableArgs []interface{}
@@ -20,6 +30,9 @@ type selectorQuery struct {
 
 	distinct bool
 
+	distinctOn     *exql.DistinctOn
+	distinctOnArgs []interface{}
+
 	where     *exql.Where
 	whereArgs []interface{}
 
@@ -38,7 +51,34 @@ type selectorQuery struct {
 	joins     []*exql.Join
 	joinsArgs []interface{}
 
+	with      []*exql.CTE
+	withArgs  []interface{}
+	recursive bool
+
+	compound     *exql.Compound
+	compoundArgs []interface{}
+
+	lock *exql.Lock
+	asOf *exql.AsOf
+
+	final   *exql.Final
+	limitBy *exql.LimitBy
+
 	amendFn func(string) string
+	timeout time.Duration
+
+	cursorName      string
+	cursorFetchSize int
+
+	scopes scopedTables
+}
+
+// context returns the context the query runs with.
+func (sq *selectorQuery) context(ctx context.Context) context.Context {
+	if sq.timeout > 0 {
+		return WithQueryTimeout(ctx, sq.timeout)
+	}
+	return ctx
 }
 
 func (sq *selectorQuery) and(b *sqlBuilder, terms ...interface{}) error {
@@ -55,6 +95,9 @@ func (sq *selectorQuery) and(b *sqlBuilder, terms ...interface{}) error {
 
 func (sq *selectorQuery) arguments() []interface{} {
 	return joinArguments(
+		sq.withArgs,
+		sq.compoundArgs,
+		sq.distinctOnArgs,
 		sq.columnsArgs,
 		sq.tableArgs,
 		sq.joinsArgs,
@@ -81,32 +124,162 @@ func (sq *selectorQuery) statement() *exql.Statement {
 		stmt.Joins = exql.JoinConditions(sq.joins...)
 	}
 
+	if sq.compound != nil {
+		stmt.Compound = sq.compound
+	}
+
+	if sq.distinctOn != nil {
+		stmt.DistinctOn = sq.distinctOn
+	}
+
+	if sq.lock != nil {
+		stmt.Lock = sq.lock
+	}
+
+	if sq.asOf != nil {
+		stmt.AsOf = sq.asOf
+	}
+
+	if sq.final != nil {
+		stmt.Final = sq.final
+	}
+
+	if sq.limitBy != nil {
+		stmt.LimitBy = sq.limitBy
+	}
+
+	if len(sq.with) > 0 {
+		stmt.With = &exql.With{
+			Recursive: sq.recursive,
+			CTEs:      sq.with,
+		}
+	}
+
 	stmt.SetAmendment(sq.amendFn)
 
 	return stmt
 }
 
-func (sq *selectorQuery) pushJoin(t string, tables []interface{}) error {
+func (sq *selectorQuery) pushJoin(b *sqlBuilder, t string, tables []interface{}) error {
 	fragments, args, err := columnFragments(tables)
 	if err != nil {
 		return err
 	}
 
+	join := &exql.Join{
+		Type:  t,
+		Table: exql.JoinColumns(fragments...),
+	}
+	if err := sq.scopes.pushJoin(b, join, tables); err != nil {
+		return err
+	}
+
 	if sq.joins == nil {
 		sq.joins = []*exql.Join{}
 	}
-	sq.joins = append(sq.joins,
-		&exql.Join{
-			Type:  t,
-			Table: exql.JoinColumns(fragments...),
-		},
-	)
+	sq.joins = append(sq.joins, join)
 
 	sq.joinsArgs = append(sq.joinsArgs, args...)
 
 	return nil
 }
 
+func (sq *selectorQuery) pushCTE(name string, query interface{}) error {
+	cte := &exql.CTE{}
+
+	if i := strings.Index(name, "("); i > 0 && strings.HasSuffix(name, ")") {
+		columns := strings.Split(name[i+1:len(name)-1], ",")
+		fragments := make([]exql.Fragment, len(columns))
+		for j := range columns {
+			fragments[j] = exql.ColumnWithName(strings.TrimSpace(columns[j]))
+		}
+		cte.Columns = exql.JoinColumns(fragments...)
+		name = strings.TrimSpace(name[:i])
+	}
+	cte.Name = exql.ColumnWithName(name)
+
+	var args []interface{}
+	switch v := query.(type) {
+	case compilable:
+		c, err := v.compile()
+		if err != nil {
+			return err
+		}
+		var q string
+		q, args = Preprocess(c, v.Arguments())
+		cte.Query = exql.RawValue(q)
+	case db.RawValue:
+		var q string
+		q, args = Preprocess(v.Raw(), v.Arguments())
+		cte.Query = exql.RawValue(q)
+	default:
+		return fmt.Errorf("unexpected argument type %T for With() argument", query)
+	}
+
+	sq.with = append(sq.with, cte)
+	sq.withArgs = append(sq.withArgs, args...)
+
+	return nil
+}
+
+// pushCompound combines the query built so far with the given selector. Once
+// a selector is part of a compound statement OrderBy(), Limit() and Offset()
+// apply to the whole statement.
+func (sq *selectorQuery) pushCompound(t *exql.Template, operator string, other Selector) error {
+	if sq.compound == nil {
+		// Common table expressions must precede the whole compound statement.
+		first := *sq
+		first.with, first.withArgs = nil, nil
+		first.amendFn = nil
+
+		c, err := first.statement().Compile(t)
+		if err != nil {
+			return err
+		}
+		q, args := Preprocess(c, first.arguments())
+
+		*sq = selectorQuery{
+			with:      sq.with,
+			withArgs:  sq.withArgs,
+			recursive: sq.recursive,
+			amendFn:   sq.amendFn,
+			compound: &exql.Compound{
+				Terms: []*exql.CompoundTerm{
+					{Query: exql.RawValue(q)},
+				},
+			},
+			compoundArgs: args,
+		}
+	}
+
+	v, ok := other.(compilable)
+	if !ok {
+		return fmt.Errorf("unexpected argument type %T for compound statement", other)
+	}
+	c, err := v.compile()
+	if err != nil {
+		return err
+	}
+	q, args := Preprocess(c, v.Arguments())
+
+	sq.compound.Terms = append(sq.compound.Terms, &exql.CompoundTerm{
+		Operator: operator,
+		Query:    exql.RawValue(q),
+	})
+	sq.compoundArgs = append(sq.compoundArgs, args...)
+
+	return nil
+}
+
+// pushLock returns the locking clause of the query, rows are locked for update
+// unless other mode is set.
+func (sq *selectorQuery) pushLock() *exql.Lock {
+	if sq.lock == nil {
+		sq.lock = &exql.Lock{Mode: exql.LockForUpdate}
+	}
+	return sq.lock
+}
+
 type selector struct {
 	builder *sqlBuilder
 
@@ -124,7 +297,7 @@ func (sel *selector) SQLBuilder() *sqlBuilder {
 }
 
 func (sel *selector) String() string {
-	s, err := sel.Compile()
+	s, err := sel.compile()
 	if err != nil {
 		panic(err.Error())
 	}
@@ -148,6 +321,9 @@ func (sel *selector) From(tables ...interface{}) Selector {
 			if err != nil {
 				return err
 			}
+			if err := sq.scopes.pushTables(sel.SQLBuilder(), tables); err != nil {
+				return err
+			}
 			sq.table = exql.JoinColumns(fragments...)
 			sq.tableArgs = args
 			return nil
@@ -193,6 +369,23 @@ func (sel *selector) Distinct(exps ...interface{}) Selector {
 	})
 }
 
+func (sel *selector) DistinctOn(exps ...interface{}) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		if len(exps) == 0 {
+			return errMissingDistinctOnColumns
+		}
+		f, args, err := columnFragments(exps)
+		if err != nil {
+			return err
+		}
+		sq.distinctOn = &exql.DistinctOn{
+			Columns: exql.JoinColumns(f...),
+		}
+		sq.distinctOnArgs = args
+		return nil
+	})
+}
+
 func (sel *selector) Where(terms ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
 		if len(terms) == 1 && terms[0] == nil {
@@ -216,8 +409,25 @@ func (sel *selector) Amend(fn func(string) string) Selector {
 	})
 }
 
+func (sel *selector) Timeout(timeout time.Duration) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		sq.timeout = timeout
+		return nil
+	})
+}
+
+func (sel *selector) Cursor(name string, fetchSize int) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		if name == "" || fetchSize < 1 {
+			return errInvalidCursor
+		}
+		sq.cursorName, sq.cursorFetchSize = name, fetchSize
+		return nil
+	})
+}
+
 func (sel *selector) Arguments() []interface{} {
-	sq, err := sel.build()
+	sq, err := sel.build(nil)
 	if err != nil {
 		return nil
 	}
@@ -249,60 +459,82 @@ func (sel *selector) OrderBy(columns ...interface{}) Selector {
 			return nil
 		}
 
-		var sortColumns exql.SortColumns
+		sortColumns, args, err := sortFragments(sel.SQLBuilder().t.Template, columns)
+		if err != nil {
+			return err
+		}
+
+		sq.orderBy = &exql.OrderBy{
+			SortColumns: sortColumns,
+		}
+		sq.orderByArgs = append(sq.orderByArgs, args...)
+		return nil
+	})
+}
 
-		for i := range columns {
-			var sort *exql.SortColumn
+// sortFragments transforms the arguments of an OrderBy() call into sort
+// columns. The template tells whether full-text ranks take the text to search
+// for as an argument, it's assumed they do if it's nil.
+func sortFragments(t *exql.Template, columns []interface{}) (*exql.SortColumns, []interface{}, error) {
+	var sortColumns exql.SortColumns
+	args := []interface{}{}
 
-			switch value := columns[i].(type) {
-			case db.RawValue:
-				query, args := Preprocess(value.Raw(), value.Arguments())
-				sort = &exql.SortColumn{
-					Column: exql.RawValue(query),
-				}
-				sq.orderByArgs = append(sq.orderByArgs, args...)
-			case db.Function:
-				fnName, fnArgs := value.Name(), value.Arguments()
-				if len(fnArgs) == 0 {
-					fnName = fnName + "()"
-				} else {
-					fnName = fnName + "(?" + strings.Repeat("?, ", len(fnArgs)-1) + ")"
-				}
-				fnName, fnArgs = Preprocess(fnName, fnArgs)
+	for i := range columns {
+		var sort *exql.SortColumn
+
+		switch value := columns[i].(type) {
+		case db.RawValue:
+			query, rawArgs := Preprocess(value.Raw(), value.Arguments())
+			sort = &exql.SortColumn{
+				Column: exql.RawValue(query),
+			}
+			args = append(args, rawArgs...)
+		case db.Function:
+			fnName, fnArgs := value.Name(), value.Arguments()
+			if len(fnArgs) == 0 {
+				fnName = fnName + "()"
+			} else {
+				fnName = fnName + "(?" + strings.Repeat("?, ", len(fnArgs)-1) + ")"
+			}
+			fnName, fnArgs = Preprocess(fnName, fnArgs)
+			sort = &exql.SortColumn{
+				Column: exql.RawValue(fnName),
+			}
+			args = append(args, fnArgs...)
+		case db.FullTextRankValue:
+			search := value.FullText()
+			sort = &exql.SortColumn{
+				Column: exql.FullTextRankWithColumn(search.Column()),
+			}
+			if t == nil || strings.Contains(t.FullTextRankLayout, "?") {
+				args = append(args, search.Query())
+			}
+		case string:
+			if strings.HasPrefix(value, "-") {
 				sort = &exql.SortColumn{
-					Column: exql.RawValue(fnName),
+					Column: exql.ColumnWithName(value[1:]),
+					Order:  exql.Descendent,
 				}
-				sq.orderByArgs = append(sq.orderByArgs, fnArgs...)
-			case string:
-				if strings.HasPrefix(value, "-") {
-					sort = &exql.SortColumn{
-						Column: exql.ColumnWithName(value[1:]),
-						Order:  exql.Descendent,
-					}
-				} else {
-					chunks := strings.SplitN(value, " ", 2)
+			} else {
+				chunks := strings.SplitN(value, " ", 2)
 
-					order := exql.Ascendent
-					if len(chunks) > 1 && strings.ToUpper(chunks[1]) == "DESC" {
-						order = exql.Descendent
-					}
+				order := exql.Ascendent
+				if len(chunks) > 1 && strings.ToUpper(chunks[1]) == "DESC" {
+					order = exql.Descendent
+				}
 
-					sort = &exql.SortColumn{
-						Column: exql.ColumnWithName(chunks[0]),
-						Order:  order,
-					}
+				sort = &exql.SortColumn{
+					Column: exql.ColumnWithName(chunks[0]),
+					Order:  order,
 				}
-			default:
-				return fmt.Errorf("Can't sort by type %T", value)
 			}
-			sortColumns.Columns = append(sortColumns.Columns, sort)
+		default:
+			return nil, nil, fmt.Errorf("Can't sort by type %T", value)
 		}
+		sortColumns.Columns = append(sortColumns.Columns, sort)
+	}
 
-		sq.orderBy = &exql.OrderBy{
-			SortColumns: &sortColumns,
-		}
-		return nil
-	})
+	return &sortColumns, args, nil
 }
 
 func (sel *selector) Using(columns ...interface{}) Selector {
@@ -332,31 +564,31 @@ func (sel *selector) Using(columns ...interface{}) Selector {
 
 func (sel *selector) FullJoin(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("FULL", tables)
+		return sq.pushJoin(sel.SQLBuilder(), "FULL", tables)
 	})
 }
 
 func (sel *selector) CrossJoin(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("CROSS", tables)
+		return sq.pushJoin(sel.SQLBuilder(), "CROSS", tables)
 	})
 }
 
 func (sel *selector) RightJoin(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("RIGHT", tables)
+		return sq.pushJoin(sel.SQLBuilder(), "RIGHT", tables)
 	})
 }
 
 func (sel *selector) LeftJoin(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("LEFT", tables)
+		return sq.pushJoin(sel.SQLBuilder(), "LEFT", tables)
 	})
 }
 
 func (sel *selector) Join(tables ...interface{}) Selector {
 	return sel.frame(func(sq *selectorQuery) error {
-		return sq.pushJoin("", tables)
+		return sq.pushJoin(sel.SQLBuilder(), "", tables)
 	})
 }
 
@@ -374,6 +606,7 @@ func (sel *selector) On(terms ...interface{}) Selector {
 		}
 
 		w, a := sel.SQLBuilder().t.toWhereWithArguments(terms)
+		a = sq.scopes.on(sel.SQLBuilder(), lastJoin, &w, a)
 		o := exql.On(w)
 
 		lastJoin.On = &o
@@ -425,8 +658,137 @@ func (sel *selector) As(alias string) Selector {
 	})
 }
 
+func (sel *selector) With(name string, query interface{}) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		return sq.pushCTE(name, query)
+	})
+}
+
+func (sel *selector) WithRecursive(name string, query interface{}) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		sq.recursive = true
+		return sq.pushCTE(name, query)
+	})
+}
+
+// withCTEs replaces the common table expressions of the selector with the ones
+// of from, or removes them if from is nil.
+func (sel *selector) withCTEs(from *selectorQuery) *selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		sq.with, sq.withArgs, sq.recursive = nil, nil, false
+		if from != nil {
+			sq.with, sq.withArgs, sq.recursive = from.with, from.withArgs, from.recursive
+		}
+		return nil
+	})
+}
+
+func (sel *selector) Union(other Selector) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		return sq.pushCompound(sel.template(), exql.Union, other)
+	})
+}
+
+func (sel *selector) UnionAll(other Selector) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		return sq.pushCompound(sel.template(), exql.UnionAll, other)
+	})
+}
+
+func (sel *selector) Intersect(other Selector) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		return sq.pushCompound(sel.template(), exql.Intersect, other)
+	})
+}
+
+func (sel *selector) Except(other Selector) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		return sq.pushCompound(sel.template(), exql.Except, other)
+	})
+}
+
+func (sel *selector) ForUpdate() Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		sq.pushLock().Mode = exql.LockForUpdate
+		return nil
+	})
+}
+
+func (sel *selector) ForShare() Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		sq.pushLock().Mode = exql.LockForShare
+		return nil
+	})
+}
+
+func (sel *selector) SkipLocked() Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		lock := sq.pushLock()
+		lock.SkipLocked, lock.NoWait = true, false
+		return nil
+	})
+}
+
+func (sel *selector) NoWait() Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		lock := sq.pushLock()
+		lock.NoWait, lock.SkipLocked = true, false
+		return nil
+	})
+}
+
+func (sel *selector) AsOf(timestamp interface{}) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		var literal string
+		switch t := timestamp.(type) {
+		case time.Time:
+			literal = "'" + t.UTC().Format("2006-01-02 15:04:05.999999") + "'"
+		case time.Duration:
+			literal = "'" + strconv.FormatFloat(t.Seconds(), 'f', -1, 64) + "s'"
+		default:
+			var err error
+			if literal, err = sqlLiteral(timestamp); err != nil {
+				return err
+			}
+		}
+		sq.asOf = &exql.AsOf{Timestamp: literal}
+		return nil
+	})
+}
+
+func (sel *selector) Final() Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		sq.final = &exql.Final{}
+		return nil
+	})
+}
+
+func (sel *selector) LimitBy(limit int, columns ...string) Selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		if len(columns) == 0 {
+			return errMissingLimitByColumns
+		}
+		fragments := make([]exql.Fragment, len(columns))
+		for i := range columns {
+			fragments[i] = exql.ColumnWithName(columns[i])
+		}
+		sq.limitBy = &exql.LimitBy{
+			Limit:   limit,
+			Columns: exql.JoinColumns(fragments...),
+		}
+		return nil
+	})
+}
+
+func (sel *selector) unlocked() *selector {
+	return sel.frame(func(sq *selectorQuery) error {
+		sq.lock = nil
+		return nil
+	})
+}
+
 func (sel *selector) statement() *exql.Statement {
-	sq, _ := sel.build()
+	sq, _ := sel.build(nil)
 	return sq.statement()
 }
 
@@ -435,12 +797,12 @@ func (sel *selector) QueryRow() (*sql.Row, error) {
 }
 
 func (sel *selector) QueryRowContext(ctx context.Context) (*sql.Row, error) {
-	sq, err := sel.build()
+	sq, err := sel.build(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return sel.SQLBuilder().sess.StatementQueryRow(ctx, sq.statement(), sq.arguments()...)
+	return sel.SQLBuilder().sess.StatementQueryRow(sq.context(ctx), sq.statement(), sq.arguments()...)
 }
 
 func (sel *selector) Prepare() (*sql.Stmt, error) {
@@ -448,7 +810,7 @@ func (sel *selector) Prepare() (*sql.Stmt, error) {
 }
 
 func (sel *selector) PrepareContext(ctx context.Context) (*sql.Stmt, error) {
-	sq, err := sel.build()
+	sq, err := sel.build(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -460,11 +822,37 @@ func (sel *selector) Query() (*sql.Rows, error) {
 }
 
 func (sel *selector) QueryContext(ctx context.Context) (*sql.Rows, error) {
-	sq, err := sel.build()
+	sq, err := sel.build(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return sel.SQLBuilder().sess.StatementQuery(ctx, sq.statement(), sq.arguments()...)
+	return sel.statementQuery(sq.context(ctx), sq)
+}
+
+// statementQuery runs the given query, through a server-side cursor if one
+// was given by Cursor.
+func (sel *selector) statementQuery(ctx context.Context, sq *selectorQuery) (*sql.Rows, error) {
+	sess := sel.SQLBuilder().sess
+	if sq.cursorName == "" {
+		return sess.StatementQuery(ctx, sq.statement(), sq.arguments()...)
+	}
+	cursorSess, ok := sess.(hasStatementCursor)
+	if !ok {
+		return nil, db.ErrUnsupported
+	}
+	return cursorSess.StatementCursor(ctx, sq.statement(), sq.cursorName, sq.cursorFetchSize, sq.arguments()...)
+}
+
+func (sel *selector) Explain(ctx context.Context) (string, error) {
+	sq, err := sel.build(ctx)
+	if err != nil {
+		return "", err
+	}
+	sess, ok := sel.SQLBuilder().sess.(hasStatementExplain)
+	if !ok {
+		return "", db.ErrUnsupported
+	}
+	return sess.StatementExplain(sq.context(ctx), sq.statement(), sq.arguments()...)
 }
 
 func (sel *selector) Iterator() Iterator {
@@ -473,12 +861,12 @@ func (sel *selector) Iterator() Iterator {
 
 func (sel *selector) IteratorContext(ctx context.Context) Iterator {
 	sess := sel.SQLBuilder().sess
-	sq, err := sel.build()
+	sq, err := sel.build(ctx)
 	if err != nil {
 		return &iterator{sess, nil, err}
 	}
 
-	rows, err := sess.StatementQuery(ctx, sq.statement(), sq.arguments()...)
+	rows, err := sel.statementQuery(sq.context(ctx), sq)
 	return &iterator{sess, rows, err}
 }
 
@@ -487,23 +875,120 @@ func (sel *selector) Paginate(pageSize uint) Paginator {
 }
 
 func (sel *selector) All(destSlice interface{}) error {
-	return sel.Iterator().All(destSlice)
+	return sel.inlineColumns(destSlice).Iterator().All(destSlice)
+}
+
+func (sel *selector) AllContext(ctx context.Context, destSlice interface{}) error {
+	return sel.inlineColumns(destSlice).IteratorContext(ctx).All(destSlice)
 }
 
 func (sel *selector) One(dest interface{}) error {
-	return sel.Iterator().One(dest)
+	return sel.inlineColumns(dest).Iterator().One(dest)
+}
+
+func (sel *selector) OneContext(ctx context.Context, dest interface{}) error {
+	return sel.inlineColumns(dest).IteratorContext(ctx).One(dest)
+}
+
+// inlineColumns expands the "alias.*" columns of the query into the columns
+// of the struct field of dest that is tagged with inline=alias, each one of
+// them is selected as "alias.column" so joined rows can be scanned into
+// nested structs.
+func (sel *selector) inlineColumns(dest interface{}) *selector {
+	t := reflect.TypeOf(dest)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return sel
+	}
+
+	m := sessionMapper(sel.SQLBuilder().sess)
+	inline := map[string][]string{}
+	for _, fi := range m.TypeMap(t).Index {
+		if fi.Name == "" || fi.Embedded {
+			continue
+		}
+		parent := fi.Parent
+		for parent != nil && parent.Embedded && parent.Options["inline"] == "" {
+			parent = parent.Parent
+		}
+		if parent == nil || parent.Options["inline"] == "" || parent.Parent != m.TypeMap(t).Tree {
+			continue
+		}
+		alias := parent.Options["inline"]
+		inline[alias] = append(inline[alias], alias+"."+fi.Name+" AS "+fi.Path)
+	}
+	if len(inline) == 0 {
+		return sel
+	}
+
+	return sel.frame(func(sq *selectorQuery) error {
+		if sq.columns == nil {
+			return nil
+		}
+		columns := make([]exql.Fragment, 0, len(sq.columns.Columns))
+		for _, c := range sq.columns.Columns {
+			if column, ok := c.(*exql.Column); ok {
+				if name, ok := column.Name.(string); ok && strings.HasSuffix(name, ".*") {
+					if names, ok := inline[strings.TrimSuffix(name, ".*")]; ok {
+						for i := range names {
+							columns = append(columns, exql.ColumnWithName(names[i]))
+						}
+						continue
+					}
+				}
+			}
+			columns = append(columns, c)
+		}
+		sq.columns = exql.JoinColumns(columns...)
+		return nil
+	})
 }
 
-func (sel *selector) build() (*selectorQuery, error) {
-	sq, err := immutable.FastForward(sel)
+func (sel *selector) build(ctx context.Context) (*selectorQuery, error) {
+	// Scopes are the ones of the context the statement runs within.
+	sqi, err := immutable.FastForwardFrom(sel, &selectorQuery{scopes: scopedTables{ctx: ctx}})
+	if err != nil {
+		return nil, err
+	}
+	sq := sqi.(*selectorQuery)
+	if sq.compound != nil && (sq.table != nil || sq.columns != nil || sq.joins != nil || sq.where != nil ||
+		sq.groupBy != nil || sq.distinct || sq.distinctOn != nil) {
+		// These clauses would apply to the last query of the compound
+		// statement only.
+		return nil, errCompoundClause
+	}
+	tables := 0
+	if sq.table != nil {
+		tables = len(sq.table.Columns)
+	}
+	scopes, err := sq.scopes.where(tables, sq.joins)
 	if err != nil {
 		return nil, err
 	}
-	return sq.(*selectorQuery), nil
+	if len(scopes) > 0 {
+		if err := sq.and(sel.SQLBuilder(), scopes...); err != nil {
+			return nil, err
+		}
+	}
+	return sq, nil
 }
 
-func (sel *selector) Compile() (string, error) {
-	return sel.statement().Compile(sel.template())
+func (sel *selector) Compile() (string, []interface{}, error) {
+	sq, err := sel.build(nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return sel.SQLBuilder().compileStatement(sq.statement(), sq.arguments())
+}
+
+func (sel *selector) compile() (string, error) {
+	sq, err := sel.build(nil)
+	if err != nil {
+		return "", err
+	}
+	return sq.statement().Compile(sel.template())
 }
 
 func (sel *selector) Prev() immutable.Immutable {
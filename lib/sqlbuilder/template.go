@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"fmt"
 	"strings"
+	"time"
 
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter/exql"
@@ -11,10 +12,14 @@ import (
 
 type templateWithUtils struct {
 	*exql.Template
+
+	// sess is the session of the builder, if any, it tells how structs are
+	// mapped.
+	sess interface{}
 }
 
 func newTemplateWithUtils(template *exql.Template) *templateWithUtils {
-	return &templateWithUtils{template}
+	return &templateWithUtils{Template: template}
 }
 
 func (tu *templateWithUtils) PlaceholderValue(in interface{}) (exql.Fragment, []interface{}) {
@@ -84,6 +89,10 @@ func (tu *templateWithUtils) toWhereWithArguments(term interface{}) (where exql.
 		where.Conditions = []exql.Fragment{exql.RawValue(r)}
 		args = append(args, v...)
 		return
+	case db.FullTextValue:
+		where.Conditions = []exql.Fragment{exql.FullTextWithColumn(t.Column())}
+		args = append(args, t.Query())
+		return
 	case db.Constraints:
 		for _, c := range t.Constraints() {
 			w, v := tu.toWhereWithArguments(c)
@@ -161,6 +170,8 @@ func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnVal
 			if len(chunks) > 1 {
 				columnValue.Operator = chunks[1]
 			}
+		} else if jsonPath, ok := t.Key().(db.JSONPathValue); ok {
+			columnValue.Column = exql.JSONPathWithColumn(jsonPath.Column(), jsonPath.Keys()...)
 		} else {
 			if rawValue, ok := t.Key().(db.RawValue); ok {
 				columnValue.Column = exql.RawValue(rawValue.Raw())
@@ -254,6 +265,53 @@ func (tu *templateWithUtils) toColumnValues(term interface{}) (cv exql.ColumnVal
 	panic(fmt.Sprintf("Unknown term type %T.", term))
 }
 
+// toAssignments converts the terms given to Updater.Set (or Inserter.DoUpdate)
+// into column values, fields tagged with auto_now are set to now.
+func (tu *templateWithUtils) toAssignments(terms []interface{}, now time.Time) ([]exql.Fragment, []interface{}) {
+	if len(terms) == 1 {
+		options := sessionMapOptions(tu.sess)
+		options.AutoNow = now
+		// Keys are given on insert only.
+		options.NewUUID = nil
+		ff, vv, err := Map(terms[0], &options)
+		if err == nil && len(ff) > 0 {
+			cvs := make([]exql.Fragment, 0, len(ff))
+			args := make([]interface{}, 0, len(vv))
+
+			for i := range ff {
+				cv, localArgs := tu.toAssignment(ff[i], vv[i])
+				args = append(args, localArgs...)
+				cvs = append(cvs, cv)
+			}
+
+			return cvs, args
+		}
+	}
+
+	cv, args := tu.setColumnValues(terms)
+	return cv.ColumnValues, args
+}
+
+// toAssignment sets the given column to value, ":column" within a raw value
+// is replaced by the name of the column.
+func (tu *templateWithUtils) toAssignment(column string, value interface{}) (*exql.ColumnValue, []interface{}) {
+	cv := &exql.ColumnValue{
+		Column:   exql.ColumnWithName(column),
+		Operator: tu.AssignmentOperator,
+	}
+
+	if raw, ok := value.(db.RawValue); ok && strings.Contains(raw.Raw(), ":column") {
+		name, err := cv.Column.Compile(tu.Template)
+		if err == nil {
+			value = db.Raw(strings.Replace(raw.Raw(), ":column", name, -1), raw.Arguments()...)
+		}
+	}
+
+	var args []interface{}
+	cv.Value, args = tu.PlaceholderValue(value)
+	return cv, args
+}
+
 func (tu *templateWithUtils) setColumnValues(term interface{}) (cv exql.ColumnValues, args []interface{}) {
 	args = []interface{}{}
 
@@ -298,6 +356,17 @@ func (tu *templateWithUtils) setColumnValues(term interface{}) (cv exql.ColumnVa
 			cv.ColumnValues = append(cv.ColumnValues, &columnValue)
 		}
 		return cv, args
+	case db.Cond:
+		ff, vv, err := Map(t, &MapOptions{Mapper: sessionMapper(tu.sess)})
+		if err != nil {
+			panic(err.Error())
+		}
+		for i := range ff {
+			p, q := tu.toAssignment(ff[i], vv[i])
+			cv.ColumnValues = append(cv.ColumnValues, p)
+			args = append(args, q...)
+		}
+		return cv, args
 	case db.RawValue:
 		columnValue := exql.ColumnValue{}
 		p, q := Preprocess(t.Raw(), t.Arguments())
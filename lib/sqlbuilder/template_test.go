@@ -22,6 +22,14 @@ const (
 	defaultTableAliasLayout    = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	defaultColumnAliasLayout   = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	defaultSortByColumnLayout  = `{{.Column}} {{.Order}}`
+	defaultWindowLayout        = `{{.Function}} OVER ({{.Definition}})`
+	defaultJSONPathLayout      = `{{.Column}}{{range .Segments}}{{if .Last}}->>{{else}}->{{end}}{{if .Index}}{{.Key}}{{else}}'{{.Key}}'{{end}}{{end}}`
+	defaultFullTextLayout      = `to_tsvector({{.Column}}) @@ plainto_tsquery(?)`
+	defaultFullTextRankLayout  = `ts_rank(to_tsvector({{.Column}}), plainto_tsquery(?)) DESC`
+	defaultDistinctOnLayout    = `DISTINCT ON ({{.Columns}})`
+	defaultLockLayout          = `{{if .Update}}FOR UPDATE{{else}}FOR SHARE{{end}}{{if .SkipLocked}} SKIP LOCKED{{end}}{{if .NoWait}} NOWAIT{{end}}`
+	defaultUpdateFromLayout    = `{{if .Tables}}FROM {{.Tables}}{{end}} {{.Joins}}`
+	defaultDeleteUsingLayout   = `{{if .Tables}}USING {{.Tables}}{{end}} {{.Joins}}`
 
 	defaultOrderByLayout = `
     {{if .SortColumns}}
@@ -63,27 +71,45 @@ const (
     {{end}}
   `
 
+	defaultWithLayout = `
+    WITH {{if .Recursive}}RECURSIVE {{end}}{{.CTEs}}
+  `
+
+	defaultCompoundLayout = `
+    {{range .}}{{if .Operator}} {{.Operator}} {{end}}({{.Query}}){{end}}
+  `
+
 	defaultSelectLayout = `
-    SELECT
-      {{if .Distinct}}
-        DISTINCT
-      {{end}}
+    {{if .With}}
+      {{.With}}
+    {{end}}
 
-      {{if .Columns}}
-        {{.Columns}}
-      {{else}}
-        *
-      {{end}}
+    {{if .Compound}}
+      {{.Compound}}
+    {{else}}
+      SELECT
+        {{if .DistinctOn}}
+          {{.DistinctOn}}
+        {{else if .Distinct}}
+          DISTINCT
+        {{end}}
 
-      {{if .Table}}
-        FROM {{.Table}}
-      {{end}}
+        {{if .Columns}}
+          {{.Columns}}
+        {{else}}
+          *
+        {{end}}
 
-      {{.Joins}}
+        {{if .Table}}
+          FROM {{.Table}}
+        {{end}}
 
-      {{.Where}}
+        {{.Joins}}
+
+        {{.Where}}
 
-      {{.GroupBy}}
+        {{.GroupBy}}
+    {{end}}
 
       {{.OrderBy}}
 
@@ -94,17 +120,29 @@ const (
       {{if .Offset}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{if .Lock}}
+        {{.Lock}}
+      {{end}}
   `
 	defaultDeleteLayout = `
     DELETE
       FROM {{.Table}}
+      {{.From}}
       {{.Where}}
+    {{if .Returning}}
+      RETURNING {{.Returning}}
+    {{end}}
   `
 	defaultUpdateLayout = `
     UPDATE
       {{.Table}}
     SET {{.ColumnValues}}
+      {{.From}}
       {{ .Where }}
+    {{if .Returning}}
+      RETURNING {{.Returning}}
+    {{end}}
   `
 
 	defaultCountLayout = `
@@ -125,17 +163,36 @@ const (
 	defaultInsertLayout = `
     INSERT INTO {{.Table}}
       {{if .Columns }}({{.Columns}}){{end}}
-    VALUES
-    {{if .Values}}
-      {{.Values}}
+    {{if .Query}}
+      {{.Query}}
     {{else}}
-      (default)
+      VALUES
+      {{if .Values}}
+        {{.Values}}
+      {{else}}
+        (default)
+      {{end}}
+    {{end}}
+    {{if .OnConflict}}
+      {{.OnConflict}}
     {{end}}
     {{if .Returning}}
       RETURNING {{.Returning}}
     {{end}}
   `
 
+	defaultOnConflictLayout = `
+    ON CONFLICT
+      {{if .Target}}({{range $i, $c := .Target}}{{if $i}}, {{end}}{{$c}}{{end}}){{end}}
+    {{if .Update}}
+      DO UPDATE SET {{.Update}}
+    {{else}}
+      DO NOTHING
+    {{end}}
+  `
+
+	defaultExcludedColumn = `EXCLUDED.{{.}}`
+
 	defaultTruncateLayout = `
     TRUNCATE TABLE {{.Table}}
   `
@@ -150,6 +207,32 @@ const (
 
 	defaultGroupByColumnLayout = `{{.Column}}`
 
+	defaultCreateTableLayout = `
+    CREATE TABLE {{.Table}} ({{.Definitions}})
+  `
+
+	defaultAlterTableLayout = `
+    ALTER TABLE {{.Table}} {{.Definitions}}
+  `
+
+	defaultCreateIndexLayout = `
+    CREATE {{if .Unique}}UNIQUE {{end}}INDEX {{.Index}} ON {{.Table}} ({{.Columns}})
+  `
+
+	defaultAddColumnLayout = `ADD COLUMN {{.}}`
+
+	defaultDropColumnLayout = `DROP COLUMN {{.}}`
+
+	defaultColumnDefinitionLayout = `
+    {{.Name}}
+    {{if .AutoIncrement}}{{if eq .Type "BIGINT"}}BIGSERIAL{{else}}SERIAL{{end}}{{else}}{{.Type}}{{end}}
+    {{if .PrimaryKey}}PRIMARY KEY{{end}}
+    {{if .NotNull}}NOT NULL{{end}}
+    {{if .Unique}}UNIQUE{{end}}
+    {{if .Default}}DEFAULT {{.Default}}{{end}}
+    {{if .References}}REFERENCES {{.References}}{{end}}
+  `
+
 	defaultGroupByLayout = `
     {{if .GroupColumns}}
       GROUP BY {{.GroupColumns}}
@@ -157,36 +240,69 @@ const (
   `
 )
 
+var defaultColumnTypes = map[exql.ColumnType]string{
+	exql.TypeBoolean:    `BOOLEAN`,
+	exql.TypeInteger:    `INTEGER`,
+	exql.TypeBigInteger: `BIGINT`,
+	exql.TypeFloat:      `DOUBLE PRECISION`,
+	exql.TypeDecimal:    `NUMERIC({{.Precision}}, {{.Scale}})`,
+	exql.TypeString:     `VARCHAR({{.Size}})`,
+	exql.TypeText:       `TEXT`,
+	exql.TypeTimestamp:  `TIMESTAMP`,
+	exql.TypeDate:       `DATE`,
+	exql.TypeBinary:     `BYTEA`,
+	exql.TypeJSON:       `JSONB`,
+}
+
 var testTemplate = exql.Template{
-	ColumnSeparator:     defaultColumnSeparator,
-	IdentifierSeparator: defaultIdentifierSeparator,
-	IdentifierQuote:     defaultIdentifierQuote,
-	ValueSeparator:      defaultValueSeparator,
-	ValueQuote:          defaultValueQuote,
-	AndKeyword:          defaultAndKeyword,
-	OrKeyword:           defaultOrKeyword,
-	DescKeyword:         defaultDescKeyword,
-	AscKeyword:          defaultAscKeyword,
-	AssignmentOperator:  defaultAssignmentOperator,
-	ClauseGroup:         defaultClauseGroup,
-	ClauseOperator:      defaultClauseOperator,
-	ColumnValue:         defaultColumnValue,
-	TableAliasLayout:    defaultTableAliasLayout,
-	ColumnAliasLayout:   defaultColumnAliasLayout,
-	SortByColumnLayout:  defaultSortByColumnLayout,
-	WhereLayout:         defaultWhereLayout,
-	OnLayout:            defaultOnLayout,
-	UsingLayout:         defaultUsingLayout,
-	JoinLayout:          defaultJoinLayout,
-	OrderByLayout:       defaultOrderByLayout,
-	InsertLayout:        defaultInsertLayout,
-	SelectLayout:        defaultSelectLayout,
-	UpdateLayout:        defaultUpdateLayout,
-	DeleteLayout:        defaultDeleteLayout,
-	TruncateLayout:      defaultTruncateLayout,
-	DropDatabaseLayout:  defaultDropDatabaseLayout,
-	DropTableLayout:     defaultDropTableLayout,
-	CountLayout:         defaultCountLayout,
-	GroupByLayout:       defaultGroupByLayout,
-	Cache:               cache.NewCache(),
+	ColumnSeparator:        defaultColumnSeparator,
+	IdentifierSeparator:    defaultIdentifierSeparator,
+	IdentifierQuote:        defaultIdentifierQuote,
+	ValueSeparator:         defaultValueSeparator,
+	ValueQuote:             defaultValueQuote,
+	AndKeyword:             defaultAndKeyword,
+	OrKeyword:              defaultOrKeyword,
+	DescKeyword:            defaultDescKeyword,
+	AscKeyword:             defaultAscKeyword,
+	AssignmentOperator:     defaultAssignmentOperator,
+	ClauseGroup:            defaultClauseGroup,
+	ClauseOperator:         defaultClauseOperator,
+	ColumnValue:            defaultColumnValue,
+	TableAliasLayout:       defaultTableAliasLayout,
+	ColumnAliasLayout:      defaultColumnAliasLayout,
+	SortByColumnLayout:     defaultSortByColumnLayout,
+	WhereLayout:            defaultWhereLayout,
+	WindowLayout:           defaultWindowLayout,
+	CompoundLayout:         defaultCompoundLayout,
+	WithLayout:             defaultWithLayout,
+	OnLayout:               defaultOnLayout,
+	UsingLayout:            defaultUsingLayout,
+	JoinLayout:             defaultJoinLayout,
+	JSONPathLayout:         defaultJSONPathLayout,
+	FullTextLayout:         defaultFullTextLayout,
+	FullTextRankLayout:     defaultFullTextRankLayout,
+	DistinctOnLayout:       defaultDistinctOnLayout,
+	LockLayout:             defaultLockLayout,
+	OrderByLayout:          defaultOrderByLayout,
+	InsertLayout:           defaultInsertLayout,
+	OnConflictLayout:       defaultOnConflictLayout,
+	ExcludedColumn:         defaultExcludedColumn,
+	SelectLayout:           defaultSelectLayout,
+	UpdateFromLayout:       defaultUpdateFromLayout,
+	UpdateLayout:           defaultUpdateLayout,
+	DeleteLayout:           defaultDeleteLayout,
+	DeleteUsingLayout:      defaultDeleteUsingLayout,
+	TruncateLayout:         defaultTruncateLayout,
+	DropDatabaseLayout:     defaultDropDatabaseLayout,
+	DropTableLayout:        defaultDropTableLayout,
+	CreateTableLayout:      defaultCreateTableLayout,
+	AlterTableLayout:       defaultAlterTableLayout,
+	CreateIndexLayout:      defaultCreateIndexLayout,
+	AddColumnLayout:        defaultAddColumnLayout,
+	DropColumnLayout:       defaultDropColumnLayout,
+	ColumnDefinitionLayout: defaultColumnDefinitionLayout,
+	ColumnTypes:            defaultColumnTypes,
+	CountLayout:            defaultCountLayout,
+	GroupByLayout:          defaultGroupByLayout,
+	Cache:                  cache.NewCache(),
 }
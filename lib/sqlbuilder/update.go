@@ -3,22 +3,34 @@ package sqlbuilder
 import (
 	"context"
 	"database/sql"
+	"errors"
 
+	"upper.io/db.v3"
 	"upper.io/db.v3/internal/immutable"
 	"upper.io/db.v3/internal/sqladapter/exql"
 )
 
+var errUpdateJoinWithoutFrom = errors.New("joins of an UPDATE statement require a table given to From() on this database")
+
 type updaterQuery struct {
 	table string
 
 	columnValues     *exql.ColumnValues
 	columnValuesArgs []interface{}
 
+	from fromTables
+
+	// fromFirst is true if the layout places the tables the statement reads
+	// from before the SET clause, see exql.Template.UpdateFromFirst.
+	fromFirst bool
+
 	limit int
 
 	where     *exql.Where
 	whereArgs []interface{}
 
+	returning []exql.Fragment
+
 	err error
 
 	amendFn func(string) string
@@ -36,6 +48,24 @@ func (uq *updaterQuery) and(b *sqlBuilder, terms ...interface{}) error {
 	return nil
 }
 
+// scope restricts the statement to the rows of the scoped tables it writes
+// and reads, the column a scoped table is restricted by can't be set.
+func (uq *updaterQuery) scope(b *sqlBuilder) error {
+	scope, err := b.tableScope(uq.from.scopes.ctx, uq.table)
+	if err != nil {
+		return err
+	}
+	if scope != nil && uq.columnValues != nil && scope.assigns(uq.columnValues.ColumnValues) {
+		return db.ErrTenantColumn
+	}
+
+	conds, err := uq.from.where(b, uq.table)
+	if err != nil || len(conds) == 0 {
+		return err
+	}
+	return uq.and(b, conds...)
+}
+
 func (uq *updaterQuery) statement() *exql.Statement {
 	stmt := &exql.Statement{
 		Type:         exql.Update,
@@ -43,6 +73,13 @@ func (uq *updaterQuery) statement() *exql.Statement {
 		ColumnValues: uq.columnValues,
 	}
 
+	if !uq.from.empty() {
+		stmt.From = &exql.UpdateFrom{
+			Tables: uq.from.tables,
+			Joins:  uq.from.joinConditions(),
+		}
+	}
+
 	if uq.where != nil {
 		stmt.Where = uq.where
 	}
@@ -51,14 +88,26 @@ func (uq *updaterQuery) statement() *exql.Statement {
 		stmt.Limit = exql.Limit(uq.limit)
 	}
 
+	if len(uq.returning) > 0 {
+		stmt.Returning = exql.ReturningColumns(uq.returning...)
+	}
+
 	stmt.SetAmendment(uq.amendFn)
 
 	return stmt
 }
 
 func (uq *updaterQuery) arguments() []interface{} {
+	if uq.fromFirst {
+		return joinArguments(
+			uq.from.arguments(),
+			uq.columnValuesArgs,
+			uq.whereArgs,
+		)
+	}
 	return joinArguments(
 		uq.columnValuesArgs,
+		uq.from.arguments(),
 		uq.whereArgs,
 	)
 }
@@ -84,7 +133,7 @@ func (upd *updater) template() *exql.Template {
 }
 
 func (upd *updater) String() string {
-	s, err := upd.Compile()
+	s, err := upd.compile()
 	if err != nil {
 		panic(err.Error())
 	}
@@ -108,39 +157,17 @@ func (upd *updater) Set(terms ...interface{}) Updater {
 			uq.columnValues = &exql.ColumnValues{}
 		}
 
-		if len(terms) == 1 {
-			ff, vv, err := Map(terms[0], nil)
-			if err == nil && len(ff) > 0 {
-				cvs := make([]exql.Fragment, 0, len(ff))
-				args := make([]interface{}, 0, len(vv))
-
-				for i := range ff {
-					cv := &exql.ColumnValue{
-						Column:   exql.ColumnWithName(ff[i]),
-						Operator: upd.SQLBuilder().t.AssignmentOperator,
-					}
-
-					var localArgs []interface{}
-					cv.Value, localArgs = upd.SQLBuilder().t.PlaceholderValue(vv[i])
-
-					args = append(args, localArgs...)
-					cvs = append(cvs, cv)
-				}
-
-				uq.columnValues.Insert(cvs...)
-				uq.columnValuesArgs = append(uq.columnValuesArgs, args...)
-
-				return nil
-			}
-		}
-
-		cv, arguments := upd.SQLBuilder().t.setColumnValues(terms)
-		uq.columnValues.Insert(cv.ColumnValues...)
-		uq.columnValuesArgs = append(uq.columnValuesArgs, arguments...)
+		cvs, args := upd.SQLBuilder().t.toAssignments(terms, upd.SQLBuilder().now())
+		uq.columnValues.Insert(cvs...)
+		uq.columnValuesArgs = append(uq.columnValuesArgs, args...)
 		return nil
 	})
 }
 
+func (upd *updater) SetExpr(column string, expr interface{}) Updater {
+	return upd.Set(db.Cond{column: expr})
+}
+
 func (upd *updater) Amend(fn func(string) string) Updater {
 	return upd.frame(func(uq *updaterQuery) error {
 		uq.amendFn = fn
@@ -149,7 +176,7 @@ func (upd *updater) Amend(fn func(string) string) Updater {
 }
 
 func (upd *updater) Arguments() []interface{} {
-	uq, err := upd.build()
+	uq, err := upd.build(nil)
 	if err != nil {
 		return nil
 	}
@@ -169,12 +196,36 @@ func (upd *updater) And(terms ...interface{}) Updater {
 	})
 }
 
+func (upd *updater) From(tables ...interface{}) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		return uq.from.pushTables(upd.SQLBuilder(), tables)
+	})
+}
+
+func (upd *updater) Join(tables ...interface{}) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		return uq.from.pushJoin(upd.SQLBuilder(), "", tables)
+	})
+}
+
+func (upd *updater) LeftJoin(tables ...interface{}) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		return uq.from.pushJoin(upd.SQLBuilder(), "LEFT", tables)
+	})
+}
+
+func (upd *updater) On(terms ...interface{}) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		return uq.from.on(upd.SQLBuilder(), terms)
+	})
+}
+
 func (upd *updater) Prepare() (*sql.Stmt, error) {
 	return upd.PrepareContext(upd.SQLBuilder().sess.Context())
 }
 
 func (upd *updater) PrepareContext(ctx context.Context) (*sql.Stmt, error) {
-	uq, err := upd.build()
+	uq, err := upd.build(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -186,13 +237,33 @@ func (upd *updater) Exec() (sql.Result, error) {
 }
 
 func (upd *updater) ExecContext(ctx context.Context) (sql.Result, error) {
-	uq, err := upd.build()
+	uq, err := upd.build(ctx)
 	if err != nil {
 		return nil, err
 	}
 	return upd.SQLBuilder().sess.StatementExec(ctx, uq.statement(), uq.arguments()...)
 }
 
+func (upd *updater) Returning(columns ...string) Updater {
+	return upd.frame(func(uq *updaterQuery) error {
+		columnsToFragments(&uq.returning, columns)
+		return nil
+	})
+}
+
+func (upd *updater) Iterator() Iterator {
+	return upd.IteratorContext(upd.SQLBuilder().sess.Context())
+}
+
+func (upd *updater) IteratorContext(ctx context.Context) Iterator {
+	uq, err := upd.build(ctx)
+	if err != nil {
+		return &iterator{upd.SQLBuilder().sess, nil, err}
+	}
+	rows, err := upd.SQLBuilder().sess.StatementQuery(ctx, uq.statement(), uq.arguments()...)
+	return &iterator{upd.SQLBuilder().sess, rows, err}
+}
+
 func (upd *updater) Limit(limit int) Updater {
 	return upd.frame(func(uq *updaterQuery) error {
 		uq.limit = limit
@@ -201,22 +272,40 @@ func (upd *updater) Limit(limit int) Updater {
 }
 
 func (upd *updater) statement() (*exql.Statement, error) {
-	iq, err := upd.build()
+	iq, err := upd.build(nil)
 	if err != nil {
 		return nil, err
 	}
 	return iq.statement(), nil
 }
 
-func (upd *updater) build() (*updaterQuery, error) {
-	uq, err := immutable.FastForward(upd)
+func (upd *updater) build(ctx context.Context) (*updaterQuery, error) {
+	// Scopes are the ones of the context the statement runs within.
+	uqi, err := immutable.FastForwardFrom(upd, &updaterQuery{from: fromTables{scopes: scopedTables{ctx: ctx}}})
 	if err != nil {
 		return nil, err
 	}
-	return uq.(*updaterQuery), nil
+	uq := uqi.(*updaterQuery)
+	uq.fromFirst = upd.template().UpdateFromFirst
+	if !uq.fromFirst && uq.from.tables == nil && len(uq.from.joins) > 0 {
+		// Joins follow the tables of the FROM clause, there must be one.
+		return nil, errUpdateJoinWithoutFrom
+	}
+	if err := uq.scope(upd.SQLBuilder()); err != nil {
+		return nil, err
+	}
+	return uq, nil
+}
+
+func (upd *updater) Compile() (string, []interface{}, error) {
+	uq, err := upd.build(nil)
+	if err != nil {
+		return "", nil, err
+	}
+	return upd.SQLBuilder().compileStatement(uq.statement(), uq.arguments())
 }
 
-func (upd *updater) Compile() (string, error) {
+func (upd *updater) compile() (string, error) {
 	s, err := upd.statement()
 	if err != nil {
 		return "", err
@@ -0,0 +1,72 @@
+package sqlbuilder
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/reflectx"
+)
+
+var (
+	uuidType   = reflect.TypeOf(db.UUID{})
+	valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// hasNewUUID is implemented by sessions that give the zero fields tagged with
+// "uuid" a value on insert.
+type hasNewUUID interface {
+	NewUUID(t reflect.Type) (interface{}, error)
+}
+
+// NewUUID returns a new version 7 UUID as a value that can be bound to a
+// field of type t, which can be a string, a 16-byte array like db.UUID, a byte
+// slice or a pointer to any of them. Arrays that can't be bound by
+// themselves are bound as a db.UUID.
+func NewUUID(t reflect.Type) (interface{}, error) {
+	u, err := db.NewUUIDv7()
+	if err != nil {
+		return nil, err
+	}
+
+	elemT := t
+	if elemT.Kind() == reflect.Ptr {
+		elemT = elemT.Elem()
+	}
+
+	var v reflect.Value
+	switch {
+	case elemT.Kind() == reflect.String:
+		v = reflect.ValueOf(u.String()).Convert(elemT)
+	case elemT.Kind() == reflect.Array && uuidType.ConvertibleTo(elemT):
+		if !elemT.Implements(valuerType) {
+			return u, nil
+		}
+		v = reflect.ValueOf(u).Convert(elemT)
+	case elemT.Kind() == reflect.Slice && elemT.Elem().Kind() == reflect.Uint8:
+		v = reflect.ValueOf(u[:]).Convert(elemT)
+	default:
+		return nil, fmt.Errorf("upper: can't use a field of type %v as an UUID", t)
+	}
+
+	if t.Kind() == reflect.Ptr {
+		ptr := reflect.New(elemT)
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	return v.Interface(), nil
+}
+
+// isZeroUUID reports whether the given field tagged with "uuid" has yet to be
+// given a value.
+func isZeroUUID(fld reflect.Value, fi *reflectx.FieldInfo) bool {
+	if !fld.IsValid() || fld.Kind() == reflect.Ptr && fld.IsNil() {
+		return true
+	}
+	if fld.Kind() == reflect.Array {
+		// isZeroField tells apart empty arrays only.
+		return reflect.DeepEqual(fi.Zero.Interface(), fld.Interface())
+	}
+	return isZeroField(fld, fi.Zero)
+}
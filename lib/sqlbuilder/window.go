@@ -0,0 +1,187 @@
+package sqlbuilder
+
+import (
+	"fmt"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/immutable"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+// FrameBound represents the start or the end of a window frame.
+type FrameBound string
+
+// Frame bounds that don't depend on an offset.
+const (
+	UnboundedPreceding FrameBound = "UNBOUNDED PRECEDING"
+	CurrentRow         FrameBound = "CURRENT ROW"
+	UnboundedFollowing FrameBound = "UNBOUNDED FOLLOWING"
+)
+
+// Preceding returns a frame bound that starts or ends n rows before the
+// current one.
+func Preceding(n uint) FrameBound {
+	return FrameBound(fmt.Sprintf("%d PRECEDING", n))
+}
+
+// Following returns a frame bound that starts or ends n rows after the
+// current one.
+func Following(n uint) FrameBound {
+	return FrameBound(fmt.Sprintf("%d FOLLOWING", n))
+}
+
+type windowQuery struct {
+	fn     exql.Fragment
+	fnArgs []interface{}
+
+	partitionBy     *exql.Columns
+	partitionByArgs []interface{}
+
+	orderBy     *exql.OrderBy
+	orderByArgs []interface{}
+
+	frame string
+	alias string
+}
+
+func (wq *windowQuery) arguments() []interface{} {
+	return joinArguments(
+		wq.fnArgs,
+		wq.partitionByArgs,
+		wq.orderByArgs,
+	)
+}
+
+func (wq *windowQuery) fragment() *exql.Window {
+	return &exql.Window{
+		Function:    wq.fn,
+		PartitionBy: wq.partitionBy,
+		OrderBy:     wq.orderBy,
+		Frame:       wq.frame,
+		Alias:       wq.alias,
+	}
+}
+
+type windowFunction struct {
+	fn   func(*windowQuery) error
+	prev *windowFunction
+}
+
+var _ = immutable.Immutable(&windowFunction{})
+
+// Over creates a window function call out of a db.Function or a db.RawValue,
+// the returned value can be used as a column of a Selector:
+//
+//	sess.Select(
+//	  "name",
+//	  sqlbuilder.Over(db.Func("ROW_NUMBER")).
+//	    PartitionBy("department").
+//	    OrderBy("-salary").
+//	    As("rank"),
+//	).From("employees")
+//
+// Functions that take column names as arguments, like LAG or LEAD, should be
+// given as a db.RawValue:
+//
+//	sqlbuilder.Over(db.Raw("LAG(price, 1)")).OrderBy("day")
+func Over(fn interface{}) WindowFunction {
+	w := &windowFunction{}
+	return w.frame(func(wq *windowQuery) error {
+		switch fn.(type) {
+		case db.Function, db.RawValue:
+		default:
+			return fmt.Errorf("unexpected argument type %T for Over() argument", fn)
+		}
+		f, args, err := columnFragments([]interface{}{fn})
+		if err != nil {
+			return err
+		}
+		wq.fn, wq.fnArgs = f[0], args
+		return nil
+	})
+}
+
+func (w *windowFunction) frame(fn func(*windowQuery) error) *windowFunction {
+	return &windowFunction{prev: w, fn: fn}
+}
+
+func (w *windowFunction) PartitionBy(columns ...interface{}) WindowFunction {
+	return w.frame(func(wq *windowQuery) error {
+		fragments, args, err := columnFragments(columns)
+		if err != nil {
+			return err
+		}
+		wq.partitionBy = exql.JoinColumns(fragments...)
+		wq.partitionByArgs = args
+		return nil
+	})
+}
+
+func (w *windowFunction) OrderBy(columns ...interface{}) WindowFunction {
+	return w.frame(func(wq *windowQuery) error {
+		sortColumns, args, err := sortFragments(nil, columns)
+		if err != nil {
+			return err
+		}
+		wq.orderBy = &exql.OrderBy{
+			SortColumns: sortColumns,
+		}
+		wq.orderByArgs = args
+		return nil
+	})
+}
+
+func (w *windowFunction) Rows(start FrameBound, end FrameBound) WindowFunction {
+	return w.frame(func(wq *windowQuery) error {
+		wq.frame = fmt.Sprintf("ROWS BETWEEN %s AND %s", start, end)
+		return nil
+	})
+}
+
+func (w *windowFunction) Range(start FrameBound, end FrameBound) WindowFunction {
+	return w.frame(func(wq *windowQuery) error {
+		wq.frame = fmt.Sprintf("RANGE BETWEEN %s AND %s", start, end)
+		return nil
+	})
+}
+
+func (w *windowFunction) As(alias string) WindowFunction {
+	return w.frame(func(wq *windowQuery) error {
+		wq.alias = alias
+		return nil
+	})
+}
+
+func (w *windowFunction) Arguments() []interface{} {
+	wq, err := w.build()
+	if err != nil {
+		return nil
+	}
+	return wq.arguments()
+}
+
+func (w *windowFunction) build() (*windowQuery, error) {
+	wq, err := immutable.FastForward(w)
+	if err != nil {
+		return nil, err
+	}
+	return wq.(*windowQuery), nil
+}
+
+func (w *windowFunction) Prev() immutable.Immutable {
+	if w == nil {
+		return nil
+	}
+	return w.prev
+}
+
+func (w *windowFunction) Fn(in interface{}) error {
+	if w.fn == nil {
+		return nil
+	}
+	return w.fn(in.(*windowQuery))
+}
+
+func (w *windowFunction) Base() interface{} {
+	return &windowQuery{}
+}
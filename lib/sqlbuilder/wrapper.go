@@ -26,6 +26,7 @@ import (
 	"database/sql"
 	"fmt"
 	"sync"
+	"time"
 
 	"upper.io/db.v3"
 )
@@ -58,6 +59,18 @@ type Tx interface {
 	// db.Tx adds Commit and Rollback methods to the transaction.
 	db.Tx
 
+	// NewTx creates and returns a nested transaction that runs on the given
+	// context. Nested transactions are delimited by savepoints: committing
+	// one releases its savepoint and rolling it back only discards the
+	// changes made after it was created. Adapters that do not support
+	// savepoints return db.ErrUnsupported.
+	NewTx(ctx context.Context) (Tx, error)
+
+	// Tx creates a nested transaction that is passed as argument to the fn
+	// function. If the fn function returns nil, the nested transaction is
+	// committed, else it is rolled back.
+	Tx(ctx context.Context, fn func(sess Tx) error) error
+
 	// Context returns the context used as default for queries on this transaction.
 	// If no context has been set, a default context.Background() is returned.
 	Context() context.Context
@@ -73,6 +86,50 @@ type Tx interface {
 
 	// TxOptions returns the defaultx TxOptions.
 	TxOptions() *sql.TxOptions
+
+	// AfterCommit adds a function to run once the transaction is committed,
+	// after the database confirms the commit, so side effects like cache
+	// invalidation or event publishing don't happen for changes that are
+	// discarded. Functions run just once, in the order they were added.
+	// Functions added to a nested transaction run once the top-level
+	// transaction is committed, and are discarded if the nested one is rolled
+	// back.
+	//
+	//  tx.AfterCommit(func() {
+	//    events.Publish("order.created", order.ID)
+	//  })
+	AfterCommit(fn func())
+
+	// AfterRollback adds a function to run once the transaction is rolled
+	// back, either with Rollback or because Commit failed. Functions run just
+	// once, in the order they were added. Functions added to a nested
+	// transaction run when it's rolled back or when the top-level transaction
+	// is.
+	AfterRollback(fn func())
+
+	// PrepareTwoPhase prepares the transaction for a two-phase commit with
+	// the given global id and closes it. The prepared transaction is kept by
+	// the database, even if the session is closed, until it's committed with
+	// Database.CommitPrepared or rolled back with Database.RollbackPrepared,
+	// from any session.
+	//
+	//  if err := tx.PrepareTwoPhase("order-42"); err != nil {
+	//    return err
+	//  }
+	//  ...
+	//  err = sess.CommitPrepared("order-42")
+	//
+	// PostgreSQL and MySQL support it, other adapters and nested transactions
+	// return db.ErrUnsupported. PostgreSQL requires max_prepared_transactions
+	// to be set. MySQL's XA transactions are global from their start, so the
+	// transaction must be begun with a context returned by WithTwoPhase for
+	// the same global id:
+	//
+	//  tx, err := sess.NewTx(sqlbuilder.WithTwoPhase(ctx, "order-42"))
+	//
+	// MySQL keeps the prepared transaction once its connection is released
+	// only if xa_detach_on_prepare is enabled, the default since 8.0.29.
+	PrepareTwoPhase(gid string) error
 }
 
 // Database represents a SQL database.
@@ -96,6 +153,13 @@ type Database interface {
 	// exits, regardless of the error value returned by fn.
 	Tx(ctx context.Context, fn func(sess Tx) error) error
 
+	// TxWithRetry is like Tx but, if the transaction fails because of a
+	// serialization failure or a deadlock, fn is run again within a new
+	// transaction according to the given policy. The fn function may be run
+	// more than once, so it must not have side effects outside of the
+	// transaction.
+	TxWithRetry(ctx context.Context, fn func(sess Tx) error, policy RetryPolicy) error
+
 	// Context returns the context used as default for queries on this session
 	// and for new transactions.  If no context has been set, a default
 	// context.Background() is returned.
@@ -107,12 +171,231 @@ type Database interface {
 	// parent session.
 	WithContext(context.Context) Database
 
+	// ReadOnly returns a copy of the session that refuses the statements that
+	// may change the database, like Exec, Insert, Update, Delete or Truncate,
+	// with db.ErrReadOnly. Its transactions are opened READ ONLY. It's useful
+	// to query replicas and to protect code paths that only read, like
+	// reports.
+	//
+	//   report := sess.ReadOnly()
+	ReadOnly() Database
+
 	// SetTxOptions sets the default TxOptions that is going to be used for new
 	// transactions created in the session.
 	SetTxOptions(sql.TxOptions)
 
 	// TxOptions returns the defaultx TxOptions.
 	TxOptions() *sql.TxOptions
+
+	// Metrics returns a snapshot of the query and connection pool statistics
+	// of the session, see Metrics.WritePrometheus to export them.
+	Metrics() Metrics
+
+	// Stats returns the statistics of the connection pool of the session. The
+	// pool of a session is configured with the Set*Conns, SetConnMaxLifetime
+	// and SetConnMaxIdleTime methods.
+	Stats() sql.DBStats
+
+	// HealthCheck pings the primary server and the replicas of the session,
+	// if any, measuring their round-trip latency and the replication lag of
+	// the replicas on adapters that support it. See HealthReport.
+	HealthCheck(ctx context.Context) HealthReport
+
+	// SetCircuitBreaker configures the circuit breaker of the session, which
+	// is shared by copies and transactions of the session. See
+	// CircuitBreaker.
+	SetCircuitBreaker(CircuitBreaker)
+
+	// CircuitOpen returns true if the circuit breaker of the session is open.
+	CircuitOpen() bool
+
+	// Schema describes the tables of the database: their columns, indexes and
+	// foreign keys. Adapters that are not able to inspect the database return
+	// db.ErrUnsupported.
+	Schema() (*Schema, error)
+
+	// NextSequenceValue advances the given sequence and returns its new
+	// value, see db.Sequence. Adapters without sequences return
+	// db.ErrUnsupported.
+	NextSequenceValue(name string) (int64, error)
+
+	// AdvisoryLock waits until the lock of the given key is free and takes
+	// it, the lock is shared by every client of the database and is held
+	// until it's released with Unlock, or until the session is closed. Locks
+	// are not reentrant, taking a lock twice without releasing it blocks
+	// forever.
+	//
+	//  if err := sess.AdvisoryLock(ctx, "billing"); err != nil {
+	//    return err
+	//  }
+	//  defer sess.Unlock(ctx, "billing")
+	//
+	// PostgreSQL and MySQL use the advisory locks of the database, which are
+	// held by a connection of the pool that's kept by the lock. Other
+	// databases keep their locks as rows of the upper_advisory_locks table,
+	// which is created the first time it's needed, such locks outlive the
+	// session if they aren't released.
+	AdvisoryLock(ctx context.Context, key string) error
+
+	// TryAdvisoryLock is like AdvisoryLock but it doesn't wait, it returns
+	// false if the lock is held by someone else.
+	TryAdvisoryLock(ctx context.Context, key string) (bool, error)
+
+	// Unlock releases the lock of the given key, it returns db.ErrLockNotHeld
+	// if the lock was not taken by the session.
+	Unlock(ctx context.Context, key string) error
+
+	// CommitPrepared commits the transaction that was prepared for a
+	// two-phase commit with the given global id, see Tx.PrepareTwoPhase.
+	CommitPrepared(gid string) error
+
+	// RollbackPrepared rolls back the transaction that was prepared for a
+	// two-phase commit with the given global id.
+	RollbackPrepared(gid string) error
+
+	// PreparedTransactions returns the global ids of the transactions that
+	// were prepared for a two-phase commit and are not finished yet, the
+	// in-doubt transactions a coordinator has to commit or roll back when it
+	// recovers.
+	PreparedTransactions() ([]string, error)
+
+	// Use installs middleware that wraps every statement the session sends
+	// to the database, after it has been compiled. Middleware runs in the
+	// order it was installed and it's shared by copies and transactions of
+	// the session. Statements don't go through the prepared statement cache
+	// while there is middleware installed. See StatementHandler.
+	Use(middleware func(next StatementHandler) StatementHandler)
+
+	// SetStatementPolicy sets the policy that decides whether the statements
+	// of the session may run, it's consulted before statements are sent to
+	// the database and it's shared by copies and transactions of the session.
+	// Refused statements fail with the error of the policy. A nil policy
+	// allows every statement. See StatementPolicy.
+	//
+	//   sess.SetStatementPolicy(sqlbuilder.NewPolicy().Forbid("truncate"))
+	SetStatementPolicy(policy StatementPolicy)
+
+	// OnConnect installs a hook that runs on every new connection of the
+	// session's pool before it's used, it's meant for setup statements like
+	// SET search_path or PRAGMAs. Hooks run in the order they were installed
+	// and a hook that returns an error discards the connection. Idle
+	// connections that were opened before the hook was installed are closed.
+	// Hooks have no effect on sessions created from an existing *sql.DB. See
+	// Conn.
+	OnConnect(hook func(ctx context.Context, conn Conn) error)
+}
+
+// Cluster represents a session on a primary database server and a set of
+// read-only replicas. SELECT statements built with Select(), Find() and
+// Count() are sent to the replicas, everything else, including raw queries
+// and transactions, is sent to the primary server.
+type Cluster interface {
+	Database
+
+	// Primary returns a copy of the session that sends every statement to the
+	// primary server, use it to read data that has just been written and may
+	// not have reached the replicas yet.
+	//
+	//   sess.Primary().Collection("accounts").Find(id).One(&account)
+	Primary() Database
+}
+
+// ReplicaPolicy defines how a Cluster picks the replica that runs a query.
+type ReplicaPolicy uint
+
+// Replica policies.
+const (
+	// RoundRobin sends queries to each replica in turn.
+	RoundRobin ReplicaPolicy = iota
+
+	// LeastLoaded sends queries to the replica with the least connections in
+	// use.
+	LeastLoaded
+)
+
+// RetryPolicy defines how TxWithRetry retries transactions.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a transaction is retried after
+	// the first attempt.
+	MaxRetries int
+
+	// InitialBackoff is the time to wait before the first retry, it is doubled
+	// after each retry. Zero means one millisecond. A random jitter of up to
+	// half the backoff is taken off each wait.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait between retries. Zero means no
+	// limit.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is a reasonable RetryPolicy for most applications.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     time.Second,
+}
+
+type noPreparedStatementCacheKey struct{}
+
+// WithoutPreparedStatementCache returns a copy of ctx that makes statements
+// run with it skip the prepared statement cache, even if the session has it
+// enabled. Use it for statements that are not worth preparing, like the ones
+// built from user input:
+//
+//	q.IteratorContext(sqlbuilder.WithoutPreparedStatementCache(ctx))
+func WithoutPreparedStatementCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noPreparedStatementCacheKey{}, true)
+}
+
+// PreparedStatementCacheDisabled returns true if ctx was returned by
+// WithoutPreparedStatementCache.
+func PreparedStatementCacheDisabled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	disabled, _ := ctx.Value(noPreparedStatementCacheKey{}).(bool)
+	return disabled
+}
+
+type twoPhaseKey struct{}
+
+// WithTwoPhase returns a copy of ctx that makes the transactions begun with it
+// global transactions with the given id, the one Tx.PrepareTwoPhase has to be
+// given. Adapters whose transactions don't need to know their global id in
+// advance, like PostgreSQL, ignore it.
+func WithTwoPhase(ctx context.Context, gid string) context.Context {
+	return context.WithValue(ctx, twoPhaseKey{}, gid)
+}
+
+// TwoPhaseID returns the global id given to WithTwoPhase, if any.
+func TwoPhaseID(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	gid, ok := ctx.Value(twoPhaseKey{}).(string)
+	return gid, ok
+}
+
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout returns a copy of ctx that limits how long statements run
+// with it may take, they fail with db.ErrQueryTimeout when it's exceeded. It
+// replaces the MaxQueryDuration of the session:
+//
+//	q.IteratorContext(sqlbuilder.WithQueryTimeout(ctx, 2*time.Second))
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, queryTimeoutKey{}, timeout)
+}
+
+// QueryTimeout returns the time limit given to WithQueryTimeout, or zero if
+// ctx has none.
+func QueryTimeout(ctx context.Context) time.Duration {
+	if ctx == nil {
+		return 0
+	}
+	timeout, _ := ctx.Value(queryTimeoutKey{}).(time.Duration)
+	return timeout
 }
 
 // AdapterFuncMap is a struct that defines a set of functions that adapters
@@ -40,6 +40,9 @@ const (
 	fmtLogError        = `Error:          %v`
 	fmtLogTimeTaken    = `Time taken:     %0.5fs`
 	fmtLogContext      = `Context:        %v`
+	fmtLogSlow         = `Slow query:     yes`
+	fmtLogPlan         = `Query plan:     %s`
+	fmtLogDryRun       = `Dry run:        yes`
 )
 
 var (
@@ -49,21 +52,47 @@ var (
 
 // QueryStatus represents the status of a query after being executed.
 type QueryStatus struct {
+	// SessID and TxID identify the session and the transaction (if any) the
+	// query was executed on.
 	SessID uint64
 	TxID   uint64
 
+	// RowsAffected and LastInsertID are only set for statements that do not
+	// return rows, and only if the driver reports them.
 	RowsAffected *int64
 	LastInsertID *int64
 
+	// Query is the SQL query that was sent to the database along with its
+	// arguments.
 	Query string
 	Args  []interface{}
 
+	// Err is the error returned by the database, if any.
 	Err error
 
 	Start time.Time
 	End   time.Time
 
+	// Context is the context the query was executed with, loggers may use it to
+	// read request-scoped values like trace IDs.
 	Context context.Context
+
+	// Slow is true if the query took longer than the SlowQueryThreshold of
+	// the session.
+	Slow bool
+
+	// Plan is the plan of a slow query, as reported by the database, if the
+	// session explains slow queries.
+	Plan string
+
+	// DryRun is true if the query was not run because the session is in
+	// dry-run mode.
+	DryRun bool
+}
+
+// Duration returns the time it took to execute the query.
+func (q *QueryStatus) Duration() time.Duration {
+	return q.End.Sub(q.Start)
 }
 
 // String returns a formatted log message.
@@ -99,7 +128,19 @@ func (q *QueryStatus) String() string {
 		lines = append(lines, fmt.Sprintf(fmtLogError, q.Err))
 	}
 
-	lines = append(lines, fmt.Sprintf(fmtLogTimeTaken, float64(q.End.UnixNano()-q.Start.UnixNano())/float64(1e9)))
+	lines = append(lines, fmt.Sprintf(fmtLogTimeTaken, q.Duration().Seconds()))
+
+	if q.Slow {
+		lines = append(lines, fmtLogSlow)
+	}
+
+	if q.Plan != "" {
+		lines = append(lines, fmt.Sprintf(fmtLogPlan, strings.Replace(q.Plan, "\n", "\n                ", -1)))
+	}
+
+	if q.DryRun {
+		lines = append(lines, fmtLogDryRun)
+	}
 
 	if q.Context != nil {
 		lines = append(lines, fmt.Sprintf(fmtLogContext, q.Context))
@@ -124,13 +165,33 @@ const (
 	EnvEnableDebug = `UPPERIO_DB_DEBUG`
 )
 
-// Logger represents a logging collector. You can pass a logging collector to
-// db.DefaultSettings.SetLogger(myCollector) to make it collect db.QueryStatus messages
-// after executing a query.
-type Logger interface {
+// QueryLogger receives a QueryStatus after each query is executed. Pass your
+// own QueryLogger to db.DefaultSettings.SetLogger(myLogger), or to the
+// SetLogger method of a session, to route query logs into any logging library
+// instead of the standard logger.
+type QueryLogger interface {
 	Log(*QueryStatus)
 }
 
+// QueryLoggerFunc is an adapter to use ordinary functions as query loggers.
+//
+//	sess.SetLogger(db.QueryLoggerFunc(func(q *db.QueryStatus) {
+//		zlog.Info().Str("query", q.Query).Dur("duration", q.Duration()).Err(q.Err).Send()
+//	}))
+type QueryLoggerFunc func(*QueryStatus)
+
+// Log calls fn(q).
+func (fn QueryLoggerFunc) Log(q *QueryStatus) {
+	fn(q)
+}
+
+// Logger represents a logging collector.
+//
+// Deprecated: use QueryLogger instead, both have the same method set.
+type Logger interface {
+	QueryLogger
+}
+
 type defaultLogger struct {
 }
 
@@ -138,7 +199,10 @@ func (lg *defaultLogger) Log(m *QueryStatus) {
 	log.Printf("\n\t%s\n\n", strings.Replace(m.String(), "\n", "\n\t", -1))
 }
 
-var _ = Logger(&defaultLogger{})
+var (
+	_ = Logger(&defaultLogger{})
+	_ = Logger(QueryLoggerFunc(nil))
+)
 
 func init() {
 	if envEnabled(EnvEnableDebug) {
@@ -0,0 +1,255 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package mocks
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"upper.io/db.v3"
+)
+
+var errMissingID = errors.New("mocks: the item has no id")
+
+type collection struct {
+	d    *Database
+	name string
+}
+
+var _ = db.Collection(&collection{})
+
+func (c *collection) Name() string {
+	return c.name
+}
+
+func (c *collection) Exists() bool {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	return c.d.table(c.name, false) != nil
+}
+
+func (c *collection) Find(conds ...interface{}) db.Result {
+	return &result{c: c, conds: conds}
+}
+
+func (c *collection) FindByIDs(ids interface{}, chunkSize int) db.Result {
+	return c.Find(db.Cond{idColumn: db.InChunks(ids, chunkSize)})
+}
+
+func (c *collection) Insert(item interface{}) (interface{}, error) {
+	return c.InsertContext(context.Background(), item)
+}
+
+func (c *collection) InsertContext(ctx context.Context, item interface{}) (interface{}, error) {
+	row, err := toRow(item)
+	if err != nil {
+		return nil, err
+	}
+	inserted, err := c.insertRows(ctx, []Row{row})
+	if err != nil {
+		return nil, err
+	}
+	return inserted[0][idColumn], nil
+}
+
+func (c *collection) InsertReturning(item interface{}) error {
+	return c.InsertReturningContext(context.Background(), item)
+}
+
+func (c *collection) InsertReturningContext(ctx context.Context, item interface{}) error {
+	if reflect.ValueOf(item).Kind() != reflect.Ptr {
+		return errExpectingPointer
+	}
+	row, err := toRow(item)
+	if err != nil {
+		return err
+	}
+	inserted, err := c.insertRows(ctx, []Row{row})
+	if err != nil {
+		return err
+	}
+	return assignOne(item, inserted[0])
+}
+
+func (c *collection) InsertReturningAll(items interface{}) error {
+	return c.InsertReturningAllContext(context.Background(), items)
+}
+
+func (c *collection) InsertReturningAllContext(ctx context.Context, items interface{}) error {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return errExpectingSlicePointer
+	}
+	rows, err := toRows(items)
+	if err != nil {
+		return err
+	}
+	inserted, err := c.insertRows(ctx, rows)
+	if err != nil {
+		return err
+	}
+	for i := range inserted {
+		if err := assignRow(v.Elem().Index(i), inserted[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *collection) CopyFrom(rows interface{}) error {
+	return c.CopyFromContext(context.Background(), rows)
+}
+
+func (c *collection) CopyFromContext(ctx context.Context, rows interface{}) error {
+	list, err := toRows(rows)
+	if err != nil {
+		return err
+	}
+	_, err = c.insertRows(ctx, list)
+	return err
+}
+
+func (c *collection) UpdateReturning(item interface{}) error {
+	return c.UpdateReturningContext(context.Background(), item)
+}
+
+func (c *collection) UpdateReturningContext(ctx context.Context, item interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if reflect.ValueOf(item).Kind() != reflect.Ptr {
+		return errExpectingPointer
+	}
+	row, err := toRow(item)
+	if err != nil {
+		return err
+	}
+	id := row[idColumn]
+	if isZero(id) {
+		return errMissingID
+	}
+
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	if e := c.d.expected(Update, c.name, []interface{}{id}); e != nil && e.err != nil {
+		return e.err
+	}
+
+	t := c.d.table(c.name, false)
+	if t == nil {
+		return db.ErrNoMoreRows
+	}
+	i := t.find(id)
+	if i < 0 {
+		return db.ErrNoMoreRows
+	}
+	for column, value := range row {
+		t.rows[i][column] = value
+	}
+	return assignOne(item, copyRow(t.rows[i]))
+}
+
+func (c *collection) Upsert(item interface{}) error {
+	return c.UpsertContext(context.Background(), item)
+}
+
+func (c *collection) UpsertContext(ctx context.Context, item interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	row, err := toRow(item)
+	if err != nil {
+		return err
+	}
+
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	if e := c.d.expected(Insert, c.name, nil); e != nil && e.err != nil {
+		return e.err
+	}
+
+	t := c.d.table(c.name, true)
+	if i := t.find(row[idColumn]); i >= 0 {
+		for column, value := range row {
+			t.rows[i][column] = value
+		}
+		row = t.rows[i]
+	} else {
+		t.insert(row)
+	}
+
+	if reflect.ValueOf(item).Kind() == reflect.Ptr {
+		return assignOne(item, copyRow(row))
+	}
+	return nil
+}
+
+func (c *collection) Truncate(opts ...db.TruncateOption) error {
+	return c.TruncateContext(context.Background(), opts...)
+}
+
+func (c *collection) TruncateContext(ctx context.Context, opts ...db.TruncateOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	if e := c.d.expected(Truncate, c.name, nil); e != nil && e.err != nil {
+		return e.err
+	}
+	if t := c.d.table(c.name, false); t != nil {
+		t.rows = nil
+	}
+	return nil
+}
+
+// insertRows adds rows to the table and returns a copy of them.
+func (c *collection) insertRows(ctx context.Context, rows []Row) ([]Row, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	if e := c.d.expected(Insert, c.name, nil); e != nil {
+		if e.err != nil {
+			return nil, e.err
+		}
+		if e.id != nil && len(rows) == 1 {
+			rows[0][idColumn] = e.id
+		}
+	}
+
+	t := c.d.table(c.name, true)
+	inserted := make([]Row, len(rows))
+	for i := range rows {
+		t.insert(rows[i])
+		inserted[i] = copyRow(rows[i])
+	}
+	return inserted, nil
+}
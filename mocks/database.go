@@ -0,0 +1,226 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package mocks provides a db.Database that keeps its tables in memory, for
+// the unit tests of code that uses upper.io/db without a database server.
+// Tables are loaded with fixtures and expectations make operations fail or
+// return given rows:
+//
+//	sess := mocks.New()
+//	sess.Fixture("artist", []Artist{{ID: 1, Name: "Ozzie"}})
+//
+//	sess.Expect(mocks.Insert, "artist").WillReturnError(errDuplicate)
+//
+//	// ... code under test uses sess ...
+//
+//	if err := sess.ExpectationsWereMet(); err != nil {
+//		t.Fatal(err)
+//	}
+//
+// Conditions are evaluated against the rows of the fixtures, db.Cond keys may
+// have an operator (like "id >") and values may be comparisons (like db.In or
+// db.Like), db.And and db.Or are supported. Raw SQL conditions, relations,
+// cursors and compiled statements are not.
+package mocks // import "upper.io/db.v3/mocks"
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"upper.io/db.v3"
+)
+
+// Adapter holds the name of the mocks adapter.
+const Adapter = `mocks`
+
+// idColumn is the primary key of every table of a mock database, rows that
+// are inserted without it are given the next integer.
+const idColumn = "id"
+
+func init() {
+	db.RegisterAdapter(Adapter, &db.AdapterFuncMap{
+		Open: Open,
+	})
+}
+
+// Row is a row of a table, values are kept by column name.
+type Row map[string]interface{}
+
+type table struct {
+	rows   []Row
+	lastID int64
+}
+
+// Database is a db.Database whose tables are kept in memory.
+type Database struct {
+	db.Settings
+
+	mu           sync.Mutex
+	connURL      db.ConnectionURL
+	tables       map[string]*table
+	expectations []*Expectation
+}
+
+var _ = db.Database(&Database{})
+
+// New returns a session with no tables.
+func New() *Database {
+	return &Database{
+		Settings: db.NewSettings(),
+		tables:   map[string]*table{},
+	}
+}
+
+// Open returns a session with no tables, the connection URL is only kept.
+func Open(connURL db.ConnectionURL) (db.Database, error) {
+	d := New()
+	if err := d.Open(connURL); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Fixture replaces the rows of the given table with rows, a slice of structs
+// or maps. The table is created if it doesn't exist.
+func (d *Database) Fixture(name string, rows interface{}) error {
+	fixture, err := toRows(rows)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t := &table{}
+	for _, row := range fixture {
+		t.insert(row)
+	}
+	d.tables[name] = t
+	return nil
+}
+
+// Rows returns a copy of the rows of the given table.
+func (d *Database) Rows(name string) []Row {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t, ok := d.tables[name]
+	if !ok {
+		return nil
+	}
+	return copyRows(t.rows)
+}
+
+// Driver returns nil, there's no underlying driver.
+func (d *Database) Driver() interface{} {
+	return nil
+}
+
+// Open keeps the connection URL.
+func (d *Database) Open(connURL db.ConnectionURL) error {
+	d.mu.Lock()
+	d.connURL = connURL
+	d.mu.Unlock()
+	return nil
+}
+
+// Ping always succeeds.
+func (d *Database) Ping() error {
+	return nil
+}
+
+// PingContext succeeds unless ctx is done.
+func (d *Database) PingContext(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// Close does nothing, the tables are kept.
+func (d *Database) Close() error {
+	return nil
+}
+
+// Collection returns the collection of the given table, the table doesn't
+// need to exist until rows are inserted.
+func (d *Database) Collection(name string) db.Collection {
+	return &collection{d: d, name: name}
+}
+
+// Collections returns the names of the tables, sorted.
+func (d *Database) Collections() ([]string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	names := make([]string, 0, len(d.tables))
+	for name := range d.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Name returns the name of the adapter.
+func (d *Database) Name() string {
+	return Adapter
+}
+
+// ConnectionURL returns the connection URL given to Open.
+func (d *Database) ConnectionURL() db.ConnectionURL {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.connURL
+}
+
+// ClearCache does nothing, there are no caches.
+func (d *Database) ClearCache() {
+}
+
+// table returns the given table, it's created if create is true and it
+// doesn't exist. d.mu must be held.
+func (d *Database) table(name string, create bool) *table {
+	t, ok := d.tables[name]
+	if !ok && create {
+		t = &table{}
+		d.tables[name] = t
+	}
+	return t
+}
+
+// insert adds row to the table, rows with no idColumn get the next one.
+func (t *table) insert(row Row) {
+	if isZero(row[idColumn]) {
+		t.lastID++
+		row[idColumn] = t.lastID
+	} else if id, ok := toInt64(row[idColumn]); ok && id > t.lastID {
+		t.lastID = id
+	}
+	t.rows = append(t.rows, row)
+}
+
+// find returns the index of the row with the given id, or -1.
+func (t *table) find(id interface{}) int {
+	for i := range t.rows {
+		if equal(t.rows[i][idColumn], id) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+)
+
+type artist struct {
+	ID   int64  `db:"id,omitempty"`
+	Name string `db:"name"`
+	Age  *int   `db:"age,omitempty"`
+}
+
+func newSession(t *testing.T) *Database {
+	sess := New()
+	age := 41
+	err := sess.Fixture("artist", []artist{
+		{ID: 1, Name: "Ozzie", Age: &age},
+		{ID: 2, Name: "Flea"},
+		{ID: 3, Name: "Slash"},
+	})
+	assert.NoError(t, err)
+	return sess
+}
+
+func TestOpen(t *testing.T) {
+	sess, err := db.Open(Adapter, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, sess.Ping())
+	assert.Equal(t, Adapter, sess.Name())
+	assert.False(t, sess.Collection("artist").Exists())
+	assert.NoError(t, sess.Close())
+}
+
+func TestFind(t *testing.T) {
+	sess := newSession(t)
+	artists := sess.Collection("artist")
+
+	var a artist
+	assert.NoError(t, artists.Find(1).One(&a))
+	assert.Equal(t, "Ozzie", a.Name)
+	assert.Equal(t, 41, *a.Age)
+
+	assert.Equal(t, db.ErrNoMoreRows, artists.Find(10).One(&a))
+
+	var all []artist
+	assert.NoError(t, artists.Find(db.Cond{"id >": 1}).OrderBy("-name").All(&all))
+	assert.Equal(t, 2, len(all))
+	assert.Equal(t, "Slash", all[0].Name)
+	assert.Equal(t, "Flea", all[1].Name)
+
+	assert.NoError(t, artists.Find(db.Or(db.Cond{"name": "Flea"}, db.Cond{"name LIKE": "Sl%"})).OrderBy("id").All(&all))
+	assert.Equal(t, 2, len(all))
+	assert.Equal(t, int64(2), all[0].ID)
+
+	assert.NoError(t, artists.Find(db.Cond{"id": db.In([]int{1, 3})}).And(db.Cond{"age": db.IsNull()}).All(&all))
+	assert.Equal(t, 1, len(all))
+	assert.Equal(t, "Slash", all[0].Name)
+
+	var maps []map[string]interface{}
+	assert.NoError(t, artists.Find().Select("name").OrderBy("id").Limit(2).Offset(1).All(&maps))
+	assert.Equal(t, []map[string]interface{}{{"name": "Flea"}, {"name": "Slash"}}, maps)
+
+	res := artists.Find().OrderBy("id").Paginate(2)
+	assert.NoError(t, res.Page(2).All(&all))
+	assert.Equal(t, 1, len(all))
+
+	pages, err := res.TotalPages()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), pages)
+
+	n, err := artists.Find(db.Cond{"name !=": "Flea"}).Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), n)
+
+	n, err = artists.FindByIDs([]int64{1, 3}, 1).Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), n)
+
+	sum, err := artists.Find().Sum("id")
+	assert.NoError(t, err)
+	assert.Equal(t, 6.0, sum)
+
+	var max int64
+	assert.NoError(t, artists.Find().Max("id", &max))
+	assert.Equal(t, int64(3), max)
+
+	iter := artists.Find().OrderBy("id")
+	var names []string
+	for iter.Next(&a) {
+		names = append(names, a.Name)
+	}
+	assert.NoError(t, iter.Err())
+	assert.Equal(t, []string{"Ozzie", "Flea", "Slash"}, names)
+
+	batched := artists.Find().OrderBy("id")
+	var sizes []int
+	for batch := range batched.Batch(context.Background(), 2, []*artist(nil)) {
+		sizes = append(sizes, len(batch.([]*artist)))
+	}
+	assert.NoError(t, batched.Err())
+	assert.Equal(t, []int{2, 1}, sizes)
+
+	var values []interface{}
+	for batch := range batched.Batch(context.Background(), 2, artist{}) {
+		values = append(values, batch)
+	}
+	assert.Equal(t, []interface{}{errExpectingSlice}, values)
+	assert.Equal(t, errExpectingSlice, batched.Err())
+
+	// Err can be read while batches are sent.
+	ctx, cancel := context.WithCancel(context.Background())
+	batched = artists.Find().OrderBy("id")
+	for range batched.Batch(ctx, 1, []*artist(nil)) {
+		assert.NoError(t, batched.Err())
+		cancel()
+	}
+	assert.Equal(t, context.Canceled, batched.Err())
+
+	names = nil
+	err = artists.Find().OrderBy("id").ForEach(func(scan func(dest ...interface{}) error) error {
+		var a artist
+		if err := scan(&a); err != nil {
+			return err
+		}
+		names = append(names, a.Name)
+		if len(names) == 2 {
+			return db.ErrNoMoreRows
+		}
+		return nil
+	})
+	assert.Equal(t, db.ErrNoMoreRows, err)
+	assert.Equal(t, []string{"Ozzie", "Flea"}, names)
+
+	assert.Error(t, artists.Find(db.Raw("id = 1")).One(&a))
+	assert.Error(t, artists.Find().Group("name").All(&all))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, artists.Find().AllContext(ctx, &all))
+}
+
+func TestWrite(t *testing.T) {
+	sess := newSession(t)
+	artists := sess.Collection("artist")
+
+	id, err := artists.Insert(artist{Name: "Angus"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), id)
+
+	a := artist{Name: "Axl"}
+	assert.NoError(t, artists.InsertReturning(&a))
+	assert.Equal(t, int64(5), a.ID)
+
+	assert.NoError(t, artists.Find(db.Cond{"name": "Axl"}).Update(map[string]interface{}{"name": "W. Axl"}))
+	assert.NoError(t, artists.Find(5).One(&a))
+	assert.Equal(t, "W. Axl", a.Name)
+
+	a.Name = "Axl Rose"
+	assert.NoError(t, artists.UpdateReturning(&a))
+	assert.Equal(t, "Axl Rose", sess.Rows("artist")[4]["name"])
+
+	var deleted []artist
+	assert.NoError(t, artists.Find(db.Cond{"id >=": 4}).DeleteReturning(&deleted))
+	assert.Equal(t, 2, len(deleted))
+	assert.Equal(t, 3, len(sess.Rows("artist")))
+
+	assert.NoError(t, artists.Upsert(&artist{ID: 2, Name: "Michael"}))
+	assert.Equal(t, "Michael", sess.Rows("artist")[1]["name"])
+
+	assert.NoError(t, artists.Truncate())
+	assert.Equal(t, 0, len(sess.Rows("artist")))
+
+	names, err := sess.Collections()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"artist"}, names)
+}
+
+func TestExpectations(t *testing.T) {
+	sess := newSession(t)
+	artists := sess.Collection("artist")
+
+	errDuplicate := errors.New("duplicate key")
+
+	sess.Expect(Insert, "artist").WillReturnError(errDuplicate)
+	sess.Expect(Insert, "artist").WillReturnID(int64(100))
+	sess.Expect(Find, "artist").WithConds(db.Cond{"name": "Ozzie"}).WillReturnRows([]artist{{ID: 7, Name: "Ozzy"}})
+	sess.Expect(Delete, "artist")
+
+	_, err := artists.Insert(artist{Name: "Angus"})
+	assert.Equal(t, errDuplicate, err)
+
+	id, err := artists.Insert(artist{Name: "Angus"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), id)
+
+	var a artist
+	assert.NoError(t, artists.Find(1).One(&a))
+	assert.Equal(t, "Ozzie", a.Name)
+
+	assert.NoError(t, artists.Find(db.Cond{"name": "Ozzie"}).One(&a))
+	assert.Equal(t, "Ozzy", a.Name)
+
+	assert.Error(t, sess.ExpectationsWereMet())
+
+	assert.NoError(t, artists.Find(100).Delete())
+	assert.NoError(t, sess.ExpectationsWereMet())
+	assert.Equal(t, 3, len(sess.Rows("artist")))
+}
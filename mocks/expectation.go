@@ -0,0 +1,154 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package mocks
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Op is a kind of operation on a table.
+type Op string
+
+// Operations expectations are set for.
+const (
+	// Insert, InsertReturning, InsertReturningAll, CopyFrom and Upsert of
+	// collections.
+	Insert Op = "insert"
+	// Reads of results, like One, All, Next, Count or Sum.
+	Find Op = "find"
+	// Update and UpdateReturning of results, and UpdateReturning of
+	// collections.
+	Update Op = "update"
+	// Delete and DeleteReturning of results.
+	Delete Op = "delete"
+	// Truncate of collections.
+	Truncate Op = "truncate"
+)
+
+// Expectation is an operation a test expects, see Database.Expect.
+type Expectation struct {
+	op    Op
+	table string
+
+	conds    []interface{}
+	hasConds bool
+
+	err  error
+	rows []Row
+	id   interface{}
+
+	met bool
+}
+
+// Expect adds an expectation for the given operation on table. Expectations
+// are met in the order they're added, by the first operation that matches
+// each one of them. Operations that match no expectation run on the rows of
+// the fixtures.
+func (d *Database) Expect(op Op, table string) *Expectation {
+	e := &Expectation{op: op, table: table}
+
+	d.mu.Lock()
+	d.expectations = append(d.expectations, e)
+	d.mu.Unlock()
+
+	return e
+}
+
+// WithConds makes the expectation match only the results found with the
+// given conditions, compared with reflect.DeepEqual:
+//
+//	sess.Expect(mocks.Find, "artist").WithConds(db.Cond{"id": 1})
+func (e *Expectation) WithConds(conds ...interface{}) *Expectation {
+	e.conds = conds
+	e.hasConds = true
+	return e
+}
+
+// WillReturnError makes the operation fail with err.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// WillReturnRows makes a Find operation read rows, a slice of structs or
+// maps, instead of the rows of the table.
+func (e *Expectation) WillReturnRows(rows interface{}) *Expectation {
+	list, err := toRows(rows)
+	if err != nil {
+		e.err = err
+		return e
+	}
+	if list == nil {
+		list = []Row{}
+	}
+	e.rows = list
+	return e
+}
+
+// WillReturnID makes an Insert operation give the inserted row the given id.
+func (e *Expectation) WillReturnID(id interface{}) *Expectation {
+	e.id = id
+	return e
+}
+
+func (e *Expectation) String() string {
+	if e.hasConds {
+		return fmt.Sprintf("%s on %q with conditions %v", e.op, e.table, e.conds)
+	}
+	return fmt.Sprintf("%s on %q", e.op, e.table)
+}
+
+// ExpectationsWereMet returns an error listing the expectations that no
+// operation matched.
+func (d *Database) ExpectationsWereMet() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var unmet []string
+	for _, e := range d.expectations {
+		if !e.met {
+			unmet = append(unmet, e.String())
+		}
+	}
+	if len(unmet) > 0 {
+		return fmt.Errorf("mocks: expectations were not met: %s", strings.Join(unmet, ", "))
+	}
+	return nil
+}
+
+// expected returns the first expectation the given operation meets, if any.
+// d.mu must be held.
+func (d *Database) expected(op Op, table string, conds []interface{}) *Expectation {
+	for _, e := range d.expectations {
+		if e.met || e.op != op || e.table != table {
+			continue
+		}
+		if e.hasConds && !reflect.DeepEqual(e.conds, conds) {
+			continue
+		}
+		e.met = true
+		return e
+	}
+	return nil
+}
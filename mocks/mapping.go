@@ -0,0 +1,211 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package mocks
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/reflectx"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+var mapper = reflectx.NewMapper("db")
+
+var (
+	errExpectingPointer      = errors.New("mocks: expecting a pointer to a struct or map")
+	errExpectingSlicePointer = errors.New("mocks: expecting a pointer to a slice")
+	errExpectingSlice        = errors.New("mocks: expecting a slice of structs or maps")
+)
+
+// toRow maps item, a struct or a map, into a row like SQL adapters map the
+// values they insert.
+func toRow(item interface{}) (Row, error) {
+	columns, values, err := sqlbuilder.Map(item, nil)
+	if err != nil {
+		return nil, err
+	}
+	row := make(Row, len(columns))
+	for i := range columns {
+		row[columns[i]] = values[i]
+	}
+	return row, nil
+}
+
+// toRows maps items, a slice of structs or maps, into rows.
+func toRows(items interface{}) ([]Row, error) {
+	v := reflect.ValueOf(items)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, errExpectingSlice
+	}
+
+	rows := make([]Row, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		row, err := toRow(v.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func copyRow(row Row) Row {
+	c := make(Row, len(row))
+	for k, v := range row {
+		c[k] = v
+	}
+	return c
+}
+
+func copyRows(rows []Row) []Row {
+	c := make([]Row, len(rows))
+	for i := range rows {
+		c[i] = copyRow(rows[i])
+	}
+	return c
+}
+
+// assignOne sets dst, a pointer to a struct or map, to row.
+func assignOne(dst interface{}, row Row) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errExpectingPointer
+	}
+	return assignRow(v.Elem(), row)
+}
+
+// assignAll sets dst, a pointer to a slice of structs or maps, to rows.
+func assignAll(dst interface{}, rows []Row) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errExpectingSlicePointer
+	}
+
+	slice := reflect.MakeSlice(v.Elem().Type(), len(rows), len(rows))
+	for i := range rows {
+		if err := assignRow(slice.Index(i), rows[i]); err != nil {
+			return err
+		}
+	}
+	v.Elem().Set(slice)
+	return nil
+}
+
+// assignRow sets v, a struct, a map or a pointer to one of them, to row.
+func assignRow(v reflect.Value, row Row) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := mapper.TypeMap(v.Type()).Names
+		for column, value := range row {
+			fi, ok := fields[column]
+			if !ok {
+				continue
+			}
+			if _, writeOnly := fi.Options["writeonly"]; writeOnly {
+				continue
+			}
+			if err := setValue(reflectx.FieldByIndexes(v, fi.Index), value); err != nil {
+				return fmt.Errorf("mocks: column %q: %v", column, err)
+			}
+		}
+		return nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return errExpectingPointer
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for column, value := range row {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, value); err != nil {
+				return fmt.Errorf("mocks: column %q: %v", column, err)
+			}
+			v.SetMapIndex(reflect.ValueOf(column).Convert(v.Type().Key()), elem)
+		}
+		return nil
+	}
+	return errExpectingPointer
+}
+
+// setValue sets v to value like a database driver would scan it.
+func setValue(v reflect.Value, value interface{}) error {
+	if v.CanAddr() {
+		switch dst := v.Addr().Interface().(type) {
+		case db.Unmarshaler:
+			return dst.UnmarshalDB(value)
+		case sql.Scanner:
+			return dst.Scan(value)
+		}
+	}
+
+	if value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	src := reflect.ValueOf(value)
+	if src.Type().AssignableTo(v.Type()) {
+		v.Set(src)
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if src.Kind() == reflect.Ptr {
+		if src.IsNil() {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		return setValue(v, src.Elem().Interface())
+	}
+
+	// Numbers are not converted into strings.
+	if v.Kind() != reflect.String || src.Kind() == reflect.String || src.Kind() == reflect.Slice {
+		if src.Type().ConvertibleTo(v.Type()) {
+			v.Set(src.Convert(v.Type()))
+			return nil
+		}
+	}
+	return fmt.Errorf("can't set %v to %T", v.Type(), value)
+}
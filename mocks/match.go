@@ -0,0 +1,359 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package mocks
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"upper.io/db.v3"
+)
+
+var comparisonOperators = map[db.ComparisonOperator]string{
+	db.ComparisonOperatorEqual:                "=",
+	db.ComparisonOperatorNotEqual:             "!=",
+	db.ComparisonOperatorLessThan:             "<",
+	db.ComparisonOperatorGreaterThan:          ">",
+	db.ComparisonOperatorLessThanOrEqualTo:    "<=",
+	db.ComparisonOperatorGreaterThanOrEqualTo: ">=",
+	db.ComparisonOperatorBetween:              "BETWEEN",
+	db.ComparisonOperatorNotBetween:           "NOT BETWEEN",
+	db.ComparisonOperatorIn:                   "IN",
+	db.ComparisonOperatorNotIn:                "NOT IN",
+	db.ComparisonOperatorIs:                   "IS",
+	db.ComparisonOperatorIsNot:                "IS NOT",
+	db.ComparisonOperatorLike:                 "LIKE",
+	db.ComparisonOperatorNotLike:              "NOT LIKE",
+	db.ComparisonOperatorRegExp:               "REGEXP",
+	db.ComparisonOperatorNotRegExp:            "NOT REGEXP",
+	db.ComparisonOperatorAfter:                ">",
+	db.ComparisonOperatorBefore:               "<",
+	db.ComparisonOperatorOnOrAfter:            ">=",
+	db.ComparisonOperatorOnOrBefore:           "<=",
+}
+
+// matches returns true if row satisfies all of the given conditions.
+func matches(row Row, conds []interface{}) (bool, error) {
+	for _, cond := range conds {
+		ok, err := matchCond(row, cond)
+		if !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchCond(row Row, cond interface{}) (bool, error) {
+	switch c := cond.(type) {
+	case nil:
+		return true, nil
+	case db.RawValue:
+		return false, fmt.Errorf("mocks: raw conditions are not supported: %v", c)
+	case db.Cond:
+		for _, key := range c.Keys() {
+			ok, err := matchConstraint(row, key, c[key])
+			if !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case db.Compound:
+		sentences := c.Sentences()
+		if c.Operator() == db.OperatorOr {
+			for _, sentence := range sentences {
+				ok, err := matchCond(row, sentence)
+				if ok || err != nil {
+					return ok, err
+				}
+			}
+			return len(sentences) == 0, nil
+		}
+		for _, sentence := range sentences {
+			ok, err := matchCond(row, sentence)
+			if !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	// Anything else is the value of the primary key.
+	return matchConstraint(row, idColumn, cond)
+}
+
+// matchConstraint returns true if the column of key, which may be followed by
+// an operator, satisfies value.
+func matchConstraint(row Row, key interface{}, value interface{}) (bool, error) {
+	name, ok := key.(string)
+	if !ok {
+		return false, fmt.Errorf("mocks: unsupported condition key %v", key)
+	}
+
+	fields := strings.Fields(name)
+	if len(fields) == 0 {
+		return false, fmt.Errorf("mocks: empty condition key")
+	}
+	column, op := fields[0], strings.ToUpper(strings.Join(fields[1:], " "))
+
+	if cmp, ok := value.(db.Comparison); ok {
+		if op = comparisonOperators[cmp.Operator()]; op == "" {
+			return false, fmt.Errorf("mocks: unsupported comparison %v", cmp)
+		}
+		value = cmp.Value()
+	}
+
+	if op == "" {
+		switch {
+		case value == nil:
+			op = "IS"
+		case isList(value):
+			op = "IN"
+		default:
+			op = "="
+		}
+	}
+
+	return compare(row[column], op, value)
+}
+
+// compare returns true if have and want satisfy op, with the semantics of SQL
+// for NULL values.
+func compare(have interface{}, op string, want interface{}) (bool, error) {
+	have, want = normalize(have), normalize(want)
+
+	switch op {
+	case "IS", "IS NOT":
+		same := have == want
+		if want != nil {
+			same = have != nil && equal(have, want)
+		}
+		return same == (op == "IS"), nil
+	}
+
+	if have == nil {
+		return false, nil
+	}
+
+	switch op {
+	case "=", "==":
+		return equal(have, want), nil
+	case "!=", "<>":
+		return want != nil && !equal(have, want), nil
+	case "<", ">", "<=", ">=":
+		c, ok := order(have, want)
+		if !ok {
+			return false, nil
+		}
+		switch op {
+		case "<":
+			return c < 0, nil
+		case ">":
+			return c > 0, nil
+		case "<=":
+			return c <= 0, nil
+		}
+		return c >= 0, nil
+	case "IN", "NOT IN":
+		found := false
+		for _, v := range toList(want) {
+			if equal(have, normalize(v)) {
+				found = true
+				break
+			}
+		}
+		return found == (op == "IN"), nil
+	case "BETWEEN", "NOT BETWEEN":
+		bounds := toList(want)
+		if len(bounds) != 2 {
+			return false, fmt.Errorf("mocks: %s expects two values", op)
+		}
+		lo, ok1 := order(have, normalize(bounds[0]))
+		hi, ok2 := order(have, normalize(bounds[1]))
+		between := ok1 && ok2 && lo >= 0 && hi <= 0
+		return between == (op == "BETWEEN"), nil
+	case "LIKE", "NOT LIKE", "ILIKE", "NOT ILIKE":
+		pattern := likePattern(fmt.Sprintf("%v", want), strings.HasSuffix(op, "ILIKE"))
+		ok, err := regexp.MatchString(pattern, fmt.Sprintf("%v", have))
+		return ok == !strings.HasPrefix(op, "NOT"), err
+	case "REGEXP", "NOT REGEXP", "~", "!~":
+		ok, err := regexp.MatchString(fmt.Sprintf("%v", want), fmt.Sprintf("%v", have))
+		return ok == (op == "REGEXP" || op == "~"), err
+	}
+	return false, fmt.Errorf("mocks: unsupported operator %q", op)
+}
+
+// likePattern returns the regular expression of the given LIKE pattern.
+func likePattern(like string, insensitive bool) string {
+	var b bytes.Buffer
+	if insensitive {
+		b.WriteString("(?i)")
+	}
+	b.WriteString("^")
+	for _, r := range like {
+		switch r {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// normalize returns v as one of nil, float64, string, bool, time.Time or
+// itself, so that values of different types can be compared.
+func normalize(v interface{}) interface{} {
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return v
+		}
+		v = value
+	}
+
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		return t
+	case []byte:
+		return string(t)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	return v
+}
+
+func equal(a, b interface{}) bool {
+	a, b = normalize(a), normalize(b)
+	if a == nil || b == nil {
+		return false
+	}
+	if c, ok := order(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// order compares two normalized values of the same type.
+func order(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, true
+			case y:
+				return -1, true
+			}
+			return 1, true
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1, true
+			case x.After(y):
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+func isList(v interface{}) bool {
+	if _, ok := v.([]byte); ok {
+		return false
+	}
+	kind := reflect.ValueOf(v).Kind()
+	return kind == reflect.Slice || kind == reflect.Array
+}
+
+func toList(v interface{}) []interface{} {
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	if !isList(v) {
+		return []interface{}{v}
+	}
+	rv := reflect.ValueOf(v)
+	list := make([]interface{}, rv.Len())
+	for i := range list {
+		list[i] = rv.Index(i).Interface()
+	}
+	return list
+}
+
+// isZero returns true if v is nil or the zero value of its type.
+func isZero(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return reflect.DeepEqual(v, reflect.Zero(rv.Type()).Interface())
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	if f, ok := normalize(v).(float64); ok {
+		return int64(f), true
+	}
+	return 0, false
+}
@@ -0,0 +1,797 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"upper.io/db.v3"
+)
+
+type result struct {
+	c *collection
+
+	conds   []interface{}
+	fields  []interface{}
+	orderBy []interface{}
+	limit   int
+	offset  int
+
+	pageSize   uint
+	pageNumber uint
+
+	err error
+
+	// Rows read by Next.
+	fetched bool
+	iter    []Row
+	iterPos int
+
+	// iterErr is written by the goroutine of Batch too, it's guarded by
+	// r.c.d.mu.
+	iterErr error
+}
+
+var _ = db.Result(&result{})
+
+// frame returns a copy of the query of the result, modified by fn.
+func (r *result) frame(fn func(*result)) db.Result {
+	n := &result{
+		c:          r.c,
+		conds:      r.conds,
+		fields:     r.fields,
+		orderBy:    r.orderBy,
+		limit:      r.limit,
+		offset:     r.offset,
+		pageSize:   r.pageSize,
+		pageNumber: r.pageNumber,
+		err:        r.err,
+	}
+	fn(n)
+	return n
+}
+
+func (r *result) unsupported(method string) db.Result {
+	return r.frame(func(n *result) {
+		n.err = fmt.Errorf("mocks: %s is not supported: %v", method, db.ErrUnsupported)
+	})
+}
+
+func (r *result) String() string {
+	return fmt.Sprintf("find on %q with conditions %v", r.c.name, r.conds)
+}
+
+func (r *result) Compile() (string, []interface{}, error) {
+	return "", nil, db.ErrUnsupported
+}
+
+func (r *result) Explain(ctx context.Context) (string, error) {
+	return "", db.ErrUnsupported
+}
+
+func (r *result) Limit(n int) db.Result {
+	return r.frame(func(n2 *result) {
+		n2.limit = n
+	})
+}
+
+func (r *result) Offset(n int) db.Result {
+	return r.frame(func(n2 *result) {
+		n2.offset = n
+	})
+}
+
+func (r *result) OrderBy(terms ...interface{}) db.Result {
+	return r.frame(func(n *result) {
+		n.orderBy = terms
+	})
+}
+
+func (r *result) Select(fields ...interface{}) db.Result {
+	return r.frame(func(n *result) {
+		n.fields = fields
+	})
+}
+
+func (r *result) Where(terms ...interface{}) db.Result {
+	return r.frame(func(n *result) {
+		n.conds = terms
+	})
+}
+
+func (r *result) And(terms ...interface{}) db.Result {
+	return r.frame(func(n *result) {
+		n.conds = append(r.conds[:len(r.conds):len(r.conds)], terms...)
+	})
+}
+
+func (r *result) Group(...interface{}) db.Result {
+	return r.unsupported("Group")
+}
+
+func (r *result) Preload(...string) db.Result {
+	return r.unsupported("Preload")
+}
+
+func (r *result) Scope(...string) db.Result {
+	return r.unsupported("Scope")
+}
+
+func (r *result) Model(interface{}) db.Result {
+	return r.unsupported("Model")
+}
+
+func (r *result) SoftDelete(string) db.Result {
+	return r.unsupported("SoftDelete")
+}
+
+// Unscoped returns the result as it is, mocked rows are never soft deleted.
+func (r *result) Unscoped() db.Result {
+	return r.frame(func(*result) {})
+}
+
+func (r *result) OnlyDeleted() db.Result {
+	return r.unsupported("OnlyDeleted")
+}
+
+func (r *result) Paginate(pageSize uint) db.Result {
+	return r.frame(func(n *result) {
+		n.pageSize = pageSize
+	})
+}
+
+func (r *result) Page(pageNumber uint) db.Result {
+	return r.frame(func(n *result) {
+		n.pageNumber = pageNumber
+	})
+}
+
+func (r *result) Cursor(string) db.Result {
+	return r.unsupported("Cursor")
+}
+
+func (r *result) NextPage(interface{}) db.Result {
+	return r.unsupported("NextPage")
+}
+
+func (r *result) PrevPage(interface{}) db.Result {
+	return r.unsupported("PrevPage")
+}
+
+func (r *result) NextPageCursor(interface{}) (db.Cursor, error) {
+	return "", db.ErrUnsupported
+}
+
+func (r *result) PrevPageCursor(interface{}) (db.Cursor, error) {
+	return "", db.ErrUnsupported
+}
+
+func (r *result) One(dst interface{}) error {
+	return r.OneContext(context.Background(), dst)
+}
+
+func (r *result) OneContext(ctx context.Context, dst interface{}) error {
+	rows, err := r.read(ctx, true)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return db.ErrNoMoreRows
+	}
+	return assignOne(dst, rows[0])
+}
+
+func (r *result) All(dst interface{}) error {
+	return r.AllContext(context.Background(), dst)
+}
+
+func (r *result) AllContext(ctx context.Context, dst interface{}) error {
+	rows, err := r.read(ctx, true)
+	if err != nil {
+		return err
+	}
+	return assignAll(dst, rows)
+}
+
+func (r *result) ForEach(fn func(scan func(dest ...interface{}) error) error) error {
+	return r.ForEachContext(context.Background(), fn)
+}
+
+// ForEachContext is like ForEach, scan assigns the current row to its only
+// destination.
+func (r *result) ForEachContext(ctx context.Context, fn func(scan func(dest ...interface{}) error) error) error {
+	rows, err := r.read(ctx, true)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		scan := func(dest ...interface{}) error {
+			if len(dest) != 1 {
+				return db.ErrUnsupported
+			}
+			return assignOne(dest[0], row)
+		}
+		if err := fn(scan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *result) Batch(ctx context.Context, size int, sliceOfStructs interface{}) <-chan interface{} {
+	sliceType := reflect.TypeOf(sliceOfStructs)
+	if sliceType != nil && sliceType.Kind() == reflect.Ptr {
+		sliceType = sliceType.Elem()
+	}
+	if sliceType == nil || sliceType.Kind() != reflect.Slice {
+		r.setIterErr(errExpectingSlice)
+		return batchErr(errExpectingSlice)
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	rows, err := r.read(ctx, true)
+	if err != nil {
+		r.setIterErr(err)
+		return batchErr(err)
+	}
+
+	batches := make(chan interface{})
+	go func() {
+		defer close(batches)
+
+		fail := func(err error) {
+			r.setIterErr(err)
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case batches <- err:
+			case <-ctx.Done():
+			}
+		}
+
+		for i := 0; i < len(rows); i += size {
+			end := i + size
+			if end > len(rows) {
+				end = len(rows)
+			}
+			batch := reflect.New(sliceType)
+			if err := assignAll(batch.Interface(), rows[i:end]); err != nil {
+				fail(err)
+				return
+			}
+			select {
+			case batches <- batch.Elem().Interface():
+			case <-ctx.Done():
+				r.setIterErr(ctx.Err())
+				return
+			}
+		}
+	}()
+
+	return batches
+}
+
+// batchErr returns a closed channel that holds err as its only value.
+func batchErr(err error) <-chan interface{} {
+	batches := make(chan interface{}, 1)
+	batches <- err
+	close(batches)
+	return batches
+}
+
+func (r *result) setIterErr(err error) {
+	r.c.d.mu.Lock()
+	defer r.c.d.mu.Unlock()
+	r.iterErr = err
+}
+
+func (r *result) Next(dst interface{}) bool {
+	return r.NextContext(context.Background(), dst)
+}
+
+func (r *result) NextContext(ctx context.Context, dst interface{}) bool {
+	if !r.fetched {
+		iter, err := r.read(ctx, true)
+		r.iter, r.fetched = iter, true
+		r.setIterErr(err)
+	}
+	if r.Err() != nil || r.iterPos >= len(r.iter) {
+		return false
+	}
+	if err := assignOne(dst, r.iter[r.iterPos]); err != nil {
+		r.setIterErr(err)
+		return false
+	}
+	r.iterPos++
+	return true
+}
+
+func (r *result) Err() error {
+	r.c.d.mu.Lock()
+	defer r.c.d.mu.Unlock()
+	return r.iterErr
+}
+
+func (r *result) Close() error {
+	r.fetched, r.iter, r.iterPos = false, nil, 0
+	r.setIterErr(nil)
+	return nil
+}
+
+func (r *result) Count(opts ...db.CountOption) (uint64, error) {
+	return r.CountContext(context.Background(), opts...)
+}
+
+func (r *result) CountContext(ctx context.Context, opts ...db.CountOption) (uint64, error) {
+	rows, err := r.read(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(rows)), nil
+}
+
+func (r *result) Exists() (bool, error) {
+	return r.ExistsContext(context.Background())
+}
+
+func (r *result) ExistsContext(ctx context.Context) (bool, error) {
+	n, err := r.CountContext(ctx)
+	return n > 0, err
+}
+
+func (r *result) TotalEntries() (uint64, error) {
+	return r.TotalEntriesContext(context.Background())
+}
+
+func (r *result) TotalEntriesContext(ctx context.Context) (uint64, error) {
+	return r.CountContext(ctx)
+}
+
+func (r *result) TotalPages() (uint, error) {
+	return r.TotalPagesContext(context.Background())
+}
+
+func (r *result) TotalPagesContext(ctx context.Context) (uint, error) {
+	n, err := r.CountContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if r.pageSize == 0 {
+		return 1, nil
+	}
+	return uint(math.Ceil(float64(n) / float64(r.pageSize))), nil
+}
+
+func (r *result) Sum(column string) (float64, error) {
+	return r.SumContext(context.Background(), column)
+}
+
+func (r *result) SumContext(ctx context.Context, column string) (float64, error) {
+	values, err := r.numbers(ctx, column)
+	if err != nil {
+		return 0, err
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum, nil
+}
+
+func (r *result) Avg(column string) (float64, error) {
+	return r.AvgContext(context.Background(), column)
+}
+
+func (r *result) AvgContext(ctx context.Context, column string) (float64, error) {
+	values, err := r.numbers(ctx, column)
+	if err != nil || len(values) == 0 {
+		return 0, err
+	}
+	sum, _ := r.SumContext(ctx, column)
+	return sum / float64(len(values)), nil
+}
+
+func (r *result) Min(column string, dst interface{}) error {
+	return r.MinContext(context.Background(), column, dst)
+}
+
+func (r *result) MinContext(ctx context.Context, column string, dst interface{}) error {
+	return r.extreme(ctx, column, dst, -1)
+}
+
+func (r *result) Max(column string, dst interface{}) error {
+	return r.MaxContext(context.Background(), column, dst)
+}
+
+func (r *result) MaxContext(ctx context.Context, column string, dst interface{}) error {
+	return r.extreme(ctx, column, dst, 1)
+}
+
+func (r *result) CountDistinct(column string) (uint64, error) {
+	return r.CountDistinctContext(context.Background(), column)
+}
+
+func (r *result) CountDistinctContext(ctx context.Context, column string) (uint64, error) {
+	rows, err := r.read(ctx, false)
+	if err != nil {
+		return 0, err
+	}
+	seen := map[string]bool{}
+	for _, row := range rows {
+		if v := normalize(row[column]); v != nil {
+			seen[fmt.Sprintf("%#v", v)] = true
+		}
+	}
+	return uint64(len(seen)), nil
+}
+
+func (r *result) Update(values interface{}) error {
+	return r.UpdateContext(context.Background(), values)
+}
+
+func (r *result) UpdateContext(ctx context.Context, values interface{}) error {
+	_, err := r.update(ctx, values)
+	return err
+}
+
+func (r *result) UpdateReturning(ptr interface{}) error {
+	return r.UpdateReturningContext(context.Background(), ptr)
+}
+
+func (r *result) UpdateReturningContext(ctx context.Context, ptr interface{}) error {
+	if reflect.ValueOf(ptr).Kind() != reflect.Ptr {
+		return errExpectingPointer
+	}
+	updated, err := r.update(ctx, ptr)
+	if err != nil {
+		return err
+	}
+	if len(updated) == 0 {
+		return db.ErrNoMoreRows
+	}
+	return assignOne(ptr, updated[0])
+}
+
+func (r *result) Delete() error {
+	return r.DeleteContext(context.Background())
+}
+
+func (r *result) DeleteContext(ctx context.Context) error {
+	_, err := r.delete(ctx)
+	return err
+}
+
+func (r *result) DeleteReturning(dst interface{}) error {
+	return r.DeleteReturningContext(context.Background(), dst)
+}
+
+func (r *result) DeleteReturningContext(ctx context.Context, dst interface{}) error {
+	deleted, err := r.delete(ctx)
+	if err != nil {
+		return err
+	}
+	return assignAll(dst, deleted)
+}
+
+// read returns a copy of the rows of the result, sorted and projected. Only
+// the rows of the current page (or within the limit and offset) are returned
+// if paged is true.
+func (r *result) read(ctx context.Context, paged bool) ([]Row, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d := r.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var rows []Row
+	if e := d.expected(Find, r.c.name, r.conds); e != nil && (e.err != nil || e.rows != nil) {
+		if e.err != nil {
+			return nil, e.err
+		}
+		rows = copyRows(e.rows)
+	} else {
+		indexes, err := r.matching()
+		if err != nil {
+			return nil, err
+		}
+		t := d.table(r.c.name, false)
+		for _, i := range indexes {
+			rows = append(rows, copyRow(t.rows[i]))
+		}
+	}
+
+	if err := sortRows(rows, r.orderBy); err != nil {
+		return nil, err
+	}
+	if paged {
+		rows = r.window(rows)
+	}
+	return project(rows, r.fields)
+}
+
+// update sets values on the rows of the result and returns a copy of them.
+func (r *result) update(ctx context.Context, values interface{}) ([]Row, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	row, err := toRow(values)
+	if err != nil {
+		return nil, err
+	}
+
+	d := r.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e := d.expected(Update, r.c.name, r.conds); e != nil && e.err != nil {
+		return nil, e.err
+	}
+
+	indexes, err := r.matching()
+	if err != nil {
+		return nil, err
+	}
+	t := d.table(r.c.name, false)
+	updated := make([]Row, 0, len(indexes))
+	for _, i := range indexes {
+		for column, value := range row {
+			t.rows[i][column] = value
+		}
+		updated = append(updated, copyRow(t.rows[i]))
+	}
+	return updated, nil
+}
+
+// delete removes the rows of the result and returns them.
+func (r *result) delete(ctx context.Context) ([]Row, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d := r.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e := d.expected(Delete, r.c.name, r.conds); e != nil && e.err != nil {
+		return nil, e.err
+	}
+
+	indexes, err := r.matching()
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) == 0 {
+		return nil, nil
+	}
+
+	t := d.table(r.c.name, false)
+	deleted := make([]Row, 0, len(indexes))
+	kept := t.rows[:0]
+	for i, row := range t.rows {
+		if len(deleted) < len(indexes) && indexes[len(deleted)] == i {
+			deleted = append(deleted, row)
+			continue
+		}
+		kept = append(kept, row)
+	}
+	t.rows = kept
+	return deleted, nil
+}
+
+// matching returns the indexes of the rows of the table that satisfy the
+// conditions of the result. r.c.d.mu must be held.
+func (r *result) matching() ([]int, error) {
+	t := r.c.d.table(r.c.name, false)
+	if t == nil {
+		return nil, nil
+	}
+	var indexes []int
+	for i := range t.rows {
+		ok, err := matches(t.rows[i], r.conds)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
+
+// window returns the rows within the page, or the limit and offset, of the
+// result.
+func (r *result) window(rows []Row) []Row {
+	offset, limit := r.offset, r.limit
+	if r.pageSize > 0 {
+		page := r.pageNumber
+		if page == 0 {
+			page = 1
+		}
+		offset, limit = int(r.pageSize*(page-1)), int(r.pageSize)
+	}
+	if offset >= len(rows) {
+		return nil
+	}
+	rows = rows[offset:]
+	if limit > 0 && limit < len(rows) {
+		rows = rows[:limit]
+	}
+	return rows
+}
+
+// numbers returns the values of column that are numbers.
+func (r *result) numbers(ctx context.Context, column string) ([]float64, error) {
+	rows, err := r.read(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	var values []float64
+	for _, row := range rows {
+		if v, ok := normalize(row[column]).(float64); ok {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+// extreme sets dst to the lowest (sign -1) or highest (sign 1) value of
+// column.
+func (r *result) extreme(ctx context.Context, column string, dst interface{}, sign int) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errExpectingPointer
+	}
+	rows, err := r.read(ctx, false)
+	if err != nil {
+		return err
+	}
+	var found interface{}
+	for _, row := range rows {
+		value := row[column]
+		if normalize(value) == nil {
+			continue
+		}
+		if c, ok := order(normalize(value), normalize(found)); found == nil || (ok && c == sign) {
+			found = value
+		}
+	}
+	return setValue(v.Elem(), found)
+}
+
+type rowSorter struct {
+	rows []Row
+	less func(a, b Row) bool
+}
+
+func (s rowSorter) Len() int {
+	return len(s.rows)
+}
+
+func (s rowSorter) Swap(i, j int) {
+	s.rows[i], s.rows[j] = s.rows[j], s.rows[i]
+}
+
+func (s rowSorter) Less(i, j int) bool {
+	return s.less(s.rows[i], s.rows[j])
+}
+
+// sortRows sorts rows by the given terms, column names that may be prefixed
+// with "-" or followed by "ASC" or "DESC". NULL values come first.
+func sortRows(rows []Row, terms []interface{}) error {
+	type key struct {
+		column string
+		desc   bool
+	}
+
+	var keys []key
+	for _, term := range terms {
+		s, ok := term.(string)
+		if !ok {
+			return fmt.Errorf("mocks: unsupported order %v", term)
+		}
+		fields := strings.Fields(s)
+		if len(fields) == 0 {
+			continue
+		}
+		k := key{column: fields[0]}
+		if strings.HasPrefix(k.column, "-") {
+			k.column, k.desc = k.column[1:], true
+		}
+		if len(fields) > 1 && strings.ToUpper(fields[1]) == "DESC" {
+			k.desc = true
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	sort.Stable(rowSorter{rows: rows, less: func(a, b Row) bool {
+		for _, k := range keys {
+			x, y := normalize(a[k.column]), normalize(b[k.column])
+			c := 0
+			switch {
+			case x == nil && y == nil:
+			case x == nil:
+				c = -1
+			case y == nil:
+				c = 1
+			default:
+				c, _ = order(x, y)
+			}
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}})
+	return nil
+}
+
+// project keeps the given columns of rows, all of them are kept if there are
+// none or one of them is "*".
+func project(rows []Row, fields []interface{}) ([]Row, error) {
+	if len(fields) == 0 {
+		return rows, nil
+	}
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		s, ok := field.(string)
+		if !ok {
+			return nil, fmt.Errorf("mocks: unsupported field %v", field)
+		}
+		if s == "*" {
+			return rows, nil
+		}
+		columns = append(columns, s)
+	}
+	for i := range rows {
+		projected := make(Row, len(columns))
+		for _, column := range columns {
+			projected[column] = rows[i][column]
+		}
+		rows[i] = projected
+	}
+	return rows, nil
+}
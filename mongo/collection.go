@@ -22,14 +22,17 @@
 package mongo
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
 
 	"reflect"
 
-	"gopkg.in/mgo.v2"
-	"gopkg.in/mgo.v2/bson"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"upper.io/db.v3"
 )
 
@@ -37,7 +40,7 @@ import (
 type Collection struct {
 	name       string
 	parent     *Source
-	collection *mgo.Collection
+	collection *mongo.Collection
 }
 
 var (
@@ -108,9 +111,9 @@ func compare(field string, cmp db.Comparison) (string, interface{}) {
 		}
 		return field, bson.M{"$ne": value}
 	case db.ComparisonOperatorRegExp, db.ComparisonOperatorLike:
-		return field, bson.RegEx{value.(string), ""}
+		return field, primitive.Regex{Pattern: value.(string)}
 	case db.ComparisonOperatorNotRegExp, db.ComparisonOperatorNotLike:
-		return field, bson.M{"$not": bson.RegEx{value.(string), ""}}
+		return field, bson.M{"$not": primitive.Regex{Pattern: value.(string)}}
 	}
 
 	if cmpOp, ok := comparisonOperators[op]; ok {
@@ -122,7 +125,7 @@ func compare(field string, cmp db.Comparison) (string, interface{}) {
 	panic(fmt.Sprintf("Unsupported operator %v", op))
 }
 
-// compileStatement transforms conditions into something *mgo.Session can
+// compileStatement transforms conditions into something the driver can
 // understand.
 func compileStatement(cond db.Cond) bson.M {
 	conds := bson.M{}
@@ -170,7 +173,7 @@ func compileStatement(cond db.Cond) bson.M {
 	return conds
 }
 
-// compileConditions compiles terms into something *mgo.Session can
+// compileConditions compiles terms into something the driver can
 // understand.
 func (col *Collection) compileConditions(term interface{}) interface{} {
 
@@ -208,7 +211,7 @@ func (col *Collection) compileConditions(term interface{}) interface{} {
 	return nil
 }
 
-// compileQuery compiles terms into something that *mgo.Session can
+// compileQuery compiles terms into something that the driver can
 // understand.
 func (col *Collection) compileQuery(terms ...interface{}) interface{} {
 	var query interface{}
@@ -224,10 +227,10 @@ func (col *Collection) compileQuery(terms ...interface{}) interface{} {
 			// query = map[string]interface{}{"$and": conditions}
 
 			// attempt to workaround https://jira.mongodb.org/browse/SERVER-4572
-			mapped := map[string]interface{}{}
+			mapped := bson.M{}
 			for _, v := range conditions {
-				for kk := range v.(map[string]interface{}) {
-					mapped[kk] = v.(map[string]interface{})[kk]
+				for kk := range v.(bson.M) {
+					mapped[kk] = v.(bson.M)[kk]
 				}
 			}
 
@@ -242,12 +245,22 @@ func (col *Collection) compileQuery(terms ...interface{}) interface{} {
 
 // Name returns the name of the table or tables that form the collection.
 func (col *Collection) Name() string {
-	return col.collection.Name
+	return col.collection.Name()
 }
 
-// Truncate deletes all rows from the table.
-func (col *Collection) Truncate() error {
-	err := col.collection.DropCollection()
+// Truncate deletes all rows from the table, options are not supported by
+// MongoDB.
+func (col *Collection) Truncate(opts ...db.TruncateOption) error {
+	return col.TruncateContext(context.Background(), opts...)
+}
+
+// TruncateContext is like Truncate.
+func (col *Collection) TruncateContext(ctx context.Context, opts ...db.TruncateOption) error {
+	if len(opts) > 0 {
+		return db.ErrUnsupported
+	}
+
+	err := col.collection.Drop(ctx)
 
 	if err != nil {
 		return err
@@ -260,46 +273,114 @@ func (col *Collection) InsertReturning(item interface{}) error {
 	return db.ErrUnsupported
 }
 
+func (col *Collection) InsertReturningContext(ctx context.Context, item interface{}) error {
+	return db.ErrUnsupported
+}
+
+func (col *Collection) InsertReturningAll(items interface{}) error {
+	return db.ErrUnsupported
+}
+
+func (col *Collection) InsertReturningAllContext(ctx context.Context, items interface{}) error {
+	return db.ErrUnsupported
+}
+
+// CopyFrom inserts all the items of the given slice with a single call.
+func (col *Collection) CopyFrom(rows interface{}) error {
+	return col.CopyFromContext(context.Background(), rows)
+}
+
+// CopyFromContext is like CopyFrom.
+func (col *Collection) CopyFromContext(ctx context.Context, rows interface{}) error {
+	rowsV := reflect.ValueOf(rows)
+	if rows == nil || rowsV.Kind() != reflect.Slice {
+		return fmt.Errorf("Expecting a slice but got %T", rows)
+	}
+	if rowsV.Len() == 0 {
+		return nil
+	}
+	docs := make([]interface{}, rowsV.Len())
+	for i := range docs {
+		docs[i] = rowsV.Index(i).Interface()
+	}
+	if _, err := col.collection.InsertMany(ctx, docs); err != nil {
+		return duplicateKeyError(err)
+	}
+	return nil
+}
+
 func (col *Collection) UpdateReturning(item interface{}) error {
 	return db.ErrUnsupported
 }
 
+func (col *Collection) UpdateReturningContext(ctx context.Context, item interface{}) error {
+	return db.ErrUnsupported
+}
+
+// Upsert inserts an item (map or struct) or replaces the one with the same
+// _id.
+func (col *Collection) Upsert(item interface{}) error {
+	return col.UpsertContext(context.Background(), item)
+}
+
+// UpsertContext is like Upsert.
+func (col *Collection) UpsertContext(ctx context.Context, item interface{}) error {
+	if _, err := col.collection.ReplaceOne(ctx, bson.M{"_id": getID(item)}, item, options.Replace().SetUpsert(true)); err != nil {
+		return duplicateKeyError(err)
+	}
+	return nil
+}
+
 // Insert inserts an item (map or struct) into the collection.
 func (col *Collection) Insert(item interface{}) (interface{}, error) {
-	var err error
+	return col.InsertContext(context.Background(), item)
+}
 
+// InsertContext is like Insert.
+func (col *Collection) InsertContext(ctx context.Context, item interface{}) (interface{}, error) {
 	id := getID(item)
 
-	if col.parent.versionAtLeast(2, 6, 0, 0) {
-		// this breaks MongoDb older than 2.6
-		if _, err = col.collection.Upsert(bson.M{"_id": id}, item); err != nil {
-			return nil, err
-		}
-	} else {
-		// Allocating a new ID.
-		if err = col.collection.Insert(bson.M{"_id": id}); err != nil {
-			return nil, err
-		}
+	if _, err := col.collection.ReplaceOne(ctx, bson.M{"_id": id}, item, options.Replace().SetUpsert(true)); err != nil {
+		return nil, duplicateKeyError(err)
+	}
+
+	return id, nil
+}
 
-		// Now append data the user wants to append.
-		if err = col.collection.Update(bson.M{"_id": id}, item); err != nil {
-			// Cleanup allocated ID
-			col.collection.Remove(bson.M{"_id": id})
-			return nil, err
+// duplicateKeyError turns the E11000 errors MongoDB reports when a document
+// would break a unique index into db.ErrUniqueViolation values.
+func duplicateKeyError(err error) error {
+	s := err.Error()
+	if !strings.Contains(s, `E11000`) {
+		return err
+	}
+
+	// E11000 duplicate key error collection: test.users index: email_1 dup key: { ... }
+	cErr := &db.ErrUniqueViolation{Err: err}
+	if i := strings.Index(s, ` index: `); i >= 0 {
+		index := s[i+len(` index: `):]
+		if j := strings.Index(index, ` `); j >= 0 {
+			index = index[:j]
 		}
+		cErr.Constraint = index
 	}
+	return cErr
+}
 
-	return id, nil
+// FindByIDs creates a result set with the documents that have any of the
+// given _id values. MongoDB has no limit of parameters, so the ids are not
+// split into chunks.
+func (col *Collection) FindByIDs(ids interface{}, chunkSize int) db.Result {
+	return col.Find(db.Cond{"_id": db.In(ids)})
 }
 
 // Exists returns true if the collection exists.
 func (col *Collection) Exists() bool {
-	query := col.parent.database.C(`system.namespaces`).Find(map[string]string{`name`: fmt.Sprintf(`%s.%s`, col.parent.database.Name, col.collection.Name)})
-	count, _ := query.Count()
-	if count > 0 {
-		return true
+	names, err := col.parent.database.ListCollectionNames(context.Background(), bson.M{"name": col.collection.Name()})
+	if err != nil {
+		return false
 	}
-	return false
+	return len(names) > 0
 }
 
 // Fetches object _id or generates a new one if object doesn't have one or the one it has is invalid
@@ -311,7 +392,7 @@ func getID(item interface{}) interface{} {
 	case reflect.Map:
 		if inItem, ok := item.(map[string]interface{}); ok {
 			if id, ok := inItem["_id"]; ok {
-				bsonID, ok := id.(bson.ObjectId)
+				bsonID, ok := id.(primitive.ObjectID)
 				if ok {
 					return bsonID
 				}
@@ -344,16 +425,16 @@ func getID(item interface{}) interface{} {
 
 				if parts[0] == "_id" {
 					fieldName = field.Name
-					idCacheMutex.RLock()
+					idCacheMutex.Lock()
 					idCache[t] = fieldName
-					idCacheMutex.RUnlock()
+					idCacheMutex.Unlock()
 					break
 				}
 			}
 		}
 		if fieldName != "" {
-			if bsonID, ok := v.FieldByName(fieldName).Interface().(bson.ObjectId); ok {
-				if bsonID.Valid() {
+			if bsonID, ok := v.FieldByName(fieldName).Interface().(primitive.ObjectID); ok {
+				if !bsonID.IsZero() {
 					return bsonID
 				}
 			} else {
@@ -362,5 +443,5 @@ func getID(item interface{}) interface{} {
 		}
 	}
 
-	return bson.NewObjectId()
+	return primitive.NewObjectID()
 }
@@ -25,6 +25,8 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+
+	"upper.io/db.v3"
 )
 
 const connectionScheme = `mongodb`
@@ -36,6 +38,9 @@ type ConnectionURL struct {
 	Host     string
 	Database string
 	Options  map[string]string
+
+	// TLS is passed to the driver as a *tls.Config.
+	TLS *db.TLSConfig
 }
 
 func (c ConnectionURL) String() (s string) {
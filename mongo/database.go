@@ -19,17 +19,23 @@
 // OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
 // WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 
-// Package mongo wraps the gopkg.in/mgo.v2 MongoDB driver. See
-// https://upper.io/db.v3/mongo for documentation, particularities and usage
-// examples.
+// Package mongo wraps the official go.mongodb.org/mongo-driver MongoDB
+// driver. See https://upper.io/db.v3/mongo for documentation, particularities
+// and usage examples.
 package mongo // import "upper.io/db.v3/mongo"
 
 import (
+	"context"
 	"strings"
 	"sync"
 	"time"
 
-	"gopkg.in/mgo.v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 	"upper.io/db.v3"
 )
 
@@ -44,9 +50,9 @@ type Source struct {
 
 	name          string
 	connURL       db.ConnectionURL
-	session       *mgo.Session
-	database      *mgo.Database
-	version       []int
+	client        *mongo.Client
+	database      *mongo.Database
+	clone         bool
 	collections   map[string]*Collection
 	collectionsMu sync.Mutex
 }
@@ -57,7 +63,7 @@ func init() {
 	})
 }
 
-// Open stablishes a new connection to a SQL server.
+// Open stablishes a new connection to a MongoDB server.
 func Open(settings db.ConnectionURL) (db.Database, error) {
 	d := &Source{Settings: db.NewSettings()}
 	if err := d.Open(settings); err != nil {
@@ -70,58 +76,56 @@ func (s *Source) ConnectionURL() db.ConnectionURL {
 	return s.connURL
 }
 
-// SetConnMaxLifetime is not supported.
-func (s *Source) SetConnMaxLifetime(time.Duration) {
-	s.Settings.SetConnMaxLifetime(time.Duration(0))
-}
-
-// SetMaxIdleConns is not supported.
+// SetMaxIdleConns is not supported, the driver keeps idle connections around
+// until they reach the connection lifetime.
 func (s *Source) SetMaxIdleConns(int) {
 	s.Settings.SetMaxIdleConns(0)
 }
 
-// SetMaxOpenConns is not supported.
-func (s *Source) SetMaxOpenConns(int) {
-	s.Settings.SetMaxOpenConns(0)
-}
-
 // Name returns the name of the database.
 func (s *Source) Name() string {
 	return s.name
 }
 
-// Open attempts to connect to the database.
+// Open attempts to connect to the database. The size of the connection pool
+// is taken from SetMaxOpenConns and idle connections are closed after
+// SetConnMaxLifetime, both must be set before calling Open. Reads and writes
+// use the "majority" concern unless the connection URL sets readConcernLevel
+// or w.
 func (s *Source) Open(connURL db.ConnectionURL) error {
 	s.connURL = connURL
 	return s.open()
 }
 
-// Clone returns a cloned db.Database session.
+// Clone returns a cloned db.Database session. The clone shares the
+// connection pool of its parent, closing the clone does not close the pool.
 func (s *Source) Clone() (db.Database, error) {
-	newSession := s.session.Copy()
 	clone := &Source{
 		Settings: db.NewSettings(),
 
 		name:        s.name,
 		connURL:     s.connURL,
-		session:     newSession,
-		database:    newSession.DB(s.database.Name),
-		version:     s.version,
+		client:      s.client,
+		database:    s.database,
+		clone:       true,
 		collections: map[string]*Collection{},
 	}
 	return clone, nil
 }
 
-// NewTransaction should support transactions, but it doesn't as MongoDB
-// currently does not support them.
+// NewTransaction is not supported.
 func (s *Source) NewTransaction() (db.Tx, error) {
 	return nil, db.ErrUnsupported
 }
 
-// Ping checks whether a connection to the database is still alive by pinging
-// it, establishing a connection if necessary.
+// Ping checks whether a connection to the primary server is alive.
 func (s *Source) Ping() error {
-	return s.session.Ping()
+	return s.PingContext(context.Background())
+}
+
+// PingContext is like Ping.
+func (s *Source) PingContext(ctx context.Context) error {
+	return s.client.Ping(ctx, readpref.Primary())
 }
 
 func (s *Source) ClearCache() {
@@ -130,38 +134,99 @@ func (s *Source) ClearCache() {
 	s.collections = make(map[string]*Collection)
 }
 
-// Driver returns the underlying *mgo.Session instance.
+// Driver returns the underlying *mongo.Client instance.
 func (s *Source) Driver() interface{} {
-	return s.session
+	return s.client
+}
+
+func (s *Source) clientOptions() (*options.ClientOptions, error) {
+	conn, err := ParseURL(s.connURL.String())
+	if err != nil {
+		return nil, err
+	}
+	s.name = conn.Database
+
+	opts := options.Client().
+		SetConnectTimeout(connTimeout).
+		SetServerSelectionTimeout(connTimeout).
+		SetReadConcern(readconcern.Majority()).
+		SetWriteConcern(writeconcern.New(writeconcern.WMajority())).
+		ApplyURI(conn.String())
+
+	if n := s.MaxOpenConns(); n > 0 {
+		opts.SetMaxPoolSize(uint64(n))
+	}
+	if d := s.ConnMaxLifetime(); d > 0 {
+		opts.SetMaxConnIdleTime(d)
+	}
+
+	if cfg := connectionTLS(s.connURL); cfg != nil {
+		tlsConfig, err := cfg.Load()
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	return opts, opts.Validate()
+}
+
+// connectionTLS returns the TLS configuration of the given connection URL.
+func connectionTLS(connURL db.ConnectionURL) *db.TLSConfig {
+	switch u := connURL.(type) {
+	case ConnectionURL:
+		return u.TLS
+	case *ConnectionURL:
+		if u != nil {
+			return u.TLS
+		}
+	}
+	return nil
 }
 
 func (s *Source) open() error {
-	var err error
+	opts, err := s.clientOptions()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), connTimeout)
+	defer cancel()
 
-	if s.session, err = mgo.DialWithTimeout(s.connURL.String(), connTimeout); err != nil {
+	if s.client, err = mongo.Connect(ctx, opts); err != nil {
+		return err
+	}
+
+	if err = s.client.Ping(ctx, readpref.Primary()); err != nil {
+		s.client.Disconnect(context.Background())
 		return err
 	}
 
 	s.collections = map[string]*Collection{}
-	s.database = s.session.DB("")
+	s.database = s.client.Database(s.name)
 
 	return nil
 }
 
 // Close terminates the current database session.
 func (s *Source) Close() error {
-	if s.session != nil {
-		s.session.Close()
+	if s.client != nil && !s.clone {
+		return s.client.Disconnect(context.Background())
 	}
 	return nil
 }
 
 // Collections returns a list of non-system tables from the database.
 func (s *Source) Collections() (cols []string, err error) {
+	return s.CollectionsContext(context.Background())
+}
+
+// CollectionsContext is like Collections.
+func (s *Source) CollectionsContext(ctx context.Context) (cols []string, err error) {
 	var rawcols []string
 	var col string
 
-	if rawcols, err = s.database.CollectionNames(); err != nil {
+	if rawcols, err = s.database.ListCollectionNames(ctx, bson.D{}); err != nil {
 		return nil, err
 	}
 
@@ -187,36 +252,10 @@ func (s *Source) Collection(name string) db.Collection {
 	if col, ok = s.collections[name]; !ok {
 		col = &Collection{
 			parent:     s,
-			collection: s.database.C(name),
+			collection: s.database.Collection(name),
 		}
 		s.collections[name] = col
 	}
 
 	return col
 }
-
-func (s *Source) versionAtLeast(version ...int) bool {
-	// only fetch this once - it makes a db call
-	if len(s.version) == 0 {
-		buildInfo, err := s.database.Session.BuildInfo()
-		if err != nil {
-			return false
-		}
-		s.version = buildInfo.VersionArray
-	}
-
-	// Check major version first
-	if s.version[0] > version[0] {
-		return true
-	}
-
-	for i := range version {
-		if i == len(s.version) {
-			return false
-		}
-		if s.version[i] < version[i] {
-			return false
-		}
-	}
-	return true
-}
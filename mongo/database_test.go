@@ -33,13 +33,14 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
-	"gopkg.in/mgo.v2/bson"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"upper.io/db.v3"
 )
 
 type artistType struct {
-	ID   bson.ObjectId `bson:"_id,omitempty"`
-	Name string        `bson:"name"`
+	ID   primitive.ObjectID `bson:"_id,omitempty"`
+	Name string             `bson:"name"`
 }
 
 // Global settings for tests.
@@ -226,12 +227,12 @@ func TestInsert(t *testing.T) {
 		t.Fatalf("Expecting an ID.")
 	}
 
-	if _, ok := id.(bson.ObjectId); ok != true {
-		t.Fatalf("Expecting a bson.ObjectId.")
+	if _, ok := id.(primitive.ObjectID); ok != true {
+		t.Fatalf("Expecting a primitive.ObjectID.")
 	}
 
-	if id.(bson.ObjectId).Valid() != true {
-		t.Fatalf("Expecting a valid bson.ObjectId.")
+	if id.(primitive.ObjectID).IsZero() {
+		t.Fatalf("Expecting a valid primitive.ObjectID.")
 	}
 
 	// Inserting a struct.
@@ -245,12 +246,12 @@ func TestInsert(t *testing.T) {
 		t.Fatalf("Expecting an ID.")
 	}
 
-	if _, ok := id.(bson.ObjectId); ok != true {
-		t.Fatalf("Expecting a bson.ObjectId.")
+	if _, ok := id.(primitive.ObjectID); ok != true {
+		t.Fatalf("Expecting a primitive.ObjectID.")
 	}
 
-	if id.(bson.ObjectId).Valid() != true {
-		t.Fatalf("Expecting a valid bson.ObjectId.")
+	if id.(primitive.ObjectID).IsZero() {
+		t.Fatalf("Expecting a valid primitive.ObjectID.")
 	}
 
 	// Inserting a struct (using tags to specify the field name).
@@ -264,12 +265,12 @@ func TestInsert(t *testing.T) {
 		t.Fatalf("Expecting an ID.")
 	}
 
-	if _, ok := id.(bson.ObjectId); ok != true {
-		t.Fatalf("Expecting a bson.ObjectId.")
+	if _, ok := id.(primitive.ObjectID); ok != true {
+		t.Fatalf("Expecting a primitive.ObjectID.")
 	}
 
-	if id.(bson.ObjectId).Valid() != true {
-		t.Fatalf("Expecting a valid bson.ObjectId.")
+	if id.(primitive.ObjectID).IsZero() {
+		t.Fatalf("Expecting a valid primitive.ObjectID.")
 	}
 
 	// Inserting a pointer to a struct
@@ -283,12 +284,12 @@ func TestInsert(t *testing.T) {
 		t.Fatalf("Expecting an ID.")
 	}
 
-	if _, ok := id.(bson.ObjectId); ok != true {
-		t.Fatalf("Expecting a bson.ObjectId.")
+	if _, ok := id.(primitive.ObjectID); ok != true {
+		t.Fatalf("Expecting a primitive.ObjectID.")
 	}
 
-	if id.(bson.ObjectId).Valid() != true {
-		t.Fatalf("Expecting a valid bson.ObjectId.")
+	if id.(primitive.ObjectID).IsZero() {
+		t.Fatalf("Expecting a valid primitive.ObjectID.")
 	}
 
 	// Inserting a pointer to a map
@@ -300,12 +301,12 @@ func TestInsert(t *testing.T) {
 		t.Fatalf("Expecting an ID.")
 	}
 
-	if _, ok := id.(bson.ObjectId); ok != true {
-		t.Fatalf("Expecting a bson.ObjectId.")
+	if _, ok := id.(primitive.ObjectID); ok != true {
+		t.Fatalf("Expecting a primitive.ObjectID.")
 	}
 
-	if id.(bson.ObjectId).Valid() != true {
-		t.Fatalf("Expecting a valid bson.ObjectId.")
+	if id.(primitive.ObjectID).IsZero() {
+		t.Fatalf("Expecting a valid primitive.ObjectID.")
 	}
 
 	var total uint64
@@ -340,7 +341,7 @@ func TestGetNonExistentRow_Issue426(t *testing.T) {
 	var all []artistType
 	err = artist.Find(db.Cond{"name": "nothing"}).All(&all)
 
-	assert.Zero(t, err, "All should not return mgo.ErrNotFound")
+	assert.Zero(t, err, "All should not return an error on empty results")
 	assert.Equal(t, 0, len(all))
 }
 
@@ -480,12 +481,12 @@ func TestResultFetch(t *testing.T) {
 		if rowM["_id"] == nil {
 			t.Fatalf("Expecting an ID.")
 		}
-		if _, ok := rowM["_id"].(bson.ObjectId); ok != true {
-			t.Fatalf("Expecting a bson.ObjectId.")
+		if _, ok := rowM["_id"].(primitive.ObjectID); ok != true {
+			t.Fatalf("Expecting a primitive.ObjectID.")
 		}
 
-		if rowM["_id"].(bson.ObjectId).Valid() != true {
-			t.Fatalf("Expecting a valid bson.ObjectId.")
+		if rowM["_id"].(primitive.ObjectID).IsZero() {
+			t.Fatalf("Expecting a valid primitive.ObjectID.")
 		}
 		if name, ok := rowM["name"].(string); !ok || name == "" {
 			t.Fatalf("Expecting a name.")
@@ -496,14 +497,14 @@ func TestResultFetch(t *testing.T) {
 
 	// Testing struct
 	rowS := struct {
-		ID   bson.ObjectId `bson:"_id"`
-		Name string        `bson:"name"`
+		ID   primitive.ObjectID `bson:"_id"`
+		Name string             `bson:"name"`
 	}{}
 
 	res = artist.Find()
 
 	for res.Next(&rowS) {
-		if rowS.ID.Valid() == false {
+		if rowS.ID.IsZero() {
 			t.Fatalf("Expecting a not null ID.")
 		}
 		if rowS.Name == "" {
@@ -515,14 +516,14 @@ func TestResultFetch(t *testing.T) {
 
 	// Testing tagged struct
 	rowT := struct {
-		Value1 bson.ObjectId `bson:"_id"`
-		Value2 string        `bson:"name"`
+		Value1 primitive.ObjectID `bson:"_id"`
+		Value2 string             `bson:"name"`
 	}{}
 
 	res = artist.Find()
 
 	for res.Next(&rowT) {
-		if rowT.Value1.Valid() == false {
+		if rowT.Value1.IsZero() {
 			t.Fatalf("Expecting a not null ID.")
 		}
 		if rowT.Value2 == "" {
@@ -552,7 +553,7 @@ func TestResultFetch(t *testing.T) {
 	res = artist.Find()
 
 	allRowsS := []struct {
-		ID   bson.ObjectId `bson:"_id"`
+		ID   primitive.ObjectID `bson:"_id"`
 		Name string
 	}{}
 	err = res.All(&allRowsS)
@@ -562,7 +563,7 @@ func TestResultFetch(t *testing.T) {
 	}
 
 	for _, singleRowS := range allRowsS {
-		if singleRowS.ID.Valid() == false {
+		if singleRowS.ID.IsZero() {
 			t.Fatalf("Expecting a not null ID.")
 		}
 	}
@@ -571,8 +572,8 @@ func TestResultFetch(t *testing.T) {
 	res = artist.Find()
 
 	allRowsT := []struct {
-		Value1 bson.ObjectId `bson:"_id"`
-		Value2 string        `bson:"name"`
+		Value1 primitive.ObjectID `bson:"_id"`
+		Value2 string             `bson:"name"`
 	}{}
 	err = res.All(&allRowsT)
 
@@ -581,7 +582,7 @@ func TestResultFetch(t *testing.T) {
 	}
 
 	for _, singleRowT := range allRowsT {
-		if singleRowT.Value1.Valid() == false {
+		if singleRowT.Value1.IsZero() {
 			t.Fatalf("Expecting a not null ID.")
 		}
 	}
@@ -606,7 +607,7 @@ func TestUpdate(t *testing.T) {
 
 	// Value
 	value := struct {
-		ID   bson.ObjectId `bson:"_id"`
+		ID   primitive.ObjectID `bson:"_id"`
 		Name string
 	}{}
 
@@ -737,7 +738,7 @@ func TestDelete(t *testing.T) {
 	res := artist.Find(db.Cond{"_id": db.NotEq(nil)}).Limit(1)
 
 	var first struct {
-		ID bson.ObjectId `bson:"_id"`
+		ID primitive.ObjectID `bson:"_id"`
 	}
 
 	err = res.One(&first)
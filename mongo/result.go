@@ -22,16 +22,19 @@
 package mongo
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
 
 	"encoding/json"
 
-	"gopkg.in/mgo.v2"
-	"gopkg.in/mgo.v2/bson"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"upper.io/db.v3"
 
 	"upper.io/db.v3/internal/immutable"
@@ -56,9 +59,9 @@ type resultQuery struct {
 }
 
 type result struct {
-	iter  *mgo.Iter
-	err   error
-	errMu sync.Mutex
+	cursor *mongo.Cursor
+	err    error
+	errMu  sync.Mutex
 
 	fn   func(*resultQuery) error
 	prev *result
@@ -124,6 +127,12 @@ func (res *result) Cursor(cursorColumn string) db.Result {
 
 func (res *result) NextPage(cursorValue interface{}) db.Result {
 	return res.frame(func(r *resultQuery) error {
+		if c, ok := cursorValue.(db.Cursor); ok {
+			var err error
+			if cursorValue, err = c.Value(); err != nil {
+				return err
+			}
+		}
 		r.cursorValue = cursorValue
 		r.cursorReverseOrder = false
 		r.cursorCond = db.Cond{
@@ -135,6 +144,12 @@ func (res *result) NextPage(cursorValue interface{}) db.Result {
 
 func (res *result) PrevPage(cursorValue interface{}) db.Result {
 	return res.frame(func(r *resultQuery) error {
+		if c, ok := cursorValue.(db.Cursor); ok {
+			var err error
+			if cursorValue, err = c.Value(); err != nil {
+				return err
+			}
+		}
 		r.cursorValue = cursorValue
 		r.cursorReverseOrder = true
 		r.cursorCond = db.Cond{
@@ -144,12 +159,33 @@ func (res *result) PrevPage(cursorValue interface{}) db.Result {
 	})
 }
 
+// NextPageCursor is not supported by the mongo adapter, cursor values like
+// bson.ObjectId can't be encoded into a db.Cursor.
+func (res *result) NextPageCursor(items interface{}) (db.Cursor, error) {
+	return "", db.ErrUnsupported
+}
+
+// PrevPageCursor is not supported by the mongo adapter.
+func (res *result) PrevPageCursor(items interface{}) (db.Cursor, error) {
+	return "", db.ErrUnsupported
+}
+
 func (res *result) TotalEntries() (uint64, error) {
-	return res.Count()
+	return res.TotalEntriesContext(context.Background())
+}
+
+// TotalEntriesContext is like TotalEntries.
+func (res *result) TotalEntriesContext(ctx context.Context) (uint64, error) {
+	return res.CountContext(ctx)
 }
 
 func (res *result) TotalPages() (uint, error) {
-	count, err := res.Count()
+	return res.TotalPagesContext(context.Background())
+}
+
+// TotalPagesContext is like TotalPages.
+func (res *result) TotalPagesContext(ctx context.Context) (uint, error) {
+	count, err := res.CountContext(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -217,14 +253,14 @@ func (res *result) Select(fields ...interface{}) db.Result {
 
 // All dumps all results into a pointer to an slice of structs or maps.
 func (res *result) All(dst interface{}) error {
-	rq, err := res.build()
-	if err != nil {
-		return err
-	}
+	return res.AllContext(context.Background(), dst)
+}
 
-	q, err := rq.query()
+// AllContext is like All.
+func (res *result) AllContext(ctx context.Context, dst interface{}) error {
+	rq, err := res.build()
 	if err != nil {
-		return err
+		return res.setErr(err)
 	}
 
 	if rq.c.parent.LoggingEnabled() {
@@ -238,11 +274,13 @@ func (res *result) All(dst interface{}) error {
 		}(time.Now())
 	}
 
-	err = q.All(dst)
-	if err == mgo.ErrNotFound {
-		return db.ErrNoMoreRows
+	cursor, err := rq.find(ctx)
+	if err != nil {
+		return res.setErr(err)
 	}
-	return err
+
+	err = cursor.All(ctx, dst)
+	return res.setErr(err)
 }
 
 // Group is used to group results that have the same value in the same column
@@ -254,17 +292,66 @@ func (res *result) Group(fields ...interface{}) db.Result {
 	})
 }
 
+// Preload is not supported by MongoDB, fetching results after calling it
+// returns db.ErrUnsupported.
+func (res *result) Preload(relations ...string) db.Result {
+	return res.frame(func(r *resultQuery) error {
+		return db.ErrUnsupported
+	})
+}
+
+// Scope is not supported by MongoDB, fetching results after calling it
+// returns db.ErrUnsupported.
+func (res *result) Scope(names ...string) db.Result {
+	return res.frame(func(r *resultQuery) error {
+		return db.ErrUnsupported
+	})
+}
+
+// Model is not supported by MongoDB, fetching results after calling it
+// returns db.ErrUnsupported.
+func (res *result) Model(item interface{}) db.Result {
+	return res.frame(func(r *resultQuery) error {
+		return db.ErrUnsupported
+	})
+}
+
+// SoftDelete is not supported by MongoDB, fetching results after calling it
+// returns db.ErrUnsupported.
+func (res *result) SoftDelete(column string) db.Result {
+	return res.frame(func(r *resultQuery) error {
+		return db.ErrUnsupported
+	})
+}
+
+// Unscoped returns the result set as it is, documents are never soft
+// deleted.
+func (res *result) Unscoped() db.Result {
+	return res.frame(func(r *resultQuery) error {
+		return nil
+	})
+}
+
+// OnlyDeleted is not supported by MongoDB, fetching results after calling it
+// returns db.ErrUnsupported.
+func (res *result) OnlyDeleted() db.Result {
+	return res.frame(func(r *resultQuery) error {
+		return db.ErrUnsupported
+	})
+}
+
 // One fetches only one result from the resultset.
 func (res *result) One(dst interface{}) error {
-	rq, err := res.build()
-	if err != nil {
-		return err
-	}
+	return res.OneContext(context.Background(), dst)
+}
 
-	q, err := rq.query()
+// OneContext is like One.
+func (res *result) OneContext(ctx context.Context, dst interface{}) error {
+	rq, err := res.build()
 	if err != nil {
-		return err
+		return res.setErr(err)
 	}
+	rq.limit = 1
 
 	if rq.c.parent.LoggingEnabled() {
 		defer func(start time.Time) {
@@ -277,11 +364,21 @@ func (res *result) One(dst interface{}) error {
 		}(time.Now())
 	}
 
-	err = q.One(dst)
-	if err == mgo.ErrNotFound {
+	cursor, err := rq.find(ctx)
+	if err != nil {
+		return res.setErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	if !cursor.Next(ctx) {
+		if err = cursor.Err(); err != nil {
+			return res.setErr(err)
+		}
 		return db.ErrNoMoreRows
 	}
-	return err
+
+	err = cursor.Decode(dst)
+	return res.setErr(err)
 }
 
 func (res *result) Err() error {
@@ -295,18 +392,20 @@ func (res *result) setErr(err error) error {
 	res.errMu.Lock()
 	defer res.errMu.Unlock()
 
+	res.err = err
 	return err
 }
 
 func (res *result) Next(dst interface{}) bool {
-	if res.iter == nil {
-		rq, err := res.build()
-		if err != nil {
-			return false
-		}
+	return res.NextContext(context.Background(), dst)
+}
 
-		q, err := rq.query()
+// NextContext is like Next.
+func (res *result) NextContext(ctx context.Context, dst interface{}) bool {
+	if res.cursor == nil {
+		rq, err := res.build()
 		if err != nil {
+			res.setErr(err)
 			return false
 		}
 
@@ -321,19 +420,167 @@ func (res *result) Next(dst interface{}) bool {
 			}(time.Now())
 		}
 
-		res.iter = q.Iter()
+		if res.cursor, err = rq.find(ctx); err != nil {
+			res.setErr(err)
+			return false
+		}
 	}
 
-	if !res.iter.Next(dst) {
-		res.setErr(res.iter.Err())
+	if !res.cursor.Next(ctx) {
+		res.setErr(res.cursor.Err())
+		return false
+	}
+
+	if err := res.cursor.Decode(dst); err != nil {
+		res.setErr(err)
 		return false
 	}
 
 	return true
 }
 
+func (res *result) ForEach(fn func(scan func(dest ...interface{}) error) error) error {
+	return res.ForEachContext(context.Background(), fn)
+}
+
+// ForEachContext is like ForEach, scan decodes the current document into its
+// only destination.
+func (res *result) ForEachContext(ctx context.Context, fn func(scan func(dest ...interface{}) error) error) error {
+	rq, err := res.build()
+	if err != nil {
+		return res.setErr(err)
+	}
+
+	if rq.c.parent.LoggingEnabled() {
+		defer func(start time.Time) {
+			rq.c.parent.Logger().Log(&db.QueryStatus{
+				Query: rq.debugQuery("Find.ForEach"),
+				Err:   err,
+				Start: start,
+				End:   time.Now(),
+			})
+		}(time.Now())
+	}
+
+	cursor, err := rq.find(ctx)
+	if err != nil {
+		return res.setErr(err)
+	}
+	defer cursor.Close(ctx)
+
+	scan := func(dest ...interface{}) error {
+		if len(dest) != 1 {
+			return db.ErrUnsupported
+		}
+		return cursor.Decode(dest[0])
+	}
+
+	for cursor.Next(ctx) {
+		if err = fn(scan); err != nil {
+			return err
+		}
+	}
+
+	err = cursor.Err()
+	return res.setErr(err)
+}
+
+// Batch streams the results in slices of up to size items, documents are
+// read from a cursor of their own as batches are received. Errors are sent as
+// the last value of the channel.
+func (res *result) Batch(ctx context.Context, size int, sliceOfStructs interface{}) <-chan interface{} {
+	sliceType := reflect.TypeOf(sliceOfStructs)
+	if sliceType != nil && sliceType.Kind() == reflect.Ptr {
+		sliceType = sliceType.Elem()
+	}
+	if sliceType == nil || sliceType.Kind() != reflect.Slice {
+		res.setErr(db.ErrUnsupportedDestination)
+		return batchErr(db.ErrUnsupportedDestination)
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	batches := make(chan interface{})
+	go func() {
+		defer close(batches)
+
+		fail := func(err error) {
+			res.setErr(err)
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case batches <- err:
+			case <-ctx.Done():
+			}
+		}
+
+		iter := res.frame(func(*resultQuery) error {
+			return nil
+		})
+		defer iter.Close()
+
+		elemType := sliceType.Elem()
+		batch := reflect.MakeSlice(sliceType, 0, size)
+
+		send := func() bool {
+			select {
+			case batches <- batch.Interface():
+			case <-ctx.Done():
+				res.setErr(ctx.Err())
+				return false
+			}
+			batch = reflect.MakeSlice(sliceType, 0, size)
+			return true
+		}
+
+		for {
+			var item reflect.Value
+			if elemType.Kind() == reflect.Ptr {
+				item = reflect.New(elemType.Elem())
+			} else {
+				item = reflect.New(elemType)
+			}
+			if !iter.NextContext(ctx, item.Interface()) {
+				break
+			}
+			if elemType.Kind() != reflect.Ptr {
+				item = item.Elem()
+			}
+			batch = reflect.Append(batch, item)
+			if batch.Len() == size && !send() {
+				return
+			}
+		}
+
+		if err := iter.Err(); err != nil {
+			fail(err)
+			return
+		}
+		if batch.Len() > 0 {
+			send()
+		}
+	}()
+
+	return batches
+}
+
+// batchErr returns a closed channel that holds err as its only value.
+func batchErr(err error) <-chan interface{} {
+	batches := make(chan interface{}, 1)
+	batches <- err
+	close(batches)
+	return batches
+}
+
 // Delete remove the matching items from the collection.
 func (res *result) Delete() error {
+	return res.DeleteContext(context.Background())
+}
+
+// DeleteContext is like Delete.
+func (res *result) DeleteContext(ctx context.Context) error {
 	rq, err := res.build()
 	if err != nil {
 		return err
@@ -350,7 +597,7 @@ func (res *result) Delete() error {
 		}(time.Now())
 	}
 
-	_, err = rq.c.collection.RemoveAll(rq.conditions)
+	_, err = rq.c.collection.DeleteMany(ctx, rq.filter())
 	if err != nil {
 		return err
 	}
@@ -361,17 +608,22 @@ func (res *result) Delete() error {
 // Close closes the result set.
 func (r *result) Close() error {
 	var err error
-	if r.iter != nil {
-		err = r.iter.Close()
-		r.iter = nil
+	if r.cursor != nil {
+		err = r.cursor.Close(context.Background())
+		r.cursor = nil
 	}
 	return err
 }
 
 // Update modified matching items from the collection with values of the given
 // map or struct.
-func (res *result) Update(src interface{}) (err error) {
-	updateSet := map[string]interface{}{"$set": src}
+func (res *result) Update(src interface{}) error {
+	return res.UpdateContext(context.Background(), src)
+}
+
+// UpdateContext is like Update.
+func (res *result) UpdateContext(ctx context.Context, src interface{}) (err error) {
+	updateSet := bson.M{"$set": src}
 
 	rq, err := res.build()
 	if err != nil {
@@ -389,13 +641,29 @@ func (res *result) Update(src interface{}) (err error) {
 		}(time.Now())
 	}
 
-	_, err = rq.c.collection.UpdateAll(rq.conditions, updateSet)
+	_, err = rq.c.collection.UpdateMany(ctx, rq.filter(), updateSet)
 	if err != nil {
-		return err
+		return duplicateKeyError(err)
 	}
 	return nil
 }
 
+func (res *result) UpdateReturning(ptr interface{}) error {
+	return db.ErrUnsupported
+}
+
+func (res *result) UpdateReturningContext(ctx context.Context, ptr interface{}) error {
+	return db.ErrUnsupported
+}
+
+func (res *result) DeleteReturning(dst interface{}) error {
+	return db.ErrUnsupported
+}
+
+func (res *result) DeleteReturningContext(ctx context.Context, dst interface{}) error {
+	return db.ErrUnsupported
+}
+
 func (res *result) build() (*resultQuery, error) {
 	rqi, err := immutable.FastForward(res)
 	if err != nil {
@@ -417,13 +685,22 @@ func (res *result) build() (*resultQuery, error) {
 	return rq, nil
 }
 
-// query executes a mgo query.
-func (r *resultQuery) query() (*mgo.Query, error) {
+// filter returns the query conditions, the driver expects a document even
+// when there are no conditions.
+func (r *resultQuery) filter() interface{} {
+	if r.conditions == nil {
+		return bson.M{}
+	}
+	return r.conditions
+}
+
+// find runs the query and returns a cursor over the matching documents.
+func (r *resultQuery) find(ctx context.Context) (*mongo.Cursor, error) {
 	if len(r.groupBy) > 0 {
 		return nil, db.ErrUnsupported
 	}
 
-	q := r.c.collection.Find(r.conditions)
+	opts := options.Find()
 
 	if r.pageSize > 0 {
 		r.offset = int(r.pageSize * r.pageNumber)
@@ -431,15 +708,15 @@ func (r *resultQuery) query() (*mgo.Query, error) {
 	}
 
 	if r.offset > 0 {
-		q.Skip(r.offset)
+		opts.SetSkip(int64(r.offset))
 	}
 
 	if r.limit > 0 {
-		q.Limit(r.limit)
+		opts.SetLimit(int64(r.limit))
 	}
 
 	if len(r.sort) > 0 {
-		q.Sort(r.sort...)
+		opts.SetSort(sortDocument(r.sort))
 	}
 
 	selectedFields := bson.M{}
@@ -453,30 +730,57 @@ func (r *resultQuery) query() (*mgo.Query, error) {
 	}
 
 	if r.cursorReverseOrder {
-		ids := make([]bson.ObjectId, 0, r.limit)
+		ids := make([]interface{}, 0, r.limit)
 
-		iter := q.Select(bson.M{"_id": true}).Iter()
-		defer iter.Close()
+		cursor, err := r.c.collection.Find(ctx, r.filter(), opts.SetProjection(bson.M{"_id": true}))
+		if err != nil {
+			return nil, err
+		}
 
-		var item map[string]bson.ObjectId
-		for iter.Next(&item) {
+		var items []bson.M
+		if err := cursor.All(ctx, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
 			ids = append(ids, item["_id"])
 		}
 
 		r.conditions = bson.M{"_id": bson.M{"$in": ids}}
 
-		q = r.c.collection.Find(r.conditions)
+		opts = options.Find()
 	}
 
 	if len(selectedFields) > 0 {
-		q.Select(selectedFields)
+		opts.SetProjection(selectedFields)
 	}
 
-	return q, nil
+	return r.c.collection.Find(ctx, r.filter(), opts)
+}
+
+// sortDocument converts sort fields like "name" or "-name" into an ordered
+// sort document.
+func sortDocument(fields []string) bson.D {
+	doc := make(bson.D, 0, len(fields))
+	for _, field := range fields {
+		order := 1
+		switch {
+		case strings.HasPrefix(field, "-"):
+			field, order = field[1:], -1
+		case strings.HasPrefix(field, "+"):
+			field = field[1:]
+		}
+		doc = append(doc, bson.E{Key: field, Value: order})
+	}
+	return doc
 }
 
 func (res *result) Exists() (bool, error) {
-	total, err := res.Count()
+	return res.ExistsContext(context.Background())
+}
+
+// ExistsContext is like Exists.
+func (res *result) ExistsContext(ctx context.Context) (bool, error) {
+	total, err := res.CountContext(ctx)
 	if err != nil {
 		return false, err
 	}
@@ -486,13 +790,30 @@ func (res *result) Exists() (bool, error) {
 	return false, nil
 }
 
-// Count counts matching elements.
-func (res *result) Count() (total uint64, err error) {
+// Count counts matching elements. The CountEstimate option is only supported
+// on results without conditions, which are counted from the metadata of the
+// collection.
+func (res *result) Count(opts ...db.CountOption) (uint64, error) {
+	return res.CountContext(context.Background(), opts...)
+}
+
+// CountContext is like Count.
+func (res *result) CountContext(ctx context.Context, opts ...db.CountOption) (total uint64, err error) {
 	rq, err := res.build()
 	if err != nil {
 		return 0, err
 	}
 
+	estimate := false
+	for _, opt := range opts {
+		if opt == db.CountEstimate {
+			estimate = true
+		}
+	}
+	if estimate && rq.conditions != nil {
+		return 0, db.ErrUnsupported
+	}
+
 	if rq.c.parent.LoggingEnabled() {
 		defer func(start time.Time) {
 			rq.c.parent.Logger().Log(&db.QueryStatus{
@@ -504,14 +825,71 @@ func (res *result) Count() (total uint64, err error) {
 		}(time.Now())
 	}
 
-	q := rq.c.collection.Find(rq.conditions)
-
-	var c int
-	c, err = q.Count()
+	var c int64
+	if estimate {
+		c, err = rq.c.collection.EstimatedDocumentCount(ctx)
+	} else {
+		c, err = rq.c.collection.CountDocuments(ctx, rq.filter())
+	}
 
 	return uint64(c), err
 }
 
+// Sum is not supported by the mongo adapter.
+func (res *result) Sum(column string) (float64, error) {
+	return 0, db.ErrUnsupported
+}
+
+func (res *result) SumContext(ctx context.Context, column string) (float64, error) {
+	return 0, db.ErrUnsupported
+}
+
+// Avg is not supported by the mongo adapter.
+func (res *result) Avg(column string) (float64, error) {
+	return 0, db.ErrUnsupported
+}
+
+func (res *result) AvgContext(ctx context.Context, column string) (float64, error) {
+	return 0, db.ErrUnsupported
+}
+
+// Min is not supported by the mongo adapter.
+func (res *result) Min(column string, dst interface{}) error {
+	return db.ErrUnsupported
+}
+
+func (res *result) MinContext(ctx context.Context, column string, dst interface{}) error {
+	return db.ErrUnsupported
+}
+
+// Max is not supported by the mongo adapter.
+func (res *result) Max(column string, dst interface{}) error {
+	return db.ErrUnsupported
+}
+
+func (res *result) MaxContext(ctx context.Context, column string, dst interface{}) error {
+	return db.ErrUnsupported
+}
+
+// CountDistinct is not supported by the mongo adapter.
+func (res *result) CountDistinct(column string) (uint64, error) {
+	return 0, db.ErrUnsupported
+}
+
+func (res *result) CountDistinctContext(ctx context.Context, column string) (uint64, error) {
+	return 0, db.ErrUnsupported
+}
+
+// Compile is not supported by the mongo adapter, use String to see the query.
+func (res *result) Compile() (string, []interface{}, error) {
+	return "", nil, db.ErrUnsupported
+}
+
+// Explain is not supported by the mongo adapter.
+func (res *result) Explain(ctx context.Context) (string, error) {
+	return "", db.ErrUnsupported
+}
+
 func (res *result) Prev() immutable.Immutable {
 	if res == nil {
 		return nil
@@ -531,7 +909,7 @@ func (res *result) Base() interface{} {
 }
 
 func (r *resultQuery) debugQuery(action string) string {
-	query := fmt.Sprintf("db.%s.%s", r.c.collection.Name, action)
+	query := fmt.Sprintf("db.%s.%s", r.c.collection.Name(), action)
 
 	if r.conditions != nil {
 		query = fmt.Sprintf("%s.conds(%v)", query, r.conditions)
@@ -22,7 +22,9 @@
 package mssql
 
 import (
+	"context"
 	"database/sql"
+	"strings"
 
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter"
@@ -37,10 +39,12 @@ type table struct {
 	name string
 
 	hasIdentityColumn *bool
+	hasTriggers       *bool
 }
 
 var (
 	_ = sqladapter.Collection(&table{})
+	_ = sqladapter.RowInserter(&table{})
 	_ = db.Collection(&table{})
 )
 
@@ -62,67 +66,45 @@ func (t *table) Database() sqladapter.Database {
 	return t.d
 }
 
-// Insert inserts an item (map or struct) into the collection.
-func (t *table) Insert(item interface{}) (interface{}, error) {
-	columnNames, columnValues, err := sqlbuilder.Map(item, nil)
+// InsertContext inserts an item (map or struct) into the collection.
+func (t *table) InsertContext(ctx context.Context, item interface{}) (interface{}, error) {
+	return sqladapter.InsertWithHooks(ctx, t.d, t.Name(), item, func() (interface{}, error) {
+		return t.insert(ctx, item)
+	})
+}
+
+func (t *table) insert(ctx context.Context, item interface{}) (interface{}, error) {
+	columnNames, columnValues, err := sqlbuilder.Map(item, t.d.MapOptions())
 	if err != nil {
 		return nil, err
 	}
 
-	pKey := t.BaseCollection.PrimaryKeys()
-
-	var hasKeys bool
-	for i := range columnNames {
-		for j := 0; j < len(pKey); j++ {
-			if pKey[j] == columnNames[i] {
-				if columnValues[i] != nil {
-					hasKeys = true
-					break
-				}
-			}
-		}
+	if columnNames, columnValues, err = t.AssignID(ctx, columnNames, columnValues); err != nil {
+		return nil, err
 	}
 
-	if hasKeys {
-		if t.hasIdentityColumn == nil {
-			var hasIdentityColumn bool
-			var identityColumns int
-
-			row, err := t.d.QueryRow("SELECT COUNT(1) FROM sys.identity_columns WHERE OBJECT_NAME(object_id) = ?", t.Name())
-			if err != nil {
-				return nil, err
-			}
-
-			err = row.Scan(&identityColumns)
-			if err != nil {
-				return nil, err
-			}
-
-			if identityColumns > 0 {
-				hasIdentityColumn = true
-			}
-
-			t.hasIdentityColumn = &hasIdentityColumn
-		}
+	pKey := t.BaseCollection.PrimaryKeys()
 
-		if *t.hasIdentityColumn {
-			_, err = t.d.Exec("SET IDENTITY_INSERT " + t.Name() + " ON")
-			if err != nil {
-				return nil, err
-			}
-			defer t.d.Exec("SET IDENTITY_INSERT " + t.Name() + " OFF")
-		}
+	identityInsertOff, err := t.identityInsert(ctx, pKey, columnNames, columnValues)
+	if err != nil {
+		return nil, err
 	}
+	defer identityInsertOff()
 
 	q := t.d.InsertInto(t.Name()).
 		Columns(columnNames...).
 		Values(columnValues...)
 
 	var res sql.Result
-	if res, err = q.Exec(); err != nil {
+	if res, err = q.ExecContext(ctx); err != nil {
 		return nil, err
 	}
 
+	if id, ok := sqladapter.InsertedKey(pKey, columnNames, columnValues); ok {
+		// The key was given a value before inserting, like an UUID.
+		return id, nil
+	}
+
 	if len(pKey) <= 1 {
 		// Attempt to use LastInsertId() (probably won't work, but the Exec()
 		// succeeded, so we can safely ignore the error from LastInsertId()).
@@ -143,3 +125,165 @@ func (t *table) Insert(item interface{}) (interface{}, error) {
 
 	return keyMap, nil
 }
+
+// InsertRowContext inserts an item and scans the inserted row into dst, the
+// row is read with an OUTPUT clause so it does not depend on LastInsertId().
+func (t *table) InsertRowContext(ctx context.Context, item interface{}, dst interface{}) error {
+	_, err := sqladapter.InsertWithHooks(ctx, t.d, t.Name(), item, func() (interface{}, error) {
+		return nil, t.insertRow(ctx, item, dst)
+	})
+	return err
+}
+
+func (t *table) insertRow(ctx context.Context, item interface{}, dst interface{}) error {
+	columnNames, columnValues, err := sqlbuilder.Map(item, t.d.MapOptions())
+	if err != nil {
+		return err
+	}
+
+	if columnNames, columnValues, err = t.AssignID(ctx, columnNames, columnValues); err != nil {
+		return err
+	}
+
+	pKey := t.BaseCollection.PrimaryKeys()
+
+	identityInsertOff, err := t.identityInsert(ctx, pKey, columnNames, columnValues)
+	if err != nil {
+		return err
+	}
+	defer identityInsertOff()
+
+	if t.hasTriggers == nil {
+		var triggers int
+
+		row, err := t.d.QueryRowContext(ctx, "SELECT COUNT(1) FROM sys.triggers WHERE parent_id = OBJECT_ID(?) AND is_disabled = 0", t.Name())
+		if err != nil {
+			return err
+		}
+
+		if err = row.Scan(&triggers); err != nil {
+			return err
+		}
+
+		hasTriggers := triggers > 0
+		t.hasTriggers = &hasTriggers
+	}
+
+	var query string
+	if *t.hasTriggers {
+		query = insertOutputIntoQuery(t.Name(), columnNames, pKey)
+	} else {
+		query = insertOutputQuery(t.Name(), columnNames)
+	}
+
+	rows, err := t.d.QueryContext(ctx, query, columnValues...)
+	if err != nil {
+		return err
+	}
+
+	return sqlbuilder.NewIterator(rows).One(dst)
+}
+
+// identityInsert enables IDENTITY_INSERT if the item sets any of the primary
+// keys and the table has an identity column, the returned function disables
+// it again.
+func (t *table) identityInsert(ctx context.Context, pKey []string, columnNames []string, columnValues []interface{}) (func(), error) {
+	var hasKeys bool
+	for i := range columnNames {
+		for j := 0; j < len(pKey); j++ {
+			if pKey[j] == columnNames[i] {
+				if columnValues[i] != nil {
+					hasKeys = true
+					break
+				}
+			}
+		}
+	}
+
+	if !hasKeys {
+		return func() {}, nil
+	}
+
+	if t.hasIdentityColumn == nil {
+		var hasIdentityColumn bool
+		var identityColumns int
+
+		row, err := t.d.QueryRowContext(ctx, "SELECT COUNT(1) FROM sys.identity_columns WHERE OBJECT_NAME(object_id) = ?", t.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		err = row.Scan(&identityColumns)
+		if err != nil {
+			return nil, err
+		}
+
+		if identityColumns > 0 {
+			hasIdentityColumn = true
+		}
+
+		t.hasIdentityColumn = &hasIdentityColumn
+	}
+
+	if !*t.hasIdentityColumn {
+		return func() {}, nil
+	}
+
+	if _, err := t.d.ExecContext(ctx, "SET IDENTITY_INSERT "+t.Name()+" ON"); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		t.d.Exec("SET IDENTITY_INSERT " + t.Name() + " OFF")
+	}, nil
+}
+
+// insertOutputQuery returns an INSERT statement that outputs the inserted
+// row.
+func insertOutputQuery(table string, columns []string) string {
+	return insertQuery(table, columns, "OUTPUT INSERTED.*")
+}
+
+// insertOutputIntoQuery is like insertOutputQuery but it works on tables with
+// enabled triggers, where OUTPUT requires an INTO clause. The primary keys of
+// the inserted row are stored into a table variable and the row is selected
+// afterwards.
+func insertOutputIntoQuery(table string, columns []string, pKey []string) string {
+	keys := make([]string, len(pKey))
+	defs := make([]string, len(pKey))
+	conds := make([]string, len(pKey))
+	for i := range pKey {
+		key := quoteIdentifier(pKey[i])
+		keys[i] = "INSERTED." + key
+		defs[i] = key + " sql_variant"
+		conds[i] = "__t." + key + " = __i." + key
+	}
+
+	return "DECLARE @__upper_inserted TABLE (" + strings.Join(defs, ", ") + "); " +
+		insertQuery(table, columns, "OUTPUT "+strings.Join(keys, ", ")+" INTO @__upper_inserted") + "; " +
+		"SELECT __t.* FROM " + quoteIdentifier(table) + " AS __t INNER JOIN @__upper_inserted AS __i ON " + strings.Join(conds, " AND ")
+}
+
+func insertQuery(table string, columns []string, output string) string {
+	if len(columns) == 0 {
+		return "INSERT INTO " + quoteIdentifier(table) + " " + output + " DEFAULT VALUES"
+	}
+
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		quoted[i] = quoteIdentifier(columns[i])
+		placeholders[i] = "?"
+	}
+
+	return "INSERT INTO " + quoteIdentifier(table) + " (" + strings.Join(quoted, ", ") + ") " +
+		output + " VALUES (" + strings.Join(placeholders, ", ") + ")"
+}
+
+func quoteIdentifier(s string) string {
+	chunks := strings.Split(s, ".")
+	for i := range chunks {
+		chunks[i] = "[" + strings.Replace(chunks[i], "]", "]]", -1) + "]"
+	}
+	return strings.Join(chunks, ".")
+}
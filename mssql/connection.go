@@ -24,6 +24,10 @@ package mssql
 import (
 	"errors"
 	"net/url"
+	"strings"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
 )
 
 // ConnectionURL implements a MSSQL connection struct.
@@ -34,6 +38,10 @@ type ConnectionURL struct {
 	Host     string
 	Socket   string
 	Options  map[string]string
+
+	// TLS is translated into the encrypt, TrustServerCertificate and
+	// certificate options, the driver doesn't accept client certificates.
+	TLS *db.TLSConfig
 }
 
 func (c ConnectionURL) String() (s string) {
@@ -51,13 +59,23 @@ func (c ConnectionURL) String() (s string) {
 
 	params := url.Values{}
 	for k, v := range c.Options {
-		if k == "instance" {
+		if k == "instance" || sqladapter.IsAdapterOption(k) {
 			continue
 		}
 		params.Add(k, v)
 	}
 	params.Set("database", c.Database)
 
+	if c.TLS != nil {
+		params.Set("encrypt", "true")
+		if c.TLS.InsecureSkipVerify {
+			params.Set("TrustServerCertificate", "true")
+		}
+		if c.TLS.CAFile != "" {
+			params.Set("certificate", c.TLS.CAFile)
+		}
+	}
+
 	u := url.URL{
 		Scheme:   "sqlserver",
 		Host:     c.Host,
@@ -97,6 +115,10 @@ func ParseURL(s string) (conn ConnectionURL, err error) {
 		conn.Password, _ = u.User.Password()
 	}
 
+	if instance := strings.TrimPrefix(u.Path, "/"); instance != "" {
+		conn.Options = map[string]string{"instance": instance}
+	}
+
 	q := u.Query()
 	for k := range q {
 		if k == "database" {
@@ -26,11 +26,11 @@ package mssql
 
 import (
 	"context"
+	"database/sql"
+	"regexp"
 	"strings"
 	"sync"
 
-	"database/sql"
-
 	_ "github.com/denisenkom/go-mssqldb" // MSSQL driver
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter"
@@ -61,6 +61,11 @@ func newDatabase(settings db.ConnectionURL) *database {
 	}
 }
 
+// Adapter returns the name of the adapter.
+func (d *database) Adapter() string {
+	return Adapter
+}
+
 // ConnectionURL returns this database session's connection URL, if any.
 func (d *database) ConnectionURL() db.ConnectionURL {
 	return d.connURL
@@ -113,15 +118,24 @@ func (d *database) open() error {
 	// Binding with sqladapter's logic.
 	d.BaseDatabase = sqladapter.NewBaseDatabase(d)
 
+	if err := sqladapter.SetPoolOptions(d.BaseDatabase, sqladapter.ConnectionOptions(d.connURL)); err != nil {
+		return err
+	}
+
+	if err := setupTLS(d.connURL); err != nil {
+		return err
+	}
+
 	// Binding with sqlbuilder.
 	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
 
 	connFn := func() error {
-		sess, err := sql.Open("mssql", d.ConnectionURL().String())
+		sess, err := d.BaseDatabase.OpenFailover("mssql", sqladapter.HostDSNs(d.connURL), sqladapter.ConnectionOptions(d.connURL))
 		if err == nil {
-			sess.SetConnMaxLifetime(db.DefaultSettings.ConnMaxLifetime())
-			sess.SetMaxIdleConns(db.DefaultSettings.MaxIdleConns())
-			sess.SetMaxOpenConns(db.DefaultSettings.MaxOpenConns())
+			sess.SetConnMaxLifetime(d.BaseDatabase.ConnMaxLifetime())
+			compat.SetConnMaxIdleTime(sess, d.BaseDatabase.ConnMaxIdleTime())
+			sess.SetMaxIdleConns(d.BaseDatabase.MaxIdleConns())
+			sess.SetMaxOpenConns(d.BaseDatabase.MaxOpenConns())
 			return d.BaseDatabase.BindSession(sess)
 		}
 		return err
@@ -134,6 +148,32 @@ func (d *database) open() error {
 	return nil
 }
 
+// connectionTLS returns the TLS configuration of the given connection URL.
+func connectionTLS(connURL db.ConnectionURL) *db.TLSConfig {
+	switch u := connURL.(type) {
+	case ConnectionURL:
+		return u.TLS
+	case *ConnectionURL:
+		if u != nil {
+			return u.TLS
+		}
+	}
+	return nil
+}
+
+// setupTLS checks that the TLS configuration of the given connection URL can
+// be used, the driver only takes the path of the CA file.
+func setupTLS(connURL db.ConnectionURL) error {
+	cfg := connectionTLS(connURL)
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Config != nil || cfg.CertFile != "" || cfg.KeyFile != "" {
+		return db.ErrUnsupported
+	}
+	return cfg.Validate()
+}
+
 // Clone creates a copy of the database session on the given context.
 func (d *database) clone(ctx context.Context, checkConn bool) (*database, error) {
 	clone := newDatabase(d.connURL)
@@ -161,6 +201,43 @@ func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (s
 	return sqlbuilder.Preprocess(compiled, args)
 }
 
+// ConvertValues binds the OUT parameters of stored procedures as sql.Out
+// values.
+func (d *database) ConvertValues(values []interface{}) []interface{} {
+	return sqlbuilder.OutValues(values)
+}
+
+// NextSequenceValueQuery returns the statement that advances the given
+// sequence, it's used by NextSequenceValue.
+func (d *database) NextSequenceValueQuery(name string) string {
+	return "SELECT NEXT VALUE FOR " + quoteIdentifier(name)
+}
+
+// ExistsQuery returns the query that tells whether the given query has rows,
+// SQL Server can only use EXISTS within conditions.
+func (d *database) ExistsQuery(sel sqlbuilder.Selector) sqlbuilder.Selector {
+	return d.Select(db.Raw("CASE WHEN EXISTS ? THEN 1 ELSE 0 END AS _t", sel))
+}
+
+// TruncateStatements returns the statements that truncate the table with the
+// given options. SQL Server can't truncate tables referenced by foreign keys,
+// so its rows are deleted instead and its identity is reseeded on its own.
+func (d *database) TruncateStatements(table string, stmt *exql.Statement) ([]*exql.Statement, error) {
+	if !stmt.Cascade {
+		// TRUNCATE TABLE reseeds the identity already.
+		return []*exql.Statement{stmt}, nil
+	}
+
+	stmts := []*exql.Statement{
+		{Type: exql.Delete, Table: stmt.Table},
+	}
+	if stmt.RestartIdentity {
+		name := strings.Replace(quoteIdentifier(table), "'", "''", -1)
+		stmts = append(stmts, exql.RawSQL("DBCC CHECKIDENT ('"+name+"', RESEED, 0)"))
+	}
+	return stmts, nil
+}
+
 // Err allows sqladapter to translate specific MySQL string errors into custom
 // error values.
 func (d *database) Err(err error) error {
@@ -170,10 +247,50 @@ func (d *database) Err(err error) error {
 		if strings.Contains(s, `many connections`) {
 			return db.ErrTooManyClients
 		}
+		// Error 1205, the transaction was chosen as a deadlock victim.
+		if strings.Contains(s, `deadlock victim`) {
+			return db.ErrSerializationFailure
+		}
+		if cErr := constraintError(s, err); cErr != nil {
+			return cErr
+		}
 	}
 	return err
 }
 
+var (
+	reConstraintName = regexp.MustCompile(`constraint ['"]([^'"]+)['"]`)
+	reUniqueIndex    = regexp.MustCompile(`unique index '([^']+)'`)
+	reConflictColumn = regexp.MustCompile(`column '([^']+)'`)
+)
+
+// constraintError maps errors 2627 and 2601 (duplicate key) and error 547
+// (FOREIGN KEY, REFERENCE or CHECK constraint conflict) into
+// db.ErrUniqueViolation, db.ErrForeignKeyViolation and db.ErrCheckViolation
+// values.
+func constraintError(s string, err error) error {
+	constraint, column := "", ""
+	if m := reConstraintName.FindStringSubmatch(s); m != nil {
+		constraint = m[1]
+	}
+	if m := reConflictColumn.FindStringSubmatch(s); m != nil {
+		column = m[1]
+	}
+
+	switch {
+	case strings.Contains(s, `Cannot insert duplicate key`):
+		if m := reUniqueIndex.FindStringSubmatch(s); m != nil {
+			constraint = m[1]
+		}
+		return &db.ErrUniqueViolation{Constraint: constraint, Err: err}
+	case strings.Contains(s, `conflicted with the FOREIGN KEY constraint`) || strings.Contains(s, `conflicted with the REFERENCE constraint`):
+		return &db.ErrForeignKeyViolation{Constraint: constraint, Column: column, Err: err}
+	case strings.Contains(s, `conflicted with the CHECK constraint`):
+		return &db.ErrCheckViolation{Constraint: constraint, Column: column, Err: err}
+	}
+	return nil
+}
+
 // NewCollection creates a db.Collection by name.
 func (d *database) NewCollection(name string) db.Collection {
 	return newTable(d, name)
@@ -187,6 +304,12 @@ func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) erro
 	return sqladapter.RunTx(d, ctx, fn)
 }
 
+// TxWithRetry is like Tx but fn is run again, within a new transaction, if the
+// transaction fails because of a serialization failure or a deadlock.
+func (d *database) TxWithRetry(ctx context.Context, fn func(tx sqlbuilder.Tx) error, policy sqlbuilder.RetryPolicy) error {
+	return sqladapter.TxWithRetry(d, ctx, fn, policy)
+}
+
 // NewDatabaseTx begins a transaction block.
 func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
 	clone, err := d.clone(ctx, true)
@@ -211,6 +334,22 @@ func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, er
 	return sqladapter.NewDatabaseTx(clone), nil
 }
 
+// IsPrimary returns true if the database of the given session can be
+// written, secondary replicas of an availability group can't.
+func (d *database) IsPrimary(ctx context.Context, sess *sql.DB) (bool, error) {
+	var updateability string
+	if err := compat.QueryRowContext(sess, ctx, "SELECT CAST(DATABASEPROPERTYEX(DB_NAME(), 'Updateability') AS NVARCHAR(128))", nil).Scan(&updateability); err != nil {
+		return false, err
+	}
+	return updateability == "READ_WRITE", nil
+}
+
+// ParseURL parses the given DSN into a ConnectionURL, so that sqladapter can
+// replace its credentials.
+func (d *database) ParseURL(dsn string) (db.ConnectionURL, error) {
+	return ParseURL(dsn)
+}
+
 // LookupName looks for the name of the database and it's often used as a
 // test to determine if the connection settings are valid.
 func (d *database) LookupName() (string, error) {
@@ -283,3 +422,10 @@ func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
 	newDB, _ := d.clone(ctx, false)
 	return newDB
 }
+
+// ReadOnly creates a copy of the session that can't change the database.
+func (d *database) ReadOnly() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UseReadOnly()
+	return newDB
+}
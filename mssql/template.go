@@ -43,6 +43,10 @@ const (
 	adapterTableAliasLayout    = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	adapterColumnAliasLayout   = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	adapterSortByColumnLayout  = `{{.Column}} {{.Order}}`
+	adapterWindowLayout        = `{{.Function}} OVER ({{.Definition}})`
+	adapterJSONPathLayout      = `JSON_VALUE({{.Column}}, '{{.Path}}')`
+	adapterFullTextLayout      = `CONTAINS({{.Column}}, ?)`
+	adapterLockLayout          = `WITH ({{if .Update}}UPDLOCK{{else}}HOLDLOCK{{end}}, ROWLOCK{{if .SkipLocked}}, READPAST{{end}}{{if .NoWait}}, NOWAIT{{end}})`
 
 	adapterOrderByLayout = `{{if .SortColumns}}ORDER BY {{.SortColumns}}{{end}}`
 
@@ -80,7 +84,19 @@ const (
     {{end}}
   `
 
+	adapterWithLayout = `
+    WITH {{.CTEs}}
+  `
+
+	adapterCompoundLayout = `
+    {{range .}}{{if .Operator}} {{.Operator}} {{end}}({{.Query}}){{end}}
+  `
+
 	adapterSelectLayout = `
+		{{if .With}}
+			{{.With}}
+		{{end}}
+
 		{{if or .Limit .Offset}}
 			SELECT __q0.* FROM (
 				SELECT TOP 100 PERCENT __q1.*,
@@ -103,6 +119,9 @@ const (
 				{{end}}
 
 
+			{{if .Compound}}
+				* FROM ({{.Compound}}) __c
+			{{else}}
 				{{if .Columns}}
 					{{.Columns}}
 				{{else}}
@@ -111,6 +130,9 @@ const (
 
 				{{if .Table}}
 					FROM {{.Table}}
+					{{if .Lock}}
+						{{.Lock}}
+					{{end}}
 				{{end}}
 
 				{{.Joins}}
@@ -118,6 +140,7 @@ const (
 				{{.Where}}
 
 				{{.GroupBy}}
+			{{end}}
 
 				{{.OrderBy}}
 
@@ -145,19 +168,42 @@ const (
   `
 
 	adapterInsertLayout = `
-    INSERT INTO {{.Table}}
-      {{if .Columns }}({{.Columns}}){{end}}
-    VALUES
-    {{if .Values}}
-      {{.Values}}
+    {{if .OnConflict}}
+      MERGE INTO {{.Table}} AS __target
+      USING ({{if .Query}}{{.Query}}{{else}}VALUES {{.Values}}{{end}}) AS __source ({{.Columns}})
+      {{.OnConflict}}
     {{else}}
-      (DEFAULT)
+      INSERT INTO {{.Table}}
+        {{if .Columns }}({{.Columns}}){{end}}
+      {{if .Query}}
+        {{.Query}}
+      {{else}}
+        VALUES
+        {{if .Values}}
+          {{.Values}}
+        {{else}}
+          (DEFAULT)
+        {{end}}
+      {{end}}
+      {{if .Returning}}
+        RETURNING {{.Returning}}
+      {{end}}
     {{end}}
-    {{if .Returning}}
-      RETURNING {{.Returning}}
+  `
+
+	adapterOnConflictLayout = `
+    ON ({{range $i, $c := .Target}}{{if $i}} AND {{end}}__target.{{$c}} = __source.{{$c}}{{end}})
+    {{if .Update}}
+      WHEN MATCHED THEN
+        UPDATE SET {{.Update}}
     {{end}}
+    WHEN NOT MATCHED THEN
+      INSERT ({{range $i, $c := .Columns}}{{if $i}}, {{end}}{{$c}}{{end}})
+      VALUES ({{range $i, $c := .Columns}}{{if $i}}, {{end}}__source.{{$c}}{{end}});
   `
 
+	adapterExcludedColumn = `__source.{{.}}`
+
 	adapterTruncateLayout = `
     TRUNCATE TABLE {{.Table}}
   `
@@ -170,6 +216,38 @@ const (
     DROP TABLE {{.Table}}
   `
 
+	adapterCreateTableLayout = `
+    CREATE TABLE {{.Table}} ({{.Definitions}})
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table}} {{.Definitions}}
+  `
+
+	adapterCreateIndexLayout = `
+    CREATE {{if .Unique}}UNIQUE {{end}}INDEX {{.Index}} ON {{.Table}} ({{.Columns}})
+  `
+
+	adapterCallLayout = `
+    EXEC {{.Table}} {{.Columns}}
+  `
+
+	adapterCallOutLayout = `? OUTPUT`
+
+	adapterAddColumnLayout = `ADD {{.}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.}}`
+
+	adapterColumnDefinitionLayout = `
+    {{.Name}} {{.Type}}
+    {{if .AutoIncrement}}IDENTITY(1,1){{end}}
+    {{if .PrimaryKey}}PRIMARY KEY{{end}}
+    {{if .NotNull}}NOT NULL{{end}}
+    {{if .Unique}}UNIQUE{{end}}
+    {{if .Default}}DEFAULT {{.Default}}{{end}}
+    {{if .References}}REFERENCES {{.References}}{{end}}
+  `
+
 	adapterGroupByLayout = `
     {{if .GroupColumns}}
       GROUP BY {{.GroupColumns}}
@@ -177,36 +255,67 @@ const (
   `
 )
 
+var adapterColumnTypes = map[exql.ColumnType]string{
+	exql.TypeBoolean:    `BIT`,
+	exql.TypeInteger:    `INT`,
+	exql.TypeBigInteger: `BIGINT`,
+	exql.TypeFloat:      `FLOAT`,
+	exql.TypeDecimal:    `DECIMAL({{.Precision}}, {{.Scale}})`,
+	exql.TypeString:     `NVARCHAR({{.Size}})`,
+	exql.TypeText:       `NVARCHAR(MAX)`,
+	exql.TypeTimestamp:  `DATETIME2`,
+	exql.TypeDate:       `DATE`,
+	exql.TypeBinary:     `VARBINARY(MAX)`,
+	exql.TypeJSON:       `NVARCHAR(MAX)`,
+}
+
 var template = &exql.Template{
-	ColumnSeparator:     adapterColumnSeparator,
-	IdentifierSeparator: adapterIdentifierSeparator,
-	IdentifierQuote:     adapterIdentifierQuote,
-	ValueSeparator:      adapterValueSeparator,
-	ValueQuote:          adapterValueQuote,
-	AndKeyword:          adapterAndKeyword,
-	OrKeyword:           adapterOrKeyword,
-	DescKeyword:         adapterDescKeyword,
-	AscKeyword:          adapterAscKeyword,
-	AssignmentOperator:  adapterAssignmentOperator,
-	ClauseGroup:         adapterClauseGroup,
-	ClauseOperator:      adapterClauseOperator,
-	ColumnValue:         adapterColumnValue,
-	TableAliasLayout:    adapterTableAliasLayout,
-	ColumnAliasLayout:   adapterColumnAliasLayout,
-	SortByColumnLayout:  adapterSortByColumnLayout,
-	WhereLayout:         adapterWhereLayout,
-	JoinLayout:          adapterJoinLayout,
-	OnLayout:            adapterOnLayout,
-	UsingLayout:         adapterUsingLayout,
-	OrderByLayout:       adapterOrderByLayout,
-	InsertLayout:        adapterInsertLayout,
-	SelectLayout:        adapterSelectLayout,
-	UpdateLayout:        adapterUpdateLayout,
-	DeleteLayout:        adapterDeleteLayout,
-	TruncateLayout:      adapterTruncateLayout,
-	DropDatabaseLayout:  adapterDropDatabaseLayout,
-	DropTableLayout:     adapterDropTableLayout,
-	CountLayout:         adapterSelectCountLayout,
-	GroupByLayout:       adapterGroupByLayout,
-	Cache:               cache.NewCache(),
+	ColumnSeparator:        adapterColumnSeparator,
+	IdentifierSeparator:    adapterIdentifierSeparator,
+	IdentifierQuote:        adapterIdentifierQuote,
+	ValueSeparator:         adapterValueSeparator,
+	ValueQuote:             adapterValueQuote,
+	AndKeyword:             adapterAndKeyword,
+	OrKeyword:              adapterOrKeyword,
+	DescKeyword:            adapterDescKeyword,
+	AscKeyword:             adapterAscKeyword,
+	AssignmentOperator:     adapterAssignmentOperator,
+	ClauseGroup:            adapterClauseGroup,
+	ClauseOperator:         adapterClauseOperator,
+	ColumnValue:            adapterColumnValue,
+	TableAliasLayout:       adapterTableAliasLayout,
+	ColumnAliasLayout:      adapterColumnAliasLayout,
+	SortByColumnLayout:     adapterSortByColumnLayout,
+	WhereLayout:            adapterWhereLayout,
+	WindowLayout:           adapterWindowLayout,
+	CompoundLayout:         adapterCompoundLayout,
+	WithLayout:             adapterWithLayout,
+	JoinLayout:             adapterJoinLayout,
+	JSONPathLayout:         adapterJSONPathLayout,
+	FullTextLayout:         adapterFullTextLayout,
+	LockLayout:             adapterLockLayout,
+	OnLayout:               adapterOnLayout,
+	UsingLayout:            adapterUsingLayout,
+	OrderByLayout:          adapterOrderByLayout,
+	InsertLayout:           adapterInsertLayout,
+	OnConflictLayout:       adapterOnConflictLayout,
+	ExcludedColumn:         adapterExcludedColumn,
+	SelectLayout:           adapterSelectLayout,
+	UpdateLayout:           adapterUpdateLayout,
+	DeleteLayout:           adapterDeleteLayout,
+	TruncateLayout:         adapterTruncateLayout,
+	DropDatabaseLayout:     adapterDropDatabaseLayout,
+	DropTableLayout:        adapterDropTableLayout,
+	CreateTableLayout:      adapterCreateTableLayout,
+	AlterTableLayout:       adapterAlterTableLayout,
+	CreateIndexLayout:      adapterCreateIndexLayout,
+	CallLayout:             adapterCallLayout,
+	CallOutLayout:          adapterCallOutLayout,
+	AddColumnLayout:        adapterAddColumnLayout,
+	DropColumnLayout:       adapterDropColumnLayout,
+	ColumnDefinitionLayout: adapterColumnDefinitionLayout,
+	ColumnTypes:            adapterColumnTypes,
+	CountLayout:            adapterSelectCountLayout,
+	GroupByLayout:          adapterGroupByLayout,
+	Cache:                  cache.NewCache(),
 }
@@ -144,6 +144,103 @@ func TestTemplateSelect(t *testing.T) {
 		"SELECT DATE()",
 		b.Select(db.Raw("DATE()")).String(),
 	)
+
+	assert.Equal(
+		"SELECT __q0.* FROM ( SELECT TOP 100 PERCENT __q1.*, ROW_NUMBER() OVER (ORDER BY (SELECT 1)) AS rnum FROM ( SELECT TOP (10 + 0) * FROM ((SELECT [name] FROM [artist]) UNION (SELECT [name] FROM [band])) __c ORDER BY [name] ASC ) __q1) __q0 WHERE rnum > 0",
+		b.Select("name").From("artist").
+			Union(b.Select("name").From("band")).
+			OrderBy("name").
+			Limit(10).
+			String(),
+	)
+
+	assert.Equal(
+		"SELECT * FROM [jobs] AS [j] WITH (UPDLOCK, ROWLOCK, READPAST) WHERE ([status] = $1)",
+		b.SelectFrom("jobs j").Where(db.Cond{"status": "pending"}).ForUpdate().SkipLocked().String(),
+	)
+
+	assert.Equal(
+		"SELECT * FROM [jobs] WITH (HOLDLOCK, ROWLOCK, NOWAIT)",
+		b.SelectFrom("jobs").ForShare().NoWait().String(),
+	)
+}
+
+func TestTemplateDDL(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`CREATE TABLE [books] ([id] INT IDENTITY(1,1) PRIMARY KEY, [title] NVARCHAR(80) NOT NULL, [price] DECIMAL(10, 2) DEFAULT 0, [author_id] INT, FOREIGN KEY ([author_id]) REFERENCES [authors] ([id]))`,
+		b.CreateTable("books").Columns(
+			sqlbuilder.NewColumn("id", sqlbuilder.Integer).PrimaryKey().AutoIncrement(),
+			sqlbuilder.NewColumn("title", sqlbuilder.String(80)).NotNull(),
+			sqlbuilder.NewColumn("price", sqlbuilder.Decimal(10, 2)).Default(0),
+			sqlbuilder.NewColumn("author_id", sqlbuilder.Integer).References("authors", "id"),
+		).String(),
+	)
+
+	assert.Equal(
+		`ALTER TABLE [books] ADD [meta] NVARCHAR(MAX)`,
+		b.AlterTable("books").AddColumn(sqlbuilder.NewColumn("meta", sqlbuilder.JSON)).String(),
+	)
+
+	assert.Equal(
+		`CREATE UNIQUE INDEX [books_title_idx] ON [books] ([title])`,
+		b.CreateIndex("books_title_idx").On("books", "title").Unique().String(),
+	)
+}
+
+func TestTemplateJSONPath(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		"SELECT * FROM [users] WHERE (JSON_VALUE([data], '$.address.city') = $1)",
+		b.SelectFrom("users").Where(db.Cond{db.JSONPath("data.address.city"): "Paris"}).String(),
+	)
+}
+
+func TestTemplateFullText(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	search := db.FullText("body", "cats")
+	assert.Equal(
+		`SELECT * FROM [posts] WHERE (CONTAINS([body], $1))`,
+		b.SelectFrom("posts").Where(search).String(),
+	)
+
+	{
+		_, _, err := b.SelectFrom("posts").Where(search).OrderBy(search.Rank()).Compile()
+		assert.Equal(db.ErrUnsupported, err)
+	}
+}
+
+func TestTemplateInsertFromSelect(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	sel := b.Select("name").From("artist_archive").Where("id >", 10)
+	assert.Equal(
+		"INSERT INTO [artist] ([name]) SELECT [name] FROM [artist_archive] WHERE ([id] > $1)",
+		b.InsertInto("artist").Columns("name").ValuesFromSelect(sel).String(),
+	)
+
+	assert.Equal(
+		"MERGE INTO [artist] AS __target USING (SELECT [name] FROM [artist_archive] WHERE ([id] > $1)) AS __source ([name]) ON (__target.[name] = __source.[name]) WHEN NOT MATCHED THEN INSERT ([name]) VALUES (__source.[name]);",
+		b.InsertInto("artist").Columns("name").ValuesFromSelect(sel).OnConflict("name").DoNothing().String(),
+	)
+}
+
+func TestTemplateCall(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	var total int
+	assert.Equal(
+		"EXEC [order_total] $1, $2 OUTPUT",
+		b.Call("order_total", 12, sqlbuilder.Out(&total)).String(),
+	)
 }
 
 func TestTemplateInsert(t *testing.T) {
@@ -188,6 +285,56 @@ func TestTemplateInsert(t *testing.T) {
 	)
 }
 
+func TestInsertOutputQuery(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(
+		"INSERT INTO [artist] ([name], [id]) OUTPUT INSERTED.* VALUES (?, ?)",
+		insertOutputQuery("artist", []string{"name", "id"}),
+	)
+
+	assert.Equal(
+		"INSERT INTO [dbo].[artist] OUTPUT INSERTED.* DEFAULT VALUES",
+		insertOutputQuery("dbo.artist", nil),
+	)
+
+	assert.Equal(
+		"DECLARE @__upper_inserted TABLE ([id] sql_variant); "+
+			"INSERT INTO [artist] ([name]) OUTPUT INSERTED.[id] INTO @__upper_inserted VALUES (?); "+
+			"SELECT __t.* FROM [artist] AS __t INNER JOIN @__upper_inserted AS __i ON __t.[id] = __i.[id]",
+		insertOutputIntoQuery("artist", []string{"name"}, []string{"id"}),
+	)
+
+	assert.Equal(
+		"DECLARE @__upper_inserted TABLE ([code] sql_variant, [year] sql_variant); "+
+			"INSERT INTO [album] ([code], [year]) OUTPUT INSERTED.[code], INSERTED.[year] INTO @__upper_inserted VALUES (?, ?); "+
+			"SELECT __t.* FROM [album] AS __t INNER JOIN @__upper_inserted AS __i ON __t.[code] = __i.[code] AND __t.[year] = __i.[year]",
+		insertOutputIntoQuery("album", []string{"code", "year"}, []string{"code", "year"}),
+	)
+}
+
+func TestTemplateUpsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	artist := map[string]interface{}{"id": 12, "name": "Chavela Vargas"}
+
+	assert.Equal(
+		"MERGE INTO [artist] AS __target USING (VALUES ($1, $2)) AS __source ([id], [name]) ON (__target.[id] = __source.[id]) WHEN MATCHED THEN UPDATE SET [name] = __source.[name] WHEN NOT MATCHED THEN INSERT ([id], [name]) VALUES (__source.[id], __source.[name]);",
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoUpdate().String(),
+	)
+
+	assert.Equal(
+		"MERGE INTO [artist] AS __target USING (VALUES ($1, $2)) AS __source ([id], [name]) ON (__target.[id] = __source.[id]) WHEN MATCHED THEN UPDATE SET [name] = $3 WHEN NOT MATCHED THEN INSERT ([id], [name]) VALUES (__source.[id], __source.[name]);",
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoUpdate("name", "Chavela").String(),
+	)
+
+	assert.Equal(
+		"MERGE INTO [artist] AS __target USING (VALUES ($1, $2)) AS __source ([id], [name]) ON (__target.[id] = __source.[id]) WHEN NOT MATCHED THEN INSERT ([id], [name]) VALUES (__source.[id], __source.[name]);",
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoNothing().String(),
+	)
+}
+
 func TestTemplateUpdate(t *testing.T) {
 	b := sqlbuilder.WithTemplate(template)
 	assert := assert.New(t)
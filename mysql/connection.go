@@ -27,6 +27,9 @@ import (
 	"net"
 	"net/url"
 	"strings"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
 )
 
 // From https://github.com/go-sql-driver/mysql/blob/master/utils.go
@@ -54,6 +57,10 @@ type ConnectionURL struct {
 	Host     string
 	Socket   string
 	Options  map[string]string
+
+	// TLS is registered with the driver when the session is opened, the DSN
+	// refers to it by name with the "tls" option.
+	TLS *db.TLSConfig
 }
 
 func (c ConnectionURL) String() (s string) {
@@ -76,12 +83,16 @@ func (c ConnectionURL) String() (s string) {
 	if c.Socket != "" {
 		s = s + fmt.Sprintf("unix(%s)", c.Socket)
 	} else if c.Host != "" {
-		host, port, err := net.SplitHostPort(c.Host)
-		if err != nil {
-			host = c.Host
-			port = "3306"
+		hosts := sqladapter.SplitHosts(c.Host)
+		for i := range hosts {
+			host, port, err := net.SplitHostPort(hosts[i])
+			if err != nil {
+				host = hosts[i]
+				port = "3306"
+			}
+			hosts[i] = fmt.Sprintf("%s:%s", host, port)
 		}
-		s = s + fmt.Sprintf("tcp(%s:%s)", host, port)
+		s = s + fmt.Sprintf("tcp(%s)", strings.Join(hosts, ","))
 	}
 
 	// Adding database
@@ -105,9 +116,18 @@ func (c ConnectionURL) String() (s string) {
 	vv := url.Values{}
 
 	for k, v := range c.Options {
+		if sqladapter.IsAdapterOption(k) {
+			// Handled by the adapter.
+			continue
+		}
 		vv.Set(k, v)
 	}
 
+	if c.TLS != nil {
+		// Registered by the adapter when the session is opened.
+		vv.Set("tls", sqladapter.TLSConfigKey(c.TLS))
+	}
+
 	// Inserting options.
 	if p := vv.Encode(); p != "" {
 		s = s + "?" + p
@@ -23,6 +23,9 @@ package mysql
 
 import (
 	"testing"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
 )
 
 func TestConnectionURL(t *testing.T) {
@@ -69,6 +72,13 @@ func TestConnectionURL(t *testing.T) {
 		t.Fatal(`Test failed, got:`, c.String())
 	}
 
+	// Setting hosts.
+	c.Host = "db1:3307,db2"
+
+	if c.String() != `user:pass@tcp(db1:3307,db2:3306)/mydbname?charset=utf8&parseTime=true` {
+		t.Fatal(`Test failed, got:`, c.String())
+	}
+
 	// Setting socket.
 	c.Socket = "/path/to/socket"
 
@@ -136,3 +146,47 @@ func TestParseConnectionURL(t *testing.T) {
 	}
 
 }
+
+func TestConnectionURLTLS(t *testing.T) {
+	c := ConnectionURL{
+		Host:     "1.2.3.4:3306",
+		Database: "mydbname",
+		TLS:      &db.TLSConfig{InsecureSkipVerify: true},
+	}
+
+	u, err := ParseURL(c.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Options["tls"] != sqladapter.TLSConfigKey(c.TLS) {
+		t.Fatal("Expecting the DSN to refer to the TLS config, got:", c.String())
+	}
+
+	if err := setupTLS(c); err != nil {
+		t.Fatal(err)
+	}
+
+	c.TLS = &db.TLSConfig{CertFile: "/etc/ssl/client.pem"}
+	if err := setupTLS(c); err == nil {
+		t.Fatal("Expecting an error without a KeyFile.")
+	}
+}
+
+func TestWithCredentials(t *testing.T) {
+	d := &database{}
+
+	u, err := d.ParseURL("user:pass@tcp(1.2.3.4:3306)/mydbname?charset=utf8&parseTime=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err = sqladapter.WithCredentials(u, "iam_user", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn := u.String()
+
+	if dsn != "iam_user:token@tcp(1.2.3.4:3306)/mydbname?charset=utf8&parseTime=true" {
+		t.Fatal("Test failed, got:", dsn)
+	}
+}
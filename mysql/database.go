@@ -26,15 +26,21 @@ package mysql
 
 import (
 	"context"
+	"crypto/sha1"
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"database/sql"
 
-	_ "github.com/go-sql-driver/mysql" // MySQL driver.
+	mysqldriver "github.com/go-sql-driver/mysql" // MySQL driver.
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter"
 	"upper.io/db.v3/internal/sqladapter/compat"
@@ -55,6 +61,7 @@ type database struct {
 var (
 	_ = sqlbuilder.Database(&database{})
 	_ = sqlbuilder.Database(&database{})
+	_ = sqlbuilder.Cluster(&database{})
 )
 
 // newDatabase creates a new *database session for internal use.
@@ -64,6 +71,11 @@ func newDatabase(settings db.ConnectionURL) *database {
 	}
 }
 
+// Adapter returns the name of the adapter.
+func (d *database) Adapter() string {
+	return Adapter
+}
+
 // ConnectionURL returns this database session's connection URL, if any.
 func (d *database) ConnectionURL() db.ConnectionURL {
 	return d.connURL
@@ -115,15 +127,24 @@ func (d *database) open() error {
 	// Binding with sqladapter's logic.
 	d.BaseDatabase = sqladapter.NewBaseDatabase(d)
 
+	if err := sqladapter.SetPoolOptions(d.BaseDatabase, sqladapter.ConnectionOptions(d.connURL)); err != nil {
+		return err
+	}
+
+	if err := setupTLS(d.connURL); err != nil {
+		return err
+	}
+
 	// Binding with sqlbuilder.
 	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
 
 	connFn := func() error {
-		sess, err := sql.Open("mysql", d.ConnectionURL().String())
+		sess, err := d.BaseDatabase.OpenFailover("mysql", sqladapter.HostDSNs(d.connURL), sqladapter.ConnectionOptions(d.connURL))
 		if err == nil {
-			sess.SetConnMaxLifetime(db.DefaultSettings.ConnMaxLifetime())
-			sess.SetMaxIdleConns(db.DefaultSettings.MaxIdleConns())
-			sess.SetMaxOpenConns(db.DefaultSettings.MaxOpenConns())
+			sess.SetConnMaxLifetime(d.BaseDatabase.ConnMaxLifetime())
+			compat.SetConnMaxIdleTime(sess, d.BaseDatabase.ConnMaxIdleTime())
+			sess.SetMaxIdleConns(d.BaseDatabase.MaxIdleConns())
+			sess.SetMaxOpenConns(d.BaseDatabase.MaxOpenConns())
 			return d.BaseDatabase.BindSession(sess)
 		}
 		return err
@@ -136,6 +157,33 @@ func (d *database) open() error {
 	return nil
 }
 
+// connectionTLS returns the TLS configuration of the given connection URL.
+func connectionTLS(connURL db.ConnectionURL) *db.TLSConfig {
+	switch u := connURL.(type) {
+	case ConnectionURL:
+		return u.TLS
+	case *ConnectionURL:
+		if u != nil {
+			return u.TLS
+		}
+	}
+	return nil
+}
+
+// setupTLS registers the TLS configuration of the given connection URL with
+// the driver, under the name its DSN refers to.
+func setupTLS(connURL db.ConnectionURL) error {
+	cfg := connectionTLS(connURL)
+	if cfg == nil {
+		return nil
+	}
+	tlsConfig, err := cfg.Load()
+	if err != nil {
+		return err
+	}
+	return mysqldriver.RegisterTLSConfig(sqladapter.TLSConfigKey(cfg), tlsConfig)
+}
+
 // Clone creates a copy of the database session on the given context.
 func (d *database) clone(ctx context.Context, checkConn bool) (*database, error) {
 	clone := newDatabase(d.connURL)
@@ -189,6 +237,28 @@ func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (s
 	return sqlbuilder.Preprocess(compiled, args)
 }
 
+// TruncateStatements returns the statements that truncate the table with the
+// given options. MySQL can't truncate tables referenced by foreign keys, so
+// its rows are deleted instead and AUTO_INCREMENT is reset on its own.
+func (d *database) TruncateStatements(table string, stmt *exql.Statement) ([]*exql.Statement, error) {
+	if !stmt.Cascade {
+		// TRUNCATE resets AUTO_INCREMENT already.
+		return []*exql.Statement{stmt}, nil
+	}
+
+	stmts := []*exql.Statement{
+		{Type: exql.Delete, Table: stmt.Table},
+	}
+	if stmt.RestartIdentity {
+		quoted, err := exql.TableWithName(table).Compile(template)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, exql.RawSQL("ALTER TABLE "+quoted+" AUTO_INCREMENT = 1"))
+	}
+	return stmts, nil
+}
+
 // Err allows sqladapter to translate specific MySQL string errors into custom
 // error values.
 func (d *database) Err(err error) error {
@@ -198,10 +268,65 @@ func (d *database) Err(err error) error {
 		if strings.Contains(s, `many connections`) {
 			return db.ErrTooManyClients
 		}
+		// Error 1213 (deadlock found) and error 1205 (lock wait timeout).
+		if strings.Contains(s, `Error 1213`) || strings.Contains(s, `Error 1205`) {
+			return db.ErrSerializationFailure
+		}
+		// Error 3024, the max_execution_time of the server was exceeded.
+		if strings.Contains(s, `Error 3024`) {
+			return db.ErrQueryTimeout
+		}
+		if cErr := constraintError(s, err); cErr != nil {
+			return cErr
+		}
 	}
 	return err
 }
 
+var (
+	reDuplicateEntry   = regexp.MustCompile("for key '([^']+)'")
+	reForeignKey       = regexp.MustCompile("CONSTRAINT `([^`]+)` FOREIGN KEY \\(([^)]+)\\)")
+	reCheckConstraint  = regexp.MustCompile("[Cc]heck constraint '([^']+)'")
+	reQuotedIdentifier = regexp.MustCompile("`([^`]+)`")
+)
+
+// constraintError maps the MySQL errors that report integrity constraint
+// violations into db.ErrUniqueViolation, db.ErrForeignKeyViolation and
+// db.ErrCheckViolation values.
+func constraintError(s string, err error) error {
+	switch {
+	case strings.Contains(s, `Error 1062`) || strings.Contains(s, `Error 1586`):
+		// Duplicate entry 'joe@example.com' for key 'users.email_idx'
+		cErr := &db.ErrUniqueViolation{Err: err}
+		if m := reDuplicateEntry.FindStringSubmatch(s); m != nil {
+			// MySQL 8 prefixes the name of the key with the name of the table.
+			cErr.Constraint = m[1][strings.LastIndex(m[1], ".")+1:]
+		}
+		return cErr
+	case strings.Contains(s, `Error 1451`) || strings.Contains(s, `Error 1452`) || strings.Contains(s, `Error 1216`) || strings.Contains(s, `Error 1217`):
+		// Cannot add or update a child row: a foreign key constraint fails
+		// (`test`.`posts`, CONSTRAINT `posts_ibfk_1` FOREIGN KEY (`user_id`)
+		// REFERENCES `users` (`id`))
+		cErr := &db.ErrForeignKeyViolation{Err: err}
+		if m := reForeignKey.FindStringSubmatch(s); m != nil {
+			columns := []string{}
+			for _, c := range reQuotedIdentifier.FindAllStringSubmatch(m[2], -1) {
+				columns = append(columns, c[1])
+			}
+			cErr.Constraint, cErr.Column = m[1], strings.Join(columns, ", ")
+		}
+		return cErr
+	case strings.Contains(s, `Error 3819`):
+		// Check constraint 'users_chk_1' is violated.
+		cErr := &db.ErrCheckViolation{Err: err}
+		if m := reCheckConstraint.FindStringSubmatch(s); m != nil {
+			cErr.Constraint = m[1]
+		}
+		return cErr
+	}
+	return nil
+}
+
 // NewCollection creates a db.Collection by name.
 func (d *database) NewCollection(name string) db.Collection {
 	return newTable(d, name)
@@ -215,7 +340,14 @@ func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) erro
 	return sqladapter.RunTx(d, ctx, fn)
 }
 
-// NewDatabaseTx begins a transaction block.
+// TxWithRetry is like Tx but fn is run again, within a new transaction, if the
+// transaction fails because of a serialization failure or a deadlock.
+func (d *database) TxWithRetry(ctx context.Context, fn func(tx sqlbuilder.Tx) error, policy sqlbuilder.RetryPolicy) error {
+	return sqladapter.TxWithRetry(d, ctx, fn, policy)
+}
+
+// NewDatabaseTx begins a transaction block. If the session is already within a
+// transaction, the new one is nested using a savepoint.
 func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
 	clone, err := d.clone(ctx, true)
 	if err != nil {
@@ -224,6 +356,14 @@ func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, er
 	clone.mu.Lock()
 	defer clone.mu.Unlock()
 
+	if currTx := d.BaseDatabase.Transaction(); currTx != nil {
+		// Already within a transaction, nest the new one with a savepoint.
+		if err := clone.BindSavepoint(ctx, currTx); err != nil {
+			return nil, err
+		}
+		return sqladapter.NewDatabaseTx(clone), nil
+	}
+
 	connFn := func() error {
 		sqlTx, err := compat.BeginTx(clone.BaseDatabase.Session(), ctx, clone.TxOptions())
 		if err == nil {
@@ -239,6 +379,222 @@ func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, er
 	return sqladapter.NewDatabaseTx(clone), nil
 }
 
+// errReplicationStopped is returned by ReplicaLag when the replica is not
+// replicating.
+var errReplicationStopped = errors.New("upper: replication is not running")
+
+// ReplicaLag returns how far the given replica is behind the primary server,
+// as reported by Seconds_Behind_Source. Servers that are not replicas are not
+// behind.
+func (d *database) ReplicaLag(ctx context.Context, sess *sql.DB) (time.Duration, error) {
+	// SHOW SLAVE STATUS was renamed on MySQL 8.0.22.
+	rows, err := compat.QueryContext(sess, ctx, "SHOW REPLICA STATUS", nil)
+	if err != nil {
+		if rows, err = compat.QueryContext(sess, ctx, "SHOW SLAVE STATUS", nil); err != nil {
+			return 0, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		return 0, rows.Err()
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+
+	for i, name := range columns {
+		if name != "Seconds_Behind_Source" && name != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errReplicationStopped
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return 0, nil
+}
+
+// IsPrimary returns true if the server of the given session is not read
+// only.
+func (d *database) IsPrimary(ctx context.Context, sess *sql.DB) (bool, error) {
+	var readOnly int
+	if err := compat.QueryRowContext(sess, ctx, "SELECT @@global.read_only", nil).Scan(&readOnly); err != nil {
+		return false, err
+	}
+	return readOnly == 0, nil
+}
+
+// ExplainQuery returns the statement that shows the plan of query, it's
+// returned by Explain and logged along with slow queries.
+func (d *database) ExplainQuery(query string) string {
+	return "EXPLAIN " + query
+}
+
+// ExplainJSONQuery is like ExplainQuery but the plan is shown in JSON format.
+func (d *database) ExplainJSONQuery(query string) string {
+	return "EXPLAIN FORMAT=JSON " + query
+}
+
+// TableRowsEstimateQuery returns the statement that selects the number of
+// rows of the table kept in information_schema, which is only approximate
+// for InnoDB tables.
+func (d *database) TableRowsEstimateQuery(table string) (string, []interface{}) {
+	return "SELECT table_rows FROM information_schema.tables WHERE table_schema = ? AND table_name = ?",
+		[]interface{}{d.BaseDatabase.Name(), table}
+}
+
+// PlanRows returns the number of rows the given plan estimates its query
+// returns, the plan of a query that can't match any row has no table.
+func (d *database) PlanRows(plan string) (uint64, error) {
+	var p struct {
+		QueryBlock map[string]interface{} `json:"query_block"`
+	}
+	if err := json.Unmarshal([]byte(plan), &p); err != nil {
+		return 0, err
+	}
+	return uint64(planNodeRows(p.QueryBlock)), nil
+}
+
+// planNodeRows returns the number of rows the given node of a plan produces.
+// Tables are found under operations like ordering_operation or
+// grouping_operation, the rows of a join are the ones of its last table and
+// the rows of a union are the sum of the ones of its queries.
+func planNodeRows(node map[string]interface{}) float64 {
+	if table, ok := node["table"].(map[string]interface{}); ok {
+		rows, _ := table["rows_produced_per_join"].(float64)
+		return rows
+	}
+	if loop, ok := node["nested_loop"].([]interface{}); ok && len(loop) > 0 {
+		last, _ := loop[len(loop)-1].(map[string]interface{})
+		return planNodeRows(last)
+	}
+	if union, ok := node["union_result"].(map[string]interface{}); ok {
+		queries, _ := union["query_specifications"].([]interface{})
+		var rows float64
+		for i := range queries {
+			if query, ok := queries[i].(map[string]interface{}); ok {
+				block, _ := query["query_block"].(map[string]interface{})
+				rows += planNodeRows(block)
+			}
+		}
+		return rows
+	}
+	var rows float64
+	for _, v := range node {
+		if child, ok := v.(map[string]interface{}); ok {
+			if n := planNodeRows(child); n > rows {
+				rows = n
+			}
+		}
+	}
+	return rows
+}
+
+// advisoryLockName returns the name of the named lock of the given key, names
+// are limited to 64 characters so longer keys are hashed.
+func advisoryLockName(key string) string {
+	if len(key) <= 64 {
+		return key
+	}
+	return fmt.Sprintf("%x", sha1.Sum([]byte(key)))
+}
+
+// AdvisoryLockQuery returns the statement that takes a named lock, it's used by
+// AdvisoryLock.
+func (d *database) AdvisoryLockQuery(key string) (string, []interface{}) {
+	return "SELECT GET_LOCK(?, -1)", []interface{}{advisoryLockName(key)}
+}
+
+// TryAdvisoryLockQuery returns the statement that takes a named lock without
+// waiting, it's used by TryAdvisoryLock.
+func (d *database) TryAdvisoryLockQuery(key string) (string, []interface{}) {
+	return "SELECT GET_LOCK(?, 0)", []interface{}{advisoryLockName(key)}
+}
+
+// AdvisoryUnlockQuery returns the statement that releases a named lock, it's
+// used by Unlock.
+func (d *database) AdvisoryUnlockQuery(key string) (string, []interface{}) {
+	return "SELECT RELEASE_LOCK(?)", []interface{}{advisoryLockName(key)}
+}
+
+// quoteXID returns the global id of an XA transaction as a string literal.
+func quoteXID(gid string) string {
+	r := strings.NewReplacer(`\`, `\\`, "'", "''")
+	return "'" + r.Replace(gid) + "'"
+}
+
+// BeginGlobalQueries returns the statements that turn the transaction that was
+// just begun into an XA transaction, the local transaction begun by the driver
+// has to be finished first. It's used by transactions begun with
+// sqlbuilder.WithTwoPhase.
+func (d *database) BeginGlobalQueries(gid string) []string {
+	return []string{"COMMIT", "XA START " + quoteXID(gid)}
+}
+
+// EndGlobalQuery returns the statement that ends the work of an XA
+// transaction.
+func (d *database) EndGlobalQuery(gid string) string {
+	return "XA END " + quoteXID(gid)
+}
+
+// CommitGlobalQuery returns the statement that commits an XA transaction that
+// was not prepared, it's used by Commit.
+func (d *database) CommitGlobalQuery(gid string) string {
+	return "XA COMMIT " + quoteXID(gid) + " ONE PHASE"
+}
+
+// RollbackGlobalQuery returns the statement that rolls back an XA transaction
+// that was not prepared, it's used by Rollback.
+func (d *database) RollbackGlobalQuery(gid string) string {
+	return "XA ROLLBACK " + quoteXID(gid)
+}
+
+// PrepareTransactionQuery returns the statement that prepares an XA
+// transaction for a two-phase commit, it's used by PrepareTwoPhase.
+func (d *database) PrepareTransactionQuery(gid string) string {
+	return "XA PREPARE " + quoteXID(gid)
+}
+
+// CommitPreparedQuery returns the statement that commits a prepared XA
+// transaction, it's used by CommitPrepared.
+func (d *database) CommitPreparedQuery(gid string) string {
+	return "XA COMMIT " + quoteXID(gid)
+}
+
+// RollbackPreparedQuery returns the statement that rolls back a prepared XA
+// transaction, it's used by RollbackPrepared.
+func (d *database) RollbackPreparedQuery(gid string) string {
+	return "XA ROLLBACK " + quoteXID(gid)
+}
+
+// PreparedTransactionsQuery returns the statement that lists the prepared XA
+// transactions, the global id is in the last column, it's used by
+// PreparedTransactions.
+func (d *database) PreparedTransactionsQuery() string {
+	return "XA RECOVER"
+}
+
+// ParseURL parses the given DSN into a ConnectionURL, so that sqladapter can
+// replace its credentials.
+func (d *database) ParseURL(dsn string) (db.ConnectionURL, error) {
+	return ParseURL(dsn)
+}
+
 // LookupName looks for the name of the database and it's often used as a
 // test to determine if the connection settings are valid.
 func (d *database) LookupName() (string, error) {
@@ -303,8 +659,94 @@ func (d *database) PrimaryKeys(tableName string) ([]string, error) {
 	return pk, nil
 }
 
+// DescribeTable returns the columns, indexes and foreign keys of the given
+// table.
+func (d *database) DescribeTable(tableName string) (*sqlbuilder.Table, error) {
+	table := &sqlbuilder.Table{Name: tableName}
+
+	var columns []struct {
+		Name     string  `db:"column_name"`
+		Type     string  `db:"data_type"`
+		Nullable string  `db:"is_nullable"`
+		Default  *string `db:"column_default"`
+	}
+	err := d.Select(
+		db.Raw("column_name AS column_name"),
+		db.Raw("data_type AS data_type"),
+		db.Raw("is_nullable AS is_nullable"),
+		db.Raw("column_default AS column_default"),
+	).
+		From("information_schema.columns").
+		Where("table_schema = ? AND table_name = ?", d.BaseDatabase.Name(), tableName).
+		OrderBy("ordinal_position").
+		All(&columns)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range columns {
+		table.Columns = append(table.Columns, sqlbuilder.Column{
+			Name:     column.Name,
+			Type:     column.Type,
+			Nullable: column.Nullable == "YES",
+			Default:  column.Default,
+		})
+	}
+
+	if table.PrimaryKey, err = d.PrimaryKeys(tableName); err != nil {
+		return nil, err
+	}
+
+	var indexColumns []sqladapter.IndexColumn
+	err = d.Select(
+		db.Raw("index_name AS index_name"),
+		db.Raw("column_name AS column_name"),
+		db.Raw("non_unique = 0 AS is_unique"),
+	).
+		From("information_schema.statistics").
+		Where("table_schema = ? AND table_name = ? AND index_name <> ?", d.BaseDatabase.Name(), tableName, "PRIMARY").
+		OrderBy("index_name", "seq_in_index").
+		All(&indexColumns)
+	if err != nil {
+		return nil, err
+	}
+	table.Indexes = sqladapter.GroupIndexes(indexColumns)
+
+	var foreignKeyColumns []sqladapter.ForeignKeyColumn
+	err = d.Select(
+		db.Raw("constraint_name AS constraint_name"),
+		db.Raw("column_name AS column_name"),
+		db.Raw("referenced_table_name AS ref_table"),
+		db.Raw("referenced_column_name AS ref_column"),
+	).
+		From("information_schema.key_column_usage").
+		Where("table_schema = ? AND table_name = ? AND referenced_table_name IS NOT NULL", d.BaseDatabase.Name(), tableName).
+		OrderBy("constraint_name", "ordinal_position").
+		All(&foreignKeyColumns)
+	if err != nil {
+		return nil, err
+	}
+	table.ForeignKeys = sqladapter.GroupForeignKeys(foreignKeyColumns)
+
+	return table, nil
+}
+
 // WithContext creates a copy of the session on the given context.
 func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
 	newDB, _ := d.clone(ctx, false)
 	return newDB
 }
+
+// ReadOnly creates a copy of the session that can't change the database.
+func (d *database) ReadOnly() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UseReadOnly()
+	return newDB
+}
+
+// Primary returns a copy of the session that sends every statement to the
+// primary server.
+func (d *database) Primary() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UsePrimary()
+	return newDB
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package mysql
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/geo"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// Geometry represents a MySQL spatial value. Geometry is bound and scanned
+// in MySQL's internal format, that is the SRID as a little-endian 32-bit
+// integer followed by WKB. Geometry satisfies sqlbuilder.ScannerValuer.
+type Geometry struct {
+	geo.Geometry
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (g *Geometry) Scan(src interface{}) error {
+	if src == nil {
+		g.Geometry = geo.Geometry{}
+		return nil
+	}
+
+	b, ok := src.([]byte)
+	if !ok {
+		return errors.New("Scan source was not []bytes")
+	}
+	if len(b) < 4 {
+		return geo.ErrInvalidWKB
+	}
+
+	geom, err := geo.ParseWKB(b[4:])
+	if err != nil {
+		return err
+	}
+	geom.SRID = int(binary.LittleEndian.Uint32(b[:4]))
+
+	g.Geometry = geom
+	return nil
+}
+
+// Value satisfies the driver.Valuer interface.
+func (g Geometry) Value() (driver.Value, error) {
+	if g.IsZero() {
+		return nil, nil
+	}
+	b, err := g.WKB()
+	if err != nil {
+		return nil, err
+	}
+	v := make([]byte, 4, 4+len(b))
+	binary.LittleEndian.PutUint32(v, uint32(g.SRID))
+	return append(v, b...), nil
+}
+
+// DWithin is a comparison that matches rows whose geometry is within
+// distance of g, using ST_Distance. The distance is given in units of the
+// spatial reference system, or in meters for geographic ones.
+//
+// Example:
+//
+//	// ST_Distance(`location`, ?) <= ?
+//	db.Cond{"location": mysql.DWithin(point, 500)}
+func DWithin(g geo.Geometry, distance float64) db.Comparison {
+	return db.Op("ST_Distance(:column, ?) <= ?", []interface{}{Geometry{g}, distance})
+}
+
+// ContainsGeometry is a comparison that matches rows whose geometry contains
+// g, using ST_Contains.
+func ContainsGeometry(g geo.Geometry) db.Comparison {
+	return db.Op("ST_Contains(:column, ?)", Geometry{g})
+}
+
+// WithinGeometry is a comparison that matches rows whose geometry is within
+// g, using ST_Within.
+func WithinGeometry(g geo.Geometry) db.Comparison {
+	return db.Op("ST_Within(:column, ?)", Geometry{g})
+}
+
+// Intersects is a comparison that matches rows whose geometry intersects g,
+// using ST_Intersects.
+func Intersects(g geo.Geometry) db.Comparison {
+	return db.Op("ST_Intersects(:column, ?)", Geometry{g})
+}
+
+var _ sqlbuilder.ScannerValuer = &Geometry{}
@@ -45,6 +45,13 @@ const (
 	adapterTableAliasLayout    = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	adapterColumnAliasLayout   = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	adapterSortByColumnLayout  = `{{.Column}} {{.Order}}`
+	adapterWindowLayout        = `{{.Function}} OVER ({{.Definition}})`
+	adapterJSONPathLayout      = `JSON_UNQUOTE(JSON_EXTRACT({{.Column}}, '{{.Path}}'))`
+	adapterFullTextLayout      = `MATCH({{.Column}}) AGAINST(? IN NATURAL LANGUAGE MODE)`
+	adapterFullTextRankLayout  = `MATCH({{.Column}}) AGAINST(? IN NATURAL LANGUAGE MODE) DESC`
+	adapterLockLayout          = `{{if .Update}}FOR UPDATE{{else}}FOR SHARE{{end}}{{if .SkipLocked}} SKIP LOCKED{{end}}{{if .NoWait}} NOWAIT{{end}}`
+	adapterUpdateFromLayout    = `{{if .Tables}}, {{.Tables}}{{end}} {{.Joins}}`
+	adapterDeleteUsingLayout   = `{{if .Tables}}, {{.Tables}}{{end}} {{.Joins}}`
 
 	adapterOrderByLayout = `
     {{if .SortColumns}}
@@ -86,27 +93,43 @@ const (
     {{end}}
   `
 
+	adapterWithLayout = `
+    WITH {{if .Recursive}}RECURSIVE {{end}}{{.CTEs}}
+  `
+
+	adapterCompoundLayout = `
+    {{range .}}{{if .Operator}} {{.Operator}} {{end}}({{.Query}}){{end}}
+  `
+
 	adapterSelectLayout = `
-    SELECT
-      {{if .Distinct}}
-        DISTINCT
-      {{end}}
+    {{if .With}}
+      {{.With}}
+    {{end}}
 
-      {{if .Columns}}
-        {{.Columns}}
-      {{else}}
-        *
-      {{end}}
+    {{if .Compound}}
+      {{.Compound}}
+    {{else}}
+      SELECT
+        {{if .Distinct}}
+          DISTINCT
+        {{end}}
 
-      {{if .Table}}
-        FROM {{.Table}}
-      {{end}}
+        {{if .Columns}}
+          {{.Columns}}
+        {{else}}
+          *
+        {{end}}
 
-      {{.Joins}}
+        {{if .Table}}
+          FROM {{.Table}}
+        {{end}}
 
-      {{.Where}}
+        {{.Joins}}
+
+        {{.Where}}
 
-      {{.GroupBy}}
+        {{.GroupBy}}
+    {{end}}
 
       {{.OrderBy}}
 
@@ -131,15 +154,23 @@ const (
 				{{end}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{if .Lock}}
+        {{.Lock}}
+      {{end}}
   `
 	adapterDeleteLayout = `
     DELETE
+    {{if .From}}
+      {{.Table}} FROM {{.Table}}{{.From}}
+    {{else}}
       FROM {{.Table}}
+    {{end}}
       {{.Where}}
   `
 	adapterUpdateLayout = `
     UPDATE
-      {{.Table}}
+      {{.Table}}{{.From}}
     SET {{.ColumnValues}}
       {{ .Where }}
   `
@@ -154,17 +185,35 @@ const (
 	adapterInsertLayout = `
     INSERT INTO {{.Table}}
       {{if .Columns }}({{.Columns}}){{end}}
-    VALUES
-    {{if .Values}}
-      {{.Values}}
+    {{if .Query}}
+      {{.Query}}
     {{else}}
-      ()
+      VALUES
+      {{if .Values}}
+        {{.Values}}
+      {{else}}
+        ()
+      {{end}}
+    {{end}}
+    {{if .OnConflict}}
+      {{.OnConflict}}
     {{end}}
     {{if .Returning}}
       RETURNING {{.Returning}}
     {{end}}
   `
 
+	adapterOnConflictLayout = `
+    ON DUPLICATE KEY UPDATE
+    {{if .Update}}
+      {{.Update}}
+    {{else}}
+      {{range $i, $c := .Columns}}{{if not $i}}{{$c}} = {{$c}}{{end}}{{end}}
+    {{end}}
+  `
+
+	adapterExcludedColumn = `VALUES({{.}})`
+
 	adapterTruncateLayout = `
     TRUNCATE TABLE {{.Table}}
   `
@@ -177,6 +226,38 @@ const (
     DROP TABLE {{.Table}}
   `
 
+	adapterCreateTableLayout = `
+    CREATE TABLE {{.Table}} ({{.Definitions}})
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table}} {{.Definitions}}
+  `
+
+	adapterCreateIndexLayout = `
+    CREATE {{if .Unique}}UNIQUE {{end}}INDEX {{.Index}} ON {{.Table}} ({{.Columns}})
+  `
+
+	adapterCallLayout = `
+    CALL {{.Table}}({{.Columns}})
+  `
+
+	adapterCallOutLayout = `@__upper_out_%d`
+
+	adapterAddColumnLayout = `ADD COLUMN {{.}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.}}`
+
+	adapterColumnDefinitionLayout = `
+    {{.Name}} {{.Type}}
+    {{if .NotNull}}NOT NULL{{end}}
+    {{if .AutoIncrement}}AUTO_INCREMENT{{end}}
+    {{if .PrimaryKey}}PRIMARY KEY{{end}}
+    {{if .Unique}}UNIQUE{{end}}
+    {{if .Default}}DEFAULT {{.Default}}{{end}}
+    {{if .References}}REFERENCES {{.References}}{{end}}
+  `
+
 	adapterGroupByLayout = `
     {{if .GroupColumns}}
       GROUP BY {{.GroupColumns}}
@@ -184,36 +265,72 @@ const (
   `
 )
 
+var adapterColumnTypes = map[exql.ColumnType]string{
+	exql.TypeBoolean:    `BOOLEAN`,
+	exql.TypeInteger:    `INT`,
+	exql.TypeBigInteger: `BIGINT`,
+	exql.TypeFloat:      `DOUBLE`,
+	exql.TypeDecimal:    `DECIMAL({{.Precision}}, {{.Scale}})`,
+	exql.TypeString:     `VARCHAR({{.Size}})`,
+	exql.TypeText:       `TEXT`,
+	exql.TypeTimestamp:  `DATETIME`,
+	exql.TypeDate:       `DATE`,
+	exql.TypeBinary:     `BLOB`,
+	exql.TypeJSON:       `JSON`,
+}
+
 var template = &exql.Template{
-	ColumnSeparator:     adapterColumnSeparator,
-	IdentifierSeparator: adapterIdentifierSeparator,
-	IdentifierQuote:     adapterIdentifierQuote,
-	ValueSeparator:      adapterValueSeparator,
-	ValueQuote:          adapterValueQuote,
-	AndKeyword:          adapterAndKeyword,
-	OrKeyword:           adapterOrKeyword,
-	DescKeyword:         adapterDescKeyword,
-	AscKeyword:          adapterAscKeyword,
-	AssignmentOperator:  adapterAssignmentOperator,
-	ClauseGroup:         adapterClauseGroup,
-	ClauseOperator:      adapterClauseOperator,
-	ColumnValue:         adapterColumnValue,
-	TableAliasLayout:    adapterTableAliasLayout,
-	ColumnAliasLayout:   adapterColumnAliasLayout,
-	SortByColumnLayout:  adapterSortByColumnLayout,
-	WhereLayout:         adapterWhereLayout,
-	JoinLayout:          adapterJoinLayout,
-	OnLayout:            adapterOnLayout,
-	UsingLayout:         adapterUsingLayout,
-	OrderByLayout:       adapterOrderByLayout,
-	InsertLayout:        adapterInsertLayout,
-	SelectLayout:        adapterSelectLayout,
-	UpdateLayout:        adapterUpdateLayout,
-	DeleteLayout:        adapterDeleteLayout,
-	TruncateLayout:      adapterTruncateLayout,
-	DropDatabaseLayout:  adapterDropDatabaseLayout,
-	DropTableLayout:     adapterDropTableLayout,
-	CountLayout:         adapterSelectCountLayout,
-	GroupByLayout:       adapterGroupByLayout,
-	Cache:               cache.NewCache(),
+	ColumnSeparator:        adapterColumnSeparator,
+	IdentifierSeparator:    adapterIdentifierSeparator,
+	IdentifierQuote:        adapterIdentifierQuote,
+	ValueSeparator:         adapterValueSeparator,
+	ValueQuote:             adapterValueQuote,
+	AndKeyword:             adapterAndKeyword,
+	OrKeyword:              adapterOrKeyword,
+	DescKeyword:            adapterDescKeyword,
+	AscKeyword:             adapterAscKeyword,
+	AssignmentOperator:     adapterAssignmentOperator,
+	ClauseGroup:            adapterClauseGroup,
+	ClauseOperator:         adapterClauseOperator,
+	ColumnValue:            adapterColumnValue,
+	TableAliasLayout:       adapterTableAliasLayout,
+	ColumnAliasLayout:      adapterColumnAliasLayout,
+	SortByColumnLayout:     adapterSortByColumnLayout,
+	WhereLayout:            adapterWhereLayout,
+	WindowLayout:           adapterWindowLayout,
+	CompoundLayout:         adapterCompoundLayout,
+	WithLayout:             adapterWithLayout,
+	JoinLayout:             adapterJoinLayout,
+	JSONPathLayout:         adapterJSONPathLayout,
+	FullTextLayout:         adapterFullTextLayout,
+	FullTextRankLayout:     adapterFullTextRankLayout,
+	LockLayout:             adapterLockLayout,
+	OnLayout:               adapterOnLayout,
+	UsingLayout:            adapterUsingLayout,
+	OrderByLayout:          adapterOrderByLayout,
+	InsertLayout:           adapterInsertLayout,
+	OnConflictLayout:       adapterOnConflictLayout,
+	ExcludedColumn:         adapterExcludedColumn,
+	SelectLayout:           adapterSelectLayout,
+	UpdateLayout:           adapterUpdateLayout,
+	UpdateFromLayout:       adapterUpdateFromLayout,
+	UpdateFromFirst:        true,
+	DeleteUsingLayout:      adapterDeleteUsingLayout,
+	DeleteLayout:           adapterDeleteLayout,
+	TruncateLayout:         adapterTruncateLayout,
+	DropDatabaseLayout:     adapterDropDatabaseLayout,
+	DropTableLayout:        adapterDropTableLayout,
+	CreateTableLayout:      adapterCreateTableLayout,
+	AlterTableLayout:       adapterAlterTableLayout,
+	CreateIndexLayout:      adapterCreateIndexLayout,
+	CallLayout:             adapterCallLayout,
+	CallOutLayout:          adapterCallOutLayout,
+	CallOutVariables:       true,
+	AddColumnLayout:        adapterAddColumnLayout,
+	DropColumnLayout:       adapterDropColumnLayout,
+	ColumnDefinitionLayout: adapterColumnDefinitionLayout,
+	ColumnTypes:            adapterColumnTypes,
+	CountLayout:            adapterSelectCountLayout,
+	GroupByLayout:          adapterGroupByLayout,
+	Cache:                  cache.NewCache(),
 }
@@ -1,10 +1,13 @@
 package mysql
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/geo"
 	"upper.io/db.v3/lib/sqlbuilder"
 )
 
@@ -162,6 +165,126 @@ func TestTemplateSelect(t *testing.T) {
 			b.SelectFrom("artist").Where(db.Cond{"name LIKE": "%foo", "id": db.In([]byte{1, 2})}).String(),
 		)
 	}
+
+	assert.Equal(
+		"SELECT * FROM `jobs` LIMIT 1 FOR UPDATE SKIP LOCKED",
+		b.SelectFrom("jobs").Limit(1).ForUpdate().SkipLocked().String(),
+	)
+}
+
+func TestTemplateDDL(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		"CREATE TABLE `books` (`id` INT AUTO_INCREMENT PRIMARY KEY, `title` VARCHAR(80) NOT NULL, `price` DECIMAL(10, 2) DEFAULT 0, `author_id` INT, FOREIGN KEY (`author_id`) REFERENCES `authors` (`id`))",
+		b.CreateTable("books").Columns(
+			sqlbuilder.NewColumn("id", sqlbuilder.Integer).PrimaryKey().AutoIncrement(),
+			sqlbuilder.NewColumn("title", sqlbuilder.String(80)).NotNull(),
+			sqlbuilder.NewColumn("price", sqlbuilder.Decimal(10, 2)).Default(0),
+			sqlbuilder.NewColumn("author_id", sqlbuilder.Integer).References("authors", "id"),
+		).String(),
+	)
+
+	assert.Equal(
+		"ALTER TABLE `books` ADD COLUMN `meta` JSON",
+		b.AlterTable("books").AddColumn(sqlbuilder.NewColumn("meta", sqlbuilder.JSON)).String(),
+	)
+
+	assert.Equal(
+		"CREATE UNIQUE INDEX `books_title_idx` ON `books` (`title`)",
+		b.CreateIndex("books_title_idx").On("books", "title").Unique().String(),
+	)
+}
+
+func TestTemplateJSONPath(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		"SELECT * FROM `users` WHERE (JSON_UNQUOTE(JSON_EXTRACT(`data`, '$.address.city')) = $1)",
+		b.SelectFrom("users").Where(db.Cond{db.JSONPath("data.address.city"): "Paris"}).String(),
+	)
+}
+
+func TestTemplateFullText(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	search := db.FullText("body", "cats")
+	assert.Equal(
+		"SELECT * FROM `posts` WHERE (MATCH(`body`) AGAINST($1 IN NATURAL LANGUAGE MODE)) ORDER BY MATCH(`body`) AGAINST($2 IN NATURAL LANGUAGE MODE) DESC",
+		b.SelectFrom("posts").Where(search).OrderBy(search.Rank()).String(),
+	)
+}
+
+func TestTemplateDistinctOn(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	_, _, err := b.SelectFrom("visits").DistinctOn("user_id").Compile()
+	assert.Equal(db.ErrUnsupported, err)
+}
+
+func TestTemplateSpatial(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	point := geo.Geometry{SRID: 4326, Shape: geo.Point{X: 2.35, Y: 48.85}}
+
+	sel := b.SelectFrom("shops").Where(db.Cond{
+		"area":     ContainsGeometry(point),
+		"location": DWithin(point, 500),
+	})
+	assert.Equal(
+		"SELECT * FROM `shops` WHERE (ST_Contains(`area`, $1) AND ST_Distance(`location`, $2) <= $3)",
+		sel.String(),
+	)
+	assert.Equal([]interface{}{Geometry{point}, Geometry{point}, float64(500)}, sel.Arguments())
+}
+
+func TestTemplateUpdateDeleteJoins(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	{
+		upd := b.Update("posts").
+			Set("title", "archived").
+			Join("users").On("users.id = posts.user_id AND users.active = ?", false).
+			Where("posts.id > ?", 10)
+		assert.Equal(
+			"UPDATE `posts` JOIN `users` ON (users.id = posts.user_id AND users.active = $1) SET `title` = $2 WHERE (posts.id > $3)",
+			upd.String(),
+		)
+		assert.Equal([]interface{}{false, "archived", 10}, upd.Arguments())
+	}
+
+	assert.Equal(
+		"UPDATE `posts`, `users` SET `author_name` = users.name WHERE (posts.user_id = users.id)",
+		b.Update("posts").Set("author_name = users.name").From("users").Where("posts.user_id = users.id").String(),
+	)
+
+	assert.Equal(
+		"DELETE `posts` FROM `posts` JOIN `users` ON (users.id = posts.user_id) WHERE (`users`.`banned` = $1)",
+		b.DeleteFrom("posts").Join("users").On("users.id = posts.user_id").Where("users.banned", true).String(),
+	)
+}
+
+func TestTemplateCall(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		"CALL `order_total`($1, $2)",
+		b.Call("order_total", 12, "EUR").String(),
+	)
+
+	// OUT parameters are passed as user variables.
+	var total, count int
+	assert.Equal(
+		"CALL `order_total`($1, @__upper_out_0, @__upper_out_1)",
+		b.Call("order_total", 12, sqlbuilder.Out(&total), sqlbuilder.InOut(&count)).String(),
+	)
 }
 
 func TestTemplateInsert(t *testing.T) {
@@ -206,6 +329,28 @@ func TestTemplateInsert(t *testing.T) {
 	)
 }
 
+func TestTemplateUpsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	artist := map[string]interface{}{"id": 12, "name": "Chavela Vargas"}
+
+	assert.Equal(
+		"INSERT INTO `artist` (`id`, `name`) VALUES ($1, $2) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)",
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoUpdate().String(),
+	)
+
+	assert.Equal(
+		"INSERT INTO `artist` (`id`, `name`) VALUES ($1, $2) ON DUPLICATE KEY UPDATE `name` = $3",
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoUpdate("name", "Chavela").String(),
+	)
+
+	assert.Equal(
+		"INSERT INTO `artist` (`id`, `name`) VALUES ($1, $2) ON DUPLICATE KEY UPDATE `id` = `id`",
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoNothing().String(),
+	)
+}
+
 func TestTemplateUpdate(t *testing.T) {
 	b := sqlbuilder.WithTemplate(template)
 	assert := assert.New(t)
@@ -262,3 +407,62 @@ func TestTemplateDelete(t *testing.T) {
 		b.DeleteFrom("artist").Where("id > 5").String(),
 	)
 }
+
+func TestTemplateTruncate(t *testing.T) {
+	d := &database{}
+
+	stmts, err := d.TruncateStatements("artist", &exql.Statement{
+		Type:            exql.Truncate,
+		Table:           exql.TableWithName("artist"),
+		Cascade:         true,
+		RestartIdentity: true,
+	})
+	assert.NoError(t, err)
+
+	var compiled []string
+	for _, stmt := range stmts {
+		s, err := stmt.Compile(template)
+		assert.NoError(t, err)
+		compiled = append(compiled, strings.Join(strings.Fields(s), " "))
+	}
+	assert.Equal(t, []string{
+		"DELETE FROM `artist`",
+		"ALTER TABLE `artist` AUTO_INCREMENT = 1",
+	}, compiled)
+}
+
+func TestTemplatePlanRows(t *testing.T) {
+	d := &database{}
+
+	rows, err := d.PlanRows(`{"query_block": {"select_id": 1, "table": {"table_name": "artist", "access_type": "ALL", "rows_examined_per_scan": 4000, "rows_produced_per_join": 400, "filtered": "10.00"}}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(400), rows)
+
+	rows, err = d.PlanRows(`{"query_block": {"select_id": 1, "message": "Impossible WHERE"}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), rows)
+
+	// Tables are nested within the operations of the plan.
+	rows, err = d.PlanRows(`{"query_block": {"select_id": 1, "ordering_operation": {"using_filesort": true, "table": {"table_name": "artist", "rows_produced_per_join": 40}}}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(40), rows)
+
+	rows, err = d.PlanRows(`{"query_block": {"select_id": 1, "grouping_operation": {"using_temporary_table": true, "nested_loop": [{"table": {"table_name": "artist", "rows_produced_per_join": 4000}}, {"table": {"table_name": "album", "rows_produced_per_join": 12000}}]}}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12000), rows)
+
+	rows, err = d.PlanRows(`{"query_block": {"union_result": {"using_temporary_table": true, "query_specifications": [{"query_block": {"select_id": 1, "table": {"rows_produced_per_join": 3}}}, {"query_block": {"select_id": 2, "table": {"rows_produced_per_join": 5}}}]}}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(8), rows)
+}
+
+func TestTemplateXA(t *testing.T) {
+	d := &database{}
+
+	assert.Equal(t, []string{"COMMIT", "XA START 'order-42'"}, d.BeginGlobalQueries("order-42"))
+	assert.Equal(t, "XA END 'order-42'", d.EndGlobalQuery("order-42"))
+	assert.Equal(t, "XA PREPARE 'order-42'", d.PrepareTransactionQuery("order-42"))
+	assert.Equal(t, "XA COMMIT 'order-42'", d.CommitPreparedQuery("order-42"))
+	assert.Equal(t, "XA COMMIT 'order-42' ONE PHASE", d.CommitGlobalQuery("order-42"))
+	assert.Equal(t, "XA ROLLBACK 'o''rder\\\\42'", d.RollbackPreparedQuery(`o'rder\42`))
+}
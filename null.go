@@ -0,0 +1,179 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+//go:build go1.18
+// +build go1.18
+
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Null is a value of type T that may be NULL, it's bound and scanned like
+// the sql.NullString family of types but works with any type:
+//
+//	type Artist struct {
+//		ID     int64            `db:"id,omitempty"`
+//		Name   string           `db:"name"`
+//		Rating db.Null[float64] `db:"rating"`
+//	}
+//
+// V is the zero value of T while Valid is false.
+type Null[T any] struct {
+	V     T
+	Valid bool
+}
+
+// NewNull returns a valid Null with the given value.
+func NewNull[T any](v T) Null[T] {
+	return Null[T]{V: v, Valid: true}
+}
+
+// NullFromPtr returns a Null with the value p points to, it's not valid if p
+// is nil.
+func NullFromPtr[T any](p *T) Null[T] {
+	if p == nil {
+		return Null[T]{}
+	}
+	return NewNull(*p)
+}
+
+// Ptr returns a pointer to a copy of the value of n, nil if it's not valid.
+func (n Null[T]) Ptr() *T {
+	if !n.Valid {
+		return nil
+	}
+	v := n.V
+	return &v
+}
+
+// Value implements driver.Valuer.
+func (n Null[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(n.V)
+}
+
+// Scan implements sql.Scanner.
+func (n *Null[T]) Scan(src interface{}) error {
+	if src == nil {
+		var zero T
+		n.V, n.Valid = zero, false
+		return nil
+	}
+	if err := assignNull(&n.V, src); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+var (
+	_ driver.Valuer = Null[int64]{}
+	_ sql.Scanner   = &Null[int64]{}
+)
+
+// assignNull sets dst to the given column value, converting between the
+// types drivers return and the basic types.
+func assignNull(dst interface{}, src interface{}) error {
+	if scanner, ok := dst.(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src)
+
+	if b, ok := src.([]byte); ok {
+		if dv.Kind() == reflect.Slice && dv.Type().Elem().Kind() == reflect.Uint8 {
+			// The driver owns b.
+			dv.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+		src, sv = string(b), reflect.ValueOf(string(b))
+	}
+
+	if s, ok := src.(string); ok {
+		switch dv.Kind() {
+		case reflect.String:
+			dv.SetString(s)
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, err := strconv.ParseInt(s, 10, dv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("db: converting %q to %v: %v", s, dv.Type(), err)
+			}
+			dv.SetInt(i)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u, err := strconv.ParseUint(s, 10, dv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("db: converting %q to %v: %v", s, dv.Type(), err)
+			}
+			dv.SetUint(u)
+			return nil
+		case reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(s, dv.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("db: converting %q to %v: %v", s, dv.Type(), err)
+			}
+			dv.SetFloat(f)
+			return nil
+		case reflect.Bool:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("db: converting %q to %v: %v", s, dv.Type(), err)
+			}
+			dv.SetBool(b)
+			return nil
+		}
+	}
+
+	if _, ok := src.(time.Time); ok && dv.Type() != sv.Type() {
+		return fmt.Errorf("db: can't convert %T to %v", src, dv.Type())
+	}
+	if sv.Type().ConvertibleTo(dv.Type()) && convertibleKinds(sv.Kind(), dv.Kind()) {
+		dv.Set(sv.Convert(dv.Type()))
+		return nil
+	}
+	return fmt.Errorf("db: can't convert %T to %v", src, dv.Type())
+}
+
+// convertibleKinds returns true if values of kind a can be converted into
+// values of kind b without changing their meaning, numbers are not turned
+// into strings.
+func convertibleKinds(a, b reflect.Kind) bool {
+	isNumber := func(k reflect.Kind) bool {
+		return k >= reflect.Int && k <= reflect.Float64
+	}
+	switch {
+	case isNumber(a):
+		return isNumber(b)
+	case a == reflect.Bool:
+		return b == reflect.Bool
+	}
+	return a == b
+}
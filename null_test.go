@@ -0,0 +1,92 @@
+//go:build go1.18
+// +build go1.18
+
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNull(t *testing.T) {
+	{
+		n := NewNull("Ozzie")
+		v, err := n.Value()
+		if err != nil || v != "Ozzie" {
+			t.Fatalf("Expecting %q, got %v (%v)", "Ozzie", v, err)
+		}
+		if p := n.Ptr(); p == nil || *p != "Ozzie" {
+			t.Fatalf("Expecting a pointer to %q", "Ozzie")
+		}
+	}
+
+	{
+		var n Null[int]
+		v, err := n.Value()
+		if err != nil || v != nil {
+			t.Fatalf("Expecting NULL, got %v (%v)", v, err)
+		}
+		if n.Ptr() != nil {
+			t.Fatal("Expecting a nil pointer")
+		}
+
+		v, err = NewNull(3).Value()
+		if err != nil || v != int64(3) {
+			t.Fatalf("Expecting %v, got %v (%v)", int64(3), v, err)
+		}
+	}
+
+	{
+		i := 5
+		if n := NullFromPtr(&i); !n.Valid || n.V != 5 {
+			t.Fatalf("Expecting a valid 5, got %v", n)
+		}
+		if n := NullFromPtr((*int)(nil)); n.Valid {
+			t.Fatal("Expecting an invalid value")
+		}
+	}
+
+	now := time.Now()
+	scans := []struct {
+		dst  interface{ Scan(interface{}) error }
+		src  interface{}
+		want interface{}
+	}{
+		{&Null[string]{}, []byte("abc"), NewNull("abc")},
+		{&Null[string]{}, "abc", NewNull("abc")},
+		{&Null[int]{}, int64(7), NewNull(7)},
+		{&Null[int32]{}, []byte("-7"), NewNull(int32(-7))},
+		{&Null[uint8]{}, "7", NewNull(uint8(7))},
+		{&Null[float64]{}, int64(2), NewNull(2.0)},
+		{&Null[float64]{}, []byte("2.5"), NewNull(2.5)},
+		{&Null[bool]{}, true, NewNull(true)},
+		{&Null[bool]{}, []byte("true"), NewNull(true)},
+		{&Null[[]byte]{}, []byte("abc"), NewNull([]byte("abc"))},
+		{&Null[time.Time]{}, now, NewNull(now)},
+		{&Null[string]{V: "x", Valid: true}, nil, Null[string]{}},
+	}
+	for i, s := range scans {
+		if err := s.dst.Scan(s.src); err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if got := reflect.ValueOf(s.dst).Elem().Interface(); !reflect.DeepEqual(got, s.want) {
+			t.Fatalf("%d: expecting %v, got %v", i, s.want, got)
+		}
+	}
+
+	for i, s := range []struct {
+		dst interface{ Scan(interface{}) error }
+		src interface{}
+	}{
+		{&Null[int]{}, []byte("x")},
+		{&Null[string]{}, int64(1)},
+		{&Null[int]{}, now},
+		{&Null[time.Time]{}, "2020-01-01"},
+	} {
+		if err := s.dst.Scan(s.src); err == nil {
+			t.Fatalf("%d: expecting an error", i)
+		}
+	}
+
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package oracle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// sequenceSuffix is appended to the name of a table to get the name of the
+// sequence that generates its IDs, when the primary key is not an identity
+// column.
+const sequenceSuffix = `_seq`
+
+var errInvalidSequenceName = errors.New("invalid sequence name")
+
+var reSequenceName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_$#]*$`)
+
+// collection is the actual implementation of a collection.
+type collection struct {
+	sqladapter.BaseCollection // Leveraged by sqladapter
+
+	d    *database
+	name string
+
+	mu        sync.Mutex
+	sequences map[string]string
+}
+
+var (
+	_ = sqladapter.Collection(&collection{})
+	_ = db.Collection(&collection{})
+)
+
+// newCollection binds *collection with sqladapter.
+func newCollection(d *database, name string) *collection {
+	c := &collection{
+		name:      name,
+		d:         d,
+		sequences: make(map[string]string),
+	}
+	c.BaseCollection = sqladapter.NewBaseCollection(c)
+	return c
+}
+
+func (c *collection) Name() string {
+	return c.name
+}
+
+func (c *collection) Database() sqladapter.Database {
+	return c.d
+}
+
+// InsertContext inserts an item (map or struct) into the collection.
+func (c *collection) InsertContext(ctx context.Context, item interface{}) (interface{}, error) {
+	return sqladapter.InsertWithHooks(ctx, c.d, c.Name(), item, func() (interface{}, error) {
+		return c.insert(ctx, item)
+	})
+}
+
+func (c *collection) insert(ctx context.Context, item interface{}) (interface{}, error) {
+	columnNames, columnValues, err := sqlbuilder.Map(item, c.d.MapOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	if columnNames, columnValues, err = c.AssignID(ctx, columnNames, columnValues); err != nil {
+		return nil, err
+	}
+
+	pKey := c.BaseCollection.PrimaryKeys()
+
+	if len(pKey) == 1 && !hasColumn(columnNames, pKey[0]) {
+		// Oracle can't tell which ID was generated, so the ID is taken from
+		// the sequence before inserting the item.
+		id, err := c.nextValue(ctx, pKey[0])
+		if err != nil {
+			return nil, err
+		}
+		columnNames = append(columnNames, pKey[0])
+		columnValues = append(columnValues, id)
+	}
+
+	q := c.d.InsertInto(c.Name()).
+		Columns(columnNames...).
+		Values(columnValues...)
+
+	if _, err = q.ExecContext(ctx); err != nil {
+		return nil, err
+	}
+
+	keyMap := db.Cond{}
+	for i := range columnNames {
+		for j := 0; j < len(pKey); j++ {
+			if pKey[j] == columnNames[i] {
+				keyMap[pKey[j]] = columnValues[i]
+			}
+		}
+	}
+
+	if len(keyMap) == 0 {
+		return nil, nil
+	}
+
+	if len(pKey) == 1 {
+		return keyMap[pKey[0]], nil
+	}
+
+	return keyMap, nil
+}
+
+// nextValue returns the next value of the sequence that generates the values
+// of the given column.
+func (c *collection) nextValue(ctx context.Context, column string) (int64, error) {
+	sequence, err := c.sequenceName(ctx, column)
+	if err != nil {
+		return 0, err
+	}
+
+	row, err := c.d.QueryRowContext(ctx, c.d.NextSequenceValueQuery(sequence))
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// sequenceName returns the name of the sequence of the identity column with
+// the given name or, if the column is not an identity column, the name of the
+// table followed by sequenceSuffix.
+func (c *collection) sequenceName(ctx context.Context, column string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name, ok := c.sequences[column]; ok {
+		return name, nil
+	}
+
+	row, err := c.d.QueryRowContext(ctx, `SELECT SEQUENCE_NAME FROM USER_TAB_IDENTITY_COLS WHERE TABLE_NAME = ? AND COLUMN_NAME = ?`, c.Name(), column)
+	if err != nil {
+		return "", err
+	}
+
+	var name string
+	if err := row.Scan(&name); err != nil {
+		if err != sql.ErrNoRows {
+			return "", err
+		}
+		name = c.Name() + sequenceSuffix
+	}
+
+	// The name is not quoted so that Oracle looks for it in uppercase, like
+	// the names of sequences that were created without quotes.
+	if !reSequenceName.MatchString(name) {
+		return "", fmt.Errorf("%w: %q", errInvalidSequenceName, name)
+	}
+
+	c.sequences[column] = name
+
+	return name, nil
+}
+
+func hasColumn(columns []string, name string) bool {
+	for i := range columns {
+		if columns[i] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteIdentifier quotes each part of the given name, parts that Oracle would
+// read without quotes are turned into uppercase first, like Oracle does.
+func quoteIdentifier(s string) string {
+	chunks := strings.Split(s, ".")
+	for i := range chunks {
+		if reSequenceName.MatchString(chunks[i]) {
+			chunks[i] = strings.ToUpper(chunks[i])
+		}
+		chunks[i] = `"` + strings.Replace(chunks[i], `"`, `""`, -1) + `"`
+	}
+	return strings.Join(chunks, ".")
+}
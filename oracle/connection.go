@@ -0,0 +1,131 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package oracle
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
+)
+
+const connectionScheme = `oracle`
+
+const defaultPort = "1521"
+
+// ConnectionURL implements an Oracle connection struct.
+type ConnectionURL struct {
+	User     string
+	Password string
+	Host     string
+	// Database is the service name.
+	Database string
+	Options  map[string]string
+
+	// TLS enables the "SSL" option, go-ora only accepts InsecureSkipVerify,
+	// certificates are taken from the Oracle wallet of the "WALLET" option.
+	TLS *db.TLSConfig
+}
+
+func (c ConnectionURL) String() (s string) {
+	if c.Database == "" {
+		return ""
+	}
+
+	hosts := sqladapter.SplitHosts(c.Host)
+	if len(hosts) == 0 {
+		hosts = []string{""}
+	}
+	for i := range hosts {
+		host, port, err := net.SplitHostPort(hosts[i])
+		if err != nil {
+			host = hosts[i]
+			port = defaultPort
+		}
+		if host == "" {
+			host = "127.0.0.1"
+		}
+		hosts[i] = net.JoinHostPort(host, port)
+	}
+
+	u := url.URL{
+		Scheme: connectionScheme,
+		Host:   strings.Join(hosts, ","),
+		Path:   "/" + c.Database,
+	}
+
+	if c.User != "" {
+		u.User = url.UserPassword(c.User, c.Password)
+	}
+
+	vv := url.Values{}
+	for k, v := range c.Options {
+		if sqladapter.IsAdapterOption(k) {
+			// Handled by the adapter.
+			continue
+		}
+		vv.Set(k, v)
+	}
+
+	if c.TLS != nil {
+		vv.Set("SSL", "enable")
+		if c.TLS.InsecureSkipVerify {
+			vv.Set("SSL VERIFY", "false")
+		}
+	}
+	u.RawQuery = vv.Encode()
+
+	return u.String()
+}
+
+// ParseURL parses s into a ConnectionURL struct.
+func ParseURL(s string) (conn ConnectionURL, err error) {
+	var u *url.URL
+
+	if !strings.HasPrefix(s, connectionScheme+"://") {
+		return conn, fmt.Errorf(`Expecting %s:// connection scheme.`, connectionScheme)
+	}
+
+	if u, err = url.Parse(s); err != nil {
+		return conn, err
+	}
+
+	conn.Host = u.Host
+	conn.Database = strings.TrimPrefix(u.Path, "/")
+
+	if u.User != nil {
+		conn.User = u.User.Username()
+		conn.Password, _ = u.User.Password()
+	}
+
+	conn.Options = map[string]string{}
+
+	vv := u.Query()
+	for k := range vv {
+		conn.Options[k] = vv.Get(k)
+	}
+
+	return conn, nil
+}
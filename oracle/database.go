@@ -0,0 +1,404 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package oracle
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
+	"upper.io/db.v3/internal/sqladapter/compat"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// database is the actual implementation of Database
+type database struct {
+	sqladapter.BaseDatabase
+
+	sqlbuilder.SQLBuilder
+
+	connURL db.ConnectionURL
+	mu      sync.Mutex
+}
+
+var (
+	_ = sqlbuilder.Database(&database{})
+	_ = sqladapter.Database(&database{})
+)
+
+// newDatabase creates a new *database session for internal use.
+func newDatabase(settings db.ConnectionURL) *database {
+	return &database{
+		connURL: settings,
+	}
+}
+
+// Adapter returns the name of the adapter.
+func (d *database) Adapter() string {
+	return Adapter
+}
+
+// ConnectionURL returns this database session's connection URL, if any.
+func (d *database) ConnectionURL() db.ConnectionURL {
+	return d.connURL
+}
+
+// Open attempts to open a connection with the database server.
+func (d *database) Open(connURL db.ConnectionURL) error {
+	if connURL == nil {
+		return db.ErrMissingConnURL
+	}
+	d.connURL = connURL
+	return d.open()
+}
+
+// NewTx begins a transaction block with the given context.
+func (d *database) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	nTx, err := d.NewDatabaseTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{DatabaseTx: nTx}, nil
+}
+
+// Collections returns a list of non-system tables from the database.
+func (d *database) Collections() (collections []string, err error) {
+	// Oracle stores unquoted names in uppercase.
+	q := d.Select("TABLE_NAME").
+		From("USER_TABLES")
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	for iter.Next() {
+		var tableName string
+		if err := iter.Scan(&tableName); err != nil {
+			return nil, err
+		}
+		collections = append(collections, tableName)
+	}
+
+	return collections, nil
+}
+
+// open attempts to establish a connection with the Oracle server.
+func (d *database) open() error {
+	// Binding with sqladapter's logic.
+	d.BaseDatabase = sqladapter.NewBaseDatabase(d)
+
+	if err := sqladapter.SetPoolOptions(d.BaseDatabase, sqladapter.ConnectionOptions(d.connURL)); err != nil {
+		return err
+	}
+
+	if err := setupTLS(d.connURL); err != nil {
+		return err
+	}
+
+	// Binding with sqlbuilder.
+	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
+
+	connFn := func() error {
+		sess, err := d.BaseDatabase.OpenFailover(sqlDriver, sqladapter.HostDSNs(d.connURL), sqladapter.ConnectionOptions(d.connURL))
+		if err == nil {
+			sess.SetConnMaxLifetime(d.BaseDatabase.ConnMaxLifetime())
+			compat.SetConnMaxIdleTime(sess, d.BaseDatabase.ConnMaxIdleTime())
+			sess.SetMaxIdleConns(d.BaseDatabase.MaxIdleConns())
+			sess.SetMaxOpenConns(d.BaseDatabase.MaxOpenConns())
+			return d.BaseDatabase.BindSession(sess)
+		}
+		return err
+	}
+
+	if err := d.BaseDatabase.WaitForConnection(connFn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// connectionTLS returns the TLS configuration of the given connection URL.
+func connectionTLS(connURL db.ConnectionURL) *db.TLSConfig {
+	switch u := connURL.(type) {
+	case ConnectionURL:
+		return u.TLS
+	case *ConnectionURL:
+		if u != nil {
+			return u.TLS
+		}
+	}
+	return nil
+}
+
+// setupTLS checks that the TLS configuration of the given connection URL can
+// be used, go-ora takes certificates from an Oracle wallet, set with the
+// "WALLET" option, rather than from files or a *tls.Config.
+func setupTLS(connURL db.ConnectionURL) error {
+	cfg := connectionTLS(connURL)
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Config != nil || cfg.CAFile != "" || cfg.CertFile != "" || cfg.KeyFile != "" {
+		return db.ErrUnsupported
+	}
+	return nil
+}
+
+// Clone creates a copy of the database session on the given context.
+func (d *database) clone(ctx context.Context, checkConn bool) (*database, error) {
+	clone := newDatabase(d.connURL)
+
+	var err error
+	clone.BaseDatabase, err = d.NewClone(clone, checkConn)
+	if err != nil {
+		return nil, err
+	}
+
+	clone.SetContext(ctx)
+
+	clone.SQLBuilder = sqlbuilder.WithSession(clone.BaseDatabase, template)
+
+	return clone, nil
+}
+
+// CompileStatement allows sqladapter to compile the given statement into the
+// format Oracle expects.
+func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (string, []interface{}) {
+	compiled, err := stmt.Compile(template)
+	if err != nil {
+		panic(err.Error())
+	}
+	query, args := sqlbuilder.Preprocess(compiled, args)
+	return sqladapter.ReplaceWithBindVariables(query), args
+}
+
+// ConvertValues binds the OUT parameters of stored procedures as sql.Out
+// values.
+func (d *database) ConvertValues(values []interface{}) []interface{} {
+	return sqlbuilder.OutValues(values)
+}
+
+// ExistsQuery returns the query that tells whether the given query has rows,
+// Oracle can only use EXISTS within conditions.
+func (d *database) ExistsQuery(sel sqlbuilder.Selector) sqlbuilder.Selector {
+	return d.Select(db.Raw(`CASE WHEN EXISTS ? THEN 1 ELSE 0 END AS "_t"`, sel)).From("DUAL")
+}
+
+// TruncateStatements returns the given Truncate statement, Oracle can't
+// restart the sequences of the table.
+func (d *database) TruncateStatements(table string, stmt *exql.Statement) ([]*exql.Statement, error) {
+	if stmt.RestartIdentity {
+		return nil, db.ErrUnsupported
+	}
+	return []*exql.Statement{stmt}, nil
+}
+
+// NextSequenceValueQuery returns the statement that advances the given
+// sequence, it's used by NextSequenceValue.
+func (d *database) NextSequenceValueQuery(name string) string {
+	return `SELECT ` + quoteIdentifier(name) + `.NEXTVAL FROM DUAL`
+}
+
+// Err allows sqladapter to translate specific Oracle errors into custom error
+// values.
+func (d *database) Err(err error) error {
+	if err != nil {
+		s := err.Error()
+		// ORA-00018 (maximum number of sessions exceeded), ORA-00020 (maximum
+		// number of processes exceeded) and ORA-12516 and ORA-12519 (no
+		// appropriate service handler found).
+		if strings.Contains(s, `ORA-00018`) || strings.Contains(s, `ORA-00020`) || strings.Contains(s, `ORA-12516`) || strings.Contains(s, `ORA-12519`) {
+			return db.ErrTooManyClients
+		}
+		// ORA-08177 (can't serialize access for this transaction) and ORA-00060
+		// (deadlock detected).
+		if strings.Contains(s, `ORA-08177`) || strings.Contains(s, `ORA-00060`) {
+			return db.ErrSerializationFailure
+		}
+		if cErr := constraintError(s, err); cErr != nil {
+			return cErr
+		}
+	}
+	return err
+}
+
+var reConstraintName = regexp.MustCompile(`constraint \(([^)]+)\)`)
+
+// constraintError maps ORA-00001 (unique constraint violated), ORA-02291 and
+// ORA-02292 (integrity constraint violated) and ORA-02290 (check constraint
+// violated) into db.ErrUniqueViolation, db.ErrForeignKeyViolation and
+// db.ErrCheckViolation values.
+func constraintError(s string, err error) error {
+	// ORA-00001: unique constraint (SCOTT.USERS_EMAIL_UK) violated
+	constraint := ""
+	if m := reConstraintName.FindStringSubmatch(s); m != nil {
+		constraint = m[1][strings.LastIndex(m[1], ".")+1:]
+	}
+
+	switch {
+	case strings.Contains(s, `ORA-00001`):
+		return &db.ErrUniqueViolation{Constraint: constraint, Err: err}
+	case strings.Contains(s, `ORA-02291`) || strings.Contains(s, `ORA-02292`):
+		return &db.ErrForeignKeyViolation{Constraint: constraint, Err: err}
+	case strings.Contains(s, `ORA-02290`):
+		return &db.ErrCheckViolation{Constraint: constraint, Err: err}
+	}
+	return nil
+}
+
+// NewCollection creates a db.Collection by name.
+func (d *database) NewCollection(name string) db.Collection {
+	return newCollection(d, name)
+}
+
+// Tx creates a transaction block on the given context and passes it to the
+// function fn. If fn returns no error the transaction is commited, else the
+// transaction is rolled back. After being commited or rolled back the
+// transaction is closed automatically.
+func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) error {
+	return sqladapter.RunTx(d, ctx, fn)
+}
+
+// TxWithRetry is like Tx but fn is run again, within a new transaction, if the
+// transaction fails because of a serialization failure or a deadlock.
+func (d *database) TxWithRetry(ctx context.Context, fn func(tx sqlbuilder.Tx) error, policy sqlbuilder.RetryPolicy) error {
+	return sqladapter.TxWithRetry(d, ctx, fn, policy)
+}
+
+// NewDatabaseTx begins a transaction block. Nested transactions are not
+// supported, Oracle has savepoints but it can't release them.
+func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
+	if d.BaseDatabase.Transaction() != nil {
+		return nil, db.ErrUnsupported
+	}
+
+	clone, err := d.clone(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	clone.mu.Lock()
+	defer clone.mu.Unlock()
+
+	connFn := func() error {
+		sqlTx, err := compat.BeginTx(clone.BaseDatabase.Session(), ctx, clone.TxOptions())
+		if err == nil {
+			return clone.BindTx(ctx, sqlTx)
+		}
+		return err
+	}
+
+	if err := clone.BaseDatabase.WaitForConnection(connFn); err != nil {
+		return nil, err
+	}
+
+	return sqladapter.NewDatabaseTx(clone), nil
+}
+
+// ParseURL parses the given DSN into a ConnectionURL, so that sqladapter can
+// replace its credentials.
+func (d *database) ParseURL(dsn string) (db.ConnectionURL, error) {
+	return ParseURL(dsn)
+}
+
+// LookupName looks for the name of the database and it's often used as a
+// test to determine if the connection settings are valid.
+func (d *database) LookupName() (string, error) {
+	q := d.Select(db.Raw("SYS_CONTEXT('USERENV', 'DB_NAME')")).From("DUAL")
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	if iter.Next() {
+		var name string
+		err := iter.Scan(&name)
+		return name, err
+	}
+
+	return "", iter.Err()
+}
+
+// TableExists returns an error if the given table name does not exist on the
+// database.
+func (d *database) TableExists(name string) error {
+	q := d.Select("TABLE_NAME").
+		From("USER_TABLES").
+		Where("TABLE_NAME = ?", name)
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	if iter.Next() {
+		var name string
+		if err := iter.Scan(&name); err != nil {
+			return err
+		}
+		return nil
+	}
+	return db.ErrCollectionDoesNotExist
+}
+
+// PrimaryKeys returns the names of all the primary keys on the table.
+func (d *database) PrimaryKeys(tableName string) ([]string, error) {
+	q := d.Select("K.COLUMN_NAME").
+		From("USER_CONSTRAINTS C").
+		Join("USER_CONS_COLUMNS K").
+		On("K.CONSTRAINT_NAME = C.CONSTRAINT_NAME").
+		Where("C.CONSTRAINT_TYPE = 'P' AND C.TABLE_NAME = ?", tableName).
+		OrderBy("K.POSITION")
+
+	iter := q.Iterator()
+	defer iter.Close()
+
+	pk := []string{}
+
+	for iter.Next() {
+		var k string
+		if err := iter.Scan(&k); err != nil {
+			return nil, err
+		}
+		pk = append(pk, k)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return pk, nil
+}
+
+// WithContext creates a copy of the session on the given context.
+func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
+	newDB, _ := d.clone(ctx, false)
+	return newDB
+}
+
+// ReadOnly creates a copy of the session that can't change the database.
+func (d *database) ReadOnly() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UseReadOnly()
+	return newDB
+}
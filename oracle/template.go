@@ -0,0 +1,287 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package oracle
+
+import (
+	"upper.io/db.v3/internal/cache"
+	"upper.io/db.v3/internal/sqladapter/exql"
+)
+
+const (
+	adapterColumnSeparator     = `.`
+	adapterIdentifierSeparator = `, `
+	adapterIdentifierQuote     = `"{{.Value}}"`
+	adapterValueSeparator      = `, `
+	adapterValueQuote          = `'{{.}}'`
+	adapterAndKeyword          = `AND`
+	adapterOrKeyword           = `OR`
+	adapterDescKeyword         = `DESC`
+	adapterAscKeyword          = `ASC`
+	adapterAssignmentOperator  = `=`
+	adapterClauseGroup         = `({{.}})`
+	adapterClauseOperator      = ` {{.}} `
+	adapterColumnValue         = `{{.Column}} {{.Operator}} {{.Value}}`
+	adapterTableAliasLayout    = `{{.Name}}{{if .Alias}} {{.Alias}}{{end}}`
+	adapterColumnAliasLayout   = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
+	adapterSortByColumnLayout  = `{{.Column}} {{.Order}}`
+	adapterWindowLayout        = `{{.Function}} OVER ({{.Definition}})`
+	adapterJSONPathLayout      = `JSON_VALUE({{.Column}}, '{{.Path}}')`
+	// Oracle does not have shared row locks, FOR UPDATE is used instead.
+	adapterLockLayout = `FOR UPDATE{{if .SkipLocked}} SKIP LOCKED{{end}}{{if .NoWait}} NOWAIT{{end}}`
+
+	adapterOrderByLayout = `
+    {{if .SortColumns}}
+      ORDER BY {{.SortColumns}}
+    {{end}}
+  `
+
+	adapterWhereLayout = `
+    {{if .Conds}}
+      WHERE {{.Conds}}
+    {{end}}
+  `
+
+	adapterUsingLayout = `
+    {{if .Columns}}
+      USING ({{.Columns}})
+    {{end}}
+  `
+
+	adapterJoinLayout = `
+    {{if .Table}}
+      {{ if .On }}
+        {{.Type}} JOIN {{.Table}}
+        {{.On}}
+      {{ else if .Using }}
+        {{.Type}} JOIN {{.Table}}
+        {{.Using}}
+      {{ else if .Type | eq "CROSS" }}
+        {{.Type}} JOIN {{.Table}}
+      {{else}}
+        NATURAL {{.Type}} JOIN {{.Table}}
+      {{end}}
+    {{end}}
+  `
+
+	adapterOnLayout = `
+    {{if .Conds}}
+      ON {{.Conds}}
+    {{end}}
+  `
+
+	adapterWithLayout = `
+    WITH {{.CTEs}}
+  `
+
+	adapterCompoundLayout = `
+    {{range .}}{{if .Operator}} {{.Operator}} {{end}}({{.Query}}){{end}}
+  `
+
+	adapterSelectLayout = `
+    {{if .With}}
+      {{.With}}
+    {{end}}
+
+    {{if .Compound}}
+      {{.Compound}}
+    {{else}}
+      SELECT
+        {{if .Distinct}}
+          DISTINCT
+        {{end}}
+
+        {{if .Columns}}
+          {{.Columns}}
+        {{else}}
+          *
+        {{end}}
+
+        {{if .Table}}
+          FROM {{.Table}}
+        {{end}}
+
+        {{.Joins}}
+
+        {{.Where}}
+
+        {{.GroupBy}}
+    {{end}}
+
+      {{.OrderBy}}
+
+      {{if .Offset}}
+        OFFSET {{.Offset}} ROWS
+      {{end}}
+
+      {{if .Limit}}
+        FETCH {{if .Offset}}NEXT{{else}}FIRST{{end}} {{.Limit}} ROWS ONLY
+      {{end}}
+
+      {{if .Lock}}
+        {{.Lock}}
+      {{end}}
+  `
+	adapterDeleteLayout = `
+    DELETE
+      FROM {{.Table}}
+      {{.Where}}
+  `
+	adapterUpdateLayout = `
+    UPDATE
+      {{.Table}}
+    SET {{.ColumnValues}}
+      {{ .Where }}
+  `
+
+	adapterSelectCountLayout = `
+    SELECT
+      COUNT(1) AS "_t"
+    FROM {{.Table}}
+      {{.Where}}
+  `
+
+	adapterInsertLayout = `
+    INSERT INTO {{.Table}}
+      {{if .Columns }}({{.Columns}}){{end}}
+    {{if .Query}}
+      {{.Query}}
+    {{else}}
+      VALUES
+      {{if .Values}}
+        {{.Values}}
+      {{else}}
+        (DEFAULT)
+      {{end}}
+    {{end}}
+  `
+
+	adapterTruncateLayout = `
+    TRUNCATE TABLE {{.Table}}
+    {{if .Cascade}}
+      CASCADE
+    {{end}}
+  `
+
+	adapterDropTableLayout = `
+    DROP TABLE {{.Table}}
+  `
+
+	adapterCreateTableLayout = `
+    CREATE TABLE {{.Table}} ({{.Definitions}})
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table}} {{.Definitions}}
+  `
+
+	adapterCreateIndexLayout = `
+    CREATE {{if .Unique}}UNIQUE {{end}}INDEX {{.Index}} ON {{.Table}} ({{.Columns}})
+  `
+
+	adapterCallLayout = `
+    BEGIN {{.Table}}({{.Columns}}); END;
+  `
+
+	adapterCallOutLayout = `?`
+
+	adapterAddColumnLayout = `ADD {{.}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.}}`
+
+	adapterColumnDefinitionLayout = `
+    {{.Name}}
+    {{.Type}}
+    {{if .AutoIncrement}}GENERATED BY DEFAULT AS IDENTITY{{end}}
+    {{if .Default}}DEFAULT {{.Default}}{{end}}
+    {{if .NotNull}}NOT NULL{{end}}
+    {{if .PrimaryKey}}PRIMARY KEY{{end}}
+    {{if .Unique}}UNIQUE{{end}}
+    {{if .References}}REFERENCES {{.References}}{{end}}
+  `
+
+	adapterGroupByLayout = `
+    {{if .GroupColumns}}
+      GROUP BY {{.GroupColumns}}
+    {{end}}
+  `
+)
+
+var adapterColumnTypes = map[exql.ColumnType]string{
+	exql.TypeBoolean:    `NUMBER(1)`,
+	exql.TypeInteger:    `NUMBER(10)`,
+	exql.TypeBigInteger: `NUMBER(19)`,
+	exql.TypeFloat:      `BINARY_DOUBLE`,
+	exql.TypeDecimal:    `NUMBER({{.Precision}}, {{.Scale}})`,
+	exql.TypeString:     `VARCHAR2({{.Size}})`,
+	exql.TypeText:       `CLOB`,
+	exql.TypeTimestamp:  `TIMESTAMP`,
+	exql.TypeDate:       `DATE`,
+	exql.TypeBinary:     `BLOB`,
+	exql.TypeJSON:       `CLOB`,
+}
+
+var template = &exql.Template{
+	ColumnSeparator:        adapterColumnSeparator,
+	IdentifierSeparator:    adapterIdentifierSeparator,
+	IdentifierQuote:        adapterIdentifierQuote,
+	ValueSeparator:         adapterValueSeparator,
+	ValueQuote:             adapterValueQuote,
+	AndKeyword:             adapterAndKeyword,
+	OrKeyword:              adapterOrKeyword,
+	DescKeyword:            adapterDescKeyword,
+	AscKeyword:             adapterAscKeyword,
+	AssignmentOperator:     adapterAssignmentOperator,
+	ClauseGroup:            adapterClauseGroup,
+	ClauseOperator:         adapterClauseOperator,
+	ColumnValue:            adapterColumnValue,
+	TableAliasLayout:       adapterTableAliasLayout,
+	ColumnAliasLayout:      adapterColumnAliasLayout,
+	SortByColumnLayout:     adapterSortByColumnLayout,
+	WhereLayout:            adapterWhereLayout,
+	WindowLayout:           adapterWindowLayout,
+	CompoundLayout:         adapterCompoundLayout,
+	WithLayout:             adapterWithLayout,
+	JoinLayout:             adapterJoinLayout,
+	JSONPathLayout:         adapterJSONPathLayout,
+	LockLayout:             adapterLockLayout,
+	OnLayout:               adapterOnLayout,
+	UsingLayout:            adapterUsingLayout,
+	OrderByLayout:          adapterOrderByLayout,
+	InsertLayout:           adapterInsertLayout,
+	SelectLayout:           adapterSelectLayout,
+	UpdateLayout:           adapterUpdateLayout,
+	DeleteLayout:           adapterDeleteLayout,
+	TruncateLayout:         adapterTruncateLayout,
+	DropTableLayout:        adapterDropTableLayout,
+	CreateTableLayout:      adapterCreateTableLayout,
+	AlterTableLayout:       adapterAlterTableLayout,
+	CreateIndexLayout:      adapterCreateIndexLayout,
+	CallLayout:             adapterCallLayout,
+	CallOutLayout:          adapterCallOutLayout,
+	AddColumnLayout:        adapterAddColumnLayout,
+	DropColumnLayout:       adapterDropColumnLayout,
+	ColumnDefinitionLayout: adapterColumnDefinitionLayout,
+	ColumnTypes:            adapterColumnTypes,
+	CountLayout:            adapterSelectCountLayout,
+	GroupByLayout:          adapterGroupByLayout,
+	Cache:                  cache.NewCache(),
+}
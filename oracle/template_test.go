@@ -0,0 +1,113 @@
+package oracle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, `"ARTIST_SEQ"`, quoteIdentifier("artist_seq"))
+	assert.Equal(t, `"APP"."ARTIST_SEQ"`, quoteIdentifier("app.artist_seq"))
+	assert.Equal(t, `"artist seq"`, quoteIdentifier("artist seq"))
+	assert.Equal(t, `"a""b"`, quoteIdentifier(`a"b`))
+	assert.Equal(t, `SELECT "ARTIST_SEQ".NEXTVAL FROM DUAL`, (&database{}).NextSequenceValueQuery("artist_seq"))
+}
+
+func TestTemplateSelect(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`SELECT * FROM "artist" WHERE ("id" = $1)`,
+		b.SelectFrom("artist").Where("id", 1).String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "artist" ORDER BY "name" ASC FETCH FIRST 10 ROWS ONLY`,
+		b.SelectFrom("artist").OrderBy("name").Limit(10).String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "artist" ORDER BY "name" ASC OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY`,
+		b.SelectFrom("artist").OrderBy("name").Limit(10).Offset(20).String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "artist" OFFSET 5 ROWS`,
+		b.SelectFrom("artist").Offset(5).String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "jobs" WHERE ("status" = $1) FOR UPDATE SKIP LOCKED`,
+		b.SelectFrom("jobs").Where("status", "pending").SkipLocked().String(),
+	)
+}
+
+func TestTemplateDDL(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`CREATE TABLE "books" ("id" NUMBER(10) GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY, "title" VARCHAR2(80) NOT NULL, "price" NUMBER(10, 2) DEFAULT 0, "author_id" NUMBER(10), FOREIGN KEY ("author_id") REFERENCES "authors" ("id"))`,
+		b.CreateTable("books").Columns(
+			sqlbuilder.NewColumn("id", sqlbuilder.Integer).PrimaryKey().AutoIncrement(),
+			sqlbuilder.NewColumn("title", sqlbuilder.String(80)).NotNull(),
+			sqlbuilder.NewColumn("price", sqlbuilder.Decimal(10, 2)).Default(0),
+			sqlbuilder.NewColumn("author_id", sqlbuilder.Integer).References("authors", "id"),
+		).String(),
+	)
+
+	assert.Equal(
+		`ALTER TABLE "books" ADD "meta" CLOB`,
+		b.AlterTable("books").AddColumn(sqlbuilder.NewColumn("meta", sqlbuilder.JSON)).String(),
+	)
+}
+
+func TestTemplateCall(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	var total int
+	assert.Equal(
+		`BEGIN "order_total"($1, $2); END;`,
+		b.Call("order_total", 12, sqlbuilder.Out(&total)).String(),
+	)
+}
+
+func TestTemplateInsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2)`,
+		b.InsertInto("artist").Columns("id", "name").Values(12, "Chavela Vargas").String(),
+	)
+
+	{
+		_, _, err := b.InsertInto("artist").Values(db.Cond{"id": 12}).OnConflict("id").DoNothing().Compile()
+		assert.Equal(db.ErrUnsupported, err)
+	}
+}
+
+func TestTemplateUpdate(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`UPDATE "artist" SET "name" = $1 WHERE ("id" = $2)`,
+		b.Update("artist").Set("name", "Artist").Where("id", 1).String(),
+	)
+}
+
+func TestTemplateDelete(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`DELETE FROM "artist" WHERE ("id" = $1)`,
+		b.DeleteFrom("artist").Where("id", 1).String(),
+	)
+}
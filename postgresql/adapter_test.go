@@ -1091,6 +1091,42 @@ func TestBinaryMode_Issue391(t *testing.T) {
 	testPostgreSQLTypes(t, sess)
 }
 
+func TestSimpleProtocol(t *testing.T) {
+	settingsWithSimpleProtocol := settings
+	settingsWithSimpleProtocol.Options = map[string]string{}
+	for k, v := range settings.Options {
+		settingsWithSimpleProtocol.Options[k] = v
+	}
+	settingsWithSimpleProtocol.Options[SimpleProtocolOption] = "yes"
+
+	sess, err := Open(settingsWithSimpleProtocol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.Close()
+
+	assert.False(t, sess.PreparedStatementCacheEnabled())
+	sess.SetPreparedStatementCache(true)
+	assert.False(t, sess.PreparedStatementCacheEnabled())
+
+	before, err := getStats(sess)
+	assert.NoError(t, err)
+
+	row, err := sess.QueryRow(`SELECT ?::text`, `O'Brien \ "quoted"`)
+	assert.NoError(t, err)
+
+	var name string
+	err = row.Scan(&name)
+	assert.NoError(t, err)
+	assert.Equal(t, `O'Brien \ "quoted"`, name)
+
+	testPostgreSQLTypes(t, sess)
+
+	after, err := getStats(sess)
+	assert.NoError(t, err)
+	assert.Equal(t, before["pg_prepared_statements_count"], after["pg_prepared_statements_count"])
+}
+
 func getStats(sess sqlbuilder.Database) (map[string]int, error) {
 	stats := make(map[string]int)
 
@@ -22,8 +22,12 @@
 package postgresql
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 
+	"github.com/lib/pq"
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter"
 )
@@ -38,9 +42,13 @@ type collection struct {
 
 var (
 	_ = sqladapter.Collection(&collection{})
+	_ = sqladapter.BatchInserter(&collection{})
+	_ = sqladapter.BulkLoader(&collection{})
 	_ = db.Collection(&collection{})
 )
 
+var errBulkLoadOutsideTx = errors.New("COPY must run within a transaction")
+
 // newCollection binds *collection with sqladapter.
 func newCollection(d *database, name string) *collection {
 	c := &collection{
@@ -59,19 +67,32 @@ func (c *collection) Database() sqladapter.Database {
 	return c.d
 }
 
-// Insert inserts an item (map or struct) into the collection.
-func (c *collection) Insert(item interface{}) (interface{}, error) {
+// InsertContext inserts an item (map or struct) into the collection.
+func (c *collection) InsertContext(ctx context.Context, item interface{}) (interface{}, error) {
+	return sqladapter.InsertWithHooks(ctx, c.d, c.Name(), item, func() (interface{}, error) {
+		return c.insert(ctx, item)
+	})
+}
+
+func (c *collection) insert(ctx context.Context, item interface{}) (interface{}, error) {
 	var err error
 
 	pKey := c.BaseCollection.PrimaryKeys()
 
-	q := c.d.InsertInto(c.Name()).Values(item)
+	// The item is mapped here so that its key and its tenant can be given a
+	// value.
+	columnNames, columnValues, err := c.InsertValues(ctx, item)
+	if err != nil {
+		return nil, err
+	}
+	q := c.d.InsertInto(c.Name()).Columns(columnNames...).Values(columnValues...)
 
-	if len(pKey) == 0 {
-		// There is no primary key.
+	if len(pKey) == 0 || c.d.DryRun() {
+		// There is no primary key, or the statement won't run because the
+		// session is in dry-run mode and there's no key to return.
 		var res sql.Result
 
-		if res, err = q.Exec(); err != nil {
+		if res, err = q.ExecContext(ctx); err != nil {
 			return nil, err
 		}
 
@@ -86,15 +107,105 @@ func (c *collection) Insert(item interface{}) (interface{}, error) {
 	q = q.Returning(pKey...)
 
 	var keyMap db.Cond
-	if err = q.Iterator().One(&keyMap); err != nil {
+	if err = q.IteratorContext(ctx).One(&keyMap); err != nil {
 		return nil, err
 	}
 
 	// The IDSetter interface does not match, look for another interface match.
 	if len(keyMap) == 1 {
-		return keyMap[pKey[0]], nil
+		return sqladapter.ReturnedKey(keyMap[pKey[0]]), nil
 	}
 
 	// This was a compound key and no interface matched it, let's return a map.
 	return keyMap, nil
 }
+
+// InsertBatchContext inserts all items with a single statement and returns
+// their primary keys in the same order.
+func (c *collection) InsertBatchContext(ctx context.Context, items []interface{}) ([]interface{}, error) {
+	pKey := c.BaseCollection.PrimaryKeys()
+	if len(pKey) == 0 {
+		return nil, db.ErrUnsupported
+	}
+
+	for i := range items {
+		if hook, ok := items[i].(db.BeforeInsertHook); ok {
+			if err := hook.BeforeInsert(c.d); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	columns, rows, err := c.BulkRows(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	q := c.d.InsertInto(c.Name()).Columns(columns...)
+	for i := range rows {
+		q = q.Values(rows[i]...)
+	}
+
+	// Asking the database to return the primary keys after insertion.
+	q = q.Returning(pKey...)
+
+	var keyMaps []db.Cond
+	if err := q.IteratorContext(ctx).All(&keyMaps); err != nil {
+		return nil, err
+	}
+
+	if len(keyMaps) != len(items) {
+		return nil, fmt.Errorf("Expecting %d IDs but got %d", len(items), len(keyMaps))
+	}
+
+	ids := make([]interface{}, len(keyMaps))
+	for i := range keyMaps {
+		if len(keyMaps[i]) == 1 {
+			ids[i] = keyMaps[i][pKey[0]]
+			continue
+		}
+		ids[i] = keyMaps[i]
+	}
+
+	for i := range items {
+		if hook, ok := items[i].(db.AfterInsertHook); ok {
+			if err := hook.AfterInsert(c.d); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// BulkLoadContext loads all items using the COPY protocol, it must be called
+// within a transaction.
+func (c *collection) BulkLoadContext(ctx context.Context, items []interface{}) error {
+	tx, ok := c.d.Driver().(*sql.Tx)
+	if !ok {
+		return errBulkLoadOutsideTx
+	}
+
+	columns, rows, err := c.BulkRows(ctx, items)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(c.Name(), columns...))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range rows {
+		if _, err := stmt.ExecContext(ctx, c.d.ConvertValues(rows[i])...); err != nil {
+			return err
+		}
+	}
+
+	// An Exec without arguments flushes the rows to the server.
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+	return nil
+}
@@ -28,6 +28,8 @@ import (
 	"unicode"
 
 	"github.com/lib/pq"
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
 )
 
 // scanner implements a tokenizer for libpq-style option strings.
@@ -93,6 +95,16 @@ const connectionScheme = `postgres`
 //
 // If you already have a valid DSN, you can use ParseURL to convert it into
 // a ConnectionURL before passing it to Open.
+//
+// Besides the options github.com/lib/pq understands, Options may set
+// "simple_protocol" to "yes" when connecting through a transaction-pooling
+// proxy like PgBouncer, see SimpleProtocolOption.
+//
+// Host may list several comma-separated hosts, they're tried in order, see
+// sqladapter.TargetSessionAttrsOption:
+//
+//   Host: "db1.example.com:5432,db2.example.com:5432",
+//   Options: map[string]string{"target_session_attrs": "primary"},
 type ConnectionURL struct {
 	User     string
 	Password string
@@ -100,6 +112,10 @@ type ConnectionURL struct {
 	Socket   string
 	Database string
 	Options  map[string]string
+
+	// TLS is translated into the sslmode, sslrootcert, sslcert and sslkey
+	// options, pq doesn't accept a *tls.Config.
+	TLS *db.TLSConfig
 }
 
 var escaper = strings.NewReplacer(` `, `\ `, `'`, `\'`, `\`, `\\`)
@@ -118,7 +134,27 @@ func (c ConnectionURL) String() (s string) {
 		u = append(u, "password="+escaper.Replace(c.Password))
 	}
 
-	if c.Host != "" {
+	if hosts := sqladapter.SplitHosts(c.Host); len(hosts) > 1 {
+		// libpq-style multi-host list, ports are given in the same order.
+		hostList := make([]string, len(hosts))
+		portList := make([]string, len(hosts))
+		for i := range hosts {
+			host, port, err := net.SplitHostPort(hosts[i])
+			if err != nil {
+				host = hosts[i]
+			}
+			if host == "" {
+				host = "127.0.0.1"
+			}
+			if port == "" {
+				port = "5432"
+			}
+			hostList[i] = escaper.Replace(host)
+			portList[i] = escaper.Replace(port)
+		}
+		u = append(u, "host="+strings.Join(hostList, ","))
+		u = append(u, "port="+strings.Join(portList, ","))
+	} else if c.Host != "" {
 		host, port, err := net.SplitHostPort(c.Host)
 		if err == nil {
 			if host == "" {
@@ -151,18 +187,51 @@ func (c ConnectionURL) String() (s string) {
 		c.Options = map[string]string{}
 	}
 
+	if c.TLS != nil {
+		options := make(map[string]string, len(c.Options)+4)
+		for k, v := range c.Options {
+			options[k] = v
+		}
+		for k, v := range tlsOptions(c.TLS) {
+			options[k] = v
+		}
+		c.Options = options
+	}
+
 	// If not present, SSL mode is assumed disabled.
 	if sslMode, ok := c.Options["sslmode"]; !ok || sslMode == "" {
 		c.Options["sslmode"] = "disable"
 	}
 
 	for k, v := range c.Options {
+		if k == SimpleProtocolOption || sqladapter.IsAdapterOption(k) {
+			// Handled by the adapter, pq would send it to the server as a
+			// run-time parameter.
+			continue
+		}
 		u = append(u, escaper.Replace(k)+"="+escaper.Replace(v))
 	}
 
 	return strings.Join(u, " ")
 }
 
+// tlsOptions returns the pq options that correspond to the given TLS
+// configuration.
+func tlsOptions(c *db.TLSConfig) map[string]string {
+	options := map[string]string{"sslmode": "verify-full"}
+	if c.InsecureSkipVerify {
+		// With sslrootcert, require behaves like verify-ca.
+		options["sslmode"] = "require"
+	} else if c.CAFile != "" {
+		options["sslrootcert"] = c.CAFile
+	}
+	if c.CertFile != "" {
+		options["sslcert"] = c.CertFile
+		options["sslkey"] = c.KeyFile
+	}
+	return options
+}
+
 // ParseURL parses the given DSN into a ConnectionURL struct.
 // A typical PostgreSQL connection URL looks like:
 //
@@ -170,7 +239,11 @@ func (c ConnectionURL) String() (s string) {
 func ParseURL(s string) (u ConnectionURL, err error) {
 	o := make(values)
 
+	var hostList string
 	if strings.HasPrefix(s, "postgres://") {
+		// pq does not understand multi-host URLs, the host list is taken out
+		// before parsing the rest.
+		s, hostList = cutHostList(s)
 		s, err = pq.ParseURL(s)
 		if err != nil {
 			return u, err
@@ -187,7 +260,11 @@ func ParseURL(s string) (u ConnectionURL, err error) {
 	h := o.Get("host")
 	p := o.Get("port")
 
-	if strings.HasPrefix(h, "/") {
+	if hostList != "" {
+		u.Host = hostList
+	} else if strings.Contains(h, ",") {
+		u.Host = joinHostPorts(sqladapter.SplitHosts(h), strings.Split(p, ","))
+	} else if strings.HasPrefix(h, "/") {
 		u.Socket = h
 	} else {
 		if p == "" {
@@ -213,6 +290,39 @@ func ParseURL(s string) (u ConnectionURL, err error) {
 	return u, err
 }
 
+// cutHostList removes the host list of the given URL if it has more than one
+// host, the URL and the list are returned.
+func cutHostList(s string) (string, string) {
+	rest := s[len("postgres://"):]
+	end := strings.IndexAny(rest, "/?")
+	if end < 0 {
+		end = len(rest)
+	}
+	start := strings.LastIndex(rest[:end], "@") + 1
+	if !strings.Contains(rest[start:end], ",") {
+		return s, ""
+	}
+	return s[:len("postgres://")+start] + rest[end:], rest[start:end]
+}
+
+// joinHostPorts pairs the hosts and ports of a libpq-style multi-host option
+// string, a single port applies to all the hosts.
+func joinHostPorts(hosts []string, ports []string) string {
+	list := make([]string, len(hosts))
+	for i, host := range hosts {
+		port := ports[0]
+		if len(ports) > 1 && i < len(ports) {
+			port = ports[i]
+		}
+		if port == "" {
+			list[i] = host
+		} else {
+			list[i] = fmt.Sprintf("%s:%s", host, port)
+		}
+	}
+	return strings.Join(list, ",")
+}
+
 // parseOpts parses the options from name and adds them to the values.
 //
 // The parsing code is based on conninfo_parse from libpq's fe-connect.c
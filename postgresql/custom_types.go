@@ -22,12 +22,14 @@
 package postgresql
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"reflect"
 
 	"github.com/lib/pq"
+	"upper.io/db.v3"
 	"upper.io/db.v3/lib/sqlbuilder"
 )
 
@@ -37,6 +39,33 @@ func Array(in interface{}) sqlbuilder.ScannerValuer {
 	return pq.Array(in)
 }
 
+// Any matches the rows where the column equals any of the elements of the
+// given slice. Unlike db.In, the slice is sent as a single array argument.
+//
+//	// "id" = ANY($1)
+//	db.Cond{"id": postgresql.Any([]int64{1, 2, 3})}
+func Any(in interface{}) db.Comparison {
+	return db.Op(":column = ANY(?)", Array(in))
+}
+
+// Contains matches the rows where the array column contains all the elements
+// of the given slice.
+//
+//	// "tags" @> $1
+//	db.Cond{"tags": postgresql.Contains([]string{"go", "sql"})}
+func Contains(in interface{}) db.Comparison {
+	return db.Op("@>", Array(in))
+}
+
+// Overlaps matches the rows where the array column has at least one element in
+// common with the given slice.
+//
+//	// "tags" && $1
+//	db.Cond{"tags": postgresql.Overlaps([]string{"go", "sql"})}
+func Overlaps(in interface{}) db.Comparison {
+	return db.Op("&&", Array(in))
+}
+
 // JSONB represents a PostgreSQL's JSONB value:
 // https://www.postgresql.org/docs/9.6/static/datatype-json.html. JSONB
 // satisfies sqlbuilder.ScannerValuer.
@@ -195,6 +224,107 @@ func (g *GenericArray) Scan(src interface{}) error {
 	return nil
 }
 
+// primitiveArray binds and scans slices of built-in primitive types that have
+// no dedicated array type, like []int or []float32, as PostgreSQL arrays.
+// Slices of named types (e.g.: []MyInt) are still encoded into JSONB.
+type primitiveArray struct {
+	v reflect.Value
+}
+
+func isPrimitiveSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice || t.Elem().PkgPath() != "" {
+		return false
+	}
+	switch t.Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool, reflect.String:
+		return true
+	}
+	return false
+}
+
+// Value satisfies the driver.Valuer interface.
+func (a *primitiveArray) Value() (driver.Value, error) {
+	v := reflect.Indirect(a.v)
+	if v.IsNil() {
+		return nil, nil
+	}
+
+	n := v.Len()
+	switch v.Type().Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		arr := make(Int64Array, n)
+		for i := 0; i < n; i++ {
+			arr[i] = v.Index(i).Int()
+		}
+		return arr.Value()
+	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		arr := make(Int64Array, n)
+		for i := 0; i < n; i++ {
+			arr[i] = int64(v.Index(i).Uint())
+		}
+		return arr.Value()
+	case reflect.Float32, reflect.Float64:
+		arr := make(Float64Array, n)
+		for i := 0; i < n; i++ {
+			arr[i] = v.Index(i).Float()
+		}
+		return arr.Value()
+	case reflect.Bool:
+		arr := make(BoolArray, n)
+		for i := 0; i < n; i++ {
+			arr[i] = v.Index(i).Bool()
+		}
+		return arr.Value()
+	}
+
+	arr := make(StringArray, n)
+	for i := 0; i < n; i++ {
+		arr[i] = v.Index(i).String()
+	}
+	return arr.Value()
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (a *primitiveArray) Scan(src interface{}) error {
+	if a.v.Kind() != reflect.Ptr {
+		return errors.New("Expecting a pointer to a slice")
+	}
+
+	dst := a.v.Elem()
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	var scanner sql.Scanner
+	switch dst.Type().Elem().Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		scanner = &Int64Array{}
+	case reflect.Float32, reflect.Float64:
+		scanner = &Float64Array{}
+	case reflect.Bool:
+		scanner = &BoolArray{}
+	default:
+		scanner = &StringArray{}
+	}
+
+	if err := scanner.Scan(src); err != nil {
+		return err
+	}
+
+	elems := reflect.ValueOf(scanner).Elem()
+	out := reflect.MakeSlice(dst.Type(), elems.Len(), elems.Len())
+	for i := 0; i < elems.Len(); i++ {
+		out.Index(i).Set(elems.Index(i).Convert(dst.Type().Elem()))
+	}
+	dst.Set(out)
+
+	return nil
+}
+
 // JSONBMap represents a map of interfaces with string keys
 // (`map[string]interface{}`) that is compatible with PostgreSQL's JSONB type.
 // JSONBMap satisfies sqlbuilder.ScannerValuer.
@@ -301,6 +431,9 @@ func autoWrap(elem reflect.Value, v interface{}) interface{} {
 	case reflect.Ptr:
 		return autoWrap(elem.Elem(), v)
 	case reflect.Slice:
+		if isPrimitiveSlice(elem.Type()) {
+			return &primitiveArray{reflect.ValueOf(v)}
+		}
 		return &JSONB{v}
 	case reflect.Map:
 		if reflect.TypeOf(v).Kind() == reflect.Ptr {
@@ -323,6 +456,7 @@ var (
 	_ sqlbuilder.ScannerValuer = &Float64Array{}
 	_ sqlbuilder.ScannerValuer = &BoolArray{}
 	_ sqlbuilder.ScannerValuer = &GenericArray{}
+	_ sqlbuilder.ScannerValuer = &primitiveArray{}
 	_ sqlbuilder.ScannerValuer = &JSONBMap{}
 	_ sqlbuilder.ScannerValuer = &JSONBArray{}
 )
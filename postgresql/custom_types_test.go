@@ -1,10 +1,13 @@
 package postgresql
 
 import (
+	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"upper.io/db.v3/lib/geo"
 )
 
 type testStruct struct {
@@ -85,3 +88,76 @@ func TestScanJSONB(t *testing.T) {
 		assert.Equal(t, 12.34, a[0].V.V)
 	}
 }
+
+func TestPrimitiveArray(t *testing.T) {
+	type ids []int
+	type label string
+
+	{
+		values := ConvertValues([]interface{}{ids{1, 2, 3}, []float32{1.5}, []byte("raw"), []label{"a"}, []interface{}{1}})
+		_, ok := values[0].(*primitiveArray)
+		assert.True(t, ok)
+		_, ok = values[1].(*primitiveArray)
+		assert.True(t, ok)
+		assert.Equal(t, []byte("raw"), values[2])
+		_, ok = values[3].(*JSONB)
+		assert.True(t, ok)
+		_, ok = values[4].(*JSONB)
+		assert.True(t, ok)
+	}
+	{
+		v, err := ConvertValues([]interface{}{[]int32{4, 5}})[0].(driver.Valuer).Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "{4,5}", v)
+	}
+	{
+		var dst []uint16
+		err := ConvertValues([]interface{}{&dst})[0].(sql.Scanner).Scan([]byte("{7,8}"))
+		assert.NoError(t, err)
+		assert.Equal(t, []uint16{7, 8}, dst)
+	}
+	{
+		dst := ids{9}
+		scanner := ConvertValues([]interface{}{&dst})[0].(sql.Scanner)
+		assert.NoError(t, scanner.Scan([]byte("{1,2}")))
+		assert.Equal(t, ids{1, 2}, dst)
+
+		assert.NoError(t, scanner.Scan(nil))
+		assert.Nil(t, dst)
+	}
+}
+
+func TestGeometry(t *testing.T) {
+	point := Geometry{geo.Geometry{SRID: 4326, Shape: geo.Point{X: 1, Y: 2}}}
+
+	{
+		v, err := point.Value()
+		assert.NoError(t, err)
+		assert.Equal(t, "0101000020e6100000000000000000f03f0000000000000040", v)
+
+		v, err = Geometry{}.Value()
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+	}
+	{
+		var g Geometry
+		assert.NoError(t, g.Scan([]byte("0101000020E6100000000000000000F03F0000000000000040")))
+		assert.Equal(t, point, g)
+
+		b, err := point.EWKB()
+		assert.NoError(t, err)
+
+		g = Geometry{}
+		assert.NoError(t, g.Scan(b))
+		assert.Equal(t, point, g)
+
+		assert.NoError(t, g.Scan(nil))
+		assert.True(t, g.IsZero())
+
+		assert.Error(t, g.Scan(12))
+	}
+	{
+		v := ConvertValues([]interface{}{point})
+		assert.Equal(t, point, v[0])
+	}
+}
@@ -28,13 +28,15 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"reflect"
 	"strings"
 	"sync"
 	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver.
+	"github.com/lib/pq" // PostgreSQL driver.
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter"
 	"upper.io/db.v3/internal/sqladapter/compat"
@@ -50,20 +52,30 @@ type database struct {
 
 	connURL db.ConnectionURL
 	mu      sync.Mutex
+
+	// simpleProtocol is set when connURL enables the simple protocol mode.
+	simpleProtocol bool
 }
 
 var (
 	_ = sqlbuilder.Database(&database{})
+	_ = sqlbuilder.Cluster(&database{})
 	_ = sqladapter.Database(&database{})
 )
 
 // newDatabase creates a new *database session for internal use.
 func newDatabase(settings db.ConnectionURL) *database {
 	return &database{
-		connURL: settings,
+		connURL:        settings,
+		simpleProtocol: simpleProtocolEnabled(settings),
 	}
 }
 
+// Adapter returns the name of the adapter.
+func (d *database) Adapter() string {
+	return Adapter
+}
+
 // ConnectionURL returns this database session's connection URL, if any.
 func (d *database) ConnectionURL() db.ConnectionURL {
 	return d.connURL
@@ -75,6 +87,7 @@ func (d *database) Open(connURL db.ConnectionURL) error {
 		return db.ErrMissingConnURL
 	}
 	d.connURL = connURL
+	d.simpleProtocol = simpleProtocolEnabled(connURL)
 	return d.open()
 }
 
@@ -115,15 +128,28 @@ func (d *database) open() error {
 	// Binding with sqladapter's logic.
 	d.BaseDatabase = sqladapter.NewBaseDatabase(d)
 
+	if err := sqladapter.SetPoolOptions(d.BaseDatabase, sqladapter.ConnectionOptions(d.connURL)); err != nil {
+		return err
+	}
+
+	if err := setupTLS(d.connURL); err != nil {
+		return err
+	}
+
+	if d.simpleProtocol {
+		d.BaseDatabase.SetPreparedStatementCache(false)
+	}
+
 	// Binding with sqlbuilder.
 	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
 
 	connFn := func() error {
-		sess, err := sql.Open("postgres", d.ConnectionURL().String())
+		sess, err := d.BaseDatabase.OpenFailover("postgres", sqladapter.HostDSNs(d.connURL), sqladapter.ConnectionOptions(d.connURL))
 		if err == nil {
-			sess.SetConnMaxLifetime(db.DefaultSettings.ConnMaxLifetime())
-			sess.SetMaxIdleConns(db.DefaultSettings.MaxIdleConns())
-			sess.SetMaxOpenConns(db.DefaultSettings.MaxOpenConns())
+			sess.SetConnMaxLifetime(d.BaseDatabase.ConnMaxLifetime())
+			compat.SetConnMaxIdleTime(sess, d.BaseDatabase.ConnMaxIdleTime())
+			sess.SetMaxIdleConns(d.BaseDatabase.MaxIdleConns())
+			sess.SetMaxOpenConns(d.BaseDatabase.MaxOpenConns())
 			return d.BaseDatabase.BindSession(sess)
 		}
 		return err
@@ -136,6 +162,32 @@ func (d *database) open() error {
 	return nil
 }
 
+// connectionTLS returns the TLS configuration of the given connection URL.
+func connectionTLS(connURL db.ConnectionURL) *db.TLSConfig {
+	switch u := connURL.(type) {
+	case ConnectionURL:
+		return u.TLS
+	case *ConnectionURL:
+		if u != nil {
+			return u.TLS
+		}
+	}
+	return nil
+}
+
+// setupTLS checks that the TLS configuration of the given connection URL can
+// be used, pq only takes file paths.
+func setupTLS(connURL db.ConnectionURL) error {
+	cfg := connectionTLS(connURL)
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Config != nil {
+		return db.ErrUnsupported
+	}
+	return cfg.Validate()
+}
+
 // Clone creates a copy of the database session on the given context.
 func (d *database) clone(ctx context.Context, checkConn bool) (*database, error) {
 	clone := newDatabase(d.connURL)
@@ -154,6 +206,87 @@ func (d *database) clone(ctx context.Context, checkConn bool) (*database, error)
 }
 
 func (d *database) ConvertValues(values []interface{}) []interface{} {
+	return ConvertValues(values)
+}
+
+// OutParamsRow reads the OUT parameters of stored procedures from the row
+// CALL returns.
+func (d *database) OutParamsRow() bool {
+	return true
+}
+
+// NextSequenceValueQuery returns the statement that advances the given
+// sequence, it's used by NextSequenceValue.
+func (d *database) NextSequenceValueQuery(name string) string {
+	return "SELECT nextval('" + strings.Replace(name, "'", "''", -1) + "')"
+}
+
+// ServerUUIDs leaves fields tagged with "uuid" to the default value of their
+// columns, like gen_random_uuid().
+func (d *database) ServerUUIDs() bool {
+	return true
+}
+
+// quoteGID returns the global id of a prepared transaction as a string
+// literal.
+func quoteGID(gid string) string {
+	return "'" + strings.Replace(gid, "'", "''", -1) + "'"
+}
+
+// PrepareTransactionQuery returns the statement that prepares the current
+// transaction for a two-phase commit, it's used by PrepareTwoPhase.
+func (d *database) PrepareTransactionQuery(gid string) string {
+	return "PREPARE TRANSACTION " + quoteGID(gid)
+}
+
+// CommitPreparedQuery returns the statement that commits a prepared
+// transaction, it's used by CommitPrepared.
+func (d *database) CommitPreparedQuery(gid string) string {
+	return "COMMIT PREPARED " + quoteGID(gid)
+}
+
+// RollbackPreparedQuery returns the statement that rolls back a prepared
+// transaction, it's used by RollbackPrepared.
+func (d *database) RollbackPreparedQuery(gid string) string {
+	return "ROLLBACK PREPARED " + quoteGID(gid)
+}
+
+// PreparedTransactionsQuery returns the statement that lists the prepared
+// transactions of the database, it's used by PreparedTransactions.
+func (d *database) PreparedTransactionsQuery() string {
+	return "SELECT gid FROM pg_prepared_xacts WHERE database = current_database() ORDER BY prepared"
+}
+
+// advisoryLockKey maps the key of an advisory lock to the bigint that
+// identifies it.
+func advisoryLockKey(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
+
+// AdvisoryLockQuery returns the statement that takes a session-level advisory
+// lock, it's used by AdvisoryLock.
+func (d *database) AdvisoryLockQuery(key string) (string, []interface{}) {
+	return "SELECT pg_advisory_lock(?)", []interface{}{advisoryLockKey(key)}
+}
+
+// TryAdvisoryLockQuery returns the statement that takes a session-level
+// advisory lock without waiting, it's used by TryAdvisoryLock.
+func (d *database) TryAdvisoryLockQuery(key string) (string, []interface{}) {
+	return "SELECT pg_try_advisory_lock(?)", []interface{}{advisoryLockKey(key)}
+}
+
+// AdvisoryUnlockQuery returns the statement that releases a session-level
+// advisory lock, it's used by Unlock.
+func (d *database) AdvisoryUnlockQuery(key string) (string, []interface{}) {
+	return "SELECT pg_advisory_unlock(?)", []interface{}{advisoryLockKey(key)}
+}
+
+// ConvertValues wraps the values github.com/lib/pq can't handle by itself,
+// like slices and maps, into types that implement driver.Valuer. Adapters for
+// databases that speak PostgreSQL's protocol can use it too.
+func ConvertValues(values []interface{}) []interface{} {
 	for i := range values {
 		switch v := values[i].(type) {
 		case *string, *bool, *int, *uint, *int64, *uint64, *int32, *uint32, *int16, *uint16, *int8, *uint8, *float32, *float64, *[]uint8, sql.Scanner, *sql.Scanner, *time.Time:
@@ -206,22 +339,80 @@ func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (s
 		panic(err.Error())
 	}
 	query, args := sqlbuilder.Preprocess(compiled, args)
+	if d.simpleProtocol && len(args) > 0 {
+		// Arguments that can't be written as literals are sent apart, as
+		// usual.
+		if interpolated, err := interpolateArgs(query, args); err == nil {
+			return interpolated, nil
+		}
+	}
 	return sqladapter.ReplaceWithDollarSign(query), args
 }
 
+// SetPreparedStatementCache enables or disables the prepared statement cache,
+// the cache stays disabled in simple protocol mode.
+func (d *database) SetPreparedStatementCache(enabled bool) {
+	d.BaseDatabase.SetPreparedStatementCache(enabled && !d.simpleProtocol)
+}
+
 // Err allows sqladapter to translate specific PostgreSQL string errors into
 // custom error values.
 func (d *database) Err(err error) error {
 	if err != nil {
+		if cErr := ConstraintError(err); cErr != nil {
+			return cErr
+		}
 		s := err.Error()
 		// These errors are not exported so we have to check them by they string value.
 		if strings.Contains(s, `too many clients`) || strings.Contains(s, `remaining connection slots are reserved`) || strings.Contains(s, `too many open`) {
 			return db.ErrTooManyClients
 		}
+		// SQLSTATE 40001 and 40P01, CockroachDB asks clients to restart
+		// transactions as well.
+		if strings.Contains(s, `could not serialize access`) || strings.Contains(s, `deadlock detected`) || strings.Contains(s, `restart transaction`) {
+			return db.ErrSerializationFailure
+		}
+		// SQLSTATE 57014, when it's the statement_timeout of the server the
+		// one that was exceeded.
+		if strings.Contains(s, `canceling statement due to statement timeout`) {
+			return db.ErrQueryTimeout
+		}
 	}
 	return err
 }
 
+// ConstraintError converts the integrity constraint violations reported by
+// github.com/lib/pq into db.ErrUniqueViolation, db.ErrForeignKeyViolation or
+// db.ErrCheckViolation values, it returns nil if err is not one of them.
+// Adapters for databases that speak PostgreSQL's protocol can use it too.
+func ConstraintError(err error) error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return nil
+	}
+
+	column := pqErr.Column
+	if column == "" {
+		// Detail looks like: Key (email)=(joe@example.com) already exists.
+		if detail := pqErr.Detail; strings.HasPrefix(detail, "Key (") {
+			if i := strings.Index(detail, ")=("); i > 0 {
+				column = detail[len("Key ("):i]
+			}
+		}
+	}
+
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		return &db.ErrUniqueViolation{Constraint: pqErr.Constraint, Column: column, Err: err}
+	case "23503": // foreign_key_violation
+		return &db.ErrForeignKeyViolation{Constraint: pqErr.Constraint, Column: column, Err: err}
+	case "23514": // check_violation
+		return &db.ErrCheckViolation{Constraint: pqErr.Constraint, Column: column, Err: err}
+	}
+
+	return nil
+}
+
 // NewCollection creates a db.Collection by name.
 func (d *database) NewCollection(name string) db.Collection {
 	return newCollection(d, name)
@@ -235,7 +426,14 @@ func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) erro
 	return sqladapter.RunTx(d, ctx, fn)
 }
 
-// NewDatabaseTx begins a transaction block.
+// TxWithRetry is like Tx but fn is run again, within a new transaction, if the
+// transaction fails because of a serialization failure or a deadlock.
+func (d *database) TxWithRetry(ctx context.Context, fn func(tx sqlbuilder.Tx) error, policy sqlbuilder.RetryPolicy) error {
+	return sqladapter.TxWithRetry(d, ctx, fn, policy)
+}
+
+// NewDatabaseTx begins a transaction block. If the session is already within a
+// transaction, the new one is nested using a savepoint.
 func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
 	clone, err := d.clone(ctx, true)
 	if err != nil {
@@ -244,6 +442,14 @@ func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, er
 	clone.mu.Lock()
 	defer clone.mu.Unlock()
 
+	if currTx := d.BaseDatabase.Transaction(); currTx != nil {
+		// Already within a transaction, nest the new one with a savepoint.
+		if err := clone.BindSavepoint(ctx, currTx); err != nil {
+			return nil, err
+		}
+		return sqladapter.NewDatabaseTx(clone), nil
+	}
+
 	connFn := func() error {
 		sqlTx, err := compat.BeginTx(clone.BaseDatabase.Session(), ctx, clone.TxOptions())
 		if err == nil {
@@ -259,6 +465,105 @@ func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, er
 	return sqladapter.NewDatabaseTx(clone), nil
 }
 
+// ReplicaLag returns how far the given replica is behind the primary server.
+// A replica that has replayed all the WAL it received is not behind, even if
+// the last transaction it replayed is old.
+func (d *database) ReplicaLag(ctx context.Context, sess *sql.DB) (time.Duration, error) {
+	row := compat.QueryRowContext(sess, ctx, `
+		SELECT CASE
+			WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+		END`, nil)
+
+	var seconds float64
+	if err := row.Scan(&seconds); err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// IsPrimary returns true if the server of the given session is not in
+// recovery, and so it accepts writes.
+func (d *database) IsPrimary(ctx context.Context, sess *sql.DB) (bool, error) {
+	var recovery bool
+	if err := compat.QueryRowContext(sess, ctx, "SELECT pg_is_in_recovery()", nil).Scan(&recovery); err != nil {
+		return false, err
+	}
+	return !recovery, nil
+}
+
+// ExplainQuery returns the statement that shows the plan of query, it's
+// returned by Explain and logged along with slow queries.
+func (d *database) ExplainQuery(query string) string {
+	return "EXPLAIN " + query
+}
+
+// ExplainJSONQuery is like ExplainQuery but the plan is shown in JSON format.
+func (d *database) ExplainJSONQuery(query string) string {
+	return "EXPLAIN (FORMAT JSON) " + query
+}
+
+// TableRowsEstimateQuery returns the statement that selects the number of
+// rows of the table estimated by the last VACUUM or ANALYZE, it's -1 if the
+// table was never analyzed.
+func (d *database) TableRowsEstimateQuery(table string) (string, []interface{}) {
+	chunks := strings.Split(table, ".")
+	for i := range chunks {
+		chunks[i] = quoteIdentifier(chunks[i])
+	}
+	return "SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass(?)", []interface{}{strings.Join(chunks, ".")}
+}
+
+// PlanRows returns the number of rows the given plan estimates its query
+// returns.
+func (d *database) PlanRows(plan string) (uint64, error) {
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		}
+	}
+	if err := json.Unmarshal([]byte(plan), &plans); err != nil {
+		return 0, err
+	}
+	if len(plans) == 0 {
+		return 0, nil
+	}
+	return uint64(plans[0].Plan.Rows), nil
+}
+
+// DeclareCursorQuery returns the statement that declares a cursor for query,
+// it's used by Selector.Cursor.
+func (d *database) DeclareCursorQuery(name string, query string) string {
+	return "DECLARE " + quoteIdentifier(name) + " NO SCROLL CURSOR FOR " + query
+}
+
+// FetchCursorQuery returns the statement that reads the next size rows of
+// the given cursor.
+func (d *database) FetchCursorQuery(name string, size int) string {
+	return fmt.Sprintf("FETCH FORWARD %d FROM %s", size, quoteIdentifier(name))
+}
+
+// CloseCursorQuery returns the statement that closes the given cursor.
+func (d *database) CloseCursorQuery(name string) string {
+	return "CLOSE " + quoteIdentifier(name)
+}
+
+// TruncateStatements returns the given Truncate statement, PostgreSQL honours
+// every option of Truncate.
+func (d *database) TruncateStatements(table string, stmt *exql.Statement) ([]*exql.Statement, error) {
+	return []*exql.Statement{stmt}, nil
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// ParseURL parses the given DSN into a ConnectionURL, so that sqladapter can
+// replace its credentials.
+func (d *database) ParseURL(dsn string) (db.ConnectionURL, error) {
+	return ParseURL(dsn)
+}
+
 // LookupName looks for the name of the database and it's often used as a
 // test to determine if the connection settings are valid.
 func (d *database) LookupName() (string, error) {
@@ -337,8 +642,94 @@ func (d *database) PrimaryKeys(tableName string) ([]string, error) {
 	return pk, nil
 }
 
+// DescribeTable returns the columns, indexes and foreign keys of the given
+// table.
+func (d *database) DescribeTable(tableName string) (*sqlbuilder.Table, error) {
+	table := &sqlbuilder.Table{Name: tableName}
+
+	var columns []struct {
+		Name     string  `db:"column_name"`
+		Type     string  `db:"data_type"`
+		Nullable string  `db:"is_nullable"`
+		Default  *string `db:"column_default"`
+	}
+	err := d.Select("column_name", "data_type", "is_nullable", "column_default").
+		From("information_schema.columns").
+		Where("table_schema = ? AND table_name = ?", "public", tableName).
+		OrderBy("ordinal_position").
+		All(&columns)
+	if err != nil {
+		return nil, err
+	}
+	for _, column := range columns {
+		table.Columns = append(table.Columns, sqlbuilder.Column{
+			Name:     column.Name,
+			Type:     column.Type,
+			Nullable: column.Nullable == "YES",
+			Default:  column.Default,
+		})
+	}
+
+	if table.PrimaryKey, err = d.PrimaryKeys(tableName); err != nil {
+		return nil, err
+	}
+
+	var indexColumns []sqladapter.IndexColumn
+	rows, err := d.Query(`
+		SELECT i.relname AS index_name, a.attname AS column_name, ix.indisunique AS is_unique
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_attribute a ON a.attrelid = ix.indrelid AND a.attnum = ANY(ix.indkey)
+		WHERE ix.indrelid = '` + quotedTableName(tableName) + `'::regclass AND NOT ix.indisprimary
+		ORDER BY i.relname, array_position(ix.indkey::int2[], a.attnum)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	if err := sqlbuilder.NewIterator(rows).All(&indexColumns); err != nil {
+		return nil, err
+	}
+	table.Indexes = sqladapter.GroupIndexes(indexColumns)
+
+	var foreignKeyColumns []sqladapter.ForeignKeyColumn
+	rows, err = d.Query(`
+		SELECT c.conname AS constraint_name, a.attname AS column_name, rt.relname AS ref_table, ra.attname AS ref_column
+		FROM pg_constraint c
+		JOIN LATERAL unnest(c.conkey, c.confkey) WITH ORDINALITY AS k(col, refcol, n) ON true
+		JOIN pg_attribute a ON a.attrelid = c.conrelid AND a.attnum = k.col
+		JOIN pg_class rt ON rt.oid = c.confrelid
+		JOIN pg_attribute ra ON ra.attrelid = c.confrelid AND ra.attnum = k.refcol
+		WHERE c.contype = 'f' AND c.conrelid = '` + quotedTableName(tableName) + `'::regclass
+		ORDER BY c.conname, k.n
+	`)
+	if err != nil {
+		return nil, err
+	}
+	if err := sqlbuilder.NewIterator(rows).All(&foreignKeyColumns); err != nil {
+		return nil, err
+	}
+	table.ForeignKeys = sqladapter.GroupForeignKeys(foreignKeyColumns)
+
+	return table, nil
+}
+
 // WithContext creates a copy of the session on the given context.
 func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
 	newDB, _ := d.clone(ctx, false)
 	return newDB
 }
+
+// ReadOnly creates a copy of the session that can't change the database.
+func (d *database) ReadOnly() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UseReadOnly()
+	return newDB
+}
+
+// Primary returns a copy of the session that sends every statement to the
+// primary server.
+func (d *database) Primary() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UsePrimary()
+	return newDB
+}
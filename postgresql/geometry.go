@@ -0,0 +1,119 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/lib/geo"
+	"upper.io/db.v3/lib/sqlbuilder"
+)
+
+// Geometry represents a PostGIS geometry or geography value. Geometry is
+// bound as hex-encoded EWKB and can be scanned from hex-encoded or binary
+// EWKB, which are the formats PostGIS uses. Geometry satisfies
+// sqlbuilder.ScannerValuer.
+type Geometry struct {
+	geo.Geometry
+}
+
+// Scan satisfies the sql.Scanner interface.
+func (g *Geometry) Scan(src interface{}) error {
+	var b []byte
+
+	switch v := src.(type) {
+	case nil:
+		g.Geometry = geo.Geometry{}
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return errors.New("Scan source was not []byte or string")
+	}
+
+	// Binary EWKB starts with the byte order mark (0 or 1) while its hex
+	// encoded form starts with a "0" character.
+	if len(b) > 0 && b[0] == '0' {
+		decoded := make([]byte, hex.DecodedLen(len(b)))
+		if _, err := hex.Decode(decoded, b); err != nil {
+			return err
+		}
+		b = decoded
+	}
+
+	geom, err := geo.ParseWKB(b)
+	if err != nil {
+		return err
+	}
+	g.Geometry = geom
+	return nil
+}
+
+// Value satisfies the driver.Valuer interface.
+func (g Geometry) Value() (driver.Value, error) {
+	if g.IsZero() {
+		return nil, nil
+	}
+	b, err := g.EWKB()
+	if err != nil {
+		return nil, err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// DWithin is a comparison that matches rows whose geometry or geography is
+// within distance of g, using ST_DWithin. The distance is given in units of
+// the spatial reference system for geometries and in meters for
+// geographies.
+//
+// Example:
+//
+//	// ST_DWithin("location", $1, $2)
+//	db.Cond{"location": postgresql.DWithin(point, 500)}
+func DWithin(g geo.Geometry, distance float64) db.Comparison {
+	return db.Op("ST_DWithin(:column, ?, ?)", []interface{}{Geometry{g}, distance})
+}
+
+// ContainsGeometry is a comparison that matches rows whose geometry contains
+// g, using ST_Contains.
+func ContainsGeometry(g geo.Geometry) db.Comparison {
+	return db.Op("ST_Contains(:column, ?)", Geometry{g})
+}
+
+// WithinGeometry is a comparison that matches rows whose geometry is within
+// g, using ST_Within.
+func WithinGeometry(g geo.Geometry) db.Comparison {
+	return db.Op("ST_Within(:column, ?)", Geometry{g})
+}
+
+// Intersects is a comparison that matches rows whose geometry or geography
+// intersects g, using ST_Intersects.
+func Intersects(g geo.Geometry) db.Comparison {
+	return db.Op("ST_Intersects(:column, ?)", Geometry{g})
+}
+
+var _ sqlbuilder.ScannerValuer = &Geometry{}
@@ -0,0 +1,290 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"upper.io/db.v3"
+)
+
+const (
+	listenerMinReconnectInterval = time.Second
+	listenerMaxReconnectInterval = time.Minute
+
+	// notificationBufferSize is the number of notifications that are kept
+	// for a subscriber that is not reading them fast enough.
+	notificationBufferSize = 32
+)
+
+var errListenerClosed = errors.New("upper: listener is closed")
+
+// Notification is a message that was sent to a channel with NOTIFY.
+type Notification struct {
+	Channel string
+	Payload string
+	// PID is the process ID of the server session that sent the
+	// notification.
+	PID int
+}
+
+type subscription struct {
+	mu   sync.Mutex
+	ch   chan Notification
+	done chan struct{}
+	once sync.Once
+}
+
+func (s *subscription) close() {
+	s.once.Do(func() {
+		// Closing done first releases a send that is blocked on ch, the lock
+		// makes sure no send is in progress when ch gets closed.
+		close(s.done)
+		s.mu.Lock()
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+func (s *subscription) send(n Notification, closing <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	select {
+	case s.ch <- n:
+	case <-s.done:
+	case <-closing:
+	}
+}
+
+// Listener receives the notifications that are sent with NOTIFY on one or
+// more channels. Listener keeps its own connection to the server, it
+// reconnects automatically when the connection is lost and listens again on
+// all channels; notifications that are sent while it is disconnected are
+// lost.
+type Listener struct {
+	l *pq.Listener
+
+	mu     sync.Mutex
+	subs   map[string][]*subscription
+	closed bool
+
+	done chan struct{}
+}
+
+// NewListener connects to the server described by settings, the same
+// settings that are given to Open, and returns a Listener.
+//
+//	listener, err := postgresql.NewListener(settings)
+//	...
+//	notifications, err := listener.Listen("jobs")
+//	...
+//	for n := range notifications {
+//	  log.Printf("got %q on %q", n.Payload, n.Channel)
+//	}
+func NewListener(settings db.ConnectionURL) (*Listener, error) {
+	connected := make(chan error, 1)
+	var once sync.Once
+
+	l := pq.NewListener(
+		settings.String(),
+		listenerMinReconnectInterval,
+		listenerMaxReconnectInterval,
+		func(event pq.ListenerEventType, err error) {
+			switch event {
+			case pq.ListenerEventConnected:
+				once.Do(func() { connected <- nil })
+			case pq.ListenerEventConnectionAttemptFailed:
+				once.Do(func() { connected <- err })
+			}
+		},
+	)
+
+	if err := <-connected; err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	ln := &Listener{
+		l:    l,
+		subs: make(map[string][]*subscription),
+		done: make(chan struct{}),
+	}
+	go ln.dispatch()
+
+	return ln, nil
+}
+
+// Listen starts listening on the given channel and returns a Go channel that
+// receives its notifications. The Go channel is closed by Unlisten or Close.
+func (ln *Listener) Listen(channel string) (<-chan Notification, error) {
+	return ln.ListenContext(context.Background(), channel)
+}
+
+// ListenContext is like Listen but the returned Go channel is also closed
+// when ctx is done.
+func (ln *Listener) ListenContext(ctx context.Context, channel string) (<-chan Notification, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	if ln.closed {
+		return nil, errListenerClosed
+	}
+
+	if len(ln.subs[channel]) == 0 {
+		if err := ln.l.Listen(channel); err != nil && err != pq.ErrChannelAlreadyOpen {
+			return nil, err
+		}
+	}
+
+	sub := &subscription{
+		ch:   make(chan Notification, notificationBufferSize),
+		done: make(chan struct{}),
+	}
+	ln.subs[channel] = append(ln.subs[channel], sub)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			ln.unsubscribe(channel, sub)
+		case <-sub.done:
+		}
+	}()
+
+	return sub.ch, nil
+}
+
+// Unlisten stops listening on the given channel and closes all the Go
+// channels that were returned by Listen for it.
+func (ln *Listener) Unlisten(channel string) error {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	if ln.closed {
+		return errListenerClosed
+	}
+
+	subs := ln.subs[channel]
+	delete(ln.subs, channel)
+	for _, sub := range subs {
+		sub.close()
+	}
+
+	if err := ln.l.Unlisten(channel); err != nil && err != pq.ErrChannelNotOpen {
+		return err
+	}
+	return nil
+}
+
+// Close stops listening on all channels, closes all the Go channels that were
+// returned by Listen and closes the connection to the server.
+func (ln *Listener) Close() error {
+	ln.mu.Lock()
+	if ln.closed {
+		ln.mu.Unlock()
+		return nil
+	}
+	ln.closed = true
+	close(ln.done)
+	subs := ln.subs
+	ln.subs = nil
+	ln.mu.Unlock()
+
+	for _, channelSubs := range subs {
+		for _, sub := range channelSubs {
+			sub.close()
+		}
+	}
+
+	return ln.l.Close()
+}
+
+func (ln *Listener) unsubscribe(channel string, sub *subscription) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+
+	if ln.closed {
+		return
+	}
+
+	subs := ln.subs[channel]
+	for i := range subs {
+		if subs[i] == sub {
+			subs = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	sub.close()
+
+	if len(subs) > 0 {
+		ln.subs[channel] = subs
+		return
+	}
+
+	delete(ln.subs, channel)
+	// The channel may be listened on again later, an error here is not fatal.
+	_ = ln.l.Unlisten(channel)
+}
+
+func (ln *Listener) dispatch() {
+	for {
+		select {
+		case <-ln.done:
+			return
+		case n, ok := <-ln.l.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				// The connection was reestablished.
+				continue
+			}
+
+			ln.mu.Lock()
+			subs := make([]*subscription, len(ln.subs[n.Channel]))
+			copy(subs, ln.subs[n.Channel])
+			ln.mu.Unlock()
+
+			for _, sub := range subs {
+				sub.send(Notification{
+					Channel: n.Channel,
+					Payload: n.Extra,
+					PID:     n.BePid,
+				}, ln.done)
+			}
+		}
+	}
+}
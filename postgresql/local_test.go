@@ -1,8 +1,10 @@
 package postgresql
 
 import (
+	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"upper.io/db.v3"
@@ -280,3 +282,184 @@ func TestNonTrivialSubqueries(t *testing.T) {
 		assert.NotNil(t, res)
 	}
 }
+
+func TestListener(t *testing.T) {
+	sess := mustOpen()
+	defer sess.Close()
+
+	listener, err := NewListener(settings)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	jobs, err := listener.Listen("upper_jobs")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := listener.ListenContext(ctx, "upper_events")
+	assert.NoError(t, err)
+
+	_, err = sess.Exec(`SELECT pg_notify('upper_jobs', 'job-1')`)
+	assert.NoError(t, err)
+
+	select {
+	case n := <-jobs:
+		assert.Equal(t, "upper_jobs", n.Channel)
+		assert.Equal(t, "job-1", n.Payload)
+		assert.NotZero(t, n.PID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a notification")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expecting the channel to be closed after cancelling the context")
+	}
+
+	assert.NoError(t, listener.Unlisten("upper_jobs"))
+	_, ok := <-jobs
+	assert.False(t, ok)
+
+	assert.NoError(t, listener.Close())
+	_, err = listener.Listen("upper_jobs")
+	assert.Error(t, err)
+}
+
+func TestTenancyWithoutIDGenerator(t *testing.T) {
+	sess := mustOpen()
+	driver := sess.Driver().(*sql.DB)
+
+	defer func() {
+		driver.Exec(`DROP TABLE IF EXISTS tenant_items`)
+		sess.Close()
+	}()
+
+	_, err := driver.Exec(`
+		CREATE TABLE tenant_items (
+			id serial primary key,
+			tenant_id integer,
+			name varchar(64)
+		)`)
+	assert.NoError(t, err)
+
+	type tenantItem struct {
+		ID       int64  `db:"id,omitempty"`
+		TenantID int64  `db:"tenant_id,omitempty"`
+		Name     string `db:"name"`
+	}
+
+	tenancy := db.NewTenancy("")
+	tenancy.Register("tenant_items")
+	sess.SetTenancy(tenancy)
+
+	// The table has no ID generator, the tenant is set all the same.
+	_, err = sess.Collection("tenant_items").Insert(tenantItem{Name: "Flea"})
+	assert.Equal(t, db.ErrMissingTenant, err)
+
+	first := sess.(*database).WithContext(db.WithTenant(context.Background(), 1))
+	second := sess.(*database).WithContext(db.WithTenant(context.Background(), 2))
+
+	_, err = first.Collection("tenant_items").Insert(tenantItem{TenantID: 2, Name: "Flea"})
+	assert.NoError(t, err)
+	_, err = second.Collection("tenant_items").Insert(tenantItem{Name: "Slash"})
+	assert.NoError(t, err)
+
+	var items []tenantItem
+	err = sess.(*database).WithContext(db.WithoutTenant(context.Background())).
+		Collection("tenant_items").Find().OrderBy("name").All(&items)
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(items)) {
+		assert.Equal(t, int64(1), items[0].TenantID)
+		assert.Equal(t, int64(2), items[1].TenantID)
+	}
+
+	// Statements made with the SQL builder are scoped as well.
+	items = nil
+	err = first.SelectFrom("tenant_items").All(&items)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(items)) {
+		assert.Equal(t, "Flea", items[0].Name)
+	}
+
+	// The tenant column is left out of updates.
+	err = first.Collection("tenant_items").Find().Update(tenantItem{TenantID: 2, Name: "Anthony"})
+	assert.NoError(t, err)
+
+	count, err := second.Collection("tenant_items").Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	_, err = first.Update("tenant_items").Set("tenant_id", 2).Exec()
+	assert.Equal(t, db.ErrTenantColumn, err)
+}
+
+func TestSoftDelete(t *testing.T) {
+	sess := mustOpen()
+	driver := sess.Driver().(*sql.DB)
+
+	defer func() {
+		driver.Exec(`DROP TABLE IF EXISTS soft_items`)
+		sess.Close()
+	}()
+
+	_, err := driver.Exec(`
+		CREATE TABLE soft_items (
+			id serial primary key,
+			name varchar(64),
+			deleted_at timestamp with time zone
+		)`)
+	assert.NoError(t, err)
+
+	type softItem struct {
+		ID        int64      `db:"id,omitempty"`
+		Name      string     `db:"name"`
+		DeletedAt *time.Time `db:"deleted_at,softdelete"`
+	}
+
+	softDeletes := db.NewSoftDeletes()
+	assert.True(t, softDeletes.RegisterModel("soft_items", softItem{}))
+	sess.SetSoftDeletes(softDeletes)
+
+	items := sess.Collection("soft_items")
+	for _, name := range []string{"Flea", "Slash", "Axl"} {
+		_, err := items.Insert(softItem{Name: name})
+		assert.NoError(t, err)
+	}
+
+	// Deleting sets the soft delete column instead of removing rows.
+	err = items.Find(db.Cond{"name": "Flea"}).Delete()
+	assert.NoError(t, err)
+
+	count, err := items.Find().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), count)
+
+	count, err = items.Find().Unscoped().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), count)
+
+	var deleted []softItem
+	err = items.Find().OnlyDeleted().All(&deleted)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(deleted)) {
+		assert.Equal(t, "Flea", deleted[0].Name)
+		assert.NotNil(t, deleted[0].DeletedAt)
+	}
+
+	var returned []softItem
+	err = items.Find(db.Cond{"name": "Slash"}).DeleteReturning(&returned)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(returned)) {
+		assert.NotNil(t, returned[0].DeletedAt)
+	}
+
+	// Unscoped result sets remove rows permanently.
+	err = items.Find().Unscoped().Delete()
+	assert.NoError(t, err)
+
+	count, err = items.Find().Unscoped().Count()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), count)
+}
@@ -56,6 +56,44 @@ func Open(settings db.ConnectionURL) (sqlbuilder.Database, error) {
 	return d, nil
 }
 
+// OpenCluster opens a session on a primary PostgreSQL server and its read-only
+// replicas. SELECT statements are sent to the replicas according to the given
+// policy while writes and transactions are sent to the primary server.
+func OpenCluster(primary db.ConnectionURL, replicas []db.ConnectionURL, policy sqlbuilder.ReplicaPolicy) (sqlbuilder.Cluster, error) {
+	d := newDatabase(primary)
+	if err := d.Open(primary); err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*sql.DB, 0, len(replicas))
+	closeAll := func() {
+		for _, sess := range sessions {
+			sess.Close()
+		}
+		d.Close()
+	}
+
+	for i := range replicas {
+		if err := setupTLS(replicas[i]); err != nil {
+			closeAll()
+			return nil, err
+		}
+		sess, err := d.BaseDatabase.OpenSession(sqlDriver, replicas[i].String())
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+
+	if err := d.BaseDatabase.BindReplicas(policy, sessions...); err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	return d, nil
+}
+
 // NewTx wraps a regular *sql.Tx transaction and returns a new upper-db
 // transaction backed by it.
 func NewTx(sqlTx *sql.Tx) (sqlbuilder.Tx, error) {
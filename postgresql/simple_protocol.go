@@ -0,0 +1,176 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package postgresql
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter"
+)
+
+// SimpleProtocolOption is the name of the ConnectionURL option that enables
+// the simple protocol mode:
+//
+//	settings.Options[postgresql.SimpleProtocolOption] = "yes"
+//
+// Transaction-pooling proxies like PgBouncer may hand each statement to a
+// different server connection, so prepared statements created on one of them
+// can't be used later. In simple protocol mode the prepared statement cache
+// is disabled and arguments are interpolated into the query before it's sent,
+// which makes github.com/lib/pq send statements without preparing them.
+const SimpleProtocolOption = `simple_protocol`
+
+var errPlaceholderMismatch = errors.New(`upper: the number of placeholders does not match the number of arguments`)
+
+// simpleProtocolEnabled reports whether the given connection URL enables the
+// simple protocol mode, connection URLs of other types than ConnectionURL are
+// parsed from their DSN.
+func simpleProtocolEnabled(connURL db.ConnectionURL) bool {
+	var options map[string]string
+
+	switch connURL.(type) {
+	case nil, ConnectionURL, *ConnectionURL:
+		options = sqladapter.ConnectionOptions(connURL)
+	default:
+		parsed, err := ParseURL(connURL.String())
+		if err != nil {
+			return false
+		}
+		options = parsed.Options
+	}
+
+	switch strings.ToLower(options[SimpleProtocolOption]) {
+	case "yes", "true", "on", "1":
+		return true
+	}
+	return false
+}
+
+// interpolateArgs replaces each '?' placeholder in query with its argument
+// as a literal, '??' is an escaped '?'.
+func interpolateArgs(query string, args []interface{}) (string, error) {
+	buf := []byte(query)
+	out := make([]byte, 0, len(buf))
+
+	i, j, k, t := 0, 0, 0, len(buf)
+
+	for i < t {
+		if buf[i] == '?' {
+			out = append(out, buf[k:i]...)
+			k = i + 1
+
+			if k < t && buf[k] == '?' {
+				i = k
+			} else {
+				if j >= len(args) {
+					return "", errPlaceholderMismatch
+				}
+				literal, err := quoteLiteral(args[j])
+				if err != nil {
+					return "", err
+				}
+				out = append(out, literal...)
+				j++
+			}
+		}
+		i++
+	}
+	out = append(out, buf[k:i]...)
+
+	if j != len(args) {
+		return "", errPlaceholderMismatch
+	}
+
+	return string(out), nil
+}
+
+// quoteLiteral returns the given value as a SQL literal. Strings and other
+// quoted values are left untyped, so the server infers their type from the
+// context they're used in, as it does with parameters.
+func quoteLiteral(v interface{}) (string, error) {
+	v, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int64:
+		return quoteNumber(strconv.FormatInt(v, 10)), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "'NaN'", nil
+		case math.IsInf(v, 1):
+			return "'Infinity'", nil
+		case math.IsInf(v, -1):
+			return "'-Infinity'", nil
+		}
+		return quoteNumber(strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case string:
+		return quoteString(v)
+	case []byte:
+		// Hex format, the escape string syntax keeps the backslash regardless
+		// of standard_conforming_strings.
+		return `E'\\x` + hex.EncodeToString(v) + `'`, nil
+	case time.Time:
+		return quoteString(v.Format("2006-01-02 15:04:05.999999999Z07:00"))
+	}
+
+	return "", errors.New("upper: unsupported argument type")
+}
+
+// quoteNumber wraps negative numbers in parentheses, so the sign can't be
+// taken as part of an operator or a comment.
+func quoteNumber(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// quoteString returns s as a string literal. Strings with backslashes use the
+// escape string syntax, so they're read the same way regardless of
+// standard_conforming_strings.
+func quoteString(s string) (string, error) {
+	if strings.IndexByte(s, 0) >= 0 {
+		return "", errors.New("upper: strings can't contain null characters")
+	}
+	s = strings.Replace(s, "'", "''", -1)
+	if strings.IndexByte(s, '\\') >= 0 {
+		return "E'" + strings.Replace(s, `\`, `\\`, -1) + "'", nil
+	}
+	return "'" + s + "'", nil
+}
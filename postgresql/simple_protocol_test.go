@@ -0,0 +1,72 @@
+package postgresql
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolateArgs(t *testing.T) {
+	ts := time.Date(2019, time.March, 4, 5, 6, 7, 800000000, time.UTC)
+
+	testCases := []struct {
+		query string
+		args  []interface{}
+		out   string
+	}{
+		{`SELECT * FROM "artist" WHERE "id" = ?`, []interface{}{1}, `SELECT * FROM "artist" WHERE "id" = 1`},
+		{`SELECT ? - ?`, []interface{}{1, -1}, `SELECT 1 - (-1)`},
+		{`SELECT ?, ?, ?`, []interface{}{nil, true, false}, `SELECT NULL, TRUE, FALSE`},
+		{`SELECT ?, ?`, []interface{}{1.5, math.Inf(-1)}, `SELECT 1.5, '-Infinity'`},
+		{`SELECT ?`, []interface{}{"O'Brien"}, `SELECT 'O''Brien'`},
+		{`SELECT ?`, []interface{}{`\'; DROP TABLE "artist"; --`}, `SELECT E'\\''; DROP TABLE "artist"; --'`},
+		{`SELECT ?`, []interface{}{[]byte{0xde, 0xad}}, `SELECT E'\\xdead'`},
+		{`SELECT ?`, []interface{}{ts}, `SELECT '2019-03-04 05:06:07.8Z'`},
+		{`SELECT ?`, []interface{}{Int64Array{1, 2}}, `SELECT '{1,2}'`},
+		{`SELECT '{"a":1}'::jsonb ?? ?`, []interface{}{"a"}, `SELECT '{"a":1}'::jsonb ? 'a'`},
+	}
+
+	for _, tc := range testCases {
+		out, err := interpolateArgs(tc.query, tc.args)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.out, out)
+	}
+
+	{
+		_, err := interpolateArgs(`SELECT ?, ?`, []interface{}{1})
+		assert.Equal(t, errPlaceholderMismatch, err)
+
+		_, err = interpolateArgs(`SELECT ?`, []interface{}{1, 2})
+		assert.Equal(t, errPlaceholderMismatch, err)
+
+		_, err = interpolateArgs(`SELECT ?`, []interface{}{"a\x00b"})
+		assert.Error(t, err)
+	}
+}
+
+type dsnURL string
+
+func (u dsnURL) String() string {
+	return string(u)
+}
+
+func TestSimpleProtocolEnabled(t *testing.T) {
+	assert.False(t, simpleProtocolEnabled(nil))
+	assert.False(t, simpleProtocolEnabled(ConnectionURL{}))
+	assert.True(t, simpleProtocolEnabled(ConnectionURL{Options: map[string]string{SimpleProtocolOption: "yes"}}))
+	assert.False(t, simpleProtocolEnabled(ConnectionURL{Options: map[string]string{SimpleProtocolOption: "no"}}))
+	assert.False(t, simpleProtocolEnabled((*ConnectionURL)(nil)))
+	assert.True(t, simpleProtocolEnabled(&ConnectionURL{Options: map[string]string{SimpleProtocolOption: "on"}}))
+
+	// Other connection URLs are parsed from their DSN.
+	assert.True(t, simpleProtocolEnabled(dsnURL("host=localhost dbname=mydb simple_protocol=yes")))
+	assert.False(t, simpleProtocolEnabled(dsnURL("host=localhost dbname=mydb")))
+
+	u := ConnectionURL{
+		Host:    "localhost",
+		Options: map[string]string{SimpleProtocolOption: "yes"},
+	}
+	assert.Equal(t, `host=localhost sslmode=disable`, u.String())
+}
@@ -44,6 +44,14 @@ const (
 	adapterTableAliasLayout    = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	adapterColumnAliasLayout   = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	adapterSortByColumnLayout  = `{{.Column}} {{.Order}}`
+	adapterWindowLayout        = `{{.Function}} OVER ({{.Definition}})`
+	adapterJSONPathLayout      = `{{.Column}}{{range .Segments}}{{if .Last}}->>{{else}}->{{end}}{{if .Index}}{{.Key}}{{else}}'{{.Key}}'{{end}}{{end}}`
+	adapterFullTextLayout      = `to_tsvector({{.Column}}) @@ plainto_tsquery(?)`
+	adapterFullTextRankLayout  = `ts_rank(to_tsvector({{.Column}}), plainto_tsquery(?)) DESC`
+	adapterDistinctOnLayout    = `DISTINCT ON ({{.Columns}})`
+	adapterLockLayout          = `{{if .Update}}FOR UPDATE{{else}}FOR SHARE{{end}}{{if .SkipLocked}} SKIP LOCKED{{end}}{{if .NoWait}} NOWAIT{{end}}`
+	adapterUpdateFromLayout    = `{{if .Tables}}FROM {{.Tables}}{{end}} {{.Joins}}`
+	adapterDeleteUsingLayout   = `{{if .Tables}}USING {{.Tables}}{{end}} {{.Joins}}`
 
 	adapterOrderByLayout = `
     {{if .SortColumns}}
@@ -85,27 +93,45 @@ const (
     {{end}}
   `
 
+	adapterWithLayout = `
+    WITH {{if .Recursive}}RECURSIVE {{end}}{{.CTEs}}
+  `
+
+	adapterCompoundLayout = `
+    {{range .}}{{if .Operator}} {{.Operator}} {{end}}({{.Query}}){{end}}
+  `
+
 	adapterSelectLayout = `
-    SELECT
-      {{if .Distinct}}
-        DISTINCT
-      {{end}}
+    {{if .With}}
+      {{.With}}
+    {{end}}
 
-      {{if .Columns}}
-        {{.Columns}}
-      {{else}}
-        *
-      {{end}}
+    {{if .Compound}}
+      {{.Compound}}
+    {{else}}
+      SELECT
+        {{if .DistinctOn}}
+          {{.DistinctOn}}
+        {{else if .Distinct}}
+          DISTINCT
+        {{end}}
 
-      {{if .Table}}
-        FROM {{.Table}}
-      {{end}}
+        {{if .Columns}}
+          {{.Columns}}
+        {{else}}
+          *
+        {{end}}
 
-      {{.Joins}}
+        {{if .Table}}
+          FROM {{.Table}}
+        {{end}}
 
-      {{.Where}}
+        {{.Joins}}
 
-      {{.GroupBy}}
+        {{.Where}}
+
+        {{.GroupBy}}
+    {{end}}
 
       {{.OrderBy}}
 
@@ -116,17 +142,29 @@ const (
       {{if .Offset}}
         OFFSET {{.Offset}}
       {{end}}
+
+      {{if .Lock}}
+        {{.Lock}}
+      {{end}}
   `
 	adapterDeleteLayout = `
     DELETE
       FROM {{.Table}}
+      {{.From}}
       {{.Where}}
+    {{if .Returning}}
+      RETURNING {{.Returning}}
+    {{end}}
   `
 	adapterUpdateLayout = `
     UPDATE
       {{.Table}}
     SET {{.ColumnValues}}
+      {{.From}}
       {{ .Where }}
+    {{if .Returning}}
+      RETURNING {{.Returning}}
+    {{end}}
   `
 
 	adapterSelectCountLayout = `
@@ -139,19 +177,41 @@ const (
 	adapterInsertLayout = `
     INSERT INTO {{.Table}}
       {{if .Columns }}({{.Columns}}){{end}}
-    VALUES
-    {{if .Values}}
-      {{.Values}}
+    {{if .Query}}
+      {{.Query}}
     {{else}}
-      (default)
+      VALUES
+      {{if .Values}}
+        {{.Values}}
+      {{else}}
+        (default)
+      {{end}}
+    {{end}}
+    {{if .OnConflict}}
+      {{.OnConflict}}
     {{end}}
     {{if .Returning}}
       RETURNING {{.Returning}}
     {{end}}
   `
 
+	adapterOnConflictLayout = `
+    ON CONFLICT
+      {{if .Target}}({{range $i, $c := .Target}}{{if $i}}, {{end}}{{$c}}{{end}}){{end}}
+    {{if .Update}}
+      DO UPDATE SET {{.Update}}
+    {{else}}
+      DO NOTHING
+    {{end}}
+  `
+
+	adapterExcludedColumn = `EXCLUDED.{{.}}`
+
 	adapterTruncateLayout = `
     TRUNCATE TABLE {{.Table}} RESTART IDENTITY
+    {{if .Cascade}}
+      CASCADE
+    {{end}}
   `
 
 	adapterDropDatabaseLayout = `
@@ -162,6 +222,38 @@ const (
     DROP TABLE {{.Table}}
   `
 
+	adapterCreateTableLayout = `
+    CREATE TABLE {{.Table}} ({{.Definitions}})
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table}} {{.Definitions}}
+  `
+
+	adapterCreateIndexLayout = `
+    CREATE {{if .Unique}}UNIQUE {{end}}INDEX {{.Index}} ON {{.Table}} ({{.Columns}})
+  `
+
+	adapterCallLayout = `
+    CALL {{.Table}}({{.Columns}})
+  `
+
+	adapterCallOutLayout = `?`
+
+	adapterAddColumnLayout = `ADD COLUMN {{.}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.}}`
+
+	adapterColumnDefinitionLayout = `
+    {{.Name}}
+    {{if .AutoIncrement}}{{if eq .Type "BIGINT"}}BIGSERIAL{{else}}SERIAL{{end}}{{else}}{{.Type}}{{end}}
+    {{if .PrimaryKey}}PRIMARY KEY{{end}}
+    {{if .NotNull}}NOT NULL{{end}}
+    {{if .Unique}}UNIQUE{{end}}
+    {{if .Default}}DEFAULT {{.Default}}{{end}}
+    {{if .References}}REFERENCES {{.References}}{{end}}
+  `
+
 	adapterGroupByLayout = `
     {{if .GroupColumns}}
       GROUP BY {{.GroupColumns}}
@@ -169,38 +261,74 @@ const (
   `
 )
 
+var adapterColumnTypes = map[exql.ColumnType]string{
+	exql.TypeBoolean:    `BOOLEAN`,
+	exql.TypeInteger:    `INTEGER`,
+	exql.TypeBigInteger: `BIGINT`,
+	exql.TypeFloat:      `DOUBLE PRECISION`,
+	exql.TypeDecimal:    `NUMERIC({{.Precision}}, {{.Scale}})`,
+	exql.TypeString:     `VARCHAR({{.Size}})`,
+	exql.TypeText:       `TEXT`,
+	exql.TypeTimestamp:  `TIMESTAMP`,
+	exql.TypeDate:       `DATE`,
+	exql.TypeBinary:     `BYTEA`,
+	exql.TypeJSON:       `JSONB`,
+}
+
 var template = &exql.Template{
-	ColumnSeparator:     adapterColumnSeparator,
-	IdentifierSeparator: adapterIdentifierSeparator,
-	IdentifierQuote:     adapterIdentifierQuote,
-	ValueSeparator:      adapterValueSeparator,
-	ValueQuote:          adapterValueQuote,
-	AndKeyword:          adapterAndKeyword,
-	OrKeyword:           adapterOrKeyword,
-	DescKeyword:         adapterDescKeyword,
-	AscKeyword:          adapterAscKeyword,
-	AssignmentOperator:  adapterAssignmentOperator,
-	ClauseGroup:         adapterClauseGroup,
-	ClauseOperator:      adapterClauseOperator,
-	ColumnValue:         adapterColumnValue,
-	TableAliasLayout:    adapterTableAliasLayout,
-	ColumnAliasLayout:   adapterColumnAliasLayout,
-	SortByColumnLayout:  adapterSortByColumnLayout,
-	WhereLayout:         adapterWhereLayout,
-	JoinLayout:          adapterJoinLayout,
-	OnLayout:            adapterOnLayout,
-	UsingLayout:         adapterUsingLayout,
-	OrderByLayout:       adapterOrderByLayout,
-	InsertLayout:        adapterInsertLayout,
-	SelectLayout:        adapterSelectLayout,
-	UpdateLayout:        adapterUpdateLayout,
-	DeleteLayout:        adapterDeleteLayout,
-	TruncateLayout:      adapterTruncateLayout,
-	DropDatabaseLayout:  adapterDropDatabaseLayout,
-	DropTableLayout:     adapterDropTableLayout,
-	CountLayout:         adapterSelectCountLayout,
-	GroupByLayout:       adapterGroupByLayout,
-	Cache:               cache.NewCache(),
+	ColumnSeparator:        adapterColumnSeparator,
+	IdentifierSeparator:    adapterIdentifierSeparator,
+	IdentifierQuote:        adapterIdentifierQuote,
+	ValueSeparator:         adapterValueSeparator,
+	ValueQuote:             adapterValueQuote,
+	AndKeyword:             adapterAndKeyword,
+	OrKeyword:              adapterOrKeyword,
+	DescKeyword:            adapterDescKeyword,
+	AscKeyword:             adapterAscKeyword,
+	AssignmentOperator:     adapterAssignmentOperator,
+	ClauseGroup:            adapterClauseGroup,
+	ClauseOperator:         adapterClauseOperator,
+	ColumnValue:            adapterColumnValue,
+	TableAliasLayout:       adapterTableAliasLayout,
+	ColumnAliasLayout:      adapterColumnAliasLayout,
+	SortByColumnLayout:     adapterSortByColumnLayout,
+	WhereLayout:            adapterWhereLayout,
+	WindowLayout:           adapterWindowLayout,
+	CompoundLayout:         adapterCompoundLayout,
+	WithLayout:             adapterWithLayout,
+	JoinLayout:             adapterJoinLayout,
+	JSONPathLayout:         adapterJSONPathLayout,
+	FullTextLayout:         adapterFullTextLayout,
+	FullTextRankLayout:     adapterFullTextRankLayout,
+	DistinctOnLayout:       adapterDistinctOnLayout,
+	LockLayout:             adapterLockLayout,
+	OnLayout:               adapterOnLayout,
+	UsingLayout:            adapterUsingLayout,
+	OrderByLayout:          adapterOrderByLayout,
+	InsertLayout:           adapterInsertLayout,
+	OnConflictLayout:       adapterOnConflictLayout,
+	ConflictTargetNeeded:   true,
+	ExcludedColumn:         adapterExcludedColumn,
+	SelectLayout:           adapterSelectLayout,
+	UpdateLayout:           adapterUpdateLayout,
+	UpdateFromLayout:       adapterUpdateFromLayout,
+	DeleteUsingLayout:      adapterDeleteUsingLayout,
+	DeleteLayout:           adapterDeleteLayout,
+	TruncateLayout:         adapterTruncateLayout,
+	DropDatabaseLayout:     adapterDropDatabaseLayout,
+	DropTableLayout:        adapterDropTableLayout,
+	CreateTableLayout:      adapterCreateTableLayout,
+	AlterTableLayout:       adapterAlterTableLayout,
+	CreateIndexLayout:      adapterCreateIndexLayout,
+	CallLayout:             adapterCallLayout,
+	CallOutLayout:          adapterCallOutLayout,
+	AddColumnLayout:        adapterAddColumnLayout,
+	DropColumnLayout:       adapterDropColumnLayout,
+	ColumnDefinitionLayout: adapterColumnDefinitionLayout,
+	ColumnTypes:            adapterColumnTypes,
+	CountLayout:            adapterSelectCountLayout,
+	GroupByLayout:          adapterGroupByLayout,
+	Cache:                  cache.NewCache(),
 	ComparisonOperator: map[db.ComparisonOperator]string{
 		db.ComparisonOperatorRegExp:    "~",
 		db.ComparisonOperatorNotRegExp: "!~",
@@ -1,10 +1,13 @@
 package postgresql
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"upper.io/db.v3"
+	"upper.io/db.v3/internal/sqladapter/exql"
+	"upper.io/db.v3/lib/geo"
 	"upper.io/db.v3/lib/sqlbuilder"
 )
 
@@ -162,6 +165,141 @@ func TestTemplateSelect(t *testing.T) {
 	)
 }
 
+func TestTemplateDDL(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`CREATE TABLE "books" ("id" SERIAL PRIMARY KEY, "title" VARCHAR(80) NOT NULL, "price" NUMERIC(10, 2) DEFAULT 0, "author_id" INTEGER, FOREIGN KEY ("author_id") REFERENCES "authors" ("id"))`,
+		b.CreateTable("books").Columns(
+			sqlbuilder.NewColumn("id", sqlbuilder.Integer).PrimaryKey().AutoIncrement(),
+			sqlbuilder.NewColumn("title", sqlbuilder.String(80)).NotNull(),
+			sqlbuilder.NewColumn("price", sqlbuilder.Decimal(10, 2)).Default(0),
+			sqlbuilder.NewColumn("author_id", sqlbuilder.Integer).References("authors", "id"),
+		).String(),
+	)
+
+	assert.Equal(
+		`ALTER TABLE "books" ADD COLUMN "meta" JSONB`,
+		b.AlterTable("books").AddColumn(sqlbuilder.NewColumn("meta", sqlbuilder.JSON)).String(),
+	)
+
+	assert.Equal(
+		`CREATE UNIQUE INDEX "books_title_idx" ON "books" ("title")`,
+		b.CreateIndex("books_title_idx").On("books", "title").Unique().String(),
+	)
+}
+
+func TestTemplateJSONPath(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`SELECT * FROM "users" WHERE ("data"->'address'->>'city' = $1)`,
+		b.SelectFrom("users").Where(db.Cond{db.JSONPath("data.address.city"): "Paris"}).String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "users" WHERE ("data"->'tags'->>0 = $1)`,
+		b.SelectFrom("users").Where(db.Cond{db.JSONPath("data.tags.0"): "go"}).String(),
+	)
+}
+
+func TestTemplateArrayOperators(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	{
+		sel := b.SelectFrom("artist").Where(db.Cond{"id": Any([]int{1, 2, 3})})
+		assert.Equal(`SELECT * FROM "artist" WHERE ("id" = ANY($1))`, sel.String())
+		assert.Len(sel.Arguments(), 1)
+	}
+
+	assert.Equal(
+		`SELECT * FROM "artist" WHERE ("tags" @> $1)`,
+		b.SelectFrom("artist").Where(db.Cond{"tags": Contains([]string{"rock"})}).String(),
+	)
+
+	assert.Equal(
+		`SELECT * FROM "artist" WHERE ("tags" && $1)`,
+		b.SelectFrom("artist").Where(db.Cond{"tags": Overlaps([]string{"rock", "pop"})}).String(),
+	)
+}
+
+func TestTemplateFullText(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	search := db.FullText("body", "cats")
+	assert.Equal(
+		`SELECT * FROM "posts" WHERE (to_tsvector("body") @@ plainto_tsquery($1)) ORDER BY ts_rank(to_tsvector("body"), plainto_tsquery($2)) DESC`,
+		b.SelectFrom("posts").Where(search).OrderBy(search.Rank()).String(),
+	)
+}
+
+func TestTemplateDistinctOn(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`SELECT DISTINCT ON ("user_id") "user_id", "created_at" FROM "visits" ORDER BY "created_at" DESC`,
+		b.Select("user_id", "created_at").DistinctOn("user_id").From("visits").OrderBy("-created_at").String(),
+	)
+}
+
+func TestTemplateSpatial(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	point := geo.Geometry{SRID: 4326, Shape: geo.Point{X: 2.35, Y: 48.85}}
+
+	sel := b.SelectFrom("shops").Where(db.Cond{
+		"area":     ContainsGeometry(point),
+		"location": DWithin(point, 500),
+	})
+	assert.Equal(
+		`SELECT * FROM "shops" WHERE (ST_Contains("area", $1) AND ST_DWithin("location", $2, $3))`,
+		sel.String(),
+	)
+	assert.Equal([]interface{}{Geometry{point}, Geometry{point}, float64(500)}, sel.Arguments())
+	assert.Equal(
+		`SELECT * FROM "shops" WHERE ((ST_Intersects("area", $1) OR ST_Within("location", $2)))`,
+		b.SelectFrom("shops").Where(db.Or(
+			db.Cond{"area": Intersects(point)},
+			db.Cond{"location": WithinGeometry(point)},
+		)).String(),
+	)
+}
+
+func TestTemplateUpdateDeleteJoins(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`UPDATE "posts" SET "author_name" = users.name FROM "users" WHERE (posts.user_id = users.id)`,
+		b.Update("posts").Set("author_name = users.name").From("users").Where("posts.user_id = users.id").String(),
+	)
+
+	_, _, err := b.Update("posts").Set("title", "archived").Join("users").On("users.id = posts.user_id").Compile()
+	assert.Error(err)
+
+	assert.Equal(
+		`DELETE FROM "posts" USING "users" JOIN "teams" ON (teams.id = users.team_id) WHERE (posts.user_id = users.id AND teams.name = $1)`,
+		b.DeleteFrom("posts").Using("users").Join("teams").On("teams.id = users.team_id").Where("posts.user_id = users.id AND teams.name = ?", "spam").String(),
+	)
+}
+
+func TestTemplateCall(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	var total int
+	assert.Equal(
+		`CALL "order_total"($1, $2)`,
+		b.Call("order_total", 12, sqlbuilder.Out(&total)).String(),
+	)
+}
+
 func TestTemplateInsert(t *testing.T) {
 	b := sqlbuilder.WithTemplate(template)
 	assert := assert.New(t)
@@ -204,6 +342,37 @@ func TestTemplateInsert(t *testing.T) {
 	)
 }
 
+func TestTemplateUpsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	artist := map[string]interface{}{"id": 12, "name": "Chavela Vargas"}
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name"`,
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoUpdate().String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "name" = $3`,
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoUpdate("name", "Chavela").String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO NOTHING`,
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoNothing().String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		b.InsertInto("artist").Values(artist).OnConflict().DoNothing().String(),
+	)
+
+	// Conflicting rows can't be updated without a conflict target.
+	_, _, err := b.InsertInto("artist").Values(artist).OnConflict().DoUpdate().Compile()
+	assert.Error(err)
+}
+
 func TestTemplateUpdate(t *testing.T) {
 	b := sqlbuilder.WithTemplate(template)
 	assert := assert.New(t)
@@ -244,6 +413,11 @@ func TestTemplateUpdate(t *testing.T) {
 			"id = id + ?", 10,
 		).Where("id > ?", 0).String(),
 	)
+
+	assert.Equal(
+		`UPDATE "artist" SET "name" = $1 WHERE ("id" = $2) RETURNING *`,
+		b.Update("artist").Set("name", "Artist").Where(db.Cond{"id": 1}).Returning("*").String(),
+	)
 }
 
 func TestTemplateDelete(t *testing.T) {
@@ -259,4 +433,42 @@ func TestTemplateDelete(t *testing.T) {
 		`DELETE FROM "artist" WHERE (id > 5)`,
 		b.DeleteFrom("artist").Where("id > 5").String(),
 	)
+
+	assert.Equal(
+		`DELETE FROM "artist" WHERE (id > 5) RETURNING "id"`,
+		b.DeleteFrom("artist").Where("id > 5").Returning("id").String(),
+	)
+}
+
+func TestTemplateTruncate(t *testing.T) {
+	stmt := &exql.Statement{
+		Type:    exql.Truncate,
+		Table:   exql.TableWithName("artist"),
+		Cascade: true,
+	}
+
+	s, err := stmt.Compile(template)
+	assert.NoError(t, err)
+	assert.Equal(t, `TRUNCATE TABLE "artist" RESTART IDENTITY CASCADE`, strings.Join(strings.Fields(s), " "))
+}
+
+func TestTemplateExists(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+
+	sel := b.Select(db.Raw("1")).From("artist").Where(db.Cond{"name": "Flea"}).Limit(1)
+	assert.Equal(t,
+		`SELECT EXISTS (SELECT 1 FROM "artist" WHERE ("name" = $1) LIMIT 1) AS _t`,
+		b.Select(db.Raw("EXISTS ? AS _t", sel)).String(),
+	)
+}
+
+func TestTemplatePlanRows(t *testing.T) {
+	d := &database{}
+
+	rows, err := d.PlanRows(`[{"Plan": {"Node Type": "Seq Scan", "Relation Name": "artist", "Plan Rows": 1234, "Plan Width": 4}}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1234), rows)
+
+	_, err = d.PlanRows(`Seq Scan on artist`)
+	assert.Error(t, err)
 }
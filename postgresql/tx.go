@@ -42,3 +42,23 @@ func (t *tx) WithContext(ctx context.Context) sqlbuilder.Tx {
 	newTx.DatabaseTx.SetContext(ctx)
 	return &newTx
 }
+
+// NewTx begins a nested transaction, which is delimited by a savepoint within
+// the current one.
+func (t *tx) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	if ctx == nil {
+		ctx = t.Context()
+	}
+	nTx, err := t.DatabaseTx.NewDatabaseTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{DatabaseTx: nTx}, nil
+}
+
+// Tx creates a nested transaction block on the given context and passes it to
+// the function fn. If fn returns no error the savepoint is released, else the
+// transaction is rolled back to it.
+func (t *tx) Tx(ctx context.Context, fn func(sess sqlbuilder.Tx) error) error {
+	return sqladapter.RunTx(t, ctx, fn)
+}
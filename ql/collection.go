@@ -22,6 +22,7 @@
 package ql
 
 import (
+	"context"
 	"database/sql"
 
 	"upper.io/db.v3"
@@ -107,13 +108,23 @@ func (t *table) Find(conds ...interface{}) db.Result {
 	return res.Select("*")
 }
 
-// Insert inserts an item (map or struct) into the collection.
-func (t *table) Insert(item interface{}) (interface{}, error) {
-	columnNames, columnValues, err := sqlbuilder.Map(item, nil)
+// InsertContext inserts an item (map or struct) into the collection.
+func (t *table) InsertContext(ctx context.Context, item interface{}) (interface{}, error) {
+	return sqladapter.InsertWithHooks(ctx, t.d, t.Name(), item, func() (interface{}, error) {
+		return t.insert(ctx, item)
+	})
+}
+
+func (t *table) insert(ctx context.Context, item interface{}) (interface{}, error) {
+	columnNames, columnValues, err := sqlbuilder.Map(item, t.d.MapOptions())
 	if err != nil {
 		return nil, err
 	}
 
+	if columnNames, columnValues, err = t.AssignID(ctx, columnNames, columnValues); err != nil {
+		return nil, err
+	}
+
 	pKey := t.BaseCollection.PrimaryKeys()
 
 	q := t.d.InsertInto(t.Name()).
@@ -121,10 +132,15 @@ func (t *table) Insert(item interface{}) (interface{}, error) {
 		Values(columnValues...)
 
 	var res sql.Result
-	if res, err = q.Exec(); err != nil {
+	if res, err = q.ExecContext(ctx); err != nil {
 		return nil, err
 	}
 
+	if id, ok := sqladapter.InsertedKey(pKey, columnNames, columnValues); ok {
+		// The key was given a value before inserting, like an UUID.
+		return id, nil
+	}
+
 	if len(pKey) <= 1 {
 		// Attempt to use LastInsertId() (probably won't work, but the Exec()
 		// succeeded, so we can safely ignore the error from LastInsertId()).
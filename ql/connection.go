@@ -26,6 +26,8 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+
+	"upper.io/db.v3/internal/sqladapter"
 )
 
 const connectionScheme = `file`
@@ -55,6 +57,10 @@ func (c ConnectionURL) String() (s string) {
 
 	// Converting options into URL values.
 	for k, v := range c.Options {
+		if sqladapter.IsPoolOption(k) {
+			// Handled by the adapter.
+			continue
+		}
 		vv.Set(k, v)
 	}
 
@@ -84,6 +84,11 @@ func (d *database) CleanUp() error {
 	return nil
 }
 
+// Adapter returns the name of the adapter.
+func (d *database) Adapter() string {
+	return Adapter
+}
+
 // ConnectionURL returns this database's ConnectionURL.
 func (d *database) ConnectionURL() db.ConnectionURL {
 	return d.connURL
@@ -170,14 +175,22 @@ func (d *database) open() error {
 	// Binding with sqladapter's logic.
 	d.BaseDatabase = sqladapter.NewBaseDatabase(d)
 
+	if err := sqladapter.SetPoolOptions(d.BaseDatabase, sqladapter.ConnectionOptions(d.connURL)); err != nil {
+		return err
+	}
+
 	// Binding with sqlbuilder.
 	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
 
 	openFn := func() error {
 		openFiles := atomic.LoadInt32(&fileOpenCount)
 		if openFiles < maxOpenFiles {
-			sess, err := sql.Open("ql", d.ConnectionURL().String())
+			sess, err := d.BaseDatabase.OpenSession("ql", d.ConnectionURL().String())
 			if err == nil {
+				sess.SetConnMaxLifetime(d.BaseDatabase.ConnMaxLifetime())
+				compat.SetConnMaxIdleTime(sess, d.BaseDatabase.ConnMaxIdleTime())
+				sess.SetMaxIdleConns(d.BaseDatabase.MaxIdleConns())
+				sess.SetMaxOpenConns(d.BaseDatabase.MaxOpenConns())
 				if err := d.BaseDatabase.BindSession(sess); err != nil {
 					return err
 				}
@@ -223,6 +236,12 @@ func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (s
 	return sqladapter.ReplaceWithDollarSign(query), args
 }
 
+// ExistsQuery returns the given query, QL has no EXISTS so the set has items
+// if the query has rows.
+func (d *database) ExistsQuery(sel sqlbuilder.Selector) sqlbuilder.Selector {
+	return sel
+}
+
 // Err allows sqladapter to translate some known errors into generic errors.
 func (d *database) Err(err error) error {
 	if err != nil {
@@ -266,6 +285,12 @@ func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) erro
 	return sqladapter.RunTx(d, ctx, fn)
 }
 
+// TxWithRetry is like Tx but fn is run again, within a new transaction, if the
+// transaction fails because of a serialization failure or a deadlock.
+func (d *database) TxWithRetry(ctx context.Context, fn func(tx sqlbuilder.Tx) error, policy sqlbuilder.RetryPolicy) error {
+	return sqladapter.TxWithRetry(d, ctx, fn, policy)
+}
+
 // NewDatabaseTx allows sqladapter start a transaction block.
 func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
 	clone, err := d.clone(ctx, true)
@@ -329,3 +354,10 @@ func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
 	newDB, _ := d.clone(ctx, false)
 	return newDB
 }
+
+// ReadOnly creates a copy of the session that can't change the database.
+func (d *database) ReadOnly() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UseReadOnly()
+	return newDB
+}
@@ -141,7 +141,9 @@ const (
 	adapterInsertLayout = `
     INSERT INTO {{.Table}}
       {{if .Columns }}({{.Columns}}){{end}}
-    {{if .Values}}
+    {{if .Query}}
+      {{.Query}}
+    {{else if .Values}}
       VALUES
       {{.Values}}
     {{else}}
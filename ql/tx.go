@@ -24,6 +24,7 @@ package ql
 import (
 	"context"
 
+	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter"
 	"upper.io/db.v3/lib/sqlbuilder"
 )
@@ -42,3 +43,13 @@ func (t *tx) WithContext(ctx context.Context) sqlbuilder.Tx {
 	newTx.DatabaseTx.SetContext(ctx)
 	return &newTx
 }
+
+// NewTx is not supported, nested transactions require savepoints.
+func (t *tx) NewTx(ctx context.Context) (sqlbuilder.Tx, error) {
+	return nil, db.ErrUnsupported
+}
+
+// Tx is not supported, nested transactions require savepoints.
+func (t *tx) Tx(ctx context.Context, fn func(sess sqlbuilder.Tx) error) error {
+	return db.ErrUnsupported
+}
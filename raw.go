@@ -83,6 +83,12 @@ func (r rawValue) Empty() bool {
 //	// SOUNDEX('Hello')
 //	Raw("SOUNDEX('Hello')")
 //
+// Slice arguments are expanded into a list of placeholders, one per element,
+// with or without the parentheses around the placeholder:
+//
+//	// id IN (1, 2, 3)
+//	Raw("id IN (?)", []int64{1, 2, 3})
+//
 // Raw returns a value that satifies the db.RawValue interface.
 func Raw(value string, args ...interface{}) RawValue {
 	r := rawValue{v: value, a: nil}
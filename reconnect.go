@@ -0,0 +1,115 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ReconnectPolicy defines how a session retries connecting to the database
+// server when it's not able to, both when the session is opened and when the
+// connections of its pool are opened again after being lost. It's set with
+// Settings.SetReconnectPolicy, set it on DefaultSettings to configure the
+// first connection of new sessions:
+//
+//	db.DefaultSettings.SetReconnectPolicy(db.ReconnectPolicy{
+//		MaxAttempts:    10,
+//		InitialBackoff: 100 * time.Millisecond,
+//		MaxBackoff:     5 * time.Second,
+//		Jitter:         0.5,
+//		OnRetry: func(attempt int, err error, wait time.Duration) {
+//			log.Printf("connection attempt %d failed: %v, retrying in %v", attempt, err, wait)
+//		},
+//	})
+type ReconnectPolicy struct {
+	// MaxAttempts is the maximum number of connection attempts, including
+	// the first one. Zero means no limit.
+	MaxAttempts int
+
+	// MaxWait is the maximum time spent retrying. Zero means no limit.
+	MaxWait time.Duration
+
+	// InitialBackoff is the time to wait before the first retry, it is doubled
+	// after each retry. Zero means MinReconnectBackoff.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait between retries. Zero means no
+	// limit.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of the backoff that is randomized, between 0 and
+	// 1, so sessions that lost their connection at the same time don't retry
+	// at the same time. A backoff of 100ms with a jitter of 0.5 waits between
+	// 50ms and 100ms.
+	Jitter float64
+
+	// Retryable tells whether a connection error is worth retrying. If nil,
+	// "too many clients" errors, driver.ErrBadConn and network errors are
+	// retried.
+	Retryable func(err error) bool
+
+	// OnRetry, if not nil, is called after each failed attempt that is going
+	// to be retried, with the number of the attempt, its error and the time
+	// to wait before the next one.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// MinReconnectBackoff is the time to wait before the first retry of policies
+// that don't set InitialBackoff.
+const MinReconnectBackoff = 10 * time.Millisecond
+
+// DefaultReconnectPolicy is the ReconnectPolicy of new sessions.
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxWait:        5 * time.Second,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     500 * time.Millisecond,
+	Jitter:         0.2,
+}
+
+// Backoff returns the time to wait after the given failed attempt, counting
+// from 1.
+func (p ReconnectPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	if backoff <= 0 {
+		backoff = MinReconnectBackoff
+	}
+	for i := 1; i < attempt; i++ {
+		if (p.MaxBackoff > 0 && backoff >= p.MaxBackoff) || backoff > math.MaxInt64/2 {
+			break
+		}
+		backoff = backoff * 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+
+	jitter := p.Jitter
+	if jitter > 1 {
+		jitter = 1
+	}
+	if jitter > 0 {
+		backoff -= time.Duration(jitter * rand.Float64() * float64(backoff))
+	}
+	return backoff
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReconnectPolicyBackoff(t *testing.T) {
+	p := ReconnectPolicy{
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+	}
+
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for i, backoff := range expected {
+		if got := p.Backoff(i + 1); got != backoff*time.Millisecond {
+			t.Fatalf("attempt %d: expecting %v, got %v", i+1, backoff*time.Millisecond, got)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := p.Backoff(2)
+		if got < 10*time.Millisecond || got > 20*time.Millisecond {
+			t.Fatalf("expecting a backoff between 10ms and 20ms, got %v", got)
+		}
+	}
+
+	// Policies without a backoff don't retry right away.
+	p = ReconnectPolicy{}
+	if got := p.Backoff(1); got != MinReconnectBackoff {
+		t.Fatalf("expecting %v, got %v", MinReconnectBackoff, got)
+	}
+
+	p = ReconnectPolicy{InitialBackoff: time.Second}
+	if got := p.Backoff(1000); got <= 0 {
+		t.Fatalf("expecting a positive backoff, got %v", got)
+	}
+}
@@ -21,6 +21,23 @@
 
 package db
 
+import (
+	"context"
+)
+
+// CountOption changes how Result.Count counts the items of a result set.
+type CountOption uint8
+
+// Options of Result.Count.
+const (
+	// CountEstimate makes Count return the number of items the database
+	// estimates from its statistics instead of counting them, which is only
+	// approximate and may be off by a wide margin, or stale until the table
+	// is analyzed again. It's meant for very large tables where an exact
+	// count is too slow, like for showing the rough size of a listing.
+	CountEstimate CountOption = 1 << iota
+)
+
 // Result is an interface that defines methods useful for working with result
 // sets.
 type Result interface {
@@ -28,6 +45,12 @@ type Result interface {
 	// the result.
 	String() string
 
+	// Compile returns the SELECT statement for the result and its arguments,
+	// as they would be sent to the database server. It's meant for logging,
+	// cache keys or running the statement by other means. Not all adapters
+	// support it, ErrUnsupported is returned by the ones that don't.
+	Compile() (query string, args []interface{}, err error)
+
 	// Limit defines the maximum number of results in this set. It only has
 	// effect on `One()`, `All()` and `Next()`. A negative limit cancels any
 	// previous limit settings.
@@ -69,27 +92,181 @@ type Result interface {
 	// or columns.
 	Group(...interface{}) Result
 
+	// Preload loads the given relations of the items fetched by One() and
+	// All(). A relation is a struct field with a `relation` tag, nested
+	// relations are given as dot-separated field names. Related items are
+	// fetched with a single IN query per relation:
+	//
+	//   type Order struct {
+	//     ID     int64  `db:"id"`
+	//     UserID int64  `db:"user_id"`
+	//     User   *User  `db:"-" relation:"belongsTo:users,fk:user_id"`
+	//     Items  []Item `db:"-" relation:"hasMany:items,fk:order_id"`
+	//   }
+	//
+	//   type User struct {
+	//     ID     int64   `db:"id"`
+	//     Orders []Order `db:"-" relation:"hasMany:orders,fk:user_id"`
+	//   }
+	//
+	//   err := users.Find().Preload("Orders.Items").All(&users)
+	//
+	// The tag starts with the kind of relation and the related table:
+	// "hasMany" and "hasOne" for items whose fk column holds the key of this
+	// item, and "belongsTo" for an item whose key is held in the fk column of
+	// this item. Keys are "id" columns unless a "key" option is given.
+	Preload(relations ...string) Result
+
+	// Scope applies the named scopes of the collection to the result set, in
+	// the given order, see Scopes. Unknown scopes make the result fail with
+	// ErrUnknownScope.
+	//
+	//   res := col.Find().Scope("published", "recent")
+	Scope(names ...string) Result
+
+	// Model tells the result set the type of its items, given as a value or a
+	// pointer, so that Delete runs their BeforeDeleteHook and AfterDeleteHook.
+	// The items are fetched before they're deleted if their type has any of
+	// those hooks. DeleteReturning runs the hooks of the type of the items it
+	// returns without it.
+	Model(item interface{}) Result
+
+	// SoftDelete makes the result set skip the items that have a value on the
+	// given column, and Delete set it to the current time instead of removing
+	// them. Result sets of the tables registered in the SoftDeletes of the
+	// session are soft deleted already.
+	SoftDelete(column string) Result
+
+	// Unscoped returns a result set that includes the soft deleted items,
+	// calling Delete on it removes them permanently.
+	Unscoped() Result
+
+	// OnlyDeleted returns a result set that only includes the soft deleted
+	// items. It fails with ErrUnsupported if the result set is not soft
+	// deleted.
+	OnlyDeleted() Result
+
 	// Delete deletes all items within the result set. `Offset()` and `Limit()` are
 	// not honoured by `Delete()`.
 	Delete() error
 
+	// DeleteContext is like Delete() but the query runs within the given
+	// context.
+	DeleteContext(ctx context.Context) error
+
 	// Update modifies all items within the result set. `Offset()` and `Limit()`
 	// are not honoured by `Update()`.
 	Update(interface{}) error
 
+	// UpdateContext is like Update() but the query runs within the given
+	// context.
+	UpdateContext(ctx context.Context, values interface{}) error
+
+	// UpdateReturning modifies all items within the result set with the values
+	// of the given pointer to map or struct and then overwrites it with the
+	// actual values of the updated item, as returned by the database. If more
+	// than one item was modified, only the first one is read back. This is done
+	// within a single statement, databases that do not support RETURNING on
+	// UPDATE return ErrUnsupported.
+	UpdateReturning(ptr interface{}) error
+
+	// UpdateReturningContext is like UpdateReturning() but the query runs
+	// within the given context.
+	UpdateReturningContext(ctx context.Context, ptr interface{}) error
+
+	// DeleteReturning deletes all items within the result set and dumps them
+	// into the given pointer to a slice of maps or structs. This is done within
+	// a single statement, databases that do not support RETURNING on DELETE
+	// return ErrUnsupported.
+	DeleteReturning(sliceOfStructs interface{}) error
+
+	// DeleteReturningContext is like DeleteReturning() but the query runs
+	// within the given context.
+	DeleteReturningContext(ctx context.Context, sliceOfStructs interface{}) error
+
 	// Count returns the number of items that match the set conditions. `Offset()`
-	// and `Limit()` are not honoured by `Count()`
-	Count() (uint64, error)
+	// and `Limit()` are not honoured by `Count()`. With the CountEstimate option
+	// the number is only approximate, it's taken from the statistics of the
+	// table if the set has no conditions, or from the plan of the query
+	// otherwise:
+	//
+	//   n, err := res.Count(db.CountEstimate)
+	//
+	// Databases that can't estimate the number of items make Count fail with
+	// ErrUnsupported.
+	Count(...CountOption) (uint64, error)
+
+	// CountContext is like Count() but the query runs within the given context.
+	CountContext(ctx context.Context, opts ...CountOption) (uint64, error)
 
 	// Exists returns true if at least one item on the collection exists. False
-	// otherwise.
+	// otherwise. Unlike `Count()` or `One()` it stops at the first matching
+	// item and fetches none of its columns.
 	Exists() (bool, error)
 
+	// ExistsContext is like Exists() but the query runs within the given
+	// context.
+	ExistsContext(ctx context.Context) (bool, error)
+
+	// Sum returns the sum of the values of the given column on the items that
+	// match the set conditions, or zero if there are no items. Unlike
+	// `Count()`, `Limit()`, `Offset()` and `Page()` are honoured, so the sum of
+	// a page can be computed, `Group()` is ignored:
+	//
+	//   total, err := orders.Find().OrderBy("-id").Paginate(20).Sum("amount")
+	Sum(column string) (float64, error)
+
+	// SumContext is like Sum() but the query runs within the given context.
+	SumContext(ctx context.Context, column string) (float64, error)
+
+	// Avg is like Sum() but returns the average of the values of the given
+	// column.
+	Avg(column string) (float64, error)
+
+	// AvgContext is like Avg() but the query runs within the given context.
+	AvgContext(ctx context.Context, column string) (float64, error)
+
+	// Min scans the smallest value of the given column on the items that match
+	// the set conditions into dst. If there are no items the value is NULL, so
+	// dst should be able to hold it (i.e.: *sql.NullInt64).
+	//
+	//   var smallest sql.NullInt64
+	//   err := orders.Find().Min("amount", &smallest)
+	Min(column string, dst interface{}) error
+
+	// MinContext is like Min() but the query runs within the given context.
+	MinContext(ctx context.Context, column string, dst interface{}) error
+
+	// Max is like Min() but scans the greatest value of the given column.
+	Max(column string, dst interface{}) error
+
+	// MaxContext is like Max() but the query runs within the given context.
+	MaxContext(ctx context.Context, column string, dst interface{}) error
+
+	// CountDistinct returns the number of distinct, non-NULL values of the given
+	// column on the items that match the set conditions.
+	CountDistinct(column string) (uint64, error)
+
+	// CountDistinctContext is like CountDistinct() but the query runs within the
+	// given context.
+	CountDistinctContext(ctx context.Context, column string) (uint64, error)
+
+	// Explain returns the plan the database would use to fetch the items of
+	// the result set, without fetching them. It's meant for tests that check
+	// which indexes are used. Not all adapters support it, ErrUnsupported is
+	// returned by the ones that don't.
+	Explain(ctx context.Context) (string, error)
+
 	// Next fetches the next result within the result set and dumps it into the
 	// given pointer to struct or pointer to map. You must call
 	// `Close()` after finishing using `Next()`.
 	Next(ptrToStruct interface{}) bool
 
+	// NextContext is like Next() but the query runs within the given context.
+	// The context is only taken into account by the call that opens the
+	// cursor, that is, the first one.
+	NextContext(ctx context.Context, ptrToStruct interface{}) bool
+
 	// Err returns the last error that has happened with the result set, nil
 	// otherwise.
 	Err() error
@@ -100,12 +277,63 @@ type Result interface {
 	// after using One().
 	One(ptrToStruct interface{}) error
 
+	// OneContext is like One() but the query runs within the given context.
+	OneContext(ctx context.Context, ptrToStruct interface{}) error
+
 	// All fetches all results within the result set and dumps them into the
 	// given pointer to slice of maps or structs.  The result set is
 	// automatically closed, so there is no need to call Close() after
 	// using All().
 	All(sliceOfStructs interface{}) error
 
+	// AllContext is like All() but the query runs within the given context.
+	AllContext(ctx context.Context, sliceOfStructs interface{}) error
+
+	// ForEach calls fn once for each result, fn reads the current one with
+	// scan, which dumps it into the given pointer to struct or pointer to map.
+	// A struct given to scan for every result is reused rather than
+	// allocated for each of them, which makes ForEach cheaper than All() for
+	// large result sets:
+	//
+	//  var artist Artist
+	//  err := res.ForEach(func(scan func(dest ...interface{}) error) error {
+	//    if err := scan(&artist); err != nil {
+	//      return err
+	//    }
+	//    ...
+	//    return nil
+	//  })
+	//
+	// Iteration stops when fn returns an error, which is returned by
+	// ForEach. The result set is automatically closed.
+	ForEach(fn func(scan func(dest ...interface{}) error) error) error
+
+	// ForEachContext is like ForEach() but the query runs within the given
+	// context.
+	ForEachContext(ctx context.Context, fn func(scan func(dest ...interface{}) error) error) error
+
+	// Batch streams the results in slices of up to size items, of the type of
+	// sliceOfStructs (a slice of maps or structs, or a pointer to one). Items
+	// are read from the database as batches are received, rather than all at
+	// once like All() does:
+	//
+	//  for batch := range res.Batch(ctx, 1000, []Artist(nil)) {
+	//    if err, ok := batch.(error); ok {
+	//      ...
+	//    }
+	//    for _, artist := range batch.([]Artist) {
+	//      ...
+	//    }
+	//  }
+	//
+	// If reading fails, the error is sent as the last value of the channel and
+	// it is also returned by Err(). The channel is closed once all the items
+	// were sent, after an error or when ctx is done. The query stays open
+	// until then, so readers must either drain the channel or cancel ctx,
+	// otherwise the goroutine that sends the batches leaks along with its
+	// rows. Cancelling ctx is not sent as an error, but Err() returns it.
+	Batch(ctx context.Context, size int, sliceOfStructs interface{}) <-chan interface{}
+
 	// Paginate splits the results of the query into pages containing pageSize
 	// items.  When using pagination previous settings for Limit and Offset are
 	// ignored. Page numbering starts at 1.
@@ -152,6 +380,9 @@ type Result interface {
 	//   cursor = q.Paginate(12).Cursor("id")
 	//   res = cursor.NextPage(items[len(items)-1].ID)
 	//
+	// A Cursor, as returned by NextPageCursor(), is also accepted as
+	// cursorValue.
+	//
 	// Note that NextPage requires a cursor, any column with an absolute order
 	// (given two values one always precedes the other) can be a cursor.
 	//
@@ -178,13 +409,41 @@ type Result interface {
 	//   res = cursor.PrevPage(upperBound)
 	PrevPage(cursorValue interface{}) Result
 
+	// NextPageCursor returns an encoded cursor with the value the cursor column
+	// has on the last element of items, which is typically the slice that was
+	// filled by All(). The Cursor can be handed to clients and given back to
+	// NextPage() to get the following page.
+	//
+	// Example:
+	//
+	//   res = q.Paginate(20).Cursor("id")
+	//   err = res.All(&items)
+	//   next, err = res.NextPageCursor(&items)
+	//   ...
+	//   err = res.NextPage(next).All(&items)
+	NextPageCursor(items interface{}) (Cursor, error)
+
+	// PrevPageCursor is like NextPageCursor but it encodes the value of the
+	// first element of items, the Cursor is meant to be given to PrevPage().
+	PrevPageCursor(items interface{}) (Cursor, error)
+
 	// TotalPages returns the total number of pages the result could produce.  If
 	// no pagination has been set this value equals 1.
 	TotalPages() (uint, error)
 
-	// TotalEntries returns the total number of entries in the query.
+	// TotalPagesContext is like TotalPages() but the query runs within the
+	// given context.
+	TotalPagesContext(ctx context.Context) (uint, error)
+
+	// TotalEntries returns the total number of entries in the query. Entries
+	// are counted with the same conditions of the query, queries that group or
+	// deduplicate rows are counted as a derived table.
 	TotalEntries() (uint64, error)
 
+	// TotalEntriesContext is like TotalEntries() but the query runs within the
+	// given context.
+	TotalEntriesContext(ctx context.Context) (uint64, error)
+
 	// Close closes the result set and frees all locked resources.
 	Close() error
 }
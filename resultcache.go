@@ -0,0 +1,96 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"upper.io/db.v3/internal/cache"
+)
+
+// ResultCache stores the rows fetched by the One and All methods of the
+// results of SQL sessions, see Settings.SetResultCache. Values are opaque and
+// can be kept anywhere, like in Redis:
+//
+//	type redisCache struct {
+//		client *redis.Client
+//	}
+//
+//	func (c redisCache) Get(ctx context.Context, key string) ([]byte, bool) {
+//		value, err := c.client.Get(ctx, key).Bytes()
+//		return value, err == nil
+//	}
+//
+//	func (c redisCache) Set(ctx context.Context, key string, value []byte) {
+//		c.client.Set(ctx, key, value, time.Minute)
+//	}
+//
+// Keys hold the versions of every table the query of a result reads from,
+// including joined tables and the ones of subqueries. Versions are kept in the
+// cache too and change when a session that uses it writes to the table, so
+// stale values are never read again by the sessions of any process that
+// shares the cache and are left to expire. Writes done without the cache are
+// not noticed, values should not be kept longer than the data they hold can
+// be out of date.
+type ResultCache interface {
+	// Get returns the value stored with the given key, if any.
+	Get(ctx context.Context, key string) ([]byte, bool)
+
+	// Set stores value with the given key.
+	Set(ctx context.Context, key string, value []byte)
+}
+
+type resultCacheKey string
+
+func (k resultCacheKey) Hash() string {
+	return string(k)
+}
+
+// memoryResultCache is a ResultCache that keeps values in memory.
+type memoryResultCache struct {
+	cache *cache.Cache
+}
+
+// NewMemoryResultCache returns a ResultCache that keeps up to capacity values
+// in memory, the least recently used ones are evicted when it's full. Values
+// expire after maxAge, zero means they don't.
+func NewMemoryResultCache(capacity int, maxAge time.Duration) (ResultCache, error) {
+	c, err := cache.NewCacheWithCapacity(capacity)
+	if err != nil {
+		return nil, err
+	}
+	c.SetMaxAge(maxAge)
+	return &memoryResultCache{cache: c}, nil
+}
+
+func (c *memoryResultCache) Get(ctx context.Context, key string) ([]byte, bool) {
+	value, ok := c.cache.ReadRaw(resultCacheKey(key))
+	if !ok {
+		return nil, false
+	}
+	return value.([]byte), true
+}
+
+func (c *memoryResultCache) Set(ctx context.Context, key string, value []byte) {
+	c.cache.Write(resultCacheKey(key), value)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"sync"
+)
+
+// Scope refines a result set, like by adding conditions or an order:
+//
+//	active := func(res db.Result) db.Result {
+//		return res.And(db.Cond{"status !=": "archived"})
+//	}
+type Scope func(Result) Result
+
+// Scopes is a registry of the scopes of collections, see Settings.SetScopes.
+// Default scopes are applied to every result set created with Find, named
+// scopes are applied on demand with Result.Scope:
+//
+//	scopes := db.NewScopes()
+//	scopes.Default("posts", active)
+//	scopes.Register("posts", "recent", func(res db.Result) db.Result {
+//		return res.OrderBy("-created_at").Limit(10)
+//	})
+//
+//	err := sess.Collection("posts").Find().Scope("recent").All(&posts)
+//
+// Scopes registered with an empty table apply to every collection. Default
+// scopes are applied right after the conditions given to Find, the conditions
+// they add with And are kept when Where replaces the others.
+// It's safe for concurrent use.
+type Scopes struct {
+	mu       sync.RWMutex
+	defaults map[string][]Scope
+	named    map[string]map[string]Scope
+}
+
+// NewScopes returns an empty registry of scopes.
+func NewScopes() *Scopes {
+	return &Scopes{
+		defaults: map[string][]Scope{},
+		named:    map[string]map[string]Scope{},
+	}
+}
+
+// Default adds a scope that is applied to every result set of the given
+// table, or of every table if it's empty. Scopes are applied in the order
+// they were added, the ones of every table first.
+func (s *Scopes) Default(table string, scope Scope) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaults[table] = append(s.defaults[table], scope)
+}
+
+// Register sets the scope with the given name of the given table, or of every
+// table if it's empty. A nil scope removes it.
+func (s *Scopes) Register(table string, name string, scope Scope) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if scope == nil {
+		delete(s.named[table], name)
+		return
+	}
+	if s.named[table] == nil {
+		s.named[table] = map[string]Scope{}
+	}
+	s.named[table][name] = scope
+}
+
+// Defaults returns the default scopes of the given table.
+func (s *Scopes) Defaults(table string) []Scope {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scopes := make([]Scope, 0, len(s.defaults[""])+len(s.defaults[table]))
+	scopes = append(scopes, s.defaults[""]...)
+	if table != "" {
+		scopes = append(scopes, s.defaults[table]...)
+	}
+	return scopes
+}
+
+// Lookup returns the scope with the given name of the given table, the ones
+// of the table take precedence over the ones of every table. It returns nil
+// if there's none.
+func (s *Scopes) Lookup(table string, name string) Scope {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if scope, ok := s.named[table][name]; ok {
+		return scope
+	}
+	return s.named[""][name]
+}
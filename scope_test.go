@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopes(t *testing.T) {
+	var none *Scopes
+	assert.Equal(t, 0, len(none.Defaults("posts")))
+	assert.Nil(t, none.Lookup("posts", "recent"))
+
+	var calls []string
+	scope := func(name string) Scope {
+		return func(res Result) Result {
+			calls = append(calls, name)
+			return res
+		}
+	}
+
+	scopes := NewScopes()
+	scopes.Default("posts", scope("posts"))
+	scopes.Default("", scope("all"))
+	scopes.Default("users", scope("users"))
+
+	for _, s := range scopes.Defaults("posts") {
+		s(nil)
+	}
+	assert.Equal(t, []string{"all", "posts"}, calls)
+
+	calls = nil
+	scopes.Register("", "recent", scope("recent"))
+	scopes.Register("posts", "recent", scope("recent posts"))
+	scopes.Lookup("posts", "recent")(nil)
+	scopes.Lookup("users", "recent")(nil)
+	assert.Equal(t, []string{"recent posts", "recent"}, calls)
+
+	scopes.Register("posts", "recent", nil)
+	assert.Nil(t, scopes.Lookup("posts", "archived"))
+	scopes.Lookup("posts", "recent")(nil)
+	assert.Equal(t, []string{"recent posts", "recent", "recent"}, calls)
+}
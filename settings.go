@@ -34,7 +34,9 @@ type Settings interface {
 	// LoggingEnabled returns true if logging is enabled, false otherwise.
 	LoggingEnabled() bool
 
-	// SetLogger defines which logger to use.
+	// SetLogger defines which logger receives a QueryStatus after each query
+	// while logging is enabled, see QueryLogger. A nil logger restores the
+	// default one, which prints to the standard logger.
 	SetLogger(Logger)
 	// Returns the currently configured logger.
 	Logger() Logger
@@ -46,6 +48,23 @@ type Settings interface {
 	// is enabled, false otherwise.
 	PreparedStatementCacheEnabled() bool
 
+	// SetPreparedStatementCacheSize sets the maximum number of prepared
+	// statements the session keeps, the least recently used statement is
+	// closed when a new one doesn't fit. Values lower than one restore the
+	// default size of 128 statements.
+	SetPreparedStatementCacheSize(int)
+	// PreparedStatementCacheSize returns the maximum number of prepared
+	// statements the session keeps.
+	PreparedStatementCacheSize() int
+
+	// SetPreparedStatementCacheMaxAge sets how long a prepared statement is
+	// reused before being prepared again, a zero duration means statements
+	// are reused until they're evicted.
+	SetPreparedStatementCacheMaxAge(time.Duration)
+	// PreparedStatementCacheMaxAge returns how long a prepared statement is
+	// reused.
+	PreparedStatementCacheMaxAge() time.Duration
+
 	// SetConnMaxLifetime sets the default maximum amount of time a connection
 	// may be reused.
 	SetConnMaxLifetime(time.Duration)
@@ -54,6 +73,14 @@ type Settings interface {
 	// may be reused.
 	ConnMaxLifetime() time.Duration
 
+	// SetConnMaxIdleTime sets the default maximum amount of time a connection
+	// may be idle before being closed. It has no effect before go1.15.
+	SetConnMaxIdleTime(time.Duration)
+
+	// ConnMaxIdleTime returns the default maximum amount of time a connection
+	// may be idle before being closed.
+	ConnMaxIdleTime() time.Duration
+
 	// SetMaxIdleConns sets the default maximum number of connections in the idle
 	// connection pool.
 	SetMaxIdleConns(int)
@@ -69,16 +96,180 @@ type Settings interface {
 	// MaxOpenConns returns the default maximum number of open connections to the
 	// database.
 	MaxOpenConns() int
+
+	// SetReconnectPolicy defines how the session retries connecting to the
+	// database server, see ReconnectPolicy.
+	SetReconnectPolicy(ReconnectPolicy)
+
+	// ReconnectPolicy returns the policy the session uses to retry connecting
+	// to the database server.
+	ReconnectPolicy() ReconnectPolicy
+
+	// SetSlowQueryThreshold makes SQL sessions log the statements that take
+	// longer than the given duration, even if logging is not enabled. Other
+	// statements are not logged. Zero disables it.
+	SetSlowQueryThreshold(time.Duration)
+
+	// SlowQueryThreshold returns the duration statements must exceed to be
+	// logged, zero if all of them are.
+	SlowQueryThreshold() time.Duration
+
+	// SetExplainSlowQueries makes the logs of slow statements include their
+	// plan, on adapters that can get it. Getting the plan takes an extra round
+	// trip to the database server.
+	SetExplainSlowQueries(bool)
+
+	// ExplainSlowQueries returns true if the plan of slow statements is
+	// logged.
+	ExplainSlowQueries() bool
+
+	// SetDryRun makes SQL sessions compile and log the statements that may
+	// change the database, instead of running them. Statements that only read
+	// are still run. Exec statements are reported to affect no rows, the ones
+	// that would return rows from a write (like INSERT ... RETURNING) fail with
+	// ErrDryRun.
+	SetDryRun(bool)
+
+	// DryRun returns true if statements that may change the database are not
+	// run.
+	DryRun() bool
+
+	// SetResultCache makes the One and All methods of the results of SQL
+	// sessions read their rows through the given cache, outside of
+	// transactions. A nil cache disables it.
+	SetResultCache(ResultCache)
+
+	// ResultCache returns the cache results are read through, if any.
+	ResultCache() ResultCache
+
+	// SetCollapseReads makes identical queries that only read (same SQL and
+	// arguments) run once when SQL sessions run them at the same time, outside
+	// of transactions, the rows are shared by all of them. They all fail if
+	// the first one does, like when its context is canceled.
+	SetCollapseReads(bool)
+
+	// CollapseReads returns true if identical concurrent reads are collapsed.
+	CollapseReads() bool
+
+	// SetFieldMapping sets how SQL sessions map the fields of structs to
+	// columns, rather than by their "db" tags or else by their Go names:
+	//
+	//	sess.SetFieldMapping(&db.FieldMapping{NameMapper: db.SnakeCase})
+	//
+	// A nil mapping restores the default one.
+	SetFieldMapping(*FieldMapping)
+
+	// FieldMapping returns the mapping of struct fields to columns, nil if
+	// it's the default one.
+	FieldMapping() *FieldMapping
+
+	// SetCodecs sets the codecs SQL sessions use to bind and scan values of
+	// types that are not supported by their drivers.
+	SetCodecs(*Codecs)
+
+	// Codecs returns the codecs of the session, if any.
+	Codecs() *Codecs
+
+	// SetIDGenerators sets the generators SQL sessions give values to the
+	// application-assigned primary keys of tables with, see IDGenerator.
+	SetIDGenerators(*IDGenerators)
+
+	// IDGenerators returns the ID generators of the session, if any.
+	IDGenerators() *IDGenerators
+
+	// SetChangeListener sets the listener SQL sessions notify of the writes
+	// made through their collections, see ChangeListener. A nil listener
+	// disables notifications.
+	SetChangeListener(ChangeListener)
+
+	// ChangeListener returns the change listener of the session, if any.
+	ChangeListener() ChangeListener
+
+	// SetTenancy sets the tables SQL sessions scope by the tenant of their
+	// context, see Tenancy.
+	SetTenancy(*Tenancy)
+
+	// Tenancy returns the tables scoped by tenant, if any.
+	Tenancy() *Tenancy
+
+	// SetSoftDeletes sets the tables whose rows SQL sessions soft delete, see
+	// SoftDeletes.
+	SetSoftDeletes(*SoftDeletes)
+
+	// SoftDeletes returns the soft deleted tables, if any.
+	SoftDeletes() *SoftDeletes
+
+	// SetScopes sets the default and named scopes of the collections of SQL
+	// sessions, see Scopes.
+	SetScopes(*Scopes)
+
+	// Scopes returns the scopes of the session, if any.
+	Scopes() *Scopes
+
+	// SetZeroValuePolicy sets whether SQL sessions write the zero values of
+	// struct fields as they are or as NULL, see ZeroValuePolicy.
+	SetZeroValuePolicy(ZeroValuePolicy)
+
+	// ZeroValuePolicy returns how the zero values of struct fields are
+	// written.
+	ZeroValuePolicy() ZeroValuePolicy
+
+	// SetMaxQueryDuration sets the default time limit of the statements of
+	// SQL sessions, they fail with ErrQueryTimeout when it's exceeded. Zero
+	// means no limit.
+	SetMaxQueryDuration(time.Duration)
+
+	// MaxQueryDuration returns the default time limit of statements.
+	MaxQueryDuration() time.Duration
+
+	// SetCredentialsProvider defines where the credentials of new connections
+	// come from, see CredentialsProvider. A nil provider means the ones of
+	// the connection URL are used.
+	SetCredentialsProvider(CredentialsProvider)
+
+	// CredentialsProvider returns the provider of the credentials of new
+	// connections.
+	CredentialsProvider() CredentialsProvider
+
+	// SetClock defines the function that returns the current time for values
+	// that are set automatically, like the ones of fields tagged with auto_now
+	// or auto_now_add. A nil function restores time.Now. This is mostly useful
+	// for testing.
+	SetClock(func() time.Time)
+
+	// Clock returns the function that returns the current time for values that
+	// are set automatically.
+	Clock() func() time.Time
 }
 
 type settings struct {
 	sync.RWMutex
 
 	preparedStatementCacheEnabled uint32
+	preparedStatementCacheSize    int
+	preparedStatementCacheMaxAge  time.Duration
 
 	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
 	maxOpenConns    int
 	maxIdleConns    int
+	reconnectPolicy ReconnectPolicy
+	credentials     CredentialsProvider
+	maxQueryTime    time.Duration
+	slowQueryTime   time.Duration
+	explainSlow     bool
+	dryRun          bool
+	resultCache     ResultCache
+	collapseReads   bool
+	fieldMapping    *FieldMapping
+	codecs          *Codecs
+	idGenerators    *IDGenerators
+	changeListener  ChangeListener
+	tenancy         *Tenancy
+	softDeletes     *SoftDeletes
+	scopes          *Scopes
+	zeroValuePolicy ZeroValuePolicy
+	clock           func() time.Time
 
 	loggingEnabled uint32
 	queryLogger    Logger
@@ -135,6 +326,33 @@ func (c *settings) PreparedStatementCacheEnabled() bool {
 	return c.binaryOption(&c.preparedStatementCacheEnabled)
 }
 
+func (c *settings) SetPreparedStatementCacheSize(n int) {
+	c.Lock()
+	c.preparedStatementCacheSize = n
+	c.Unlock()
+}
+
+func (c *settings) PreparedStatementCacheSize() int {
+	c.RLock()
+	defer c.RUnlock()
+	if c.preparedStatementCacheSize < 1 {
+		return defaultPreparedStatementCacheSize
+	}
+	return c.preparedStatementCacheSize
+}
+
+func (c *settings) SetPreparedStatementCacheMaxAge(t time.Duration) {
+	c.Lock()
+	c.preparedStatementCacheMaxAge = t
+	c.Unlock()
+}
+
+func (c *settings) PreparedStatementCacheMaxAge() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.preparedStatementCacheMaxAge
+}
+
 func (c *settings) SetConnMaxLifetime(t time.Duration) {
 	c.Lock()
 	c.connMaxLifetime = t
@@ -147,6 +365,18 @@ func (c *settings) ConnMaxLifetime() time.Duration {
 	return c.connMaxLifetime
 }
 
+func (c *settings) SetConnMaxIdleTime(t time.Duration) {
+	c.Lock()
+	c.connMaxIdleTime = t
+	c.Unlock()
+}
+
+func (c *settings) ConnMaxIdleTime() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.connMaxIdleTime
+}
+
 func (c *settings) SetMaxIdleConns(n int) {
 	c.Lock()
 	c.maxIdleConns = n
@@ -171,6 +401,213 @@ func (c *settings) MaxOpenConns() int {
 	return c.maxOpenConns
 }
 
+func (c *settings) SetReconnectPolicy(policy ReconnectPolicy) {
+	c.Lock()
+	c.reconnectPolicy = policy
+	c.Unlock()
+}
+
+func (c *settings) ReconnectPolicy() ReconnectPolicy {
+	c.RLock()
+	defer c.RUnlock()
+	return c.reconnectPolicy
+}
+
+func (c *settings) SetSlowQueryThreshold(t time.Duration) {
+	c.Lock()
+	c.slowQueryTime = t
+	c.Unlock()
+}
+
+func (c *settings) SlowQueryThreshold() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.slowQueryTime
+}
+
+func (c *settings) SetExplainSlowQueries(value bool) {
+	c.Lock()
+	c.explainSlow = value
+	c.Unlock()
+}
+
+func (c *settings) ExplainSlowQueries() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.explainSlow
+}
+
+func (c *settings) SetDryRun(value bool) {
+	c.Lock()
+	c.dryRun = value
+	c.Unlock()
+}
+
+func (c *settings) DryRun() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.dryRun
+}
+
+func (c *settings) SetResultCache(cache ResultCache) {
+	c.Lock()
+	c.resultCache = cache
+	c.Unlock()
+}
+
+func (c *settings) ResultCache() ResultCache {
+	c.RLock()
+	defer c.RUnlock()
+	return c.resultCache
+}
+
+func (c *settings) SetCollapseReads(value bool) {
+	c.Lock()
+	c.collapseReads = value
+	c.Unlock()
+}
+
+func (c *settings) CollapseReads() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.collapseReads
+}
+
+func (c *settings) SetFieldMapping(mapping *FieldMapping) {
+	c.Lock()
+	c.fieldMapping = mapping
+	c.Unlock()
+}
+
+func (c *settings) FieldMapping() *FieldMapping {
+	c.RLock()
+	defer c.RUnlock()
+	return c.fieldMapping
+}
+
+func (c *settings) SetCodecs(codecs *Codecs) {
+	c.Lock()
+	c.codecs = codecs
+	c.Unlock()
+}
+
+func (c *settings) Codecs() *Codecs {
+	c.RLock()
+	defer c.RUnlock()
+	return c.codecs
+}
+
+func (c *settings) SetIDGenerators(generators *IDGenerators) {
+	c.Lock()
+	c.idGenerators = generators
+	c.Unlock()
+}
+
+func (c *settings) IDGenerators() *IDGenerators {
+	c.RLock()
+	defer c.RUnlock()
+	return c.idGenerators
+}
+
+func (c *settings) SetChangeListener(listener ChangeListener) {
+	c.Lock()
+	c.changeListener = listener
+	c.Unlock()
+}
+
+func (c *settings) ChangeListener() ChangeListener {
+	c.RLock()
+	defer c.RUnlock()
+	return c.changeListener
+}
+
+func (c *settings) SetTenancy(tenancy *Tenancy) {
+	c.Lock()
+	c.tenancy = tenancy
+	c.Unlock()
+}
+
+func (c *settings) Tenancy() *Tenancy {
+	c.RLock()
+	defer c.RUnlock()
+	return c.tenancy
+}
+
+func (c *settings) SetSoftDeletes(softDeletes *SoftDeletes) {
+	c.Lock()
+	c.softDeletes = softDeletes
+	c.Unlock()
+}
+
+func (c *settings) SoftDeletes() *SoftDeletes {
+	c.RLock()
+	defer c.RUnlock()
+	return c.softDeletes
+}
+
+func (c *settings) SetScopes(scopes *Scopes) {
+	c.Lock()
+	c.scopes = scopes
+	c.Unlock()
+}
+
+func (c *settings) Scopes() *Scopes {
+	c.RLock()
+	defer c.RUnlock()
+	return c.scopes
+}
+
+func (c *settings) SetZeroValuePolicy(policy ZeroValuePolicy) {
+	c.Lock()
+	c.zeroValuePolicy = policy
+	c.Unlock()
+}
+
+func (c *settings) ZeroValuePolicy() ZeroValuePolicy {
+	c.RLock()
+	defer c.RUnlock()
+	return c.zeroValuePolicy
+}
+
+func (c *settings) SetMaxQueryDuration(t time.Duration) {
+	c.Lock()
+	c.maxQueryTime = t
+	c.Unlock()
+}
+
+func (c *settings) MaxQueryDuration() time.Duration {
+	c.RLock()
+	defer c.RUnlock()
+	return c.maxQueryTime
+}
+
+func (c *settings) SetCredentialsProvider(provider CredentialsProvider) {
+	c.Lock()
+	c.credentials = provider
+	c.Unlock()
+}
+
+func (c *settings) CredentialsProvider() CredentialsProvider {
+	c.RLock()
+	defer c.RUnlock()
+	return c.credentials
+}
+
+func (c *settings) SetClock(fn func() time.Time) {
+	c.Lock()
+	c.clock = fn
+	c.Unlock()
+}
+
+func (c *settings) Clock() func() time.Time {
+	c.RLock()
+	defer c.RUnlock()
+	if c.clock == nil {
+		return time.Now
+	}
+	return c.clock
+}
+
 // NewSettings returns a new settings value prefilled with the current default
 // settings.
 func NewSettings() Settings {
@@ -178,11 +615,18 @@ func NewSettings() Settings {
 	return &newSettings
 }
 
+// defaultPreparedStatementCacheSize is the number of prepared statements a
+// session keeps by default.
+const defaultPreparedStatementCacheSize = 128
+
 // DefaultSettings provides default global configuration settings for database
 // sessions.
 var DefaultSettings Settings = &settings{
 	preparedStatementCacheEnabled: 0,
+	preparedStatementCacheSize:    defaultPreparedStatementCacheSize,
 	connMaxLifetime:               time.Duration(0),
+	connMaxIdleTime:               time.Duration(0),
 	maxIdleConns:                  10,
 	maxOpenConns:                  0,
+	reconnectPolicy:               DefaultReconnectPolicy,
 }
@@ -0,0 +1,107 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SoftDeletes is a registry of the tables whose rows are soft deleted, see
+// Settings.SetSoftDeletes. The result sets SQL sessions create with Find on
+// those tables skip the rows that have a value on the soft delete column, and
+// Delete sets it to the current time instead of removing rows, see
+// Result.Unscoped and Result.OnlyDeleted. It's safe for concurrent use.
+type SoftDeletes struct {
+	mu      sync.RWMutex
+	columns map[string]string
+}
+
+// NewSoftDeletes returns an empty registry of soft deleted tables.
+func NewSoftDeletes() *SoftDeletes {
+	return &SoftDeletes{columns: map[string]string{}}
+}
+
+// Register soft deletes the rows of the given table on the given column, an
+// empty column removes it.
+func (s *SoftDeletes) Register(table string, column string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if column == "" {
+		delete(s.columns, table)
+		return
+	}
+	s.columns[table] = column
+}
+
+// RegisterModel soft deletes the rows of the given table on the column of the
+// field of model tagged with the softdelete option, like
+// `db:"deleted_at,softdelete"`. It returns false if model has no such field.
+func (s *SoftDeletes) RegisterModel(table string, model interface{}) bool {
+	column := SoftDeleteColumn(reflect.TypeOf(model))
+	if column == "" {
+		return false
+	}
+	s.Register(table, column)
+	return true
+}
+
+// Column returns the soft delete column of the given table, or an empty
+// string if its rows are not soft deleted.
+func (s *SoftDeletes) Column(table string) string {
+	if s == nil {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.columns[table]
+}
+
+// SoftDeleteColumn returns the column of the field of the given struct type
+// tagged with the softdelete option, or an empty string if there's none.
+func SoftDeleteColumn(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return ""
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("db"), ",")
+		for _, option := range tag[1:] {
+			if option == "softdelete" && tag[0] != "" {
+				return tag[0]
+			}
+		}
+		if field.Anonymous {
+			if column := SoftDeleteColumn(field.Type); column != "" {
+				return column
+			}
+		}
+	}
+	return ""
+}
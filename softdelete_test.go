@@ -0,0 +1,38 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftDeletes(t *testing.T) {
+	type base struct {
+		DeletedAt *time.Time `db:"deleted_at,softdelete"`
+	}
+	type post struct {
+		base
+		ID int64 `db:"id"`
+	}
+
+	assert.Equal(t, "deleted_at", SoftDeleteColumn(reflect.TypeOf(&post{})))
+	assert.Equal(t, "", SoftDeleteColumn(reflect.TypeOf(struct{ ID int64 }{})))
+	assert.Equal(t, "", SoftDeleteColumn(reflect.TypeOf(1)))
+
+	var none *SoftDeletes
+	assert.Equal(t, "", none.Column("posts"))
+
+	softDeletes := NewSoftDeletes()
+	assert.True(t, softDeletes.RegisterModel("posts", post{}))
+	assert.False(t, softDeletes.RegisterModel("tags", struct{ ID int64 }{}))
+	softDeletes.Register("comments", "removed_at")
+
+	assert.Equal(t, "deleted_at", softDeletes.Column("posts"))
+	assert.Equal(t, "removed_at", softDeletes.Column("comments"))
+	assert.Equal(t, "", softDeletes.Column("tags"))
+
+	softDeletes.Register("comments", "")
+	assert.Equal(t, "", softDeletes.Column("comments"))
+}
@@ -26,11 +26,43 @@ import (
 	"net/url"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+
+	"upper.io/db.v3/internal/sqladapter"
 )
 
 const connectionScheme = `file`
 
+// Names of the database/sql drivers this adapter can use.
+const (
+	// cgoDriver is github.com/mattn/go-sqlite3.
+	cgoDriver = `sqlite3`
+	// pureGoDriver is modernc.org/sqlite, which does not require cgo.
+	pureGoDriver = `sqlite`
+)
+
+// driverOption is the connection URL option that chooses the driver, the
+// driver must have been imported.
+const driverOption = `_driver`
+
+// pureGoPragmas maps the options of github.com/mattn/go-sqlite3 to the
+// pragmas modernc.org/sqlite expects as _pragma options.
+var pureGoPragmas = map[string]string{
+	"_busy_timeout":       "busy_timeout",
+	"_timeout":            "busy_timeout",
+	"_foreign_keys":       "foreign_keys",
+	"_fk":                 "foreign_keys",
+	"_journal_mode":       "journal_mode",
+	"_journal":            "journal_mode",
+	"_locking_mode":       "locking_mode",
+	"_locking":            "locking_mode",
+	"_recursive_triggers": "recursive_triggers",
+	"_rt":                 "recursive_triggers",
+	"_synchronous":        "synchronous",
+	"_sync":               "synchronous",
+}
+
 // ConnectionURL implements a SQLite connection struct.
 type ConnectionURL struct {
 	Database string
@@ -64,6 +96,10 @@ func (c ConnectionURL) String() (s string) {
 
 	// Converting options into URL values.
 	for k, v := range c.Options {
+		if sqladapter.IsPoolOption(k) {
+			// Handled by the adapter.
+			continue
+		}
 		vv.Set(k, v)
 	}
 
@@ -108,3 +144,41 @@ func ParseURL(s string) (conn ConnectionURL, err error) {
 
 	return conn, err
 }
+
+// driverDSN returns the name of the driver and the DSN that open the database
+// described by the given connection URL. Both drivers get the same options,
+// the options of github.com/mattn/go-sqlite3 are translated into pragmas for
+// modernc.org/sqlite.
+func driverDSN(s string) (driverName string, dsn string) {
+	driverName = defaultDriver
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return driverName, s
+	}
+
+	vv := u.Query()
+	if name := vv.Get(driverOption); name != "" {
+		driverName = name
+		vv.Del(driverOption)
+	}
+
+	if driverName == pureGoDriver {
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if pragma, ok := pureGoPragmas[k]; ok {
+				vv.Add("_pragma", pragma+"("+vv.Get(k)+")")
+				vv.Del(k)
+			}
+		}
+	}
+
+	u.RawQuery = vv.Encode()
+
+	return driverName, u.String()
+}
@@ -61,6 +61,13 @@ func TestConnectionURL(t *testing.T) {
 		t.Fatal(`Test failed, got:`, c.String())
 	}
 
+	// Pool options are not passed to the driver.
+	c.Options["max_open_conns"] = "1"
+
+	if c.String() != `file:///another/database?_busy_timeout=10000&cache=foobar&mode=ro` {
+		t.Fatal(`Test failed, got:`, c.String())
+	}
+
 }
 
 func TestParseConnectionURL(t *testing.T) {
@@ -107,3 +114,46 @@ func TestParseConnectionURL(t *testing.T) {
 	}
 
 }
+
+func TestDriverDSN(t *testing.T) {
+	c := ConnectionURL{
+		Database: "/path/to/database.db",
+		Options: map[string]string{
+			"_foreign_keys": "1",
+			"mode":          "ro",
+		},
+	}
+
+	driverName, dsn := driverDSN(c.String())
+	if driverName != defaultDriver {
+		t.Fatal(`Expecting default driver, got:`, driverName)
+	}
+	if defaultDriver == cgoDriver && dsn != c.String() {
+		t.Fatal(`Expecting the same DSN, got:`, dsn)
+	}
+
+	c.Options[driverOption] = pureGoDriver
+
+	driverName, dsn = driverDSN(c.String())
+	if driverName != pureGoDriver {
+		t.Fatal(`Expecting pure Go driver, got:`, driverName)
+	}
+	if dsn != `file:///path/to/database.db?_pragma=busy_timeout%2810000%29&_pragma=foreign_keys%281%29&mode=ro` {
+		t.Fatal(`Test failed, got:`, dsn)
+	}
+
+	c.Options[driverOption] = cgoDriver
+
+	driverName, dsn = driverDSN(c.String())
+	if driverName != cgoDriver {
+		t.Fatal(`Expecting cgo driver, got:`, driverName)
+	}
+	if dsn != `file:///path/to/database.db?_busy_timeout=10000&_foreign_keys=1&mode=ro` {
+		t.Fatal(`Test failed, got:`, dsn)
+	}
+
+	driverName, dsn = driverDSN(`:memory:`)
+	if driverName != defaultDriver || dsn != `:memory:` {
+		t.Fatal(`Test failed, got:`, driverName, dsn)
+	}
+}
@@ -19,7 +19,8 @@
 // OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
 // WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
 
-// Package sqlite wraps the github.com/lib/sqlite SQLite driver. See
+// Package sqlite wraps the github.com/mattn/go-sqlite3 SQLite driver, or the
+// cgo-free modernc.org/sqlite driver when built with the purego tag. See
 // https://upper.io/db.v3/sqlite for documentation, particularities and
 // usage examples.
 package sqlite
@@ -29,10 +30,12 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 
-	_ "github.com/mattn/go-sqlite3" // SQLite3 driver.
 	"upper.io/db.v3"
 	"upper.io/db.v3/internal/sqladapter"
 	"upper.io/db.v3/internal/sqladapter/compat"
@@ -76,6 +79,11 @@ func (d *database) CleanUp() error {
 	return nil
 }
 
+// Adapter returns the name of the adapter.
+func (d *database) Adapter() string {
+	return Adapter
+}
+
 // ConnectionURL returns this database's ConnectionURL.
 func (d *database) ConnectionURL() db.ConnectionURL {
 	return d.connURL
@@ -123,14 +131,22 @@ func (d *database) open() error {
 	// Binding with sqladapter's logic.
 	d.BaseDatabase = sqladapter.NewBaseDatabase(d)
 
+	if err := sqladapter.SetPoolOptions(d.BaseDatabase, sqladapter.ConnectionOptions(d.connURL)); err != nil {
+		return err
+	}
+
 	// Binding with sqlbuilder.
 	d.SQLBuilder = sqlbuilder.WithSession(d.BaseDatabase, template)
 
 	openFn := func() error {
 		openFiles := atomic.LoadInt32(&fileOpenCount)
 		if openFiles < maxOpenFiles {
-			sess, err := sql.Open("sqlite3", d.ConnectionURL().String())
+			sess, err := d.BaseDatabase.OpenSession(driverDSN(d.ConnectionURL().String()))
 			if err == nil {
+				sess.SetConnMaxLifetime(d.BaseDatabase.ConnMaxLifetime())
+				compat.SetConnMaxIdleTime(sess, d.BaseDatabase.ConnMaxIdleTime())
+				sess.SetMaxIdleConns(d.BaseDatabase.MaxIdleConns())
+				sess.SetMaxOpenConns(d.BaseDatabase.MaxOpenConns())
 				if err := d.BaseDatabase.BindSession(sess); err != nil {
 					return err
 				}
@@ -175,16 +191,76 @@ func (d *database) CompileStatement(stmt *exql.Statement, args []interface{}) (s
 	return sqlbuilder.Preprocess(compiled, args)
 }
 
+// TruncateStatements returns the statements that empty the table with the
+// given options. SQLite deletes the rows of the table, which doesn't check
+// foreign keys unless they're enabled, and restarts its AUTOINCREMENT by
+// forgetting it in sqlite_sequence.
+func (d *database) TruncateStatements(table string, stmt *exql.Statement) ([]*exql.Statement, error) {
+	stmts := []*exql.Statement{stmt}
+	if stmt.RestartIdentity && d.TableExists("sqlite_sequence") == nil {
+		name := strings.Replace(table, "'", "''", -1)
+		stmts = append(stmts, exql.RawSQL("DELETE FROM sqlite_sequence WHERE name = '"+name+"'"))
+	}
+	return stmts, nil
+}
+
 // Err allows sqladapter to translate some known errors into generic errors.
 func (d *database) Err(err error) error {
 	if err != nil {
 		if err == errTooManyOpenFiles {
 			return db.ErrTooManyClients
 		}
+		// SQLITE_BUSY, another connection holds a conflicting lock.
+		if strings.Contains(err.Error(), `database is locked`) {
+			return db.ErrSerializationFailure
+		}
+		if cErr := constraintError(err); cErr != nil {
+			return cErr
+		}
 	}
 	return err
 }
 
+// constraintError maps the SQLITE_CONSTRAINT errors into
+// db.ErrUniqueViolation, db.ErrForeignKeyViolation and db.ErrCheckViolation
+// values.
+func constraintError(err error) error {
+	s := err.Error()
+
+	// UNIQUE constraint failed: users.first_name, users.last_name
+	if i := strings.Index(s, `UNIQUE constraint failed: `); i >= 0 {
+		columns := strings.Split(constraintDetail(s[i+len(`UNIQUE constraint failed: `):]), ", ")
+		for j := range columns {
+			columns[j] = columns[j][strings.LastIndex(columns[j], ".")+1:]
+		}
+		return &db.ErrUniqueViolation{Column: strings.Join(columns, ", "), Err: err}
+	}
+
+	// SQLite does not report which foreign key failed.
+	if strings.Contains(s, `FOREIGN KEY constraint failed`) {
+		return &db.ErrForeignKeyViolation{Err: err}
+	}
+
+	// CHECK constraint failed: users_age_check
+	if i := strings.Index(s, `CHECK constraint failed: `); i >= 0 {
+		return &db.ErrCheckViolation{Constraint: constraintDetail(s[i+len(`CHECK constraint failed: `):]), Err: err}
+	}
+
+	return nil
+}
+
+// constraintDetail strips the extended SQLITE_CONSTRAINT result code some
+// drivers append to the message, like in "users.email (2067)".
+func constraintDetail(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndex(s, ` (`); i >= 0 && strings.HasSuffix(s, `)`) {
+		if code, err := strconv.Atoi(s[i+2 : len(s)-1]); err == nil && code&0xff == 19 {
+			s = s[:i]
+		}
+	}
+	return s
+}
+
 // StatementExec wraps the statement to execute around a transaction.
 func (d *database) StatementExec(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
 	d.mu.Lock()
@@ -221,7 +297,14 @@ func (d *database) Tx(ctx context.Context, fn func(tx sqlbuilder.Tx) error) erro
 	return sqladapter.RunTx(d, ctx, fn)
 }
 
-// NewDatabaseTx allows sqladapter start a transaction block.
+// TxWithRetry is like Tx but fn is run again, within a new transaction, if the
+// transaction fails because of a serialization failure or a deadlock.
+func (d *database) TxWithRetry(ctx context.Context, fn func(tx sqlbuilder.Tx) error, policy sqlbuilder.RetryPolicy) error {
+	return sqladapter.TxWithRetry(d, ctx, fn, policy)
+}
+
+// NewDatabaseTx allows sqladapter start a transaction block. If the session is
+// already within a transaction, the new one is nested using a savepoint.
 func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, error) {
 	clone, err := d.clone(ctx, true)
 	if err != nil {
@@ -230,6 +313,14 @@ func (d *database) NewDatabaseTx(ctx context.Context) (sqladapter.DatabaseTx, er
 	clone.mu.Lock()
 	defer clone.mu.Unlock()
 
+	if currTx := d.BaseDatabase.Transaction(); currTx != nil {
+		// Already within a transaction, nest the new one with a savepoint.
+		if err := clone.BindSavepoint(ctx, currTx); err != nil {
+			return nil, err
+		}
+		return sqladapter.NewDatabaseTx(clone), nil
+	}
+
 	openFn := func() error {
 		//sqlTx, err := compat.BeginTx(clone.BaseDatabase.Session(), ctx, nil) // Temporal fix.
 		sqlTx, err := clone.BaseDatabase.Session().Begin()
@@ -314,8 +405,111 @@ func (d *database) PrimaryKeys(tableName string) ([]string, error) {
 	return pk, nil
 }
 
+// DescribeTable returns the columns, indexes and foreign keys of the given
+// table.
+func (d *database) DescribeTable(tableName string) (*sqlbuilder.Table, error) {
+	table := &sqlbuilder.Table{Name: tableName}
+
+	var columns []struct {
+		Name    string  `db:"name"`
+		Type    string  `db:"type"`
+		NotNull int     `db:"notnull"`
+		Default *string `db:"dflt_value"`
+	}
+	if err := d.pragma(&columns, "TABLE_INFO", tableName); err != nil {
+		return nil, err
+	}
+	for _, column := range columns {
+		table.Columns = append(table.Columns, sqlbuilder.Column{
+			Name:     column.Name,
+			Type:     column.Type,
+			Nullable: column.NotNull == 0,
+			Default:  column.Default,
+		})
+	}
+
+	var err error
+	if table.PrimaryKey, err = d.PrimaryKeys(tableName); err != nil {
+		return nil, err
+	}
+
+	var indexes []struct {
+		Name   string `db:"name"`
+		Unique int    `db:"unique"`
+		Origin string `db:"origin"`
+	}
+	if err := d.pragma(&indexes, "INDEX_LIST", tableName); err != nil {
+		return nil, err
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i].Name < indexes[j].Name
+	})
+	for _, index := range indexes {
+		if index.Origin == "pk" {
+			continue
+		}
+		var indexColumns []struct {
+			Name string `db:"name"`
+		}
+		if err := d.pragma(&indexColumns, "INDEX_INFO", index.Name); err != nil {
+			return nil, err
+		}
+		idx := sqlbuilder.Index{Name: index.Name, Unique: index.Unique == 1}
+		for _, column := range indexColumns {
+			idx.Columns = append(idx.Columns, column.Name)
+		}
+		table.Indexes = append(table.Indexes, idx)
+	}
+
+	var foreignKeyColumns []struct {
+		ID       int    `db:"id"`
+		RefTable string `db:"table"`
+		From     string `db:"from"`
+		To       string `db:"to"`
+	}
+	if err := d.pragma(&foreignKeyColumns, "FOREIGN_KEY_LIST", tableName); err != nil {
+		return nil, err
+	}
+	// SQLite doesn't name foreign keys, columns of the same constraint share
+	// the same id.
+	for i, column := range foreignKeyColumns {
+		if i > 0 && foreignKeyColumns[i-1].ID == column.ID {
+			fk := &table.ForeignKeys[len(table.ForeignKeys)-1]
+			fk.Columns = append(fk.Columns, column.From)
+			fk.RefColumns = append(fk.RefColumns, column.To)
+			continue
+		}
+		table.ForeignKeys = append(table.ForeignKeys, sqlbuilder.ForeignKey{
+			Columns:    []string{column.From},
+			RefTable:   column.RefTable,
+			RefColumns: []string{column.To},
+		})
+	}
+
+	return table, nil
+}
+
+// pragma runs PRAGMA name('arg') and dumps its rows into dst.
+func (d *database) pragma(dst interface{}, name string, arg string) error {
+	stmt := exql.RawSQL(fmt.Sprintf("PRAGMA %s('%s')", name, arg))
+
+	rows, err := d.Query(stmt)
+	if err != nil {
+		return err
+	}
+
+	return sqlbuilder.NewIterator(rows).All(dst)
+}
+
 // WithContext creates a copy of the session on the given context.
 func (d *database) WithContext(ctx context.Context) sqlbuilder.Database {
 	newDB, _ := d.clone(ctx, false)
 	return newDB
 }
+
+// ReadOnly creates a copy of the session that can't change the database.
+func (d *database) ReadOnly() sqlbuilder.Database {
+	newDB, _ := d.clone(d.Context(), false)
+	newDB.BaseDatabase.UseReadOnly()
+	return newDB
+}
@@ -0,0 +1,11 @@
+// +build !purego
+
+package sqlite
+
+import (
+	_ "github.com/mattn/go-sqlite3" // SQLite3 driver.
+)
+
+// defaultDriver is the database/sql driver used unless the connection URL
+// asks for another one with the _driver option.
+const defaultDriver = cgoDriver
@@ -0,0 +1,12 @@
+// +build purego
+
+package sqlite
+
+import (
+	_ "modernc.org/sqlite" // Pure Go SQLite driver.
+)
+
+// defaultDriver is the database/sql driver used unless the connection URL
+// asks for another one with the _driver option. Building with the purego tag
+// links a driver that does not require cgo.
+const defaultDriver = pureGoDriver
@@ -45,6 +45,11 @@ const (
 	adapterTableAliasLayout    = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	adapterColumnAliasLayout   = `{{.Name}}{{if .Alias}} AS {{.Alias}}{{end}}`
 	adapterSortByColumnLayout  = `{{.Column}} {{.Order}}`
+	adapterWindowLayout        = `{{.Function}} OVER ({{.Definition}})`
+	adapterJSONPathLayout      = `JSON_EXTRACT({{.Column}}, '{{.Path}}')`
+	adapterFullTextLayout      = `{{.Column}} MATCH ?`
+	adapterFullTextRankLayout  = `rank`
+	adapterUpdateFromLayout    = `{{if .Tables}}FROM {{.Tables}}{{end}} {{.Joins}}`
 
 	adapterOrderByLayout = `
     {{if .SortColumns}}
@@ -86,27 +91,43 @@ const (
     {{end}}
   `
 
+	adapterWithLayout = `
+    WITH {{if .Recursive}}RECURSIVE {{end}}{{.CTEs}}
+  `
+
+	adapterCompoundLayout = `
+    {{range .}}{{if .Operator}} {{.Operator}} {{end}}SELECT * FROM ({{.Query}}){{end}}
+  `
+
 	adapterSelectLayout = `
-    SELECT
-      {{if .Distinct}}
-        DISTINCT
-      {{end}}
+    {{if .With}}
+      {{.With}}
+    {{end}}
 
-      {{if .Columns}}
-        {{.Columns}}
-      {{else}}
-        *
-      {{end}}
+    {{if .Compound}}
+      {{.Compound}}
+    {{else}}
+      SELECT
+        {{if .Distinct}}
+          DISTINCT
+        {{end}}
 
-      {{if .Table}}
-        FROM {{.Table}}
-      {{end}}
+        {{if .Columns}}
+          {{.Columns}}
+        {{else}}
+          *
+        {{end}}
 
-      {{.Joins}}
+        {{if .Table}}
+          FROM {{.Table}}
+        {{end}}
 
-      {{.Where}}
+        {{.Joins}}
+
+        {{.Where}}
 
-      {{.GroupBy}}
+        {{.GroupBy}}
+    {{end}}
 
       {{.OrderBy}}
 
@@ -130,6 +151,7 @@ const (
     UPDATE
       {{.Table}}
     SET {{.ColumnValues}}
+      {{.From}}
       {{ .Where }}
   `
 
@@ -143,17 +165,34 @@ const (
 	adapterInsertLayout = `
     INSERT INTO {{.Table}}
       {{if .Columns }}({{.Columns}}){{end}}
-    {{if .Values}}
+    {{if .Query}}
+      {{.Query}}
+    {{else if .Values}}
       VALUES
       {{.Values}}
     {{else}}
       DEFAULT VALUES
     {{end}}
+    {{if .OnConflict}}
+      {{.OnConflict}}
+    {{end}}
     {{if .Returning}}
       RETURNING {{.Returning}}
     {{end}}
   `
 
+	adapterOnConflictLayout = `
+    ON CONFLICT
+      {{if .Target}}({{range $i, $c := .Target}}{{if $i}}, {{end}}{{$c}}{{end}}){{end}}
+    {{if .Update}}
+      DO UPDATE SET {{.Update}}
+    {{else}}
+      DO NOTHING
+    {{end}}
+  `
+
+	adapterExcludedColumn = `excluded.{{.}}`
+
 	adapterTruncateLayout = `
     DELETE FROM {{.Table}}
   `
@@ -166,6 +205,31 @@ const (
     DROP TABLE {{.Table}}
   `
 
+	adapterCreateTableLayout = `
+    CREATE TABLE {{.Table}} ({{.Definitions}})
+  `
+
+	adapterAlterTableLayout = `
+    ALTER TABLE {{.Table}} {{.Definitions}}
+  `
+
+	adapterCreateIndexLayout = `
+    CREATE {{if .Unique}}UNIQUE {{end}}INDEX {{.Index}} ON {{.Table}} ({{.Columns}})
+  `
+
+	adapterAddColumnLayout = `ADD COLUMN {{.}}`
+
+	adapterDropColumnLayout = `DROP COLUMN {{.}}`
+
+	adapterColumnDefinitionLayout = `
+    {{.Name}}
+    {{if .AutoIncrement}}INTEGER PRIMARY KEY AUTOINCREMENT{{else}}{{.Type}}{{if .PrimaryKey}} PRIMARY KEY{{end}}{{end}}
+    {{if .NotNull}}NOT NULL{{end}}
+    {{if .Unique}}UNIQUE{{end}}
+    {{if .Default}}DEFAULT {{.Default}}{{end}}
+    {{if .References}}REFERENCES {{.References}}{{end}}
+  `
+
 	adapterGroupByLayout = `
     {{if .GroupColumns}}
       GROUP BY {{.GroupColumns}}
@@ -173,36 +237,66 @@ const (
   `
 )
 
+var adapterColumnTypes = map[exql.ColumnType]string{
+	exql.TypeBoolean:    `BOOLEAN`,
+	exql.TypeInteger:    `INTEGER`,
+	exql.TypeBigInteger: `INTEGER`,
+	exql.TypeFloat:      `REAL`,
+	exql.TypeDecimal:    `NUMERIC`,
+	exql.TypeString:     `VARCHAR({{.Size}})`,
+	exql.TypeText:       `TEXT`,
+	exql.TypeTimestamp:  `DATETIME`,
+	exql.TypeDate:       `DATE`,
+	exql.TypeBinary:     `BLOB`,
+	exql.TypeJSON:       `TEXT`,
+}
+
 var template = &exql.Template{
-	ColumnSeparator:     adapterColumnSeparator,
-	IdentifierSeparator: adapterIdentifierSeparator,
-	IdentifierQuote:     adapterIdentifierQuote,
-	ValueSeparator:      adapterValueSeparator,
-	ValueQuote:          adapterValueQuote,
-	AndKeyword:          adapterAndKeyword,
-	OrKeyword:           adapterOrKeyword,
-	DescKeyword:         adapterDescKeyword,
-	AscKeyword:          adapterAscKeyword,
-	AssignmentOperator:  adapterAssignmentOperator,
-	ClauseGroup:         adapterClauseGroup,
-	ClauseOperator:      adapterClauseOperator,
-	ColumnValue:         adapterColumnValue,
-	TableAliasLayout:    adapterTableAliasLayout,
-	ColumnAliasLayout:   adapterColumnAliasLayout,
-	SortByColumnLayout:  adapterSortByColumnLayout,
-	WhereLayout:         adapterWhereLayout,
-	JoinLayout:          adapterJoinLayout,
-	OnLayout:            adapterOnLayout,
-	UsingLayout:         adapterUsingLayout,
-	OrderByLayout:       adapterOrderByLayout,
-	InsertLayout:        adapterInsertLayout,
-	SelectLayout:        adapterSelectLayout,
-	UpdateLayout:        adapterUpdateLayout,
-	DeleteLayout:        adapterDeleteLayout,
-	TruncateLayout:      adapterTruncateLayout,
-	DropDatabaseLayout:  adapterDropDatabaseLayout,
-	DropTableLayout:     adapterDropTableLayout,
-	CountLayout:         adapterSelectCountLayout,
-	GroupByLayout:       adapterGroupByLayout,
-	Cache:               cache.NewCache(),
+	ColumnSeparator:        adapterColumnSeparator,
+	IdentifierSeparator:    adapterIdentifierSeparator,
+	IdentifierQuote:        adapterIdentifierQuote,
+	ValueSeparator:         adapterValueSeparator,
+	ValueQuote:             adapterValueQuote,
+	AndKeyword:             adapterAndKeyword,
+	OrKeyword:              adapterOrKeyword,
+	DescKeyword:            adapterDescKeyword,
+	AscKeyword:             adapterAscKeyword,
+	AssignmentOperator:     adapterAssignmentOperator,
+	ClauseGroup:            adapterClauseGroup,
+	ClauseOperator:         adapterClauseOperator,
+	ColumnValue:            adapterColumnValue,
+	TableAliasLayout:       adapterTableAliasLayout,
+	ColumnAliasLayout:      adapterColumnAliasLayout,
+	SortByColumnLayout:     adapterSortByColumnLayout,
+	WhereLayout:            adapterWhereLayout,
+	WindowLayout:           adapterWindowLayout,
+	CompoundLayout:         adapterCompoundLayout,
+	WithLayout:             adapterWithLayout,
+	JoinLayout:             adapterJoinLayout,
+	JSONPathLayout:         adapterJSONPathLayout,
+	FullTextLayout:         adapterFullTextLayout,
+	FullTextRankLayout:     adapterFullTextRankLayout,
+	OnLayout:               adapterOnLayout,
+	UsingLayout:            adapterUsingLayout,
+	OrderByLayout:          adapterOrderByLayout,
+	InsertLayout:           adapterInsertLayout,
+	OnConflictLayout:       adapterOnConflictLayout,
+	ExcludedColumn:         adapterExcludedColumn,
+	SelectLayout:           adapterSelectLayout,
+	UpdateLayout:           adapterUpdateLayout,
+	UpdateFromLayout:       adapterUpdateFromLayout,
+	DeleteLayout:           adapterDeleteLayout,
+	TruncateLayout:         adapterTruncateLayout,
+	DropDatabaseLayout:     adapterDropDatabaseLayout,
+	DropTableLayout:        adapterDropTableLayout,
+	CreateTableLayout:      adapterCreateTableLayout,
+	AlterTableLayout:       adapterAlterTableLayout,
+	CreateIndexLayout:      adapterCreateIndexLayout,
+	AddColumnLayout:        adapterAddColumnLayout,
+	DropColumnLayout:       adapterDropColumnLayout,
+	ColumnDefinitionLayout: adapterColumnDefinitionLayout,
+	ColumnTypes:            adapterColumnTypes,
+	CountLayout:            adapterSelectCountLayout,
+	GroupByLayout:          adapterGroupByLayout,
+	Cache:                  cache.NewCache(),
 }
@@ -144,6 +144,87 @@ func TestTemplateSelect(t *testing.T) {
 		`SELECT DATE()`,
 		b.Select(db.Raw("DATE()")).String(),
 	)
+
+	assert.Equal(
+		`SELECT * FROM (SELECT "name" FROM "artist") UNION SELECT * FROM (SELECT "name" FROM "band" ORDER BY "name" ASC LIMIT 5) ORDER BY "name" ASC`,
+		b.Select("name").From("artist").
+			Union(b.Select("name").From("band").OrderBy("name").Limit(5)).
+			OrderBy("name").
+			String(),
+	)
+}
+
+func TestTemplateDDL(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`CREATE TABLE "books" ("id" INTEGER PRIMARY KEY AUTOINCREMENT, "title" VARCHAR(80) NOT NULL, "price" NUMERIC DEFAULT 0, "author_id" INTEGER, FOREIGN KEY ("author_id") REFERENCES "authors" ("id"))`,
+		b.CreateTable("books").Columns(
+			sqlbuilder.NewColumn("id", sqlbuilder.Integer).PrimaryKey().AutoIncrement(),
+			sqlbuilder.NewColumn("title", sqlbuilder.String(80)).NotNull(),
+			sqlbuilder.NewColumn("price", sqlbuilder.Decimal(10, 2)).Default(0),
+			sqlbuilder.NewColumn("author_id", sqlbuilder.Integer).References("authors", "id"),
+		).String(),
+	)
+
+	assert.Equal(
+		`ALTER TABLE "books" ADD COLUMN "meta" TEXT`,
+		b.AlterTable("books").AddColumn(sqlbuilder.NewColumn("meta", sqlbuilder.JSON)).String(),
+	)
+
+	assert.Equal(
+		`CREATE UNIQUE INDEX "books_title_idx" ON "books" ("title")`,
+		b.CreateIndex("books_title_idx").On("books", "title").Unique().String(),
+	)
+}
+
+func TestTemplateJSONPath(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`SELECT * FROM "users" WHERE (JSON_EXTRACT("data", '$.address.city') = $1)`,
+		b.SelectFrom("users").Where(db.Cond{db.JSONPath("data.address.city"): "Paris"}).String(),
+	)
+}
+
+func TestTemplateFullText(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	search := db.FullText("body", "cats")
+	sel := b.SelectFrom("posts").Where(search).OrderBy(search.Rank())
+	assert.Equal(
+		`SELECT * FROM "posts" WHERE ("body" MATCH $1) ORDER BY rank`,
+		sel.String(),
+	)
+	// The rank takes no argument, only the one of the search is bound.
+	assert.Equal([]interface{}{"cats"}, sel.Arguments())
+}
+
+func TestTemplateInsertFromSelect(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	sel := b.Select("name").From("artist_archive").Where("id >", 10)
+	assert.Equal(
+		`INSERT INTO "artist" ("name") SELECT "name" FROM "artist_archive" WHERE ("id" > $1)`,
+		b.InsertInto("artist").Columns("name").ValuesFromSelect(sel).String(),
+	)
+}
+
+func TestTemplateUpdateDeleteJoins(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	assert.Equal(
+		`UPDATE "posts" SET "author_name" = users.name FROM "users" WHERE (posts.user_id = users.id)`,
+		b.Update("posts").Set("author_name = users.name").From("users").Where("posts.user_id = users.id").String(),
+	)
+
+	_, _, err := b.DeleteFrom("posts").Using("users").Compile()
+	assert.Equal(db.ErrUnsupported, err)
 }
 
 func TestTemplateInsert(t *testing.T) {
@@ -188,6 +269,28 @@ func TestTemplateInsert(t *testing.T) {
 	)
 }
 
+func TestTemplateUpsert(t *testing.T) {
+	b := sqlbuilder.WithTemplate(template)
+	assert := assert.New(t)
+
+	artist := map[string]interface{}{"id": 12, "name": "Chavela Vargas"}
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "name" = excluded."name"`,
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoUpdate().String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO UPDATE SET "name" = $3`,
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoUpdate("name", "Chavela").String(),
+	)
+
+	assert.Equal(
+		`INSERT INTO "artist" ("id", "name") VALUES ($1, $2) ON CONFLICT ("id") DO NOTHING`,
+		b.InsertInto("artist").Values(artist).OnConflict("id").DoNothing().String(),
+	)
+}
+
 func TestTemplateUpdate(t *testing.T) {
 	b := sqlbuilder.WithTemplate(template)
 	assert := assert.New(t)
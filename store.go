@@ -0,0 +1,382 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+//go:build go1.18
+// +build go1.18
+
+package db
+
+import (
+	"context"
+	"reflect"
+)
+
+// Store is a typed wrapper around a Collection, items that go into or come
+// out of a Store are values of type T instead of interface{}:
+//
+//	type Artist struct {
+//		ID   int64  `db:"id,omitempty"`
+//		Name string `db:"name"`
+//	}
+//
+//	artists := db.NewStore[Artist](sess.Collection("artist"))
+//
+//	items, err := artists.Find(db.Cond{"name LIKE": "A%"}).All()
+//	...
+//	artist, err := artists.Find(db.Cond{"id": 1}).One()
+//
+// The untyped API remains available through Collection().
+//
+// If T has a field tagged with the softdelete option, like
+// `db:"deleted_at,softdelete"`, result sets of the Store skip the rows that
+// have a value on that column and Delete() sets the column to the current
+// time instead of removing rows, see Unscoped() and OnlyDeleted().
+type Store[T any] struct {
+	coll       Collection
+	softDelete string
+}
+
+// NewStore creates a Store that reads and writes values of type T into the
+// given collection.
+func NewStore[T any](c Collection) *Store[T] {
+	return &Store[T]{
+		coll:       c,
+		softDelete: SoftDeleteColumn(reflect.TypeOf((*T)(nil)).Elem()),
+	}
+}
+
+// Collection returns the underlying untyped collection.
+func (s *Store[T]) Collection() Collection {
+	return s.coll
+}
+
+// Name returns the name of the collection.
+func (s *Store[T]) Name() string {
+	return s.coll.Name()
+}
+
+// Exists returns true if the collection exists, false otherwise.
+func (s *Store[T]) Exists() bool {
+	return s.coll.Exists()
+}
+
+// Insert inserts a new item into the collection and returns its primary key.
+func (s *Store[T]) Insert(item T) (interface{}, error) {
+	return s.coll.Insert(item)
+}
+
+// InsertContext is like Insert() but the query runs within the given context.
+func (s *Store[T]) InsertContext(ctx context.Context, item T) (interface{}, error) {
+	return s.coll.InsertContext(ctx, item)
+}
+
+// InsertReturning inserts the given item and updates it with the values the
+// database assigned to it.
+func (s *Store[T]) InsertReturning(item *T) error {
+	return s.coll.InsertReturning(item)
+}
+
+// InsertReturningContext is like InsertReturning() but the query runs within
+// the given context.
+func (s *Store[T]) InsertReturningContext(ctx context.Context, item *T) error {
+	return s.coll.InsertReturningContext(ctx, item)
+}
+
+// CopyFrom inserts all the given items using the fastest method the adapter
+// supports, see Collection.CopyFrom().
+func (s *Store[T]) CopyFrom(items []T) error {
+	return s.coll.CopyFrom(items)
+}
+
+// CopyFromContext is like CopyFrom() but the queries run within the given
+// context.
+func (s *Store[T]) CopyFromContext(ctx context.Context, items []T) error {
+	return s.coll.CopyFromContext(ctx, items)
+}
+
+// UpdateReturning updates the record the given item points to and refreshes
+// it with the values stored in the database.
+func (s *Store[T]) UpdateReturning(item *T) error {
+	return s.coll.UpdateReturning(item)
+}
+
+// UpdateReturningContext is like UpdateReturning() but the query runs within
+// the given context.
+func (s *Store[T]) UpdateReturningContext(ctx context.Context, item *T) error {
+	return s.coll.UpdateReturningContext(ctx, item)
+}
+
+// Upsert inserts the given item or updates it if it already exists.
+func (s *Store[T]) Upsert(item *T) error {
+	return s.coll.Upsert(item)
+}
+
+// UpsertContext is like Upsert() but the query runs within the given context.
+func (s *Store[T]) UpsertContext(ctx context.Context, item *T) error {
+	return s.coll.UpsertContext(ctx, item)
+}
+
+// Find defines a new result set of items of type T, see Collection.Find().
+func (s *Store[T]) Find(conds ...interface{}) *TypedResult[T] {
+	return newTypedResult[T](s.coll.Find(conds...), s.softDelete)
+}
+
+// FindByIDs defines a new result set of the items of type T with the given
+// primary key values, see Collection.FindByIDs().
+func (s *Store[T]) FindByIDs(ids interface{}, chunkSize int) *TypedResult[T] {
+	return newTypedResult[T](s.coll.FindByIDs(ids, chunkSize), s.softDelete)
+}
+
+// Truncate removes all items from the collection, see Collection.Truncate.
+func (s *Store[T]) Truncate(opts ...TruncateOption) error {
+	return s.coll.Truncate(opts...)
+}
+
+// TruncateContext is like Truncate() but the query runs within the given
+// context.
+func (s *Store[T]) TruncateContext(ctx context.Context, opts ...TruncateOption) error {
+	return s.coll.TruncateContext(ctx, opts...)
+}
+
+// TypedResult is a typed wrapper around a Result, it has the same methods as
+// Result but the ones that fetch items return values of type T. The name
+// Result[T] can't be used because it would clash with the Result interface.
+type TypedResult[T any] struct {
+	res Result
+}
+
+// newTypedResult wraps res, which is soft deleted on the given column if it's
+// not empty, see Result.SoftDelete(). Result sets of items with delete hooks
+// are given their type, see Result.Model().
+func newTypedResult[T any](res Result, softDelete string) *TypedResult[T] {
+	if hasDeleteHooks[T]() {
+		res = res.Model(new(T))
+	}
+	if softDelete != "" {
+		res = res.SoftDelete(softDelete)
+	}
+	return &TypedResult[T]{res: res}
+}
+
+// hasDeleteHooks reports whether items of type T have any delete hook.
+func hasDeleteHooks[T any]() bool {
+	var item interface{} = new(T)
+	_, before := item.(BeforeDeleteHook)
+	_, after := item.(AfterDeleteHook)
+	return before || after
+}
+
+func (r *TypedResult[T]) with(res Result) *TypedResult[T] {
+	return &TypedResult[T]{res: res}
+}
+
+// Unscoped is like Result.Unscoped().
+func (r *TypedResult[T]) Unscoped() *TypedResult[T] {
+	return r.with(r.res.Unscoped())
+}
+
+// OnlyDeleted is like Result.OnlyDeleted().
+func (r *TypedResult[T]) OnlyDeleted() *TypedResult[T] {
+	return r.with(r.res.OnlyDeleted())
+}
+
+// Result returns the underlying untyped result.
+func (r *TypedResult[T]) Result() Result {
+	return r.res
+}
+
+// String returns the query that represents the result set.
+func (r *TypedResult[T]) String() string {
+	return r.res.String()
+}
+
+// Limit is like Result.Limit().
+func (r *TypedResult[T]) Limit(n int) *TypedResult[T] {
+	return r.with(r.res.Limit(n))
+}
+
+// Offset is like Result.Offset().
+func (r *TypedResult[T]) Offset(n int) *TypedResult[T] {
+	return r.with(r.res.Offset(n))
+}
+
+// OrderBy is like Result.OrderBy().
+func (r *TypedResult[T]) OrderBy(fields ...interface{}) *TypedResult[T] {
+	return r.with(r.res.OrderBy(fields...))
+}
+
+// Select is like Result.Select().
+func (r *TypedResult[T]) Select(fields ...interface{}) *TypedResult[T] {
+	return r.with(r.res.Select(fields...))
+}
+
+// Where is like Result.Where().
+func (r *TypedResult[T]) Where(conds ...interface{}) *TypedResult[T] {
+	return r.with(r.res.Where(conds...))
+}
+
+// And is like Result.And().
+func (r *TypedResult[T]) And(conds ...interface{}) *TypedResult[T] {
+	return r.with(r.res.And(conds...))
+}
+
+// Group is like Result.Group().
+func (r *TypedResult[T]) Group(fields ...interface{}) *TypedResult[T] {
+	return r.with(r.res.Group(fields...))
+}
+
+// Paginate is like Result.Paginate().
+func (r *TypedResult[T]) Paginate(pageSize uint) *TypedResult[T] {
+	return r.with(r.res.Paginate(pageSize))
+}
+
+// Page is like Result.Page().
+func (r *TypedResult[T]) Page(pageNumber uint) *TypedResult[T] {
+	return r.with(r.res.Page(pageNumber))
+}
+
+// Cursor is like Result.Cursor().
+func (r *TypedResult[T]) Cursor(cursorColumn string) *TypedResult[T] {
+	return r.with(r.res.Cursor(cursorColumn))
+}
+
+// NextPage is like Result.NextPage().
+func (r *TypedResult[T]) NextPage(cursorValue interface{}) *TypedResult[T] {
+	return r.with(r.res.NextPage(cursorValue))
+}
+
+// PrevPage is like Result.PrevPage().
+func (r *TypedResult[T]) PrevPage(cursorValue interface{}) *TypedResult[T] {
+	return r.with(r.res.PrevPage(cursorValue))
+}
+
+// NextPageCursor is like Result.NextPageCursor().
+func (r *TypedResult[T]) NextPageCursor(items []T) (Cursor, error) {
+	return r.res.NextPageCursor(&items)
+}
+
+// PrevPageCursor is like Result.PrevPageCursor().
+func (r *TypedResult[T]) PrevPageCursor(items []T) (Cursor, error) {
+	return r.res.PrevPageCursor(&items)
+}
+
+// One fetches the first item of the result set.
+func (r *TypedResult[T]) One() (T, error) {
+	return r.OneContext(context.Background())
+}
+
+// OneContext is like One() but the query runs within the given context.
+func (r *TypedResult[T]) OneContext(ctx context.Context) (T, error) {
+	var item T
+	if err := r.res.OneContext(ctx, &item); err != nil {
+		var zero T
+		return zero, err
+	}
+	return item, nil
+}
+
+// All fetches all the items of the result set.
+func (r *TypedResult[T]) All() ([]T, error) {
+	return r.AllContext(context.Background())
+}
+
+// AllContext is like All() but the query runs within the given context.
+func (r *TypedResult[T]) AllContext(ctx context.Context) ([]T, error) {
+	var items []T
+	if err := r.res.AllContext(ctx, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Next fetches the next item of the result set into the given pointer, see
+// Result.Next().
+func (r *TypedResult[T]) Next(item *T) bool {
+	return r.res.Next(item)
+}
+
+// NextContext is like Next() but the query runs within the given context.
+func (r *TypedResult[T]) NextContext(ctx context.Context, item *T) bool {
+	return r.res.NextContext(ctx, item)
+}
+
+// Err returns the last error that happened while iterating the result set.
+func (r *TypedResult[T]) Err() error {
+	return r.res.Err()
+}
+
+// Update modifies all the items of the result set, see Result.Update().
+func (r *TypedResult[T]) Update(values interface{}) error {
+	return r.res.Update(values)
+}
+
+// UpdateContext is like Update() but the query runs within the given context.
+func (r *TypedResult[T]) UpdateContext(ctx context.Context, values interface{}) error {
+	return r.res.UpdateContext(ctx, values)
+}
+
+// Delete removes all the items of the result set. If T has a soft delete
+// column the items are kept and the column is set to the current time
+// instead, unless the result set is Unscoped().
+func (r *TypedResult[T]) Delete() error {
+	return r.DeleteContext(context.Background())
+}
+
+// DeleteContext is like Delete() but the query runs within the given context.
+func (r *TypedResult[T]) DeleteContext(ctx context.Context) error {
+	return r.res.DeleteContext(ctx)
+}
+
+// Count returns the number of items of the result set.
+func (r *TypedResult[T]) Count(opts ...CountOption) (uint64, error) {
+	return r.res.Count(opts...)
+}
+
+// CountContext is like Count() but the query runs within the given context.
+func (r *TypedResult[T]) CountContext(ctx context.Context, opts ...CountOption) (uint64, error) {
+	return r.res.CountContext(ctx, opts...)
+}
+
+// Exists returns true if the result set has at least one item.
+func (r *TypedResult[T]) Exists() (bool, error) {
+	return r.res.Exists()
+}
+
+// ExistsContext is like Exists() but the query runs within the given
+// context.
+func (r *TypedResult[T]) ExistsContext(ctx context.Context) (bool, error) {
+	return r.res.ExistsContext(ctx)
+}
+
+// TotalPages is like Result.TotalPages().
+func (r *TypedResult[T]) TotalPages() (uint, error) {
+	return r.res.TotalPages()
+}
+
+// TotalEntries is like Result.TotalEntries().
+func (r *TypedResult[T]) TotalEntries() (uint64, error) {
+	return r.res.TotalEntries()
+}
+
+// Close closes the result set.
+func (r *TypedResult[T]) Close() error {
+	return r.res.Close()
+}
@@ -0,0 +1,146 @@
+//go:build go1.18
+// +build go1.18
+
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type storeItem struct {
+	ID   int64
+	Name string
+}
+
+// fakeResult implements the methods of Result that TestStore needs, any other
+// method panics.
+type fakeResult struct {
+	Result
+
+	items []storeItem
+	conds []interface{}
+
+	// softDelete and deleted record the soft delete column and scope of the
+	// result.
+	softDelete string
+	deleted    string
+}
+
+func (r *fakeResult) with(fn func(*fakeResult)) Result {
+	n := *r
+	fn(&n)
+	return &n
+}
+
+func (r *fakeResult) Where(conds ...interface{}) Result {
+	return r.with(func(n *fakeResult) { n.conds = conds })
+}
+
+func (r *fakeResult) SoftDelete(column string) Result {
+	return r.with(func(n *fakeResult) { n.softDelete = column })
+}
+
+func (r *fakeResult) Unscoped() Result {
+	return r.with(func(n *fakeResult) { n.deleted = "unscoped" })
+}
+
+func (r *fakeResult) OnlyDeleted() Result {
+	return r.with(func(n *fakeResult) { n.deleted = "only" })
+}
+
+func (r *fakeResult) OneContext(ctx context.Context, dst interface{}) error {
+	if len(r.items) == 0 {
+		return ErrNoMoreRows
+	}
+	*(dst.(*storeItem)) = r.items[0]
+	return nil
+}
+
+func (r *fakeResult) AllContext(ctx context.Context, dst interface{}) error {
+	*(dst.(*[]storeItem)) = append([]storeItem(nil), r.items...)
+	return nil
+}
+
+type fakeCollection struct {
+	Collection
+
+	items []storeItem
+}
+
+func (c *fakeCollection) Find(conds ...interface{}) Result {
+	return &fakeResult{items: c.items, conds: conds}
+}
+
+func TestStore(t *testing.T) {
+	items := []storeItem{{1, "Ozzie"}, {2, "Flea"}}
+
+	store := NewStore[storeItem](&fakeCollection{items: items})
+
+	all, err := store.Find().All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(items, all) {
+		t.Fatalf("Got: %v, Expecting: %v", all, items)
+	}
+
+	res := store.Find().Where(Cond{"id": 1})
+	if conds := res.Result().(*fakeResult).conds; !reflect.DeepEqual([]interface{}{Cond{"id": 1}}, conds) {
+		t.Fatalf("Got: %v, Expecting conditions to be kept", conds)
+	}
+
+	one, err := res.One()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if one != items[0] {
+		t.Fatalf("Got: %v, Expecting: %v", one, items[0])
+	}
+
+	empty := NewStore[storeItem](&fakeCollection{})
+	if _, err := empty.Find().One(); err != ErrNoMoreRows {
+		t.Fatalf("Got: %v, Expecting: %v", err, ErrNoMoreRows)
+	}
+}
+
+type softDeletedItem struct {
+	storeItem
+	DeletedAt *time.Time `db:"deleted_at,softdelete"`
+}
+
+func TestStoreSoftDelete(t *testing.T) {
+	coll := &fakeCollection{}
+
+	if column := NewStore[storeItem](coll).Find().Result().(*fakeResult).softDelete; column != "" {
+		t.Fatalf("Got: %q, Expecting no soft delete column", column)
+	}
+
+	res := NewStore[softDeletedItem](coll).Find(Cond{"id": 1})
+
+	scopes := []struct {
+		res     *TypedResult[softDeletedItem]
+		conds   []interface{}
+		deleted string
+	}{
+		{res, []interface{}{Cond{"id": 1}}, ""},
+		{res.Unscoped(), []interface{}{Cond{"id": 1}}, "unscoped"},
+		{res.OnlyDeleted(), []interface{}{Cond{"id": 1}}, "only"},
+		{res.Unscoped().Where(Cond{"id": 2}), []interface{}{Cond{"id": 2}}, "unscoped"},
+	}
+
+	for i := range scopes {
+		scoped := scopes[i].res.Result().(*fakeResult)
+		if scoped.softDelete != "deleted_at" {
+			t.Fatalf("Got: %q, Expecting: %q", scoped.softDelete, "deleted_at")
+		}
+		if !reflect.DeepEqual(scopes[i].conds, scoped.conds) {
+			t.Fatalf("Got: %v, Expecting: %v", scoped.conds, scopes[i].conds)
+		}
+		if scoped.deleted != scopes[i].deleted {
+			t.Fatalf("Got: %q, Expecting: %q", scoped.deleted, scopes[i].deleted)
+		}
+	}
+}
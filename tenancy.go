@@ -0,0 +1,124 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultTenantColumn is the column that holds the tenant of the rows of
+// scoped tables when Tenancy.Column is empty.
+const DefaultTenantColumn = "tenant_id"
+
+type tenantKey struct{}
+
+type noTenantKey struct{}
+
+// WithTenant returns a copy of ctx that carries the given tenant, the
+// collections of a session with that context only read and write the rows of
+// the tenant on scoped tables, see Tenancy.
+func WithTenant(ctx context.Context, tenant interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// WithoutTenant returns a copy of ctx that lifts the tenant scope, the
+// collections of a session with that context read and write the rows of
+// every tenant. It's meant for administrative tasks, like migrations.
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTenantKey{}, true)
+}
+
+// Tenant returns the tenant carried by ctx, if any.
+func Tenant(ctx context.Context) (interface{}, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	tenant := ctx.Value(tenantKey{})
+	return tenant, tenant != nil
+}
+
+// Unscoped reports whether the tenant scope was lifted from ctx with
+// WithoutTenant.
+func Unscoped(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	unscoped, _ := ctx.Value(noTenantKey{}).(bool)
+	return unscoped
+}
+
+// Tenancy is a registry of the tables that are scoped by tenant, see
+// Settings.SetTenancy. The statements SQL sessions build on scoped tables,
+// through collections or with the SQL builder, only read, update and delete
+// the rows whose tenant column matches the tenant of the context they run
+// within, and they set it on the rows they insert. That's the context given to
+// the methods whose name ends with Context, or the session context if it
+// carries no tenant. Joined tables are scoped within the ON clause of their
+// join. Statements whose contexts carry no tenant fail with ErrMissingTenant
+// on scoped tables, unless the scope was lifted with WithoutTenant.
+//
+// The tenant column can't be updated: collections leave it out of the values
+// they update with, and the SQL builder fails with ErrTenantColumn. Truncate
+// and Upsert fail with ErrUnsupported since they would delete or update the
+// rows of other tenants. Raw SQL is left as it is. It's safe for concurrent
+// use.
+type Tenancy struct {
+	column string
+
+	mu     sync.RWMutex
+	tables map[string]struct{}
+}
+
+// NewTenancy returns a registry of scoped tables that keep the tenant of their
+// rows in the given column, or in DefaultTenantColumn if it's empty.
+func NewTenancy(column string) *Tenancy {
+	if column == "" {
+		column = DefaultTenantColumn
+	}
+	return &Tenancy{column: column, tables: map[string]struct{}{}}
+}
+
+// Column returns the column that holds the tenant of the rows.
+func (t *Tenancy) Column() string {
+	return t.column
+}
+
+// Register scopes the given tables by tenant.
+func (t *Tenancy) Register(tables ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, table := range tables {
+		t.tables[table] = struct{}{}
+	}
+}
+
+// Scoped reports whether the given table is scoped by tenant.
+func (t *Tenancy) Scoped(table string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.tables[table]
+	return ok
+}
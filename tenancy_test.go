@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenancy(t *testing.T) {
+	ctx := context.Background()
+
+	_, ok := Tenant(ctx)
+	assert.False(t, ok)
+	assert.False(t, Unscoped(ctx))
+
+	tenant, ok := Tenant(WithTenant(ctx, 42))
+	assert.True(t, ok)
+	assert.Equal(t, 42, tenant)
+	assert.True(t, Unscoped(WithoutTenant(ctx)))
+
+	var none *Tenancy
+	assert.False(t, none.Scoped("accounts"))
+
+	tenancy := NewTenancy("")
+	assert.Equal(t, DefaultTenantColumn, tenancy.Column())
+	tenancy.Register("accounts", "invoices")
+	assert.True(t, tenancy.Scoped("accounts"))
+	assert.False(t, tenancy.Scoped("plans"))
+
+	assert.Equal(t, "org_id", NewTenancy("org_id").Column())
+}
@@ -22,6 +22,7 @@
 package db_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -32,8 +33,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
-	"gopkg.in/mgo.v2"
-	"gopkg.in/mgo.v2/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
 	"upper.io/db.v3"
 	"upper.io/db.v3/mongo"
 	"upper.io/db.v3/mssql"
@@ -126,19 +127,20 @@ func init() {
 
 var setupFn = map[string]func(driver interface{}) error{
 	`mongo`: func(driver interface{}) error {
-		if mgod, ok := driver.(*mgo.Session); ok {
-			var col *mgo.Collection
-			col = mgod.DB("upperio_tests").C("birthdays")
-			col.DropCollection()
+		if client, ok := driver.(*mongodriver.Client); ok {
+			ctx := context.Background()
+			var col *mongodriver.Collection
+			col = client.Database("upperio_tests").Collection("birthdays")
+			col.Drop(ctx)
 
-			col = mgod.DB("upperio_tests").C("fibonacci")
-			col.DropCollection()
+			col = client.Database("upperio_tests").Collection("fibonacci")
+			col.Drop(ctx)
 
-			col = mgod.DB("upperio_tests").C("is_even")
-			col.DropCollection()
+			col = client.Database("upperio_tests").Collection("is_even")
+			col.Drop(ctx)
 
-			col = mgod.DB("upperio_tests").C("CaSe_TesT")
-			col.DropCollection()
+			col = client.Database("upperio_tests").Collection("CaSe_TesT")
+			col.Drop(ctx)
 			return nil
 		}
 		return errDriverErr
@@ -468,23 +470,23 @@ type oddEven struct {
 	// Test for JSON option.
 	Input int `json:"input" db:"input"`
 	// Test for JSON option.
-	// The "bson" tag is required by mgo.
+	// The "bson" tag is required by the mongo driver.
 	IsEven bool `json:"is_even" db:"is_even,json" bson:"is_even"`
 	OmitMe bool `json:"omit_me" db:"-" bson:"-"`
 }
 
 // Struct that relies on explicit mapping.
 type mapE struct {
-	ID       uint          `db:"id,omitempty" bson:"-"`
-	MongoID  bson.ObjectId `db:"-" bson:"_id,omitempty"`
-	CaseTest string        `db:"case_test" bson:"case_test"`
+	ID       uint               `db:"id,omitempty" bson:"-"`
+	MongoID  primitive.ObjectID `db:"-" bson:"_id,omitempty"`
+	CaseTest string             `db:"case_test" bson:"case_test"`
 }
 
 // Struct that will fallback to default mapping.
 type mapN struct {
-	ID        uint          `db:"id,omitempty"`
-	MongoID   bson.ObjectId `db:"-" bson:"_id,omitempty"`
-	Case_TEST string        `db:"case_test"`
+	ID        uint               `db:"id,omitempty"`
+	MongoID   primitive.ObjectID `db:"-" bson:"_id,omitempty"`
+	Case_TEST string             `db:"case_test"`
 }
 
 // Struct for testing marshalling.
@@ -631,7 +633,7 @@ func TestSimpleCRUD(t *testing.T) {
 			var res db.Result
 			switch wrapper {
 			case `mongo`:
-				res = col.Find(db.Cond{"_id": id.(bson.ObjectId)})
+				res = col.Find(db.Cond{"_id": id.(primitive.ObjectID)})
 			case `ql`:
 				res = col.Find(db.Cond{"id()": id})
 			default:
@@ -1041,7 +1043,7 @@ func TestEven(t *testing.T) {
 			res = col.Find()
 
 			var item2 struct {
-				Value uint `db:"input" bson:"input"` // The "bson" tag is required by mgo.
+				Value uint `db:"input" bson:"input"` // The "bson" tag is required by the mongo driver.
 			}
 			for res.Next(&item2) {
 				if item2.Value%2 == 0 {
@@ -1159,7 +1161,7 @@ func TestExplicitAndDefaultMapping(t *testing.T) {
 			}
 
 			if wrapper == `mongo` {
-				if testE.MongoID.Valid() == false {
+				if testE.MongoID.IsZero() {
 					t.Fatalf("Expecting an ID.")
 				}
 			} else {
@@ -1192,7 +1194,7 @@ func TestExplicitAndDefaultMapping(t *testing.T) {
 			}
 
 			if wrapper == `mongo` {
-				if testN.MongoID.Valid() == false {
+				if testN.MongoID.IsZero() {
 					t.Fatalf("Expecting an ID.")
 				}
 			} else {
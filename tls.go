@@ -0,0 +1,109 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSConfig tells how connections to the database server are encrypted. The
+// ConnectionURL of each adapter accepts one and translates it into the
+// options of its driver, so there's no need to learn how each driver encodes
+// them:
+//
+//	settings := postgresql.ConnectionURL{
+//		Host:     "db.example.com",
+//		Database: "billing",
+//		TLS: &db.TLSConfig{
+//			CAFile:   "/etc/ssl/db-ca.pem",
+//			CertFile: "/etc/ssl/client.pem",
+//			KeyFile:  "/etc/ssl/client.key",
+//		},
+//	}
+//
+// Open fails with ErrUnsupported if the driver can't use some of the fields,
+// and with a descriptive error if the files can't be loaded.
+type TLSConfig struct {
+	// Config is used as it is, it can't be combined with the other fields.
+	Config *tls.Config
+
+	// CAFile is the path of the PEM-encoded certificates of the authorities
+	// the server certificate is verified with. The ones of the system are
+	// used if it's empty.
+	CAFile string
+
+	// CertFile and KeyFile are the paths of the PEM-encoded client
+	// certificate and its key.
+	CertFile string
+	KeyFile  string
+
+	// InsecureSkipVerify disables the verification of the server
+	// certificate, connections are still encrypted.
+	InsecureSkipVerify bool
+}
+
+// Validate checks that the fields can be used together and that the files
+// can be loaded.
+func (c *TLSConfig) Validate() error {
+	_, err := c.Load()
+	return err
+}
+
+// Load returns the *tls.Config described by c.
+func (c *TLSConfig) Load() (*tls.Config, error) {
+	if c.Config != nil {
+		if c.CAFile != "" || c.CertFile != "" || c.KeyFile != "" || c.InsecureSkipVerify {
+			return nil, errors.New("upper: TLS Config can't be combined with other TLS options")
+		}
+		return c.Config, nil
+	}
+
+	cfg := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("upper: could not read TLS CA file: %v", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("upper: no certificates found in TLS CA file %q", c.CAFile)
+		}
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, errors.New("upper: TLS CertFile and KeyFile must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("upper: could not load TLS client certificate: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
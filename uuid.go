@@ -0,0 +1,141 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package db
+
+import (
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// UUID is a 128-bit universally unique identifier, it's bound to queries in its
+// canonical textual form and can be scanned from both that form and 16 raw
+// bytes.
+//
+// Fields tagged with "uuid" are given a new UUID on insert when they're zero,
+// either by the database, like PostgreSQL's gen_random_uuid(), or by the
+// adapter with NewUUIDv7. The "pk" option is informative, keys are still read
+// from the schema:
+//
+//	type Account struct {
+//		ID   db.UUID `db:"id,pk,uuid"`
+//		Name string  `db:"name"`
+//	}
+type UUID [16]byte
+
+// ErrInvalidUUID is returned when a value can't be parsed as an UUID.
+var ErrInvalidUUID = errors.New(`upper: invalid UUID`)
+
+// NewUUIDv7 returns a new version 7 UUID, its first 48 bits are the current
+// Unix time in milliseconds so that UUIDs sort by their creation time.
+func NewUUIDv7() (UUID, error) {
+	var u UUID
+	if _, err := rand.Read(u[6:]); err != nil {
+		return UUID{}, err
+	}
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	copy(u[:6], ts[2:])
+	u[6] = (u[6] & 0x0f) | 0x70 // Version 7.
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant.
+	return u, nil
+}
+
+// ParseUUID parses an UUID in its canonical textual form, like
+// "0189d4a2-7b3c-7def-8a01-23456789abcd".
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return UUID{}, ErrInvalidUUID
+	}
+	src := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	if _, err := hex.Decode(u[:], []byte(src)); err != nil {
+		return UUID{}, ErrInvalidUUID
+	}
+	return u, nil
+}
+
+// String returns the canonical textual form of the UUID.
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// IsZero reports whether the UUID is the nil UUID.
+func (u UUID) IsZero() bool {
+	return u == UUID{}
+}
+
+// Value implements driver.Valuer.
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// Scan implements sql.Scanner.
+func (u *UUID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*u = UUID{}
+		return nil
+	case string:
+		return u.parse(v)
+	case []byte:
+		if len(v) == 16 {
+			copy(u[:], v)
+			return nil
+		}
+		return u.parse(string(v))
+	}
+	return fmt.Errorf("upper: can't scan %T into UUID", src)
+}
+
+func (u *UUID) parse(s string) error {
+	v, err := ParseUUID(s)
+	if err != nil {
+		return err
+	}
+	*u = v
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (u *UUID) UnmarshalText(text []byte) error {
+	return u.parse(string(text))
+}
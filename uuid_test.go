@@ -0,0 +1,54 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUUIDv7(t *testing.T) {
+	a, err := NewUUIDv7()
+	assert.NoError(t, err)
+	b, err := NewUUIDv7()
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, a, b)
+	assert.False(t, a.IsZero())
+	assert.Equal(t, byte(0x70), a[6]&0xf0)
+	assert.Equal(t, byte(0x80), a[8]&0xc0)
+	assert.True(t, a.String()[:8] <= b.String()[:8])
+}
+
+func TestParseUUID(t *testing.T) {
+	u, err := ParseUUID("0189d4a2-7b3c-7def-8a01-23456789abcd")
+	assert.NoError(t, err)
+	assert.Equal(t, "0189d4a2-7b3c-7def-8a01-23456789abcd", u.String())
+
+	v, err := u.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "0189d4a2-7b3c-7def-8a01-23456789abcd", v)
+
+	for _, s := range []string{"", "0189d4a27b3c7def8a0123456789abcd", "0189d4a2-7b3c-7def-8a01-23456789abcx"} {
+		_, err := ParseUUID(s)
+		assert.Equal(t, ErrInvalidUUID, err)
+	}
+}
+
+func TestUUIDScan(t *testing.T) {
+	var u UUID
+	assert.NoError(t, u.Scan("0189d4a2-7b3c-7def-8a01-23456789abcd"))
+	assert.Equal(t, "0189d4a2-7b3c-7def-8a01-23456789abcd", u.String())
+
+	var raw UUID
+	assert.NoError(t, raw.Scan(u[:]))
+	assert.Equal(t, u, raw)
+
+	var text UUID
+	assert.NoError(t, text.Scan([]byte(u.String())))
+	assert.Equal(t, u, text)
+
+	assert.NoError(t, u.Scan(nil))
+	assert.True(t, u.IsZero())
+
+	assert.Error(t, u.Scan(1))
+}
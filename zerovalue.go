@@ -0,0 +1,48 @@
+// Copyright (c) 2012-present The upper.io/db authors. All rights reserved.
+//
+// Permission is hereby granted, free of charge, to any person obtaining
+// a copy of this software and associated documentation files (the
+// "Software"), to deal in the Software without restriction, including
+// without limitation the rights to use, copy, modify, merge, publish,
+// distribute, sublicense, and/or sell copies of the Software, and to
+// permit persons to whom the Software is furnished to do so, subject to
+// the following conditions:
+//
+// The above copyright notice and this permission notice shall be
+// included in all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+// EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+// MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND
+// NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE
+// LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION
+// OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION
+// WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+package db
+
+// ZeroValuePolicy tells SQL sessions how to write the zero values of struct
+// fields on inserts and updates, see Settings.SetZeroValuePolicy.
+//
+// Fields may override the policy of the session with the "nullzero" tag
+// option, which writes their zero values as NULL, or with "keepzero", which
+// writes them as they are:
+//
+//	type Account struct {
+//		ID        int64     `db:"id,omitempty"`
+//		Email     string    `db:"email,keepzero"`
+//		DeletedAt time.Time `db:"deleted_at,nullzero"`
+//	}
+//
+// Zero fields tagged with omitempty are left out either way so the default
+// values of their columns apply.
+type ZeroValuePolicy int
+
+// Zero value policies.
+const (
+	// ZeroAsValue writes zero values as they are, like 0, "" or
+	// "0001-01-01 00:00:00". This is the default.
+	ZeroAsValue ZeroValuePolicy = iota
+
+	// ZeroAsNull writes zero values as NULL.
+	ZeroAsNull
+)